@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultActivityDays   = 7
+	defaultActivityEvents = 50
+	maxActivityEvents     = 200
+	// activityLookbackDays is how long before the window pull requests are read for, so those
+	// completed or abandoned in the window are found even when they were opened earlier.
+	activityLookbackDays = 30
+)
+
+// activityEvent is one entry of a repository's activity feed.
+type activityEvent struct {
+	at    time.Time
+	entry map[string]interface{}
+}
+
+// newActivityEvent returns an event of a kind at a time, formatting the time into its entry.
+func newActivityEvent(kind string, at time.Time, entry map[string]interface{}) activityEvent {
+	entry["type"] = kind
+	entry["time"] = at.UTC().Format(time.RFC3339)
+	return activityEvent{at: at, entry: entry}
+}
+
+// pushEvents returns the pushes to a repository since a time, with the branches each updated.
+func (c *AzureDevOpsClient) pushEvents(ctx context.Context, repoID string, since time.Time) ([]activityEvent, error) {
+	includeRefUpdates := true
+	top := maxActivityEvents
+	pushes, err := c.gitClient.GetPushes(ctx, git.GetPushesArgs{
+		RepositoryId: &repoID,
+		Project:      &c.config.AzureDevOps.Project,
+		Top:          &top,
+		SearchCriteria: &git.GitPushSearchCriteria{
+			FromDate:          &azuredevops.Time{Time: since},
+			IncludeRefUpdates: &includeRefUpdates,
+		},
+	})
+	if err != nil {
+		log.Printf("Error getting pushes: %v", err)
+		return nil, fmt.Errorf("error getting pushes: %w", err)
+	}
+
+	events := []activityEvent{}
+	for _, push := range *pushes {
+		if push.Date == nil || push.Date.Time.Before(since) {
+			continue
+		}
+		entry := map[string]interface{}{}
+		if push.PushId != nil {
+			entry["pushId"] = *push.PushId
+		}
+		if push.PushedBy != nil {
+			entry["by"] = stringValue(push.PushedBy.DisplayName)
+		}
+		if push.RefUpdates != nil {
+			branches := []string{}
+			for _, update := range *push.RefUpdates {
+				branches = append(branches, stringValue(update.Name))
+			}
+			entry["refs"] = branches
+		}
+		events = append(events, newActivityEvent("push", push.Date.Time, entry))
+	}
+	return events, nil
+}
+
+// pullRequestEvents returns the pull requests of a repository opened, completed, or abandoned
+// since a time. Pull requests opened more than activityLookbackDays before it are not read.
+func (c *AzureDevOpsClient) pullRequestEvents(ctx context.Context, repoID, repoName string, since time.Time) ([]activityEvent, error) {
+	status := git.PullRequestStatusValues.All
+	criteria := &git.GitPullRequestSearchCriteria{Status: &status}
+	oldest := since.AddDate(0, 0, -activityLookbackDays)
+	events := []activityEvent{}
+	top := pullRequestPageSize
+	skip := 0
+	// Pull requests come newest first, so paging stops at the first one opened before the lookback.
+	for done := false; !done; skip += top {
+		page, err := c.gitClient.GetPullRequests(ctx, git.GetPullRequestsArgs{
+			RepositoryId:   &repoID,
+			Project:        &c.config.AzureDevOps.Project,
+			SearchCriteria: criteria,
+			Top:            &top,
+			Skip:           &skip,
+		})
+		if err != nil {
+			log.Printf("Error getting pull requests: %v", err)
+			return nil, fmt.Errorf("error getting pull requests of %s: %w", repoName, err)
+		}
+		for _, pr := range *page {
+			if pr.CreationDate == nil || pr.CreationDate.Time.Before(oldest) {
+				done = true
+				break
+			}
+			if pr.PullRequestId == nil {
+				continue
+			}
+			id := *pr.PullRequestId
+			details := func() map[string]interface{} {
+				return map[string]interface{}{
+					"pullRequestId": id,
+					"title":         stringValue(pr.Title),
+					"targetBranch":  stringValue(pr.TargetRefName),
+					"webUrl":        c.links.pullRequest(c.config.AzureDevOps.Project, repoName, id),
+				}
+			}
+			if !pr.CreationDate.Time.Before(since) {
+				entry := details()
+				if pr.CreatedBy != nil {
+					entry["by"] = stringValue(pr.CreatedBy.DisplayName)
+				}
+				entry["isDraft"] = pr.IsDraft != nil && *pr.IsDraft
+				events = append(events, newActivityEvent("pullRequestOpened", pr.CreationDate.Time, entry))
+			}
+			if pr.ClosedDate != nil && !pr.ClosedDate.Time.Before(since) && pr.Status != nil {
+				kind := "pullRequestAbandoned"
+				if *pr.Status == git.PullRequestStatusValues.Completed {
+					kind = "pullRequestCompleted"
+				}
+				entry := details()
+				if pr.ClosedBy != nil {
+					entry["by"] = stringValue(pr.ClosedBy.DisplayName)
+				}
+				events = append(events, newActivityEvent(kind, pr.ClosedDate.Time, entry))
+			}
+		}
+		if len(*page) < top {
+			done = true
+		}
+	}
+	return events, nil
+}
+
+// buildEvents returns the builds of a repository's pipelines that finished since a time.
+func (c *AzureDevOpsClient) buildEvents(ctx context.Context, repoID string, since time.Time) ([]activityEvent, error) {
+	top := maxActivityEvents
+	status := build.BuildStatusValues.Completed
+	order := build.BuildQueryOrderValues.FinishTimeDescending
+	repositoryType := "TfsGit"
+	builds, err := c.buildClient.GetBuilds(ctx, build.GetBuildsArgs{
+		Project:        &c.config.AzureDevOps.Project,
+		RepositoryId:   &repoID,
+		RepositoryType: &repositoryType,
+		MinTime:        &azuredevops.Time{Time: since},
+		StatusFilter:   &status,
+		QueryOrder:     &order,
+		Top:            &top,
+	})
+	if err != nil {
+		log.Printf("Error getting builds: %v", err)
+		return nil, fmt.Errorf("error getting builds: %w", err)
+	}
+
+	events := []activityEvent{}
+	for _, run := range builds.Value {
+		if run.Id == nil || run.FinishTime == nil || run.FinishTime.Time.Before(since) {
+			continue
+		}
+		entry := map[string]interface{}{
+			"buildId":      *run.Id,
+			"buildNumber":  stringValue(run.BuildNumber),
+			"sourceBranch": stringValue(run.SourceBranch),
+			"webUrl":       c.links.build(c.config.AzureDevOps.Project, *run.Id),
+		}
+		if run.Definition != nil {
+			entry["pipeline"] = stringValue(run.Definition.Name)
+		}
+		if run.Result != nil {
+			entry["result"] = string(*run.Result)
+		}
+		if run.Reason != nil {
+			entry["reason"] = string(*run.Reason)
+		}
+		if run.RequestedFor != nil {
+			entry["by"] = stringValue(run.RequestedFor.DisplayName)
+		}
+		events = append(events, newActivityEvent("build", run.FinishTime.Time, entry))
+	}
+	return events, nil
+}
+
+// repositoryActivity merges the pushes, pull request changes, and finished builds of a repository
+// in its last days into one feed, newest first, with the number of events of each type. Each
+// source is read independently and a failing one is reported under errors.
+func (c *AzureDevOpsClient) repositoryActivity(ctx context.Context, repoName string, days, top int) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+	repoName = stringValue(repo.Name)
+	since := time.Now().AddDate(0, 0, -days)
+
+	events := []activityEvent{}
+	errors := map[string]string{}
+	if pushes, err := c.pushEvents(ctx, repoID, since); err != nil {
+		errors["pushes"] = err.Error()
+	} else {
+		events = append(events, pushes...)
+	}
+	if pullRequests, err := c.pullRequestEvents(ctx, repoID, repoName, since); err != nil {
+		errors["pullRequests"] = err.Error()
+	} else {
+		events = append(events, pullRequests...)
+	}
+	if builds, err := c.buildEvents(ctx, repoID, since); err != nil {
+		errors["builds"] = err.Error()
+	} else {
+		events = append(events, builds...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.After(events[j].at)
+	})
+	counts := map[string]int{}
+	for _, event := range events {
+		counts[event.entry["type"].(string)]++
+	}
+	feed := []map[string]interface{}{}
+	for _, event := range events {
+		if len(feed) == top {
+			break
+		}
+		feed = append(feed, event.entry)
+	}
+
+	result := map[string]interface{}{
+		"repository": repoName,
+		"since":      since.Format("2006-01-02"),
+		"counts":     counts,
+		"events":     feed,
+	}
+	if len(events) > top {
+		result["truncated"] = fmt.Sprintf("only the latest %d of %d events are listed", top, len(events))
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addActivityTools(s tools.Server, client *AzureDevOpsClient) {
+	activityTool := mcp.NewTool("get_repository_activity",
+		mcp.WithDescription("Catch up on a repository: its pushes, pull requests opened, completed, and abandoned, and finished builds in the last days, merged into one feed, newest first"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithNumber("days",
+			mcp.Description("Length of the window in days, ending now"),
+			mcp.DefaultNumber(defaultActivityDays),
+		),
+		mcp.WithNumber("top",
+			mcp.Description(fmt.Sprintf("Maximum number of events, at most %d", maxActivityEvents)),
+			mcp.DefaultNumber(defaultActivityEvents),
+		),
+	)
+
+	s.AddTool(activityTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		days := defaultActivityDays
+		if value, ok := request.Params.Arguments["days"].(float64); ok && value > 0 {
+			days = int(value)
+		}
+		top := defaultActivityEvents
+		if value, ok := request.Params.Arguments["top"].(float64); ok && value > 0 {
+			top = int(value)
+		}
+		if top > maxActivityEvents {
+			top = maxActivityEvents
+		}
+
+		result, err := client.repositoryActivity(ctx, repo, days, top)
+		if err != nil {
+			log.Printf("Error getting repository activity: %v", err)
+			return nil, fmt.Errorf("error getting repository activity: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxAnalyticsRows caps how many rows are collected across OData result pages.
+const maxAnalyticsRows = 1000
+
+// analyticsEntitySet guards the entity set segment of the request path.
+var analyticsEntitySet = regexp.MustCompile(`^[A-Za-z]+$`)
+
+// odataEscape escapes a query option value; OData expects %20 rather than + for spaces.
+func odataEscape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+// flattenODataRow turns an OData entity into a single-level row. Expanded navigation properties
+// become Parent/Child columns and OData annotations are dropped.
+func flattenODataRow(prefix string, entity map[string]interface{}, row map[string]interface{}) {
+	for key, value := range entity {
+		if strings.Contains(key, "@odata.") {
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenODataRow(prefix+key+"/", nested, row)
+			continue
+		}
+		row[prefix+key] = value
+	}
+}
+
+// queryAnalytics runs an OData query against an Analytics entity set of the configured project
+// and returns its rows as a table.
+func (c *AzureDevOpsClient) queryAnalytics(ctx context.Context, entitySet string, options map[string]string) (map[string]interface{}, error) {
+	if !analyticsEntitySet.MatchString(entitySet) {
+		return nil, fmt.Errorf("invalid entity set: %s", entitySet)
+	}
+
+	params := []string{}
+	for _, key := range []string{"$apply", "$filter", "$select", "$expand", "$orderby", "$top"} {
+		if value := options[key]; value != "" {
+			params = append(params, key+"="+odataEscape(value))
+		}
+	}
+	requestURL := entitySet
+	if len(params) > 0 {
+		requestURL += "?" + strings.Join(params, "&")
+	}
+	requestURL = c.analyticsURL(requestURL)
+
+	columns := []string{}
+	seen := map[string]bool{}
+	rows := []map[string]interface{}{}
+	truncated := false
+	for requestURL != "" {
+		request, err := c.analyticsClient.CreateRequestMessage(ctx, http.MethodGet, requestURL, "", nil, "", azuredevops.MediaTypeApplicationJson, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating analytics request: %w", err)
+		}
+		response, err := c.analyticsClient.SendRequest(request)
+		if err != nil {
+			log.Printf("Error querying analytics: %v", err)
+			return nil, fmt.Errorf("error querying analytics %s: %w", entitySet, err)
+		}
+
+		var page struct {
+			Value    []map[string]interface{} `json:"value"`
+			NextLink string                   `json:"@odata.nextLink"`
+		}
+		if err := c.analyticsClient.UnmarshalBody(response, &page); err != nil {
+			log.Printf("Error decoding analytics response: %v", err)
+			return nil, fmt.Errorf("error decoding analytics response: %w", err)
+		}
+
+		for _, entity := range page.Value {
+			if len(rows) >= maxAnalyticsRows {
+				truncated = true
+				break
+			}
+			row := map[string]interface{}{}
+			flattenODataRow("", entity, row)
+			for key := range row {
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, key)
+				}
+			}
+			rows = append(rows, row)
+		}
+		if truncated {
+			break
+		}
+		requestURL = page.NextLink
+	}
+
+	table := make([][]interface{}, 0, len(rows))
+	for _, row := range rows {
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			values[i] = row[column]
+		}
+		table = append(table, values)
+	}
+
+	return map[string]interface{}{
+		"columns":   columns,
+		"rows":      table,
+		"rowCount":  len(table),
+		"truncated": truncated,
+	}, nil
+}
+
+// analyticsURL resolves a path relative to the project's OData endpoint.
+func (c *AzureDevOpsClient) analyticsURL(path string) string {
+	return fmt.Sprintf("%s/%s/_odata/v3.0-preview/%s", c.links.analytics(), url.PathEscape(c.config.AzureDevOps.Project), path)
+}
+
+func addAnalyticsTools(s tools.Server, client *AzureDevOpsClient) {
+	analyticsTool := mcp.NewTool("query_analytics",
+		mcp.WithDescription("Run an OData query against Azure DevOps Analytics for metrics such as work item counts, cycle and lead time, or burnup trends. "+
+			"Example: entitySet WorkItems with apply \"filter(WorkItemType eq 'Bug')/groupby((State), aggregate($count as Count))\". "+
+			"Use WorkItemSnapshot with a DateSK filter for trends over time. Results are returned as columns and rows"),
+		mcp.WithString("entitySet",
+			mcp.Required(),
+			mcp.Description("Entity set, e.g. WorkItems, WorkItemSnapshot, WorkItemBoardSnapshot, Iterations, Areas, PipelineRuns, TestResultsDaily"),
+		),
+		mcp.WithString("apply",
+			mcp.Description("$apply aggregation, e.g. groupby((State), aggregate($count as Count))"),
+		),
+		mcp.WithString("filter",
+			mcp.Description("$filter expression, e.g. WorkItemType eq 'User Story' and CompletedDate ge 2024-01-01Z"),
+		),
+		mcp.WithString("select",
+			mcp.Description("$select columns, e.g. WorkItemId,Title,CycleTimeDays"),
+		),
+		mcp.WithString("expand",
+			mcp.Description("$expand navigation properties, e.g. AssignedTo($select=UserName)"),
+		),
+		mcp.WithString("orderby",
+			mcp.Description("$orderby, e.g. CompletedDate desc"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description(fmt.Sprintf("Maximum number of rows; at most %d are returned", maxAnalyticsRows)),
+		),
+	)
+
+	s.AddTool(analyticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		entitySet, ok := request.Params.Arguments["entitySet"].(string)
+		if !ok {
+			log.Print("Entity set must be a string")
+			return nil, fmt.Errorf("entitySet must be a string")
+		}
+
+		options := map[string]string{}
+		for _, name := range []string{"apply", "filter", "select", "expand", "orderby"} {
+			if value, ok := request.Params.Arguments[name].(string); ok {
+				options["$"+name] = value
+			}
+		}
+		if top, ok := request.Params.Arguments["top"].(float64); ok && top > 0 {
+			options["$top"] = fmt.Sprintf("%d", int(top))
+		}
+
+		result, err := client.queryAnalytics(ctx, entitySet, options)
+		if err != nil {
+			log.Printf("Error querying analytics: %v", err)
+			return nil, fmt.Errorf("error querying analytics: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
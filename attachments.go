@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/wiki"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxAttachmentUploadBytes caps the attachments uploaded, which pass through the server in full.
+const maxAttachmentUploadBytes = 25 << 20
+
+// attachmentSourceDescription documents the resourceUri argument of the attachment tools.
+const attachmentSourceDescription = "URI of the attachment's body instead of content: an azdo:// file URI, a repository README or docs resource of this server, or a test attachment URI from download_test_attachment"
+
+// testAttachmentURI matches the URI download_test_attachment returns an attachment under.
+var testAttachmentURI = regexp.MustCompile(`/_apis/test/Runs/(\d+)(?:/Results/(\d+))?/Attachments/(\d+)$`)
+
+// resourceBytes returns the body of a resource, decoding blobs.
+func resourceBytes(contents mcp.ResourceContents) ([]byte, error) {
+	switch content := contents.(type) {
+	case mcp.TextResourceContents:
+		return []byte(content.Text), nil
+	case mcp.BlobResourceContents:
+		return base64.StdEncoding.DecodeString(content.Blob)
+	}
+	return nil, fmt.Errorf("unsupported resource contents %T", contents)
+}
+
+// readResourceURI reads the body of a resource the server can resolve, with a file name for it:
+// a file of a repository by its azdo:// URI, a repository README or docs index, or a test
+// attachment.
+func (c *AzureDevOpsClient) readResourceURI(ctx context.Context, uri string) ([]byte, string, error) {
+	if strings.HasPrefix(uri, "azdo://") {
+		entity, err := c.parseURI(uri, fileEntity)
+		if err != nil {
+			return nil, "", err
+		}
+		repo, err := c.findRepositoryIn(ctx, entity.Project, entity.Repository)
+		if err != nil {
+			return nil, "", err
+		}
+		repoID := repo.Id.String()
+		reader, err := c.gitClient.GetItemContent(ctx, git.GetItemContentArgs{
+			RepositoryId:      &repoID,
+			Path:              &entity.Path,
+			Project:           &entity.Project,
+			VersionDescriptor: versionDescriptor(entity.Ref),
+		})
+		if err != nil {
+			log.Printf("Error getting file content: %v", err)
+			return nil, "", fmt.Errorf("error reading %s: %w", entity.Path, err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(io.LimitReader(reader, maxAttachmentUploadBytes+1))
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading %s: %w", entity.Path, err)
+		}
+		return data, path.Base(entity.Path), nil
+	}
+
+	if match := testAttachmentURI.FindStringSubmatch(uri); match != nil && strings.HasPrefix(uri, c.links.project(c.config.AzureDevOps.Project)) {
+		runID, _ := strconv.Atoi(match[1])
+		resultID, _ := strconv.Atoi(match[2])
+		attachmentID, _ := strconv.Atoi(match[3])
+		contents, name, err := c.downloadTestAttachment(ctx, runID, resultID, attachmentID)
+		if err != nil {
+			return nil, "", err
+		}
+		data, err := resourceBytes(contents)
+		return data, name, err
+	}
+
+	prefix := c.repositoryResourceURI("", "")
+	prefix = strings.TrimSuffix(prefix, "/")
+	if rest, ok := strings.CutPrefix(uri, prefix); ok {
+		repoSegment, resource, _ := strings.Cut(rest, "/")
+		repoName, err := url.PathUnescape(repoSegment)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s names no repository: %w", uri, err)
+		}
+		var contents []mcp.ResourceContents
+		name := repoName + "-" + resource + ".md"
+		switch resource {
+		case "readme":
+			contents, err = c.readRepositoryReadme(ctx, uri, repoName)
+			name = repoName + "-README.md"
+		case "docs":
+			contents, err = c.readRepositoryDocsIndex(ctx, uri, repoName)
+		default:
+			return nil, "", fmt.Errorf("%s is not a resource of this server", uri)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		data, err := resourceBytes(contents[0])
+		return data, name, err
+	}
+
+	return nil, "", fmt.Errorf("%s is not a file, repository resource, or test attachment URI this server can read", uri)
+}
+
+// attachmentBody reads the body of an attachment from the base64 content argument or the
+// resource named by the resourceUri argument, with the file name the resource suggests.
+func (c *AzureDevOpsClient) attachmentBody(ctx context.Context, arguments map[string]interface{}) ([]byte, string, error) {
+	content, _ := arguments["content"].(string)
+	uri, _ := arguments["resourceUri"].(string)
+	if (content == "") == (uri == "") {
+		return nil, "", fmt.Errorf("pass either content or resourceUri")
+	}
+
+	var data []byte
+	name := ""
+	var err error
+	if content != "" {
+		data, err = base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, "", fmt.Errorf("content must be base64: %w", err)
+		}
+	} else {
+		data, name, err = c.readResourceURI(ctx, uri)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if len(data) > maxAttachmentUploadBytes {
+		return nil, "", fmt.Errorf("attachments are limited to %d MB", maxAttachmentUploadBytes>>20)
+	}
+	return data, name, nil
+}
+
+// attachToWorkItem uploads data as an attachment named fileName and links it to a work item with
+// an optional comment. A dry run returns what would be attached instead.
+func (c *AzureDevOpsClient) attachToWorkItem(ctx context.Context, id int, fileName, comment string, data []byte, dryRun bool) (map[string]interface{}, error) {
+	if dryRun {
+		return tools.DryRunResult("add_work_item_attachment", map[string]interface{}{
+			"workItemId": id,
+			"fileName":   fileName,
+			"size":       len(data),
+			"comment":    comment,
+		}), nil
+	}
+
+	attachment, err := c.witClient.CreateAttachment(ctx, workitemtracking.CreateAttachmentArgs{
+		UploadStream: bytes.NewReader(data),
+		Project:      &c.config.AzureDevOps.Project,
+		FileName:     &fileName,
+	})
+	if err != nil {
+		log.Printf("Error uploading attachment: %v", err)
+		return nil, fmt.Errorf("error uploading %s: %w", fileName, err)
+	}
+
+	relation := map[string]interface{}{
+		"rel": "AttachedFile",
+		"url": stringValue(attachment.Url),
+	}
+	if comment != "" {
+		relation["attributes"] = map[string]interface{}{"comment": comment}
+	}
+	path := "/relations/-"
+	document := []webapi.JsonPatchOperation{{
+		Op:    &webapi.OperationValues.Add,
+		Path:  &path,
+		Value: relation,
+	}}
+	_, err = c.witClient.UpdateWorkItem(ctx, workitemtracking.UpdateWorkItemArgs{
+		Document: &document,
+		Id:       &id,
+		Project:  &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error linking attachment: %v", err)
+		return nil, fmt.Errorf("error attaching %s to work item %d: %w", fileName, id, err)
+	}
+
+	return map[string]interface{}{
+		"workItemId": id,
+		"fileName":   fileName,
+		"size":       len(data),
+		"url":        stringValue(attachment.Url),
+	}, nil
+}
+
+// attachToWiki uploads data as an attachment of a wiki, returning its path and the markdown that
+// embeds it in a page. The wiki API takes the body base64 encoded. A dry run returns what would
+// be uploaded instead.
+func (c *AzureDevOpsClient) attachToWiki(ctx context.Context, wikiName, name string, data []byte, dryRun bool) (map[string]interface{}, error) {
+	link := "[" + name + "](/.attachments/" + url.PathEscape(name) + ")"
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp":
+		link = "!" + link
+	}
+	if dryRun {
+		return tools.DryRunResult("add_wiki_attachment", map[string]interface{}{
+			"wiki": wikiName,
+			"name": name,
+			"size": len(data),
+		}), nil
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	response, err := c.wikiClient.CreateAttachment(ctx, wiki.CreateAttachmentArgs{
+		UploadStream:   strings.NewReader(encoded),
+		Project:        &c.config.AzureDevOps.Project,
+		WikiIdentifier: &wikiName,
+		Name:           &name,
+	})
+	if err != nil {
+		log.Printf("Error uploading wiki attachment: %v", err)
+		return nil, fmt.Errorf("error uploading %s to wiki %s: %w", name, wikiName, err)
+	}
+
+	result := map[string]interface{}{
+		"wiki":     wikiName,
+		"name":     name,
+		"size":     len(data),
+		"markdown": link,
+	}
+	if response.Attachment != nil {
+		if attachmentPath := stringValue(response.Attachment.Path); attachmentPath != "" {
+			result["path"] = attachmentPath
+			result["markdown"] = strings.Replace(link, "/.attachments/"+url.PathEscape(name), attachmentPath, 1)
+		}
+	}
+	return result, nil
+}
+
+// attachmentFileName returns the name argument, or else the name the resource suggested.
+func attachmentFileName(arguments map[string]interface{}, key, suggested string) (string, error) {
+	name, _ := arguments[key].(string)
+	if name == "" {
+		name = suggested
+	}
+	if name == "" {
+		return "", fmt.Errorf("%s is required with content", key)
+	}
+	return name, nil
+}
+
+func addWorkItemAttachmentTools(s tools.Server, client *AzureDevOpsClient) {
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	attachTool := mcp.NewTool("add_work_item_attachment",
+		mcp.WithDescription("Attach a file to a work item, such as a log, screenshot, or repro file, from base64 content or a resource URI"),
+		mcp.WithNumber("workItemId",
+			mcp.Description("Work item ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the work item"),
+		),
+		mcp.WithString("fileName",
+			mcp.Description("File name of the attachment; defaults to the resource's"),
+		),
+		mcp.WithString("content",
+			mcp.Description("Base64 body of the attachment"),
+		),
+		mcp.WithString("resourceUri",
+			mcp.Description(attachmentSourceDescription),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Comment on the attachment"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(attachTool, writeHandler(client, attachTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := client.idArgument(request, "workItemId", workItemEntity)
+		if err != nil {
+			log.Printf("Invalid work item: %v", err)
+			return nil, err
+		}
+		data, suggested, err := client.attachmentBody(ctx, request.Params.Arguments)
+		if err != nil {
+			log.Printf("Invalid attachment: %v", err)
+			return nil, err
+		}
+		fileName, err := attachmentFileName(request.Params.Arguments, "fileName", suggested)
+		if err != nil {
+			log.Printf("Invalid attachment: %v", err)
+			return nil, err
+		}
+		comment, _ := request.Params.Arguments["comment"].(string)
+
+		result, err := client.attachToWorkItem(ctx, id, fileName, comment, data, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error attaching file to work item: %v", err)
+			return nil, fmt.Errorf("error attaching file to work item: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
+
+func addWikiAttachmentTools(s tools.Server, client *AzureDevOpsClient) {
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	attachTool := mcp.NewTool("add_wiki_attachment",
+		mcp.WithDescription("Upload a file, such as an image or diagram, to a wiki's attachments from base64 content or a resource URI, and return the markdown that embeds it in a page"),
+		mcp.WithString("wiki",
+			mcp.Required(),
+			mcp.Description("Wiki name or ID"),
+		),
+		mcp.WithString("name",
+			mcp.Description("File name of the attachment; defaults to the resource's"),
+		),
+		mcp.WithString("content",
+			mcp.Description("Base64 body of the attachment"),
+		),
+		mcp.WithString("resourceUri",
+			mcp.Description(attachmentSourceDescription),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(attachTool, writeHandler(client, attachTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		wikiName, ok := request.Params.Arguments["wiki"].(string)
+		if !ok {
+			log.Print("Wiki must be a string")
+			return nil, fmt.Errorf("wiki must be a string")
+		}
+		data, suggested, err := client.attachmentBody(ctx, request.Params.Arguments)
+		if err != nil {
+			log.Printf("Invalid attachment: %v", err)
+			return nil, err
+		}
+		name, err := attachmentFileName(request.Params.Arguments, "name", suggested)
+		if err != nil {
+			log.Printf("Invalid attachment: %v", err)
+			return nil, err
+		}
+
+		result, err := client.attachToWiki(ctx, wikiName, name, data, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error uploading wiki attachment: %v", err)
+			return nil, fmt.Errorf("error uploading wiki attachment: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
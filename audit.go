@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/audit"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultAuditDays    = 7
+	defaultAuditEntries = 100
+	maxAuditEntries     = 1000
+	auditBatchSize      = 200
+	maxAuditEntriesRead = 10000
+)
+
+// AuditLogFilter narrows audit log entries. The audit API only filters by time, so actor,
+// category, area, and project are matched client side.
+type AuditLogFilter struct {
+	Start    time.Time
+	End      time.Time
+	Actor    string
+	Category string
+	Area     string
+	Project  string
+	Top      int
+}
+
+func (f AuditLogFilter) matches(entry audit.DecoratedAuditLogEntry) bool {
+	if f.Actor != "" {
+		actor := strings.ToLower(f.Actor)
+		if !strings.Contains(strings.ToLower(stringValue(entry.ActorDisplayName)), actor) &&
+			!strings.Contains(strings.ToLower(stringValue(entry.ActorUPN)), actor) {
+			return false
+		}
+	}
+	if f.Category != "" {
+		category := ""
+		if entry.Category != nil {
+			category = string(*entry.Category)
+		}
+		if !strings.EqualFold(category, f.Category) && !strings.EqualFold(stringValue(entry.CategoryDisplayName), f.Category) {
+			return false
+		}
+	}
+	if f.Area != "" && !strings.EqualFold(stringValue(entry.Area), f.Area) {
+		return false
+	}
+	if f.Project != "" && !strings.EqualFold(stringValue(entry.ProjectName), f.Project) {
+		return false
+	}
+	return true
+}
+
+// queryAuditLog returns organization audit log entries matching the filter, newest first.
+func (c *AzureDevOpsClient) queryAuditLog(ctx context.Context, filter AuditLogFilter) (map[string]interface{}, error) {
+	batchSize := auditBatchSize
+	skipAggregation := false
+	args := audit.QueryLogArgs{
+		StartTime:       &azuredevops.Time{Time: filter.Start},
+		EndTime:         &azuredevops.Time{Time: filter.End},
+		BatchSize:       &batchSize,
+		SkipAggregation: &skipAggregation,
+	}
+
+	results := []map[string]interface{}{}
+	read := 0
+	hasMore := false
+	for len(results) < filter.Top && read < maxAuditEntriesRead {
+		page, err := c.auditClient.QueryLog(ctx, args)
+		if err != nil {
+			log.Printf("Error querying audit log: %v", err)
+			return nil, fmt.Errorf("error querying audit log: %w", err)
+		}
+		hasMore = page.HasMore != nil && *page.HasMore
+		if page.DecoratedAuditLogEntries == nil {
+			break
+		}
+
+		for _, entry := range *page.DecoratedAuditLogEntries {
+			read++
+			if !filter.matches(entry) || len(results) >= filter.Top {
+				continue
+			}
+			result := map[string]interface{}{
+				"actionId":  stringValue(entry.ActionId),
+				"area":      stringValue(entry.Area),
+				"category":  stringValue(entry.CategoryDisplayName),
+				"actor":     stringValue(entry.ActorDisplayName),
+				"actorUPN":  stringValue(entry.ActorUPN),
+				"details":   stringValue(entry.Details),
+				"ipAddress": stringValue(entry.IpAddress),
+				"scope":     stringValue(entry.ScopeDisplayName),
+			}
+			if entry.ProjectName != nil {
+				result["project"] = *entry.ProjectName
+			}
+			if entry.Timestamp != nil {
+				result["timestamp"] = entry.Timestamp.Time.Format(time.RFC3339)
+			}
+			if entry.Data != nil {
+				result["data"] = *entry.Data
+			}
+			results = append(results, result)
+		}
+
+		if !hasMore || page.ContinuationToken == nil || *page.ContinuationToken == "" {
+			break
+		}
+		args.ContinuationToken = page.ContinuationToken
+	}
+
+	return map[string]interface{}{
+		"startTime": filter.Start.Format(time.RFC3339),
+		"endTime":   filter.End.Format(time.RFC3339),
+		"entries":   results,
+		"scanned":   read,
+		"hasMore":   hasMore,
+	}, nil
+}
+
+func addAuditTools(s tools.Server, client *AzureDevOpsClient) {
+	auditTool := mcp.NewTool("query_audit_log",
+		mcp.WithDescription("Query the organization audit log, e.g. who changed a branch policy, removed a repository, or modified permissions. Requires the audit log to be enabled and a PAT with the Audit Log (Read) scope"),
+		mcp.WithString("startDate",
+			mcp.Description(fmt.Sprintf("Start of the window (YYYY-MM-DD or RFC 3339); defaults to %d days ago", defaultAuditDays)),
+		),
+		mcp.WithString("endDate",
+			mcp.Description("End of the window (YYYY-MM-DD or RFC 3339); defaults to now"),
+		),
+		mcp.WithString("actor",
+			mcp.Description("Only entries by actors whose display name or UPN contains this text"),
+		),
+		mcp.WithString("category",
+			mcp.Description("Only entries of this category: create, modify, remove, access, or execute"),
+		),
+		mcp.WithString("area",
+			mcp.Description("Only entries of this area, e.g. Git, Policy, Permissions, Project, Pipelines, Token"),
+		),
+		mcp.WithString("project",
+			mcp.Description("Only entries for this project name"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description(fmt.Sprintf("Maximum number of entries, at most %d", maxAuditEntries)),
+			mcp.DefaultNumber(defaultAuditEntries),
+		),
+	)
+
+	s.AddTool(auditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filter := AuditLogFilter{
+			End: time.Now().UTC(),
+			Top: defaultAuditEntries,
+		}
+		filter.Start = filter.End.AddDate(0, 0, -defaultAuditDays)
+
+		start, err := timeArgument(request.Params.Arguments, "startDate")
+		if err != nil {
+			return nil, err
+		}
+		if start != nil {
+			filter.Start = *start
+		}
+		end, err := timeArgument(request.Params.Arguments, "endDate")
+		if err != nil {
+			return nil, err
+		}
+		if end != nil {
+			filter.End = *end
+		}
+		filter.Actor, _ = request.Params.Arguments["actor"].(string)
+		filter.Category, _ = request.Params.Arguments["category"].(string)
+		filter.Area, _ = request.Params.Arguments["area"].(string)
+		filter.Project, _ = request.Params.Arguments["project"].(string)
+		if top, ok := request.Params.Arguments["top"].(float64); ok && top > 0 {
+			filter.Top = int(top)
+		}
+		if filter.Top > maxAuditEntries {
+			filter.Top = maxAuditEntries
+		}
+
+		result, err := client.queryAuditLog(ctx, filter)
+		if err != nil {
+			log.Printf("Error querying audit log: %v", err)
+			return nil, fmt.Errorf("error querying audit log: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/location"
+)
+
+// auditCaller identifies who made a tool call: the Azure DevOps user of the call's credential, and
+// the credential itself without revealing it.
+type auditCaller struct {
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Credential string `json:"credential"`
+}
+
+// auditEntry is one line of the tool audit log.
+type auditEntry struct {
+	Time         string                 `json:"time"`
+	Organization string                 `json:"organization"`
+	Project      string                 `json:"project"`
+	Session      string                 `json:"session,omitempty"`
+	Caller       auditCaller            `json:"caller"`
+	Tool         string                 `json:"tool"`
+	Write        bool                   `json:"write"`
+	DryRun       bool                   `json:"dryRun,omitempty"`
+	Arguments    map[string]interface{} `json:"arguments"`
+	Status       string                 `json:"status"`
+	Error        string                 `json:"error,omitempty"`
+	DurationMS   int64                  `json:"durationMs"`
+}
+
+// toolAuditLog appends an entry for every tool call to a JSON Lines file: who called which tool
+// from which session, with what arguments, and how it ended. Each entry is synced to disk before
+// the call returns. Arguments are redacted like the slow-call log.
+type toolAuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+
+	callersMu sync.Mutex
+	callers   map[string]auditCaller
+}
+
+// newToolAuditLog opens the audit log at path for appending, or returns nil when path is empty.
+func newToolAuditLog(path string) (*toolAuditLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		log.Printf("Error opening audit log: %v", err)
+		return nil, fmt.Errorf("error opening audit log %s: %w", path, err)
+	}
+	return &toolAuditLog{file: file, callers: map[string]auditCaller{}}, nil
+}
+
+func (l *toolAuditLog) write(entry auditEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error encoding audit entry: %v", err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		log.Printf("Error writing audit entry: %v", err)
+		return
+	}
+	if err := l.file.Sync(); err != nil {
+		log.Printf("Error syncing audit log: %v", err)
+	}
+}
+
+// caller returns who a tool call is made by, looking the user of each credential up once.
+func (c *AzureDevOpsClient) caller(ctx context.Context) auditCaller {
+	credential := credentialID(ctx)
+	if credential == "" {
+		credential = "service"
+	}
+	key := c.config.AzureDevOps.Organization + "/" + credential
+	c.audit.callersMu.Lock()
+	caller, ok := c.audit.callers[key]
+	c.audit.callersMu.Unlock()
+	if ok {
+		return caller
+	}
+
+	caller = auditCaller{Credential: credential}
+	data, err := c.locationClient.GetConnectionData(ctx, location.GetConnectionDataArgs{})
+	if err != nil {
+		// The entry is still written; the user is looked up again on the next call.
+		log.Printf("Error getting caller of tool call: %v", err)
+		return caller
+	}
+	if user := data.AuthenticatedUser; user != nil {
+		if user.Id != nil {
+			caller.ID = user.Id.String()
+		}
+		caller.Name = stringValue(user.ProviderDisplayName)
+	}
+	c.audit.callersMu.Lock()
+	c.audit.callers[key] = caller
+	c.audit.callersMu.Unlock()
+	return caller
+}
+
+// withAuditLog records every call of a tool in the audit log, when one is configured.
+func withAuditLog(client *AzureDevOpsClient, tool mcp.Tool, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if client.audit == nil {
+		return handler
+	}
+	_, write := tool.InputSchema.Properties["dryRun"]
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := handler(ctx, request)
+
+		entry := auditEntry{
+			Time:         start.UTC().Format(time.RFC3339Nano),
+			Organization: client.config.AzureDevOps.Organization,
+			Project:      client.sessionProject(ctx),
+			Caller:       client.caller(ctx),
+			Tool:         tool.Name,
+			Write:        write,
+			DryRun:       write && client.dryRun(request),
+			Arguments:    redactArguments(request.Params.Arguments),
+			Status:       "ok",
+			DurationMS:   time.Since(start).Milliseconds(),
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			entry.Session = session.SessionID()
+		}
+		switch {
+		case err != nil:
+			entry.Status = "error"
+			entry.Error = err.Error()
+		case result != nil && result.IsError:
+			entry.Status = "tool_error"
+		}
+		client.audit.write(entry)
+		return result, err
+	}
+}
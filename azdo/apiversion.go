@@ -0,0 +1,74 @@
+package azdo
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// apiVersionPattern matches an api-version such as 7.1 or 7.1-preview.1.
+var apiVersionPattern = regexp.MustCompile(`^(\d+)\.(\d+)(-preview(\.\d+)?)?$`)
+
+// apiVersionTransport caps the api-version of Azure DevOps requests at azure_devops.api_version.
+// The SDK asks for the version each endpoint was generated against, and already negotiates it
+// down to the newest the server supports; the cap keeps an older Azure DevOps Server, or an
+// organization pinned to older behavior, on the configured version even where it advertises newer
+// ones. Preview endpoints stay preview at the capped version.
+type apiVersionTransport struct {
+	base         http.RoundTripper
+	major, minor int
+	home         string
+}
+
+func (t *apiVersionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !azureDevOpsHost(strings.ToLower(req.URL.Hostname()), t.home) {
+		return t.base.RoundTrip(req)
+	}
+	accept := req.Header.Get("Accept")
+	mediaType, version, ok := strings.Cut(accept, ";api-version=")
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	matches := apiVersionPattern.FindStringSubmatch(version)
+	if matches == nil {
+		return t.base.RoundTrip(req)
+	}
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+	if major < t.major || major == t.major && minor <= t.minor {
+		return t.base.RoundTrip(req)
+	}
+
+	capped := fmt.Sprintf("%d.%d", t.major, t.minor)
+	if matches[3] != "" {
+		// The resource version of the requested preview need not exist at the capped version.
+		capped += "-preview"
+	}
+	// A RoundTripper must not modify the request it was given.
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept", mediaType+";api-version="+capped)
+	return t.base.RoundTrip(req)
+}
+
+// installAPIVersion caps Azure DevOps requests at apiVersion, such as 6.0, when it is set. baseURL
+// is the URL of the configured organization or server collection.
+func installAPIVersion(apiVersion, baseURL string) error {
+	if apiVersion == "" {
+		return nil
+	}
+	matches := apiVersionPattern.FindStringSubmatch(apiVersion)
+	if matches == nil || matches[3] != "" {
+		return fmt.Errorf("invalid azure_devops.api_version %q; use a version such as 7.1", apiVersion)
+	}
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+	http.DefaultTransport = &apiVersionTransport{
+		base:  http.DefaultTransport,
+		major: major,
+		minor: minor,
+		home:  hostOf(baseURL),
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package azdo carries the server's requests to Azure DevOps. The SDK creates its own HTTP
+// clients for each resource area with no way to pass a transport, so the layers here are
+// installed as http.DefaultTransport, each wrapping the one before; all of them leave requests to
+// other hosts, such as the embeddings API, alone.
+package azdo
+
+import (
+	"log"
+
+	"github.com/signify/sgfy-mcp/config"
+)
+
+// Status reports on the Azure DevOps requests of the installed transports.
+type Status struct {
+	rateLimit      *rateLimitTransport
+	circuitBreaker *circuitBreakerTransport
+}
+
+// RateLimits returns the state of each organization requests were made for, or nil when no rate
+// limit is configured.
+func (s *Status) RateLimits() []RateLimitState {
+	if s.rateLimit == nil {
+		return nil
+	}
+	return s.rateLimit.states()
+}
+
+// Circuits returns the state of each host whose last request failed.
+func (s *Status) Circuits() []CircuitState {
+	return s.circuitBreaker.states()
+}
+
+// Install routes Azure DevOps requests through the configured proxy and CA, a cassette when
+// testing, the API version cap, the rate limits of each organization, and a circuit breaker per
+// host, in that order from the network out. baseURL is the URL of the configured organization or
+// server collection.
+func Install(config *config.Config, baseURL string) (*Status, error) {
+	// Reach Azure DevOps through the configured proxy, trusting the configured CA
+	if err := installAzureDevOpsTransport(config.AzureDevOps.ProxyURL, config.AzureDevOps.CAFile, config.AzureDevOps.SkipVerify, baseURL); err != nil {
+		log.Printf("Invalid proxy or TLS settings: %v", err)
+		return nil, err
+	}
+
+	// Record or replay Azure DevOps responses when testing
+	if err := installCassette(config.Cassette, baseURL); err != nil {
+		log.Printf("Invalid cassette: %v", err)
+		return nil, err
+	}
+
+	// Keep requests at or below the configured API version
+	if err := installAPIVersion(config.AzureDevOps.APIVersion, baseURL); err != nil {
+		log.Printf("Invalid API version: %v", err)
+		return nil, err
+	}
+
+	// Queue requests over the limits of each organization, and fail fast while Azure DevOps is down
+	rateLimit := installRateLimit(config.RateLimit, baseURL)
+	circuitBreaker := installCircuitBreaker(config.CircuitBreaker, baseURL)
+	return &Status{rateLimit: rateLimit, circuitBreaker: circuitBreaker}, nil
+}
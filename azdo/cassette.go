@@ -0,0 +1,243 @@
+package azdo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/signify/sgfy-mcp/config"
+)
+
+// maxInteractionBytes caps the length of a line of a cassette.
+const maxInteractionBytes = 10 << 20
+
+// mockFixtures is the cassette the server replays with --mock: an organization mock-org with a
+// project MockProject and a repository mock-service.
+//
+//go:embed fixtures/mock.jsonl
+var mockFixtures []byte
+
+// interaction is one recorded request and its response, a line of a cassette.
+type interaction struct {
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Body     string            `json:"body,omitempty"`
+	Status   int               `json:"status"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Response json.RawMessage   `json:"response"`
+}
+
+// recordedHeaders are the response headers a cassette keeps; the rest, such as cookies and
+// session IDs, are dropped. Request headers, which carry the credential, are never recorded.
+var recordedHeaders = []string{"Content-Type", "X-MS-ContinuationToken"}
+
+// cassetteTransport records Azure DevOps requests and responses, or answers them from a cassette
+// without any network access. A replayed request is matched on its method, path, query, and body,
+// and failing that on its method and path alone, which lets hand-written fixtures leave queries
+// out. Repeated requests get the recorded responses in order, the last one again once they run
+// out.
+type cassetteTransport struct {
+	base   http.RoundTripper
+	record bool
+	home   string
+
+	mu        sync.Mutex
+	file      *os.File
+	responses map[string][]interaction
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !azureDevOpsHost(strings.ToLower(req.URL.Hostname()), t.home) {
+		return t.base.RoundTrip(req)
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if t.record {
+		return t.recordResponse(req, body)
+	}
+	return t.replay(req, body), nil
+}
+
+func (t *cassetteTransport) recordResponse(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	response, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(response))
+
+	recorded := interaction{
+		Method:  req.Method,
+		URL:     req.URL.RequestURI(),
+		Body:    string(body),
+		Status:  resp.StatusCode,
+		Headers: map[string]string{},
+	}
+	for _, name := range recordedHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			recorded.Headers[name] = value
+		}
+	}
+	// JSON responses are kept as JSON, so cassettes can be read and edited; anything else, such as
+	// raw file contents, as a JSON string.
+	if json.Valid(response) {
+		recorded.Response = response
+	} else {
+		recorded.Response, _ = json.Marshal(string(response))
+	}
+	line, err := json.Marshal(recorded)
+	if err != nil {
+		log.Printf("Error encoding interaction: %v", err)
+		return resp, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.file.Write(append(line, '\n')); err != nil {
+		log.Printf("Error recording interaction: %v", err)
+	}
+	return resp, nil
+}
+
+func (t *cassetteTransport) replay(req *http.Request, body []byte) *http.Response {
+	t.mu.Lock()
+	key := cassetteKey(req.Method, req.URL.RequestURI(), string(body))
+	recorded, ok := t.next(key)
+	if !ok {
+		recorded, ok = t.next(cassetteKey(req.Method, req.URL.EscapedPath(), ""))
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		message, _ := json.Marshal(map[string]string{
+			"message": fmt.Sprintf("no recorded response for %s %s", req.Method, req.URL.RequestURI()),
+		})
+		recorded = interaction{Status: http.StatusNotFound, Response: message}
+	}
+	header := http.Header{}
+	for name, value := range recorded.Headers {
+		header.Set(name, value)
+	}
+	response := []byte(recorded.Response)
+	var text string
+	if json.Unmarshal(recorded.Response, &text) == nil {
+		response = []byte(text)
+	} else if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(response)),
+		ContentLength: int64(len(response)),
+		Request:       req,
+	}
+}
+
+// next returns the next response recorded for key, keeping the last one for later requests.
+func (t *cassetteTransport) next(key string) (interaction, bool) {
+	responses := t.responses[key]
+	if len(responses) == 0 {
+		return interaction{}, false
+	}
+	if len(responses) > 1 {
+		t.responses[key] = responses[1:]
+	}
+	return responses[0], true
+}
+
+func cassetteKey(method, uri, body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return method + " " + uri + " " + hex.EncodeToString(sum[:8])
+}
+
+// loadCassette reads the interactions of a cassette, indexing each under both of the keys a
+// replayed request is matched on.
+func loadCassette(data []byte) (map[string][]interaction, error) {
+	responses := map[string][]interaction{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), maxInteractionBytes)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var recorded interaction
+		if err := json.Unmarshal(scanner.Bytes(), &recorded); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if recorded.Status == 0 {
+			recorded.Status = http.StatusOK
+		}
+		exact := cassetteKey(recorded.Method, recorded.URL, recorded.Body)
+		responses[exact] = append(responses[exact], recorded)
+		path, _, _ := strings.Cut(recorded.URL, "?")
+		if loose := cassetteKey(recorded.Method, path, ""); loose != exact {
+			responses[loose] = append(responses[loose], recorded)
+		}
+	}
+	return responses, scanner.Err()
+}
+
+// installCassette records Azure DevOps traffic to cassette.Path, or replays it from there, or from
+// the bundled fixtures in mock mode. baseURL is the URL of the configured organization or server
+// collection.
+func installCassette(cassette config.CassetteConfig, baseURL string) error {
+	transport := &cassetteTransport{base: http.DefaultTransport, home: hostOf(baseURL)}
+	switch cassette.Mode {
+	case "":
+		return nil
+	case config.CassetteRecord:
+		file, err := os.OpenFile(cassette.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			log.Printf("Error opening cassette: %v", err)
+			return fmt.Errorf("error opening cassette %s: %w", cassette.Path, err)
+		}
+		transport.record = true
+		transport.file = file
+		log.Printf("Recording Azure DevOps responses to %s", cassette.Path)
+	case config.CassetteReplay, config.CassetteMock:
+		data := mockFixtures
+		if cassette.Mode == config.CassetteReplay {
+			var err error
+			if data, err = os.ReadFile(cassette.Path); err != nil {
+				log.Printf("Error reading cassette: %v", err)
+				return fmt.Errorf("error reading cassette %s: %w", cassette.Path, err)
+			}
+		}
+		responses, err := loadCassette(data)
+		if err != nil {
+			return fmt.Errorf("error reading cassette %s: %w", cassette.Path, err)
+		}
+		transport.responses = responses
+		log.Printf("Replaying Azure DevOps responses instead of calling Azure DevOps")
+	default:
+		return fmt.Errorf("unknown cassette mode %q; use record, replay, or mock", cassette.Mode)
+	}
+	http.DefaultTransport = transport
+	return nil
+}
@@ -0,0 +1,57 @@
+package azdo
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCassetteReplay(t *testing.T) {
+	responses, err := loadCassette([]byte(`{"method":"GET","url":"/org/_apis/projects?$top=1","response":{"count":1}}
+{"method":"GET","url":"/org/_apis/projects","response":{"count":2}}
+{"method":"GET","url":"/org/_apis/projects","response":{"count":3}}
+{"method":"GET","url":"/org/file","response":"plain text"}
+`))
+	if err != nil {
+		t.Fatalf("error loading cassette: %v", err)
+	}
+	transport := &cassetteTransport{home: "dev.azure.com", responses: responses}
+
+	replay := func(url string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "https://dev.azure.com"+url, nil)
+		if err != nil {
+			t.Fatalf("error creating request: %v", err)
+		}
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("error replaying %s: %v", url, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	for _, step := range []struct {
+		url    string
+		status int
+		body   string
+	}{
+		// An exact match wins over the path alone.
+		{"/org/_apis/projects?$top=1", http.StatusOK, `{"count":1}`},
+		// A query with no recording falls back to the path, whose responses come in order, the
+		// last one again once they run out.
+		{"/org/_apis/projects?$top=5", http.StatusOK, `{"count":1}`},
+		{"/org/_apis/projects", http.StatusOK, `{"count":2}`},
+		{"/org/_apis/projects", http.StatusOK, `{"count":3}`},
+		{"/org/_apis/projects", http.StatusOK, `{"count":3}`},
+		// Responses recorded as JSON strings are replayed as they were returned.
+		{"/org/file", http.StatusOK, "plain text"},
+		{"/org/missing", http.StatusNotFound, "no recorded response"},
+	} {
+		status, body := replay(step.url)
+		if status != step.status || !strings.Contains(body, step.body) {
+			t.Errorf("GET %s returned %d %s, want %d %s", step.url, status, body, step.status, step.body)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package azdo
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/signify/sgfy-mcp/config"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenSeconds      = 30
+)
+
+// circuitKey names a circuit: a host, and on Azure DevOps Services the organization, so that
+// failing requests of one organization do not stop those of the others.
+type circuitKey struct {
+	host         string
+	organization string
+}
+
+func (k circuitKey) String() string {
+	if k.organization == "" {
+		return k.host
+	}
+	return k.host + "/" + k.organization
+}
+
+// keyOf returns the circuit of a request to Azure DevOps.
+func keyOf(host, path string) circuitKey {
+	organization := organizationOf(host, path)
+	if organization == host {
+		return circuitKey{host: host}
+	}
+	return circuitKey{host: host, organization: organization}
+}
+
+// circuit is the state of the requests to one host of one organization.
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// circuitBreakerTransport fails Azure DevOps requests at once while their host is down, rather than
+// letting every tool call wait out the outage. Each host has its own circuit per organization, so
+// an outage of code search leaves Git requests alone, and one organization's failures leave the
+// others alone. Transport errors and 500, 502, 503, and 504 responses count
+// as failures; any other response, including throttling, shows the service is up. Requests to
+// other hosts, such as the embeddings API, pass through.
+type circuitBreakerTransport struct {
+	base      http.RoundTripper
+	threshold int
+	open      time.Duration
+	home      string
+
+	mu       sync.Mutex
+	circuits map[circuitKey]*circuit
+}
+
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	if !azureDevOpsHost(host, t.home) {
+		return t.base.RoundTrip(req)
+	}
+
+	key := keyOf(host, req.URL.Path)
+	probe, err := t.allow(key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// The caller gave up; that says nothing about the service.
+		t.release(key, probe)
+	case err != nil:
+		t.record(key, false)
+	default:
+		switch resp.StatusCode {
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			t.record(key, false)
+		default:
+			t.record(key, true)
+		}
+	}
+	return resp, err
+}
+
+// azureDevOpsHost reports whether requests to host go to Azure DevOps: home, the host of the
+// configured organization or server, or one of the hosts of Azure DevOps Services.
+func azureDevOpsHost(host, home string) bool {
+	return host == home || host == "dev.azure.com" ||
+		strings.HasSuffix(host, ".dev.azure.com") || strings.HasSuffix(host, ".visualstudio.com")
+}
+
+// allow returns an error while a circuit is open, and reports whether the request is the one
+// probe let through once it has been open long enough.
+func (t *circuitBreakerTransport) allow(key circuitKey) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.circuits[key]
+	if !ok || c.failures < t.threshold {
+		return false, nil
+	}
+	if wait := time.Until(c.openUntil); wait > 0 || c.probing {
+		if wait < time.Second {
+			wait = time.Second
+		}
+		return false, fmt.Errorf("Azure DevOps service degraded: the last %d requests to %s failed; retrying in %s", c.failures, key, wait.Round(time.Second))
+	}
+	c.probing = true
+	return true, nil
+}
+
+func (t *circuitBreakerTransport) record(key circuitKey, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, found := t.circuits[key]
+	if !found {
+		if ok {
+			return
+		}
+		c = &circuit{}
+		t.circuits[key] = c
+	}
+	c.probing = false
+	if ok {
+		if c.failures >= t.threshold {
+			log.Printf("Azure DevOps requests to %s recovered", key)
+		}
+		c.failures = 0
+		return
+	}
+	c.failures++
+	if c.failures >= t.threshold {
+		if c.failures == t.threshold {
+			log.Printf("Azure DevOps requests to %s are failing; failing fast for %s", key, t.open)
+		}
+		c.openUntil = time.Now().Add(t.open)
+	}
+}
+
+// release lets another request probe when a probe ended without an answer.
+func (t *circuitBreakerTransport) release(key circuitKey, probe bool) {
+	if !probe {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c, ok := t.circuits[key]; ok {
+		c.probing = false
+	}
+}
+
+// CircuitState is the state of the requests to a host of an organization that have been failing.
+type CircuitState struct {
+	Host         string `json:"host"`
+	Organization string `json:"organization,omitempty"`
+	Failures     int    `json:"failures"`
+	// Open is set while the requests fail fast.
+	Open      bool   `json:"open"`
+	OpenUntil string `json:"openUntil,omitempty"`
+}
+
+// states returns the state of every circuit whose last request failed, by host and organization.
+func (t *circuitBreakerTransport) states() []CircuitState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	states := []CircuitState{}
+	for key, c := range t.circuits {
+		if c.failures == 0 {
+			continue
+		}
+		state := CircuitState{Host: key.host, Organization: key.organization, Failures: c.failures}
+		if c.failures >= t.threshold {
+			state.Open = true
+			state.OpenUntil = c.openUntil.UTC().Format(time.RFC3339)
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Host != states[j].Host {
+			return states[i].Host < states[j].Host
+		}
+		return states[i].Organization < states[j].Organization
+	})
+	return states
+}
+
+// installCircuitBreaker routes Azure DevOps requests through circuitBreakerTransport, and returns
+// it. baseURL is the URL of the configured organization or server collection.
+func installCircuitBreaker(config config.CircuitBreakerConfig, baseURL string) *circuitBreakerTransport {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaultFailureThreshold
+	}
+	if config.OpenSeconds <= 0 {
+		config.OpenSeconds = defaultOpenSeconds
+	}
+	transport := &circuitBreakerTransport{
+		base:      http.DefaultTransport,
+		threshold: config.FailureThreshold,
+		open:      time.Duration(config.OpenSeconds) * time.Second,
+		home:      hostOf(baseURL),
+		circuits:  map[circuitKey]*circuit{},
+	}
+	http.DefaultTransport = transport
+	return transport
+}
+
+// hostOf returns the lower-case host name of a URL, or "" when it does not parse.
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
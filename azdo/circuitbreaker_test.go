@@ -0,0 +1,52 @@
+package azdo
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statusTransport answers every request with a status, by the organization in its path.
+type statusTransport map[string]int
+
+func (t statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	organization, _, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	return &http.Response{StatusCode: t[organization], Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func TestCircuitBreakerKeepsOrganizationsApart(t *testing.T) {
+	transport := &circuitBreakerTransport{
+		base:      statusTransport{"failing": http.StatusServiceUnavailable, "healthy": http.StatusOK},
+		threshold: 2,
+		open:      time.Minute,
+		circuits:  map[circuitKey]*circuit{},
+	}
+	get := func(organization string) error {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "https://dev.azure.com/"+organization+"/_apis/projects", nil)
+		if err != nil {
+			t.Fatalf("error creating request: %v", err)
+		}
+		_, err = transport.RoundTrip(req)
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := get("failing"); err != nil {
+			t.Fatalf("request %d failed fast before the circuit opened: %v", i+1, err)
+		}
+	}
+	if err := get("failing"); err == nil || !strings.Contains(err.Error(), "dev.azure.com/failing") {
+		t.Errorf("request with an open circuit returned %v, want a service degraded error", err)
+	}
+	if err := get("healthy"); err != nil {
+		t.Errorf("request of another organization failed: %v", err)
+	}
+
+	states := transport.states()
+	if len(states) != 1 || states[0].Organization != "failing" || !states[0].Open {
+		t.Errorf("states are %+v, want only the failing organization open", states)
+	}
+}
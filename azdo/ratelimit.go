@@ -0,0 +1,178 @@
+package azdo
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/signify/sgfy-mcp/config"
+)
+
+// organizationLimit is the limiter of one organization.
+type organizationLimit struct {
+	// slots holds a token for each request in flight.
+	slots chan struct{}
+
+	mu sync.Mutex
+	// next is when the next request may start; it trails the present by up to a burst.
+	next time.Time
+	// waiting counts the requests queued for their turn or a slot.
+	waiting int
+}
+
+// rateLimitTransport queues Azure DevOps requests over an organization's limits until they may
+// start, or until their tool call is cancelled. A request holds its slot until its response
+// headers arrive, which is when Azure DevOps has done its work. Requests to other hosts pass
+// through.
+type rateLimitTransport struct {
+	base     http.RoundTripper
+	config   config.RateLimitConfig
+	interval time.Duration
+	burst    time.Duration
+	home     string
+
+	mu     sync.Mutex
+	limits map[string]*organizationLimit
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	if !azureDevOpsHost(host, t.home) {
+		return t.base.RoundTrip(req)
+	}
+	limit := t.limit(organizationOf(host, req.URL.Path))
+
+	limit.wait(1)
+	release, err := t.acquire(req.Context(), limit)
+	limit.wait(-1)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return t.base.RoundTrip(req)
+}
+
+// acquire waits until a request may start under limit, and returns the function that frees its
+// slot.
+func (t *rateLimitTransport) acquire(ctx context.Context, limit *organizationLimit) (func(), error) {
+	if t.interval > 0 {
+		limit.mu.Lock()
+		now := time.Now()
+		if earliest := now.Add(-t.burst); limit.next.Before(earliest) {
+			limit.next = earliest
+		}
+		start := limit.next
+		limit.next = limit.next.Add(t.interval)
+		limit.mu.Unlock()
+		if err := sleepContext(ctx, time.Until(start)); err != nil {
+			return nil, err
+		}
+	}
+
+	if limit.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case limit.slots <- struct{}{}:
+		return func() { <-limit.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *organizationLimit) wait(delta int) {
+	l.mu.Lock()
+	l.waiting += delta
+	l.mu.Unlock()
+}
+
+// RateLimitState is the state of the requests of one organization.
+type RateLimitState struct {
+	Organization string `json:"organization"`
+	// InFlight counts the requests holding a slot; it is only tracked with a concurrency limit.
+	InFlight int `json:"inFlight"`
+	Waiting  int `json:"waiting"`
+}
+
+// states returns the state of every organization requests were made for, by name.
+func (t *rateLimitTransport) states() []RateLimitState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	states := make([]RateLimitState, 0, len(t.limits))
+	for organization, limit := range t.limits {
+		limit.mu.Lock()
+		states = append(states, RateLimitState{Organization: organization, InFlight: len(limit.slots), Waiting: limit.waiting})
+		limit.mu.Unlock()
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Organization < states[j].Organization })
+	return states
+}
+
+func (t *rateLimitTransport) limit(organization string) *organizationLimit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	limit, ok := t.limits[organization]
+	if !ok {
+		limit = &organizationLimit{}
+		if t.config.MaxConcurrentRequests > 0 {
+			limit.slots = make(chan struct{}, t.config.MaxConcurrentRequests)
+		}
+		t.limits[organization] = limit
+	}
+	return limit
+}
+
+// organizationOf returns the organization a request to Azure DevOps is for: the first segment of
+// the path on dev.azure.com and its subdomains, the subdomain on visualstudio.com, and the server
+// itself on Azure DevOps Server.
+func organizationOf(host, path string) string {
+	switch {
+	case host == "dev.azure.com" || strings.HasSuffix(host, ".dev.azure.com"):
+		organization, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		return strings.ToLower(organization)
+	case strings.HasSuffix(host, ".visualstudio.com"):
+		organization, _, _ := strings.Cut(host, ".")
+		return organization
+	}
+	return host
+}
+
+// sleepContext waits for d, or returns the context's error when it is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// installRateLimit routes Azure DevOps requests through rateLimitTransport when limits are set,
+// and returns it, or nil without limits.
+// Requests may start in bursts of up to a tenth of a minute's allowance. baseURL is the URL of
+// the configured organization or server collection.
+func installRateLimit(config config.RateLimitConfig, baseURL string) *rateLimitTransport {
+	if config.MaxConcurrentRequests <= 0 && config.RequestsPerMinute <= 0 {
+		return nil
+	}
+	transport := &rateLimitTransport{
+		base:   http.DefaultTransport,
+		config: config,
+		home:   hostOf(baseURL),
+		limits: map[string]*organizationLimit{},
+	}
+	if config.RequestsPerMinute > 0 {
+		transport.interval = time.Minute / time.Duration(config.RequestsPerMinute)
+		transport.burst = time.Duration(config.RequestsPerMinute/10) * transport.interval
+	}
+	http.DefaultTransport = transport
+	return transport
+}
@@ -0,0 +1,81 @@
+package azdo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// azureDevOpsTransport sends requests to Azure DevOps through a transport with the proxy and TLS
+// settings of azure_devops, and other requests, such as those to the embeddings API, through the
+// default one, so trusting a private CA or skipping verification stays limited to Azure DevOps.
+type azureDevOpsTransport struct {
+	azureDevOps http.RoundTripper
+	other       http.RoundTripper
+	home        string
+}
+
+func (t *azureDevOpsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if azureDevOpsHost(strings.ToLower(req.URL.Hostname()), t.home) {
+		return t.azureDevOps.RoundTrip(req)
+	}
+	return t.other.RoundTrip(req)
+}
+
+// installAzureDevOpsTransport routes Azure DevOps requests through proxyURL, or else the proxy of
+// the HTTPS_PROXY, HTTP_PROXY, and NO_PROXY environment variables, trusting the certificates of
+// caFile besides the system's. baseURL is the URL of the configured organization or server
+// collection.
+func installAzureDevOpsTransport(proxyURL, caFile string, insecureSkipVerify bool, baseURL string) error {
+	if proxyURL == "" && caFile == "" && !insecureSkipVerify {
+		// The default transport already honors the proxy environment variables.
+		return nil
+	}
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("the default transport was replaced before proxy and TLS settings were applied")
+	}
+	transport := base.Clone()
+
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil || proxy.Host == "" {
+			return fmt.Errorf("invalid azure_devops.proxy_url %q", proxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			log.Printf("Error reading CA bundle: %v", err)
+			return fmt.Errorf("error reading CA bundle %s: %w", caFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no PEM certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if insecureSkipVerify {
+		log.Print("Warning: TLS certificates of Azure DevOps are not verified")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	http.DefaultTransport = &azureDevOpsTransport{
+		azureDevOps: transport,
+		other:       base,
+		home:        hostOf(baseURL),
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/work"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxBacklogDepth limits how many levels of children are expanded below a backlog level.
+const maxBacklogDepth = 3
+
+const hierarchyForwardRel = "System.LinkTypes.Hierarchy-Forward"
+
+func (c *AzureDevOpsClient) listBoards(ctx context.Context, team string) ([]map[string]interface{}, error) {
+	team = c.teamName(team)
+	boards, err := c.workClient.GetBoards(ctx, work.GetBoardsArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Team:    &team,
+	})
+	if err != nil {
+		log.Printf("Error getting boards: %v", err)
+		return nil, fmt.Errorf("error getting boards for team %s: %w", team, err)
+	}
+
+	results := []map[string]interface{}{}
+	if boards == nil {
+		return results, nil
+	}
+	for _, board := range *boards {
+		if board.Id == nil || board.Name == nil {
+			continue
+		}
+		results = append(results, map[string]interface{}{
+			"id":   board.Id.String(),
+			"name": *board.Name,
+		})
+	}
+
+	return results, nil
+}
+
+// getBoard returns the columns of a team board with the cards currently in each column.
+func (c *AzureDevOpsClient) getBoard(ctx context.Context, team, boardName string) (map[string]interface{}, error) {
+	team = c.teamName(team)
+	board, err := c.workClient.GetBoard(ctx, work.GetBoardArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Id:      &boardName,
+		Team:    &team,
+	})
+	if err != nil {
+		log.Printf("Error getting board: %v", err)
+		return nil, fmt.Errorf("error getting board %s: %w", boardName, err)
+	}
+
+	columnField, rowField := "", ""
+	if board.Fields != nil {
+		if board.Fields.ColumnField != nil {
+			columnField = stringValue(board.Fields.ColumnField.ReferenceName)
+		}
+		if board.Fields.RowField != nil {
+			rowField = stringValue(board.Fields.RowField.ReferenceName)
+		}
+	}
+
+	// Boards are named after the backlog level they display, so the cards are that level's work items.
+	cardsByColumn := map[string][]map[string]interface{}{}
+	backlogs, err := c.workClient.GetBacklogs(ctx, work.GetBacklogsArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Team:    &team,
+	})
+	if err != nil {
+		log.Printf("Error getting backlogs: %v", err)
+		return nil, fmt.Errorf("error getting backlogs for team %s: %w", team, err)
+	}
+	if backlogs == nil {
+		backlogs = &[]work.BacklogLevelConfiguration{}
+	}
+	for _, backlog := range *backlogs {
+		if !strings.EqualFold(stringValue(backlog.Name), stringValue(board.Name)) || backlog.Id == nil {
+			continue
+		}
+		ids, err := c.getBacklogWorkItemIDs(ctx, team, *backlog.Id)
+		if err != nil {
+			return nil, err
+		}
+		fields := []string{"System.WorkItemType", "System.Title", "System.State", "System.AssignedTo"}
+		if columnField != "" {
+			fields = append(fields, columnField)
+		}
+		if rowField != "" {
+			fields = append(fields, rowField)
+		}
+		items, err := c.getWorkItemsBatch(ctx, ids, fields)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if item.Fields == nil {
+				continue
+			}
+			card := c.workItemSummary(item)
+			if rowField != "" {
+				if row, _ := (*item.Fields)[rowField].(string); row != "" {
+					card["row"] = row
+				}
+			}
+			column, _ := (*item.Fields)[columnField].(string)
+			cardsByColumn[column] = append(cardsByColumn[column], card)
+		}
+		break
+	}
+
+	columns := []map[string]interface{}{}
+	if board.Columns != nil {
+		for _, column := range *board.Columns {
+			name := stringValue(column.Name)
+			cards := cardsByColumn[name]
+			if cards == nil {
+				cards = []map[string]interface{}{}
+			}
+			entry := map[string]interface{}{
+				"name":  name,
+				"cards": cards,
+			}
+			if column.ColumnType != nil {
+				entry["columnType"] = string(*column.ColumnType)
+			}
+			if column.ItemLimit != nil && *column.ItemLimit > 0 {
+				entry["itemLimit"] = *column.ItemLimit
+			}
+			if column.IsSplit != nil {
+				entry["isSplit"] = *column.IsSplit
+			}
+			if column.StateMappings != nil {
+				entry["stateMappings"] = *column.StateMappings
+			}
+			columns = append(columns, entry)
+		}
+	}
+
+	rows := []string{}
+	if board.Rows != nil {
+		for _, row := range *board.Rows {
+			if name := stringValue(row.Name); name != "" {
+				rows = append(rows, name)
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"name":    stringValue(board.Name),
+		"team":    team,
+		"columns": columns,
+		"rows":    rows,
+	}, nil
+}
+
+func (c *AzureDevOpsClient) listBacklogs(ctx context.Context, team string) ([]map[string]interface{}, error) {
+	team = c.teamName(team)
+	backlogs, err := c.workClient.GetBacklogs(ctx, work.GetBacklogsArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Team:    &team,
+	})
+	if err != nil {
+		log.Printf("Error getting backlogs: %v", err)
+		return nil, fmt.Errorf("error getting backlogs for team %s: %w", team, err)
+	}
+
+	results := []map[string]interface{}{}
+	if backlogs == nil {
+		return results, nil
+	}
+	for _, backlog := range *backlogs {
+		if backlog.Id == nil {
+			continue
+		}
+		types := []string{}
+		if backlog.WorkItemTypes != nil {
+			for _, t := range *backlog.WorkItemTypes {
+				types = append(types, stringValue(t.Name))
+			}
+		}
+		entry := map[string]interface{}{
+			"id":            *backlog.Id,
+			"name":          stringValue(backlog.Name),
+			"workItemTypes": types,
+			"isHidden":      backlog.IsHidden != nil && *backlog.IsHidden,
+		}
+		if backlog.Rank != nil {
+			entry["rank"] = *backlog.Rank
+		}
+		results = append(results, entry)
+	}
+
+	return results, nil
+}
+
+func (c *AzureDevOpsClient) getBacklogWorkItemIDs(ctx context.Context, team, backlogID string) ([]int, error) {
+	levelItems, err := c.workClient.GetBacklogLevelWorkItems(ctx, work.GetBacklogLevelWorkItemsArgs{
+		Project:   &c.config.AzureDevOps.Project,
+		Team:      &team,
+		BacklogId: &backlogID,
+	})
+	if err != nil {
+		log.Printf("Error getting backlog work items: %v", err)
+		return nil, fmt.Errorf("error getting work items for backlog %s: %w", backlogID, err)
+	}
+
+	ids := []int{}
+	if levelItems.WorkItems != nil {
+		for _, link := range *levelItems.WorkItems {
+			if link.Target != nil && link.Target.Id != nil {
+				ids = append(ids, *link.Target.Id)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// getBacklogHierarchy returns the work items of a backlog level in backlog order, with their
+// children expanded depth levels deep (e.g. Epics → Features → Stories).
+func (c *AzureDevOpsClient) getBacklogHierarchy(ctx context.Context, team, backlogID string, depth int) ([]map[string]interface{}, error) {
+	team = c.teamName(team)
+	if depth > maxBacklogDepth {
+		depth = maxBacklogDepth
+	}
+
+	rootIDs, err := c.getBacklogWorkItemIDs(ctx, team, backlogID)
+	if err != nil {
+		return nil, err
+	}
+
+	itemsByID := map[int]workitemtracking.WorkItem{}
+	pending := rootIDs
+	for level := 0; level <= depth && len(pending) > 0; level++ {
+		items, err := c.getWorkItemsWithRelations(ctx, pending)
+		if err != nil {
+			return nil, err
+		}
+		pending = []int{}
+		for _, item := range items {
+			if item.Id == nil {
+				continue
+			}
+			itemsByID[*item.Id] = item
+			for _, childID := range relatedWorkItemIDs(item, hierarchyForwardRel) {
+				if _, seen := itemsByID[childID]; !seen {
+					pending = append(pending, childID)
+				}
+			}
+		}
+	}
+
+	var build func(id, level int) map[string]interface{}
+	build = func(id, level int) map[string]interface{} {
+		item, ok := itemsByID[id]
+		if !ok {
+			return nil
+		}
+		node := c.workItemSummary(item)
+		if level < depth {
+			children := []map[string]interface{}{}
+			for _, childID := range relatedWorkItemIDs(item, hierarchyForwardRel) {
+				if child := build(childID, level+1); child != nil {
+					children = append(children, child)
+				}
+			}
+			node["children"] = children
+		}
+		return node
+	}
+
+	results := []map[string]interface{}{}
+	for _, id := range rootIDs {
+		if node := build(id, 0); node != nil {
+			results = append(results, node)
+		}
+	}
+
+	return results, nil
+}
+
+func addBoardTools(s tools.Server, client *AzureDevOpsClient) {
+	listBoardsTool := mcp.NewTool("list_boards",
+		mcp.WithDescription("List the boards of a team"),
+		mcp.WithString("team",
+			mcp.Description("Optional team name, defaults to the configured team"),
+		),
+	)
+
+	s.AddTool(listBoardsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		team, _ := request.Params.Arguments["team"].(string)
+
+		results, err := client.listBoards(ctx, team)
+		if err != nil {
+			log.Printf("Error listing boards: %v", err)
+			return nil, fmt.Errorf("error listing boards: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	getBoardTool := mcp.NewTool("get_board",
+		mcp.WithDescription("Get a team board with its columns, swimlanes, and the cards in each column"),
+		mcp.WithString("board",
+			mcp.Required(),
+			mcp.Description("Board name or ID, e.g. Stories or Features"),
+		),
+		mcp.WithString("team",
+			mcp.Description("Optional team name, defaults to the configured team"),
+		),
+	)
+
+	s.AddTool(getBoardTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		board, ok := request.Params.Arguments["board"].(string)
+		if !ok {
+			log.Print("Board must be a string")
+			return nil, fmt.Errorf("board must be a string")
+		}
+		team, _ := request.Params.Arguments["team"].(string)
+
+		result, err := client.getBoard(ctx, team, board)
+		if err != nil {
+			log.Printf("Error getting board: %v", err)
+			return nil, fmt.Errorf("error getting board: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	listBacklogsTool := mcp.NewTool("list_backlogs",
+		mcp.WithDescription("List the backlog levels of a team (e.g. Epics, Features, Stories) with their work item types"),
+		mcp.WithString("team",
+			mcp.Description("Optional team name, defaults to the configured team"),
+		),
+	)
+
+	s.AddTool(listBacklogsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		team, _ := request.Params.Arguments["team"].(string)
+
+		results, err := client.listBacklogs(ctx, team)
+		if err != nil {
+			log.Printf("Error listing backlogs: %v", err)
+			return nil, fmt.Errorf("error listing backlogs: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	getBacklogTool := mcp.NewTool("get_backlog",
+		mcp.WithDescription("Get the work items of a backlog level in priority order, optionally expanding their children (e.g. Epics → Features → Stories)"),
+		mcp.WithString("backlog",
+			mcp.Required(),
+			mcp.Description("Backlog level ID from list_backlogs, e.g. Microsoft.EpicCategory"),
+		),
+		mcp.WithNumber("depth",
+			mcp.Description("Number of child levels to expand (0-3)"),
+			mcp.DefaultNumber(0),
+			mcp.Min(0),
+			mcp.Max(maxBacklogDepth),
+		),
+		mcp.WithString("team",
+			mcp.Description("Optional team name, defaults to the configured team"),
+		),
+	)
+
+	s.AddTool(getBacklogTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		backlog, ok := request.Params.Arguments["backlog"].(string)
+		if !ok {
+			log.Print("Backlog must be a string")
+			return nil, fmt.Errorf("backlog must be a string")
+		}
+		depth, _ := request.Params.Arguments["depth"].(float64)
+		team, _ := request.Params.Arguments["team"].(string)
+
+		results, err := client.getBacklogHierarchy(ctx, team, backlog, int(depth))
+		if err != nil {
+			log.Printf("Error getting backlog: %v", err)
+			return nil, fmt.Errorf("error getting backlog: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+}
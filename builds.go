@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// foreverLeaseDays is how long a retention lease lasts unless told otherwise; Azure DevOps shows
+// leases of 100 years or more as retaining the build forever.
+const foreverLeaseDays = 36500
+
+// retentionLease describes a retention lease of a build.
+func retentionLease(lease build.RetentionLease) map[string]interface{} {
+	entry := map[string]interface{}{
+		"leaseId":         *lease.LeaseId,
+		"ownerId":         stringValue(lease.OwnerId),
+		"protectPipeline": lease.ProtectPipeline != nil && *lease.ProtectPipeline,
+	}
+	if lease.CreatedOn != nil {
+		entry["createdOn"] = lease.CreatedOn.Time.Format(time.RFC3339)
+	}
+	if lease.ValidUntil != nil {
+		entry["validUntil"] = lease.ValidUntil.Time.Format(time.RFC3339)
+	}
+	return entry
+}
+
+// buildRetention reports what keeps a build from being deleted by retention policies: its
+// retention leases, keep forever flag, and release retention, along with its tags.
+func (c *AzureDevOpsClient) buildRetention(ctx context.Context, buildID int) (map[string]interface{}, error) {
+	run, err := c.buildClient.GetBuild(ctx, build.GetBuildArgs{
+		Project: &c.config.AzureDevOps.Project,
+		BuildId: &buildID,
+	})
+	if err != nil {
+		log.Printf("Error getting build: %v", err)
+		return nil, fmt.Errorf("error getting build %d: %w", buildID, err)
+	}
+	leases, err := c.buildClient.GetRetentionLeasesForBuild(ctx, build.GetRetentionLeasesForBuildArgs{
+		Project: &c.config.AzureDevOps.Project,
+		BuildId: &buildID,
+	})
+	if err != nil {
+		log.Printf("Error getting retention leases: %v", err)
+		return nil, fmt.Errorf("error getting retention leases of build %d: %w", buildID, err)
+	}
+
+	tags := []string{}
+	if run.Tags != nil {
+		tags = append(tags, *run.Tags...)
+	}
+	sort.Strings(tags)
+	list := []map[string]interface{}{}
+	for _, lease := range *leases {
+		list = append(list, retentionLease(lease))
+	}
+	result := map[string]interface{}{
+		"id":                buildID,
+		"buildNumber":       stringValue(run.BuildNumber),
+		"tags":              tags,
+		"retentionLeases":   list,
+		"keepForever":       run.KeepForever != nil && *run.KeepForever,
+		"retainedByRelease": run.RetainedByRelease != nil && *run.RetainedByRelease,
+		"webUrl":            c.links.build(c.config.AzureDevOps.Project, buildID),
+	}
+	if run.Definition != nil {
+		result["pipeline"] = stringValue(run.Definition.Name)
+	}
+	return result, nil
+}
+
+// updateBuildTags adds and removes tags of a build and returns its tags.
+func (c *AzureDevOpsClient) updateBuildTags(ctx context.Context, buildID int, add, remove []string, dryRun bool) (map[string]interface{}, error) {
+	if len(add) == 0 && len(remove) == 0 {
+		return nil, fmt.Errorf("pass add and/or remove")
+	}
+	if dryRun {
+		return tools.DryRunResult("update build tags", map[string]interface{}{
+			"buildId": buildID,
+			"add":     add,
+			"remove":  remove,
+		}), nil
+	}
+	tags, err := c.buildClient.UpdateBuildTags(ctx, build.UpdateBuildTagsArgs{
+		UpdateParameters: &build.UpdateTagParameters{
+			TagsToAdd:    &add,
+			TagsToRemove: &remove,
+		},
+		Project: &c.config.AzureDevOps.Project,
+		BuildId: &buildID,
+	})
+	if err != nil {
+		log.Printf("Error updating build tags: %v", err)
+		return nil, fmt.Errorf("error updating tags of build %d: %w", buildID, err)
+	}
+	sort.Strings(*tags)
+	return map[string]interface{}{
+		"buildId": buildID,
+		"tags":    *tags,
+	}, nil
+}
+
+// addRetentionLease retains a build for days. Without an owner the lease is the authenticated
+// user's, like one added with Retain in Azure DevOps.
+func (c *AzureDevOpsClient) addRetentionLease(ctx context.Context, buildID, days int, owner string, protectPipeline, dryRun bool) (map[string]interface{}, error) {
+	run, err := c.buildClient.GetBuild(ctx, build.GetBuildArgs{
+		Project: &c.config.AzureDevOps.Project,
+		BuildId: &buildID,
+	})
+	if err != nil {
+		log.Printf("Error getting build: %v", err)
+		return nil, fmt.Errorf("error getting build %d: %w", buildID, err)
+	}
+	if run.Definition == nil || run.Definition.Id == nil {
+		return nil, fmt.Errorf("build %d has no pipeline", buildID)
+	}
+	if owner == "" {
+		user, err := c.currentUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+		owner = "User:" + user.ID
+	}
+	lease := build.NewRetentionLease{
+		DaysValid:       &days,
+		DefinitionId:    run.Definition.Id,
+		OwnerId:         &owner,
+		ProtectPipeline: &protectPipeline,
+		RunId:           &buildID,
+	}
+	if dryRun {
+		return tools.DryRunResult("add retention lease", map[string]interface{}{"lease": lease}), nil
+	}
+
+	leases, err := c.buildClient.AddRetentionLeases(ctx, build.AddRetentionLeasesArgs{
+		NewLeases: &[]build.NewRetentionLease{lease},
+		Project:   &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error adding retention lease: %v", err)
+		return nil, fmt.Errorf("error adding a retention lease to build %d: %w", buildID, err)
+	}
+	if len(*leases) == 0 {
+		return nil, fmt.Errorf("no retention lease was created for build %d", buildID)
+	}
+	return retentionLease((*leases)[0]), nil
+}
+
+// deleteRetentionLeases deletes retention leases by ID, after which retention policies may delete
+// their builds.
+func (c *AzureDevOpsClient) deleteRetentionLeases(ctx context.Context, ids []int, dryRun bool) (map[string]interface{}, error) {
+	if dryRun {
+		return tools.DryRunResult("delete retention leases", map[string]interface{}{"leaseIds": ids}), nil
+	}
+	err := c.buildClient.DeleteRetentionLeasesById(ctx, build.DeleteRetentionLeasesByIdArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Ids:     &ids,
+	})
+	if err != nil {
+		log.Printf("Error deleting retention leases: %v", err)
+		return nil, fmt.Errorf("error deleting retention leases: %w", err)
+	}
+	return map[string]interface{}{"deleted": ids}, nil
+}
+
+func addBuildTools(s tools.Server, client *AzureDevOpsClient) {
+	retentionTool := mcp.NewTool("get_build_retention",
+		mcp.WithDescription("Get the tags of a build and what keeps it from being deleted by retention policies: its retention leases, keep forever flag, and retention by releases"),
+		mcp.WithNumber("buildId",
+			mcp.Description("Build ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the build"),
+		),
+	)
+
+	s.AddTool(retentionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		buildID, err := client.idArgument(request, "buildId", buildEntity)
+		if err != nil {
+			log.Printf("Invalid build: %v", err)
+			return nil, err
+		}
+
+		result, err := client.buildRetention(ctx, buildID)
+		if err != nil {
+			log.Printf("Error getting build retention: %v", err)
+			return nil, fmt.Errorf("error getting build retention: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	tagsTool := mcp.NewTool("update_build_tags",
+		mcp.WithDescription("Add and remove tags of a build, e.g. to mark a release candidate"),
+		mcp.WithNumber("buildId",
+			mcp.Description("Build ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the build"),
+		),
+		mcp.WithArray("add",
+			mcp.Description("Tags to add"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("remove",
+			mcp.Description("Tags to remove"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(tagsTool, writeHandler(client, tagsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		buildID, err := client.idArgument(request, "buildId", buildEntity)
+		if err != nil {
+			log.Printf("Invalid build: %v", err)
+			return nil, err
+		}
+		add, err := tools.StringSliceArgument(request.Params.Arguments, "add")
+		if err != nil {
+			log.Printf("Invalid add: %v", err)
+			return nil, err
+		}
+		remove, err := tools.StringSliceArgument(request.Params.Arguments, "remove")
+		if err != nil {
+			log.Printf("Invalid remove: %v", err)
+			return nil, err
+		}
+
+		result, err := client.updateBuildTags(ctx, buildID, add, remove, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error updating build tags: %v", err)
+			return nil, fmt.Errorf("error updating build tags: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	leaseTool := mcp.NewTool("add_retention_lease",
+		mcp.WithDescription("Retain a build so retention policies do not delete it, e.g. a build that was released"),
+		mcp.WithNumber("buildId",
+			mcp.Description("Build ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the build"),
+		),
+		mcp.WithNumber("days",
+			mcp.Description("How many days to retain the build; 36500 or more is forever"),
+			mcp.DefaultNumber(foreverLeaseDays),
+		),
+		mcp.WithString("ownerId",
+			mcp.Description("Owner of the lease, e.g. Release:42; defaults to the PAT's user"),
+		),
+		mcp.WithBoolean("protectPipeline",
+			mcp.Description("Also keep the pipeline from being deleted while the lease lasts"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(leaseTool, writeHandler(client, leaseTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		buildID, err := client.idArgument(request, "buildId", buildEntity)
+		if err != nil {
+			log.Printf("Invalid build: %v", err)
+			return nil, err
+		}
+		days := foreverLeaseDays
+		if value, ok := request.Params.Arguments["days"].(float64); ok && value > 0 {
+			days = int(value)
+		}
+		owner, _ := request.Params.Arguments["ownerId"].(string)
+		protectPipeline, _ := request.Params.Arguments["protectPipeline"].(bool)
+
+		result, err := client.addRetentionLease(ctx, buildID, days, owner, protectPipeline, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error adding retention lease: %v", err)
+			return nil, fmt.Errorf("error adding retention lease: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	deleteTool := mcp.NewTool("delete_retention_leases",
+		mcp.WithDescription("Delete retention leases, letting retention policies delete their builds again"),
+		mcp.WithArray("leaseIds",
+			mcp.Required(),
+			mcp.Description("IDs of the leases, from get_build_retention"),
+			mcp.Items(map[string]interface{}{"type": "number"}),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(deleteTool, writeHandler(client, deleteTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ids, err := tools.IntSliceArgument(request.Params.Arguments, "leaseIds")
+		if err != nil {
+			log.Printf("Invalid lease IDs: %v", err)
+			return nil, err
+		}
+		if len(ids) == 0 {
+			log.Print("Lease IDs must not be empty")
+			return nil, fmt.Errorf("leaseIds must not be empty")
+		}
+
+		result, err := client.deleteRetentionLeases(ctx, ids, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error deleting retention leases: %v", err)
+			return nil, fmt.Errorf("error deleting retention leases: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/signify/sgfy-mcp/config"
+)
+
+const (
+	defaultCacheMemoryMB = 64
+	defaultCacheDiskMB   = 512
+)
+
+// cacheBackend stores values that never change under their key. Stores may drop entries at any
+// time, and a failing store behaves as a miss, so callers fall back to Azure DevOps.
+type cacheBackend interface {
+	get(key string) ([]byte, bool)
+	put(key string, value []byte)
+}
+
+// newCacheBackend builds the cache selected by config: a memory cache, in front of a disk or Redis
+// store when one is configured. The backend defaults to disk when a directory is set, for
+// configurations written before backends could be chosen.
+func newCacheBackend(config config.CacheConfig) (cacheBackend, error) {
+	if config.MemoryMB <= 0 {
+		config.MemoryMB = defaultCacheMemoryMB
+	}
+	memory := newMemoryCache(int64(config.MemoryMB) << 20)
+
+	backend := config.Backend
+	if backend == "" && config.Directory != "" {
+		backend = "disk"
+	}
+	switch backend {
+	case "", "memory":
+		return memory, nil
+	case "disk":
+		if config.Directory == "" {
+			return nil, fmt.Errorf("cache.directory is required for the disk cache")
+		}
+		if config.DiskMB <= 0 {
+			config.DiskMB = defaultCacheDiskMB
+		}
+		disk, err := newDiskCache(config.Directory, int64(config.DiskMB)<<20)
+		if err != nil {
+			return nil, err
+		}
+		return &tieredCache{memory: memory, store: disk}, nil
+	case "redis":
+		redis, err := newRedisCache(config.Redis)
+		if err != nil {
+			return nil, err
+		}
+		return &tieredCache{memory: memory, store: redis}, nil
+	}
+	return nil, fmt.Errorf("unknown cache backend %q; use memory, disk, or redis", config.Backend)
+}
+
+// memoryCache keeps entries in memory up to a size, evicting the least recently used first.
+type memoryCache struct {
+	limit int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int64
+}
+
+type cacheEntry struct {
+	key   string
+	value []byte
+}
+
+func newMemoryCache(limit int64) *memoryCache {
+	return &memoryCache{
+		limit:   limit,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (c *memoryCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).value, true
+}
+
+func (c *memoryCache) put(key string, value []byte) {
+	size := int64(len(value))
+	if size > c.limit/4 {
+		// One large file should not flush everything else.
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value})
+	c.size += size
+	for c.size > c.limit {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*cacheEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= int64(len(entry.value))
+	}
+}
+
+// diskCache keeps entries as files of a directory up to a size, evicting the least recently used
+// first.
+type diskCache struct {
+	directory string
+	limit     int64
+
+	mu   sync.Mutex
+	size int64
+}
+
+func newDiskCache(directory string, limit int64) (*diskCache, error) {
+	if err := os.MkdirAll(directory, 0o700); err != nil {
+		log.Printf("Error creating cache directory: %v", err)
+		return nil, fmt.Errorf("error creating cache directory %s: %w", directory, err)
+	}
+	cache := &diskCache{directory: directory, limit: limit}
+	cache.size = cache.prune()
+	return cache, nil
+}
+
+func (c *diskCache) get(key string) ([]byte, bool) {
+	file := c.path(key)
+	value, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	// The modification time orders entries for eviction.
+	now := time.Now()
+	_ = os.Chtimes(file, now, now)
+	return value, true
+}
+
+func (c *diskCache) put(key string, value []byte) {
+	temp, err := os.CreateTemp(c.directory, ".tmp-*")
+	if err != nil {
+		log.Printf("Error writing cache entry: %v", err)
+		return
+	}
+	_, err = temp.Write(value)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), c.path(key))
+	}
+	if err != nil {
+		log.Printf("Error writing cache entry: %v", err)
+		os.Remove(temp.Name())
+		return
+	}
+
+	c.mu.Lock()
+	c.size += int64(len(value))
+	prune := c.size > c.limit
+	c.mu.Unlock()
+	if prune {
+		size := c.prune()
+		c.mu.Lock()
+		c.size = size
+		c.mu.Unlock()
+	}
+}
+
+// path returns the file of a key; keys hold paths, so they are hashed into file names.
+func (c *diskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.directory, hex.EncodeToString(sum[:]))
+}
+
+// prune removes the least recently used files until the directory is under 90% of its limit,
+// leaving room to grow before the next prune, and returns the size left.
+func (c *diskCache) prune() int64 {
+	entries, err := os.ReadDir(c.directory)
+	if err != nil {
+		log.Printf("Error reading cache directory: %v", err)
+		return 0
+	}
+	files := []os.FileInfo{}
+	var size int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, info)
+		size += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	target := c.limit / 10 * 9
+	for _, info := range files {
+		if size <= target {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.directory, info.Name())); err == nil {
+			size -= info.Size()
+		}
+	}
+	return size
+}
+
+// tieredCache answers from memory first and from a slower shared or persistent store second,
+// copying the store's hits into memory.
+type tieredCache struct {
+	memory *memoryCache
+	store  cacheBackend
+}
+
+func (c *tieredCache) get(key string) ([]byte, bool) {
+	if value, ok := c.memory.get(key); ok {
+		return value, true
+	}
+	value, ok := c.store.get(key)
+	if ok {
+		c.memory.put(key, value)
+	}
+	return value, ok
+}
+
+func (c *tieredCache) put(key string, value []byte) {
+	c.memory.put(key, value)
+	c.store.put(key, value)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// clientRequestTimeout bounds how long a request to the client waits for its response. Clients
+	// may ask the user to approve a request first, so it is generous.
+	clientRequestTimeout  = 2 * time.Minute
+	maxClientMessageBytes = 10 << 20
+)
+
+// clientCapabilities is what a client declared it supports in its initialize request. mcp-go's
+// ClientCapabilities predates elicitation, so the capabilities used here are decoded directly.
+type clientCapabilities struct {
+	Sampling    *struct{} `json:"sampling"`
+	Elicitation *struct{} `json:"elicitation"`
+	Roots       *struct{} `json:"roots"`
+}
+
+// clientRequestsKey is the context key of the clientRequests of the session making a tool call.
+type clientRequestsKey struct{}
+
+// clientResponse is the result or error a client returned for a request.
+type clientResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// clientRequests lets tools send requests such as sampling/createMessage to the client that
+// called them. mcp-go only sends notifications to clients, so requests are written to the
+// session's SSE stream directly, and the client's responses, which it POSTs to the message
+// endpoint like any other message, are picked out here before the rest reach the SSE server.
+// The capabilities each client declared in its initialize request are recorded the same way, and
+// since mcp-go ignores notifications/cancelled, requests from the client run with a context that
+// is cancelled when the client cancels them, which stops their Azure DevOps requests.
+type clientRequests struct {
+	sse         *server.SSEServer
+	sessionAuth bool
+
+	mu           sync.Mutex
+	lastID       int64
+	pending      map[string]chan clientResponse
+	capabilities map[string]clientCapabilities
+	running      map[string]context.CancelFunc
+	roots        map[string][]mcp.Root
+	credentials  map[string]clientCredential
+}
+
+func newClientRequests(sessionAuth bool) *clientRequests {
+	return &clientRequests{
+		sessionAuth:  sessionAuth,
+		credentials:  map[string]clientCredential{},
+		pending:      map[string]chan clientResponse{},
+		capabilities: map[string]clientCapabilities{},
+		running:      map[string]context.CancelFunc{},
+		roots:        map[string][]mcp.Root{},
+	}
+}
+
+// withContext makes the clientRequests available to tool handlers and, with session auth, makes
+// their calls use the client's credential: from the message's headers, or else from its
+// initialize request. It is the SSE server's context function.
+func (r *clientRequests) withContext(ctx context.Context, req *http.Request) context.Context {
+	if r.sessionAuth {
+		credential := headerCredential(req.Header)
+		if credential.authorization() == "" {
+			r.mu.Lock()
+			credential = r.credentials[req.URL.Query().Get("sessionId")]
+			r.mu.Unlock()
+		}
+		ctx = withCredential(ctx, credential)
+	}
+	return context.WithValue(ctx, clientRequestsKey{}, r)
+}
+
+// clientRequestsFromContext returns the clientRequests of a tool call, or nil outside the SSE
+// server.
+func clientRequestsFromContext(ctx context.Context) *clientRequests {
+	r, _ := ctx.Value(clientRequestsKey{}).(*clientRequests)
+	return r
+}
+
+func (r *clientRequests) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost || req.URL.Path != r.sse.CompleteMessagePath() {
+		r.sse.ServeHTTP(w, req)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxClientMessageBytes))
+	if err != nil {
+		http.Error(w, "error reading message", http.StatusBadRequest)
+		return
+	}
+	var message struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			Capabilities clientCapabilities `json:"capabilities"`
+			RequestID    json.RawMessage    `json:"requestId"`
+			Meta         clientCredential   `json:"_meta"`
+		} `json:"params"`
+	}
+	// Anything that does not parse is left for the SSE server to reject.
+	if json.Unmarshal(body, &message) == nil {
+		sessionID := req.URL.Query().Get("sessionId")
+		switch {
+		case message.Method == "" && len(message.ID) > 0:
+			var response clientResponse
+			if err := json.Unmarshal(body, &response); err != nil {
+				http.Error(w, "invalid response", http.StatusBadRequest)
+				return
+			}
+			r.deliver(sessionID, string(message.ID), response)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		case message.Method == "notifications/roots/list_changed":
+			r.mu.Lock()
+			delete(r.roots, sessionID)
+			r.mu.Unlock()
+		case message.Method == "notifications/cancelled":
+			r.mu.Lock()
+			cancel, ok := r.running[sessionID+"/"+string(message.Params.RequestID)]
+			r.mu.Unlock()
+			if ok {
+				cancel()
+			}
+		case len(message.ID) > 0:
+			if message.Method == string(mcp.MethodInitialize) && sessionID != "" {
+				r.mu.Lock()
+				r.capabilities[sessionID] = message.Params.Capabilities
+				if r.sessionAuth && message.Params.Meta.authorization() != "" {
+					r.credentials[sessionID] = message.Params.Meta
+				}
+				r.mu.Unlock()
+			}
+			// The SSE server handles a request before answering the POST, so the request is
+			// cancellable for as long as it runs.
+			key := sessionID + "/" + string(message.ID)
+			ctx, cancel := context.WithCancel(req.Context())
+			r.mu.Lock()
+			r.running[key] = cancel
+			r.mu.Unlock()
+			defer func() {
+				r.mu.Lock()
+				delete(r.running, key)
+				r.mu.Unlock()
+				cancel()
+			}()
+			req = req.WithContext(ctx)
+		}
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	r.sse.ServeHTTP(w, req)
+}
+
+// deliver hands a response to the request waiting for it. Responses nobody waits for, e.g. after
+// a timeout, are dropped.
+func (r *clientRequests) deliver(sessionID, id string, response clientResponse) {
+	r.mu.Lock()
+	waiting, ok := r.pending[sessionID+"/"+id]
+	delete(r.pending, sessionID+"/"+id)
+	r.mu.Unlock()
+	if ok {
+		waiting <- response
+	}
+}
+
+// resume carries what the client of a session declared over to the session of its new stream.
+func (r *clientRequests) resume(from, to string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if capabilities, ok := r.capabilities[from]; ok {
+		r.capabilities[to] = capabilities
+	}
+	if credential, ok := r.credentials[from]; ok {
+		r.credentials[to] = credential
+	}
+	if roots, ok := r.roots[from]; ok {
+		r.roots[to] = roots
+	}
+}
+
+// credential returns the credential the client of a session passed in its initialize request.
+func (r *clientRequests) credential(sessionID string) clientCredential {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.credentials[sessionID]
+}
+
+// clientCapabilities returns what the client of a session declared it supports. Entries are a few
+// bytes and are kept for the life of the server.
+func (r *clientRequests) clientCapabilities(sessionID string) clientCapabilities {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.capabilities[sessionID]
+}
+
+// listRoots returns the roots the client of a session declared, asking it on first use and again
+// after it announced a change. Clients without the roots capability have none.
+func (r *clientRequests) listRoots(ctx context.Context, sessionID string) ([]mcp.Root, error) {
+	if r.clientCapabilities(sessionID).Roots == nil {
+		return nil, nil
+	}
+	r.mu.Lock()
+	roots, ok := r.roots[sessionID]
+	r.mu.Unlock()
+	if ok {
+		return roots, nil
+	}
+
+	var result mcp.ListRootsResult
+	if err := r.call(ctx, sessionID, "roots/list", map[string]interface{}{}, &result); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.roots[sessionID] = result.Roots
+	r.mu.Unlock()
+	return result.Roots, nil
+}
+
+// call sends a request to the client of a session and decodes its result into result.
+func (r *clientRequests) call(ctx context.Context, sessionID string, method string, params interface{}, result interface{}) error {
+	r.mu.Lock()
+	r.lastID++
+	id := strconv.FormatInt(r.lastID, 10)
+	key := sessionID + "/" + id
+	waiting := make(chan clientResponse, 1)
+	r.pending[key] = waiting
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, key)
+		r.mu.Unlock()
+	}()
+
+	request := map[string]interface{}{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      json.Number(id),
+		"method":  method,
+		"params":  params,
+	}
+	if err := r.sse.SendEventToSession(sessionID, request); err != nil {
+		return fmt.Errorf("error sending %s to the client: %w", method, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, clientRequestTimeout)
+	defer cancel()
+	select {
+	case response := <-waiting:
+		if response.Error != nil {
+			return fmt.Errorf("client declined %s: %s (code %d)", method, response.Error.Message, response.Error.Code)
+		}
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("invalid %s result from the client: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("no response to %s from the client: %w", method, ctx.Err())
+	}
+}
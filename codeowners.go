@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// codeOwnersLocations are the paths a CODEOWNERS file is looked up at, in order.
+var codeOwnersLocations = []string{"/CODEOWNERS", "/.azuredevops/CODEOWNERS", "/.github/CODEOWNERS", "/docs/CODEOWNERS"}
+
+// codeOwnersRule is one line of a CODEOWNERS file.
+type codeOwnersRule struct {
+	pattern string
+	line    int
+	owners  []string
+	re      *regexp.Regexp
+}
+
+// codeOwnersPattern compiles a CODEOWNERS path pattern, which follows .gitignore rules: a pattern
+// containing a slash is anchored at the repository root, * and ? stay within a folder, ** spans
+// folders, and a pattern matching a folder covers everything below it.
+func codeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	folderOnly := strings.HasSuffix(pattern, "/")
+	trimmed := strings.Trim(pattern, "/")
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(trimmed, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(trimmed); i++ {
+		switch {
+		case strings.HasPrefix(trimmed[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case trimmed[i] == '*':
+			expr.WriteString("[^/]*")
+		case trimmed[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(trimmed[i : i+1]))
+		}
+	}
+	if folderOnly {
+		expr.WriteString("/.*$")
+	} else {
+		expr.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(expr.String())
+}
+
+// parseCodeOwners reads the rules of a CODEOWNERS file. Lines that cannot be parsed are returned
+// as problems rather than failing the whole file.
+func parseCodeOwners(content string) ([]codeOwnersRule, []string) {
+	rules := []codeOwnersRule{}
+	problems := []string{}
+	for i, line := range splitLines(content) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// GitLab-style [Section] headers group rules but do not change them.
+		if strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		if code, _, found := strings.Cut(line, " #"); found {
+			line = code
+		}
+		fields := strings.Fields(line)
+		re, err := codeOwnersPattern(fields[0])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", i+1, err))
+			continue
+		}
+		rules = append(rules, codeOwnersRule{
+			pattern: fields[0],
+			line:    i + 1,
+			owners:  fields[1:],
+			re:      re,
+		})
+	}
+	return rules, problems
+}
+
+// ownerOf returns the rule that owns a path: the last matching rule, as in GitHub and GitLab.
+// A matching rule without owners leaves the path unowned.
+func ownerOf(rules []codeOwnersRule, filePath string) *codeOwnersRule {
+	filePath = strings.TrimPrefix(filePath, "/")
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].re.MatchString(filePath) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// loadCodeOwners reads the CODEOWNERS file of a repository at a ref from the first location that
+// has one. It returns an empty path when the repository has none.
+func (c *AzureDevOpsClient) loadCodeOwners(ctx context.Context, repoID, ref string) (string, []codeOwnersRule, []string) {
+	for _, location := range codeOwnersLocations {
+		content, err := c.contentAt(ctx, repoID, location, ref)
+		if err != nil {
+			continue
+		}
+		rules, problems := parseCodeOwners(content)
+		return location, rules, problems
+	}
+	return "", nil, nil
+}
+
+// codeOwnerName strips the @ of a user or team owner; email owners are returned as they are.
+func codeOwnerName(owner string) string {
+	return strings.TrimPrefix(owner, "@")
+}
+
+// codeOwners maps changed paths to their owners according to the repository's CODEOWNERS file,
+// and lists each owner with the paths they own, resolved to an identity ID where possible so they
+// can be added as reviewers.
+func (c *AzureDevOpsClient) codeOwners(ctx context.Context, repoID, ref string, paths []string) (map[string]interface{}, error) {
+	location, rules, problems := c.loadCodeOwners(ctx, repoID, ref)
+	if location == "" {
+		return nil, fmt.Errorf("no CODEOWNERS file at %s on %s", strings.Join(codeOwnersLocations, ", "), ref)
+	}
+
+	files := []map[string]interface{}{}
+	unowned := []string{}
+	ownedPaths := map[string][]string{}
+	for _, filePath := range paths {
+		rule := ownerOf(rules, filePath)
+		if rule == nil || len(rule.owners) == 0 {
+			unowned = append(unowned, filePath)
+			continue
+		}
+		files = append(files, map[string]interface{}{
+			"path":    filePath,
+			"owners":  rule.owners,
+			"pattern": rule.pattern,
+			"line":    rule.line,
+		})
+		for _, owner := range rule.owners {
+			ownedPaths[owner] = append(ownedPaths[owner], filePath)
+		}
+	}
+
+	names := []string{}
+	for owner := range ownedPaths {
+		names = append(names, owner)
+	}
+	sort.Strings(names)
+	errors := map[string]string{}
+	owners := []map[string]interface{}{}
+	for _, owner := range names {
+		entry := map[string]interface{}{
+			"owner": owner,
+			"paths": ownedPaths[owner],
+		}
+		identities, err := c.resolveIdentities(ctx, codeOwnerName(owner))
+		if err != nil {
+			errors[owner] = err.Error()
+		} else if len(identities) == 1 {
+			entry["id"] = identities[0].ID
+			entry["displayName"] = identities[0].DisplayName
+			entry["isGroup"] = identities[0].IsGroup
+		}
+		owners = append(owners, entry)
+	}
+
+	result := map[string]interface{}{
+		"codeOwnersFile": location,
+		"ref":            ref,
+		"files":          files,
+		"owners":         owners,
+		"unowned":        unowned,
+	}
+	if len(problems) > 0 {
+		result["problems"] = problems
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// pullRequestCodeOwners maps the files changed by a pull request to their owners according to the
+// CODEOWNERS file of its target branch.
+func (c *AzureDevOpsClient) pullRequestCodeOwners(ctx context.Context, prID int) (map[string]interface{}, error) {
+	pr, err := c.getPullRequest(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Repository == nil || pr.Repository.Id == nil {
+		return nil, fmt.Errorf("pull request %d has no repository", prID)
+	}
+	repoID := pr.Repository.Id.String()
+
+	_, changes, err := c.pullRequestChanges(ctx, repoID, prID)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, change := range pullRequestFiles(changes) {
+		paths = append(paths, change.Path)
+	}
+
+	result, err := c.codeOwners(ctx, repoID, strings.TrimPrefix(stringValue(pr.TargetRefName), "refs/heads/"), paths)
+	if err != nil {
+		return nil, err
+	}
+	result["pullRequestId"] = prID
+	return result, nil
+}
+
+func addCodeOwnersTools(s tools.Server, client *AzureDevOpsClient) {
+	codeOwnersTool := mcp.NewTool("get_code_owners",
+		mcp.WithDescription("Map changed paths to their owning users and teams using the repository's CODEOWNERS file (at /, /.azuredevops, /.github, or /docs). Pass a pull request to use its changed files and target branch. Owners are resolved to identity IDs for add_pr_reviewers where possible"),
+		mcp.WithNumber("pullRequestId",
+			mcp.Description("Pull request whose changed files are mapped"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the pull request"),
+		),
+		mcp.WithString("repository",
+			mcp.Description("Repository name, when passing paths instead of a pull request"),
+		),
+		mcp.WithArray("paths",
+			mcp.Description("File paths to map, when no pull request is given"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("branch",
+			mcp.Description("Branch to read CODEOWNERS from; defaults to the repository's default branch"),
+		),
+	)
+
+	s.AddTool(codeOwnersTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pr, _, err := client.uriArgument(request, pullRequestEntity)
+		if err != nil {
+			log.Printf("Invalid pull request: %v", err)
+			return nil, err
+		}
+		if id, ok := request.Params.Arguments["pullRequestId"].(float64); ok && id > 0 {
+			pr.ID = int(id)
+		}
+		if pr.ID > 0 {
+			result, err := client.pullRequestCodeOwners(ctx, pr.ID)
+			if err != nil {
+				log.Printf("Error getting code owners: %v", err)
+				return nil, fmt.Errorf("error getting code owners: %w", err)
+			}
+			return tools.JSONResult(result)
+		}
+
+		repo, _ := request.Params.Arguments["repository"].(string)
+		paths, err := tools.StringSliceArgument(request.Params.Arguments, "paths")
+		if err != nil {
+			log.Printf("Invalid paths: %v", err)
+			return nil, err
+		}
+		if repo == "" || len(paths) == 0 {
+			log.Print("No paths to map")
+			return nil, fmt.Errorf("pass a pullRequestId, or a repository and paths")
+		}
+		branch, _ := request.Params.Arguments["branch"].(string)
+
+		repoID, branch, err := client.historyRepository(ctx, repo, branch)
+		if err != nil {
+			log.Printf("Error getting code owners: %v", err)
+			return nil, fmt.Errorf("error getting code owners: %w", err)
+		}
+		result, err := client.codeOwners(ctx, repoID, branch, paths)
+		if err != nil {
+			log.Printf("Error getting code owners: %v", err)
+			return nil, fmt.Errorf("error getting code owners: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,653 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// commitSHA matches a full commit ID.
+var commitSHA = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// resolveCommit returns the commit ID for a full commit SHA, the commit a tag (refs/tags/...)
+// points to, or the head commit of a branch.
+func (c *AzureDevOpsClient) resolveCommit(ctx context.Context, repoID, ref string) (string, error) {
+	return c.resolveCommitIn(ctx, c.config.AzureDevOps.Project, repoID, ref)
+}
+
+// resolveCommitIn resolves a ref of a repository of another project to a commit ID.
+func (c *AzureDevOpsClient) resolveCommitIn(ctx context.Context, project, repoID, ref string) (string, error) {
+	if commitSHA.MatchString(ref) {
+		return strings.ToLower(ref), nil
+	}
+	if strings.HasPrefix(ref, "refs/tags/") {
+		return c.resolveTagIn(ctx, project, repoID, ref)
+	}
+
+	name := strings.TrimPrefix(branchRef(ref), "refs/heads/")
+	branch, err := c.gitClient.GetBranch(ctx, git.GetBranchArgs{
+		RepositoryId: &repoID,
+		Name:         &name,
+		Project:      &project,
+	})
+	if err != nil {
+		log.Printf("Error getting branch: %v", err)
+		return "", fmt.Errorf("error resolving %s to a commit: %w", ref, err)
+	}
+	if branch.Commit == nil || branch.Commit.CommitId == nil {
+		return "", fmt.Errorf("branch %s has no commit", ref)
+	}
+	return *branch.Commit.CommitId, nil
+}
+
+func commitStatusSummary(status git.GitStatus) map[string]interface{} {
+	entry := map[string]interface{}{
+		"description": stringValue(status.Description),
+		"targetUrl":   stringValue(status.TargetUrl),
+	}
+	if status.State != nil {
+		entry["state"] = string(*status.State)
+	}
+	if status.Context != nil {
+		entry["context"] = stringValue(status.Context.Name)
+		if status.Context.Genre != nil {
+			entry["context"] = *status.Context.Genre + "/" + stringValue(status.Context.Name)
+		}
+	}
+	if status.CreatedBy != nil {
+		entry["createdBy"] = stringValue(status.CreatedBy.DisplayName)
+	}
+	if status.CreationDate != nil {
+		entry["creationDate"] = status.CreationDate.Time.Format(time.RFC3339)
+	}
+	return entry
+}
+
+// getCommitStatuses returns the statuses posted to a commit by CI and other systems. With
+// latestOnly, only the newest status per context is returned.
+func (c *AzureDevOpsClient) getCommitStatuses(ctx context.Context, repoName, ref string, latestOnly bool) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+
+	commitID, err := c.resolveCommit(ctx, repoID, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := c.gitClient.GetStatuses(ctx, git.GetStatusesArgs{
+		CommitId:     &commitID,
+		RepositoryId: &repoID,
+		Project:      &c.config.AzureDevOps.Project,
+		LatestOnly:   &latestOnly,
+	})
+	if err != nil {
+		log.Printf("Error getting commit statuses: %v", err)
+		return nil, fmt.Errorf("error getting statuses of commit %s: %w", commitID, err)
+	}
+
+	results := []map[string]interface{}{}
+	if statuses != nil {
+		for _, status := range *statuses {
+			results = append(results, commitStatusSummary(status))
+		}
+	}
+
+	return map[string]interface{}{
+		"commitId": commitID,
+		"webUrl":   c.links.commit(c.config.AzureDevOps.Project, stringValue(repo.Name), commitID),
+		"uri":      c.commitURI(c.config.AzureDevOps.Project, stringValue(repo.Name), commitID),
+		"statuses": results,
+	}, nil
+}
+
+// createCommitStatus posts a status to a commit on behalf of an external system.
+func (c *AzureDevOpsClient) createCommitStatus(ctx context.Context, repoName, ref, state, genre, name, description, targetURL string, dryRun bool) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+
+	commitID, err := c.resolveCommit(ctx, repoID, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	statusState := git.GitStatusState(state)
+	status := &git.GitStatus{
+		State:   &statusState,
+		Context: &git.GitStatusContext{Name: &name},
+	}
+	if genre != "" {
+		status.Context.Genre = &genre
+	}
+	if description != "" {
+		status.Description = &description
+	}
+	if targetURL != "" {
+		status.TargetUrl = &targetURL
+	}
+	if dryRun {
+		return tools.DryRunResult("create commit status", map[string]interface{}{
+			"repositoryId": repoID,
+			"commitId":     commitID,
+			"status":       status,
+		}), nil
+	}
+
+	created, err := c.gitClient.CreateCommitStatus(ctx, git.CreateCommitStatusArgs{
+		GitCommitStatusToCreate: status,
+		CommitId:                &commitID,
+		RepositoryId:            &repoID,
+		Project:                 &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error creating commit status: %v", err)
+		return nil, fmt.Errorf("error creating status on commit %s: %w", commitID, err)
+	}
+
+	result := commitStatusSummary(*created)
+	result["commitId"] = commitID
+	return result, nil
+}
+
+// resolveTag returns the commit a tag points to, peeling annotated tags.
+func (c *AzureDevOpsClient) resolveTag(ctx context.Context, repoID, ref string) (string, error) {
+	return c.resolveTagIn(ctx, c.config.AzureDevOps.Project, repoID, ref)
+}
+
+// resolveTagIn resolves a tag of a repository of another project to a commit ID.
+func (c *AzureDevOpsClient) resolveTagIn(ctx context.Context, project, repoID, ref string) (string, error) {
+	filter := strings.TrimPrefix(ref, "refs/")
+	peelTags := true
+	refs, err := c.gitClient.GetRefs(ctx, git.GetRefsArgs{
+		RepositoryId: &repoID,
+		Project:      &project,
+		Filter:       &filter,
+		PeelTags:     &peelTags,
+	})
+	if err != nil {
+		log.Printf("Error getting refs: %v", err)
+		return "", fmt.Errorf("error resolving %s to a commit: %w", ref, err)
+	}
+	// The filter is a prefix match, so refs/tags/v1 also returns refs/tags/v1.1.
+	for _, candidate := range refs.Value {
+		if stringValue(candidate.Name) != ref {
+			continue
+		}
+		if candidate.PeeledObjectId != nil {
+			return *candidate.PeeledObjectId, nil
+		}
+		return stringValue(candidate.ObjectId), nil
+	}
+	return "", fmt.Errorf("tag %s not found", ref)
+}
+
+// maxCommitsBetween caps the commits read when comparing two refs.
+const maxCommitsBetween = 1000
+
+// commitsBetween returns the commits reachable from targetRef but not from baseRef, newest first,
+// with the work items linked to each.
+func (c *AzureDevOpsClient) commitsBetween(ctx context.Context, repoID, baseRef, targetRef string) ([]git.GitCommitRef, error) {
+	includeWorkItems := true
+	top := pullRequestPageSize
+	skip := 0
+	commits := []git.GitCommitRef{}
+	for len(commits) < maxCommitsBetween {
+		page, err := c.gitClient.GetCommits(ctx, git.GetCommitsArgs{
+			RepositoryId: &repoID,
+			Project:      &c.config.AzureDevOps.Project,
+			SearchCriteria: &git.GitQueryCommitsCriteria{
+				ItemVersion:      versionDescriptor(targetRef),
+				CompareVersion:   versionDescriptor(baseRef),
+				IncludeWorkItems: &includeWorkItems,
+				Top:              &top,
+				Skip:             &skip,
+			},
+		})
+		if err != nil {
+			log.Printf("Error getting commits: %v", err)
+			return nil, fmt.Errorf("error getting commits between %s and %s: %w", baseRef, targetRef, err)
+		}
+		if page == nil || len(*page) == 0 {
+			break
+		}
+		commits = append(commits, *page...)
+		if len(*page) < top {
+			break
+		}
+		skip += top
+	}
+	return commits, nil
+}
+
+// changesBetween returns the files changed on targetCommit since its common ancestor with
+// baseCommit, and that common ancestor.
+func (c *AzureDevOpsClient) changesBetween(ctx context.Context, repoID, baseCommit, targetCommit string) ([]fileChange, string, error) {
+	commitType := git.GitVersionTypeValues.Commit
+	diffCommonCommit := true
+	top := pullRequestPageSize
+	skip := 0
+	changes := []fileChange{}
+	commonCommit := ""
+	for len(changes) < maxPullRequestChange {
+		diffs, err := c.gitClient.GetCommitDiffs(ctx, git.GetCommitDiffsArgs{
+			RepositoryId:            &repoID,
+			Project:                 &c.config.AzureDevOps.Project,
+			DiffCommonCommit:        &diffCommonCommit,
+			Top:                     &top,
+			Skip:                    &skip,
+			BaseVersionDescriptor:   &git.GitBaseVersionDescriptor{Version: &baseCommit, VersionType: &commitType},
+			TargetVersionDescriptor: &git.GitTargetVersionDescriptor{Version: &targetCommit, VersionType: &commitType},
+		})
+		if err != nil {
+			log.Printf("Error getting commit diffs: %v", err)
+			return nil, "", fmt.Errorf("error getting changes between %s and %s: %w", baseCommit, targetCommit, err)
+		}
+		if diffs.CommonCommit != nil {
+			commonCommit = *diffs.CommonCommit
+		}
+		if diffs.Changes == nil || len(*diffs.Changes) == 0 {
+			break
+		}
+		changes = append(changes, changedFiles(*diffs.Changes)...)
+		if diffs.AllChangesIncluded != nil && *diffs.AllChangesIncluded {
+			break
+		}
+		skip += top
+	}
+	return changes, commonCommit, nil
+}
+
+// getMergeBase returns the best common ancestor of two refs, with how many commits each ref has
+// beyond it.
+func (c *AzureDevOpsClient) getMergeBase(ctx context.Context, repoName, baseRef, targetRef string) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+
+	baseCommit, err := c.resolveCommit(ctx, repoID, baseRef)
+	if err != nil {
+		return nil, err
+	}
+	targetCommit, err := c.resolveCommit(ctx, repoID, targetRef)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeBases, err := c.gitClient.GetMergeBases(ctx, git.GetMergeBasesArgs{
+		RepositoryNameOrId: &repoID,
+		CommitId:           &baseCommit,
+		OtherCommitId:      &targetCommit,
+		Project:            &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting merge bases: %v", err)
+		return nil, fmt.Errorf("error getting merge base of %s and %s: %w", baseRef, targetRef, err)
+	}
+
+	result := map[string]interface{}{
+		"baseCommit":   baseCommit,
+		"targetCommit": targetCommit,
+	}
+	if mergeBases == nil || len(*mergeBases) == 0 {
+		result["mergeBase"] = nil
+		return result, nil
+	}
+
+	mergeBaseID := stringValue((*mergeBases)[0].CommitId)
+	mergeBase := map[string]interface{}{
+		"commitId": mergeBaseID,
+	}
+	commit, err := c.gitClient.GetCommit(ctx, git.GetCommitArgs{
+		CommitId:     &mergeBaseID,
+		RepositoryId: &repoID,
+		Project:      &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		// The commit details are informational; the merge base itself is already known.
+		log.Printf("Error getting merge base commit: %v", err)
+	} else {
+		mergeBase["comment"] = stringValue(commit.Comment)
+		if commit.Author != nil {
+			mergeBase["author"] = stringValue(commit.Author.Name)
+			if commit.Author.Date != nil {
+				mergeBase["date"] = commit.Author.Date.Time.Format(time.RFC3339)
+			}
+		}
+	}
+	result["mergeBase"] = mergeBase
+
+	if len(*mergeBases) > 1 {
+		others := []string{}
+		for _, other := range (*mergeBases)[1:] {
+			others = append(others, stringValue(other.CommitId))
+		}
+		result["otherMergeBases"] = others
+	}
+
+	commitType := git.GitVersionTypeValues.Commit
+	top := 1
+	diffs, err := c.gitClient.GetCommitDiffs(ctx, git.GetCommitDiffsArgs{
+		RepositoryId:            &repoID,
+		Project:                 &c.config.AzureDevOps.Project,
+		Top:                     &top,
+		BaseVersionDescriptor:   &git.GitBaseVersionDescriptor{Version: &baseCommit, VersionType: &commitType},
+		TargetVersionDescriptor: &git.GitTargetVersionDescriptor{Version: &targetCommit, VersionType: &commitType},
+	})
+	if err != nil {
+		log.Printf("Error getting commit divergence: %v", err)
+	} else {
+		if diffs.AheadCount != nil {
+			result["targetAhead"] = *diffs.AheadCount
+		}
+		if diffs.BehindCount != nil {
+			result["targetBehind"] = *diffs.BehindCount
+		}
+	}
+
+	return result, nil
+}
+
+// diffFile returns the unified diff of a file between two refs, computed here so only the diff
+// reaches the client. A file missing at one ref diffs as added or deleted; basePath names the
+// file at base when it was renamed.
+func (c *AzureDevOpsClient) diffFile(ctx context.Context, repoName, basePath, path, baseRef, targetRef string) (map[string]interface{}, error) {
+	project := c.sessionProject(ctx)
+	if err := c.checkRoots(ctx, project, repoName); err != nil {
+		return nil, err
+	}
+	repo, err := c.findRepositoryIn(ctx, project, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+
+	baseCommit, err := c.resolveCommit(ctx, repoID, baseRef)
+	if err != nil {
+		return nil, err
+	}
+	targetCommit, err := c.resolveCommit(ctx, repoID, targetRef)
+	if err != nil {
+		return nil, err
+	}
+
+	oldFile, err := c.fileOrNil(ctx, project, repoID, basePath, baseCommit)
+	if err != nil {
+		return nil, err
+	}
+	newFile, err := c.fileOrNil(ctx, project, repoID, path, targetCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"repository":   repoName,
+		"path":         path,
+		"baseCommit":   baseCommit,
+		"targetCommit": targetCommit,
+	}
+	if basePath != path {
+		result["basePath"] = basePath
+	}
+	oldText, newText := "", ""
+	switch {
+	case oldFile == nil && newFile == nil:
+		return nil, fmt.Errorf("%s exists at neither %s nor %s", path, baseRef, targetRef)
+	case oldFile == nil:
+		result["status"] = "added"
+		newText = newFile.Content
+	case newFile == nil:
+		result["status"] = "deleted"
+		oldText = oldFile.Content
+	case oldFile.ObjectID == newFile.ObjectID:
+		result["status"] = "unchanged"
+		result["diff"] = ""
+		return result, nil
+	default:
+		result["status"] = "modified"
+		oldText, newText = oldFile.Content, newFile.Content
+	}
+	for _, file := range []*fileContent{oldFile, newFile} {
+		if file != nil && file.Encoding == encodingBinary {
+			result["binary"] = true
+			return result, nil
+		}
+	}
+
+	diff, err := unifiedDiff(basePath, path, oldText, newText)
+	if err != nil {
+		return nil, err
+	}
+	result["diff"] = diff
+	return result, nil
+}
+
+func addCommitTools(s tools.Server, client *AzureDevOpsClient) {
+	statusesTool := mcp.NewTool("get_commit_statuses",
+		mcp.WithDescription("List the statuses attached to a commit by CI, quality gates, and other systems"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("commit",
+			mcp.Required(),
+			mcp.Description("Commit SHA, or a branch name for its head commit"),
+		),
+		mcp.WithBoolean("latestOnly",
+			mcp.Description("Only the newest status of each context"),
+			mcp.DefaultBool(true),
+		),
+	)
+
+	s.AddTool(statusesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		commit, ok := request.Params.Arguments["commit"].(string)
+		if !ok {
+			log.Print("Commit must be a string")
+			return nil, fmt.Errorf("commit must be a string")
+		}
+		latestOnly := true
+		if value, ok := request.Params.Arguments["latestOnly"].(bool); ok {
+			latestOnly = value
+		}
+
+		result, err := client.getCommitStatuses(ctx, repo, commit, latestOnly)
+		if err != nil {
+			log.Printf("Error getting commit statuses: %v", err)
+			return nil, fmt.Errorf("error getting commit statuses: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	mergeBaseTool := mcp.NewTool("get_merge_base",
+		mcp.WithDescription("Find the common ancestor of two branches or commits and how far each has diverged from it"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("base",
+			mcp.Required(),
+			mcp.Description("Commit SHA or branch name, usually the target of a merge, e.g. main"),
+		),
+		mcp.WithString("target",
+			mcp.Required(),
+			mcp.Description("Commit SHA or branch name, usually the source of a merge, e.g. a feature branch"),
+		),
+	)
+
+	s.AddTool(mergeBaseTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		base, ok := request.Params.Arguments["base"].(string)
+		if !ok {
+			log.Print("Base must be a string")
+			return nil, fmt.Errorf("base must be a string")
+		}
+		target, ok := request.Params.Arguments["target"].(string)
+		if !ok {
+			log.Print("Target must be a string")
+			return nil, fmt.Errorf("target must be a string")
+		}
+
+		result, err := client.getMergeBase(ctx, repo, base, target)
+		if err != nil {
+			log.Printf("Error getting merge base: %v", err)
+			return nil, fmt.Errorf("error getting merge base: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	diffFileTool := mcp.NewTool("diff_file",
+		mcp.WithDescription("Get the unified diff of one file between two branches, tags, or commits, without reading either version in full"),
+		mcp.WithString("repository",
+			mcp.Description("Repository name; defaults to the session's repository from set_context"),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("File path at target"),
+		),
+		mcp.WithString("base",
+			mcp.Required(),
+			mcp.Description("Branch name, refs/tags/<tag>, or commit SHA of the old version"),
+		),
+		mcp.WithString("target",
+			mcp.Required(),
+			mcp.Description("Branch name, refs/tags/<tag>, or commit SHA of the new version"),
+		),
+		mcp.WithString("basePath",
+			mcp.Description("File path at base, when the file was renamed; defaults to path"),
+		),
+	)
+
+	s.AddTool(diffFileTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, _ := request.Params.Arguments["repository"].(string)
+		repo, _ = client.sessionRepository(ctx, repo, "")
+		if repo == "" {
+			log.Print("No repository to diff in")
+			return nil, fmt.Errorf("repository is required unless set with set_context")
+		}
+		path, ok := request.Params.Arguments["path"].(string)
+		if !ok {
+			log.Print("Path must be a string")
+			return nil, fmt.Errorf("path must be a string")
+		}
+		base, ok := request.Params.Arguments["base"].(string)
+		if !ok {
+			log.Print("Base must be a string")
+			return nil, fmt.Errorf("base must be a string")
+		}
+		target, ok := request.Params.Arguments["target"].(string)
+		if !ok {
+			log.Print("Target must be a string")
+			return nil, fmt.Errorf("target must be a string")
+		}
+		basePath, _ := request.Params.Arguments["basePath"].(string)
+		if basePath == "" {
+			basePath = path
+		}
+
+		result, err := client.diffFile(ctx, repo, basePath, path, base, target)
+		if err != nil {
+			log.Printf("Error diffing file: %v", err)
+			return nil, fmt.Errorf("error diffing file: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	createStatusTool := mcp.NewTool("create_commit_status",
+		mcp.WithDescription("Post a status to a commit from an external system, e.g. a scanner or deployment bot"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("commit",
+			mcp.Required(),
+			mcp.Description("Commit SHA, or a branch name for its head commit"),
+		),
+		mcp.WithString("state",
+			mcp.Required(),
+			mcp.Description("Status state"),
+			mcp.Enum("pending", "succeeded", "failed", "error", "notApplicable"),
+		),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Status context name, e.g. security-scan"),
+		),
+		mcp.WithString("genre",
+			mcp.Description("Status context genre grouping related statuses, e.g. my-bot"),
+		),
+		mcp.WithString("description",
+			mcp.Description("Short description shown with the status"),
+		),
+		mcp.WithString("targetUrl",
+			mcp.Description("Link to details, e.g. the external run"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(createStatusTool, writeHandler(client, createStatusTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		commit, ok := request.Params.Arguments["commit"].(string)
+		if !ok {
+			log.Print("Commit must be a string")
+			return nil, fmt.Errorf("commit must be a string")
+		}
+		state, ok := request.Params.Arguments["state"].(string)
+		if !ok {
+			log.Print("State must be a string")
+			return nil, fmt.Errorf("state must be a string")
+		}
+		name, ok := request.Params.Arguments["name"].(string)
+		if !ok {
+			log.Print("Name must be a string")
+			return nil, fmt.Errorf("name must be a string")
+		}
+		genre, _ := request.Params.Arguments["genre"].(string)
+		description, _ := request.Params.Arguments["description"].(string)
+		targetURL, _ := request.Params.Arguments["targetUrl"].(string)
+
+		result, err := client.createCommitStatus(ctx, repo, commit, state, genre, name, description, targetURL, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error creating commit status: %v", err)
+			return nil, fmt.Errorf("error creating commit status: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// resultEncodingHeader is sent by clients that accept large tool results gzip-compressed.
+	resultEncodingHeader = "X-Accept-Result-Encoding"
+	// defaultCompressionMinBytes is the smallest response compressed when compression.min_bytes is
+	// not set; smaller ones gain less than the encoding costs.
+	defaultCompressionMinBytes = 1024
+)
+
+// resultEncodingKey is the context key marking a tool call whose client accepts compressed results.
+type resultEncodingKey struct{}
+
+// withResultEncoding records whether the client of a request accepts gzip-compressed tool results.
+func withResultEncoding(ctx context.Context, req *http.Request) context.Context {
+	if !acceptsEncoding(req.Header.Get(resultEncodingHeader), "gzip") {
+		return ctx
+	}
+	return context.WithValue(ctx, resultEncodingKey{}, true)
+}
+
+// withResultCompression gzips the text content of a tool's results over threshold bytes for
+// clients that accept it, base64-encoding it so it stays text. The result's _meta marks the
+// encoding and which content items have it, so diffs and logs of several megabytes cross slow
+// links in a fraction of the time.
+func withResultCompression(threshold int, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if threshold <= 0 {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := handler(ctx, request)
+		if err != nil || result == nil || ctx.Value(resultEncodingKey{}) == nil {
+			return result, err
+		}
+		compressed := []int{}
+		for i, content := range result.Content {
+			text, ok := content.(mcp.TextContent)
+			if !ok || len(text.Text) <= threshold {
+				continue
+			}
+			var buf bytes.Buffer
+			writer := gzip.NewWriter(&buf)
+			if _, err := io.WriteString(writer, text.Text); err != nil || writer.Close() != nil {
+				log.Printf("Error compressing result: %v", err)
+				continue
+			}
+			text.Text = base64.StdEncoding.EncodeToString(buf.Bytes())
+			result.Content[i] = text
+			compressed = append(compressed, i)
+		}
+		if len(compressed) > 0 {
+			if result.Meta == nil {
+				result.Meta = map[string]interface{}{}
+			}
+			result.Meta["contentEncoding"] = "gzip+base64"
+			result.Meta["compressedContent"] = compressed
+		}
+		return result, nil
+	}
+}
+
+// acceptsEncoding reports whether an Accept-Encoding style header accepts encoding.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		value, err := strconv.ParseFloat(q, 64)
+		return err == nil && value > 0
+	}
+	return false
+}
+
+// withCompression gzips, or else deflates, the responses of clients that accept it, except those
+// skip picks, such as the long-lived SSE stream. Responses are buffered up to minBytes to leave
+// small ones uncompressed; event streams, which are flushed as they go, are compressed from the
+// start.
+func withCompression(next http.Handler, minBytes int, skip func(req *http.Request) bool) http.Handler {
+	if minBytes <= 0 {
+		minBytes = defaultCompressionMinBytes
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := ""
+		switch accept := req.Header.Get("Accept-Encoding"); {
+		case skip(req):
+		case acceptsEncoding(accept, "gzip"):
+			encoding = "gzip"
+		case acceptsEncoding(accept, "deflate"):
+			encoding = "deflate"
+		}
+		if encoding == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+		writer := &compressWriter{ResponseWriter: w, encoding: encoding, minBytes: minBytes, status: http.StatusOK}
+		defer writer.close()
+		next.ServeHTTP(writer, req)
+	})
+}
+
+// compressWriter compresses a response once it is known to be worth it.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minBytes int
+
+	status     int
+	started    bool
+	buf        []byte
+	compressor interface {
+		io.WriteCloser
+		Flush() error
+	}
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if !w.started {
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) >= w.minBytes || w.streaming() {
+			if err := w.start(); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	if w.compressor != nil {
+		return w.compressor.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *compressWriter) Flush() {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return
+		}
+	}
+	if w.compressor != nil {
+		w.compressor.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// streaming reports whether the response is an event stream.
+func (w *compressWriter) streaming() bool {
+	return strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+}
+
+// start writes the status and what was buffered, compressing the rest of the response when it is
+// an event stream or has reached minBytes, and the handler has not encoded it already.
+func (w *compressWriter) start() error {
+	w.started = true
+	header := w.Header()
+	compress := (len(w.buf) >= w.minBytes || w.streaming()) && header.Get("Content-Encoding") == "" &&
+		w.status != http.StatusNoContent && w.status != http.StatusNotModified
+	if compress {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		if w.encoding == "gzip" {
+			w.compressor = gzip.NewWriter(w.ResponseWriter)
+		} else {
+			w.compressor = zlib.NewWriter(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func (w *compressWriter) close() {
+	if !w.started {
+		w.start()
+	}
+	if w.compressor != nil {
+		if err := w.compressor.Close(); err != nil {
+			log.Printf("Error compressing response: %v", err)
+		}
+	}
+}
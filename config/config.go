@@ -0,0 +1,212 @@
+// Package config reads the server's configuration from config.yaml and the environment.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// Modes of server.session_auth.
+const (
+	SessionAuthOff      = "off"
+	SessionAuthOptional = "optional"
+	SessionAuthRequired = "required"
+)
+
+// Modes of cassette.mode.
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+	CassetteMock   = "mock"
+)
+
+// Config is the configuration of the server.
+type Config struct {
+	AzureDevOps struct {
+		Organization string `mapstructure:"organization"`
+		URL          string `mapstructure:"url"`
+		Project      string `mapstructure:"project"`
+		Team         string `mapstructure:"team"`
+		PAT          string `mapstructure:"pat"`
+		APIVersion   string `mapstructure:"api_version"`
+		ProxyURL     string `mapstructure:"proxy_url"`
+		CAFile       string `mapstructure:"ca_file"`
+		SkipVerify   bool   `mapstructure:"insecure_skip_verify"`
+	} `mapstructure:"azure_devops"`
+	Server struct {
+		Port                  int      `mapstructure:"port"`
+		Host                  string   `mapstructure:"host"`
+		AllowWrites           bool     `mapstructure:"allow_writes"`
+		DryRun                bool     `mapstructure:"dry_run"`
+		ConfirmTools          []string `mapstructure:"confirm_tools"`
+		IdempotencyTTLMinutes int      `mapstructure:"idempotency_ttl_minutes"`
+		StateStore            string   `mapstructure:"state_store"`
+		SessionAuth           string   `mapstructure:"session_auth"`
+		MultiTenant           bool     `mapstructure:"multi_tenant"`
+		TenantOrganizations   []string `mapstructure:"tenant_organizations"`
+		WebhookSecret         string   `mapstructure:"webhook_secret"`
+		SlowCallSeconds       float64  `mapstructure:"slow_call_seconds"`
+		AuditLog              string   `mapstructure:"audit_log"`
+		ProbeScopes           bool     `mapstructure:"probe_scopes"`
+		ProtectedBranches     []string `mapstructure:"protected_branches"`
+	} `mapstructure:"server"`
+	SemanticSearch  SemanticSearchConfig  `mapstructure:"semantic_search"`
+	RepositoryIndex RepositoryIndexConfig `mapstructure:"repository_index"`
+	Cache           CacheConfig           `mapstructure:"cache"`
+	CircuitBreaker  CircuitBreakerConfig  `mapstructure:"circuit_breaker"`
+	RateLimit       RateLimitConfig       `mapstructure:"rate_limit"`
+	Cassette        CassetteConfig        `mapstructure:"cassette"`
+	SSE             SSEConfig             `mapstructure:"sse"`
+	Compression     CompressionConfig     `mapstructure:"compression"`
+	Files           FilesConfig           `mapstructure:"files"`
+	Search          SearchConfig          `mapstructure:"search"`
+}
+
+// CacheConfig selects and sizes the cache of file contents and folder listings. Entries are kept
+// in memory up to MemoryMB, in front of the Backend: "memory" alone, "disk" up to DiskMB in
+// Directory so they survive restarts, or "redis" so several server instances share them.
+type CacheConfig struct {
+	Backend   string      `mapstructure:"backend"`
+	MemoryMB  int         `mapstructure:"memory_mb"`
+	Directory string      `mapstructure:"directory"`
+	DiskMB    int         `mapstructure:"disk_mb"`
+	Redis     RedisConfig `mapstructure:"redis"`
+}
+
+// RedisConfig locates the Redis server a cache is shared through.
+type RedisConfig struct {
+	Address   string `mapstructure:"address"`
+	Password  string `mapstructure:"password"`
+	DB        int    `mapstructure:"db"`
+	KeyPrefix string `mapstructure:"key_prefix"`
+	TTLHours  int    `mapstructure:"ttl_hours"`
+}
+
+// RepositoryIndexConfig configures the background repository indexer. Repositories are indexed
+// when at least one is listed.
+type RepositoryIndexConfig struct {
+	Repositories   []string `mapstructure:"repositories"`
+	RefreshMinutes int      `mapstructure:"refresh_minutes"`
+}
+
+// SemanticSearchConfig configures the semantic search index. The index is built when at least
+// one repository is listed.
+type SemanticSearchConfig struct {
+	Repositories []string `mapstructure:"repositories"`
+	Provider     string   `mapstructure:"provider"`
+	Endpoint     string   `mapstructure:"endpoint"`
+	Model        string   `mapstructure:"model"`
+	APIKey       string   `mapstructure:"api_key"`
+	IndexPath    string   `mapstructure:"index_path"`
+	Extensions   []string `mapstructure:"extensions"`
+}
+
+// CircuitBreakerConfig sets when Azure DevOps requests fail fast: after FailureThreshold failures in
+// a row to a host, for OpenSeconds before a single request probes whether it has recovered.
+type CircuitBreakerConfig struct {
+	FailureThreshold int `mapstructure:"failure_threshold"`
+	OpenSeconds      int `mapstructure:"open_seconds"`
+}
+
+// RateLimitConfig caps the Azure DevOps requests made for each organization, so a busy client does
+// not get the server's identity throttled across the organization. Zero means no limit.
+type RateLimitConfig struct {
+	MaxConcurrentRequests int `mapstructure:"max_concurrent_requests"`
+	RequestsPerMinute     int `mapstructure:"requests_per_minute"`
+}
+
+// SSEConfig keeps SSE streams open through proxies that drop quiet connections, and lets clients
+// resume their session when one is dropped anyway. Zero turns each off.
+type SSEConfig struct {
+	HeartbeatSeconds   int `mapstructure:"heartbeat_seconds"`
+	IdleTimeoutMinutes int `mapstructure:"idle_timeout_minutes"`
+	ResumeSeconds      int `mapstructure:"resume_seconds"`
+}
+
+// CompressionConfig compresses HTTP responses of MinBytes or more for clients that accept gzip or
+// deflate, and the text of tool results over ResultThresholdKB for clients that ask for it.
+type CompressionConfig struct {
+	HTTP              bool `mapstructure:"http"`
+	MinBytes          int  `mapstructure:"min_bytes"`
+	ResultThresholdKB int  `mapstructure:"result_threshold_kb"`
+}
+
+// FilesConfig sets how the read tools present file contents: with CRLF line endings normalized to
+// LF, and without a byte order mark. Write tools restore both for files stored with them.
+type FilesConfig struct {
+	NormalizeLineEndings bool `mapstructure:"normalize_line_endings"`
+	StripBOM             bool `mapstructure:"strip_bom"`
+}
+
+// SearchConfig filters and orders code search results, in .gitignore syntax: hits whose paths
+// match DownRank, such as vendored and generated code, are listed after the rest, and paths
+// matching Ignore are left out of search results and tree listings.
+type SearchConfig struct {
+	DownRank []string `mapstructure:"down_rank"`
+	Ignore   []string `mapstructure:"ignore"`
+}
+
+// CassetteConfig records Azure DevOps responses to a file, or replays them from one, so tools can
+// be tried and tested without an organization or PAT.
+type CassetteConfig struct {
+	Mode string `mapstructure:"mode"`
+	Path string `mapstructure:"path"`
+}
+
+// Load reads config.yaml from the working directory, over any values already set with viper, and
+// fills in the secrets that may be passed in the environment instead.
+func Load() (*Config, error) {
+	var config Config
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath(".")
+	viper.SetDefault("server.probe_scopes", true)
+	viper.SetDefault("sse.heartbeat_seconds", 15)
+	viper.SetDefault("sse.resume_seconds", 300)
+	viper.SetDefault("compression.http", true)
+	viper.SetDefault("compression.result_threshold_kb", 64)
+	viper.SetDefault("files.strip_bom", true)
+	viper.SetDefault("search.down_rank", []string{"vendor/", "node_modules/", "third_party/", "*.min.js", "*.generated.*", "*.designer.cs", "*.g.cs", "*.pb.go"})
+
+	if err := viper.ReadInConfig(); err != nil {
+		// The mock fixtures need no configuration
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) || viper.GetString("cassette.mode") != CassetteMock {
+			log.Printf("Error reading config: %v", err)
+			return nil, fmt.Errorf("error reading config: %w", err)
+		}
+	}
+
+	if err := viper.Unmarshal(&config); err != nil {
+		log.Printf("Error unmarshaling config: %v", err)
+		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	}
+
+	// Check if PAT is empty and try to get it from environment variable
+	if config.AzureDevOps.PAT == "" {
+		if pat := os.Getenv("AZURE_DEVOPS_PAT"); pat != "" {
+			config.AzureDevOps.PAT = pat
+		} else {
+			log.Print("Azure DevOps PAT is required")
+			return nil, fmt.Errorf("Azure DevOps PAT is required")
+		}
+	}
+
+	if config.Server.WebhookSecret == "" {
+		config.Server.WebhookSecret = os.Getenv("AZURE_DEVOPS_WEBHOOK_SECRET")
+	}
+
+	if config.SemanticSearch.APIKey == "" {
+		config.SemanticSearch.APIKey = os.Getenv("EMBEDDINGS_API_KEY")
+	}
+
+	// Tenants bring their own credentials; the service PAT belongs to the configured organization
+	if config.Server.MultiTenant {
+		config.Server.SessionAuth = SessionAuthRequired
+	}
+	return &config, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+)
+
+// cachedItem is the metadata of a file at a commit.
+type cachedItem struct {
+	ObjectID string `json:"objectId"`
+	CommitID string `json:"commitId"`
+}
+
+// fileContent is a file of a repository as read at a ref, transcoded to UTF-8. CommitID is the
+// last commit that changed it, and Encoding, BOM, and LineEndings tell how it is stored.
+type fileContent struct {
+	Content     string
+	ObjectID    string
+	CommitID    string
+	Encoding    string
+	BOM         bool
+	LineEndings string
+}
+
+// newFileContent decodes the stored content of a file.
+func newFileContent(data []byte, item cachedItem) fileContent {
+	text := decodeText(data)
+	file := fileContent{Content: text.Text, ObjectID: item.ObjectID, CommitID: item.CommitID, Encoding: text.Encoding, BOM: text.BOM}
+	if text.Encoding != encodingBinary {
+		file.LineEndings = detectLineEndings(text.Text)
+	}
+	return file
+}
+
+// format returns how the file is stored, for writing it back the same way.
+func (f fileContent) format() textFormat {
+	return textFormat{Encoding: f.Encoding, BOM: f.BOM, LineEndings: f.LineEndings}
+}
+
+// fileAt reads a file of a repository at a ref through the content cache. Contents are cached by
+// object ID, so a file is downloaded again only when it changed: at a commit SHA a cached file
+// needs no request at all, and at a branch or tag only its metadata is fetched. Files are
+// downloaded as stored and transcoded to UTF-8, so UTF-16 and legacy encodings read correctly.
+func (c *AzureDevOpsClient) fileAt(ctx context.Context, project, repoID, path, ref string) (fileContent, error) {
+	var item cachedItem
+	itemKey := ""
+	if commitSHA.MatchString(ref) {
+		itemKey = "item/" + repoID + "/" + strings.ToLower(ref) + "/" + path
+		if value, ok := c.cache.get(itemKey); ok && json.Unmarshal(value, &item) == nil {
+			if content, ok := c.cache.get("blob/" + item.ObjectID); ok {
+				return newFileContent(content, item), nil
+			}
+		}
+	}
+
+	if item.ObjectID == "" {
+		metadata, err := c.gitClient.GetItem(ctx, git.GetItemArgs{
+			RepositoryId:      &repoID,
+			Project:           &project,
+			Path:              &path,
+			VersionDescriptor: versionDescriptor(ref),
+		})
+		if err != nil {
+			return fileContent{}, err
+		}
+		item = cachedItem{ObjectID: stringValue(metadata.ObjectId), CommitID: stringValue(metadata.CommitId)}
+		if item.ObjectID == "" {
+			return fileContent{}, fmt.Errorf("no object ID for %s", path)
+		}
+		if content, ok := c.cache.get("blob/" + item.ObjectID); ok {
+			c.cacheItem(itemKey, item)
+			return newFileContent(content, item), nil
+		}
+	}
+
+	// Download the object the metadata named, which stays the same if the ref moves meanwhile
+	reader, err := c.gitClient.GetBlobContent(ctx, git.GetBlobContentArgs{
+		RepositoryId: &repoID,
+		Project:      &project,
+		Sha1:         &item.ObjectID,
+		Download:     &[]bool{false}[0],
+	})
+	if err != nil {
+		return fileContent{}, err
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return fileContent{}, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	c.cache.put("blob/"+item.ObjectID, content)
+	c.cacheItem(itemKey, item)
+	return newFileContent(content, item), nil
+}
+
+func (c *AzureDevOpsClient) cacheItem(key string, item cachedItem) {
+	if key == "" {
+		return
+	}
+	if value, err := json.Marshal(item); err == nil {
+		c.cache.put(key, value)
+	}
+}
+
+// itemsAt lists a folder of a repository at a ref through the content cache. Listings at a commit
+// SHA are cached by commit. Full listings at a branch or tag are cached by the folder's object
+// ID, which takes one small request to read; one-level listings cost as much as that request and
+// are not cached.
+func (c *AzureDevOpsClient) itemsAt(ctx context.Context, project, repoID, scopePath, ref string, recursion git.VersionControlRecursionType) ([]git.GitItem, error) {
+	key := ""
+	switch {
+	case commitSHA.MatchString(ref):
+		key = "items/" + repoID + "/" + strings.ToLower(ref) + "/" + string(recursion) + "/" + scopePath
+	case recursion == git.VersionControlRecursionTypeValues.Full:
+		folder, err := c.gitClient.GetItem(ctx, git.GetItemArgs{
+			RepositoryId:      &repoID,
+			Project:           &project,
+			Path:              &scopePath,
+			VersionDescriptor: versionDescriptor(ref),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if folder.ObjectId != nil {
+			key = "tree/" + repoID + "/" + *folder.ObjectId + "/" + string(recursion) + "/" + scopePath
+		}
+	}
+	if key != "" {
+		var items []git.GitItem
+		if value, ok := c.cache.get(key); ok && json.Unmarshal(value, &items) == nil {
+			return items, nil
+		}
+	}
+
+	items, err := c.gitClient.GetItems(ctx, git.GetItemsArgs{
+		RepositoryId:      &repoID,
+		Project:           &project,
+		ScopePath:         &scopePath,
+		RecursionLevel:    &recursion,
+		VersionDescriptor: versionDescriptor(ref),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		if value, err := json.Marshal(*items); err == nil {
+			c.cache.put(key, value)
+		}
+	}
+	return *items, nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxZipBytes caps the size of a downloaded folder archive, which is returned base64 encoded in
+// the tool result.
+const maxZipBytes = 20 << 20
+
+// maxReadFiles caps the number of files fetched by one read_files call.
+const maxReadFiles = 20
+
+// FileRequest names a file of a repository at an optional ref. An empty Project is the session's.
+type FileRequest struct {
+	Project    string
+	Repository string
+	Path       string
+	Ref        string
+}
+
+// versionDescriptor selects a version of a repository's content: a full commit SHA, a tag given
+// as refs/tags/<name>, or a branch. An empty ref selects the default branch.
+func versionDescriptor(ref string) *git.GitVersionDescriptor {
+	if ref == "" {
+		return nil
+	}
+
+	versionType := git.GitVersionTypeValues.Branch
+	version := strings.TrimPrefix(ref, "refs/heads/")
+	switch {
+	case commitSHA.MatchString(ref):
+		versionType = git.GitVersionTypeValues.Commit
+	case strings.HasPrefix(ref, "refs/tags/"):
+		versionType = git.GitVersionTypeValues.Tag
+		version = strings.TrimPrefix(ref, "refs/tags/")
+	}
+	return &git.GitVersionDescriptor{
+		Version:     &version,
+		VersionType: &versionType,
+	}
+}
+
+// contentURI identifies a path of a repository at a ref, used to name returned resources.
+func (c *AzureDevOpsClient) contentURI(repoName, path, ref string) string {
+	uri := fmt.Sprintf("azure-devops://%s/%s/%s/%s", c.config.AzureDevOps.Organization,
+		url.PathEscape(c.config.AzureDevOps.Project), url.PathEscape(repoName), strings.TrimPrefix(path, "/"))
+	if ref != "" {
+		uri += "?version=" + url.QueryEscape(ref)
+	}
+	return uri
+}
+
+// downloadFolderZip returns the files under a path of a repository at a ref as a zip archive.
+func (c *AzureDevOpsClient) downloadFolderZip(ctx context.Context, repoName, path, ref string) ([]byte, error) {
+	if err := c.checkRoots(ctx, c.config.AzureDevOps.Project, repoName); err != nil {
+		return nil, err
+	}
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+
+	recursion := git.VersionControlRecursionTypeValues.Full
+	download := true
+	reader, err := c.gitClient.GetItemZip(ctx, git.GetItemZipArgs{
+		RepositoryId:      &repoID,
+		Project:           &c.config.AzureDevOps.Project,
+		Path:              &path,
+		RecursionLevel:    &recursion,
+		Download:          &download,
+		VersionDescriptor: versionDescriptor(ref),
+	})
+	if err != nil {
+		log.Printf("Error downloading folder: %v", err)
+		return nil, fmt.Errorf("error downloading %s as zip: %w", path, err)
+	}
+	defer reader.Close()
+
+	progress := &progressReader{ctx: ctx, reader: reader, message: "Downloading " + path}
+	data, err := io.ReadAll(io.LimitReader(progress, maxZipBytes+1))
+	if err != nil {
+		log.Printf("Error reading zip: %v", err)
+		return nil, fmt.Errorf("error reading zip of %s: %w", path, err)
+	}
+	if len(data) > maxZipBytes {
+		return nil, fmt.Errorf("zip of %s exceeds %d MB; download a smaller folder", path, maxZipBytes>>20)
+	}
+
+	return data, nil
+}
+
+// readFiles returns the content of each requested file. Failures are reported per file, so one
+// missing path does not fail the whole batch.
+func (c *AzureDevOpsClient) readFiles(ctx context.Context, files []FileRequest, options readOptions) ([]map[string]interface{}, error) {
+	// Repository IDs by lowercase project, then lowercase repository name.
+	repoIDs := map[string]map[string]string{}
+	for i, file := range files {
+		if file.Project == "" {
+			files[i].Project = c.sessionProject(ctx)
+		}
+		key := strings.ToLower(files[i].Project)
+		if _, ok := repoIDs[key]; ok {
+			continue
+		}
+		project := files[i].Project
+		repos, err := c.gitClient.GetRepositories(ctx, git.GetRepositoriesArgs{
+			Project: &project,
+		})
+		if err != nil {
+			log.Printf("Error getting repositories: %v", err)
+			return nil, fmt.Errorf("error getting repositories of %s: %w", project, err)
+		}
+		repoIDs[key] = map[string]string{}
+		for _, repo := range *repos {
+			repoIDs[key][strings.ToLower(stringValue(repo.Name))] = repo.Id.String()
+		}
+	}
+
+	results := []map[string]interface{}{}
+	for _, file := range files {
+		project := file.Project
+		result := map[string]interface{}{
+			"repository": file.Repository,
+			"path":       file.Path,
+			"webUrl":     c.links.file(project, file.Repository, file.Path, file.Ref),
+			"uri":        c.fileURI(project, file.Repository, file.Path, file.Ref),
+		}
+		if file.Ref != "" {
+			result["ref"] = file.Ref
+		}
+		results = append(results, result)
+
+		repoID, ok := repoIDs[strings.ToLower(project)][strings.ToLower(file.Repository)]
+		if !ok {
+			result["error"] = fmt.Sprintf("repository not found: %s", file.Repository)
+			continue
+		}
+		if err := c.checkRoots(ctx, project, file.Repository); err != nil {
+			result["error"] = err.Error()
+			continue
+		}
+		content, err := c.fileAt(ctx, project, repoID, file.Path, file.Ref)
+		if err != nil {
+			log.Printf("Error getting file content: %v", err)
+			result["error"] = err.Error()
+			continue
+		}
+		result["content"] = options.text(content)
+		if content.Encoding != encodingUTF8 {
+			result["encoding"] = content.Encoding
+		}
+		if content.LineEndings != "" {
+			result["lineEndings"] = content.LineEndings
+		}
+		if content.ObjectID != "" {
+			result["objectId"] = content.ObjectID
+		}
+		if content.CommitID != "" {
+			result["commitId"] = content.CommitID
+		}
+	}
+
+	return results, nil
+}
+
+func addContentTools(s tools.Server, client *AzureDevOpsClient) {
+	zipTool := mcp.NewTool("download_folder_zip",
+		mcp.WithDescription(fmt.Sprintf("Download a folder of a repository as a zip archive, returned as a binary resource, to export a whole module for offline analysis. Archives are limited to %d MB", maxZipBytes>>20)),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Folder path, e.g. /src/module; / for the whole repository"),
+		),
+		mcp.WithString("ref",
+			mcp.Description("Branch name, refs/tags/<tag>, or commit SHA; defaults to the default branch"),
+		),
+	)
+
+	s.AddTool(zipTool, tools.WithProgress(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		path, ok := request.Params.Arguments["path"].(string)
+		if !ok {
+			log.Print("Path must be a string")
+			return nil, fmt.Errorf("path must be a string")
+		}
+		ref, _ := request.Params.Arguments["ref"].(string)
+
+		data, err := client.downloadFolderZip(ctx, repo, path, ref)
+		if err != nil {
+			log.Printf("Error downloading folder zip: %v", err)
+			return nil, fmt.Errorf("error downloading folder zip: %w", err)
+		}
+
+		return mcp.NewToolResultResource(
+			fmt.Sprintf("Zip of %s in %s (%d bytes)", path, repo, len(data)),
+			mcp.BlobResourceContents{
+				URI:      client.contentURI(repo, path, ref),
+				MIMEType: "application/zip",
+				Blob:     base64.StdEncoding.EncodeToString(data),
+			},
+		), nil
+	}))
+
+	readFilesTool := mcp.NewTool("read_files",
+		mcp.WithDescription(fmt.Sprintf("Read up to %d files in one call, each from its own repository and ref. Files that cannot be read are returned with an error instead of failing the batch", maxReadFiles)),
+		mcp.WithArray("files",
+			mcp.Required(),
+			mcp.Description("Files to read"),
+			mcp.Items(map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repository": map[string]interface{}{"type": "string", "description": "Repository name; defaults to the session's repository from set_context"},
+					"path":       map[string]interface{}{"type": "string", "description": "File path"},
+					"ref":        map[string]interface{}{"type": "string", "description": "Branch name, refs/tags/<tag>, or commit SHA; defaults to the session's branch, then the default branch"},
+					"uri":        map[string]interface{}{"type": "string", "description": tools.URIDescription + ": the repository, path, and ref of a file"},
+				},
+			}),
+		),
+		mcp.WithBoolean("normalizeLineEndings",
+			mcp.Description("Convert CRLF line endings to LF; defaults to files.normalize_line_endings"),
+		),
+		mcp.WithBoolean("stripBom",
+			mcp.Description("Leave out byte order marks; defaults to files.strip_bom"),
+		),
+	)
+
+	s.AddTool(readFilesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		raw, ok := request.Params.Arguments["files"].([]interface{})
+		if !ok {
+			log.Print("Files must be an array")
+			return nil, fmt.Errorf("files must be an array")
+		}
+		if len(raw) > maxReadFiles {
+			return nil, fmt.Errorf("at most %d files can be read at once", maxReadFiles)
+		}
+
+		files := make([]FileRequest, 0, len(raw))
+		for _, value := range raw {
+			entry, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("files must be objects with repository and path, or uri")
+			}
+			if uri, ok := entry["uri"].(string); ok && uri != "" {
+				entity, err := client.parseURI(uri, fileEntity)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, FileRequest{
+					Project:    entity.Project,
+					Repository: entity.Repository,
+					Path:       entity.Path,
+					Ref:        entity.Ref,
+				})
+				continue
+			}
+			file := FileRequest{}
+			file.Repository, _ = entry["repository"].(string)
+			file.Path, _ = entry["path"].(string)
+			file.Ref, _ = entry["ref"].(string)
+			file.Repository, file.Ref = client.sessionRepository(ctx, file.Repository, file.Ref)
+			if file.Repository == "" || file.Path == "" {
+				return nil, fmt.Errorf("files must be objects with repository and path, or uri")
+			}
+			files = append(files, file)
+		}
+
+		results, err := client.readFiles(ctx, files, client.readOptions(request.Params.Arguments))
+		if err != nil {
+			log.Printf("Error reading files: %v", err)
+			return nil, fmt.Errorf("error reading files: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+}
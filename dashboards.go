@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/dashboard"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// markdownWidgetContribution is the Markdown widget, whose settings are the markdown text itself.
+const markdownWidgetContribution = "ms.vss-dashboards-web.Microsoft.VisualStudioOnline.Dashboards.MarkdownWidget"
+
+func dashboardSummary(d dashboard.Dashboard) map[string]interface{} {
+	entry := map[string]interface{}{
+		"name":        stringValue(d.Name),
+		"description": stringValue(d.Description),
+	}
+	if d.Id != nil {
+		entry["id"] = d.Id.String()
+	}
+	if d.DashboardScope != nil {
+		entry["scope"] = string(*d.DashboardScope)
+	}
+	if d.Widgets != nil {
+		entry["widgetCount"] = len(*d.Widgets)
+	}
+	return entry
+}
+
+// listDashboards returns the dashboards of a team, or the configured default team.
+func (c *AzureDevOpsClient) listDashboards(ctx context.Context, team string) ([]map[string]interface{}, error) {
+	team = c.teamName(team)
+	dashboards, err := c.dashboardClient.GetDashboardsByProject(ctx, dashboard.GetDashboardsByProjectArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Team:    &team,
+	})
+	if err != nil {
+		log.Printf("Error listing dashboards: %v", err)
+		return nil, fmt.Errorf("error listing dashboards of team %s: %w", team, err)
+	}
+
+	results := []map[string]interface{}{}
+	if dashboards == nil {
+		return results, nil
+	}
+	for _, d := range *dashboards {
+		results = append(results, dashboardSummary(d))
+	}
+
+	return results, nil
+}
+
+// findDashboard resolves a dashboard by ID or case-insensitive name.
+func (c *AzureDevOpsClient) findDashboard(ctx context.Context, team, nameOrID string) (*dashboard.Dashboard, error) {
+	team = c.teamName(team)
+	dashboardID, err := uuid.Parse(nameOrID)
+	if err != nil {
+		dashboards, err := c.dashboardClient.GetDashboardsByProject(ctx, dashboard.GetDashboardsByProjectArgs{
+			Project: &c.config.AzureDevOps.Project,
+			Team:    &team,
+		})
+		if err != nil {
+			log.Printf("Error listing dashboards: %v", err)
+			return nil, fmt.Errorf("error listing dashboards of team %s: %w", team, err)
+		}
+		found := false
+		if dashboards != nil {
+			for _, d := range *dashboards {
+				if strings.EqualFold(stringValue(d.Name), nameOrID) && d.Id != nil {
+					dashboardID = *d.Id
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("dashboard not found: %s", nameOrID)
+		}
+	}
+
+	d, err := c.dashboardClient.GetDashboard(ctx, dashboard.GetDashboardArgs{
+		Project:     &c.config.AzureDevOps.Project,
+		Team:        &team,
+		DashboardId: &dashboardID,
+	})
+	if err != nil {
+		log.Printf("Error getting dashboard: %v", err)
+		return nil, fmt.Errorf("error getting dashboard %s: %w", nameOrID, err)
+	}
+
+	return d, nil
+}
+
+// widgetQueryID finds the stored work item query a widget is built on: query tiles keep it in
+// queryId, charts in transformOptions.filter.
+func widgetQueryID(settings map[string]interface{}) (uuid.UUID, bool) {
+	candidates := []interface{}{settings["queryId"]}
+	if query, ok := settings["query"].(map[string]interface{}); ok {
+		candidates = append(candidates, query["queryId"])
+	}
+	if transform, ok := settings["transformOptions"].(map[string]interface{}); ok {
+		candidates = append(candidates, transform["filter"])
+	}
+	for _, candidate := range candidates {
+		if s, ok := candidate.(string); ok {
+			if id, err := uuid.Parse(s); err == nil {
+				return id, true
+			}
+		}
+	}
+	return uuid.UUID{}, false
+}
+
+// widgetSummary describes a widget and its settings. Widgets render their data in the browser,
+// so the only data filled in server side is the result count of widgets backed by a work item query.
+func (c *AzureDevOpsClient) widgetSummary(ctx context.Context, team string, widget dashboard.Widget) map[string]interface{} {
+	entry := map[string]interface{}{
+		"name":           stringValue(widget.Name),
+		"contributionId": stringValue(widget.ContributionId),
+	}
+	if widget.Id != nil {
+		entry["id"] = widget.Id.String()
+	}
+	if widget.Position != nil && widget.Position.Row != nil && widget.Position.Column != nil {
+		entry["position"] = map[string]int{"row": *widget.Position.Row, "column": *widget.Position.Column}
+	}
+	if widget.Size != nil && widget.Size.RowSpan != nil && widget.Size.ColumnSpan != nil {
+		entry["size"] = map[string]int{"rows": *widget.Size.RowSpan, "columns": *widget.Size.ColumnSpan}
+	}
+
+	raw := stringValue(widget.Settings)
+	if raw == "" {
+		return entry
+	}
+	if stringValue(widget.ContributionId) == markdownWidgetContribution {
+		entry["markdown"] = raw
+		return entry
+	}
+
+	var settings map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+		entry["settings"] = raw
+		return entry
+	}
+	entry["settings"] = settings
+
+	if queryID, ok := widgetQueryID(settings); ok {
+		result, err := c.witClient.QueryById(ctx, workitemtracking.QueryByIdArgs{
+			Id:      &queryID,
+			Project: &c.config.AzureDevOps.Project,
+			Team:    &team,
+		})
+		if err != nil {
+			log.Printf("Error running widget query %s: %v", queryID, err)
+			entry["queryError"] = err.Error()
+			return entry
+		}
+		count := 0
+		if result.WorkItems != nil {
+			count = len(*result.WorkItems)
+		} else if result.WorkItemRelations != nil {
+			count = len(*result.WorkItemRelations)
+		}
+		entry["queryId"] = queryID.String()
+		entry["workItemCount"] = count
+	}
+
+	return entry
+}
+
+func (c *AzureDevOpsClient) getDashboard(ctx context.Context, team, nameOrID string) (map[string]interface{}, error) {
+	d, err := c.findDashboard(ctx, team, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := dashboardSummary(*d)
+	widgets := []map[string]interface{}{}
+	if d.Widgets != nil {
+		for _, widget := range *d.Widgets {
+			widgets = append(widgets, c.widgetSummary(ctx, c.teamName(team), widget))
+		}
+	}
+	result["widgets"] = widgets
+
+	return result, nil
+}
+
+func addDashboardTools(s tools.Server, client *AzureDevOpsClient) {
+	listDashboardsTool := mcp.NewTool("list_dashboards",
+		mcp.WithDescription("List the dashboards of a team"),
+		mcp.WithString("team",
+			mcp.Description("Team name; defaults to the configured team"),
+		),
+	)
+
+	s.AddTool(listDashboardsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		team, _ := request.Params.Arguments["team"].(string)
+
+		results, err := client.listDashboards(ctx, team)
+		if err != nil {
+			log.Printf("Error listing dashboards: %v", err)
+			return nil, fmt.Errorf("error listing dashboards: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	getDashboardTool := mcp.NewTool("get_dashboard",
+		mcp.WithDescription("Read a dashboard's widgets with their configuration: markdown text, chart and query settings, and the current work item count of query-backed widgets"),
+		mcp.WithString("dashboard",
+			mcp.Required(),
+			mcp.Description("Dashboard name or ID"),
+		),
+		mcp.WithString("team",
+			mcp.Description("Team name; defaults to the configured team"),
+		),
+	)
+
+	s.AddTool(getDashboardTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name, ok := request.Params.Arguments["dashboard"].(string)
+		if !ok {
+			log.Print("Dashboard must be a string")
+			return nil, fmt.Errorf("dashboard must be a string")
+		}
+		team, _ := request.Params.Arguments["team"].(string)
+
+		result, err := client.getDashboard(ctx, team, name)
+		if err != nil {
+			log.Printf("Error getting dashboard: %v", err)
+			return nil, fmt.Errorf("error getting dashboard: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
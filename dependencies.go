@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	maxManifestsPerRepository = 100
+	defaultDependencies       = 500
+	maxDependencies           = 5000
+)
+
+// dependency is one package a manifest depends on. Scope is empty for runtime dependencies and
+// otherwise names the kind, e.g. dev, test, or indirect.
+type dependency struct {
+	Name    string
+	Version string
+	Scope   string
+}
+
+// manifestParser parses one kind of dependency manifest.
+type manifestParser struct {
+	ecosystem string
+	parse     func(content string) ([]dependency, error)
+}
+
+// manifestParsers maps manifest file names to their parser. Project files are matched by
+// extension in manifestParserFor.
+var manifestParsers = map[string]manifestParser{
+	"go.mod":           {"go", parseGoMod},
+	"package.json":     {"npm", parsePackageJSON},
+	"requirements.txt": {"pypi", parseRequirements},
+	"pom.xml":          {"maven", parsePom},
+}
+
+// vendoredFolders holds folders of third-party code whose manifests are not the repository's own.
+var vendoredFolders = []string{"/node_modules/", "/vendor/", "/bower_components/", "/.venv/", "/site-packages/"}
+
+// requirementLine matches a requirements.txt entry: a name, optional extras, and a version spec.
+var requirementLine = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(\[[^\]]*\])?\s*(.*)$`)
+
+// manifestParserFor returns the parser of a manifest path, if it is one.
+func manifestParserFor(filePath string) (manifestParser, bool) {
+	for _, folder := range vendoredFolders {
+		if strings.Contains(filePath, folder) {
+			return manifestParser{}, false
+		}
+	}
+	name := path.Base(filePath)
+	if parser, ok := manifestParsers[name]; ok {
+		return parser, true
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csproj", ".fsproj", ".vbproj":
+		return manifestParser{"nuget", parseProjectFile}, true
+	}
+	return manifestParser{}, false
+}
+
+// parseGoMod reads the required modules of a go.mod file.
+func parseGoMod(content string) ([]dependency, error) {
+	dependencies := []dependency{}
+	inBlock := false
+	for _, line := range splitLines(content) {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "require (":
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require"))
+		case !inBlock:
+			continue
+		}
+
+		scope := ""
+		if code, comment, found := strings.Cut(line, "//"); found {
+			if strings.TrimSpace(comment) == "indirect" {
+				scope = "indirect"
+			}
+			line = code
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		dependencies = append(dependencies, dependency{Name: fields[0], Version: fields[1], Scope: scope})
+	}
+	return dependencies, nil
+}
+
+// parsePackageJSON reads the dependencies of an npm package.json file.
+func parsePackageJSON(content string) ([]dependency, error) {
+	var manifest struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		return nil, fmt.Errorf("invalid package.json: %w", err)
+	}
+
+	dependencies := []dependency{}
+	for _, group := range []struct {
+		scope    string
+		packages map[string]string
+	}{
+		{"", manifest.Dependencies},
+		{"dev", manifest.DevDependencies},
+		{"peer", manifest.PeerDependencies},
+		{"optional", manifest.OptionalDependencies},
+	} {
+		for name, version := range group.packages {
+			dependencies = append(dependencies, dependency{Name: name, Version: version, Scope: group.scope})
+		}
+	}
+	return dependencies, nil
+}
+
+// parseRequirements reads a pip requirements.txt file. Options, includes, and URLs are skipped.
+func parseRequirements(content string) ([]dependency, error) {
+	dependencies := []dependency{}
+	for _, line := range splitLines(content) {
+		line, _, _ = strings.Cut(line, " #")
+		// Environment markers such as ; python_version < "3.8" do not change the package.
+		line, _, _ = strings.Cut(line, ";")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+			continue
+		}
+		match := requirementLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		version := strings.ReplaceAll(match[3], " ", "")
+		if strings.HasPrefix(version, "==") && !strings.ContainsAny(version[2:], "<>=!~,") {
+			version = strings.TrimPrefix(version, "==")
+		}
+		dependencies = append(dependencies, dependency{Name: match[1], Version: version})
+	}
+	return dependencies, nil
+}
+
+// parseProjectFile reads the NuGet package references of an MSBuild project file.
+func parseProjectFile(content string) ([]dependency, error) {
+	var project struct {
+		ItemGroups []struct {
+			PackageReferences []struct {
+				Include      string `xml:"Include,attr"`
+				VersionAttr  string `xml:"Version,attr"`
+				VersionChild string `xml:"Version"`
+			} `xml:"PackageReference"`
+		} `xml:"ItemGroup"`
+	}
+	if err := xml.Unmarshal([]byte(content), &project); err != nil {
+		return nil, fmt.Errorf("invalid project file: %w", err)
+	}
+
+	dependencies := []dependency{}
+	for _, group := range project.ItemGroups {
+		for _, reference := range group.PackageReferences {
+			if reference.Include == "" {
+				continue
+			}
+			version := reference.VersionAttr
+			if version == "" {
+				version = strings.TrimSpace(reference.VersionChild)
+			}
+			dependencies = append(dependencies, dependency{Name: reference.Include, Version: version})
+		}
+	}
+	return dependencies, nil
+}
+
+// parsePom reads the dependencies of a Maven pom.xml, resolving versions set through its own
+// properties. Versions inherited from a parent POM are left empty.
+func parsePom(content string) ([]dependency, error) {
+	type pomDependency struct {
+		GroupID    string `xml:"groupId"`
+		ArtifactID string `xml:"artifactId"`
+		Version    string `xml:"version"`
+		Scope      string `xml:"scope"`
+	}
+	var pom struct {
+		Version    string `xml:"version"`
+		Properties struct {
+			Entries []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:"properties"`
+		Dependencies []pomDependency `xml:"dependencies>dependency"`
+		Managed      []pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+	}
+	if err := xml.Unmarshal([]byte(content), &pom); err != nil {
+		return nil, fmt.Errorf("invalid pom.xml: %w", err)
+	}
+
+	properties := map[string]string{"project.version": pom.Version}
+	for _, entry := range pom.Properties.Entries {
+		properties[entry.XMLName.Local] = strings.TrimSpace(entry.Value)
+	}
+	resolve := func(value string) string {
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+			if resolved, ok := properties[value[2:len(value)-1]]; ok {
+				return resolved
+			}
+		}
+		return value
+	}
+
+	dependencies := []dependency{}
+	for _, group := range []struct {
+		managed bool
+		entries []pomDependency
+	}{{false, pom.Dependencies}, {true, pom.Managed}} {
+		for _, entry := range group.entries {
+			scope := strings.TrimSpace(entry.Scope)
+			if scope == "compile" {
+				scope = ""
+			}
+			if group.managed {
+				scope = "managed"
+			}
+			dependencies = append(dependencies, dependency{
+				Name:    strings.TrimSpace(entry.GroupID) + ":" + strings.TrimSpace(entry.ArtifactID),
+				Version: resolve(entry.Version),
+				Scope:   scope,
+			})
+		}
+	}
+	return dependencies, nil
+}
+
+// dependencyInventory finds the dependency manifests on the default branch of each repository and
+// returns their dependencies, optionally only packages whose name contains packageFilter.
+func (c *AzureDevOpsClient) dependencyInventory(ctx context.Context, repoNames []string, packageFilter, ecosystem string, top int) (map[string]interface{}, error) {
+	repos, err := c.selectRepositories(ctx, repoNames)
+	if err != nil {
+		return nil, err
+	}
+	packageFilter = strings.ToLower(packageFilter)
+
+	dependencies := []map[string]interface{}{}
+	matched := 0
+	manifests := 0
+	errors := map[string]string{}
+	for i, repo := range repos {
+		repoName := stringValue(repo.Name)
+		tools.ReportProgress(ctx, i, len(repos), "Reading manifests of "+repoName)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if repo.DefaultBranch == nil {
+			continue
+		}
+		repoID := repo.Id.String()
+		commitID, err := c.resolveCommit(ctx, repoID, *repo.DefaultBranch)
+		if err != nil {
+			errors[repoName] = err.Error()
+			continue
+		}
+		files, err := c.listTreeFiles(ctx, repoID, commitID)
+		if err != nil {
+			errors[repoName] = err.Error()
+			continue
+		}
+
+		read := 0
+		for _, file := range files {
+			parser, ok := manifestParserFor(file.Path)
+			if !ok || (ecosystem != "" && parser.ecosystem != ecosystem) {
+				continue
+			}
+			if read == maxManifestsPerRepository {
+				errors[repoName] = fmt.Sprintf("only the first %d manifests were read", maxManifestsPerRepository)
+				break
+			}
+			read++
+
+			content, err := c.contentAt(ctx, repoID, file.Path, commitID)
+			if err != nil {
+				errors[repoName+file.Path] = err.Error()
+				continue
+			}
+			parsed, err := parser.parse(content)
+			if err != nil {
+				errors[repoName+file.Path] = err.Error()
+				continue
+			}
+			manifests++
+
+			for _, dep := range parsed {
+				if packageFilter != "" && !strings.Contains(strings.ToLower(dep.Name), packageFilter) {
+					continue
+				}
+				matched++
+				if len(dependencies) == top {
+					continue
+				}
+				entry := map[string]interface{}{
+					"repository": repoName,
+					"manifest":   file.Path,
+					"ecosystem":  parser.ecosystem,
+					"name":       dep.Name,
+					"version":    dep.Version,
+				}
+				if dep.Scope != "" {
+					entry["scope"] = dep.Scope
+				}
+				dependencies = append(dependencies, entry)
+			}
+		}
+	}
+
+	sort.SliceStable(dependencies, func(i, j int) bool {
+		return dependencies[i]["name"].(string) < dependencies[j]["name"].(string)
+	})
+	result := map[string]interface{}{
+		"repositories": len(repos),
+		"manifests":    manifests,
+		"dependencies": dependencies,
+		"matched":      matched,
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addDependencyTools(s tools.Server, client *AzureDevOpsClient) {
+	ecosystems := []string{"nuget"}
+	for _, parser := range manifestParsers {
+		ecosystems = append(ecosystems, parser.ecosystem)
+	}
+	sort.Strings(ecosystems)
+
+	inventoryTool := mcp.NewTool("get_dependency_inventory",
+		mcp.WithDescription("List the dependencies declared in manifests (go.mod, package.json, requirements.txt, pom.xml, .csproj) on the default branch of repositories, normalized to repository, manifest, ecosystem, name, and version. Filter by package to answer questions like which repositories still use log4j 1.x"),
+		mcp.WithArray("repositories",
+			mcp.Description("Repository names; omit to scan every repository of the project"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("package",
+			mcp.Description("Only packages whose name contains this text, ignoring case, e.g. log4j"),
+		),
+		mcp.WithString("ecosystem",
+			mcp.Description("Only manifests of this ecosystem"),
+			mcp.Enum(ecosystems...),
+		),
+		mcp.WithNumber("top",
+			mcp.Description(fmt.Sprintf("Maximum number of dependencies, at most %d", maxDependencies)),
+			mcp.DefaultNumber(defaultDependencies),
+		),
+	)
+
+	s.AddTool(inventoryTool, tools.WithProgress(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repos, err := tools.StringSliceArgument(request.Params.Arguments, "repositories")
+		if err != nil {
+			log.Printf("Invalid repositories: %v", err)
+			return nil, err
+		}
+		packageFilter, _ := request.Params.Arguments["package"].(string)
+		ecosystem, _ := request.Params.Arguments["ecosystem"].(string)
+		top := defaultDependencies
+		if value, ok := request.Params.Arguments["top"].(float64); ok && value > 0 {
+			top = int(value)
+		}
+		if top > maxDependencies {
+			top = maxDependencies
+		}
+
+		result, err := client.dependencyInventory(ctx, repos, packageFilter, ecosystem, top)
+		if err != nil {
+			log.Printf("Error getting dependency inventory: %v", err)
+			return nil, fmt.Errorf("error getting dependency inventory: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultDeployments = 20
+	maxDeployments     = 100
+)
+
+// findEnvironment returns the pipeline environment of the project with a name.
+func (c *AzureDevOpsClient) findEnvironment(ctx context.Context, name string) (*taskagent.EnvironmentInstance, error) {
+	environments, err := c.taskAgentClient.GetEnvironments(ctx, taskagent.GetEnvironmentsArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Name:    &name,
+	})
+	if err != nil {
+		log.Printf("Error getting environments: %v", err)
+		return nil, fmt.Errorf("error finding environment %s: %w", name, err)
+	}
+	for i, environment := range environments.Value {
+		if strings.EqualFold(stringValue(environment.Name), name) {
+			return &environments.Value[i], nil
+		}
+	}
+	return nil, fmt.Errorf("environment %s not found", name)
+}
+
+// deployment is one run of a pipeline stage that deployed to an environment, made up of the
+// deployment jobs of that stage.
+type deployment struct {
+	runID      int
+	pipeline   string
+	pipelineID int
+	stage      string
+	jobs       []string
+	result     string
+	startTime  time.Time
+	finishTime time.Time
+	// outside marks the deployment kept from before since, to name the commit it replaced.
+	outside bool
+}
+
+// deploymentSucceeded reports whether a deployment finished with a result that shipped it.
+func deploymentSucceeded(result string) bool {
+	return result == string(taskagent.TaskResultValues.Succeeded) || result == string(taskagent.TaskResultValues.SucceededWithIssues)
+}
+
+// environmentDeployments lists the latest deployments to an environment, newest first, with the
+// version, commit, and requester of the runs that made them. Each deployment names the commit of
+// the previous successful deployment of its pipeline, so get_release_notes can tell what changed.
+func (c *AzureDevOpsClient) environmentDeployments(ctx context.Context, name string, since *time.Time, top int) (map[string]interface{}, error) {
+	environment, err := c.findEnvironment(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Records are per deployment job and come newest first.
+	deployments := []*deployment{}
+	byStage := map[string]*deployment{}
+	token := ""
+	for done := false; !done; {
+		pageSize := maxDeployments
+		args := taskagent.GetEnvironmentDeploymentExecutionRecordsArgs{
+			Project:       &c.config.AzureDevOps.Project,
+			EnvironmentId: environment.Id,
+			Top:           &pageSize,
+		}
+		if token != "" {
+			args.ContinuationToken = &token
+		}
+		page, err := c.taskAgentClient.GetEnvironmentDeploymentExecutionRecords(ctx, args)
+		if err != nil {
+			log.Printf("Error getting environment deployments: %v", err)
+			return nil, fmt.Errorf("error getting deployments to %s: %w", name, err)
+		}
+		for _, record := range page.Value {
+			if record.Owner == nil || record.Owner.Id == nil {
+				continue
+			}
+			started := time.Time{}
+			if record.StartTime != nil {
+				started = record.StartTime.Time
+			} else if record.QueueTime != nil {
+				started = record.QueueTime.Time
+			}
+			outside := since != nil && !started.IsZero() && started.Before(*since)
+			key := fmt.Sprintf("%d/%s", *record.Owner.Id, stringValue(record.StageName))
+			entry, ok := byStage[key]
+			if !ok {
+				// One more deployment than listed is kept, to name the commit before the oldest.
+				if len(deployments) > top || len(deployments) > 0 && deployments[len(deployments)-1].outside {
+					done = true
+					break
+				}
+				entry = &deployment{runID: *record.Owner.Id, stage: stringValue(record.StageName), startTime: started, outside: outside}
+				if record.Definition != nil {
+					entry.pipeline = stringValue(record.Definition.Name)
+					if record.Definition.Id != nil {
+						entry.pipelineID = *record.Definition.Id
+					}
+				}
+				byStage[key] = entry
+				deployments = append(deployments, entry)
+			}
+			entry.jobs = append(entry.jobs, stringValue(record.JobName))
+			if !started.IsZero() && started.Before(entry.startTime) {
+				entry.startTime = started
+			}
+			if record.FinishTime != nil && record.FinishTime.Time.After(entry.finishTime) {
+				entry.finishTime = record.FinishTime.Time
+			}
+			// A stage succeeds only when all of its deployment jobs do.
+			if record.Result == nil {
+				entry.result = "inProgress"
+			} else if entry.result == "" || deploymentSucceeded(entry.result) {
+				entry.result = string(*record.Result)
+			}
+		}
+		token = page.ContinuationToken
+		if token == "" || len(page.Value) == 0 {
+			done = true
+		}
+	}
+
+	// Deployments past top, rather than before since, were left out.
+	truncated := len(deployments) > top && !deployments[len(deployments)-1].outside
+
+	runIDs := []int{}
+	seen := map[int]bool{}
+	for _, entry := range deployments {
+		if !seen[entry.runID] {
+			seen[entry.runID] = true
+			runIDs = append(runIDs, entry.runID)
+		}
+	}
+	runs := map[int]build.Build{}
+	errors := map[string]string{}
+	if len(runIDs) > 0 {
+		builds, err := c.buildClient.GetBuilds(ctx, build.GetBuildsArgs{
+			Project:  &c.config.AzureDevOps.Project,
+			BuildIds: &runIDs,
+		})
+		if err != nil {
+			log.Printf("Error getting builds: %v", err)
+			errors["runs"] = err.Error()
+		} else {
+			for _, run := range builds.Value {
+				if run.Id != nil {
+					runs[*run.Id] = run
+				}
+			}
+		}
+	}
+
+	list := []map[string]interface{}{}
+	for i, entry := range deployments {
+		if i == top || entry.outside {
+			break
+		}
+		item := map[string]interface{}{
+			"pipeline": entry.pipeline,
+			"runId":    entry.runID,
+			"stage":    entry.stage,
+			"jobs":     entry.jobs,
+			"result":   entry.result,
+			"webUrl":   c.links.build(c.config.AzureDevOps.Project, entry.runID),
+		}
+		if !entry.startTime.IsZero() {
+			item["startTime"] = entry.startTime.Format(time.RFC3339)
+		}
+		if !entry.finishTime.IsZero() {
+			item["finishTime"] = entry.finishTime.Format(time.RFC3339)
+		}
+		if run, ok := runs[entry.runID]; ok {
+			item["version"] = stringValue(run.BuildNumber)
+			item["sourceBranch"] = stringValue(run.SourceBranch)
+			item["commit"] = stringValue(run.SourceVersion)
+			if run.Repository != nil {
+				item["repository"] = stringValue(run.Repository.Name)
+			}
+			if run.RequestedFor != nil {
+				item["requestedFor"] = stringValue(run.RequestedFor.DisplayName)
+			}
+			if run.Reason != nil {
+				item["reason"] = string(*run.Reason)
+			}
+		}
+		for _, previous := range deployments[i+1:] {
+			if previous.pipelineID != entry.pipelineID || !deploymentSucceeded(previous.result) {
+				continue
+			}
+			if run, ok := runs[previous.runID]; ok && run.SourceVersion != nil {
+				item["previousCommit"] = *run.SourceVersion
+				item["previousRunId"] = previous.runID
+			}
+			break
+		}
+		list = append(list, item)
+	}
+
+	result := map[string]interface{}{
+		"environment": stringValue(environment.Name),
+		"deployments": list,
+	}
+	if since != nil {
+		result["since"] = since.Format(time.RFC3339)
+	}
+	if truncated {
+		result["truncated"] = fmt.Sprintf("only the latest %d deployments are listed", top)
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addDeploymentTools(s tools.Server, client *AzureDevOpsClient) {
+	deploymentsTool := mcp.NewTool("list_environment_deployments",
+		mcp.WithDescription("List the latest deployments to a pipeline environment, such as prod, newest first: the pipeline, stage, version, commit, requester, and result of each, and the commit of the previous successful deployment, to pass to get_release_notes. Answers questions like what changed in prod yesterday"),
+		mcp.WithString("environment",
+			mcp.Required(),
+			mcp.Description("Environment name"),
+		),
+		mcp.WithString("since",
+			mcp.Description("Only deployments started on or after this date (YYYY-MM-DD) or RFC 3339 time"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description(fmt.Sprintf("Maximum number of deployments, at most %d", maxDeployments)),
+			mcp.DefaultNumber(defaultDeployments),
+		),
+	)
+
+	s.AddTool(deploymentsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		environment, ok := request.Params.Arguments["environment"].(string)
+		if !ok {
+			log.Print("Environment must be a string")
+			return nil, fmt.Errorf("environment must be a string")
+		}
+		since, err := timeArgument(request.Params.Arguments, "since")
+		if err != nil {
+			log.Printf("Invalid since: %v", err)
+			return nil, err
+		}
+		top := defaultDeployments
+		if value, ok := request.Params.Arguments["top"].(float64); ok && value > 0 {
+			top = int(value)
+		}
+		if top > maxDeployments {
+			top = maxDeployments
+		}
+
+		result, err := client.environmentDeployments(ctx, environment, since, top)
+		if err != nil {
+			log.Printf("Error listing environment deployments: %v", err)
+			return nil, fmt.Errorf("error listing environment deployments: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around each change.
+	diffContextLines = 3
+	// maxDiffLines caps the lines of either side of a file diff; the line diff is quadratic.
+	maxDiffLines = 3000
+)
+
+// unifiedDiff returns a unified diff of two versions of a file, or an empty string when they are
+// equal. Files longer than maxDiffLines return an error instead of a diff.
+func unifiedDiff(oldPath, newPath, oldText, newText string) (string, error) {
+	if oldText == newText {
+		return "", nil
+	}
+	oldLines := splitLines(oldText)
+	newLines := splitLines(newText)
+	if len(oldLines) > maxDiffLines || len(newLines) > maxDiffLines {
+		return "", fmt.Errorf("file is too large to diff (%d and %d lines, limit %d)", len(oldLines), len(newLines), maxDiffLines)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:].
+	lcs := make([][]int32, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Walk the table into a list of operations: ' ' keeps, '-' removes, '+' adds a line. Removals
+	// come before additions, as in diff -u.
+	type operation struct {
+		kind byte
+		text string
+	}
+	operations := []operation{}
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			operations = append(operations, operation{' ', oldLines[i]})
+			i++
+			j++
+		case i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			operations = append(operations, operation{'-', oldLines[i]})
+			i++
+		default:
+			operations = append(operations, operation{'+', newLines[j]})
+			j++
+		}
+	}
+
+	// oldAt and newAt are the line numbers each operation starts at on either side.
+	oldAt := make([]int, len(operations)+1)
+	newAt := make([]int, len(operations)+1)
+	oldAt[0], newAt[0] = 1, 1
+	changes := []int{}
+	for k, op := range operations {
+		oldAt[k+1], newAt[k+1] = oldAt[k], newAt[k]
+		if op.kind != '+' {
+			oldAt[k+1]++
+		}
+		if op.kind != '-' {
+			newAt[k+1]++
+		}
+		if op.kind != ' ' {
+			changes = append(changes, k)
+		}
+	}
+
+	var diff strings.Builder
+	fmt.Fprintf(&diff, "--- a%s\n+++ b%s\n", oldPath, newPath)
+	for c := 0; c < len(changes); {
+		// Changes separated by at most twice the context share a hunk.
+		last := c
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*diffContextLines+1 {
+			last++
+		}
+		start := changes[c] - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := changes[last] + diffContextLines + 1
+		if end > len(operations) {
+			end = len(operations)
+		}
+
+		fmt.Fprintf(&diff, "@@ -%s +%s @@\n", hunkRange(oldAt[start], oldAt[end]-oldAt[start]), hunkRange(newAt[start], newAt[end]-newAt[start]))
+		for _, op := range operations[start:end] {
+			diff.WriteByte(op.kind)
+			diff.WriteString(op.text)
+			diff.WriteByte('\n')
+		}
+		c = last + 1
+	}
+
+	return diff.String(), nil
+}
+
+// hunkRange formats the start and length of one side of a hunk. An empty side starts at the line
+// before it, as in diff -u.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines without their terminators.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// dryRun reports whether a write tool call should only be validated, either because the server
+// runs with server.dry_run or because the call passed dryRun.
+func (c *AzureDevOpsClient) dryRun(request mcp.CallToolRequest) bool {
+	if c.config.Server.DryRun {
+		return true
+	}
+	dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+	return dryRun
+}
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// fileChangeSummary describes a change of a push for dry runs and results.
+type fileChangeSummary struct {
+	Path         string `json:"path"`
+	ChangeType   string `json:"changeType"`
+	LinesAdded   int    `json:"linesAdded,omitempty"`
+	LinesRemoved int    `json:"linesRemoved,omitempty"`
+}
+
+// writeBranch resolves the branch of a repository of a project a write tool commits to and its
+// head commit. Tags and commits cannot be committed to, nor can protected branches unless the
+// commit goes to a newBranch.
+func (c *AzureDevOpsClient) writeBranch(ctx context.Context, project, repoID, branch, newBranch string) (string, string, error) {
+	if commitSHA.MatchString(branch) || strings.HasPrefix(branch, "refs/tags/") {
+		return "", "", fmt.Errorf("%s is not a branch; commits can only be made on branches", branch)
+	}
+	ref := branchRef(branch)
+	head, err := c.resolveCommitIn(ctx, project, repoID, ref)
+	if err != nil {
+		return "", "", err
+	}
+	if newBranch == "" {
+		if err := c.checkBranchProtection(ctx, project, repoID, ref, head); err != nil {
+			return "", "", err
+		}
+	}
+	return ref, head, nil
+}
+
+// fileOrNil reads a file at a commit, returning nil when it does not exist there.
+func (c *AzureDevOpsClient) fileOrNil(ctx context.Context, project, repoID, path, commitID string) (*fileContent, error) {
+	file, err := c.fileAt(ctx, project, repoID, path, commitID)
+	if statusCode(err) == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Error getting file content: %v", err)
+		return nil, fmt.Errorf("error getting %s at %s: %w", path, commitID, err)
+	}
+	return &file, nil
+}
+
+// pushChange returns the change of a push that sets path to content, base64-encoded so it is
+// stored byte for byte.
+func pushChange(changeType git.VersionControlChangeType, path string, content []byte) git.GitChange {
+	change := git.GitChange{
+		ChangeType: &changeType,
+		Item:       map[string]string{"path": path},
+	}
+	if changeType != git.VersionControlChangeTypeValues.Delete {
+		encoded := base64.StdEncoding.EncodeToString(content)
+		contentType := git.ItemContentTypeValues.Base64Encoded
+		change.NewContent = &git.ItemContent{Content: &encoded, ContentType: &contentType}
+	}
+	return change
+}
+
+// pushCommit commits changes on top of oldCommit, the head of branch, so a push that moved the
+// branch meanwhile makes it fail instead of being overwritten. With newBranch the commit goes on
+// a new branch started from oldCommit, and branch is left as it is.
+func (c *AzureDevOpsClient) pushCommit(ctx context.Context, project, repoID, branch, oldCommit, newBranch, message string, changes []interface{}) (string, error) {
+	target := branch
+	if newBranch != "" {
+		target = branchRef(newBranch)
+	}
+	push, err := c.gitClient.CreatePush(ctx, git.CreatePushArgs{
+		Push: &git.GitPush{
+			RefUpdates: &[]git.GitRefUpdate{{Name: &target, OldObjectId: &oldCommit}},
+			Commits:    &[]git.GitCommitRef{{Comment: &message, Changes: &changes}},
+		},
+		RepositoryId: &repoID,
+		Project:      &project,
+	})
+	if err != nil {
+		log.Printf("Error pushing commit: %v", err)
+		if statusCode(err) == http.StatusConflict {
+			return "", fmt.Errorf("%s moved since %s was read; read the files again and retry: %w", target, oldCommit, err)
+		}
+		return "", fmt.Errorf("error pushing to %s: %w", target, err)
+	}
+	if push.Commits == nil || len(*push.Commits) == 0 || (*push.Commits)[0].CommitId == nil {
+		return "", fmt.Errorf("push to %s returned no commit", target)
+	}
+	return *(*push.Commits)[0].CommitId, nil
+}
+
+// applyPatch applies a unified diff to the files of a branch at its head and commits the result.
+// Every hunk must match before anything is pushed, and files keep their encoding, byte order mark,
+// and line endings.
+func (c *AzureDevOpsClient) applyPatch(ctx context.Context, repoName, branch, newBranch, patch, message string, dryRun bool) (map[string]interface{}, error) {
+	files, err := parsePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	project := c.sessionProject(ctx)
+	if err := c.checkRoots(ctx, project, repoName); err != nil {
+		return nil, err
+	}
+	repo, err := c.findRepositoryIn(ctx, project, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+	ref, head, err := c.writeBranch(ctx, project, repoID, branch, newBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	readFile := func(path string) (*fileContent, error) {
+		return c.fileOrNil(ctx, project, repoID, path, head)
+	}
+
+	changes := []interface{}{}
+	summaries := []fileChangeSummary{}
+	seen := map[string]bool{}
+	for _, file := range files {
+		paths := map[string]string{strings.ToLower(file.OldPath): file.OldPath, strings.ToLower(file.NewPath): file.NewPath}
+		delete(paths, "")
+		for key, path := range paths {
+			if seen[key] {
+				return nil, fmt.Errorf("the patch changes %s more than once", path)
+			}
+			seen[key] = true
+		}
+		summary := fileChangeSummary{Path: file.path()}
+		for _, hunk := range file.Hunks {
+			for _, line := range hunk.Lines {
+				switch line[0] {
+				case '+':
+					summary.LinesAdded++
+				case '-':
+					summary.LinesRemoved++
+				}
+			}
+		}
+
+		current := &fileContent{Encoding: encodingUTF8}
+		if file.OldPath != "" {
+			if current, err = readFile(file.OldPath); err != nil {
+				return nil, err
+			}
+			if current == nil {
+				return nil, fmt.Errorf("%s does not exist on %s", file.OldPath, branch)
+			}
+			if current.Encoding == encodingBinary {
+				return nil, fmt.Errorf("%s is a binary file and cannot be patched", file.OldPath)
+			}
+		} else if existing, err := readFile(file.NewPath); err != nil {
+			return nil, err
+		} else if existing != nil {
+			return nil, fmt.Errorf("the patch adds %s, which already exists on %s", file.NewPath, branch)
+		}
+		text, err := file.apply(current.Content, current.LineEndings)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case file.NewPath == "":
+			summary.ChangeType = "delete"
+			changes = append(changes, pushChange(git.VersionControlChangeTypeValues.Delete, file.OldPath, nil))
+		default:
+			content, err := encodeText(text, current.format())
+			if err != nil {
+				return nil, fmt.Errorf("error writing %s: %w", file.NewPath, err)
+			}
+			changeType := git.VersionControlChangeTypeValues.Edit
+			summary.ChangeType = "edit"
+			if file.OldPath == "" {
+				changeType = git.VersionControlChangeTypeValues.Add
+				summary.ChangeType = "add"
+			} else if file.OldPath != file.NewPath {
+				// A rename is pushed as the old path deleted and the new one added
+				if existing, err := readFile(file.NewPath); err != nil {
+					return nil, err
+				} else if existing != nil {
+					return nil, fmt.Errorf("the patch renames %s to %s, which already exists on %s", file.OldPath, file.NewPath, branch)
+				}
+				changes = append(changes, pushChange(git.VersionControlChangeTypeValues.Delete, file.OldPath, nil))
+				changeType = git.VersionControlChangeTypeValues.Add
+				summary.ChangeType = "rename"
+			}
+			changes = append(changes, pushChange(changeType, file.NewPath, content))
+		}
+		summaries = append(summaries, summary)
+	}
+
+	target := ref
+	if newBranch != "" {
+		target = branchRef(newBranch)
+	}
+	if dryRun {
+		return tools.DryRunResult("apply patch", map[string]interface{}{
+			"repositoryId": repoID,
+			"branch":       target,
+			"baseCommit":   head,
+			"message":      message,
+			"files":        summaries,
+		}), nil
+	}
+
+	commitID, err := c.pushCommit(ctx, project, repoID, ref, head, newBranch, message, changes)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"commitId":   commitID,
+		"branch":     target,
+		"baseCommit": head,
+		"files":      summaries,
+		"webUrl":     c.links.commit(project, stringValue(repo.Name), commitID),
+		"uri":        c.commitURI(project, stringValue(repo.Name), commitID),
+	}, nil
+}
+
+// nullObjectID is Git's ID of no object: the expected object ID of a file that must not exist yet.
+const nullObjectID = "0000000000000000000000000000000000000000"
+
+// writeFile replaces the content of a file on a branch, or creates it, provided it is still what
+// the caller read: its object ID is expectedObjectID, or it has not changed since expectedCommit.
+// Otherwise it fails with a conflict, so a write never clobbers an edit the caller has not seen.
+func (c *AzureDevOpsClient) writeFile(ctx context.Context, repoName, branch, newBranch, path, content, message, expectedObjectID, expectedCommit string, dryRun bool) (map[string]interface{}, error) {
+	project := c.sessionProject(ctx)
+	if err := c.checkRoots(ctx, project, repoName); err != nil {
+		return nil, err
+	}
+	repo, err := c.findRepositoryIn(ctx, project, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+	ref, head, err := c.writeBranch(ctx, project, repoID, branch, newBranch)
+	if err != nil {
+		return nil, err
+	}
+	path = "/" + strings.TrimPrefix(path, "/")
+
+	current, err := c.fileOrNil(ctx, project, repoID, path, head)
+	if err != nil {
+		return nil, err
+	}
+	currentID := nullObjectID
+	if current != nil {
+		currentID = current.ObjectID
+		if current.Encoding == encodingBinary {
+			return nil, fmt.Errorf("%s is a binary file and cannot be written as text", path)
+		}
+	}
+	if expectedCommit != "" && expectedObjectID == "" {
+		expectedCommit = strings.ToLower(expectedCommit)
+		expectedObjectID = nullObjectID
+		if expectedCommit != head {
+			expected, err := c.fileOrNil(ctx, project, repoID, path, expectedCommit)
+			if err != nil {
+				return nil, err
+			}
+			if expected != nil {
+				expectedObjectID = expected.ObjectID
+			}
+		} else {
+			expectedObjectID = currentID
+		}
+	}
+	if !strings.EqualFold(expectedObjectID, currentID) {
+		if current == nil {
+			return nil, fmt.Errorf("conflict: %s was deleted from %s since it was read; nothing was written", path, branch)
+		}
+		if expectedObjectID == nullObjectID {
+			return nil, fmt.Errorf("conflict: %s already exists on %s (object %s, last changed in commit %s); read it and retry", path, branch, current.ObjectID, current.CommitID)
+		}
+		return nil, fmt.Errorf("conflict: %s changed on %s since it was read (now object %s, last changed in commit %s); read it again, reapply the change, and retry", path, branch, current.ObjectID, current.CommitID)
+	}
+
+	format := textFormat{Encoding: encodingUTF8}
+	changeType := git.VersionControlChangeTypeValues.Add
+	if current != nil {
+		format = current.format()
+		changeType = git.VersionControlChangeTypeValues.Edit
+	}
+	data, err := encodeText(content, format)
+	if err != nil {
+		return nil, fmt.Errorf("error writing %s: %w", path, err)
+	}
+	summary := fileChangeSummary{Path: path, ChangeType: string(changeType)}
+
+	target := ref
+	if newBranch != "" {
+		target = branchRef(newBranch)
+	}
+	if dryRun {
+		return tools.DryRunResult("write file", map[string]interface{}{
+			"repositoryId": repoID,
+			"branch":       target,
+			"baseCommit":   head,
+			"message":      message,
+			"files":        []fileChangeSummary{summary},
+			"bytes":        len(data),
+		}), nil
+	}
+
+	commitID, err := c.pushCommit(ctx, project, repoID, ref, head, newBranch, message, []interface{}{pushChange(changeType, path, data)})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"commitId":   commitID,
+		"branch":     target,
+		"baseCommit": head,
+		"files":      []fileChangeSummary{summary},
+		"webUrl":     c.links.commit(project, stringValue(repo.Name), commitID),
+		"uri":        c.commitURI(project, stringValue(repo.Name), commitID),
+	}, nil
+}
+
+// addEditTools adds the tools that commit changes to files. They are all write tools.
+func addEditTools(s tools.Server, client *AzureDevOpsClient) {
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	applyPatchTool := mcp.NewTool("apply_patch",
+		mcp.WithDescription("Apply a unified diff to the files of a branch and commit it. Every hunk is checked against the files at the branch head first, and nothing is committed unless all of them apply; hunks may be a little off from the line numbers they name. Files keep their encoding and line endings"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("branch",
+			mcp.Required(),
+			mcp.Description("Branch to apply the patch to, e.g. main or refs/heads/feature"),
+		),
+		mcp.WithString("patch",
+			mcp.Required(),
+			mcp.Description("Unified diff with --- and +++ headers per file, as from git diff; /dev/null adds or deletes a file"),
+		),
+		mcp.WithString("message",
+			mcp.Required(),
+			mcp.Description("Commit message"),
+		),
+		mcp.WithString("newBranch",
+			mcp.Description("Commit to this new branch, started from branch, instead of to branch itself"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(applyPatchTool, writeHandler(client, applyPatchTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		branch, ok := request.Params.Arguments["branch"].(string)
+		if !ok {
+			log.Print("Branch must be a string")
+			return nil, fmt.Errorf("branch must be a string")
+		}
+		patch, ok := request.Params.Arguments["patch"].(string)
+		if !ok {
+			log.Print("Patch must be a string")
+			return nil, fmt.Errorf("patch must be a string")
+		}
+		message, ok := request.Params.Arguments["message"].(string)
+		if !ok || strings.TrimSpace(message) == "" {
+			log.Print("Message must be a non-empty string")
+			return nil, fmt.Errorf("message must be a non-empty string")
+		}
+		newBranch, _ := request.Params.Arguments["newBranch"].(string)
+
+		result, err := client.applyPatch(ctx, repo, branch, newBranch, patch, message, client.dryRun(request))
+		var protected *protectedBranchError
+		if errors.As(err, &protected) {
+			return protected.result()
+		}
+		if err != nil {
+			log.Printf("Error applying patch: %v", err)
+			return nil, fmt.Errorf("error applying patch: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+	writeFileTool := mcp.NewTool("write_file",
+		mcp.WithDescription("Replace the whole content of a file on a branch, or create it, and commit it. The write is rejected with a conflict when the file changed since it was read, so pass the objectId that read returned, or the commit it was read at"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("branch",
+			mcp.Required(),
+			mcp.Description("Branch to write to, e.g. main or refs/heads/feature"),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("File path"),
+		),
+		mcp.WithString("content",
+			mcp.Required(),
+			mcp.Description("New content of the file"),
+		),
+		mcp.WithString("message",
+			mcp.Required(),
+			mcp.Description("Commit message"),
+		),
+		mcp.WithString("expectedObjectId",
+			mcp.Description("Object ID of the file as it was read, from read's _meta; "+nullObjectID+" for a file that must not exist yet. Required unless expectedCommit is given"),
+		),
+		mcp.WithString("expectedCommit",
+			mcp.Description("Commit the file was read at; the write is rejected if the file changed on the branch since"),
+		),
+		mcp.WithString("newBranch",
+			mcp.Description("Commit to this new branch, started from branch, instead of to branch itself"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(writeFileTool, writeHandler(client, writeFileTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		branch, ok := request.Params.Arguments["branch"].(string)
+		if !ok {
+			log.Print("Branch must be a string")
+			return nil, fmt.Errorf("branch must be a string")
+		}
+		path, ok := request.Params.Arguments["path"].(string)
+		if !ok || path == "" {
+			log.Print("Path must be a non-empty string")
+			return nil, fmt.Errorf("path must be a non-empty string")
+		}
+		content, ok := request.Params.Arguments["content"].(string)
+		if !ok {
+			log.Print("Content must be a string")
+			return nil, fmt.Errorf("content must be a string")
+		}
+		message, ok := request.Params.Arguments["message"].(string)
+		if !ok || strings.TrimSpace(message) == "" {
+			log.Print("Message must be a non-empty string")
+			return nil, fmt.Errorf("message must be a non-empty string")
+		}
+		expectedObjectID, _ := request.Params.Arguments["expectedObjectId"].(string)
+		expectedCommit, _ := request.Params.Arguments["expectedCommit"].(string)
+		if expectedObjectID == "" && expectedCommit == "" {
+			return nil, fmt.Errorf("expectedObjectId or expectedCommit is required, so the write cannot overwrite changes that were not read")
+		}
+		if expectedCommit != "" && !commitSHA.MatchString(expectedCommit) {
+			return nil, fmt.Errorf("expectedCommit must be a commit SHA")
+		}
+		newBranch, _ := request.Params.Arguments["newBranch"].(string)
+
+		result, err := client.writeFile(ctx, repo, branch, newBranch, path, content, message, expectedObjectID, expectedCommit, client.dryRun(request))
+		var protected *protectedBranchError
+		if errors.As(err, &protected) {
+			return protected.result()
+		}
+		if err != nil {
+			log.Printf("Error writing file: %v", err)
+			return nil, fmt.Errorf("error writing file: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
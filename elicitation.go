@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxConfirmationMessage caps how much of a dry run's result is shown when asking for
+// confirmation.
+const maxConfirmationMessage = 4000
+
+// confirmationMessage describes what a write tool call will change from the result of its dry run.
+func confirmationMessage(tool mcp.Tool, preview *mcp.CallToolResult) string {
+	var text strings.Builder
+	for _, content := range preview.Content {
+		if textContent, ok := content.(mcp.TextContent); ok {
+			text.WriteString(textContent.Text)
+		}
+	}
+	changes := text.String()
+	var indented bytes.Buffer
+	if json.Indent(&indented, []byte(changes), "", "  ") == nil {
+		changes = indented.String()
+	}
+	if len(changes) > maxConfirmationMessage {
+		changes = changes[:maxConfirmationMessage] + "..."
+	}
+	return fmt.Sprintf("%s will modify Azure DevOps: %s. Its dry run reports these changes:\n%s", tool.Name, tool.Description, changes)
+}
+
+// canConfirm reports whether the client making a tool call can be asked to confirm it, which
+// takes a session whose client declared the elicitation capability.
+func canConfirm(ctx context.Context) bool {
+	requests := clientRequestsFromContext(ctx)
+	session := server.ClientSessionFromContext(ctx)
+	return requests != nil && session != nil && requests.clientCapabilities(session.SessionID()).Elicitation != nil
+}
+
+// confirmWrite asks the user of the client making a tool call to confirm the changes its dry run
+// reported.
+func confirmWrite(ctx context.Context, tool mcp.Tool, preview *mcp.CallToolResult) error {
+	requests := clientRequestsFromContext(ctx)
+	session := server.ClientSessionFromContext(ctx)
+
+	params := map[string]interface{}{
+		"message": confirmationMessage(tool, preview),
+		"requestedSchema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"confirm": map[string]interface{}{
+					"type":        "boolean",
+					"title":       "Confirm",
+					"description": "Apply these changes",
+				},
+			},
+			"required": []string{"confirm"},
+		},
+	}
+	var result struct {
+		Action  string `json:"action"`
+		Content struct {
+			Confirm bool `json:"confirm"`
+		} `json:"content"`
+	}
+	if err := requests.call(ctx, session.SessionID(), "elicitation/create", params, &result); err != nil {
+		return err
+	}
+	if result.Action != "accept" || !result.Content.Confirm {
+		return fmt.Errorf("%s was not confirmed by the user", tool.Name)
+	}
+	return nil
+}
+
+// withConfirmation makes a write tool ask the user to confirm each call when it is listed in
+// server.confirm_tools, showing the changes a dry run of the call reports. Calls from clients that
+// cannot be asked fail, so they can only dry-run the tool. Dry runs change nothing and are not
+// confirmed.
+func withConfirmation(client *AzureDevOpsClient, tool mcp.Tool, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	confirm := false
+	for _, name := range client.config.Server.ConfirmTools {
+		if name == tool.Name {
+			confirm = true
+		}
+	}
+	if !confirm {
+		return handler
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if client.dryRun(request) {
+			return handler(ctx, request)
+		}
+		if !canConfirm(ctx) {
+			log.Printf("Cannot confirm %s: the client does not support elicitation", tool.Name)
+			return nil, fmt.Errorf("%s must be confirmed by the user, which this client cannot do; call it with dryRun to preview the changes", tool.Name)
+		}
+
+		preview := request
+		preview.Params.Arguments = map[string]interface{}{}
+		for name, value := range request.Params.Arguments {
+			preview.Params.Arguments[name] = value
+		}
+		preview.Params.Arguments["dryRun"] = true
+		result, err := handler(ctx, preview)
+		if err != nil || result != nil && result.IsError {
+			// Nothing can be confirmed when the dry run fails; the call would fail the same way.
+			return result, err
+		}
+		if result == nil {
+			log.Printf("Error confirming %s: its dry run returned no result", tool.Name)
+			return nil, fmt.Errorf("error confirming %s: its dry run returned no changes to show", tool.Name)
+		}
+
+		if err := confirmWrite(ctx, tool, result); err != nil {
+			log.Printf("Error confirming %s: %v", tool.Name, err)
+			return nil, fmt.Errorf("error confirming %s: %w", tool.Name, err)
+		}
+		return handler(ctx, request)
+	}
+}
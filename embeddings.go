@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/signify/sgfy-mcp/config"
+)
+
+// Embedder turns texts into embedding vectors for semantic search.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// embeddingProviders creates an Embedder for each supported semantic_search.provider.
+var embeddingProviders = map[string]func(config config.SemanticSearchConfig) (Embedder, error){
+	"openai":       newOpenAIEmbedder,
+	"azure-openai": newAzureOpenAIEmbedder,
+}
+
+// newEmbedder creates the Embedder configured for semantic search.
+func newEmbedder(config config.SemanticSearchConfig) (Embedder, error) {
+	provider := config.Provider
+	if provider == "" {
+		provider = "openai"
+	}
+	create, ok := embeddingProviders[provider]
+	if !ok {
+		names := []string{}
+		for name := range embeddingProviders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown embedding provider %s; use one of %s", provider, strings.Join(names, ", "))
+	}
+	return create(config)
+}
+
+// httpEmbedder calls an OpenAI-compatible embeddings endpoint. OpenAI, Azure OpenAI, and local
+// servers such as Ollama accept the same request and differ only in URL and authentication.
+type httpEmbedder struct {
+	url         string
+	model       string
+	headerName  string
+	headerValue string
+}
+
+// newOpenAIEmbedder calls the OpenAI embeddings API, or any server compatible with it at
+// semantic_search.endpoint, e.g. http://localhost:11434/v1 for Ollama.
+func newOpenAIEmbedder(config config.SemanticSearchConfig) (Embedder, error) {
+	if config.Model == "" {
+		return nil, fmt.Errorf("semantic_search.model is required for the openai provider")
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.openai.com/v1"
+	}
+	embedder := &httpEmbedder{
+		url:   strings.TrimSuffix(endpoint, "/") + "/embeddings",
+		model: config.Model,
+	}
+	if config.APIKey != "" {
+		embedder.headerName = "Authorization"
+		embedder.headerValue = "Bearer " + config.APIKey
+	}
+	return embedder, nil
+}
+
+// newAzureOpenAIEmbedder calls an Azure OpenAI embeddings deployment. semantic_search.endpoint is
+// the deployment's embeddings URL including its api-version.
+func newAzureOpenAIEmbedder(config config.SemanticSearchConfig) (Embedder, error) {
+	if config.Endpoint == "" || config.APIKey == "" {
+		return nil, fmt.Errorf("semantic_search.endpoint and api_key are required for the azure-openai provider")
+	}
+	return &httpEmbedder{
+		url:         config.Endpoint,
+		model:       config.Model,
+		headerName:  "api-key",
+		headerValue: config.APIKey,
+	}, nil
+}
+
+func (e *httpEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body := map[string]interface{}{
+		"input": texts,
+	}
+	if e.model != "" {
+		body["model"] = e.model
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding embeddings request: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error creating embeddings request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if e.headerName != "" {
+		request.Header.Set(e.headerName, e.headerValue)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error calling embeddings endpoint: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return nil, fmt.Errorf("embeddings endpoint returned %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error decoding embeddings response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings endpoint returned %d vectors for %d texts", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings endpoint returned an out of range index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// The encodings files are reported to be stored in.
+const (
+	encodingUTF8        = "utf-8"
+	encodingUTF16LE     = "utf-16le"
+	encodingUTF16BE     = "utf-16be"
+	encodingWindows1252 = "windows-1252"
+	encodingBinary      = "binary"
+)
+
+// binarySniffBytes is how much of a file is looked at to tell binary and UTF-16 files apart, as
+// Git does.
+const binarySniffBytes = 8000
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// decodedText is the UTF-8 text of a file with the encoding it was stored in.
+type decodedText struct {
+	Text     string
+	Encoding string
+	// BOM is set when the file starts with a byte order mark, which is left out of Text.
+	BOM bool
+}
+
+// decodeText transcodes the content of a file to UTF-8. UTF-8 and UTF-16 are recognized by their
+// byte order marks, UTF-16 without one by its zero bytes, and content that is none of these nor
+// binary is taken to be Windows-1252, the superset of Latin-1 that legacy Windows sources use.
+// Binary content is returned as it is.
+func decodeText(data []byte) decodedText {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return decodedText{Text: string(data[len(utf8BOM):]), Encoding: encodingUTF8, BOM: true}
+	case bytes.HasPrefix(data, utf16LEBOM):
+		return decodeUTF16(data[len(utf16LEBOM):], unicode.LittleEndian, encodingUTF16LE, true)
+	case bytes.HasPrefix(data, utf16BEBOM):
+		return decodeUTF16(data[len(utf16BEBOM):], unicode.BigEndian, encodingUTF16BE, true)
+	}
+
+	sniff := data
+	if len(sniff) > binarySniffBytes {
+		sniff = sniff[:binarySniffBytes]
+	}
+	if bytes.IndexByte(sniff, 0) < 0 {
+		if utf8.Valid(data) {
+			return decodedText{Text: string(data), Encoding: encodingUTF8}
+		}
+		if text, err := charmap.Windows1252.NewDecoder().Bytes(data); err == nil {
+			return decodedText{Text: string(text), Encoding: encodingWindows1252}
+		}
+	}
+	if order, ok := utf16Order(sniff); ok {
+		if order == unicode.LittleEndian {
+			return decodeUTF16(data, order, encodingUTF16LE, false)
+		}
+		return decodeUTF16(data, order, encodingUTF16BE, false)
+	}
+	return decodedText{Text: string(data), Encoding: encodingBinary}
+}
+
+func decodeUTF16(data []byte, order unicode.Endianness, encoding string, bom bool) decodedText {
+	text, err := unicode.UTF16(order, unicode.IgnoreBOM).NewDecoder().Bytes(data)
+	if err != nil {
+		return decodedText{Text: string(data), Encoding: encodingBinary}
+	}
+	return decodedText{Text: string(text), Encoding: encoding, BOM: bom}
+}
+
+// utf16Order guesses the byte order of UTF-16 text without a byte order mark from where its zero
+// bytes fall: mostly ASCII text has a zero in every other byte. It returns false for content that
+// does not look like UTF-16.
+func utf16Order(sniff []byte) (unicode.Endianness, bool) {
+	if len(sniff) < 2 || len(sniff)%2 != 0 && len(sniff) < binarySniffBytes {
+		return false, false
+	}
+	even, odd := 0, 0
+	for i := 0; i+1 < len(sniff); i += 2 {
+		if sniff[i] == 0 {
+			even++
+		}
+		if sniff[i+1] == 0 {
+			odd++
+		}
+	}
+	pairs := len(sniff) / 2
+	switch {
+	case odd*2 > pairs && even == 0:
+		return unicode.LittleEndian, true
+	case even*2 > pairs && odd == 0:
+		return unicode.BigEndian, true
+	}
+	return false, false
+}
+
+// The line endings files are reported to use.
+const (
+	lineEndingsLF    = "lf"
+	lineEndingsCRLF  = "crlf"
+	lineEndingsMixed = "mixed"
+)
+
+// detectLineEndings returns the line endings of text, or "" when it has a single line.
+func detectLineEndings(text string) string {
+	lines := strings.Count(text, "\n")
+	crlf := strings.Count(text, "\r\n")
+	switch {
+	case lines == 0:
+		return ""
+	case crlf == 0:
+		return lineEndingsLF
+	case crlf == lines:
+		return lineEndingsCRLF
+	}
+	return lineEndingsMixed
+}
+
+// textFormat is how the text of a file is stored.
+type textFormat struct {
+	Encoding    string
+	BOM         bool
+	LineEndings string
+}
+
+// encodeText is the reverse of reading a file: it stores text in format, so a file written back
+// keeps its encoding, byte order mark, and CRLF line endings even when it was read without them.
+// Files of mixed line endings are written as given.
+func encodeText(text string, format textFormat) ([]byte, error) {
+	text = strings.TrimPrefix(text, "\uFEFF")
+	if format.LineEndings == lineEndingsCRLF {
+		text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+	}
+
+	var data []byte
+	var err error
+	var bom []byte
+	switch format.Encoding {
+	case encodingUTF16LE:
+		data, err = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte(text))
+		bom = utf16LEBOM
+	case encodingUTF16BE:
+		data, err = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte(text))
+		bom = utf16BEBOM
+	case encodingWindows1252:
+		data, err = charmap.Windows1252.NewEncoder().Bytes([]byte(text))
+		if err != nil {
+			return nil, fmt.Errorf("text has characters that %s cannot store: %w", format.Encoding, err)
+		}
+	default:
+		data = []byte(text)
+		bom = utf8BOM
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error encoding text as %s: %w", format.Encoding, err)
+	}
+	if format.BOM {
+		data = append(append([]byte{}, bom...), data...)
+	}
+	return data, nil
+}
+
+// readOptions are how a read tool presents file contents.
+type readOptions struct {
+	normalizeLineEndings bool
+	stripBOM             bool
+}
+
+// readOptions returns the configured read options, overridden by the normalizeLineEndings and
+// stripBom arguments of a call.
+func (c *AzureDevOpsClient) readOptions(arguments map[string]interface{}) readOptions {
+	options := readOptions{
+		normalizeLineEndings: c.config.Files.NormalizeLineEndings,
+		stripBOM:             c.config.Files.StripBOM,
+	}
+	if value, ok := arguments["normalizeLineEndings"].(bool); ok {
+		options.normalizeLineEndings = value
+	}
+	if value, ok := arguments["stripBom"].(bool); ok {
+		options.stripBOM = value
+	}
+	return options
+}
+
+// text returns the content of a file as the options present it.
+func (o readOptions) text(file fileContent) string {
+	text := file.Content
+	if o.normalizeLineEndings && file.Encoding != encodingBinary {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	if file.BOM && !o.stripBOM {
+		text = "\uFEFF" + text
+	}
+	return text
+}
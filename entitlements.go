@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/memberentitlementmanagement"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultEntitlements = 100
+	maxEntitlements     = 1000
+)
+
+// userEntitlementsLocationID is the user entitlements search REST resource. The SDK's response
+// model lacks the continuation token, so pages are read with a plain client.
+var userEntitlementsLocationID = uuid.MustParse("387f832c-dbf2-4643-88e9-c1aa94dbb737")
+
+// entitlementLicenses maps the license names users know to the license IDs entitlement filters take.
+var entitlementLicenses = map[string]string{
+	"stakeholder": "Account-Stakeholder",
+	"basic":       "Account-Express",
+	"basic+test":  "Account-Advanced",
+}
+
+// entitlementPage is a page of user entitlements with the token of the next one.
+type entitlementPage struct {
+	Members           []memberentitlementmanagement.UserEntitlement `json:"members"`
+	ContinuationToken string                                        `json:"continuationToken"`
+}
+
+// searchEntitlements reads user entitlements matching an OData filter, up to top, following
+// continuation tokens. selection adds projects, extensions, or group rules to each.
+func (c *AzureDevOpsClient) searchEntitlements(ctx context.Context, filter, orderBy, selection string, top int) ([]memberentitlementmanagement.UserEntitlement, bool, error) {
+	entitlements := []memberentitlementmanagement.UserEntitlement{}
+	token := ""
+	for {
+		query := url.Values{}
+		if filter != "" {
+			query.Set("$filter", filter)
+		}
+		if orderBy != "" {
+			query.Set("$orderBy", orderBy)
+		}
+		if selection != "" {
+			query.Set("select", selection)
+		}
+		if token != "" {
+			query.Set("continuationToken", token)
+		}
+		response, err := c.entitlementClient.Send(ctx, http.MethodGet, userEntitlementsLocationID, "7.1-preview.3", nil, query, nil, "", "application/json", nil)
+		if err != nil {
+			log.Printf("Error searching user entitlements: %v", err)
+			return nil, false, fmt.Errorf("error searching user entitlements: %w", err)
+		}
+		var page entitlementPage
+		if err := c.entitlementClient.UnmarshalBody(response, &page); err != nil {
+			return nil, false, fmt.Errorf("error reading user entitlements: %w", err)
+		}
+		for _, entitlement := range page.Members {
+			if len(entitlements) == top {
+				return entitlements, true, nil
+			}
+			entitlements = append(entitlements, entitlement)
+		}
+		if page.ContinuationToken == "" || len(page.Members) == 0 {
+			return entitlements, false, nil
+		}
+		token = page.ContinuationToken
+	}
+}
+
+// entitlementSummary describes the access level of a user and when they last used it.
+func entitlementSummary(entitlement memberentitlementmanagement.UserEntitlement) map[string]interface{} {
+	entry := map[string]interface{}{}
+	if user := entitlement.User; user != nil {
+		entry["name"] = stringValue(user.DisplayName)
+		entry["principalName"] = stringValue(user.PrincipalName)
+		if email := stringValue(user.MailAddress); email != "" && !strings.EqualFold(email, stringValue(user.PrincipalName)) {
+			entry["email"] = email
+		}
+		entry["userType"] = stringValue(user.MetaType)
+		entry["origin"] = stringValue(user.Origin)
+	}
+	if entitlement.Id != nil {
+		entry["id"] = entitlement.Id.String()
+	}
+	if level := entitlement.AccessLevel; level != nil {
+		entry["license"] = stringValue(level.LicenseDisplayName)
+		if level.AccountLicenseType != nil {
+			entry["licenseType"] = string(*level.AccountLicenseType)
+		}
+		if level.LicensingSource != nil {
+			entry["licensingSource"] = string(*level.LicensingSource)
+		}
+		if level.AssignmentSource != nil {
+			entry["assignmentSource"] = string(*level.AssignmentSource)
+		}
+		if level.Status != nil {
+			entry["status"] = string(*level.Status)
+		}
+	}
+	// Users who never signed in have a zero last access date.
+	if entitlement.LastAccessedDate != nil && entitlement.LastAccessedDate.Time.Year() > 1 {
+		entry["lastAccessedDate"] = entitlement.LastAccessedDate.Time.Format(time.RFC3339)
+	} else {
+		entry["neverAccessed"] = true
+	}
+	if entitlement.DateCreated != nil {
+		entry["dateCreated"] = entitlement.DateCreated.Time.Format(time.RFC3339)
+	}
+	return entry
+}
+
+// listEntitlements lists the users of the organization with their access level and last access,
+// optionally only those with a license, of a user type, or inactive for inactiveDays, with a
+// count per license.
+func (c *AzureDevOpsClient) listEntitlements(ctx context.Context, license, userType string, inactiveDays, top int) (map[string]interface{}, error) {
+	filters := []string{}
+	if license != "" {
+		id, ok := entitlementLicenses[strings.ToLower(license)]
+		if !ok {
+			id = license
+		}
+		filters = append(filters, fmt.Sprintf("licenseId eq '%s'", id))
+	}
+	if userType != "" {
+		filters = append(filters, fmt.Sprintf("userType eq '%s'", strings.ToLower(userType)))
+	}
+
+	// Inactive users are found client side, so every matching user is read.
+	limit := top
+	if inactiveDays > 0 {
+		limit = maxEntitlements
+	}
+	entitlements, truncated, err := c.searchEntitlements(ctx, strings.Join(filters, " and "), "lastAccessed asc", "", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -inactiveDays)
+	users := []map[string]interface{}{}
+	licenses := map[string]int{}
+	for _, entitlement := range entitlements {
+		if inactiveDays > 0 && entitlement.LastAccessedDate != nil && entitlement.LastAccessedDate.Time.After(cutoff) {
+			continue
+		}
+		entry := entitlementSummary(entitlement)
+		licenses[fmt.Sprint(entry["license"])]++
+		if len(users) < top {
+			users = append(users, entry)
+		} else {
+			truncated = true
+		}
+	}
+
+	result := map[string]interface{}{
+		"users":     users,
+		"byLicense": licenses,
+	}
+	if inactiveDays > 0 {
+		result["inactiveSince"] = cutoff.Format("2006-01-02")
+	}
+	if truncated {
+		result["truncated"] = fmt.Sprintf("only %d users are listed; byLicense counts the users read", len(users))
+	}
+	return result, nil
+}
+
+// findEntitlement returns the entitlements of the users whose name or email contains user, with
+// the projects, groups, and extensions that give them access.
+func (c *AzureDevOpsClient) findEntitlement(ctx context.Context, user string) ([]map[string]interface{}, error) {
+	filter := fmt.Sprintf("name eq '%s'", strings.ReplaceAll(user, "'", "''"))
+	entitlements, _, err := c.searchEntitlements(ctx, filter, "", "Projects,Extensions,GroupRules", maxEntitlements)
+	if err != nil {
+		return nil, err
+	}
+	if len(entitlements) == 0 {
+		return nil, fmt.Errorf("no user matches %s", user)
+	}
+
+	results := []map[string]interface{}{}
+	for _, entitlement := range entitlements {
+		entry := entitlementSummary(entitlement)
+		if entitlement.ProjectEntitlements != nil {
+			projects := []map[string]interface{}{}
+			for _, project := range *entitlement.ProjectEntitlements {
+				item := map[string]interface{}{}
+				if project.ProjectRef != nil {
+					item["project"] = stringValue(project.ProjectRef.Name)
+				}
+				if project.Group != nil {
+					item["group"] = stringValue(project.Group.DisplayName)
+				}
+				if project.AssignmentSource != nil {
+					item["assignmentSource"] = string(*project.AssignmentSource)
+				}
+				projects = append(projects, item)
+			}
+			entry["projects"] = projects
+		}
+		if entitlement.GroupAssignments != nil {
+			groups := []string{}
+			for _, assignment := range *entitlement.GroupAssignments {
+				if assignment.Group != nil {
+					groups = append(groups, stringValue(assignment.Group.DisplayName))
+				}
+			}
+			sort.Strings(groups)
+			entry["groupRules"] = groups
+		}
+		if entitlement.Extensions != nil {
+			extensions := []string{}
+			for _, extension := range *entitlement.Extensions {
+				extensions = append(extensions, stringValue(extension.Name))
+			}
+			sort.Strings(extensions)
+			entry["extensions"] = extensions
+		}
+		results = append(results, entry)
+	}
+	return results, nil
+}
+
+func addEntitlementTools(s tools.Server, client *AzureDevOpsClient) {
+	listTool := mcp.NewTool("list_user_entitlements",
+		mcp.WithDescription("List the users of the organization with their access level (license), how it was assigned, and when they last signed in, least recently active first, with a count per license. Answers license usage questions such as who has a Basic license but has not signed in for 90 days"),
+		mcp.WithString("license",
+			mcp.Description("Only users with this license: stakeholder, basic, basic+test, or a license ID such as Account-Express"),
+		),
+		mcp.WithString("userType",
+			mcp.Description("Only members or guests"),
+			mcp.Enum("member", "guest"),
+		),
+		mcp.WithNumber("inactiveDays",
+			mcp.Description("Only users who have not signed in for this many days, or never"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description(fmt.Sprintf("Maximum number of users, at most %d", maxEntitlements)),
+			mcp.DefaultNumber(defaultEntitlements),
+		),
+	)
+
+	s.AddTool(listTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		license, _ := request.Params.Arguments["license"].(string)
+		userType, _ := request.Params.Arguments["userType"].(string)
+		inactiveDays := 0
+		if value, ok := request.Params.Arguments["inactiveDays"].(float64); ok && value > 0 {
+			inactiveDays = int(value)
+		}
+		top := defaultEntitlements
+		if value, ok := request.Params.Arguments["top"].(float64); ok && value > 0 {
+			top = int(value)
+		}
+		if top > maxEntitlements {
+			top = maxEntitlements
+		}
+
+		result, err := client.listEntitlements(ctx, license, userType, inactiveDays, top)
+		if err != nil {
+			log.Printf("Error listing user entitlements: %v", err)
+			return nil, fmt.Errorf("error listing user entitlements: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	findTool := mcp.NewTool("get_user_entitlement",
+		mcp.WithDescription("Get the access level and last sign-in of the users whose name or email contains a query, with the projects, group rules, and extensions that give them access"),
+		mcp.WithString("user",
+			mcp.Required(),
+			mcp.Description("Part of a user's display name or email"),
+		),
+	)
+
+	s.AddTool(findTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		user, ok := request.Params.Arguments["user"].(string)
+		if !ok {
+			log.Print("User must be a string")
+			return nil, fmt.Errorf("user must be a string")
+		}
+
+		result, err := client.findEntitlement(ctx, user)
+		if err != nil {
+			log.Printf("Error getting user entitlement: %v", err)
+			return nil, fmt.Errorf("error getting user entitlement: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/signify/sgfy-mcp/tools/workitems"
+)
+
+// Kinds of entity an azdo:// URI can name.
+const (
+	fileEntity        = "file"
+	commitEntity      = "commit"
+	pullRequestEntity = "pullrequest"
+	workItemEntity    = workitems.Entity
+	buildEntity       = "build"
+)
+
+// entityURI is an entity of the organization named by an azdo:// URI. Repository, Path, and Ref
+// are set for files and commits, and ID for pull requests, work items, and builds.
+type entityURI struct {
+	Kind       string
+	Project    string
+	Repository string
+	Path       string
+	Ref        string
+	ID         int
+}
+
+// String formats the URI: azdo://<organization>/<project>/<kind>/... with the repository and path
+// of a file (and ?ref=<ref> when it is read at one), the repository and SHA of a commit, or the ID
+// of a pull request, work item, or build. Pull request IDs are unique in the organization, so
+// their URIs leave out the repository.
+func (e entityURI) String(organization string) string {
+	segments := []string{url.PathEscape(organization), url.PathEscape(e.Project), e.Kind}
+	switch e.Kind {
+	case fileEntity:
+		segments = append(segments, url.PathEscape(e.Repository))
+		for _, segment := range strings.Split(strings.Trim(e.Path, "/"), "/") {
+			segments = append(segments, url.PathEscape(segment))
+		}
+	case commitEntity:
+		segments = append(segments, url.PathEscape(e.Repository), e.Ref)
+	default:
+		segments = append(segments, strconv.Itoa(e.ID))
+	}
+	uri := "azdo://" + strings.Join(segments, "/")
+	if e.Kind == fileEntity && e.Ref != "" {
+		uri += "?ref=" + url.QueryEscape(e.Ref)
+	}
+	return uri
+}
+
+// parseEntityURI reads a URI formatted by entityURI.String, which must name an entity of the
+// configured organization.
+func parseEntityURI(uri, organization string) (entityURI, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "azdo" {
+		return entityURI{}, fmt.Errorf("%s is not an azdo:// URI", uri)
+	}
+	if !strings.EqualFold(parsed.Host, organization) {
+		return entityURI{}, fmt.Errorf("%s is outside the %s organization", uri, organization)
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(segments) < 3 {
+		return entityURI{}, fmt.Errorf("%s does not name an entity", uri)
+	}
+
+	entity := entityURI{Project: segments[0], Kind: segments[1]}
+	rest := segments[2:]
+	switch entity.Kind {
+	case fileEntity:
+		if len(rest) < 2 {
+			return entityURI{}, fmt.Errorf("%s does not name a file of a repository", uri)
+		}
+		entity.Repository = rest[0]
+		entity.Path = "/" + strings.Join(rest[1:], "/")
+		entity.Ref = parsed.Query().Get("ref")
+	case commitEntity:
+		if len(rest) != 2 || !commitSHA.MatchString(rest[1]) {
+			return entityURI{}, fmt.Errorf("%s does not name a commit of a repository", uri)
+		}
+		entity.Repository = rest[0]
+		entity.Ref = rest[1]
+	case pullRequestEntity, workItemEntity, buildEntity:
+		id, err := strconv.Atoi(rest[0])
+		if len(rest) != 1 || err != nil || id <= 0 {
+			return entityURI{}, fmt.Errorf("%s does not end in a %s ID", uri, entity.Kind)
+		}
+		entity.ID = id
+	default:
+		return entityURI{}, fmt.Errorf("%s names an unknown kind of entity: %s", uri, entity.Kind)
+	}
+	return entity, nil
+}
+
+func (c *AzureDevOpsClient) fileURI(project, repo, path, ref string) string {
+	return entityURI{Kind: fileEntity, Project: project, Repository: repo, Path: path, Ref: ref}.String(c.config.AzureDevOps.Organization)
+}
+
+func (c *AzureDevOpsClient) commitURI(project, repo, commitID string) string {
+	return entityURI{Kind: commitEntity, Project: project, Repository: repo, Ref: commitID}.String(c.config.AzureDevOps.Organization)
+}
+
+func (c *AzureDevOpsClient) idURI(kind, project string, id int) string {
+	return entityURI{Kind: kind, Project: project, ID: id}.String(c.config.AzureDevOps.Organization)
+}
+
+// parseURI reads a URI that must name an entity of the given kind.
+func (c *AzureDevOpsClient) parseURI(uri, kind string) (entityURI, error) {
+	entity, err := parseEntityURI(uri, c.config.AzureDevOps.Organization)
+	if err != nil {
+		return entityURI{}, err
+	}
+	if entity.Kind != kind {
+		return entityURI{}, fmt.Errorf("%s names a %s, not a %s", uri, entity.Kind, kind)
+	}
+	return entity, nil
+}
+
+// uriArgument reads the uri argument of a tool call, which must name an entity of the given kind.
+// ok is false when the call has no uri.
+func (c *AzureDevOpsClient) uriArgument(request mcp.CallToolRequest, kind string) (entityURI, bool, error) {
+	uri, _ := request.Params.Arguments["uri"].(string)
+	if uri == "" {
+		return entityURI{}, false, nil
+	}
+	entity, err := c.parseURI(uri, kind)
+	return entity, err == nil, err
+}
+
+// idArgument reads the ID of an entity from the named number argument or, failing that, from the
+// uri argument.
+func (c *AzureDevOpsClient) idArgument(request mcp.CallToolRequest, name, kind string) (int, error) {
+	if id, ok := request.Params.Arguments[name].(float64); ok {
+		return int(id), nil
+	}
+	entity, ok, err := c.uriArgument(request, kind)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("%s must be a number, or pass uri", name)
+	}
+	return entity.ID, nil
+}
+
+// uriIDs reads the IDs of entities of a kind from their URIs.
+func (c *AzureDevOpsClient) uriIDs(uris []string, kind string) ([]int, error) {
+	ids := make([]int, 0, len(uris))
+	for _, uri := range uris {
+		entity, err := c.parseURI(uri, kind)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, entity.ID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	// webhookPath is where Azure DevOps service hooks POST their events.
+	webhookPath = "/webhooks/azure-devops"
+
+	// eventNotificationMethod is the MCP notification sent to a client when a watched event arrives.
+	eventNotificationMethod = "notifications/azure_devops/event"
+
+	// webhookUsername is the basic auth user name set on subscriptions that use the webhook secret.
+	webhookUsername = "sgfy-mcp"
+
+	maxWebhookBodySize     = 1 << 20
+	maxWatchesPerSession   = 20
+	defaultWatchTTLMinutes = 60
+	maxWatchTTLMinutes     = 24 * 60
+)
+
+// pullRequestBuildRef matches the merge ref a pull request validation build runs on.
+var pullRequestBuildRef = regexp.MustCompile(`^refs/pull/(\d+)/merge$`)
+
+// serviceHookEvent is the payload Azure DevOps POSTs for a service hook event.
+type serviceHookEvent struct {
+	ID          string `json:"id"`
+	EventType   string `json:"eventType"`
+	PublisherID string `json:"publisherId"`
+	Message     struct {
+		Text     string `json:"text"`
+		Markdown string `json:"markdown"`
+	} `json:"message"`
+	DetailedMessage struct {
+		Markdown string `json:"markdown"`
+	} `json:"detailedMessage"`
+	Resource    map[string]interface{} `json:"resource"`
+	CreatedDate string                 `json:"createdDate"`
+}
+
+// eventWatch routes events matching its filters to one MCP client session. Zero-valued filters
+// match everything.
+type eventWatch struct {
+	ID            string    `json:"id"`
+	EventType     string    `json:"eventType,omitempty"`
+	Repository    string    `json:"repository,omitempty"`
+	PullRequestID int       `json:"pullRequestId,omitempty"`
+	BuildID       int       `json:"buildId,omitempty"`
+	WorkItemID    int       `json:"workItemId,omitempty"`
+	Expires       time.Time `json:"expires"`
+
+	session server.ClientSession
+}
+
+// eventHub receives service hook events over HTTP and forwards them to the sessions watching them.
+// Watches hold the live sessions of this process and are not shared through the state store, so
+// only a single replica of the server is supported: a webhook that reaches one replica never
+// notifies watchers on another.
+type eventHub struct {
+	secret string
+
+	mu      sync.Mutex
+	watches map[string]*eventWatch
+}
+
+func newEventHub(secret string) *eventHub {
+	return &eventHub{
+		secret:  secret,
+		watches: map[string]*eventWatch{},
+	}
+}
+
+// resourceNumber reads a numeric field from a nested path of a JSON object.
+func resourceNumber(resource map[string]interface{}, path ...string) int {
+	var current interface{} = resource
+	for _, key := range path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return 0
+		}
+		current = object[key]
+	}
+	switch v := current.(type) {
+	case float64:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+func resourceString(resource map[string]interface{}, path ...string) string {
+	var current interface{} = resource
+	for _, key := range path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		current = object[key]
+	}
+	s, _ := current.(string)
+	return s
+}
+
+// pullRequestID returns the pull request an event concerns: the pull request itself, the pull
+// request a comment was made on, or the pull request a validation build ran for.
+func (e *serviceHookEvent) pullRequestID() int {
+	if id := resourceNumber(e.Resource, "pullRequestId"); id != 0 {
+		return id
+	}
+	if id := resourceNumber(e.Resource, "pullRequest", "pullRequestId"); id != 0 {
+		return id
+	}
+	if id := resourceNumber(e.Resource, "triggerInfo", "pr.number"); id != 0 {
+		return id
+	}
+	if match := pullRequestBuildRef.FindStringSubmatch(resourceString(e.Resource, "sourceBranch")); match != nil {
+		id, _ := strconv.Atoi(match[1])
+		return id
+	}
+	return 0
+}
+
+func (e *serviceHookEvent) buildID() int {
+	if strings.HasPrefix(e.EventType, "build.") {
+		return resourceNumber(e.Resource, "id")
+	}
+	return 0
+}
+
+func (e *serviceHookEvent) workItemID() int {
+	if !strings.HasPrefix(e.EventType, "workitem.") {
+		return 0
+	}
+	if id := resourceNumber(e.Resource, "workItemId"); id != 0 {
+		return id
+	}
+	return resourceNumber(e.Resource, "id")
+}
+
+func (e *serviceHookEvent) repository() (string, string) {
+	for _, path := range [][]string{{"repository"}, {"pullRequest", "repository"}} {
+		name := resourceString(e.Resource, append(path, "name")...)
+		id := resourceString(e.Resource, append(path, "id")...)
+		if name != "" || id != "" {
+			return name, id
+		}
+	}
+	return "", ""
+}
+
+func (w *eventWatch) matches(e *serviceHookEvent) bool {
+	if w.EventType != "" && !strings.HasPrefix(e.EventType, w.EventType) {
+		return false
+	}
+	if w.Repository != "" {
+		name, id := e.repository()
+		if !strings.EqualFold(name, w.Repository) && !strings.EqualFold(id, w.Repository) {
+			return false
+		}
+	}
+	if w.PullRequestID != 0 && e.pullRequestID() != w.PullRequestID {
+		return false
+	}
+	if w.BuildID != 0 && e.buildID() != w.BuildID {
+		return false
+	}
+	if w.WorkItemID != 0 && e.workItemID() != w.WorkItemID {
+		return false
+	}
+	return true
+}
+
+// add registers a watch for the session, dropping expired watches first.
+func (h *eventHub) add(watch *eventWatch) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pruneLocked(time.Now())
+	count := 0
+	for _, existing := range h.watches {
+		if existing.session.SessionID() == watch.session.SessionID() {
+			count++
+		}
+	}
+	if count >= maxWatchesPerSession {
+		return fmt.Errorf("at most %d event watches are allowed per session", maxWatchesPerSession)
+	}
+
+	h.watches[watch.ID] = watch
+	return nil
+}
+
+func (h *eventHub) remove(sessionID, id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	watch, ok := h.watches[id]
+	if !ok || watch.session.SessionID() != sessionID {
+		return false
+	}
+	delete(h.watches, id)
+	return true
+}
+
+func (h *eventHub) list(sessionID string) []*eventWatch {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pruneLocked(time.Now())
+	results := []*eventWatch{}
+	for _, watch := range h.watches {
+		if watch.session.SessionID() == sessionID {
+			results = append(results, watch)
+		}
+	}
+	return results
+}
+
+// pruneLocked drops expired watches. Sessions are not told when their client disconnects, so
+// expiry is what eventually releases watches of clients that went away.
+func (h *eventHub) pruneLocked(now time.Time) {
+	for id, watch := range h.watches {
+		if now.After(watch.Expires) {
+			delete(h.watches, id)
+		}
+	}
+}
+
+// dispatch sends an event to every session with a matching watch.
+func (h *eventHub) dispatch(event *serviceHookEvent) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pruneLocked(time.Now())
+	delivered := 0
+	for _, watch := range h.watches {
+		if !watch.matches(event) || !watch.session.Initialized() {
+			continue
+		}
+
+		params := map[string]interface{}{
+			"watchId":         watch.ID,
+			"eventId":         event.ID,
+			"eventType":       event.EventType,
+			"message":         event.Message.Text,
+			"detailedMessage": event.DetailedMessage.Markdown,
+			"createdDate":     event.CreatedDate,
+		}
+		if id := event.pullRequestID(); id != 0 {
+			params["pullRequestId"] = id
+		}
+		if id := event.buildID(); id != 0 {
+			params["buildId"] = id
+			params["result"] = resourceString(event.Resource, "result")
+		}
+		if id := event.workItemID(); id != 0 {
+			params["workItemId"] = id
+		}
+		if status := resourceString(event.Resource, "status"); status != "" {
+			params["status"] = status
+		}
+
+		notification := mcp.JSONRPCNotification{
+			JSONRPC: mcp.JSONRPC_VERSION,
+			Notification: mcp.Notification{
+				Method: eventNotificationMethod,
+				Params: mcp.NotificationParams{AdditionalFields: params},
+			},
+		}
+		select {
+		case watch.session.NotificationChannel() <- notification:
+			delivered++
+		default:
+			log.Printf("Dropping event %s for session %s: notification channel full", event.ID, watch.session.SessionID())
+		}
+	}
+	return delivered
+}
+
+// ServeHTTP accepts service hook events. Azure DevOps must send the webhook secret as the basic
+// auth password, which create_service_hook_subscription sets up with useWebhookSecret.
+func (h *eventHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, password, ok := r.BasicAuth()
+	if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(h.secret)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var event serviceHookEvent
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBodySize)).Decode(&event); err != nil {
+		log.Printf("Error decoding service hook event: %v", err)
+		http.Error(w, "Invalid event payload", http.StatusBadRequest)
+		return
+	}
+
+	delivered := h.dispatch(&event)
+	log.Printf("Received %s event %s, notified %d watches", event.EventType, event.ID, delivered)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func addEventTools(s tools.Server, hub *eventHub) {
+	watchTool := mcp.NewTool("watch_events",
+		mcp.WithDescription("Get notified when an Azure DevOps event arrives, e.g. when a pull request's build finishes, without polling. "+
+			"Events reach this server through a service hook subscription on its webhook endpoint; matching events are sent to this session as "+
+			eventNotificationMethod+" notifications"),
+		mcp.WithString("eventType",
+			mcp.Description("Event type or prefix, e.g. build.complete, git.pullrequest, or workitem.updated"),
+		),
+		mcp.WithString("repository",
+			mcp.Description("Only events for this repository name or ID"),
+		),
+		mcp.WithNumber("pullRequestId",
+			mcp.Description("Only events for this pull request, including its comments and validation builds"),
+		),
+		mcp.WithNumber("buildId",
+			mcp.Description("Only events for this build"),
+		),
+		mcp.WithNumber("workItemId",
+			mcp.Description("Only events for this work item"),
+		),
+		mcp.WithNumber("ttlMinutes",
+			mcp.Description(fmt.Sprintf("Minutes until the watch expires, at most %d", maxWatchTTLMinutes)),
+			mcp.DefaultNumber(defaultWatchTTLMinutes),
+		),
+	)
+
+	s.AddTool(watchTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil {
+			return nil, fmt.Errorf("watching events requires a client session")
+		}
+
+		ttl := defaultWatchTTLMinutes
+		if v, ok := request.Params.Arguments["ttlMinutes"].(float64); ok && v > 0 {
+			ttl = int(v)
+		}
+		if ttl > maxWatchTTLMinutes {
+			ttl = maxWatchTTLMinutes
+		}
+
+		watch := &eventWatch{
+			ID:      uuid.NewString(),
+			Expires: time.Now().Add(time.Duration(ttl) * time.Minute),
+			session: session,
+		}
+		watch.EventType, _ = request.Params.Arguments["eventType"].(string)
+		watch.Repository, _ = request.Params.Arguments["repository"].(string)
+		if v, ok := request.Params.Arguments["pullRequestId"].(float64); ok {
+			watch.PullRequestID = int(v)
+		}
+		if v, ok := request.Params.Arguments["buildId"].(float64); ok {
+			watch.BuildID = int(v)
+		}
+		if v, ok := request.Params.Arguments["workItemId"].(float64); ok {
+			watch.WorkItemID = int(v)
+		}
+
+		if err := hub.add(watch); err != nil {
+			log.Printf("Error adding event watch: %v", err)
+			return nil, fmt.Errorf("error adding event watch: %w", err)
+		}
+
+		return tools.JSONResult(watch)
+	})
+
+	listWatchesTool := mcp.NewTool("list_event_watches",
+		mcp.WithDescription("List this session's active event watches"),
+	)
+
+	s.AddTool(listWatchesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil {
+			return nil, fmt.Errorf("listing event watches requires a client session")
+		}
+
+		return tools.JSONResult(hub.list(session.SessionID()))
+	})
+
+	unwatchTool := mcp.NewTool("unwatch_events",
+		mcp.WithDescription("Stop an event watch"),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("Watch ID returned by watch_events"),
+		),
+	)
+
+	s.AddTool(unwatchTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, ok := request.Params.Arguments["id"].(string)
+		if !ok {
+			log.Print("ID must be a string")
+			return nil, fmt.Errorf("id must be a string")
+		}
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil || !hub.remove(session.SessionID(), id) {
+			return nil, fmt.Errorf("event watch not found: %s", id)
+		}
+
+		return mcp.NewToolResultText(fmt.Sprintf("Stopped event watch %s", id)), nil
+	})
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/projectanalysis"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	// maxExploreReadme caps the README text included in an overview.
+	maxExploreReadme = 8000
+	exploreCommits   = 10
+	explorePRs       = 10
+	exploreLanguages = 5
+)
+
+// isReadme reports whether a file name is a README, e.g. README.md or readme.txt.
+func isReadme(name string) bool {
+	name = strings.ToLower(name)
+	return name == "readme" || strings.HasPrefix(name, "readme.")
+}
+
+// exploreTree lists the top level of a repository at a ref, leaving out ignored files and folders,
+// and returns the path of its README, if it has one.
+func (c *AzureDevOpsClient) exploreTree(ctx context.Context, repoID, ref string, ignore pathPatterns) ([]map[string]interface{}, string, error) {
+	items, err := c.itemsAt(ctx, c.config.AzureDevOps.Project, repoID, "/", ref, git.VersionControlRecursionTypeValues.OneLevel)
+	if err != nil {
+		log.Printf("Error listing repository root: %v", err)
+		return nil, "", fmt.Errorf("error listing repository root: %w", err)
+	}
+
+	tree := []map[string]interface{}{}
+	readme := ""
+	for _, item := range items {
+		itemPath := stringValue(item.Path)
+		if itemPath == "/" {
+			continue
+		}
+		isFolder := item.IsFolder != nil && *item.IsFolder
+		if ignore.matchItem(itemPath, isFolder) {
+			continue
+		}
+		tree = append(tree, map[string]interface{}{
+			"path":     itemPath,
+			"isFolder": isFolder,
+		})
+		if !isFolder && readme == "" && isReadme(path.Base(itemPath)) {
+			readme = itemPath
+		}
+	}
+	return tree, readme, nil
+}
+
+func float64Value(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
+
+// repositoryLanguages returns the largest languages of a repository from the project's language
+// analytics.
+func (c *AzureDevOpsClient) repositoryLanguages(ctx context.Context, repoID string) ([]map[string]interface{}, error) {
+	analytics, err := c.projectAnalysisClient.GetProjectLanguageAnalytics(ctx, projectanalysis.GetProjectLanguageAnalyticsArgs{
+		Project: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting language analytics: %v", err)
+		return nil, fmt.Errorf("error getting language analytics: %w", err)
+	}
+
+	languages := []map[string]interface{}{}
+	if analytics.RepositoryLanguageAnalytics == nil {
+		return languages, nil
+	}
+	for _, repo := range *analytics.RepositoryLanguageAnalytics {
+		if repo.Id == nil || !strings.EqualFold(repo.Id.String(), repoID) || repo.LanguageBreakdown == nil {
+			continue
+		}
+		breakdown := *repo.LanguageBreakdown
+		sort.SliceStable(breakdown, func(i, j int) bool {
+			return float64Value(breakdown[i].LanguagePercentage) > float64Value(breakdown[j].LanguagePercentage)
+		})
+		for _, language := range breakdown {
+			if len(languages) == exploreLanguages {
+				break
+			}
+			entry := map[string]interface{}{
+				"name": stringValue(language.Name),
+			}
+			if language.LanguagePercentage != nil {
+				entry["percentage"] = *language.LanguagePercentage
+			}
+			if language.Files != nil {
+				entry["files"] = *language.Files
+			}
+			languages = append(languages, entry)
+		}
+	}
+	return languages, nil
+}
+
+// exploreRepository gathers a starting overview of a repository: its README, top-level tree,
+// primary languages, recent commits, and active pull requests. Each part is fetched
+// independently and a failing part is reported under errors rather than failing the overview.
+// Paths matching ignore, or search.ignore, are left out of the tree.
+func (c *AzureDevOpsClient) exploreRepository(ctx context.Context, repoName, ref string, ignore []string) (map[string]interface{}, error) {
+	ignorePatterns, err := c.ignorePatterns(ignore)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+
+	result := map[string]interface{}{
+		"repository":    stringValue(repo.Name),
+		"defaultBranch": stringValue(repo.DefaultBranch),
+		"webUrl":        stringValue(repo.WebUrl),
+	}
+	if ref != "" {
+		result["ref"] = ref
+	}
+	errors := map[string]string{}
+
+	tree, readmePath, err := c.exploreTree(ctx, repoID, ref, ignorePatterns)
+	if err != nil {
+		errors["tree"] = err.Error()
+	} else {
+		result["tree"] = tree
+	}
+
+	if readmePath != "" {
+		file, err := c.fileAt(ctx, c.config.AzureDevOps.Project, repoID, readmePath, ref)
+		if err != nil {
+			log.Printf("Error getting README: %v", err)
+			errors["readme"] = err.Error()
+		} else {
+			content := file.Content
+			readme := map[string]interface{}{
+				"path":    readmePath,
+				"content": content,
+			}
+			if len(content) > maxExploreReadme {
+				readme["content"] = content[:maxExploreReadme]
+				readme["truncated"] = true
+			}
+			result["readme"] = readme
+		}
+	}
+
+	languages, err := c.repositoryLanguages(ctx, repoID)
+	if err != nil {
+		errors["languages"] = err.Error()
+	} else {
+		result["languages"] = languages
+	}
+
+	top := exploreCommits
+	commits, err := c.gitClient.GetCommits(ctx, git.GetCommitsArgs{
+		RepositoryId: &repoID,
+		Project:      &c.config.AzureDevOps.Project,
+		SearchCriteria: &git.GitQueryCommitsCriteria{
+			Top:         &top,
+			ItemVersion: versionDescriptor(ref),
+		},
+	})
+	if err != nil {
+		log.Printf("Error getting commits: %v", err)
+		errors["recentCommits"] = err.Error()
+	} else {
+		recent := []map[string]interface{}{}
+		for _, commit := range *commits {
+			entry := map[string]interface{}{
+				"commitId": stringValue(commit.CommitId),
+				"comment":  stringValue(commit.Comment),
+				"webUrl":   c.links.commit(c.config.AzureDevOps.Project, repoName, stringValue(commit.CommitId)),
+				"uri":      c.commitURI(c.config.AzureDevOps.Project, repoName, stringValue(commit.CommitId)),
+			}
+			if commit.Author != nil {
+				entry["author"] = stringValue(commit.Author.Name)
+				if commit.Author.Date != nil {
+					entry["date"] = commit.Author.Date.Time.Format(time.RFC3339)
+				}
+			}
+			recent = append(recent, entry)
+		}
+		result["recentCommits"] = recent
+	}
+
+	status := git.PullRequestStatusValues.Active
+	topPRs := explorePRs
+	pullRequests, err := c.gitClient.GetPullRequests(ctx, git.GetPullRequestsArgs{
+		RepositoryId:   &repoID,
+		Project:        &c.config.AzureDevOps.Project,
+		SearchCriteria: &git.GitPullRequestSearchCriteria{Status: &status},
+		Top:            &topPRs,
+	})
+	if err != nil {
+		log.Printf("Error getting pull requests: %v", err)
+		errors["activePullRequests"] = err.Error()
+	} else {
+		active := []map[string]interface{}{}
+		for _, pr := range *pullRequests {
+			entry := map[string]interface{}{
+				"title":        stringValue(pr.Title),
+				"sourceBranch": stringValue(pr.SourceRefName),
+				"targetBranch": stringValue(pr.TargetRefName),
+				"isDraft":      pr.IsDraft != nil && *pr.IsDraft,
+			}
+			if pr.PullRequestId != nil {
+				entry["id"] = *pr.PullRequestId
+			}
+			if pr.CreatedBy != nil {
+				entry["createdBy"] = stringValue(pr.CreatedBy.DisplayName)
+			}
+			if pr.CreationDate != nil {
+				entry["creationDate"] = pr.CreationDate.Time.Format("2006-01-02")
+			}
+			active = append(active, entry)
+		}
+		result["activePullRequests"] = active
+	}
+
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addExploreTools(s tools.Server, client *AzureDevOpsClient) {
+	exploreTool := mcp.NewTool("explore_repository",
+		mcp.WithDescription("Get a starting overview of a repository in one call: README, top-level files and folders, primary languages, recent commits, and active pull requests"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("ref",
+			mcp.Description("Branch name, refs/tags/<tag>, or commit SHA for the README, tree, and commits; defaults to the default branch"),
+		),
+		mcp.WithArray("ignore",
+			mcp.Description(ignoreDescription),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+
+	s.AddTool(exploreTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		ref, _ := request.Params.Arguments["ref"].(string)
+		ignore, err := tools.StringSliceArgument(request.Params.Arguments, "ignore")
+		if err != nil {
+			log.Printf("Invalid ignore: %v", err)
+			return nil, err
+		}
+
+		result, err := client.exploreRepository(ctx, repo, ref, ignore)
+		if err != nil {
+			log.Printf("Error exploring repository: %v", err)
+			return nil, fmt.Errorf("error exploring repository: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
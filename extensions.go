@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/extensionmanagement"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// extensionFlags splits the comma separated flags Azure DevOps reports for an extension.
+func extensionFlags(flags string) []string {
+	list := []string{}
+	for _, flag := range strings.Split(flags, ",") {
+		if flag = strings.TrimSpace(flag); flag != "" && flag != "none" {
+			list = append(list, flag)
+		}
+	}
+	return list
+}
+
+// listExtensions describes the marketplace extensions installed in the organization for security
+// and license audits: publisher, version, state, and the scopes each was granted. Built-in
+// extensions, which every organization has, are left out unless includeBuiltIn is set.
+func (c *AzureDevOpsClient) listExtensions(ctx context.Context, includeBuiltIn bool) (map[string]interface{}, error) {
+	includeDisabled := true
+	includeErrors := true
+	includeIssues := true
+	extensions, err := c.extensionClient.GetInstalledExtensions(ctx, extensionmanagement.GetInstalledExtensionsArgs{
+		IncludeDisabledExtensions: &includeDisabled,
+		IncludeErrors:             &includeErrors,
+		IncludeInstallationIssues: &includeIssues,
+	})
+	if err != nil {
+		log.Printf("Error getting installed extensions: %v", err)
+		return nil, fmt.Errorf("error getting installed extensions: %w", err)
+	}
+
+	has := func(flags []string, flag string) bool {
+		for _, f := range flags {
+			if f == flag {
+				return true
+			}
+		}
+		return false
+	}
+	list := []map[string]interface{}{}
+	publishers := map[string]int{}
+	disabled := 0
+	for _, extension := range *extensions {
+		flags := []string{}
+		if extension.Flags != nil {
+			flags = extensionFlags(string(*extension.Flags))
+		}
+		state := []string{}
+		if extension.InstallState != nil && extension.InstallState.Flags != nil {
+			state = extensionFlags(string(*extension.InstallState.Flags))
+		}
+		builtIn := has(flags, string(extensionmanagement.ExtensionFlagsValues.BuiltIn)) || has(state, string(extensionmanagement.ExtensionStateFlagsValues.BuiltIn))
+		if builtIn && !includeBuiltIn {
+			continue
+		}
+
+		publisher := stringValue(extension.PublisherId)
+		id := publisher + "." + stringValue(extension.ExtensionId)
+		entry := map[string]interface{}{
+			"id":             id,
+			"name":           stringValue(extension.ExtensionName),
+			"publisherId":    publisher,
+			"publisherName":  stringValue(extension.PublisherName),
+			"version":        stringValue(extension.Version),
+			"marketplaceUrl": "https://marketplace.visualstudio.com/items?itemName=" + id,
+			"trusted":        has(flags, string(extensionmanagement.ExtensionFlagsValues.Trusted)),
+			"disabled":       has(state, string(extensionmanagement.ExtensionStateFlagsValues.Disabled)),
+		}
+		if builtIn {
+			entry["builtIn"] = true
+		}
+		if len(state) > 0 {
+			entry["state"] = state
+		}
+		if extension.Scopes != nil {
+			scopes := append([]string{}, *extension.Scopes...)
+			sort.Strings(scopes)
+			entry["scopes"] = scopes
+		}
+		if extension.LastPublished != nil {
+			entry["lastPublished"] = extension.LastPublished.Time.Format(time.RFC3339)
+		}
+		if extension.InstallState != nil {
+			if extension.InstallState.LastUpdated != nil {
+				entry["lastUpdated"] = extension.InstallState.LastUpdated.Time.Format(time.RFC3339)
+			}
+			if extension.InstallState.InstallationIssues != nil {
+				issues := []string{}
+				for _, issue := range *extension.InstallState.InstallationIssues {
+					issues = append(issues, stringValue(issue.Message))
+				}
+				if len(issues) > 0 {
+					entry["issues"] = issues
+				}
+			}
+		}
+		if entry["disabled"] == true {
+			disabled++
+		}
+		publishers[publisher]++
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i]["id"].(string) < list[j]["id"].(string) })
+
+	return map[string]interface{}{
+		"extensions": list,
+		"count":      len(list),
+		"disabled":   disabled,
+		"publishers": publishers,
+	}, nil
+}
+
+func addExtensionTools(s tools.Server, client *AzureDevOpsClient) {
+	extensionsTool := mcp.NewTool("list_extensions",
+		mcp.WithDescription("List the marketplace extensions installed in the organization, for security and license audits: publisher, version, whether each is disabled or from a trusted publisher, the scopes it was granted, and installation issues"),
+		mcp.WithBoolean("includeBuiltIn",
+			mcp.Description("Also list the built-in extensions every organization has"),
+		),
+	)
+
+	s.AddTool(extensionsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		includeBuiltIn, _ := request.Params.Arguments["includeBuiltIn"].(bool)
+
+		result, err := client.listExtensions(ctx, includeBuiltIn)
+		if err != nil {
+			log.Printf("Error listing extensions: %v", err)
+			return nil, fmt.Errorf("error listing extensions: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
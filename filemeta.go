@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"log"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+)
+
+const (
+	// sizedSearchResults caps the search results whose size is looked up when it is not cached;
+	// each costs a request.
+	sizedSearchResults = 50
+	// sizeLookups caps the size lookups of one search made at once.
+	sizeLookups = 8
+)
+
+// extensionLanguages names the language of common file extensions.
+var extensionLanguages = map[string]string{
+	".go": "Go", ".cs": "C#", ".csx": "C#", ".fs": "F#", ".fsx": "F#", ".vb": "Visual Basic",
+	".java": "Java", ".kt": "Kotlin", ".kts": "Kotlin", ".scala": "Scala", ".groovy": "Groovy", ".gradle": "Groovy",
+	".py": "Python", ".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript", ".vue": "Vue", ".svelte": "Svelte",
+	".rb": "Ruby", ".rs": "Rust", ".c": "C", ".h": "C", ".cpp": "C++", ".cc": "C++", ".cxx": "C++", ".hpp": "C++",
+	".m": "Objective-C", ".swift": "Swift", ".dart": "Dart", ".php": "PHP", ".pl": "Perl", ".lua": "Lua", ".r": "R",
+	".sql": "SQL", ".ps1": "PowerShell", ".psm1": "PowerShell", ".sh": "Shell", ".bash": "Shell", ".bat": "Batch", ".cmd": "Batch",
+	".md": "Markdown", ".rst": "reStructuredText", ".txt": "Text",
+	".yaml": "YAML", ".yml": "YAML", ".json": "JSON", ".xml": "XML", ".toml": "TOML", ".ini": "INI", ".proto": "Protocol Buffers",
+	".html": "HTML", ".cshtml": "Razor", ".razor": "Razor", ".css": "CSS", ".scss": "SCSS", ".less": "Less",
+	".tf": "Terraform", ".bicep": "Bicep", ".csproj": "MSBuild", ".vbproj": "MSBuild", ".props": "MSBuild", ".targets": "MSBuild",
+	".sln": "Visual Studio", ".graphql": "GraphQL",
+}
+
+// fileNameLanguages names the language of well-known files without a telling extension.
+var fileNameLanguages = map[string]string{
+	"dockerfile": "Dockerfile", "makefile": "Makefile", "jenkinsfile": "Groovy", "cmakelists.txt": "CMake",
+	"gemfile": "Ruby", "rakefile": "Ruby", "go.mod": "Go Module", ".gitignore": "Ignore List", ".editorconfig": "EditorConfig",
+}
+
+// fileLanguage returns the language of a file from its name, or "" when it is not known.
+func fileLanguage(filePath string) string {
+	name := strings.ToLower(path.Base(filePath))
+	if language, ok := fileNameLanguages[name]; ok {
+		return language
+	}
+	if strings.HasPrefix(name, "dockerfile.") {
+		return "Dockerfile"
+	}
+	return extensionLanguages[path.Ext(name)]
+}
+
+// blobSize returns the size of a Git object: from the content cache when it holds it, or else
+// from the object's metadata.
+func (c *AzureDevOpsClient) blobSize(ctx context.Context, project, repoID, objectID string) (uint64, error) {
+	if content, ok := c.cache.get("blob/" + objectID); ok {
+		return uint64(len(content)), nil
+	}
+	blob, err := c.gitClient.GetBlob(ctx, git.GetBlobArgs{
+		RepositoryId: &repoID,
+		Project:      &project,
+		Sha1:         &objectID,
+		Download:     &[]bool{false}[0],
+	})
+	if err != nil {
+		return 0, err
+	}
+	if blob.Size == nil {
+		return 0, nil
+	}
+	return *blob.Size, nil
+}
+
+// searchHit is a code search result whose size is to be looked up.
+type searchHit struct {
+	result    map[string]interface{}
+	project   string
+	repoID    string
+	contentID string
+}
+
+// addSizes sets the size of the first sizedSearchResults hits, and of later ones whose content is
+// cached. A size that cannot be looked up is left out rather than failing the search.
+func (c *AzureDevOpsClient) addSizes(ctx context.Context, hits []searchHit) {
+	var wg sync.WaitGroup
+	lookups := make(chan struct{}, sizeLookups)
+	for i, hit := range hits {
+		if hit.contentID == "" || hit.repoID == "" {
+			continue
+		}
+		if i >= sizedSearchResults {
+			if content, ok := c.cache.get("blob/" + hit.contentID); ok {
+				hit.result["size"] = uint64(len(content))
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(hit searchHit) {
+			defer wg.Done()
+			lookups <- struct{}{}
+			defer func() { <-lookups }()
+			size, err := c.blobSize(ctx, hit.project, hit.repoID, hit.contentID)
+			if err != nil {
+				log.Printf("Error getting size of %s: %v", hit.result["path"], err)
+				return
+			}
+			hit.result["size"] = size
+		}(hit)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/signify/sgfy-mcp/tools"
+	"github.com/signify/sgfy-mcp/tools/pipelines"
+	"github.com/signify/sgfy-mcp/tools/workitems"
+)
+
+// The client serves the tool groups that live in packages of their own through the methods below,
+// which expose what each group's Client interface asks for.
+var (
+	_ tools.Writer     = (*AzureDevOpsClient)(nil)
+	_ workitems.Client = (*AzureDevOpsClient)(nil)
+	_ pipelines.Client = (*AzureDevOpsClient)(nil)
+)
+
+func (c *AzureDevOpsClient) AllowWrites() bool { return c.config.Server.AllowWrites }
+
+func (c *AzureDevOpsClient) DryRun(request mcp.CallToolRequest) bool { return c.dryRun(request) }
+
+func (c *AzureDevOpsClient) WriteHandler(tool mcp.Tool, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return writeHandler(c, tool, handler)
+}
+
+func (c *AzureDevOpsClient) IDArgument(request mcp.CallToolRequest, name, kind string) (int, error) {
+	return c.idArgument(request, name, kind)
+}
+
+func (c *AzureDevOpsClient) URIIDs(uris []string, kind string) ([]int, error) {
+	return c.uriIDs(uris, kind)
+}
+
+func (c *AzureDevOpsClient) ListWorkItemTypes(ctx context.Context) ([]map[string]interface{}, error) {
+	return c.listWorkItemTypes(ctx)
+}
+
+func (c *AzureDevOpsClient) WorkItemTypeFields(ctx context.Context, workItemType string) ([]workitems.TypeField, error) {
+	return c.getWorkItemTypeFields(ctx, workItemType)
+}
+
+func (c *AzureDevOpsClient) WorkItemTypeStates(ctx context.Context, workItemType string) ([]workitems.TypeState, error) {
+	return c.getWorkItemTypeStates(ctx, workItemType)
+}
+
+func (c *AzureDevOpsClient) QueryWorkItemIDs(ctx context.Context, wiql string) ([]int, error) {
+	return c.queryWorkItemIDs(ctx, wiql)
+}
+
+func (c *AzureDevOpsClient) BulkUpdateWorkItems(ctx context.Context, ids []int, fields map[string]interface{}, state string, dryRun bool) ([]map[string]interface{}, error) {
+	return c.bulkUpdateWorkItems(ctx, ids, fields, state, dryRun)
+}
+
+func (c *AzureDevOpsClient) AddWorkItemComment(ctx context.Context, id int, text string, dryRun bool) (map[string]interface{}, error) {
+	return c.addWorkItemComment(ctx, id, text, dryRun)
+}
+
+func (c *AzureDevOpsClient) PipelineTriggers(ctx context.Context, pipeline string) (map[string]interface{}, error) {
+	return c.pipelineTriggers(ctx, pipeline)
+}
+
+func (c *AzureDevOpsClient) PipelineInputs(ctx context.Context, pipeline, branch string, runParameters, runVariables map[string]interface{}) (map[string]interface{}, error) {
+	return c.pipelineInputs(ctx, pipeline, branch, runParameters, runVariables)
+}
+
+func (c *AzureDevOpsClient) CreatePipeline(ctx context.Context, name, repoName, yamlPath, folder, defaultBranch string, variables map[string]*build.BuildDefinitionVariable, dryRun bool) (map[string]interface{}, error) {
+	return c.createPipeline(ctx, name, repoName, yamlPath, folder, defaultBranch, variables, dryRun)
+}
+
+func (c *AzureDevOpsClient) UpdatePipeline(ctx context.Context, pipeline, name, folder, yamlPath, defaultBranch string, variables map[string]*build.BuildDefinitionVariable, dryRun bool) (map[string]interface{}, error) {
+	return c.updatePipeline(ctx, pipeline, name, folder, yamlPath, defaultBranch, variables, dryRun)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxGroupExpansionDepth limits how many levels of nested groups are expanded when listing members.
+const maxGroupExpansionDepth = 5
+
+// maxSubjectLookupBatch is the largest number of descriptors resolved per Graph lookup call.
+const maxSubjectLookupBatch = 100
+
+func (c *AzureDevOpsClient) projectScopeDescriptor(ctx context.Context) (string, error) {
+	project, err := c.coreClient.GetProject(ctx, core.GetProjectArgs{
+		ProjectId: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting project: %v", err)
+		return "", fmt.Errorf("error getting project %s: %w", c.config.AzureDevOps.Project, err)
+	}
+
+	descriptor, err := c.graphClient.GetDescriptor(ctx, graph.GetDescriptorArgs{
+		StorageKey: project.Id,
+	})
+	if err != nil {
+		log.Printf("Error getting project descriptor: %v", err)
+		return "", fmt.Errorf("error getting descriptor for project %s: %w", c.config.AzureDevOps.Project, err)
+	}
+
+	return stringValue(descriptor.Value), nil
+}
+
+// listGroups returns the security groups in the project, or in the whole organization when
+// organization is true.
+func (c *AzureDevOpsClient) listGroups(ctx context.Context, organization bool) ([]map[string]interface{}, error) {
+	args := graph.ListGroupsArgs{}
+	if !organization {
+		scope, err := c.projectScopeDescriptor(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args.ScopeDescriptor = &scope
+	}
+
+	results := []map[string]interface{}{}
+	for {
+		page, err := c.graphClient.ListGroups(ctx, args)
+		if err != nil {
+			log.Printf("Error listing groups: %v", err)
+			return nil, fmt.Errorf("error listing groups: %w", err)
+		}
+		if page.GraphGroups != nil {
+			for _, group := range *page.GraphGroups {
+				results = append(results, map[string]interface{}{
+					"displayName":   stringValue(group.DisplayName),
+					"principalName": stringValue(group.PrincipalName),
+					"description":   stringValue(group.Description),
+					"descriptor":    stringValue(group.Descriptor),
+					"origin":        stringValue(group.Origin),
+				})
+			}
+		}
+		if page.ContinuationToken == nil || len(*page.ContinuationToken) == 0 || (*page.ContinuationToken)[0] == "" {
+			break
+		}
+		args.ContinuationToken = &(*page.ContinuationToken)[0]
+	}
+
+	return results, nil
+}
+
+// resolveGroupDescriptor accepts a group descriptor, display name, or principal name
+// (e.g. [Project]\Project Administrators) and returns the group's descriptor.
+func (c *AzureDevOpsClient) resolveGroupDescriptor(ctx context.Context, group string) (string, error) {
+	if strings.HasPrefix(group, "vssgp.") || strings.HasPrefix(group, "aadgp.") {
+		return group, nil
+	}
+
+	for _, organization := range []bool{false, true} {
+		groups, err := c.listGroups(ctx, organization)
+		if err != nil {
+			return "", err
+		}
+		for _, g := range groups {
+			if strings.EqualFold(g["displayName"].(string), group) || strings.EqualFold(g["principalName"].(string), group) {
+				return g["descriptor"].(string), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("group not found: %s", group)
+}
+
+func (c *AzureDevOpsClient) lookupSubjects(ctx context.Context, descriptors []string) (map[string]graph.GraphSubject, error) {
+	subjects := map[string]graph.GraphSubject{}
+	for start := 0; start < len(descriptors); start += maxSubjectLookupBatch {
+		end := start + maxSubjectLookupBatch
+		if end > len(descriptors) {
+			end = len(descriptors)
+		}
+		keys := []graph.GraphSubjectLookupKey{}
+		for i := start; i < end; i++ {
+			descriptor := descriptors[i]
+			keys = append(keys, graph.GraphSubjectLookupKey{Descriptor: &descriptor})
+		}
+
+		batch, err := c.graphClient.LookupSubjects(ctx, graph.LookupSubjectsArgs{
+			SubjectLookup: &graph.GraphSubjectLookup{LookupKeys: &keys},
+		})
+		if err != nil {
+			log.Printf("Error looking up subjects: %v", err)
+			return nil, fmt.Errorf("error looking up subjects: %w", err)
+		}
+		if batch != nil {
+			for descriptor, subject := range *batch {
+				subjects[descriptor] = subject
+			}
+		}
+	}
+
+	return subjects, nil
+}
+
+// getGroupMembers lists the direct members of a group. With expand, members of nested groups
+// are included too, each annotated with the group it was inherited through.
+func (c *AzureDevOpsClient) getGroupMembers(ctx context.Context, group string, expand bool) ([]map[string]interface{}, error) {
+	root, err := c.resolveGroupDescriptor(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []map[string]interface{}{}
+	visited := map[string]bool{root: true}
+	type pendingGroup struct {
+		descriptor string
+		via        string
+	}
+	queue := []pendingGroup{{descriptor: root}}
+	direction := graph.GraphTraversalDirectionValues.Down
+
+	for depth := 0; len(queue) > 0 && depth <= maxGroupExpansionDepth; depth++ {
+		next := []pendingGroup{}
+		for _, current := range queue {
+			memberships, err := c.graphClient.ListMemberships(ctx, graph.ListMembershipsArgs{
+				SubjectDescriptor: &current.descriptor,
+				Direction:         &direction,
+			})
+			if err != nil {
+				log.Printf("Error listing memberships: %v", err)
+				return nil, fmt.Errorf("error listing members of %s: %w", current.descriptor, err)
+			}
+
+			descriptors := []string{}
+			if memberships != nil {
+				for _, membership := range *memberships {
+					if membership.MemberDescriptor != nil {
+						descriptors = append(descriptors, *membership.MemberDescriptor)
+					}
+				}
+			}
+			subjects, err := c.lookupSubjects(ctx, descriptors)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, descriptor := range descriptors {
+				subject := subjects[descriptor]
+				kind := stringValue(subject.SubjectKind)
+				entry := map[string]interface{}{
+					"displayName": stringValue(subject.DisplayName),
+					"descriptor":  descriptor,
+					"kind":        kind,
+					"origin":      stringValue(subject.Origin),
+				}
+				if current.via != "" {
+					entry["via"] = current.via
+				}
+				results = append(results, entry)
+
+				if expand && strings.EqualFold(kind, "group") && !visited[descriptor] {
+					visited[descriptor] = true
+					next = append(next, pendingGroup{descriptor: descriptor, via: stringValue(subject.DisplayName)})
+				}
+			}
+		}
+		queue = next
+	}
+
+	return results, nil
+}
+
+func addGroupTools(s tools.Server, client *AzureDevOpsClient) {
+	listGroupsTool := mcp.NewTool("list_groups",
+		mcp.WithDescription("List security groups in the project or the whole organization"),
+		mcp.WithBoolean("organization",
+			mcp.Description("List organization-level groups instead of project groups"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.AddTool(listGroupsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		organization, _ := request.Params.Arguments["organization"].(bool)
+
+		results, err := client.listGroups(ctx, organization)
+		if err != nil {
+			log.Printf("Error listing groups: %v", err)
+			return nil, fmt.Errorf("error listing groups: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	groupMembersTool := mcp.NewTool("get_group_members",
+		mcp.WithDescription("List the members of a security group, e.g. to answer who is in Project Administrators"),
+		mcp.WithString("group",
+			mcp.Required(),
+			mcp.Description("Group display name, principal name (e.g. [Project]\\Project Administrators), or descriptor"),
+		),
+		mcp.WithBoolean("expand",
+			mcp.Description("Also list members of nested groups"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.AddTool(groupMembersTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		group, ok := request.Params.Arguments["group"].(string)
+		if !ok {
+			log.Print("Group must be a string")
+			return nil, fmt.Errorf("group must be a string")
+		}
+		expand, _ := request.Params.Arguments["expand"].(bool)
+
+		results, err := client.getGroupMembers(ctx, group, expand)
+		if err != nil {
+			log.Printf("Error getting group members: %v", err)
+			return nil, fmt.Errorf("error getting group members: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+}
@@ -0,0 +1,540 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultHistoryDays = 90
+	defaultChurnFiles  = 20
+	// maxChurnCommits caps the commits whose changes are read for churn; each is one request.
+	maxChurnCommits = 300
+	// maxHistoryCommits caps the commits read for contributor statistics.
+	maxHistoryCommits = 5000
+	defaultRecentDays = 7
+)
+
+// commitsSince returns up to max commits of ref since a time, newest first, optionally only those
+// touching a path. A zero time reads commits of any age.
+func (c *AzureDevOpsClient) commitsSince(ctx context.Context, repoID, ref, itemPath string, since time.Time, max int) ([]git.GitCommitRef, error) {
+	fromDate := ""
+	if !since.IsZero() {
+		fromDate = since.UTC().Format(time.RFC3339)
+	}
+	commits := []git.GitCommitRef{}
+	skip := 0
+	for len(commits) < max {
+		top := pullRequestPageSize
+		if max-len(commits) < top {
+			top = max - len(commits)
+		}
+		criteria := &git.GitQueryCommitsCriteria{
+			ItemVersion: versionDescriptor(ref),
+			Top:         &top,
+			Skip:        &skip,
+		}
+		if fromDate != "" {
+			criteria.FromDate = &fromDate
+		}
+		if itemPath != "" {
+			criteria.ItemPath = &itemPath
+		}
+		page, err := c.gitClient.GetCommits(ctx, git.GetCommitsArgs{
+			RepositoryId:   &repoID,
+			Project:        &c.config.AzureDevOps.Project,
+			SearchCriteria: criteria,
+		})
+		if err != nil {
+			log.Printf("Error getting commits: %v", err)
+			return nil, fmt.Errorf("error getting commits since %s: %w", fromDate, err)
+		}
+		if page == nil || len(*page) == 0 {
+			break
+		}
+		commits = append(commits, *page...)
+		if len(*page) < top {
+			break
+		}
+		skip += top
+	}
+	return commits, nil
+}
+
+// historyRepository resolves a repository and the branch to read history from, defaulting to its
+// default branch.
+func (c *AzureDevOpsClient) historyRepository(ctx context.Context, repoName, branch string) (string, string, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return "", "", err
+	}
+	if branch == "" {
+		if repo.DefaultBranch == nil {
+			return "", "", fmt.Errorf("repository %s has no default branch; pass branch", repoName)
+		}
+		branch = *repo.DefaultBranch
+	}
+	return repo.Id.String(), strings.TrimPrefix(branchRef(branch), "refs/heads/"), nil
+}
+
+// historyFolder normalizes a folder filter to an absolute path without a trailing slash.
+func historyFolder(folder string) string {
+	if folder == "" {
+		return ""
+	}
+	return "/" + strings.Trim(folder, "/")
+}
+
+// codeChurn returns the files changed by the most commits of a branch in the last days, with how
+// many authors changed them.
+func (c *AzureDevOpsClient) codeChurn(ctx context.Context, repoName, branch, folder string, days, top int) (map[string]interface{}, error) {
+	repoID, branch, err := c.historyRepository(ctx, repoName, branch)
+	if err != nil {
+		return nil, err
+	}
+	folder = historyFolder(folder)
+	since := time.Now().AddDate(0, 0, -days)
+	commits, err := c.commitsSince(ctx, repoID, branch, folder, since, maxChurnCommits+1)
+	if err != nil {
+		return nil, err
+	}
+	truncated := len(commits) > maxChurnCommits
+	if truncated {
+		commits = commits[:maxChurnCommits]
+	}
+
+	type fileChurn struct {
+		path    string
+		commits int
+		authors map[string]bool
+		changes map[string]int
+	}
+	files := map[string]*fileChurn{}
+	errors := map[string]string{}
+	for i, commit := range commits {
+		tools.ReportProgress(ctx, i, len(commits), "Reading commit changes")
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		commitID := stringValue(commit.CommitId)
+		// Merge commits repeat the changes of the commits they merge.
+		if commit.Parents != nil && len(*commit.Parents) > 1 {
+			continue
+		}
+		changes, err := c.gitClient.GetChanges(ctx, git.GetChangesArgs{
+			CommitId:     &commitID,
+			RepositoryId: &repoID,
+			Project:      &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error getting commit changes: %v", err)
+			errors[commitID] = err.Error()
+			continue
+		}
+		if changes.Changes == nil {
+			continue
+		}
+		author := ""
+		if commit.Author != nil {
+			author = strings.ToLower(stringValue(commit.Author.Email))
+		}
+		for _, change := range changedFiles(*changes.Changes) {
+			if folder != "" && !strings.HasPrefix(change.Path, folder+"/") {
+				continue
+			}
+			file := files[change.Path]
+			if file == nil {
+				file = &fileChurn{path: change.Path, authors: map[string]bool{}, changes: map[string]int{}}
+				files[change.Path] = file
+			}
+			file.commits++
+			file.authors[author] = true
+			file.changes[change.ChangeType]++
+		}
+	}
+
+	ranked := make([]*fileChurn, 0, len(files))
+	for _, file := range files {
+		ranked = append(ranked, file)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].commits != ranked[j].commits {
+			return ranked[i].commits > ranked[j].commits
+		}
+		return ranked[i].path < ranked[j].path
+	})
+	if len(ranked) > top {
+		ranked = ranked[:top]
+	}
+	results := []map[string]interface{}{}
+	for _, file := range ranked {
+		results = append(results, map[string]interface{}{
+			"path":    file.path,
+			"commits": file.commits,
+			"authors": len(file.authors),
+			"changes": file.changes,
+		})
+	}
+
+	result := map[string]interface{}{
+		"repository":   repoName,
+		"branch":       branch,
+		"since":        since.Format("2006-01-02"),
+		"commitsRead":  len(commits),
+		"filesChanged": len(files),
+		"files":        results,
+	}
+	if truncated {
+		result["truncated"] = fmt.Sprintf("only the latest %d commits were read; shorten days for the full window", maxChurnCommits)
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// recentChanges lists the files a branch changed in its last days, or its last commits when
+// commits is set, grouped by folder. The files are the net changes between the branch head and
+// the parent of the oldest commit in the window, so they take one diff rather than a request per
+// commit. With a folder, only the commits and files under it count. Ignored paths are left out.
+func (c *AzureDevOpsClient) recentChanges(ctx context.Context, repoName, branch, folder string, days, commits int, ignore []string) (map[string]interface{}, error) {
+	ignorePatterns, err := c.ignorePatterns(ignore)
+	if err != nil {
+		return nil, err
+	}
+	repoID, branch, err := c.historyRepository(ctx, repoName, branch)
+	if err != nil {
+		return nil, err
+	}
+	folder = historyFolder(folder)
+
+	var since time.Time
+	limit := commits
+	if commits <= 0 {
+		since = time.Now().AddDate(0, 0, -days)
+		limit = maxHistoryCommits
+	}
+	window, err := c.commitsSince(ctx, repoID, branch, folder, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{
+		"repository": repoName,
+		"branch":     branch,
+		"commits":    len(window),
+	}
+	if !since.IsZero() {
+		result["since"] = since.Format("2006-01-02")
+	}
+	if folder != "" {
+		result["folder"] = folder
+	}
+	if len(window) == 0 {
+		result["filesChanged"] = 0
+		result["folders"] = []map[string]interface{}{}
+		return result, nil
+	}
+
+	head := stringValue(window[0].CommitId)
+	oldest := window[len(window)-1]
+	if oldest.Parents == nil || len(*oldest.Parents) == 0 {
+		return nil, fmt.Errorf("the window reaches the first commit of %s; pass fewer days or commits", repoName)
+	}
+	base := (*oldest.Parents)[0]
+	changes, _, err := c.changesBetween(ctx, repoID, base, head)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := map[string][]map[string]interface{}{}
+	count := 0
+	for _, change := range changes {
+		if folder != "" && !strings.HasPrefix(change.Path, folder+"/") || ignorePatterns.match(change.Path) {
+			continue
+		}
+		entry := map[string]interface{}{
+			"path":       change.Path,
+			"changeType": change.ChangeType,
+		}
+		if change.OriginalPath != "" {
+			entry["originalPath"] = change.OriginalPath
+		}
+		dir := path.Dir(change.Path)
+		folders[dir] = append(folders[dir], entry)
+		count++
+	}
+	grouped := []map[string]interface{}{}
+	for dir, files := range folders {
+		grouped = append(grouped, map[string]interface{}{
+			"folder": dir,
+			"files":  files,
+		})
+	}
+	sort.Slice(grouped, func(i, j int) bool {
+		return grouped[i]["folder"].(string) < grouped[j]["folder"].(string)
+	})
+
+	result["fromCommit"] = base
+	result["toCommit"] = head
+	result["filesChanged"] = count
+	result["folders"] = grouped
+	if len(changes) >= maxPullRequestChange {
+		result["truncated"] = fmt.Sprintf("only the first %d changed files were read", maxPullRequestChange)
+	}
+	if commits <= 0 && len(window) == maxHistoryCommits {
+		result["truncatedCommits"] = fmt.Sprintf("only the latest %d commits count; shorten days for the full window", maxHistoryCommits)
+	}
+	return result, nil
+}
+
+// contributorStats summarizes who committed to a branch in the last days: commits and active dates
+// per author, and the bus factor, the fewest authors that together made half of the commits.
+func (c *AzureDevOpsClient) contributorStats(ctx context.Context, repoName, branch, folder string, days int) (map[string]interface{}, error) {
+	repoID, branch, err := c.historyRepository(ctx, repoName, branch)
+	if err != nil {
+		return nil, err
+	}
+	folder = historyFolder(folder)
+	since := time.Now().AddDate(0, 0, -days)
+	commits, err := c.commitsSince(ctx, repoID, branch, folder, since, maxHistoryCommits)
+	if err != nil {
+		return nil, err
+	}
+
+	type contributor struct {
+		name, email string
+		commits     int
+		first, last time.Time
+		activeDays  map[string]bool
+	}
+	contributors := map[string]*contributor{}
+	for _, commit := range commits {
+		if commit.Author == nil {
+			continue
+		}
+		email := strings.ToLower(stringValue(commit.Author.Email))
+		entry := contributors[email]
+		if entry == nil {
+			entry = &contributor{name: stringValue(commit.Author.Name), email: email, activeDays: map[string]bool{}}
+			contributors[email] = entry
+		}
+		entry.commits++
+		if commit.Author.Date != nil {
+			date := commit.Author.Date.Time
+			if entry.first.IsZero() || date.Before(entry.first) {
+				entry.first = date
+			}
+			if date.After(entry.last) {
+				entry.last = date
+			}
+			entry.activeDays[date.Format("2006-01-02")] = true
+		}
+	}
+
+	ranked := make([]*contributor, 0, len(contributors))
+	for _, entry := range contributors {
+		ranked = append(ranked, entry)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].commits != ranked[j].commits {
+			return ranked[i].commits > ranked[j].commits
+		}
+		return ranked[i].email < ranked[j].email
+	})
+
+	results := []map[string]interface{}{}
+	busFactor := 0
+	covered := 0
+	for _, entry := range ranked {
+		if covered*2 < len(commits) {
+			busFactor++
+			covered += entry.commits
+		}
+		item := map[string]interface{}{
+			"name":       entry.name,
+			"email":      entry.email,
+			"commits":    entry.commits,
+			"share":      float64(entry.commits) / float64(len(commits)),
+			"activeDays": len(entry.activeDays),
+		}
+		if !entry.first.IsZero() {
+			item["firstCommit"] = entry.first.Format(time.RFC3339)
+			item["lastCommit"] = entry.last.Format(time.RFC3339)
+		}
+		results = append(results, item)
+	}
+
+	result := map[string]interface{}{
+		"repository":   repoName,
+		"branch":       branch,
+		"since":        since.Format("2006-01-02"),
+		"commits":      len(commits),
+		"busFactor":    busFactor,
+		"contributors": results,
+	}
+	if folder != "" {
+		result["folder"] = folder
+	}
+	if len(commits) == maxHistoryCommits {
+		result["truncated"] = fmt.Sprintf("only the latest %d commits were read", maxHistoryCommits)
+	}
+	return result, nil
+}
+
+func addHistoryTools(s tools.Server, client *AzureDevOpsClient) {
+	churnTool := mcp.NewTool("get_code_churn",
+		mcp.WithDescription(fmt.Sprintf("List the files changed by the most commits of a branch in a time window, with the number of distinct authors and change types, to find maintenance hot spots. Reads at most the latest %d commits", maxChurnCommits)),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("branch",
+			mcp.Description("Branch name; defaults to the repository's default branch"),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Only files under this folder, e.g. /src/api"),
+		),
+		mcp.WithNumber("days",
+			mcp.Description("Length of the window in days, ending today"),
+			mcp.DefaultNumber(defaultHistoryDays),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of files to return"),
+			mcp.DefaultNumber(defaultChurnFiles),
+		),
+	)
+
+	s.AddTool(churnTool, tools.WithProgress(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		branch, _ := request.Params.Arguments["branch"].(string)
+		folder, _ := request.Params.Arguments["folder"].(string)
+		days := defaultHistoryDays
+		if value, ok := request.Params.Arguments["days"].(float64); ok && value > 0 {
+			days = int(value)
+		}
+		top := defaultChurnFiles
+		if value, ok := request.Params.Arguments["top"].(float64); ok && value > 0 {
+			top = int(value)
+		}
+
+		result, err := client.codeChurn(ctx, repo, branch, folder, days, top)
+		if err != nil {
+			log.Printf("Error getting code churn: %v", err)
+			return nil, fmt.Errorf("error getting code churn: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	contributorsTool := mcp.NewTool("get_contributor_stats",
+		mcp.WithDescription("Summarize who committed to a branch in a time window: commits, share, active days, and first and last commit per author, plus the bus factor, the fewest authors that together made half of the commits"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("branch",
+			mcp.Description("Branch name; defaults to the repository's default branch"),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Only commits touching this folder, e.g. /src/api"),
+		),
+		mcp.WithNumber("days",
+			mcp.Description("Length of the window in days, ending today"),
+			mcp.DefaultNumber(defaultHistoryDays),
+		),
+	)
+
+	s.AddTool(contributorsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		branch, _ := request.Params.Arguments["branch"].(string)
+		folder, _ := request.Params.Arguments["folder"].(string)
+		days := defaultHistoryDays
+		if value, ok := request.Params.Arguments["days"].(float64); ok && value > 0 {
+			days = int(value)
+		}
+
+		result, err := client.contributorStats(ctx, repo, branch, folder, days)
+		if err != nil {
+			log.Printf("Error getting contributor stats: %v", err)
+			return nil, fmt.Errorf("error getting contributor stats: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	recentTool := mcp.NewTool("get_recent_changes",
+		mcp.WithDescription("List the files a branch changed in its last days or last commits, grouped by folder, as a cheap way to see where an active codebase is moving. Files are the net changes over the window, from a single diff"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("branch",
+			mcp.Description("Branch name; defaults to the repository's default branch"),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Only commits and files under this folder, e.g. /src/api"),
+		),
+		mcp.WithNumber("days",
+			mcp.Description("Length of the window in days, ending today"),
+			mcp.DefaultNumber(defaultRecentDays),
+		),
+		mcp.WithNumber("commits",
+			mcp.Description("Use the latest this many commits as the window instead of days"),
+		),
+		mcp.WithArray("ignore",
+			mcp.Description(ignoreDescription),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+
+	s.AddTool(recentTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		branch, _ := request.Params.Arguments["branch"].(string)
+		folder, _ := request.Params.Arguments["folder"].(string)
+		days := defaultRecentDays
+		if value, ok := request.Params.Arguments["days"].(float64); ok && value > 0 {
+			days = int(value)
+		}
+		commits := 0
+		if value, ok := request.Params.Arguments["commits"].(float64); ok && value > 0 {
+			commits = int(value)
+		}
+		if commits > maxHistoryCommits {
+			commits = maxHistoryCommits
+		}
+		ignore, err := tools.StringSliceArgument(request.Params.Arguments, "ignore")
+		if err != nil {
+			log.Printf("Invalid ignore: %v", err)
+			return nil, err
+		}
+
+		result, err := client.recentChanges(ctx, repo, branch, folder, days, commits, ignore)
+		if err != nil {
+			log.Printf("Error getting recent changes: %v", err)
+			return nil, fmt.Errorf("error getting recent changes: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
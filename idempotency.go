@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const defaultIdempotencyTTLMinutes = 60
+
+// idempotencyPollInterval is how often a repeated call checks whether the first one finished.
+const idempotencyPollInterval = 250 * time.Millisecond
+
+// idempotentCall is the record of a write tool call made with an idempotency key. Done is false
+// while the call runs. Write tools return text, so the result is kept as its text parts.
+type idempotentCall struct {
+	Arguments string   `json:"arguments"`
+	Done      bool     `json:"done"`
+	Texts     []string `json:"texts,omitempty"`
+	IsError   bool     `json:"isError,omitempty"`
+}
+
+// idempotencyCache remembers the results of write tool calls by idempotency key, so an agent that
+// retries a call, e.g. after a timeout, does not create a second pull request or work item.
+// Records live in the state store, so a retry that reaches another replica is answered too.
+// Failed calls are forgotten so they can be retried.
+type idempotencyCache struct {
+	ttl   time.Duration
+	store stateStore
+}
+
+func newIdempotencyCache(ttlMinutes int, store stateStore) *idempotencyCache {
+	if ttlMinutes <= 0 {
+		ttlMinutes = defaultIdempotencyTTLMinutes
+	}
+	return &idempotencyCache{
+		ttl:   time.Duration(ttlMinutes) * time.Minute,
+		store: store,
+	}
+}
+
+// do runs call unless a call with the same key ran within the TTL, in which case its result is
+// returned, waiting for it if it is still running. Reusing a key with other arguments is an error.
+func (c *idempotencyCache) do(ctx context.Context, key, arguments string, call func() (*mcp.CallToolResult, error)) (*mcp.CallToolResult, error) {
+	key = "idempotency/" + key
+	running, err := json.Marshal(idempotentCall{Arguments: arguments})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding idempotency record: %w", err)
+	}
+	for {
+		// A claim expires with the TTL, so a replica that dies mid-call does not block the key.
+		claimed, err := c.store.setNX(key, running, c.ttl)
+		if err != nil {
+			log.Printf("Error claiming idempotency key: %v", err)
+			return nil, fmt.Errorf("error claiming idempotency key: %w", err)
+		}
+		if claimed {
+			return c.run(key, arguments, call)
+		}
+
+		value, ok, err := c.store.get(key)
+		if err != nil {
+			log.Printf("Error reading idempotency key: %v", err)
+			return nil, fmt.Errorf("error reading idempotency key: %w", err)
+		}
+		if !ok {
+			// The first call failed or expired between the claim and the read; claim it again.
+			continue
+		}
+		var previous idempotentCall
+		if err := json.Unmarshal(value, &previous); err != nil {
+			return nil, fmt.Errorf("error decoding idempotency record: %w", err)
+		}
+		if previous.Arguments != arguments {
+			return nil, fmt.Errorf("idempotency key was already used with different arguments")
+		}
+		if previous.Done {
+			result := &mcp.CallToolResult{IsError: previous.IsError}
+			for _, text := range previous.Texts {
+				result.Content = append(result.Content, mcp.NewTextContent(text))
+			}
+			return result, nil
+		}
+
+		select {
+		case <-time.After(idempotencyPollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// run makes a claimed call and records its result, or releases the key when it fails or panics.
+func (c *idempotencyCache) run(key, arguments string, call func() (*mcp.CallToolResult, error)) (*mcp.CallToolResult, error) {
+	done := false
+	defer func() {
+		if done {
+			return
+		}
+		if err := c.store.delete(key); err != nil {
+			log.Printf("Error releasing idempotency key: %v", err)
+		}
+	}()
+
+	result, err := call()
+	if err != nil || result == nil {
+		return result, err
+	}
+	done = true
+
+	record := idempotentCall{Arguments: arguments, Done: true, IsError: result.IsError}
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			record.Texts = append(record.Texts, text.Text)
+		}
+	}
+	value, err := json.Marshal(record)
+	if err == nil {
+		err = c.store.set(key, value, c.ttl)
+	}
+	if err != nil {
+		// The write happened; only the protection against repeating it is lost.
+		log.Printf("Error recording idempotency key: %v", err)
+	}
+	return result, nil
+}
+
+// withIdempotency makes calls of a write tool that pass an idempotencyKey run at most once per key
+// within server.idempotency_ttl_minutes. Dry runs change nothing and are not remembered.
+func withIdempotency(client *AzureDevOpsClient, tool mcp.Tool, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		key, _ := request.Params.Arguments["idempotencyKey"].(string)
+		if key == "" || client.dryRun(request) {
+			return handler(ctx, request)
+		}
+
+		arguments := map[string]interface{}{}
+		for name, value := range request.Params.Arguments {
+			if name != "idempotencyKey" {
+				arguments[name] = value
+			}
+		}
+		fingerprint, err := json.Marshal(arguments)
+		if err != nil {
+			log.Printf("Error encoding arguments: %v", err)
+			return nil, fmt.Errorf("error encoding arguments: %w", err)
+		}
+
+		return client.idempotency.do(ctx, client.config.AzureDevOps.Organization+"/"+tool.Name+"/"+credentialID(ctx)+"/"+key, string(fingerprint), func() (*mcp.CallToolResult, error) {
+			return handler(ctx, request)
+		})
+	}
+}
+
+// writeHandler wraps the handler of a write tool: repeated calls are answered by idempotency key
+// first, and the remaining ones are confirmed by the user where configured.
+func writeHandler(client *AzureDevOpsClient, tool mcp.Tool, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return withIdempotency(client, tool, withConfirmation(client, tool, handler))
+}
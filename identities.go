@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// ResolvedIdentity is an Azure DevOps user or group matched by an identity search.
+type ResolvedIdentity struct {
+	ID                string `json:"id"`
+	DisplayName       string `json:"displayName"`
+	Mail              string `json:"mail,omitempty"`
+	Descriptor        string `json:"descriptor,omitempty"`
+	SubjectDescriptor string `json:"subjectDescriptor,omitempty"`
+	IsGroup           bool   `json:"isGroup"`
+}
+
+// identityProperty reads a property such as Mail or Account from an identity's property bag,
+// where each value is wrapped as {"$type": ..., "$value": ...}.
+func identityProperty(properties interface{}, name string) string {
+	bag, ok := properties.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	switch v := bag[name].(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		value, _ := v["$value"].(string)
+		return value
+	}
+	return ""
+}
+
+// resolveIdentities searches the organization for users and groups whose display name, account
+// name, or email matches query.
+func (c *AzureDevOpsClient) resolveIdentities(ctx context.Context, query string) ([]ResolvedIdentity, error) {
+	searchFilter := "General"
+	if strings.Contains(query, "@") {
+		searchFilter = "MailAddress"
+	}
+
+	identities, err := c.identityClient.ReadIdentities(ctx, identity.ReadIdentitiesArgs{
+		SearchFilter: &searchFilter,
+		FilterValue:  &query,
+	})
+	if err != nil {
+		log.Printf("Error reading identities: %v", err)
+		return nil, fmt.Errorf("error resolving identity %s: %w", query, err)
+	}
+
+	results := []ResolvedIdentity{}
+	if identities == nil {
+		return results, nil
+	}
+	for _, ident := range *identities {
+		if ident.Id == nil {
+			continue
+		}
+		displayName := stringValue(ident.CustomDisplayName)
+		if displayName == "" {
+			displayName = stringValue(ident.ProviderDisplayName)
+		}
+		results = append(results, ResolvedIdentity{
+			ID:                ident.Id.String(),
+			DisplayName:       displayName,
+			Mail:              identityProperty(ident.Properties, "Mail"),
+			Descriptor:        stringValue(ident.Descriptor),
+			SubjectDescriptor: stringValue(ident.SubjectDescriptor),
+			IsGroup:           ident.IsContainer != nil && *ident.IsContainer,
+		})
+	}
+
+	return results, nil
+}
+
+// listProjectMembers returns the members of every team in the project, or of a single team,
+// with the teams each member belongs to.
+func (c *AzureDevOpsClient) listProjectMembers(ctx context.Context, team string) ([]map[string]interface{}, error) {
+	teams := []string{team}
+	if team == "" {
+		list, err := c.listTeams(ctx)
+		if err != nil {
+			return nil, err
+		}
+		teams = []string{}
+		for _, t := range list {
+			teams = append(teams, t["name"].(string))
+		}
+	}
+
+	type member struct {
+		id          string
+		displayName string
+		uniqueName  string
+		teams       []string
+		adminOf     []string
+	}
+	members := map[string]*member{}
+	for _, teamName := range teams {
+		teamMembers, err := c.coreClient.GetTeamMembersWithExtendedProperties(ctx, core.GetTeamMembersWithExtendedPropertiesArgs{
+			ProjectId: &c.config.AzureDevOps.Project,
+			TeamId:    &teamName,
+		})
+		if err != nil {
+			log.Printf("Error getting team members: %v", err)
+			return nil, fmt.Errorf("error getting members of team %s: %w", teamName, err)
+		}
+		if teamMembers == nil {
+			continue
+		}
+		for _, tm := range *teamMembers {
+			if tm.Identity == nil || tm.Identity.Id == nil {
+				continue
+			}
+			id := *tm.Identity.Id
+			m, ok := members[id]
+			if !ok {
+				m = &member{
+					id:          id,
+					displayName: stringValue(tm.Identity.DisplayName),
+					uniqueName:  stringValue(tm.Identity.UniqueName),
+				}
+				members[id] = m
+			}
+			m.teams = append(m.teams, teamName)
+			if tm.IsTeamAdmin != nil && *tm.IsTeamAdmin {
+				m.adminOf = append(m.adminOf, teamName)
+			}
+		}
+	}
+
+	results := []map[string]interface{}{}
+	for _, m := range members {
+		entry := map[string]interface{}{
+			"id":          m.id,
+			"displayName": m.displayName,
+			"uniqueName":  m.uniqueName,
+			"teams":       m.teams,
+		}
+		if len(m.adminOf) > 0 {
+			entry["adminOf"] = m.adminOf
+		}
+		results = append(results, entry)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return strings.ToLower(results[i]["displayName"].(string)) < strings.ToLower(results[j]["displayName"].(string))
+	})
+
+	return results, nil
+}
+
+func addIdentityTools(s tools.Server, client *AzureDevOpsClient) {
+	resolveIdentityTool := mcp.NewTool("resolve_identity",
+		mcp.WithDescription("Resolve a display name, account name, or email to Azure DevOps identities with their IDs and descriptors, for use as reviewers, assignees, or @mentions"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Display name, account name, or email address"),
+		),
+	)
+
+	s.AddTool(resolveIdentityTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		query, ok := request.Params.Arguments["query"].(string)
+		if !ok {
+			log.Print("Query must be a string")
+			return nil, fmt.Errorf("query must be a string")
+		}
+
+		results, err := client.resolveIdentities(ctx, query)
+		if err != nil {
+			log.Printf("Error resolving identity: %v", err)
+			return nil, fmt.Errorf("error resolving identity: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	projectMembersTool := mcp.NewTool("list_project_members",
+		mcp.WithDescription("List the members of the project's teams, with the teams each member belongs to"),
+		mcp.WithString("team",
+			mcp.Description("Optional team name to list only that team's members"),
+		),
+	)
+
+	s.AddTool(projectMembersTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		team, _ := request.Params.Arguments["team"].(string)
+
+		results, err := client.listProjectMembers(ctx, team)
+		if err != nil {
+			log.Printf("Error listing project members: %v", err)
+			return nil, fmt.Errorf("error listing project members: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+}
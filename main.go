@@ -1,99 +1,407 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
-	"os"
+	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
-	"github.com/microsoft/azure-devops-go-api/azuredevops/v6"
-	"github.com/microsoft/azure-devops-go-api/azuredevops/v6/git"
-	"github.com/microsoft/azure-devops-go-api/azuredevops/v6/search"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/audit"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/dashboard"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/extensionmanagement"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/location"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/memberentitlementmanagement"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/notification"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/pipelinepermissions"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/policy"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/projectanalysis"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/search"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/servicehooks"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/test"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/wiki"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/work"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtrackingprocess"
+	"github.com/signify/sgfy-mcp/azdo"
+	"github.com/signify/sgfy-mcp/config"
+	"github.com/signify/sgfy-mcp/tools"
+	"github.com/signify/sgfy-mcp/tools/pipelines"
+	"github.com/signify/sgfy-mcp/tools/workitems"
 	"github.com/spf13/viper"
 )
 
-type Config struct {
-	AzureDevOps struct {
-		Organization string `mapstructure:"organization"`
-		Project      string `mapstructure:"project"`
-		PAT          string `mapstructure:"pat"`
-		APIVersion   string `mapstructure:"api_version"`
-	} `mapstructure:"azure_devops"`
-	Server struct {
-		Port int    `mapstructure:"port"`
-		Host string `mapstructure:"host"`
-	} `mapstructure:"server"`
-}
-
 type AzureDevOpsClient struct {
-	config       *Config
-	connection   *azuredevops.Connection
-	gitClient    git.Client
-	searchClient search.Client
+	config                *config.Config
+	connection            *azuredevops.Connection
+	gitClient             git.Client
+	searchClient          search.Client
+	witClient             workitemtracking.Client
+	workClient            work.Client
+	coreClient            core.Client
+	identityClient        identity.Client
+	graphClient           graph.Client
+	securityClient        security.Client
+	serviceHooksClient    servicehooks.Client
+	notificationClient    notification.Client
+	dashboardClient       dashboard.Client
+	analyticsClient       *azuredevops.Client
+	auditClient           audit.Client
+	processClient         workitemtrackingprocess.Client
+	projectAnalysisClient projectanalysis.Client
+	policyClient          policy.Client
+	buildClient           build.Client
+	testClient            test.Client
+	taskAgentClient       taskagent.Client
+	pipelineAccessClient  pipelinepermissions.Client
+	extensionClient       extensionmanagement.Client
+	entitlementClient     *azuredevops.Client
+	locationClient        location.Client
+	wikiClient            wiki.Client
+	idempotency           *idempotencyCache
+	contexts              *sessionContexts
+	links                 webLinks
+	cache                 cacheBackend
+	metrics               *toolMetrics
+	audit                 *toolAuditLog
+	requests              *azdo.Status
+	// hiddenGroups are the tool groups left out because the PAT lacks their scope, with that scope.
+	hiddenGroups map[string]string
 }
 
 func NewAzureDevOpsClient() (*AzureDevOpsClient, error) {
-	var config Config
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
+	config, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
 
-	if err := viper.ReadInConfig(); err != nil {
-		log.Printf("Error reading config: %v", err)
-		return nil, fmt.Errorf("error reading config: %w", err)
+	// Create Azure DevOps connection
+	links := newWebLinks(config.AzureDevOps.Organization, config.AzureDevOps.URL)
+	connection := azuredevops.NewPatConnection(links.base, config.AzureDevOps.PAT)
+
+	// Route Azure DevOps requests through the configured proxy, limits, and circuit breaker
+	requests, err := azdo.Install(config, links.base)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := viper.Unmarshal(&config); err != nil {
-		log.Printf("Error unmarshaling config: %v", err)
-		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	// Let clients make requests with their own credentials when configured
+	if err := installSessionAuth(config.Server.SessionAuth, connection.AuthorizationString); err != nil {
+		log.Printf("Invalid session auth: %v", err)
+		return nil, err
 	}
 
-	// Check if PAT is empty and try to get it from environment variable
-	if config.AzureDevOps.PAT == "" {
-		if pat := os.Getenv("AZURE_DEVOPS_PAT"); pat != "" {
-			config.AzureDevOps.PAT = pat
-		} else {
-			log.Print("Azure DevOps PAT is required")
-			return nil, fmt.Errorf("Azure DevOps PAT is required")
-		}
+	// Create content cache
+	cache, err := newCacheBackend(config.Cache)
+	if err != nil {
+		log.Printf("Failed to create content cache: %v", err)
+		return nil, fmt.Errorf("failed to create content cache: %w", err)
 	}
 
-	// Create Azure DevOps connection
-	organizationURL := fmt.Sprintf("https://dev.azure.com/%s", config.AzureDevOps.Organization)
-	connection := azuredevops.NewPatConnection(organizationURL, config.AzureDevOps.PAT)
+	// Create state store for session defaults and idempotency keys
+	store, err := newStateStore(config.Server.StateStore, config.Cache.Redis)
+	if err != nil {
+		log.Printf("Failed to create state store: %v", err)
+		return nil, fmt.Errorf("failed to create state store: %w", err)
+	}
+	contexts, err := newSessionContexts(store)
+	if err != nil {
+		log.Printf("Failed to create session contexts: %v", err)
+		return nil, err
+	}
+
+	// Open the audit log of tool calls when configured
+	audit, err := newToolAuditLog(config.Server.AuditLog)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAzureDevOpsClient(context.Background(), config, connection, &AzureDevOpsClient{
+		idempotency: newIdempotencyCache(config.Server.IdempotencyTTLMinutes, store),
+		contexts:    contexts,
+		cache:       cache,
+		metrics:     newToolMetrics(config.Server.SlowCallSeconds),
+		audit:       audit,
+		requests:    requests,
+	})
+}
 
+// newAzureDevOpsClient creates the Azure DevOps clients of an organization over connection, sharing
+// the caches and state of shared. ctx carries the credential of the resource area lookups.
+func newAzureDevOpsClient(ctx context.Context, config *config.Config, connection *azuredevops.Connection, shared *AzureDevOpsClient) (*AzureDevOpsClient, error) {
 	// Create Git client
-	gitClient, err := git.NewClient(context.Background(), connection)
+	gitClient, err := git.NewClient(ctx, connection)
 	if err != nil {
 		log.Printf("Failed to create git client: %v", err)
 		return nil, fmt.Errorf("failed to create git client: %w", err)
 	}
 
 	// Create Search client
-	searchClient, err := search.NewClient(context.Background(), connection)
+	searchClient, err := search.NewClient(ctx, connection)
 	if err != nil {
 		log.Printf("Failed to create search client: %v", err)
 		return nil, fmt.Errorf("failed to create search client: %w", err)
 	}
 
+	// Create Work Item Tracking client
+	witClient, err := workitemtracking.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create work item tracking client: %v", err)
+		return nil, fmt.Errorf("failed to create work item tracking client: %w", err)
+	}
+
+	// Create Work client
+	workClient, err := work.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create work client: %v", err)
+		return nil, fmt.Errorf("failed to create work client: %w", err)
+	}
+
+	// Create Core client
+	coreClient, err := core.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create core client: %v", err)
+		return nil, fmt.Errorf("failed to create core client: %w", err)
+	}
+
+	// Create Identity client
+	identityClient, err := identity.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create identity client: %v", err)
+		return nil, fmt.Errorf("failed to create identity client: %w", err)
+	}
+
+	// Create Graph client
+	graphClient, err := graph.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create graph client: %v", err)
+		return nil, fmt.Errorf("failed to create graph client: %w", err)
+	}
+
+	// Create Security client
+	securityClient := security.NewClient(ctx, connection)
+
+	// Create Service Hooks client
+	serviceHooksClient := servicehooks.NewClient(ctx, connection)
+
+	// Create Dashboard client
+	dashboardClient, err := dashboard.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create dashboard client: %v", err)
+		return nil, fmt.Errorf("failed to create dashboard client: %w", err)
+	}
+
+	// Create Analytics client; OData has no typed client, so requests are built against analyticsURL
+	links := newWebLinks(config.AzureDevOps.Organization, config.AzureDevOps.URL)
+	analyticsClient := azuredevops.NewClient(connection, links.analytics())
+
+	// Create Audit client
+	auditClient, err := audit.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create audit client: %v", err)
+		return nil, fmt.Errorf("failed to create audit client: %w", err)
+	}
+
+	// Create Work Item Tracking Process client
+	processClient, err := workitemtrackingprocess.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create work item tracking process client: %v", err)
+		return nil, fmt.Errorf("failed to create work item tracking process client: %w", err)
+	}
+
+	// Create Project Analysis client
+	projectAnalysisClient, err := projectanalysis.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create project analysis client: %v", err)
+		return nil, fmt.Errorf("failed to create project analysis client: %w", err)
+	}
+
+	// Create Policy client
+	policyClient, err := policy.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create policy client: %v", err)
+		return nil, fmt.Errorf("failed to create policy client: %w", err)
+	}
+
+	// Create Build client
+	buildClient, err := build.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create build client: %v", err)
+		return nil, fmt.Errorf("failed to create build client: %w", err)
+	}
+
+	// Create Test client
+	testClient, err := test.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create test client: %v", err)
+		return nil, fmt.Errorf("failed to create test client: %w", err)
+	}
+
+	// Create Task Agent client
+	taskAgentClient, err := taskagent.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create task agent client: %v", err)
+		return nil, fmt.Errorf("failed to create task agent client: %w", err)
+	}
+
+	// Create Pipeline Permissions client
+	pipelineAccessClient, err := pipelinepermissions.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create pipeline permissions client: %v", err)
+		return nil, fmt.Errorf("failed to create pipeline permissions client: %w", err)
+	}
+
+	// Create Extension Management client
+	extensionClient, err := extensionmanagement.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create extension management client: %v", err)
+		return nil, fmt.Errorf("failed to create extension management client: %w", err)
+	}
+
+	// Create Member Entitlement Management client; entitlement searches are paged by hand, so a plain client is used
+	entitlementClient, err := connection.GetClientByResourceAreaId(ctx, memberentitlementmanagement.ResourceAreaId)
+	if err != nil {
+		log.Printf("Failed to create member entitlement management client: %v", err)
+		return nil, fmt.Errorf("failed to create member entitlement management client: %w", err)
+	}
+
+	// Create Wiki client
+	wikiClient, err := wiki.NewClient(ctx, connection)
+	if err != nil {
+		log.Printf("Failed to create wiki client: %v", err)
+		return nil, fmt.Errorf("failed to create wiki client: %w", err)
+	}
+
 	return &AzureDevOpsClient{
-		config:       &config,
-		connection:   connection,
-		gitClient:    gitClient,
-		searchClient: searchClient,
+		config:                config,
+		connection:            connection,
+		gitClient:             gitClient,
+		searchClient:          searchClient,
+		witClient:             witClient,
+		workClient:            workClient,
+		coreClient:            coreClient,
+		identityClient:        identityClient,
+		graphClient:           graphClient,
+		securityClient:        securityClient,
+		serviceHooksClient:    serviceHooksClient,
+		notificationClient:    notification.NewClient(ctx, connection),
+		dashboardClient:       dashboardClient,
+		analyticsClient:       analyticsClient,
+		auditClient:           auditClient,
+		processClient:         processClient,
+		projectAnalysisClient: projectAnalysisClient,
+		policyClient:          policyClient,
+		buildClient:           buildClient,
+		testClient:            testClient,
+		taskAgentClient:       taskAgentClient,
+		pipelineAccessClient:  pipelineAccessClient,
+		extensionClient:       extensionClient,
+		entitlementClient:     entitlementClient,
+		locationClient:        location.NewClient(ctx, connection),
+		wikiClient:            wikiClient,
+		idempotency:           shared.idempotency,
+		contexts:              shared.contexts,
+		links:                 links,
+		cache:                 shared.cache,
+		metrics:               shared.metrics,
+		audit:                 shared.audit,
+		requests:              shared.requests,
 	}, nil
 }
 
-func (c *AzureDevOpsClient) searchRepository(ctx context.Context, query string, repoName string) ([]map[string]interface{}, error) {
+// projectID returns the ID of the configured project.
+func (c *AzureDevOpsClient) projectID(ctx context.Context) (string, error) {
+	project, err := c.coreClient.GetProject(ctx, core.GetProjectArgs{
+		ProjectId: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting project: %v", err)
+		return "", fmt.Errorf("error getting project %s: %w", c.config.AzureDevOps.Project, err)
+	}
+	return project.Id.String(), nil
+}
+
+// teamName returns team, falling back to the configured team and then to the project's default team.
+func (c *AzureDevOpsClient) teamName(team string) string {
+	if team != "" {
+		return team
+	}
+	if c.config.AzureDevOps.Team != "" {
+		return c.config.AzureDevOps.Team
+	}
+	return c.config.AzureDevOps.Project + " Team"
+}
+
+// searchRepository searches code in the session's project, or in the options' projects when given,
+// where "*" searches every project of the organization. A repository can only be named with one
+// project, and a folder to search under needs a repository.
+func (c *AzureDevOpsClient) searchRepository(ctx context.Context, query string, repoName string, options codeSearchOptions) ([]map[string]interface{}, error) {
+	projects, folder := options.Projects, options.Folder
+	downRank, err := compilePathPatterns(c.config.Search.DownRank, options.DownRank)
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := c.ignorePatterns(options.Ignore)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(projects) == 0:
+		projects = []string{c.sessionProject(ctx)}
+	case len(projects) == 1 && projects[0] == "*":
+		projects = nil
+	}
+
 	// Create search request
 	filters := make(map[string][]string)
-	filters["Project"] = []string{c.config.AzureDevOps.Project}
-	if repoName != "" {
-		filters["Repository"] = []string{repoName}
+	project := ""
+	// Searches of several projects are sent to the organization and their hits checked against
+	// the client's roots, since the Repository filter only applies within one project.
+	var roots []repositoryRoot
+	if len(projects) == 1 {
+		project = projects[0]
+		filters["Project"] = projects
+		repos, err := c.searchRepositories(ctx, project, repoName)
+		if err != nil {
+			return nil, err
+		}
+		if repos != nil {
+			filters["Repository"] = repos
+		}
+		if folder != "" {
+			if repoName == "" {
+				return nil, fmt.Errorf("path %s needs a repo to search", folder)
+			}
+			filters["Path"] = []string{folder}
+		}
+	} else {
+		if repoName != "" {
+			return nil, fmt.Errorf("repo %s needs a single project to search", repoName)
+		}
+		if projects != nil {
+			filters["Project"] = projects
+		}
+		roots, err = c.sessionRoots(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	includeSnippet := true
@@ -106,7 +414,7 @@ func (c *AzureDevOpsClient) searchRepository(ctx context.Context, query string,
 	}
 	// Call search API
 	response, err := c.searchClient.FetchCodeSearchResults(ctx, search.FetchCodeSearchResultsArgs{
-		Project: &c.config.AzureDevOps.Project,
+		Project: &project,
 		Request: searchRequest,
 	})
 	if err != nil {
@@ -115,90 +423,309 @@ func (c *AzureDevOpsClient) searchRepository(ctx context.Context, query string,
 	}
 
 	// Process results
-	results := []map[string]interface{}{}
+	hits := []searchHit{}
 	if response != nil && response.Results != nil {
 		for _, result := range *response.Results {
 			if result.Repository == nil || result.Path == nil || result.FileName == nil {
 				continue
 			}
-			results = append(results, map[string]interface{}{
+			// The Path filter is a prefix of folder names, so /src/a also finds /src/ab.
+			if folder != "" && !strings.HasPrefix(*result.Path, folder+"/") {
+				continue
+			}
+			if ignore.match(*result.Path) {
+				continue
+			}
+			if roots != nil && !inRoots(roots, stringValue(result.Project.Name), stringValue(result.Repository.Name)) {
+				continue
+			}
+			entry := map[string]interface{}{
 				"repository": *result.Repository.Name,
 				"path":       *result.Path,
 				"fileName":   *result.FileName,
 				"project":    *result.Project.Name,
+				"webUrl":     c.links.file(*result.Project.Name, *result.Repository.Name, *result.Path, ""),
+				"uri":        c.fileURI(*result.Project.Name, *result.Repository.Name, *result.Path, ""),
+			}
+			if language := fileLanguage(*result.Path); language != "" {
+				entry["language"] = language
+			}
+			entry["matches"] = codeSearchMatches(result)
+			if branches := codeSearchBranches(result); len(branches) > 0 {
+				entry["branches"] = branches
+			}
+			hits = append(hits, searchHit{
+				result:    entry,
+				project:   *result.Project.Name,
+				repoID:    stringValue(result.Repository.Id),
+				contentID: stringValue(result.ContentId),
 			})
 		}
 	}
+	if options.Collapse {
+		hits = collapseSearchHits(hits)
+	}
+	downRankSearchHits(hits, downRank)
+	c.addSizes(ctx, hits)
 
+	results := []map[string]interface{}{}
+	for _, hit := range hits {
+		results = append(results, hit.result)
+	}
 	return results, nil
 }
 
-func (c *AzureDevOpsClient) getFileContent(ctx context.Context, repoName, path string) (string, error) {
+// findRepository looks up a repository in the project by name, ignoring case.
+func (c *AzureDevOpsClient) findRepository(ctx context.Context, repoName string) (*git.GitRepository, error) {
+	return c.findRepositoryIn(ctx, c.config.AzureDevOps.Project, repoName)
+}
+
+// findRepositoryIn looks up a repository in another project by name, ignoring case.
+func (c *AzureDevOpsClient) findRepositoryIn(ctx context.Context, project, repoName string) (*git.GitRepository, error) {
 	repos, err := c.gitClient.GetRepositories(ctx, git.GetRepositoriesArgs{
-		Project: &c.config.AzureDevOps.Project,
+		Project: &project,
 	})
 	if err != nil {
 		log.Printf("Error getting repositories: %v", err)
-		return "", err
+		return nil, err
 	}
 
-	var targetRepo *git.GitRepository
 	for _, repo := range *repos {
 		if strings.EqualFold(*repo.Name, repoName) {
-			targetRepo = &repo
-			break
+			return &repo, nil
+		}
+	}
+
+	log.Printf("Repository not found: %s", repoName)
+	return nil, fmt.Errorf("repository not found: %s", repoName)
+}
+
+// selectRepositories returns the named repositories of the project, or all of them when no names
+// are given.
+func (c *AzureDevOpsClient) selectRepositories(ctx context.Context, repoNames []string) ([]git.GitRepository, error) {
+	repos, err := c.gitClient.GetRepositories(ctx, git.GetRepositoriesArgs{
+		Project: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting repositories: %v", err)
+		return nil, fmt.Errorf("error getting repositories: %w", err)
+	}
+	if len(repoNames) == 0 {
+		return *repos, nil
+	}
+
+	selected := []git.GitRepository{}
+	for _, name := range repoNames {
+		found := false
+		for _, repo := range *repos {
+			if strings.EqualFold(stringValue(repo.Name), name) {
+				selected = append(selected, repo)
+				found = true
+				break
+			}
 		}
+		if !found {
+			return nil, fmt.Errorf("repository not found: %s", name)
+		}
+	}
+	return selected, nil
+}
+
+// branchRef returns the fully-qualified ref name for a branch, accepting either main or refs/heads/main.
+func branchRef(branch string) string {
+	if strings.HasPrefix(branch, "refs/") {
+		return branch
 	}
+	return "refs/heads/" + branch
+}
 
-	if targetRepo == nil {
-		log.Printf("Repository not found: %s", repoName)
-		return "", fmt.Errorf("repository not found: %s", repoName)
+func (c *AzureDevOpsClient) getFileContent(ctx context.Context, project, repoName, path, ref string) (fileContent, error) {
+	if err := c.checkRoots(ctx, project, repoName); err != nil {
+		return fileContent{}, err
+	}
+	targetRepo, err := c.findRepositoryIn(ctx, project, repoName)
+	if err != nil {
+		return fileContent{}, err
 	}
 
 	repoID := targetRepo.Id.String()
 
-	item, err := c.gitClient.GetItem(ctx, git.GetItemArgs{
-		RepositoryId:   &repoID,
-		Project:        &c.config.AzureDevOps.Project,
-		Path:           &path,
-		IncludeContent: &[]bool{true}[0],
-	})
+	file, err := c.fileAt(ctx, project, repoID, path, ref)
 	if err != nil {
 		log.Printf("Error getting file content: %v", err)
-		return "", err
+		return fileContent{}, err
+	}
+
+	return file, nil
+}
+
+// sendJSON calls an Azure DevOps REST resource directly, for fields and operations the v7 SDK
+// does not model. body, when not nil, is sent as JSON and the response is decoded into result.
+func (c *AzureDevOpsClient) sendJSON(ctx context.Context, method string, locationID uuid.UUID, apiVersion string, routeValues map[string]string, queryParams url.Values, body, result interface{}) error {
+	var reader io.Reader
+	mediaType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error encoding request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+		mediaType = azuredevops.MediaTypeApplicationJson
 	}
 
-	if item.Content == nil {
-		return "", nil
+	client := azuredevops.NewClient(c.connection, c.connection.BaseUrl)
+	response, err := client.Send(ctx, method, locationID, apiVersion, routeValues, queryParams, reader, mediaType, azuredevops.MediaTypeApplicationJson, nil)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return response.Body.Close()
 	}
+	return client.UnmarshalBody(response, result)
+}
 
-	return *item.Content, nil
+// stringValue dereferences an optional string returned by the Azure DevOps SDK.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
 
-func main() {
-	client, err := NewAzureDevOpsClient()
-	if err != nil {
-		log.Fatalf("Failed to create Azure DevOps client: %v", err)
+// timeArgument reads an optional date (YYYY-MM-DD) or RFC 3339 timestamp from the tool arguments.
+func timeArgument(arguments map[string]interface{}, key string) (*time.Time, error) {
+	value, _ := arguments[key].(string)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
 	}
+	return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD) or RFC 3339 timestamp", key)
+}
 
+// newMCPServer creates an MCP server with the tools and resources of an organization.
+func newMCPServer(client *AzureDevOpsClient) *toolServer {
 	// Create MCP server
-	s := server.NewMCPServer(
-		"Azure DevOps MCP Server",
-		"1.0.0",
+	mcpServer := server.NewMCPServer(
+		serverName,
+		serverVersion,
 		server.WithResourceCapabilities(true, true),
 		server.WithPromptCapabilities(true),
 		server.WithToolCapabilities(true),
+		server.WithHooks(client.metrics.hooks()),
+	)
+	s := &toolServer{MCPServer: mcpServer, client: client}
+	enabledToolGroups(client).Register(s)
+	return s
+}
+
+// enabledToolGroups returns the tool groups of an organization without those hidden for lack of
+// PAT scope.
+func enabledToolGroups(client *AzureDevOpsClient) *tools.Registry {
+	groups := toolGroups(client)
+	for name := range client.hiddenGroups {
+		groups.Remove(name)
+	}
+	return groups
+}
+
+// toolGroups returns the tool groups of an organization, in the order their tools are listed.
+func toolGroups(client *AzureDevOpsClient) *tools.Registry {
+	group := func(name string, add func(s tools.Server, client *AzureDevOpsClient)) tools.Group {
+		return tools.NewGroup(name, func(s tools.Server) { add(s, client) })
+	}
+	registry := &tools.Registry{}
+	registry.Add(
+		group("server", addServerInfoTools),
+		group("code", addCodeTools),
+		group("context", addContextTools),
+		workitems.NewGroup(client),
+		group("processes", addProcessTools),
+		group("templates", addTemplateTools),
+		group("workitemclone", addWorkItemCloneTools),
+		group("mywork", addMyWorkTools),
+		group("tags", addTagTools),
+		group("workitemattachments", addWorkItemAttachmentTools),
+		group("boards", addBoardTools),
+		group("sprints", addSprintTools),
+		group("teams", addTeamTools),
+		group("identities", addIdentityTools),
+		group("groups", addGroupTools),
+		group("policies", addPolicyTools),
+		group("permissions", addPermissionTools),
+		group("servicehooks", addServiceHookTools),
+		group("notifications", addNotificationTools),
+		group("dashboards", addDashboardTools),
+		group("analytics", addAnalyticsTools),
+		group("plans", addPlanTools),
+		group("audit", addAuditTools),
+		group("projects", addProjectTools),
+		group("repositories", addRepositoryTools),
+		group("commits", addCommitTools),
+		group("edits", addEditTools),
+		group("contents", addContentTools),
+		group("wikiattachments", addWikiAttachmentTools),
+		group("explore", addExploreTools),
+		group("related", addRelatedTools),
+		group("symbols", addSymbolTools),
+		group("pullrequests", addPullRequestTools),
+		group("prtemplates", addPullRequestTemplateTools),
+		group("threads", addThreadTools),
+		group("reviewers", addReviewerTools),
+		group("codeowners", addCodeOwnersTools),
+		group("releasenotes", addReleaseNoteTools),
+		pipelines.NewGroup(client),
+		group("builds", addBuildTools),
+		group("tests", addTestTools),
+		group("triage", addTriageTools),
+		group("deployments", addDeploymentTools),
+		group("securefiles", addSecureFileTools),
+		group("taskgroups", addTaskGroupTools),
+		group("extensions", addExtensionTools),
+		group("entitlements", addEntitlementTools),
+		group("dependencies", addDependencyTools),
+		group("usage", addUsageTools),
+		group("history", addHistoryTools),
+		group("activity", addActivityTools),
+		group("prstats", addPullRequestStatsTools),
+		group("resources", addRepositoryResources),
 	)
+	return registry
+}
 
+// addCodeTools adds the tools that search and read code.
+func addCodeTools(s tools.Server, client *AzureDevOpsClient) {
 	// Add search tool
 	searchTool := mcp.NewTool("search",
-		mcp.WithDescription("Search for files in Azure DevOps repositories. The key to getting this to work well is asking for at least 5 results from the search tool, then asking specifically for code examples"),
+		mcp.WithDescription("Search for files in Azure DevOps repositories; each result has the file's language and size in bytes when known. The key to getting this to work well is asking for at least 5 results from the search tool, then asking specifically for code examples"),
 		mcp.WithString("query",
 			mcp.Required(),
 			mcp.Description("Search query"),
 		),
 		mcp.WithString("repo",
-			mcp.Description("Optional repository name to search in"),
+			mcp.Description("Optional repository name to search in; defaults to the session's repository from set_context. Needs a single project"),
+		),
+		mcp.WithString("path",
+			mcp.Description("Folder to search under, e.g. src/services/payments; needs a repo"),
+		),
+		mcp.WithArray("projects",
+			mcp.Description("Projects to search instead of the session's, or [\"*\"] for every project of the organization"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("collapse",
+			mcp.Description("Merge the hits of the same file, e.g. on several branches, into one entry with their total matches; defaults to true"),
+		),
+		mcp.WithArray("downRank",
+			mcp.Description("Path patterns in .gitignore syntax, e.g. vendor/ or *.min.js, whose hits are listed last, in addition to search.down_rank"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("ignore",
+			mcp.Description(ignoreDescription),
+			mcp.Items(map[string]interface{}{"type": "string"}),
 		),
 	)
 
@@ -209,66 +736,238 @@ func main() {
 			return nil, fmt.Errorf("query must be a string")
 		}
 
-		repoName, _ := request.Params.Arguments["repo"].(string)
+		projects, err := tools.StringSliceArgument(request.Params.Arguments, "projects")
+		if err != nil {
+			log.Printf("Invalid projects: %v", err)
+			return nil, err
+		}
 
-		results, err := client.searchRepository(ctx, query, repoName)
+		repoName, ok := request.Params.Arguments["repo"].(string)
+		if !ok && len(projects) == 0 {
+			repoName, _ = client.sessionRepository(ctx, "", "")
+		}
+
+		downRank, err := tools.StringSliceArgument(request.Params.Arguments, "downRank")
 		if err != nil {
-			log.Printf("Error searching repositories: %v", err)
-			return nil, fmt.Errorf("error searching repositories: %w", err)
+			log.Printf("Invalid downRank: %v", err)
+			return nil, err
+		}
+		ignore, err := tools.StringSliceArgument(request.Params.Arguments, "ignore")
+		if err != nil {
+			log.Printf("Invalid ignore: %v", err)
+			return nil, err
+		}
+		folder, _ := request.Params.Arguments["path"].(string)
+		collapse := true
+		if value, ok := request.Params.Arguments["collapse"].(bool); ok {
+			collapse = value
 		}
 
-		jsonData, err := json.Marshal(results)
+		results, err := client.searchRepository(ctx, query, repoName, codeSearchOptions{
+			Projects: projects,
+			Folder:   historyFolder(folder),
+			Collapse: collapse,
+			DownRank: downRank,
+			Ignore:   ignore,
+		})
 		if err != nil {
-			log.Printf("Error marshaling results: %v", err)
-			return nil, fmt.Errorf("error marshaling results: %w", err)
+			log.Printf("Error searching repositories: %v", err)
+			return nil, fmt.Errorf("error searching repositories: %w", err)
 		}
 
-		return mcp.NewToolResultText(string(jsonData)), nil
+		return tools.JSONResult(results)
 	})
 
 	// Add read tool
 	readTool := mcp.NewTool("read",
-		mcp.WithDescription("Read file content from Azure DevOps. The key to getting this to work well is asking for at least 5 results from the search tool, then asking specifically for code examples"),
+		mcp.WithDescription("Read file content from Azure DevOps; the result's _meta has the file's path, size in bytes, language, encoding, line endings, and object ID for write_file. The key to getting this to work well is asking for at least 5 results from the search tool, then asking specifically for code examples"),
 		mcp.WithString("repository",
-			mcp.Required(),
-			mcp.Description("Repository name"),
+			mcp.Description("Repository name; defaults to the session's repository from set_context"),
 		),
 		mcp.WithString("path",
-			mcp.Required(),
-			mcp.Description("File path"),
+			mcp.Description("File path; required unless uri is passed"),
+		),
+		mcp.WithString("ref",
+			mcp.Description("Branch name, refs/tags/<tag>, or commit SHA; defaults to the session's branch, then the default branch"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the repository, path, and ref of a file"),
+		),
+		mcp.WithBoolean("normalizeLineEndings",
+			mcp.Description("Convert CRLF line endings to LF; defaults to files.normalize_line_endings"),
+		),
+		mcp.WithBoolean("stripBom",
+			mcp.Description("Leave out a byte order mark; defaults to files.strip_bom"),
 		),
 	)
 
 	s.AddTool(readTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		repo, ok := request.Params.Arguments["repository"].(string)
-		if !ok {
-			log.Print("Repository must be a string")
-			return nil, fmt.Errorf("repository must be a string")
+		file, ok, err := client.uriArgument(request, fileEntity)
+		if err != nil {
+			log.Printf("Error reading uri: %v", err)
+			return nil, err
 		}
-
-		path, ok := request.Params.Arguments["path"].(string)
+		project, repo, path, ref := file.Project, file.Repository, file.Path, file.Ref
 		if !ok {
-			log.Print("Path must be a string")
-			return nil, fmt.Errorf("path must be a string")
+			project = client.sessionProject(ctx)
+			repo, _ = request.Params.Arguments["repository"].(string)
+			ref, _ = request.Params.Arguments["ref"].(string)
+			repo, ref = client.sessionRepository(ctx, repo, ref)
+			if repo == "" {
+				log.Print("No repository to read from")
+				return nil, fmt.Errorf("repository is required unless set with set_context")
+			}
+
+			path, ok = request.Params.Arguments["path"].(string)
+			if !ok {
+				log.Print("Path must be a string")
+				return nil, fmt.Errorf("path must be a string")
+			}
 		}
 
-		content, err := client.getFileContent(ctx, repo, path)
+		content, err := client.getFileContent(ctx, project, repo, path, ref)
 		if err != nil {
 			log.Printf("Error getting file content: %v", err)
 			return nil, fmt.Errorf("error getting file content: %w", err)
 		}
 
-		return mcp.NewToolResultText(content), nil
+		// Describe the file in _meta, leaving the content as it is
+		text := client.readOptions(request.Params.Arguments).text(content)
+		result := mcp.NewToolResultText(text)
+		result.Meta = map[string]interface{}{"path": path, "size": len(text), "encoding": content.Encoding}
+		if content.BOM {
+			result.Meta["bom"] = true
+		}
+		if content.LineEndings != "" {
+			result.Meta["lineEndings"] = content.LineEndings
+		}
+		if content.ObjectID != "" {
+			result.Meta["objectId"] = content.ObjectID
+			result.Meta["commitId"] = content.CommitID
+		}
+		if language := fileLanguage(path); language != "" {
+			result.Meta["language"] = language
+		}
+		return result, nil
 	})
+}
+
+// useMockFixtures configures the server to answer from the bundled fixtures of the organization
+// mock-org, whatever config.yaml says about the organization and where state is kept.
+func useMockFixtures() {
+	viper.Set("cassette.mode", config.CassetteMock)
+	viper.Set("azure_devops.organization", "mock-org")
+	viper.Set("azure_devops.url", "")
+	viper.Set("azure_devops.project", "MockProject")
+	viper.Set("azure_devops.pat", "mock")
+	viper.Set("cache.backend", "memory")
+	viper.Set("server.state_store", "memory")
+	viper.SetDefault("server.host", "localhost")
+	viper.SetDefault("server.port", 8080)
+}
+
+func main() {
+	record := flag.String("record", "", "record Azure DevOps responses to this cassette file")
+	replay := flag.String("replay", "", "answer Azure DevOps requests from this cassette file instead of Azure DevOps")
+	mock := flag.Bool("mock", false, "answer Azure DevOps requests from the bundled fixtures; needs no organization or PAT")
+	flag.Parse()
+	switch {
+	case *mock:
+		useMockFixtures()
+	case *record != "":
+		viper.Set("cassette.mode", config.CassetteRecord)
+		viper.Set("cassette.path", *record)
+	case *replay != "":
+		viper.Set("cassette.mode", config.CassetteReplay)
+		viper.Set("cassette.path", *replay)
+	}
 
-	// Create SSE server
-	sseServer := server.NewSSEServer(s,
+	client, err := NewAzureDevOpsClient()
+	if err != nil {
+		log.Fatalf("Failed to create Azure DevOps client: %v", err)
+	}
+	sessionAuth := client.config.Server.SessionAuth != "" && client.config.Server.SessionAuth != config.SessionAuthOff
+
+	// Leave out the tools of APIs the PAT has no scope for; with session auth, callers bring their own
+	if client.config.Server.ProbeScopes && !sessionAuth {
+		client.hiddenGroups = client.probeScopes(context.Background())
+	}
+	s := newMCPServer(client)
+	// In multi-tenant mode the indexes and service hook events are left out: they are built with the
+	// service credential and would answer every tenant's callers alike
+	multiTenant := client.config.Server.MultiTenant
+
+	// Keep file listings and statistics of the configured repositories up to date in the background
+	if !multiTenant && len(client.config.RepositoryIndex.Repositories) > 0 {
+		index := newRepositoryIndex(client)
+		go index.run(context.Background())
+		addRepositoryIndexTools(s, index)
+	}
+
+	// Index the configured repositories for semantic search in the background
+	if !multiTenant && len(client.config.SemanticSearch.Repositories) > 0 {
+		index, err := newSemanticIndex(client)
+		if err != nil {
+			log.Printf("Semantic search disabled: %v", err)
+		} else {
+			go index.build(context.Background())
+			addSemanticSearchTools(s, index)
+		}
+	}
+
+	// Create SSE server, routing requests from tools to clients such as sampling through it
+	requests := newClientRequests(sessionAuth)
+	var streams *sseStreams
+	sseServer := server.NewSSEServer(s.MCPServer,
 		server.WithBaseURL(fmt.Sprintf("http://%s:%d", client.config.Server.Host, client.config.Server.Port)),
+		server.WithSSEContextFunc(func(ctx context.Context, req *http.Request) context.Context {
+			return requests.withContext(streams.withContext(withResultEncoding(ctx, req), req), req)
+		}),
 	)
+	requests.sse = sseServer
+	// Keep SSE streams alive through proxies, and let clients resume their sessions on new ones
+	streams = newSSEStreams(requests, sseServer, requests, client.contexts, client.config.SSE)
+
+	mux := http.NewServeMux()
+	if multiTenant {
+		// Serve each tenant named by the gateway's headers over Streamable HTTP only; an SSE session
+		// is bound to the one server it was opened on
+		mux.Handle(streamablePath, newTenantPool(client))
+		log.Printf("Serving tenants from the %s and %s headers on %s", organizationHeader, projectHeader, streamablePath)
+	} else {
+		mux.Handle("/", streams)
+		// Serve Streamable HTTP too, which needs no sticky sessions behind a load balancer
+		mux.Handle(streamablePath, newStreamableHandler(s.MCPServer, client.contexts, sessionAuth))
+	}
+
+	// Expose tool call metrics for scraping
+	mux.Handle(metricsPath, client.metrics)
+
+	// Forward service hook events to watching clients when a webhook secret is configured. With session
+	// auth they are left out: events are not checked against what each caller's credential can read
+	if sessionAuth && client.config.Server.WebhookSecret != "" {
+		log.Print("Service hook events disabled: server.webhook_secret is ignored with session_auth, since events are not filtered per caller")
+	}
+	if !multiTenant && !sessionAuth && client.config.Server.WebhookSecret != "" {
+		if client.config.Server.StateStore == "redis" {
+			log.Print("Service hook events only reach clients watching on the replica that receives the webhook; run a single replica when server.webhook_secret is set")
+		}
+		hub := newEventHub(client.config.Server.WebhookSecret)
+		addEventTools(s, hub)
+		mux.Handle(webhookPath, hub)
+		log.Printf("Receiving service hook events on %s", webhookPath)
+	}
 
 	// Start the SSE server
 	log.Printf("SSE server listening on %s:%d", client.config.Server.Host, client.config.Server.Port)
-	if err := sseServer.Start(fmt.Sprintf("%s:%d", client.config.Server.Host, client.config.Server.Port)); err != nil {
+	var handler http.Handler = mux
+	if client.config.Compression.HTTP {
+		// The SSE stream is left alone: proxies that buffer compressed streams would hold its events
+		handler = withCompression(mux, client.config.Compression.MinBytes, func(req *http.Request) bool {
+			return req.Method == http.MethodGet && req.URL.Path == sseServer.CompleteSsePath()
+		})
+	}
+	if err := http.ListenAndServe(fmt.Sprintf("%s:%d", client.config.Server.Host, client.config.Server.Port), handler); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// mentionToken matches an @mention not preceded by a word character, so email addresses in the
+// text are left alone: @[Display Name] for names with spaces, or @name and @user@example.com.
+var mentionToken = regexp.MustCompile(`(^|[^\w@])@(?:\[([^\]\n]+)\]|([\w][\w.\-]*(?:@[\w\-]+(?:\.[\w\-]+)+)?))`)
+
+// codeSpan matches fenced code blocks and inline code, where @ is not a mention.
+var codeSpan = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+
+// mentionFormat is how a comment marks up a mention: pull request comments are markdown, and work
+// item comments are HTML.
+type mentionFormat int
+
+const (
+	markdownMentions mentionFormat = iota
+	htmlMentions
+)
+
+// mentionMarkup is the markup that makes Azure DevOps notify an identity, which a plain @name does
+// not.
+func mentionMarkup(format mentionFormat, identity ResolvedIdentity) string {
+	if format == htmlMentions {
+		return fmt.Sprintf(`<a href="#" data-vss-mention="version:2.0,%s">@%s</a>`, identity.ID, html.EscapeString(identity.DisplayName))
+	}
+	return "@<" + strings.ToUpper(identity.ID) + ">"
+}
+
+// resolveMention picks the identity a mention names: the only match, or the only one whose display
+// name, email, or email's user name equals the mention.
+func (c *AzureDevOpsClient) resolveMention(ctx context.Context, name string) (ResolvedIdentity, error) {
+	candidates, err := c.resolveIdentities(ctx, name)
+	if err != nil {
+		return ResolvedIdentity{}, err
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	exact := []ResolvedIdentity{}
+	for _, candidate := range candidates {
+		user, _, _ := strings.Cut(candidate.Mail, "@")
+		if strings.EqualFold(candidate.DisplayName, name) || strings.EqualFold(candidate.Mail, name) || user != "" && strings.EqualFold(user, name) {
+			exact = append(exact, candidate)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+	if len(candidates) == 0 {
+		return ResolvedIdentity{}, fmt.Errorf("no user or group matches @%s; write @[Display Name] for names with spaces and put code in backticks", name)
+	}
+	names := []string{}
+	for _, candidate := range candidates {
+		label := candidate.DisplayName
+		if candidate.Mail != "" {
+			label += " <" + candidate.Mail + ">"
+		}
+		names = append(names, label)
+	}
+	return ResolvedIdentity{}, fmt.Errorf("@%s matches %d identities (%s); mention one by email", name, len(candidates), strings.Join(names, ", "))
+}
+
+// resolveMentions replaces the @mentions of a comment outside code with the markup that notifies
+// the identities they name, and returns the identities mentioned. A mention that matches no one,
+// or several, fails the call rather than posting a mention that notifies nobody.
+func (c *AzureDevOpsClient) resolveMentions(ctx context.Context, text string, format mentionFormat) (string, []ResolvedIdentity, error) {
+	resolved := map[string]ResolvedIdentity{}
+	mentioned := []ResolvedIdentity{}
+	replace := func(segment string) (string, error) {
+		var failure error
+		result := mentionToken.ReplaceAllStringFunc(segment, func(match string) string {
+			parts := mentionToken.FindStringSubmatch(match)
+			name := strings.TrimSpace(parts[2])
+			suffix := ""
+			if name == "" {
+				// A period or hyphen ending a plain mention ends the sentence, not the name.
+				name = strings.TrimRight(parts[3], ".-")
+				suffix = parts[3][len(name):]
+			}
+			key := strings.ToLower(name)
+			identity, ok := resolved[key]
+			if !ok {
+				var err error
+				identity, err = c.resolveMention(ctx, name)
+				if err != nil {
+					if failure == nil {
+						failure = err
+					}
+					return match
+				}
+				resolved[key] = identity
+				mentioned = append(mentioned, identity)
+			}
+			return parts[1] + mentionMarkup(format, identity) + suffix
+		})
+		return result, failure
+	}
+
+	var builder strings.Builder
+	last := 0
+	for _, span := range codeSpan.FindAllStringIndex(text, -1) {
+		segment, err := replace(text[last:span[0]])
+		if err != nil {
+			return "", nil, err
+		}
+		builder.WriteString(segment)
+		builder.WriteString(text[span[0]:span[1]])
+		last = span[1]
+	}
+	segment, err := replace(text[last:])
+	if err != nil {
+		return "", nil, err
+	}
+	builder.WriteString(segment)
+	return builder.String(), mentioned, nil
+}
+
+// mentionNames lists the display names of mentioned identities for a tool's result.
+func mentionNames(identities []ResolvedIdentity) []string {
+	names := []string{}
+	for _, identity := range identities {
+		names = append(names, identity.DisplayName)
+	}
+	return names
+}
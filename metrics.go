@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// metricsPath serves the tool metrics in the Prometheus text format.
+	metricsPath = "/metrics"
+	// maxLoggedArgumentLength shortens long arguments, such as file contents, in slow-call logs.
+	maxLoggedArgumentLength = 200
+)
+
+// secretArguments are the substrings of argument names whose values are never logged.
+var secretArguments = []string{"pat", "token", "password", "secret", "credential"}
+
+// errorCategories sorts failed calls by substrings of their errors, checked in order. Errors reach
+// the server's hooks as text, so the categories are read from the messages of Azure DevOps and of
+// the tools.
+var errorCategories = []struct {
+	category string
+	markers  []string
+}{
+	{"cancelled", []string{"context canceled"}},
+	{"timeout", []string{"deadline exceeded", "timeout", "timed out"}},
+	{"degraded", []string{"service degraded"}},
+	{"throttled", []string{"429", "too many requests", "rate limit", "throttl"}},
+	{"permission", []string{"401", "403", "unauthorized", "not authorized", "permission", "access denied", "credential"}},
+	{"not_found", []string{"not found", "does not exist", "could not be found"}},
+	{"invalid_arguments", []string{"must be", "is required", "invalid", "unknown"}},
+}
+
+// toolStats are the totals of one tool.
+type toolStats struct {
+	calls         int64
+	errors        map[string]int64
+	seconds       float64
+	maxSeconds    float64
+	requestBytes  int64
+	responseBytes int64
+}
+
+// toolMetrics records the calls of every tool: how many, how long they took, how large their
+// arguments and results were, and how they failed. It hooks into the MCP servers, which see each
+// call whatever its handler, and logs calls slower than slowCall with their arguments redacted.
+type toolMetrics struct {
+	slowCall time.Duration
+
+	mu      sync.Mutex
+	started map[*mcp.CallToolRequest]time.Time
+	tools   map[string]*toolStats
+}
+
+// newToolMetrics returns metrics logging calls slower than slowCallSeconds; zero logs none.
+func newToolMetrics(slowCallSeconds float64) *toolMetrics {
+	return &toolMetrics{
+		slowCall: time.Duration(slowCallSeconds * float64(time.Second)),
+		started:  map[*mcp.CallToolRequest]time.Time{},
+		tools:    map[string]*toolStats{},
+	}
+}
+
+// hooks returns the server hooks that record tool calls. A call's request is the same value from
+// its start to its end, so it keys the call's start time.
+func (m *toolMetrics) hooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddBeforeCallTool(func(id any, request *mcp.CallToolRequest) {
+		m.mu.Lock()
+		m.started[request] = time.Now()
+		m.mu.Unlock()
+	})
+	hooks.AddAfterCallTool(func(id any, request *mcp.CallToolRequest, result *mcp.CallToolResult) {
+		category := ""
+		if result.IsError {
+			category = "tool_error"
+		}
+		m.record(request, resultSize(result), category, "")
+	})
+	hooks.AddOnError(func(id any, method mcp.MCPMethod, message any, err error) {
+		if request, ok := message.(*mcp.CallToolRequest); ok && method == mcp.MethodToolsCall {
+			m.record(request, 0, errorCategory(err), err.Error())
+		}
+	})
+	return hooks
+}
+
+// record ends a call; category is empty for a call that succeeded.
+func (m *toolMetrics) record(request *mcp.CallToolRequest, responseBytes int, category, message string) {
+	name := request.Params.Name
+	if strings.Contains(message, fmt.Sprintf("tool '%s' not found", name)) {
+		// Clients choose the names they call; only the server's own tools get series of their own.
+		name = "unknown"
+	}
+	arguments, _ := json.Marshal(request.Params.Arguments)
+
+	m.mu.Lock()
+	start, ok := m.started[request]
+	delete(m.started, request)
+	if !ok {
+		// The request did not parse, so the call never started.
+		start = time.Now()
+	}
+	elapsed := time.Since(start)
+	stats, ok := m.tools[name]
+	if !ok {
+		stats = &toolStats{errors: map[string]int64{}}
+		m.tools[name] = stats
+	}
+	stats.calls++
+	if category != "" {
+		stats.errors[category]++
+	}
+	stats.seconds += elapsed.Seconds()
+	if elapsed.Seconds() > stats.maxSeconds {
+		stats.maxSeconds = elapsed.Seconds()
+	}
+	stats.requestBytes += int64(len(arguments))
+	stats.responseBytes += int64(responseBytes)
+	m.mu.Unlock()
+
+	if m.slowCall > 0 && elapsed >= m.slowCall {
+		logged, _ := json.Marshal(redactArguments(request.Params.Arguments))
+		if message != "" {
+			log.Printf("Slow call of %s took %s and failed (%s): %s", name, elapsed.Round(time.Millisecond), message, logged)
+		} else {
+			log.Printf("Slow call of %s took %s: %s", name, elapsed.Round(time.Millisecond), logged)
+		}
+	}
+}
+
+// resultSize returns the bytes of text and data a result carries.
+func resultSize(result *mcp.CallToolResult) int {
+	size := 0
+	for _, content := range result.Content {
+		switch content := content.(type) {
+		case mcp.TextContent:
+			size += len(content.Text)
+		case mcp.ImageContent:
+			size += len(content.Data)
+		case mcp.EmbeddedResource:
+			if resource, ok := content.Resource.(mcp.TextResourceContents); ok {
+				size += len(resource.Text)
+			}
+		}
+	}
+	return size
+}
+
+func errorCategory(err error) string {
+	message := strings.ToLower(err.Error())
+	for _, category := range errorCategories {
+		for _, marker := range category.markers {
+			if strings.Contains(message, marker) {
+				return category.category
+			}
+		}
+	}
+	return "other"
+}
+
+// redactArguments returns the arguments of a call fit for a log: secrets replaced, long strings
+// shortened, and nested values redacted the same way.
+func redactArguments(arguments map[string]interface{}) map[string]interface{} {
+	redacted := map[string]interface{}{}
+	for name, value := range arguments {
+		lower := strings.ToLower(name)
+		secret := false
+		for _, marker := range secretArguments {
+			if strings.Contains(lower, marker) {
+				secret = true
+				break
+			}
+		}
+		if secret {
+			redacted[name] = "[redacted]"
+			continue
+		}
+		redacted[name] = redactValue(value)
+	}
+	return redacted
+}
+
+func redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		if len(value) > maxLoggedArgumentLength {
+			return fmt.Sprintf("%s... (%d bytes)", value[:maxLoggedArgumentLength], len(value))
+		}
+		return value
+	case map[string]interface{}:
+		return redactArguments(value)
+	case []interface{}:
+		items := make([]interface{}, len(value))
+		for i, item := range value {
+			items[i] = redactValue(item)
+		}
+		return items
+	}
+	return value
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *toolMetrics) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.tools))
+	for name := range m.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP mcp_tool_calls_total Tool calls, by tool.")
+	fmt.Fprintln(w, "# TYPE mcp_tool_calls_total counter")
+	for _, name := range names {
+		fmt.Fprintf(w, "mcp_tool_calls_total{tool=%q} %d\n", name, m.tools[name].calls)
+	}
+	fmt.Fprintln(w, "# HELP mcp_tool_errors_total Failed tool calls, by tool and category.")
+	fmt.Fprintln(w, "# TYPE mcp_tool_errors_total counter")
+	for _, name := range names {
+		categories := make([]string, 0, len(m.tools[name].errors))
+		for category := range m.tools[name].errors {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		for _, category := range categories {
+			fmt.Fprintf(w, "mcp_tool_errors_total{tool=%q,category=%q} %d\n", name, category, m.tools[name].errors[category])
+		}
+	}
+	fmt.Fprintln(w, "# HELP mcp_tool_duration_seconds Time spent in tool calls, by tool.")
+	fmt.Fprintln(w, "# TYPE mcp_tool_duration_seconds summary")
+	for _, name := range names {
+		fmt.Fprintf(w, "mcp_tool_duration_seconds_sum{tool=%q} %g\n", name, m.tools[name].seconds)
+		fmt.Fprintf(w, "mcp_tool_duration_seconds_count{tool=%q} %d\n", name, m.tools[name].calls)
+	}
+	fmt.Fprintln(w, "# HELP mcp_tool_duration_seconds_max Longest tool call, by tool.")
+	fmt.Fprintln(w, "# TYPE mcp_tool_duration_seconds_max gauge")
+	for _, name := range names {
+		fmt.Fprintf(w, "mcp_tool_duration_seconds_max{tool=%q} %g\n", name, m.tools[name].maxSeconds)
+	}
+	fmt.Fprintln(w, "# HELP mcp_tool_request_bytes_total Size of tool call arguments, by tool.")
+	fmt.Fprintln(w, "# TYPE mcp_tool_request_bytes_total counter")
+	for _, name := range names {
+		fmt.Fprintf(w, "mcp_tool_request_bytes_total{tool=%q} %d\n", name, m.tools[name].requestBytes)
+	}
+	fmt.Fprintln(w, "# HELP mcp_tool_response_bytes_total Size of tool results, by tool.")
+	fmt.Fprintln(w, "# TYPE mcp_tool_response_bytes_total counter")
+	for _, name := range names {
+		fmt.Fprintf(w, "mcp_tool_response_bytes_total{tool=%q} %d\n", name, m.tools[name].responseBytes)
+	}
+}
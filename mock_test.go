@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	mockServerOnce sync.Once
+	mockServer     *toolServer
+	mockServerErr  error
+)
+
+// newMockServer returns a server with write tools answering from the bundled fixtures. The
+// cassette replaces http.DefaultTransport, so all tests share one server.
+func newMockServer(t *testing.T) *toolServer {
+	t.Helper()
+	mockServerOnce.Do(func() {
+		useMockFixtures()
+		viper.Set("server.allow_writes", true)
+		client, err := NewAzureDevOpsClient()
+		if err != nil {
+			mockServerErr = err
+			return
+		}
+		mockServer = newMCPServer(client)
+	})
+	if mockServerErr != nil {
+		t.Fatalf("error creating mock server: %v", mockServerErr)
+	}
+	return mockServer
+}
+
+// toolResult is the result of a tool call as a client receives it.
+type toolResult struct {
+	Meta    map[string]interface{} `json:"_meta"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	IsError bool `json:"isError"`
+}
+
+// sendToolCall calls a tool of the mock server through the MCP protocol and returns its result,
+// or the message of the error it failed with.
+func sendToolCall(t *testing.T, name string, arguments map[string]interface{}) (*toolResult, string) {
+	t.Helper()
+	request, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": name, "arguments": arguments},
+	})
+	if err != nil {
+		t.Fatalf("error encoding request: %v", err)
+	}
+	data, err := json.Marshal(newMockServer(t).HandleMessage(context.Background(), request))
+	if err != nil {
+		t.Fatalf("error encoding response: %v", err)
+	}
+	var response struct {
+		Result *toolResult `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		t.Fatalf("error decoding response %s: %v", data, err)
+	}
+	if response.Error != nil {
+		return nil, response.Error.Message
+	}
+	if response.Result == nil {
+		t.Fatalf("%s returned %s", name, data)
+	}
+	return response.Result, ""
+}
+
+// callTool calls a tool of the mock server and returns the text of its result, failing the test
+// when the call fails.
+func callTool(t *testing.T, name string, arguments map[string]interface{}) (string, toolResult) {
+	t.Helper()
+	result, message := sendToolCall(t, name, arguments)
+	if message != "" {
+		t.Fatalf("%s failed: %s", name, message)
+	}
+	if len(result.Content) != 1 || result.IsError {
+		t.Fatalf("%s returned %+v", name, result)
+	}
+	return result.Content[0].Text, *result
+}
+
+func TestMockRead(t *testing.T) {
+	text, result := callTool(t, "read", map[string]interface{}{
+		"repository": "mock-service",
+		"path":       "/README.md",
+	})
+
+	if want := "# mock-service\n\nA repository served by the bundled mock fixtures.\n"; text != want {
+		t.Errorf("read returned %q, want %q", text, want)
+	}
+	if result.Meta["objectId"] != "4b825dc642cb6eb9a060e54bf8d69288fbee4904" {
+		t.Errorf("read returned objectId %v", result.Meta["objectId"])
+	}
+	if result.Meta["commitId"] != "9f1c2a3b4d5e6f708192a3b4c5d6e7f801234567" {
+		t.Errorf("read returned commitId %v", result.Meta["commitId"])
+	}
+}
+
+func TestMockSearch(t *testing.T) {
+	text, _ := callTool(t, "search", map[string]interface{}{
+		"query": "mock",
+	})
+
+	var hits []struct {
+		Path       string   `json:"path"`
+		Repository string   `json:"repository"`
+		Project    string   `json:"project"`
+		Branches   []string `json:"branches"`
+		Matches    int      `json:"matches"`
+	}
+	if err := json.Unmarshal([]byte(text), &hits); err != nil {
+		t.Fatalf("error decoding search results %s: %v", text, err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("search returned %d hits, want 1: %s", len(hits), text)
+	}
+	hit := hits[0]
+	if hit.Path != "/README.md" || hit.Repository != "mock-service" || hit.Project != "MockProject" {
+		t.Errorf("search returned %s in %s/%s", hit.Path, hit.Project, hit.Repository)
+	}
+	if len(hit.Branches) != 1 || hit.Branches[0] != "main" || hit.Matches != 1 {
+		t.Errorf("search returned %d matches on %v", hit.Matches, hit.Branches)
+	}
+}
+
+func TestMockWriteFileDryRun(t *testing.T) {
+	// A dry run reads the branch and file but pushes nothing; the fixtures have no push to replay.
+	text, _ := callTool(t, "write_file", map[string]interface{}{
+		"repository":       "mock-service",
+		"branch":           "main",
+		"newBranch":        "docs",
+		"path":             "/README.md",
+		"content":          "# mock-service\n",
+		"message":          "Shorten the README",
+		"expectedObjectId": "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		"dryRun":           true,
+	})
+
+	var result struct {
+		DryRun  bool   `json:"dryRun"`
+		Action  string `json:"action"`
+		Changes struct {
+			Branch     string              `json:"branch"`
+			BaseCommit string              `json:"baseCommit"`
+			Files      []fileChangeSummary `json:"files"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		t.Fatalf("error decoding dry run %s: %v", text, err)
+	}
+	if !result.DryRun || result.Action != "write file" {
+		t.Fatalf("write_file returned %s, want a dry run", text)
+	}
+	if result.Changes.Branch != "refs/heads/docs" || result.Changes.BaseCommit != "9f1c2a3b4d5e6f708192a3b4c5d6e7f801234567" {
+		t.Errorf("write_file would commit to %s on top of %s", result.Changes.Branch, result.Changes.BaseCommit)
+	}
+	if len(result.Changes.Files) != 1 || result.Changes.Files[0].Path != "/README.md" || result.Changes.Files[0].ChangeType != "edit" {
+		t.Errorf("write_file would change %+v", result.Changes.Files)
+	}
+}
+
+func TestMockWriteFileDryRunConflict(t *testing.T) {
+	// The file exists, so a write expecting it not to is rejected before anything is pushed.
+	_, message := sendToolCall(t, "write_file", map[string]interface{}{
+		"repository":       "mock-service",
+		"branch":           "main",
+		"newBranch":        "docs",
+		"path":             "/README.md",
+		"content":          "# mock-service\n",
+		"message":          "Add a README",
+		"expectedObjectId": nullObjectID,
+		"dryRun":           true,
+	})
+	if !strings.Contains(message, "conflict") {
+		t.Errorf("write_file of an existing file returned %q, want a conflict", message)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxMyWorkItems caps the assigned work items a work summary lists.
+const maxMyWorkItems = 100
+
+// finishedStates are the work item states of the default processes that count as done, so their
+// work items are left out of a work summary.
+var finishedStates = []string{"Closed", "Done", "Removed", "Completed", "Cut"}
+
+// assignedWorkItems returns the unfinished work items of the project assigned to a user, most
+// recently changed first. assignee is a WIQL value: @Me or a quoted name or email.
+func (c *AzureDevOpsClient) assignedWorkItems(ctx context.Context, assignee string) ([]map[string]interface{}, bool, error) {
+	states := []string{}
+	for _, state := range finishedStates {
+		states = append(states, wiqlString(state))
+	}
+	wiql := fmt.Sprintf("SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @project AND [System.AssignedTo] = %s AND [System.State] NOT IN (%s) ORDER BY [System.ChangedDate] DESC",
+		assignee, strings.Join(states, ", "))
+	ids, err := c.queryWorkItemIDs(ctx, wiql)
+	if err != nil {
+		return nil, false, err
+	}
+	truncated := len(ids) > maxMyWorkItems
+	if truncated {
+		ids = ids[:maxMyWorkItems]
+	}
+	summaries, err := c.workItemSummaries(ctx, ids)
+	if err != nil {
+		return nil, false, err
+	}
+	// The batch comes back in no particular order; keep the query's.
+	byID := map[int]map[string]interface{}{}
+	for _, summary := range summaries {
+		byID[summary["id"].(int)] = summary
+	}
+	ordered := []map[string]interface{}{}
+	for _, id := range ids {
+		if summary, ok := byID[id]; ok {
+			delete(summary, "assignedTo")
+			ordered = append(ordered, summary)
+		}
+	}
+	return ordered, truncated, nil
+}
+
+// activePullRequests returns the active pull requests of the project matching criteria.
+func (c *AzureDevOpsClient) activePullRequests(ctx context.Context, criteria git.GitPullRequestSearchCriteria) ([]git.GitPullRequest, error) {
+	status := git.PullRequestStatusValues.Active
+	criteria.Status = &status
+	top := maxThreadPullRequests
+	pullRequests, err := c.gitClient.GetPullRequestsByProject(ctx, git.GetPullRequestsByProjectArgs{
+		Project:        &c.config.AzureDevOps.Project,
+		SearchCriteria: &criteria,
+		Top:            &top,
+	})
+	if err != nil {
+		log.Printf("Error getting pull requests: %v", err)
+		return nil, fmt.Errorf("error getting active pull requests: %w", err)
+	}
+	return *pullRequests, nil
+}
+
+// workPullRequest summarizes a pull request for a work summary, without its description.
+func (c *AzureDevOpsClient) workPullRequest(pr git.GitPullRequest) map[string]interface{} {
+	entry := c.pullRequestSummary(pr)
+	delete(entry, "description")
+	return entry
+}
+
+// myWork summarizes what is on a user's plate for a standup: their unfinished work items, the
+// active pull requests waiting on their vote, and their own active pull requests. query names the
+// user by identity ID, email, or name, and defaults to the user the server authenticates as. Each
+// part is read independently and a failing one is reported under errors.
+func (c *AzureDevOpsClient) myWork(ctx context.Context, query string) (map[string]interface{}, error) {
+	user, err := c.threadUserFor(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("user %s has no identity ID", user.Name)
+	}
+	// WIQL knows the authenticated user as @Me, and others by name or email.
+	assignee := "@Me"
+	switch {
+	case query == "":
+	case strings.Contains(query, "@"):
+		assignee = wiqlString(query)
+	default:
+		assignee = wiqlString(user.Name)
+	}
+
+	result := map[string]interface{}{
+		"user": user.Name,
+	}
+	errors := map[string]string{}
+
+	workItems, truncated, err := c.assignedWorkItems(ctx, assignee)
+	if err != nil {
+		errors["workItems"] = err.Error()
+	} else {
+		result["workItems"] = workItems
+		if truncated {
+			result["truncatedWorkItems"] = fmt.Sprintf("only the %d most recently changed work items are listed", maxMyWorkItems)
+		}
+	}
+
+	// A reviewer who has not voted, or voted to wait for the author, is still to review.
+	toReview, err := c.activePullRequests(ctx, git.GitPullRequestSearchCriteria{ReviewerId: &userID})
+	if err != nil {
+		errors["reviews"] = err.Error()
+	} else {
+		reviews := []map[string]interface{}{}
+		for _, pr := range toReview {
+			if pr.IsDraft != nil && *pr.IsDraft || pr.Reviewers == nil {
+				continue
+			}
+			for _, reviewer := range *pr.Reviewers {
+				if !strings.EqualFold(stringValue(reviewer.Id), user.ID) {
+					continue
+				}
+				if reviewer.Vote == nil || *reviewer.Vote == 0 {
+					entry := c.workPullRequest(pr)
+					entry["isRequired"] = reviewer.IsRequired != nil && *reviewer.IsRequired
+					reviews = append(reviews, entry)
+				}
+				break
+			}
+		}
+		result["awaitingReview"] = reviews
+	}
+
+	created, err := c.activePullRequests(ctx, git.GitPullRequestSearchCriteria{CreatorId: &userID})
+	if err != nil {
+		errors["pullRequests"] = err.Error()
+	} else {
+		own := []map[string]interface{}{}
+		for _, pr := range created {
+			own = append(own, c.workPullRequest(pr))
+		}
+		result["pullRequests"] = own
+	}
+
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addMyWorkTools(s tools.Server, client *AzureDevOpsClient) {
+	myWorkTool := mcp.NewTool("get_my_work",
+		mcp.WithDescription("Summarize a user's work for a standup in one call: their unfinished work items, active pull requests waiting on their review, and their own active pull requests with reviewer votes"),
+		mcp.WithString("user",
+			mcp.Description("Identity ID, email, or name of the user; defaults to the user the server authenticates as"),
+		),
+	)
+
+	s.AddTool(myWorkTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		user, _ := request.Params.Arguments["user"].(string)
+
+		result, err := client.myWork(ctx, user)
+		if err != nil {
+			log.Printf("Error getting work summary: %v", err)
+			return nil, fmt.Errorf("error getting work summary: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/notification"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// notificationSubscriptionsLocationID is the notification subscriptions REST resource.
+var notificationSubscriptionsLocationID = uuid.MustParse("70f911d6-abac-488c-85b3-a206bf57e165")
+
+// notificationEvents names the events a personal subscription is commonly made for.
+var notificationEvents = map[string]string{
+	"pullrequest": "ms.vss-code.git-pullrequest-event",
+	"push":        "ms.vss-code.git-push-event",
+	"build":       "ms.vss-build.build-completed-event",
+	"workitem":    "ms.vss-work.workitem-changed-event",
+}
+
+// subscriptionFilter is the filter of a notification subscription including the criteria of
+// expression filters, which the SDK model lacks.
+type subscriptionFilter struct {
+	Type      string                              `json:"type,omitempty"`
+	EventType string                              `json:"eventType,omitempty"`
+	Criteria  *notification.ExpressionFilterModel `json:"criteria,omitempty"`
+}
+
+// subscriptionDetails is a notification subscription with the criteria of its filter.
+type subscriptionDetails struct {
+	notification.NotificationSubscription
+	Filter *subscriptionFilter `json:"filter,omitempty"`
+}
+
+// isPersonal reports whether a subscription belongs to the user rather than to a team or group
+// the user is in.
+func (s subscriptionDetails) isPersonal(userID string) bool {
+	return s.Subscriber != nil && strings.EqualFold(stringValue(s.Subscriber.Id), userID)
+}
+
+// criteriaText renders expression filter clauses as the Azure DevOps settings page shows them,
+// e.g. Repository = api AND Target branch = refs/heads/main.
+func criteriaText(criteria *notification.ExpressionFilterModel) string {
+	if criteria == nil || criteria.Clauses == nil {
+		return ""
+	}
+	parts := []string{}
+	for _, clause := range *criteria.Clauses {
+		if operator := stringValue(clause.LogicalOperator); operator != "" && len(parts) > 0 {
+			parts = append(parts, operator)
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", stringValue(clause.FieldName), stringValue(clause.Operator), stringValue(clause.Value)))
+	}
+	return strings.Join(parts, " ")
+}
+
+func notificationSubscriptionSummary(subscription subscriptionDetails, userID string) map[string]interface{} {
+	entry := map[string]interface{}{
+		"id":          stringValue(subscription.Id),
+		"description": stringValue(subscription.Description),
+		"personal":    subscription.isPersonal(userID),
+	}
+	if subscription.Filter != nil {
+		entry["eventType"] = subscription.Filter.EventType
+		if criteria := criteriaText(subscription.Filter.Criteria); criteria != "" {
+			entry["criteria"] = criteria
+		}
+	}
+	if subscription.Status != nil {
+		entry["status"] = string(*subscription.Status)
+	}
+	if subscription.Channel != nil {
+		entry["channel"] = stringValue(subscription.Channel.Type)
+	}
+	if subscription.Scope != nil {
+		entry["scope"] = stringValue(subscription.Scope.Name)
+	}
+	if !entry["personal"].(bool) {
+		if subscription.Subscriber != nil {
+			entry["subscriber"] = stringValue(subscription.Subscriber.DisplayName)
+		}
+		entry["canOptOut"] = subscription.Flags != nil && strings.Contains(string(*subscription.Flags), string(notification.SubscriptionFlagsValues.CanOptOut))
+		entry["optedOut"] = subscription.UserSettings != nil && subscription.UserSettings.OptedOut != nil && *subscription.UserSettings.OptedOut
+	}
+	return entry
+}
+
+// notificationSubscriptions returns the subscriptions that notify the user: personal ones, and
+// those of the user's teams and groups.
+func (c *AzureDevOpsClient) notificationSubscriptions(ctx context.Context, userID string) ([]subscriptionDetails, error) {
+	var page struct {
+		Value []subscriptionDetails `json:"value"`
+	}
+	query := url.Values{}
+	query.Add("targetId", userID)
+	query.Add("queryFlags", string(notification.SubscriptionQueryFlagsValues.IncludeFilterDetails))
+	if err := c.sendJSON(ctx, http.MethodGet, notificationSubscriptionsLocationID, "7.1-preview.1", nil, query, nil, &page); err != nil {
+		log.Printf("Error listing notification subscriptions: %v", err)
+		return nil, fmt.Errorf("error listing notification subscriptions: %w", err)
+	}
+	return page.Value, nil
+}
+
+// listNotificationSubscriptions summarizes the subscriptions that notify the user the server
+// authenticates as, personal ones first.
+func (c *AzureDevOpsClient) listNotificationSubscriptions(ctx context.Context) (map[string]interface{}, error) {
+	user, err := c.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, err := c.notificationSubscriptions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []map[string]interface{}{}
+	for _, subscription := range subscriptions {
+		results = append(results, notificationSubscriptionSummary(subscription, user.ID))
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i]["personal"].(bool) && !results[j]["personal"].(bool)
+	})
+	return map[string]interface{}{
+		"user":          user.Name,
+		"subscriptions": results,
+	}, nil
+}
+
+// findNotificationSubscription returns a subscription that notifies the user by ID.
+func (c *AzureDevOpsClient) findNotificationSubscription(ctx context.Context, user threadUser, id string) (subscriptionDetails, error) {
+	subscriptions, err := c.notificationSubscriptions(ctx, user.ID)
+	if err != nil {
+		return subscriptionDetails{}, err
+	}
+	for _, subscription := range subscriptions {
+		if stringValue(subscription.Id) == id {
+			return subscription, nil
+		}
+	}
+	return subscriptionDetails{}, fmt.Errorf("no notification subscription %s notifies %s", id, user.Name)
+}
+
+// subscribeNotifications creates a personal subscription of the user to an event of the project,
+// delivered to the user's preferred email address. Each filter is a field of the event that must
+// equal a value, e.g. Repository = api.
+func (c *AzureDevOpsClient) subscribeNotifications(ctx context.Context, event, description string, filters map[string]string, dryRun bool) (map[string]interface{}, error) {
+	eventType, ok := notificationEvents[event]
+	if !ok {
+		eventType = event
+	}
+	user, err := c.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	projectID, err := c.projectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(filters))
+	for field := range filters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	clauses := []map[string]interface{}{}
+	for i, field := range fields {
+		clause := map[string]interface{}{
+			"index":     i + 1,
+			"fieldName": field,
+			"operator":  "=",
+			"value":     filters[field],
+		}
+		if i > 0 {
+			clause["logicalOperator"] = "And"
+		}
+		clauses = append(clauses, clause)
+	}
+	if description == "" {
+		description = fmt.Sprintf("%s in %s", event, c.config.AzureDevOps.Project)
+		for _, field := range fields {
+			description += fmt.Sprintf(", %s %s", field, filters[field])
+		}
+	}
+
+	body := map[string]interface{}{
+		"description": description,
+		"filter": map[string]interface{}{
+			"type":      "Expression",
+			"eventType": eventType,
+			"criteria": map[string]interface{}{
+				"clauses":       clauses,
+				"groups":        []interface{}{},
+				"maxGroupLevel": 0,
+			},
+		},
+		"channel":    map[string]interface{}{"type": "User"},
+		"subscriber": map[string]interface{}{"id": user.ID},
+		"scope":      map[string]interface{}{"id": projectID},
+	}
+	if dryRun {
+		return tools.DryRunResult("create notification subscription", body), nil
+	}
+
+	var subscription subscriptionDetails
+	if err := c.sendJSON(ctx, http.MethodPost, notificationSubscriptionsLocationID, "7.1-preview.1", nil, nil, body, &subscription); err != nil {
+		log.Printf("Error creating notification subscription: %v", err)
+		return nil, fmt.Errorf("error creating notification subscription: %w", err)
+	}
+	return notificationSubscriptionSummary(subscription, user.ID), nil
+}
+
+// setNotificationSubscription turns a subscription on or off for the user: a personal one by its
+// status, and one of a team or group by opting out of it, which leaves it on for everyone else.
+func (c *AzureDevOpsClient) setNotificationSubscription(ctx context.Context, id string, enabled, dryRun bool) (map[string]interface{}, error) {
+	user, err := c.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := c.findNotificationSubscription(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+	personal := subscription.isPersonal(user.ID)
+	summary := notificationSubscriptionSummary(subscription, user.ID)
+	if !personal && !summary["canOptOut"].(bool) {
+		return nil, fmt.Errorf("subscription %s of %s cannot be opted out of", id, summary["subscriber"])
+	}
+
+	if dryRun {
+		summary["newEnabled"] = enabled
+		return tools.DryRunResult("update notification subscription", summary), nil
+	}
+
+	if personal {
+		status := notification.SubscriptionStatusValues.Enabled
+		if !enabled {
+			status = notification.SubscriptionStatusValues.Disabled
+		}
+		if _, err := c.notificationClient.UpdateSubscription(ctx, notification.UpdateSubscriptionArgs{
+			UpdateParameters: &notification.NotificationSubscriptionUpdateParameters{Status: &status},
+			SubscriptionId:   &id,
+		}); err != nil {
+			log.Printf("Error updating notification subscription: %v", err)
+			return nil, fmt.Errorf("error updating notification subscription %s: %w", id, err)
+		}
+	} else {
+		userID, err := uuid.Parse(user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID %s: %w", user.ID, err)
+		}
+		optedOut := !enabled
+		if _, err := c.notificationClient.UpdateSubscriptionUserSettings(ctx, notification.UpdateSubscriptionUserSettingsArgs{
+			UserSettings:   &notification.SubscriptionUserSettings{OptedOut: &optedOut},
+			SubscriptionId: &id,
+			UserId:         &userID,
+		}); err != nil {
+			log.Printf("Error updating notification subscription settings: %v", err)
+			return nil, fmt.Errorf("error opting out of notification subscription %s: %w", id, err)
+		}
+	}
+
+	return map[string]interface{}{
+		"id":       id,
+		"personal": personal,
+		"enabled":  enabled,
+	}, nil
+}
+
+// deleteNotificationSubscription deletes a personal subscription of the user.
+func (c *AzureDevOpsClient) deleteNotificationSubscription(ctx context.Context, id string, dryRun bool) (map[string]interface{}, error) {
+	user, err := c.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := c.findNotificationSubscription(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+	if !subscription.isPersonal(user.ID) {
+		return nil, fmt.Errorf("subscription %s belongs to %s; opt out of it with set_notification_subscription instead", id, stringValue(subscription.Subscriber.DisplayName))
+	}
+	summary := notificationSubscriptionSummary(subscription, user.ID)
+	if dryRun {
+		return tools.DryRunResult("delete notification subscription", summary), nil
+	}
+
+	if err := c.notificationClient.DeleteSubscription(ctx, notification.DeleteSubscriptionArgs{
+		SubscriptionId: &id,
+	}); err != nil {
+		log.Printf("Error deleting notification subscription: %v", err)
+		return nil, fmt.Errorf("error deleting notification subscription %s: %w", id, err)
+	}
+	return map[string]interface{}{
+		"deleted":     id,
+		"description": summary["description"],
+	}, nil
+}
+
+func addNotificationTools(s tools.Server, client *AzureDevOpsClient) {
+	listTool := mcp.NewTool("list_notification_subscriptions",
+		mcp.WithDescription("List the notification subscriptions that email the user the server authenticates as: personal ones, and those of the user's teams and groups with whether the user opted out"),
+	)
+
+	s.AddTool(listTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := client.listNotificationSubscriptions(ctx)
+		if err != nil {
+			log.Printf("Error listing notification subscriptions: %v", err)
+			return nil, fmt.Errorf("error listing notification subscriptions: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	subscribeTool := mcp.NewTool("subscribe_notifications",
+		mcp.WithDescription("Subscribe the user the server authenticates as to email notifications of an event in the project, e.g. pull requests of one repository"),
+		mcp.WithString("event",
+			mcp.Required(),
+			mcp.Description("pullrequest, push, build, or workitem, or a notification event type ID"),
+		),
+		mcp.WithObject("filters",
+			mcp.Description("Fields of the event that must equal a value, e.g. {\"Repository\": \"api\", \"Target branch\": \"refs/heads/main\"}"),
+		),
+		mcp.WithString("description",
+			mcp.Description("Name of the subscription; defaults to one made from the event and filters"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(subscribeTool, writeHandler(client, subscribeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		event, ok := request.Params.Arguments["event"].(string)
+		if !ok {
+			log.Print("Event must be a string")
+			return nil, fmt.Errorf("event must be a string")
+		}
+		description, _ := request.Params.Arguments["description"].(string)
+		filters := map[string]string{}
+		if raw, ok := request.Params.Arguments["filters"].(map[string]interface{}); ok {
+			for field, value := range raw {
+				filters[field] = fmt.Sprint(value)
+			}
+		}
+
+		result, err := client.subscribeNotifications(ctx, event, description, filters, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error creating notification subscription: %v", err)
+			return nil, fmt.Errorf("error creating notification subscription: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	setTool := mcp.NewTool("set_notification_subscription",
+		mcp.WithDescription("Turn a notification subscription on or off for the user the server authenticates as: a personal subscription is enabled or disabled, and one of a team or group is opted out of or back into"),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("Subscription ID from list_notification_subscriptions"),
+		),
+		mcp.WithBoolean("enabled",
+			mcp.Required(),
+			mcp.Description("Whether the subscription should notify the user"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(setTool, writeHandler(client, setTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, ok := request.Params.Arguments["id"].(string)
+		if !ok {
+			log.Print("ID must be a string")
+			return nil, fmt.Errorf("id must be a string")
+		}
+		enabled, ok := request.Params.Arguments["enabled"].(bool)
+		if !ok {
+			log.Print("Enabled must be a boolean")
+			return nil, fmt.Errorf("enabled must be a boolean")
+		}
+
+		result, err := client.setNotificationSubscription(ctx, id, enabled, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error updating notification subscription: %v", err)
+			return nil, fmt.Errorf("error updating notification subscription: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	deleteTool := mcp.NewTool("delete_notification_subscription",
+		mcp.WithDescription("Delete a personal notification subscription of the user the server authenticates as"),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("Subscription ID from list_notification_subscriptions"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(deleteTool, writeHandler(client, deleteTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, ok := request.Params.Arguments["id"].(string)
+		if !ok {
+			log.Print("ID must be a string")
+			return nil, fmt.Errorf("id must be a string")
+		}
+
+		result, err := client.deleteNotificationSubscription(ctx, id, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error deleting notification subscription: %v", err)
+			return nil, fmt.Errorf("error deleting notification subscription: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
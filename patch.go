@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// patchSearchLines is how far from the line a hunk names it is looked for, since hunks written by
+// hand or by a model often carry slightly wrong line numbers.
+const patchSearchLines = 200
+
+// hunkHeader matches the header of a hunk, e.g. @@ -12,7 +12,8 @@ func main() {
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// patchFile is the change a unified diff makes to one file. OldPath is "" for a file the patch
+// adds, and NewPath is "" for one it deletes.
+type patchFile struct {
+	OldPath string
+	NewPath string
+	Hunks   []patchHunk
+	// EOFNewline is set when the patch says whether the new file ends with a newline; without it
+	// the file keeps the ending it had.
+	EOFNewline *bool
+}
+
+// patchHunk is one hunk of a file's change. Lines keep their ' ', '-', or '+' prefix.
+type patchHunk struct {
+	Header   string
+	OldStart int
+	Lines    []string
+}
+
+// path returns the path the change is to.
+func (f patchFile) path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// parsePatch parses a unified diff of one or more files, as written by git diff or diff -u. Hunks
+// are read up to the next header, since the line counts of hand-written ones are often wrong; the
+// counts only decide whether a removed "-- " line followed by an added "++ " line is a file header.
+func parsePatch(text string) ([]patchFile, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	files := []patchFile{}
+	var file *patchFile
+	var hunk *patchHunk
+	// oldLeft and newLeft are the lines of the hunk its header says are still to come
+	var oldLeft, newLeft int
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") && (hunk == nil || oldLeft <= 0 && newLeft <= 0):
+			files = append(files, patchFile{
+				OldPath: patchPath(strings.TrimPrefix(line, "--- "), "a/"),
+				NewPath: patchPath(strings.TrimPrefix(lines[i+1], "+++ "), "b/"),
+			})
+			file = &files[len(files)-1]
+			hunk = nil
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if file == nil {
+				return nil, fmt.Errorf("line %d: hunk before a --- and +++ file header", i+1)
+			}
+			match := hunkHeader.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", i+1, line)
+			}
+			start, _ := strconv.Atoi(match[1])
+			oldLeft, newLeft = hunkCount(match[2]), hunkCount(match[4])
+			file.Hunks = append(file.Hunks, patchHunk{Header: line, OldStart: start})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+		case hunk != nil && strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" applies to the line before it
+			if len(hunk.Lines) > 0 {
+				last := hunk.Lines[len(hunk.Lines)-1][0]
+				noNewline := false
+				switch {
+				case last == '+' || last == ' ':
+					file.EOFNewline = &noNewline
+				case file.EOFNewline == nil:
+					newline := true
+					file.EOFNewline = &newline
+				}
+			}
+		case hunk != nil && line != "" && strings.ContainsRune(" -+", rune(line[0])):
+			hunk.Lines = append(hunk.Lines, line)
+			if line[0] != '+' {
+				oldLeft--
+			}
+			if line[0] != '-' {
+				newLeft--
+			}
+		case hunk != nil && line == "" && i < len(lines)-1:
+			// An empty context line whose space was trimmed
+			hunk.Lines = append(hunk.Lines, " ")
+			oldLeft--
+			newLeft--
+		default:
+			// Lines between files, such as diff --git and index headers
+			hunk = nil
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file changes found; expected --- and +++ file headers")
+	}
+	for _, file := range files {
+		if file.OldPath == "" && file.NewPath == "" {
+			return nil, fmt.Errorf("a file change has neither an old nor a new path")
+		}
+		if len(file.Hunks) == 0 && file.NewPath != "" {
+			return nil, fmt.Errorf("%s has no hunks", file.path())
+		}
+	}
+	return files, nil
+}
+
+// hunkCount returns a line count of a hunk header, which is 1 when left out.
+func hunkCount(count string) int {
+	if count == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(count)
+	return n
+}
+
+// patchPath returns the repository path of a --- or +++ header, or "" for /dev/null.
+func patchPath(header, prefix string) string {
+	if tab := strings.IndexByte(header, '\t'); tab >= 0 {
+		header = header[:tab]
+	}
+	header = strings.TrimSpace(header)
+	if header == "/dev/null" {
+		return ""
+	}
+	header = strings.TrimPrefix(header, prefix)
+	return "/" + strings.TrimPrefix(header, "/")
+}
+
+// apply applies the hunks of the change to content and returns the new content. Lines are
+// compared without their line endings; added lines get CRLF endings in a file that uses them. A
+// hunk that does not match near the line it names fails with the first line that differs.
+func (f patchFile) apply(content, lineEndings string) (string, error) {
+	eofNewline := content == "" || strings.HasSuffix(content, "\n")
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+	added := ""
+	if lineEndings == lineEndingsCRLF {
+		added = "\r"
+	}
+
+	result := []string{}
+	next := 0 // first line of content not yet copied to result
+	offset := 0
+	for n, hunk := range f.Hunks {
+		old := []string{}
+		for _, line := range hunk.Lines {
+			if line[0] != '+' {
+				old = append(old, line[1:])
+			}
+		}
+
+		expected := hunk.OldStart - 1 + offset
+		if len(old) == 0 && hunk.OldStart > 0 {
+			// A hunk that only adds lines names the line it adds them after
+			expected++
+		}
+		at := findHunk(lines, old, expected, next)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d (%s) of %s does not match the file: %s", n+1, hunk.Header, f.path(), hunkMismatch(lines, old, expected))
+		}
+
+		result = append(result, lines[next:at]...)
+		i := at
+		for _, line := range hunk.Lines {
+			switch line[0] {
+			case ' ':
+				result = append(result, lines[i])
+				i++
+			case '-':
+				i++
+			case '+':
+				result = append(result, line[1:]+added)
+			}
+		}
+		next = at + len(old)
+		// Later hunks are likely off by as much as this one
+		offset += at - expected
+	}
+	result = append(result, lines[next:]...)
+
+	if f.EOFNewline != nil {
+		eofNewline = *f.EOFNewline
+	}
+	text := strings.Join(result, "\n")
+	if eofNewline && len(result) > 0 {
+		text += "\n"
+	}
+	return text, nil
+}
+
+// findHunk returns where the old lines of a hunk are in lines, at or after from, looking outward
+// from expected; or -1 when they are nowhere near it.
+func findHunk(lines, old []string, expected, from int) int {
+	if expected < from {
+		expected = from
+	}
+	for distance := 0; distance <= patchSearchLines; distance++ {
+		for _, at := range []int{expected - distance, expected + distance} {
+			if at >= from && at+len(old) <= len(lines) && hunkMatches(lines[at:], old) {
+				return at
+			}
+			if distance == 0 {
+				break
+			}
+		}
+	}
+	return -1
+}
+
+func hunkMatches(lines, old []string) bool {
+	for i, line := range old {
+		if strings.TrimSuffix(lines[i], "\r") != strings.TrimSuffix(line, "\r") {
+			return false
+		}
+	}
+	return true
+}
+
+// hunkMismatch describes the first line of a hunk that differs from the file where the hunk says
+// it applies.
+func hunkMismatch(lines, old []string, expected int) string {
+	for i, line := range old {
+		at := expected + i
+		if at < 0 || at >= len(lines) {
+			return fmt.Sprintf("line %d is past the end of the file, which has %d lines", at+1, len(lines))
+		}
+		if found := strings.TrimSuffix(lines[at], "\r"); found != strings.TrimSuffix(line, "\r") {
+			return fmt.Sprintf("line %d is %q, not %q", at+1, found, line)
+		}
+	}
+	return "its lines are not where it says"
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatchApply(t *testing.T) {
+	tests := []struct {
+		name        string
+		patch       string
+		content     string
+		lineEndings string
+		want        string
+		wantErr     string
+	}{
+		{
+			name:    "exact",
+			patch:   "--- a/f.txt\n+++ b/f.txt\n@@ -2,3 +2,3 @@\n b\n-c\n+C\n d\n",
+			content: "a\nb\nc\nd\ne\n",
+			want:    "a\nb\nC\nd\ne\n",
+		},
+		{
+			name:    "hunk offset from the line it names",
+			patch:   "--- a/f.txt\n+++ b/f.txt\n@@ -1,3 +1,3 @@\n b\n-c\n+C\n d\n@@ -4,2 +4,2 @@\n e\n-f\n+F\n",
+			content: "x\ny\na\nb\nc\nd\ne\nf\n",
+			want:    "x\ny\na\nb\nC\nd\ne\nF\n",
+		},
+		{
+			name:    "hunk that does not match",
+			patch:   "--- a/f.txt\n+++ b/f.txt\n@@ -2,2 +2,2 @@\n b\n-z\n+Z\n",
+			content: "a\nb\nc\n",
+			wantErr: `line 3 is "c", not "z"`,
+		},
+		{
+			name:    "no newline at end of the new file",
+			patch:   "--- a/f.txt\n+++ b/f.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+B\n\\ No newline at end of file\n",
+			content: "a\nb\n",
+			want:    "a\nB",
+		},
+		{
+			name:    "no newline at end of the old file",
+			patch:   "--- a/f.txt\n+++ b/f.txt\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+B\n",
+			content: "a\nb",
+			want:    "a\nB\n",
+		},
+		{
+			name:        "CRLF file",
+			patch:       "--- a/f.txt\r\n+++ b/f.txt\r\n@@ -1,3 +1,3 @@\r\n a\r\n-b\r\n+B\r\n c\r\n",
+			content:     "a\r\nb\r\nc\r\n",
+			lineEndings: lineEndingsCRLF,
+			want:        "a\r\nB\r\nc\r\n",
+		},
+		{
+			name:    "removed -- line next to an added ++ line",
+			patch:   "--- a/q.sql\n+++ b/q.sql\n@@ -1,3 +1,3 @@\n select 1;\n--- old comment\n+++ new comment\n select 2;\n",
+			content: "select 1;\n-- old comment\nselect 2;\n",
+			want:    "select 1;\n++ new comment\nselect 2;\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files, err := parsePatch(test.patch)
+			if err != nil {
+				t.Fatalf("parsePatch: %v", err)
+			}
+			if len(files) != 1 {
+				t.Fatalf("parsePatch returned %d files, want 1", len(files))
+			}
+			got, err := files[0].apply(test.content, test.lineEndings)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("apply error = %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("apply = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePatchFiles(t *testing.T) {
+	patch := strings.Join([]string{
+		"diff --git a/src/a.go b/src/a.go",
+		"index 1111111..2222222 100644",
+		"--- a/src/a.go",
+		"+++ b/src/a.go",
+		"@@ -1 +1 @@",
+		"-old",
+		"+new",
+		"diff --git a/docs/new.md b/docs/new.md",
+		"new file mode 100644",
+		"--- /dev/null",
+		"+++ b/docs/new.md",
+		"@@ -0,0 +1,2 @@",
+		"+# Title",
+		"+text",
+		"diff --git a/gone.txt b/gone.txt",
+		"deleted file mode 100644",
+		"--- a/gone.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-bye",
+		"",
+	}, "\n")
+	files, err := parsePatch(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ oldPath, newPath string }{
+		{"/src/a.go", "/src/a.go"},
+		{"", "/docs/new.md"},
+		{"/gone.txt", ""},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("parsePatch returned %d files, want %d", len(files), len(want))
+	}
+	for i, file := range files {
+		if file.OldPath != want[i].oldPath || file.NewPath != want[i].newPath || len(file.Hunks) != 1 {
+			t.Errorf("file %d = %s -> %s with %d hunks, want %s -> %s with 1", i, file.OldPath, file.NewPath, len(file.Hunks), want[i].oldPath, want[i].newPath)
+		}
+	}
+
+	added, err := files[1].apply("", "")
+	if err != nil || added != "# Title\ntext\n" {
+		t.Errorf("applying the added file = %q, %v", added, err)
+	}
+}
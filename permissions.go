@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// gitRepositoriesNamespaceID is the security namespace that holds Git repository and branch permissions.
+var gitRepositoriesNamespaceID = uuid.MustParse("2e9eb7ed-3c0a-47d4-87c1-0ffdd275fd87")
+
+// gitSecurityToken builds the Git Repositories namespace token for a repository, or for a
+// branch of it. Branch path segments are hex-encoded UTF-16LE, as Azure DevOps expects.
+func gitSecurityToken(projectID, repoID, branch string) string {
+	token := fmt.Sprintf("repoV2/%s/%s", projectID, repoID)
+	if branch == "" {
+		return token
+	}
+
+	segments := strings.Split(strings.TrimPrefix(branchRef(branch), "refs/heads/"), "/")
+	encoded := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		units := utf16.Encode([]rune(segment))
+		raw := make([]byte, 0, len(units)*2)
+		for _, u := range units {
+			raw = append(raw, byte(u), byte(u>>8))
+		}
+		encoded = append(encoded, hex.EncodeToString(raw))
+	}
+	return token + "/refs/heads/" + strings.Join(encoded, "/")
+}
+
+// permissionNames decodes a permission bit mask into action names using the namespace's actions.
+func permissionNames(mask int, actions []security.ActionDefinition) []string {
+	names := []string{}
+	for _, action := range actions {
+		if action.Bit != nil && mask&*action.Bit != 0 {
+			name := stringValue(action.DisplayName)
+			if name == "" {
+				name = stringValue(action.Name)
+			}
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// getRepositoryPermissions reports the effective Git permissions a user or group has on a
+// repository, or on one branch of it.
+func (c *AzureDevOpsClient) getRepositoryPermissions(ctx context.Context, repoName, branch, subject string) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	if repo.Project == nil || repo.Project.Id == nil {
+		return nil, fmt.Errorf("repository %s has no project", repoName)
+	}
+
+	identities, err := c.resolveIdentities(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no user or group found matching %s", subject)
+	}
+	if len(identities) > 1 {
+		names := []string{}
+		for _, ident := range identities {
+			names = append(names, ident.DisplayName)
+		}
+		return nil, fmt.Errorf("%s matches several identities, be more specific: %s", subject, strings.Join(names, ", "))
+	}
+	target := identities[0]
+
+	namespaces, err := c.securityClient.QuerySecurityNamespaces(ctx, security.QuerySecurityNamespacesArgs{
+		SecurityNamespaceId: &gitRepositoriesNamespaceID,
+	})
+	if err != nil {
+		log.Printf("Error getting security namespace: %v", err)
+		return nil, fmt.Errorf("error getting Git security namespace: %w", err)
+	}
+	actions := []security.ActionDefinition{}
+	if namespaces != nil && len(*namespaces) > 0 && (*namespaces)[0].Actions != nil {
+		actions = *(*namespaces)[0].Actions
+	}
+
+	token := gitSecurityToken(repo.Project.Id.String(), repo.Id.String(), branch)
+	includeExtendedInfo := true
+	acls, err := c.securityClient.QueryAccessControlLists(ctx, security.QueryAccessControlListsArgs{
+		SecurityNamespaceId: &gitRepositoriesNamespaceID,
+		Token:               &token,
+		Descriptors:         &target.Descriptor,
+		IncludeExtendedInfo: &includeExtendedInfo,
+	})
+	if err != nil {
+		log.Printf("Error querying access control lists: %v", err)
+		return nil, fmt.Errorf("error querying permissions: %w", err)
+	}
+
+	effectiveAllow, effectiveDeny := 0, 0
+	explicitAllow, explicitDeny := 0, 0
+	if acls != nil {
+		for _, acl := range *acls {
+			if acl.AcesDictionary == nil {
+				continue
+			}
+			for _, ace := range *acl.AcesDictionary {
+				if ace.Allow != nil {
+					explicitAllow |= *ace.Allow
+				}
+				if ace.Deny != nil {
+					explicitDeny |= *ace.Deny
+				}
+				if ace.ExtendedInfo == nil {
+					continue
+				}
+				if ace.ExtendedInfo.EffectiveAllow != nil {
+					effectiveAllow |= *ace.ExtendedInfo.EffectiveAllow
+				}
+				if ace.ExtendedInfo.EffectiveDeny != nil {
+					effectiveDeny |= *ace.ExtendedInfo.EffectiveDeny
+				}
+			}
+		}
+	}
+
+	permissions := []map[string]interface{}{}
+	for _, action := range actions {
+		if action.Bit == nil {
+			continue
+		}
+		bit := *action.Bit
+		state := "not set"
+		switch {
+		case effectiveDeny&bit != 0:
+			state = "deny"
+		case effectiveAllow&bit != 0:
+			state = "allow"
+		}
+		entry := map[string]interface{}{
+			"permission": stringValue(action.DisplayName),
+			"effective":  state,
+		}
+		if (explicitAllow|explicitDeny)&bit != 0 {
+			entry["explicit"] = true
+		}
+		permissions = append(permissions, entry)
+	}
+
+	return map[string]interface{}{
+		"repository":  stringValue(repo.Name),
+		"branch":      branch,
+		"identity":    target,
+		"token":       token,
+		"allowed":     permissionNames(effectiveAllow&^effectiveDeny, actions),
+		"denied":      permissionNames(effectiveDeny, actions),
+		"permissions": permissions,
+	}, nil
+}
+
+func addPermissionTools(s tools.Server, client *AzureDevOpsClient) {
+	repoPermissionsTool := mcp.NewTool("get_repository_permissions",
+		mcp.WithDescription("Report the effective Git permissions (read, contribute, create branch, bypass policies, etc.) of a user or group on a repository or branch"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("identity",
+			mcp.Required(),
+			mcp.Description("User display name or email, or group name"),
+		),
+		mcp.WithString("branch",
+			mcp.Description("Optional branch name to check branch-level permissions"),
+		),
+	)
+
+	s.AddTool(repoPermissionsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		subject, ok := request.Params.Arguments["identity"].(string)
+		if !ok {
+			log.Print("Identity must be a string")
+			return nil, fmt.Errorf("identity must be a string")
+		}
+		branch, _ := request.Params.Arguments["branch"].(string)
+
+		result, err := client.getRepositoryPermissions(ctx, repo, branch, subject)
+		if err != nil {
+			log.Printf("Error getting repository permissions: %v", err)
+			return nil, fmt.Errorf("error getting repository permissions: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
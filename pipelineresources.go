@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/pipelinepermissions"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// secureFilesLocationID is the secure files REST resource, which the SDK does not cover.
+var secureFilesLocationID = uuid.MustParse("adcfd8bc-b184-43ba-bd84-7c8c6a2ff421")
+
+// identityName names the identity of a reference, or returns "" for none.
+func identityName(identity *webapi.IdentityRef) string {
+	if identity == nil {
+		return ""
+	}
+	return stringValue(identity.DisplayName)
+}
+
+// secureFiles lists the secure files of the project. Their contents and download tickets are
+// never requested.
+func (c *AzureDevOpsClient) secureFiles(ctx context.Context) ([]taskagent.SecureFile, error) {
+	var page struct {
+		Value []taskagent.SecureFile `json:"value"`
+	}
+	err := c.sendJSON(ctx, http.MethodGet, secureFilesLocationID, "7.1-preview.1", map[string]string{
+		"project": c.config.AzureDevOps.Project,
+	}, url.Values{"includeDownloadTickets": {"false"}}, nil, &page)
+	if err != nil {
+		log.Printf("Error getting secure files: %v", err)
+		return nil, fmt.Errorf("error getting secure files: %w", err)
+	}
+	return page.Value, nil
+}
+
+// pipelineNames returns the names of pipelines by ID.
+func (c *AzureDevOpsClient) pipelineNames(ctx context.Context, ids []int) (map[int]string, error) {
+	names := map[int]string{}
+	if len(ids) == 0 {
+		return names, nil
+	}
+	definitions, err := c.buildClient.GetDefinitions(ctx, build.GetDefinitionsArgs{
+		Project:       &c.config.AzureDevOps.Project,
+		DefinitionIds: &ids,
+	})
+	if err != nil {
+		log.Printf("Error getting pipelines: %v", err)
+		return nil, fmt.Errorf("error getting pipelines: %w", err)
+	}
+	for _, definition := range definitions.Value {
+		if definition.Id != nil {
+			names[*definition.Id] = stringValue(definition.Name)
+		}
+	}
+	return names, nil
+}
+
+// listSecureFiles describes the secure files of the project for governance reviews: who created
+// and last changed each, and which pipelines may use it. File contents are never read.
+func (c *AzureDevOpsClient) listSecureFiles(ctx context.Context) (map[string]interface{}, error) {
+	files, err := c.secureFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return stringValue(files[i].Name) < stringValue(files[j].Name) })
+
+	resourceType := "securefile"
+	list := []map[string]interface{}{}
+	authorized := map[int][]map[string]interface{}{}
+	errors := map[string]string{}
+	for _, file := range files {
+		if file.Id == nil {
+			continue
+		}
+		entry := map[string]interface{}{
+			"id":         file.Id.String(),
+			"name":       stringValue(file.Name),
+			"createdBy":  identityName(file.CreatedBy),
+			"modifiedBy": identityName(file.ModifiedBy),
+		}
+		if file.CreatedOn != nil {
+			entry["createdOn"] = file.CreatedOn.Time.Format(time.RFC3339)
+		}
+		if file.ModifiedOn != nil {
+			entry["modifiedOn"] = file.ModifiedOn.Time.Format(time.RFC3339)
+		}
+		if file.Properties != nil && len(*file.Properties) > 0 {
+			entry["properties"] = *file.Properties
+		}
+		list = append(list, entry)
+
+		resourceID := file.Id.String()
+		permissions, err := c.pipelineAccessClient.GetPipelinePermissionsForResource(ctx, pipelinepermissions.GetPipelinePermissionsForResourceArgs{
+			Project:      &c.config.AzureDevOps.Project,
+			ResourceType: &resourceType,
+			ResourceId:   &resourceID,
+		})
+		if err != nil {
+			log.Printf("Error getting pipeline permissions: %v", err)
+			errors[stringValue(file.Name)] = err.Error()
+			continue
+		}
+		entry["allPipelines"] = permissions.AllPipelines != nil && permissions.AllPipelines.Authorized != nil && *permissions.AllPipelines.Authorized
+		pipelines := []map[string]interface{}{}
+		if permissions.Pipelines != nil {
+			for _, pipeline := range *permissions.Pipelines {
+				if pipeline.Id == nil || pipeline.Authorized == nil || !*pipeline.Authorized {
+					continue
+				}
+				item := map[string]interface{}{
+					"id":           *pipeline.Id,
+					"authorizedBy": identityName(pipeline.AuthorizedBy),
+				}
+				if pipeline.AuthorizedOn != nil {
+					item["authorizedOn"] = pipeline.AuthorizedOn.Time.Format(time.RFC3339)
+				}
+				pipelines = append(pipelines, item)
+				authorized[*pipeline.Id] = append(authorized[*pipeline.Id], item)
+			}
+		}
+		entry["authorizedPipelines"] = pipelines
+	}
+
+	ids := []int{}
+	for id := range authorized {
+		ids = append(ids, id)
+	}
+	names, err := c.pipelineNames(ctx, ids)
+	if err != nil {
+		errors["pipelines"] = err.Error()
+	}
+	for id, items := range authorized {
+		for _, item := range items {
+			item["name"] = names[id]
+		}
+	}
+
+	result := map[string]interface{}{
+		"secureFiles": list,
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// listTaskGroups describes the task groups of the project: their owners, tasks, inputs, and the
+// pipelines that use them.
+func (c *AzureDevOpsClient) listTaskGroups(ctx context.Context) (map[string]interface{}, error) {
+	groups, err := c.taskAgentClient.GetTaskGroups(ctx, taskagent.GetTaskGroupsArgs{
+		Project: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting task groups: %v", err)
+		return nil, fmt.Errorf("error getting task groups: %w", err)
+	}
+	sort.Slice(*groups, func(i, j int) bool { return stringValue((*groups)[i].Name) < stringValue((*groups)[j].Name) })
+
+	list := []map[string]interface{}{}
+	errors := map[string]string{}
+	for _, group := range *groups {
+		if group.Id == nil || group.Deleted != nil && *group.Deleted {
+			continue
+		}
+		entry := map[string]interface{}{
+			"id":         group.Id.String(),
+			"name":       stringValue(group.Name),
+			"category":   stringValue(group.Category),
+			"createdBy":  identityName(group.CreatedBy),
+			"modifiedBy": identityName(group.ModifiedBy),
+		}
+		if owner := stringValue(group.Owner); owner != "" {
+			entry["owner"] = owner
+		}
+		if description := stringValue(group.Description); description != "" {
+			entry["description"] = description
+		}
+		if group.Version != nil && group.Version.Major != nil {
+			entry["version"] = *group.Version.Major
+		}
+		if group.Revision != nil {
+			entry["revision"] = *group.Revision
+		}
+		if group.ModifiedOn != nil {
+			entry["modifiedOn"] = group.ModifiedOn.Time.Format(time.RFC3339)
+		}
+		steps := []map[string]interface{}{}
+		if group.Tasks != nil {
+			for _, step := range *group.Tasks {
+				item := map[string]interface{}{
+					"displayName": stringValue(step.DisplayName),
+					"enabled":     step.Enabled == nil || *step.Enabled,
+				}
+				if step.Task != nil {
+					if step.Task.Id != nil {
+						item["taskId"] = step.Task.Id.String()
+					}
+					item["version"] = stringValue(step.Task.VersionSpec)
+					if stringValue(step.Task.DefinitionType) == "metaTask" {
+						item["taskGroup"] = true
+					}
+				}
+				steps = append(steps, item)
+			}
+		}
+		entry["tasks"] = steps
+		inputs := []string{}
+		if group.Inputs != nil {
+			for _, input := range *group.Inputs {
+				inputs = append(inputs, stringValue(input.Name))
+			}
+		}
+		entry["inputs"] = inputs
+
+		// Task groups are referenced like tasks, so pipelines using one are found by its ID.
+		definitions, err := c.buildClient.GetDefinitions(ctx, build.GetDefinitionsArgs{
+			Project:      &c.config.AzureDevOps.Project,
+			TaskIdFilter: group.Id,
+		})
+		if err != nil {
+			log.Printf("Error getting pipelines: %v", err)
+			errors[stringValue(group.Name)] = err.Error()
+		} else {
+			pipelines := []map[string]interface{}{}
+			for _, definition := range definitions.Value {
+				if definition.Id != nil {
+					pipelines = append(pipelines, map[string]interface{}{
+						"id":   *definition.Id,
+						"name": stringValue(definition.Name),
+					})
+				}
+			}
+			entry["usedByPipelines"] = pipelines
+		}
+		list = append(list, entry)
+	}
+
+	result := map[string]interface{}{
+		"taskGroups": list,
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addSecureFileTools(s tools.Server, client *AzureDevOpsClient) {
+	secureFilesTool := mcp.NewTool("list_secure_files",
+		mcp.WithDescription("List the secure files of the project for pipeline governance reviews: who created and last changed each, and whether all pipelines or which pipelines are authorized to use it. File contents are never read"),
+	)
+
+	s.AddTool(secureFilesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := client.listSecureFiles(ctx)
+		if err != nil {
+			log.Printf("Error listing secure files: %v", err)
+			return nil, fmt.Errorf("error listing secure files: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
+
+func addTaskGroupTools(s tools.Server, client *AzureDevOpsClient) {
+	taskGroupsTool := mcp.NewTool("list_task_groups",
+		mcp.WithDescription("List the task groups of the project for pipeline governance reviews: their owners, versions, tasks, inputs, and the pipelines that use them"),
+	)
+
+	s.AddTool(taskGroupsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := client.listTaskGroups(ctx)
+		if err != nil {
+			log.Printf("Error listing task groups: %v", err)
+			return nil, fmt.Errorf("error listing task groups: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,679 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/signify/sgfy-mcp/tools"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// recentTriggerBuilds is how many of a pipeline's latest runs are listed with why they started.
+	recentTriggerBuilds = 10
+	// yamlSettingsSource marks a definition trigger whose settings come from the pipeline's YAML.
+	yamlSettingsSource = 2
+	// yamlProcessType is the process type of YAML pipelines; designer pipelines are 1.
+	yamlProcessType = 2
+)
+
+// findPipeline returns the full definition of a pipeline by ID or by name.
+func (c *AzureDevOpsClient) findPipeline(ctx context.Context, pipeline string) (*build.BuildDefinition, error) {
+	id, err := strconv.Atoi(pipeline)
+	if err != nil {
+		definitions, err := c.buildClient.GetDefinitions(ctx, build.GetDefinitionsArgs{
+			Project: &c.config.AzureDevOps.Project,
+			Name:    &pipeline,
+		})
+		if err != nil {
+			log.Printf("Error getting pipelines: %v", err)
+			return nil, fmt.Errorf("error finding pipeline %s: %w", pipeline, err)
+		}
+		switch len(definitions.Value) {
+		case 0:
+			return nil, fmt.Errorf("pipeline %s not found", pipeline)
+		case 1:
+			id = *definitions.Value[0].Id
+		default:
+			paths := []string{}
+			for _, definition := range definitions.Value {
+				paths = append(paths, fmt.Sprintf("%d (%s)", *definition.Id, stringValue(definition.Path)))
+			}
+			return nil, fmt.Errorf("%d pipelines are named %s: %s; pass the ID", len(definitions.Value), pipeline, strings.Join(paths, ", "))
+		}
+	}
+
+	definition, err := c.buildClient.GetDefinition(ctx, build.GetDefinitionArgs{
+		Project:      &c.config.AzureDevOps.Project,
+		DefinitionId: &id,
+	})
+	if err != nil {
+		log.Printf("Error getting pipeline: %v", err)
+		return nil, fmt.Errorf("error getting pipeline %s: %w", pipeline, err)
+	}
+	return definition, nil
+}
+
+// pipelineYAMLFile returns the YAML file of a YAML pipeline, or "" for a designer pipeline.
+func pipelineYAMLFile(definition *build.BuildDefinition) string {
+	process, _ := definition.Process.(map[string]interface{})
+	if fmt.Sprint(process["type"]) != fmt.Sprint(yamlProcessType) {
+		return ""
+	}
+	file, _ := process["yamlFilename"].(string)
+	return file
+}
+
+// yamlTrigger normalizes the trigger or pr section of a pipeline's YAML, which can be none, a list
+// of branches, or a mapping. A missing section runs for every branch.
+func yamlTrigger(section interface{}, present bool) map[string]interface{} {
+	if !present {
+		return map[string]interface{}{"enabled": true, "branches": map[string]interface{}{"include": []string{"*"}}, "implicit": true}
+	}
+	switch value := section.(type) {
+	case string:
+		if value == "none" {
+			return map[string]interface{}{"enabled": false}
+		}
+		return map[string]interface{}{"enabled": true, "branches": map[string]interface{}{"include": []string{value}}}
+	case []interface{}:
+		return map[string]interface{}{"enabled": true, "branches": map[string]interface{}{"include": value}}
+	case map[string]interface{}:
+		trigger := map[string]interface{}{"enabled": true}
+		for key, setting := range value {
+			trigger[key] = setting
+		}
+		return trigger
+	}
+	// An empty section, e.g. "trigger:" on its own, runs for every branch too.
+	return map[string]interface{}{"enabled": true, "branches": map[string]interface{}{"include": []string{"*"}}}
+}
+
+// describeCron says when a cron schedule fires in words, for the common case of a fixed minute and
+// hour. Pipeline schedules are always in UTC.
+func describeCron(cron string) string {
+	fields := strings.Fields(cron)
+	if len(fields) != 5 {
+		return ""
+	}
+	minute, hour, dayOfMonth, month, weekday := fields[0], fields[1], fields[2], fields[3], fields[4]
+	m, minuteErr := strconv.Atoi(minute)
+	h, hourErr := strconv.Atoi(hour)
+	when := fmt.Sprintf("at minute %s of hour %s UTC", minute, hour)
+	if minuteErr == nil && hourErr == nil {
+		when = fmt.Sprintf("at %02d:%02d UTC", h, m)
+	}
+	days := []string{}
+	if dayOfMonth != "*" {
+		days = append(days, "day of month "+dayOfMonth)
+	}
+	if month != "*" {
+		days = append(days, "month "+month)
+	}
+	if weekday != "*" {
+		days = append(days, "weekday "+weekday+" (0 is Sunday)")
+	}
+	if len(days) == 0 {
+		return when + " every day"
+	}
+	return when + " on " + strings.Join(days, ", ")
+}
+
+// yamlTriggers reads the triggers of a pipeline's YAML file: CI, pull request, schedules, and
+// completion of other pipelines.
+func yamlTriggers(content string) (map[string]interface{}, error) {
+	var document map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		return nil, fmt.Errorf("error parsing pipeline YAML: %w", err)
+	}
+	triggerSection, hasTrigger := document["trigger"]
+	prSection, hasPR := document["pr"]
+	triggers := map[string]interface{}{
+		"trigger": yamlTrigger(triggerSection, hasTrigger),
+		"pr":      yamlTrigger(prSection, hasPR),
+	}
+
+	schedules := []map[string]interface{}{}
+	list, _ := document["schedules"].([]interface{})
+	for _, raw := range list {
+		schedule, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		entry := map[string]interface{}{}
+		for key, value := range schedule {
+			entry[key] = value
+		}
+		if cron, ok := schedule["cron"].(string); ok {
+			if when := describeCron(cron); when != "" {
+				entry["runs"] = when
+			}
+		}
+		// Without always: true a schedule is skipped when nothing changed since its last run.
+		if _, ok := schedule["always"]; !ok {
+			entry["always"] = false
+		}
+		schedules = append(schedules, entry)
+	}
+	triggers["schedules"] = schedules
+
+	resources, _ := document["resources"].(map[string]interface{})
+	pipelines, _ := resources["pipelines"].([]interface{})
+	completions := []map[string]interface{}{}
+	for _, raw := range pipelines {
+		resource, ok := raw.(map[string]interface{})
+		if !ok || resource["trigger"] == nil {
+			continue
+		}
+		completions = append(completions, map[string]interface{}{
+			"pipeline": resource["source"],
+			"alias":    resource["pipeline"],
+			"trigger":  resource["trigger"],
+		})
+	}
+	if len(completions) > 0 {
+		triggers["pipelineCompletion"] = completions
+	}
+	return triggers, nil
+}
+
+// pipelineTriggers reports what starts a pipeline: the triggers of its definition, those of its
+// YAML file on the default branch, and why its latest runs started.
+func (c *AzureDevOpsClient) pipelineTriggers(ctx context.Context, pipeline string) (map[string]interface{}, error) {
+	definition, err := c.findPipeline(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := map[string]interface{}{
+		"id":     *definition.Id,
+		"name":   stringValue(definition.Name),
+		"folder": stringValue(definition.Path),
+	}
+	if definition.QueueStatus != nil {
+		summary["queueStatus"] = string(*definition.QueueStatus)
+	}
+	yamlFile := pipelineYAMLFile(definition)
+	if yamlFile != "" {
+		summary["yamlFile"] = yamlFile
+	}
+	var repo *build.BuildRepository
+	if definition.Repository != nil {
+		repo = definition.Repository
+		summary["repository"] = stringValue(repo.Name)
+		summary["defaultBranch"] = stringValue(repo.DefaultBranch)
+	}
+	result := map[string]interface{}{
+		"pipeline": summary,
+	}
+	notes := []string{}
+	errors := map[string]string{}
+
+	// Designer triggers, and YAML triggers overridden in the pipeline settings, live in the
+	// definition; the others only mark that the YAML file decides.
+	definitionTriggers := []map[string]interface{}{}
+	overridden := map[string]bool{}
+	if definition.Triggers != nil {
+		for _, raw := range *definition.Triggers {
+			trigger, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if source, _ := trigger["settingsSourceType"].(float64); int(source) == yamlSettingsSource {
+				continue
+			}
+			if yamlFile != "" {
+				triggerType, _ := trigger["triggerType"].(string)
+				overridden[triggerType] = true
+			}
+			definitionTriggers = append(definitionTriggers, trigger)
+		}
+	}
+	result["definitionTriggers"] = definitionTriggers
+
+	if yamlFile != "" {
+		switch {
+		case repo == nil || stringValue(repo.Type) != "TfsGit":
+			notes = append(notes, "The YAML file is not in an Azure Repos Git repository, so its triggers were not read")
+		default:
+			file, err := c.fileAt(ctx, c.config.AzureDevOps.Project, stringValue(repo.Id), yamlFile, stringValue(repo.DefaultBranch))
+			if err != nil {
+				errors["yaml"] = err.Error()
+			} else if triggers, err := yamlTriggers(file.Content); err != nil {
+				errors["yaml"] = err.Error()
+			} else {
+				result["yamlTriggers"] = triggers
+			}
+		}
+		if overridden["continuousIntegration"] {
+			notes = append(notes, "The CI trigger is overridden in the pipeline settings, so the YAML trigger section is ignored")
+		}
+		if overridden["schedule"] {
+			notes = append(notes, "Schedules are set in the pipeline settings, so the YAML schedules are ignored")
+		}
+		notes = append(notes,
+			"Azure Repos Git ignores the YAML pr section; pull request runs come from build validation branch policies",
+			"Schedules in YAML are read from the default branch and other branches the schedules name; their cron times are UTC")
+	}
+	if len(notes) > 0 {
+		result["notes"] = notes
+	}
+
+	top := recentTriggerBuilds
+	builds, err := c.buildClient.GetBuilds(ctx, build.GetBuildsArgs{
+		Project:     &c.config.AzureDevOps.Project,
+		Definitions: &[]int{*definition.Id},
+		Top:         &top,
+	})
+	if err != nil {
+		log.Printf("Error getting builds: %v", err)
+		errors["recentRuns"] = err.Error()
+	} else {
+		runs := []map[string]interface{}{}
+		for _, run := range builds.Value {
+			entry := map[string]interface{}{
+				"id":           *run.Id,
+				"buildNumber":  stringValue(run.BuildNumber),
+				"sourceBranch": stringValue(run.SourceBranch),
+				"webUrl":       c.links.build(c.config.AzureDevOps.Project, *run.Id),
+			}
+			if run.Reason != nil {
+				entry["reason"] = string(*run.Reason)
+			}
+			if run.QueueTime != nil {
+				entry["queueTime"] = run.QueueTime.Time.UTC().Format(time.RFC3339)
+			}
+			if run.RequestedFor != nil {
+				entry["requestedFor"] = stringValue(run.RequestedFor.DisplayName)
+			}
+			if run.Result != nil {
+				entry["result"] = string(*run.Result)
+			} else if run.Status != nil {
+				entry["status"] = string(*run.Status)
+			}
+			if run.TriggerInfo != nil && len(*run.TriggerInfo) > 0 {
+				entry["triggerInfo"] = *run.TriggerInfo
+			}
+			runs = append(runs, entry)
+		}
+		result["recentRuns"] = runs
+	}
+
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// pipelineParameter is a runtime parameter declared in a pipeline's YAML.
+type pipelineParameter struct {
+	Name        string        `yaml:"name" json:"name"`
+	DisplayName string        `yaml:"displayName" json:"displayName,omitempty"`
+	Type        string        `yaml:"type" json:"type"`
+	Default     interface{}   `yaml:"default" json:"default,omitempty"`
+	Values      []interface{} `yaml:"values" json:"values,omitempty"`
+	Required    bool          `yaml:"-" json:"required"`
+}
+
+// yamlInputs reads the runtime parameters of a pipeline's YAML and the variables and variable
+// groups it declares at the top level.
+func yamlInputs(content string) ([]pipelineParameter, []string, []string, error) {
+	var document struct {
+		Parameters []pipelineParameter `yaml:"parameters"`
+		Variables  yaml.Node           `yaml:"variables"`
+	}
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		return nil, nil, nil, fmt.Errorf("error parsing pipeline YAML: %w", err)
+	}
+	parameters := []pipelineParameter{}
+	for _, parameter := range document.Parameters {
+		if parameter.Type == "" {
+			parameter.Type = "string"
+		}
+		// A parameter without a default must be given a value when the pipeline is run.
+		parameter.Required = parameter.Default == nil
+		parameters = append(parameters, parameter)
+	}
+
+	variables := []string{}
+	groups := []string{}
+	switch document.Variables.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(document.Variables.Content); i += 2 {
+			variables = append(variables, document.Variables.Content[i].Value)
+		}
+	case yaml.SequenceNode:
+		for _, node := range document.Variables.Content {
+			var entry map[string]interface{}
+			if err := node.Decode(&entry); err != nil {
+				continue
+			}
+			if name, ok := entry["name"].(string); ok {
+				variables = append(variables, name)
+			} else if group, ok := entry["group"].(string); ok {
+				groups = append(groups, group)
+			}
+		}
+	}
+	return parameters, variables, groups, nil
+}
+
+// queueTimeSystemVariables can be set when queueing any pipeline.
+var queueTimeSystemVariables = []string{"system.debug"}
+
+// checkParameterValue returns why value is not valid for a runtime parameter, or "".
+func checkParameterValue(parameter pipelineParameter, value interface{}) string {
+	text := fmt.Sprint(value)
+	switch parameter.Type {
+	case "boolean":
+		if _, ok := value.(bool); !ok && text != "true" && text != "false" {
+			return fmt.Sprintf("parameter %s must be true or false", parameter.Name)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return fmt.Sprintf("parameter %s must be a number", parameter.Name)
+		}
+	}
+	if len(parameter.Values) == 0 {
+		return ""
+	}
+	allowed := []string{}
+	for _, option := range parameter.Values {
+		if fmt.Sprint(option) == text {
+			return ""
+		}
+		allowed = append(allowed, fmt.Sprint(option))
+	}
+	return fmt.Sprintf("parameter %s must be one of %s", parameter.Name, strings.Join(allowed, ", "))
+}
+
+// checkPipelineInputs returns why the parameters and variables of a run would be rejected or
+// ignored: unknown or missing parameters, values a parameter does not allow, and variables that
+// cannot be set at queue time.
+func checkPipelineInputs(parameters []pipelineParameter, settable []string, runParameters, runVariables map[string]interface{}) []string {
+	problems := []string{}
+	declared := map[string]pipelineParameter{}
+	for _, parameter := range parameters {
+		declared[parameter.Name] = parameter
+		value, given := runParameters[parameter.Name]
+		if !given {
+			if parameter.Required {
+				problems = append(problems, fmt.Sprintf("parameter %s is required", parameter.Name))
+			}
+			continue
+		}
+		if problem := checkParameterValue(parameter, value); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	for name := range runParameters {
+		if _, ok := declared[name]; !ok {
+			problems = append(problems, fmt.Sprintf("parameter %s is not declared by the pipeline", name))
+		}
+	}
+	for name := range runVariables {
+		ok := false
+		for _, variable := range settable {
+			if strings.EqualFold(variable, name) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("variable %s cannot be set at queue time; only variables marked settable at queue time in the pipeline settings can", name))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// pipelineInputs reports what can be passed when running a pipeline: the runtime parameters of
+// its YAML file on branch (the default branch when empty) and the variables settable at queue
+// time. Given runParameters or runVariables, it also checks them.
+func (c *AzureDevOpsClient) pipelineInputs(ctx context.Context, pipeline, branch string, runParameters, runVariables map[string]interface{}) (map[string]interface{}, error) {
+	definition, err := c.findPipeline(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	summary := map[string]interface{}{
+		"id":     *definition.Id,
+		"name":   stringValue(definition.Name),
+		"webUrl": c.links.pipeline(c.config.AzureDevOps.Project, *definition.Id),
+	}
+	result := map[string]interface{}{
+		"pipeline": summary,
+	}
+
+	// Variables of the definition can only be set at queue time when they allow overrides.
+	settable := append([]string{}, queueTimeSystemVariables...)
+	fixed := []string{}
+	if definition.Variables != nil {
+		for name, variable := range *definition.Variables {
+			if variable.AllowOverride != nil && *variable.AllowOverride {
+				settable = append(settable, name)
+			} else {
+				fixed = append(fixed, name)
+			}
+		}
+	}
+	sort.Strings(settable)
+	sort.Strings(fixed)
+	result["settableVariables"] = settable
+	if len(fixed) > 0 {
+		result["fixedVariables"] = fixed
+	}
+
+	parameters := []pipelineParameter{}
+	yamlFile := pipelineYAMLFile(definition)
+	repo := definition.Repository
+	switch {
+	case yamlFile == "":
+		result["notes"] = []string{"Designer pipelines have no runtime parameters"}
+	case repo == nil || stringValue(repo.Type) != "TfsGit":
+		result["notes"] = []string{"The YAML file is not in an Azure Repos Git repository, so its parameters were not read"}
+	default:
+		if branch == "" {
+			branch = stringValue(repo.DefaultBranch)
+		}
+		branch = branchRef(branch)
+		summary["yamlFile"] = yamlFile
+		summary["branch"] = branch
+		file, err := c.fileAt(ctx, c.config.AzureDevOps.Project, stringValue(repo.Id), yamlFile, branch)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s on %s: %w", yamlFile, branch, err)
+		}
+		var variables, groups []string
+		parameters, variables, groups, err = yamlInputs(file.Content)
+		if err != nil {
+			return nil, err
+		}
+		if len(variables) > 0 {
+			// Variables declared in YAML win over values given at queue time.
+			result["yamlVariables"] = variables
+		}
+		if len(groups) > 0 {
+			result["variableGroups"] = groups
+		}
+	}
+	result["parameters"] = parameters
+
+	if runParameters != nil || runVariables != nil {
+		problems := checkPipelineInputs(parameters, settable, runParameters, runVariables)
+		result["valid"] = len(problems) == 0
+		if len(problems) > 0 {
+			result["problems"] = problems
+		}
+	}
+	return result, nil
+}
+
+// pipelineSummary describes a pipeline definition. Secret variable values are left out.
+func (c *AzureDevOpsClient) pipelineSummary(definition *build.BuildDefinition) map[string]interface{} {
+	entry := map[string]interface{}{
+		"name":   stringValue(definition.Name),
+		"folder": stringValue(definition.Path),
+	}
+	if definition.Id != nil {
+		entry["id"] = *definition.Id
+		entry["webUrl"] = c.links.pipeline(c.config.AzureDevOps.Project, *definition.Id)
+	}
+	if definition.Revision != nil {
+		entry["revision"] = *definition.Revision
+	}
+	if yamlFile := pipelineYAMLFile(definition); yamlFile != "" {
+		entry["yamlFile"] = yamlFile
+	}
+	if definition.Repository != nil {
+		entry["repository"] = stringValue(definition.Repository.Name)
+		entry["defaultBranch"] = stringValue(definition.Repository.DefaultBranch)
+	}
+	if definition.Variables != nil {
+		variables := map[string]interface{}{}
+		for name, variable := range *definition.Variables {
+			item := map[string]interface{}{
+				"allowOverride": variable.AllowOverride != nil && *variable.AllowOverride,
+			}
+			if variable.IsSecret != nil && *variable.IsSecret {
+				item["isSecret"] = true
+			} else {
+				item["value"] = stringValue(variable.Value)
+			}
+			variables[name] = item
+		}
+		entry["variables"] = variables
+	}
+	return entry
+}
+
+// mergeVariables applies changed variables to a pipeline's, removing those set to nil. Secrets come
+// back without their values; Azure DevOps keeps the stored value of a secret sent without one.
+func mergeVariables(current *map[string]build.BuildDefinitionVariable, changes map[string]*build.BuildDefinitionVariable) *map[string]build.BuildDefinitionVariable {
+	merged := map[string]build.BuildDefinitionVariable{}
+	if current != nil {
+		for name, variable := range *current {
+			merged[name] = variable
+		}
+	}
+	for name, variable := range changes {
+		if variable == nil {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = *variable
+	}
+	return &merged
+}
+
+// createPipeline creates a YAML pipeline that runs yamlPath of a repository, with its CI trigger
+// taken from the YAML file.
+func (c *AzureDevOpsClient) createPipeline(ctx context.Context, name, repoName, yamlPath, folder, defaultBranch string, variables map[string]*build.BuildDefinitionVariable, dryRun bool) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	if defaultBranch == "" {
+		if repo.DefaultBranch == nil {
+			return nil, fmt.Errorf("repository %s has no default branch; pass defaultBranch", repoName)
+		}
+		defaultBranch = *repo.DefaultBranch
+	}
+	defaultBranch = branchRef(defaultBranch)
+	yamlPath = "/" + strings.TrimPrefix(yamlPath, "/")
+	if _, err := c.fileAt(ctx, c.config.AzureDevOps.Project, repo.Id.String(), yamlPath, defaultBranch); err != nil {
+		return nil, fmt.Errorf("%s not found on %s of %s: %w", yamlPath, defaultBranch, repoName, err)
+	}
+	if folder == "" {
+		folder = `\`
+	}
+	folder = `\` + strings.Trim(strings.ReplaceAll(folder, "/", `\`), `\`)
+
+	repoID := repo.Id.String()
+	repoType := "TfsGit"
+	definition := &build.BuildDefinition{
+		Name: &name,
+		Path: &folder,
+		Process: map[string]interface{}{
+			"type":         yamlProcessType,
+			"yamlFilename": yamlPath,
+		},
+		Repository: &build.BuildRepository{
+			Id:            &repoID,
+			Name:          repo.Name,
+			Type:          &repoType,
+			DefaultBranch: &defaultBranch,
+		},
+		Triggers: &[]interface{}{
+			map[string]interface{}{
+				"triggerType":        "continuousIntegration",
+				"settingsSourceType": yamlSettingsSource,
+				"branchFilters":      []string{},
+				"pathFilters":        []string{},
+			},
+		},
+		Variables: mergeVariables(nil, variables),
+	}
+	if dryRun {
+		return tools.DryRunResult("create pipeline", c.pipelineSummary(definition)), nil
+	}
+
+	created, err := c.buildClient.CreateDefinition(ctx, build.CreateDefinitionArgs{
+		Definition: definition,
+		Project:    &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error creating pipeline: %v", err)
+		return nil, fmt.Errorf("error creating pipeline %s: %w", name, err)
+	}
+	return c.pipelineSummary(created), nil
+}
+
+// updatePipeline changes the name, folder, YAML file, default branch, or variables of a YAML
+// pipeline; empty arguments leave a setting as it is.
+func (c *AzureDevOpsClient) updatePipeline(ctx context.Context, pipeline, name, folder, yamlPath, defaultBranch string, variables map[string]*build.BuildDefinitionVariable, dryRun bool) (map[string]interface{}, error) {
+	definition, err := c.findPipeline(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	if pipelineYAMLFile(definition) == "" {
+		return nil, fmt.Errorf("pipeline %s is not a YAML pipeline; edit designer pipelines in Azure DevOps", pipeline)
+	}
+
+	if name != "" {
+		definition.Name = &name
+	}
+	if folder != "" {
+		folder = `\` + strings.Trim(strings.ReplaceAll(folder, "/", `\`), `\`)
+		definition.Path = &folder
+	}
+	if yamlPath != "" {
+		process, _ := definition.Process.(map[string]interface{})
+		process["yamlFilename"] = "/" + strings.TrimPrefix(yamlPath, "/")
+	}
+	if defaultBranch != "" && definition.Repository != nil {
+		defaultBranch = branchRef(defaultBranch)
+		definition.Repository.DefaultBranch = &defaultBranch
+	}
+	if variables != nil {
+		definition.Variables = mergeVariables(definition.Variables, variables)
+	}
+	if dryRun {
+		return tools.DryRunResult("update pipeline", c.pipelineSummary(definition)), nil
+	}
+
+	updated, err := c.buildClient.UpdateDefinition(ctx, build.UpdateDefinitionArgs{
+		Definition:   definition,
+		Project:      &c.config.AzureDevOps.Project,
+		DefinitionId: definition.Id,
+	})
+	if err != nil {
+		log.Printf("Error updating pipeline: %v", err)
+		return nil, fmt.Errorf("error updating pipeline %s: %w", pipeline, err)
+	}
+	return c.pipelineSummary(updated), nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/work"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// defaultPlanWindowWeeks is how far the timeline reaches on each side of today when no dates are given.
+const defaultPlanWindowWeeks = 12
+
+func (c *AzureDevOpsClient) getPlans(ctx context.Context) ([]work.Plan, error) {
+	plans, err := c.workClient.GetPlans(ctx, work.GetPlansArgs{
+		Project: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error listing delivery plans: %v", err)
+		return nil, fmt.Errorf("error listing delivery plans: %w", err)
+	}
+	if plans == nil {
+		return []work.Plan{}, nil
+	}
+	return *plans, nil
+}
+
+func (c *AzureDevOpsClient) listDeliveryPlans(ctx context.Context) ([]map[string]interface{}, error) {
+	plans, err := c.getPlans(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []map[string]interface{}{}
+	for _, plan := range plans {
+		entry := map[string]interface{}{
+			"name":        stringValue(plan.Name),
+			"description": stringValue(plan.Description),
+		}
+		if plan.Id != nil {
+			entry["id"] = plan.Id.String()
+		}
+		if plan.Type != nil {
+			entry["type"] = string(*plan.Type)
+		}
+		if plan.ModifiedDate != nil {
+			entry["modifiedDate"] = plan.ModifiedDate.Time.Format("2006-01-02")
+		}
+		results = append(results, entry)
+	}
+
+	return results, nil
+}
+
+// timelineWorkItems maps the positional work item rows of a timeline iteration onto the team's
+// field reference names.
+func timelineWorkItems(rows *[][]interface{}, fields []string) []map[string]interface{} {
+	items := []map[string]interface{}{}
+	if rows == nil {
+		return items
+	}
+	for _, row := range *rows {
+		item := map[string]interface{}{}
+		for i, value := range row {
+			if i < len(fields) && value != nil {
+				item[fields[i]] = value
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// getDeliveryPlanTimeline returns the teams, iterations, and scheduled work items of a delivery
+// plan between start and end.
+func (c *AzureDevOpsClient) getDeliveryPlanTimeline(ctx context.Context, nameOrID string, start, end time.Time) (map[string]interface{}, error) {
+	plans, err := c.getPlans(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var plan *work.Plan
+	for i := range plans {
+		if plans[i].Id != nil && (strings.EqualFold(plans[i].Id.String(), nameOrID) || strings.EqualFold(stringValue(plans[i].Name), nameOrID)) {
+			plan = &plans[i]
+			break
+		}
+	}
+	if plan == nil {
+		return nil, fmt.Errorf("delivery plan not found: %s", nameOrID)
+	}
+
+	planID := plan.Id.String()
+	timeline, err := c.workClient.GetDeliveryTimelineData(ctx, work.GetDeliveryTimelineDataArgs{
+		Project:   &c.config.AzureDevOps.Project,
+		Id:        &planID,
+		StartDate: &azuredevops.Time{Time: start},
+		EndDate:   &azuredevops.Time{Time: end},
+	})
+	if err != nil {
+		log.Printf("Error getting delivery timeline: %v", err)
+		return nil, fmt.Errorf("error getting timeline of delivery plan %s: %w", nameOrID, err)
+	}
+
+	teams := []map[string]interface{}{}
+	if timeline.Teams != nil {
+		for _, team := range *timeline.Teams {
+			fields := []string{}
+			if team.FieldReferenceNames != nil {
+				fields = *team.FieldReferenceNames
+			}
+			iterations := []map[string]interface{}{}
+			if team.Iterations != nil {
+				for _, iteration := range *team.Iterations {
+					entry := map[string]interface{}{
+						"name":      stringValue(iteration.Name),
+						"path":      stringValue(iteration.Path),
+						"workItems": timelineWorkItems(iteration.WorkItems, fields),
+					}
+					if iteration.StartDate != nil {
+						entry["startDate"] = iteration.StartDate.Time.Format("2006-01-02")
+					}
+					if iteration.FinishDate != nil {
+						entry["finishDate"] = iteration.FinishDate.Time.Format("2006-01-02")
+					}
+					iterations = append(iterations, entry)
+				}
+			}
+			entry := map[string]interface{}{
+				"name":       stringValue(team.Name),
+				"iterations": iterations,
+			}
+			if team.Backlog != nil {
+				entry["backlog"] = stringValue(team.Backlog.PluralName)
+			}
+			if team.Status != nil && team.Status.Message != nil {
+				entry["status"] = stringValue(team.Status.Message)
+			}
+			teams = append(teams, entry)
+		}
+	}
+
+	return map[string]interface{}{
+		"plan":      stringValue(plan.Name),
+		"startDate": start.Format("2006-01-02"),
+		"endDate":   end.Format("2006-01-02"),
+		"teams":     teams,
+	}, nil
+}
+
+func addPlanTools(s tools.Server, client *AzureDevOpsClient) {
+	listPlansTool := mcp.NewTool("list_delivery_plans",
+		mcp.WithDescription("List the delivery plans of the project"),
+	)
+
+	s.AddTool(listPlansTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		results, err := client.listDeliveryPlans(ctx)
+		if err != nil {
+			log.Printf("Error listing delivery plans: %v", err)
+			return nil, fmt.Errorf("error listing delivery plans: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	timelineTool := mcp.NewTool("get_delivery_plan_timeline",
+		mcp.WithDescription("Read a delivery plan's timeline: each team's iterations and the work items scheduled in them, for cross-team roadmap questions"),
+		mcp.WithString("plan",
+			mcp.Required(),
+			mcp.Description("Delivery plan name or ID"),
+		),
+		mcp.WithString("startDate",
+			mcp.Description(fmt.Sprintf("Timeline start (YYYY-MM-DD); defaults to %d weeks ago", defaultPlanWindowWeeks)),
+		),
+		mcp.WithString("endDate",
+			mcp.Description(fmt.Sprintf("Timeline end (YYYY-MM-DD); defaults to %d weeks from now", defaultPlanWindowWeeks)),
+		),
+	)
+
+	s.AddTool(timelineTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		plan, ok := request.Params.Arguments["plan"].(string)
+		if !ok {
+			log.Print("Plan must be a string")
+			return nil, fmt.Errorf("plan must be a string")
+		}
+
+		now := time.Now().UTC()
+		start := now.AddDate(0, 0, -7*defaultPlanWindowWeeks)
+		end := now.AddDate(0, 0, 7*defaultPlanWindowWeeks)
+		if value, ok := request.Params.Arguments["startDate"].(string); ok && value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return nil, fmt.Errorf("startDate must be YYYY-MM-DD: %w", err)
+			}
+			start = parsed
+		}
+		if value, ok := request.Params.Arguments["endDate"].(string); ok && value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return nil, fmt.Errorf("endDate must be YYYY-MM-DD: %w", err)
+			}
+			end = parsed
+		}
+
+		result, err := client.getDeliveryPlanTimeline(ctx, plan, start, end)
+		if err != nil {
+			log.Printf("Error getting delivery plan timeline: %v", err)
+			return nil, fmt.Errorf("error getting delivery plan timeline: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/policy"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// policySettingKeys are the settings worth surfacing for the common branch policy types; the
+// full settings object is always returned alongside them.
+var policySettingKeys = map[string][]string{
+	"Minimum number of reviewers": {"minimumApproverCount", "creatorVoteCounts", "allowDownvotes", "resetOnSourcePush", "requireVoteOnLastIteration"},
+	"Build":                       {"buildDefinitionId", "displayName", "queueOnSourceUpdateOnly", "manualQueueOnly", "validDuration", "filenamePatterns"},
+	"Required reviewers":          {"requiredReviewerIds", "minimumApproverCount", "message", "filenamePatterns"},
+	"Comment requirements":        {},
+	"Work item linking":           {},
+	"Require a merge strategy":    {"allowNoFastForward", "allowSquash", "allowRebase", "allowRebaseMerge"},
+	"Status":                      {"statusGenre", "statusName", "invalidateOnSourceUpdate", "policyApplicability"},
+}
+
+func policySummary(configuration policy.PolicyConfiguration) map[string]interface{} {
+	typeName := ""
+	if configuration.Type != nil {
+		typeName = stringValue(configuration.Type.DisplayName)
+	}
+	entry := map[string]interface{}{
+		"type":       typeName,
+		"isEnabled":  configuration.IsEnabled != nil && *configuration.IsEnabled,
+		"isBlocking": configuration.IsBlocking != nil && *configuration.IsBlocking,
+		"settings":   configuration.Settings,
+	}
+	if configuration.Id != nil {
+		entry["id"] = *configuration.Id
+	}
+
+	settings, _ := configuration.Settings.(map[string]interface{})
+	if keys, ok := policySettingKeys[typeName]; ok && settings != nil {
+		summary := map[string]interface{}{}
+		for _, key := range keys {
+			if value, ok := settings[key]; ok {
+				summary[key] = value
+			}
+		}
+		entry["summary"] = summary
+	}
+	if settings != nil {
+		if scope, ok := settings["scope"]; ok {
+			entry["scope"] = scope
+		}
+	}
+	return entry
+}
+
+// listBranchPolicies returns the policies that apply to a branch of a repository, or to the
+// whole repository when branch is empty.
+func (c *AzureDevOpsClient) listBranchPolicies(ctx context.Context, repoName, branch string) ([]map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	args := git.GetPolicyConfigurationsArgs{
+		Project:      &c.config.AzureDevOps.Project,
+		RepositoryId: repo.Id,
+	}
+	if branch != "" {
+		ref := branchRef(branch)
+		args.RefName = &ref
+	}
+
+	results := []map[string]interface{}{}
+	for {
+		response, err := c.gitClient.GetPolicyConfigurations(ctx, args)
+		if err != nil {
+			log.Printf("Error getting policy configurations: %v", err)
+			return nil, fmt.Errorf("error getting policies for repository %s: %w", repoName, err)
+		}
+		if response.PolicyConfigurations != nil {
+			for _, configuration := range *response.PolicyConfigurations {
+				if configuration.IsDeleted != nil && *configuration.IsDeleted {
+					continue
+				}
+				results = append(results, policySummary(configuration))
+			}
+		}
+		if response.ContinuationToken == nil || *response.ContinuationToken == "" {
+			break
+		}
+		args.ContinuationToken = response.ContinuationToken
+	}
+
+	return results, nil
+}
+
+// Policy types of the branch policies the write tools manage.
+var (
+	minimumReviewersPolicyType = uuid.MustParse("fa4e907d-c16b-4a4c-9dfa-4906e5d171dd")
+	buildPolicyType            = uuid.MustParse("0609b952-1397-4640-95ec-e00a01b2c241")
+)
+
+// branchPolicyChange is a branch policy to set on each of several repositories: its settings, and
+// how to tell the existing policy it replaces, since a branch can have several of a type, such as
+// build validation of different pipelines.
+type branchPolicyChange struct {
+	policyType uuid.UUID
+	settings   map[string]interface{}
+	blocking   bool
+	enabled    bool
+	matches    func(settings map[string]interface{}) bool
+}
+
+// scopedTo reports whether a policy's settings apply to exactly a branch of a repository, as
+// opposed to a branch prefix or every repository of the project.
+func scopedTo(settings map[string]interface{}, repoID, ref string) bool {
+	scopes, _ := settings["scope"].([]interface{})
+	if len(scopes) != 1 {
+		return false
+	}
+	scope, _ := scopes[0].(map[string]interface{})
+	repository, _ := scope["repositoryId"].(string)
+	refName, _ := scope["refName"].(string)
+	matchKind, _ := scope["matchKind"].(string)
+	return strings.EqualFold(repository, repoID) && refName == ref && strings.EqualFold(matchKind, "exact")
+}
+
+// sameSetting compares setting values by their JSON, as settings read back have float64 numbers.
+func sameSetting(a, b interface{}) bool {
+	left, _ := json.Marshal(a)
+	right, _ := json.Marshal(b)
+	return string(left) == string(right)
+}
+
+// setBranchPolicy creates a branch policy on a branch of a repository, or updates the existing
+// policy that change matches. Settings the change does not name keep their current values, and a
+// policy that already has the change's settings is left alone.
+func (c *AzureDevOpsClient) setBranchPolicy(ctx context.Context, repoName, branch string, change branchPolicyChange, dryRun bool) map[string]interface{} {
+	result := map[string]interface{}{"repository": repoName}
+	fail := func(err error) map[string]interface{} {
+		result["status"] = "failed"
+		result["error"] = err.Error()
+		return result
+	}
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return fail(err)
+	}
+	repoID := repo.Id.String()
+	ref := branchRef(branch)
+
+	var existing *policy.PolicyConfiguration
+	args := git.GetPolicyConfigurationsArgs{
+		Project:      &c.config.AzureDevOps.Project,
+		RepositoryId: repo.Id,
+		RefName:      &ref,
+		PolicyType:   &change.policyType,
+	}
+	for existing == nil {
+		response, err := c.gitClient.GetPolicyConfigurations(ctx, args)
+		if err != nil {
+			log.Printf("Error getting policy configurations: %v", err)
+			return fail(fmt.Errorf("error getting policies for repository %s: %w", repoName, err))
+		}
+		if response.PolicyConfigurations != nil {
+			for i, configuration := range *response.PolicyConfigurations {
+				settings, _ := configuration.Settings.(map[string]interface{})
+				if configuration.IsDeleted != nil && *configuration.IsDeleted || !scopedTo(settings, repoID, ref) || !change.matches(settings) {
+					continue
+				}
+				existing = &(*response.PolicyConfigurations)[i]
+				break
+			}
+		}
+		if response.ContinuationToken == nil || *response.ContinuationToken == "" {
+			break
+		}
+		args.ContinuationToken = response.ContinuationToken
+	}
+
+	settings := map[string]interface{}{}
+	unchanged := existing != nil && existing.IsBlocking != nil && *existing.IsBlocking == change.blocking &&
+		existing.IsEnabled != nil && *existing.IsEnabled == change.enabled
+	if existing != nil {
+		current, _ := existing.Settings.(map[string]interface{})
+		for key, value := range current {
+			settings[key] = value
+		}
+		result["id"] = *existing.Id
+	}
+	for key, value := range change.settings {
+		if !sameSetting(settings[key], value) {
+			unchanged = false
+		}
+		settings[key] = value
+	}
+	settings["scope"] = []map[string]interface{}{{
+		"repositoryId": repoID,
+		"refName":      ref,
+		"matchKind":    "exact",
+	}}
+	result["settings"] = change.settings
+	result["isBlocking"] = change.blocking
+	result["isEnabled"] = change.enabled
+
+	switch {
+	case unchanged:
+		result["status"] = "unchanged"
+		return result
+	case dryRun && existing != nil:
+		result["status"] = "wouldUpdate"
+		result["from"] = policySummary(*existing)
+		return result
+	case dryRun:
+		result["status"] = "wouldCreate"
+		return result
+	}
+
+	configuration := policy.PolicyConfiguration{
+		Type:       &policy.PolicyTypeRef{Id: &change.policyType},
+		Settings:   settings,
+		IsBlocking: &change.blocking,
+		IsEnabled:  &change.enabled,
+	}
+	var saved *policy.PolicyConfiguration
+	if existing != nil {
+		saved, err = c.policyClient.UpdatePolicyConfiguration(ctx, policy.UpdatePolicyConfigurationArgs{
+			Configuration:   &configuration,
+			Project:         &c.config.AzureDevOps.Project,
+			ConfigurationId: existing.Id,
+		})
+		result["status"] = "updated"
+	} else {
+		saved, err = c.policyClient.CreatePolicyConfiguration(ctx, policy.CreatePolicyConfigurationArgs{
+			Configuration: &configuration,
+			Project:       &c.config.AzureDevOps.Project,
+		})
+		result["status"] = "created"
+	}
+	if err != nil {
+		log.Printf("Error saving policy configuration: %v", err)
+		return fail(fmt.Errorf("error setting policy on %s of %s: %w", ref, repoName, err))
+	}
+	if saved.Id != nil {
+		result["id"] = *saved.Id
+	}
+	return result
+}
+
+// setBranchPolicies sets a branch policy on the same branch of each repository. A repository that
+// fails is reported and the rest are still changed.
+func (c *AzureDevOpsClient) setBranchPolicies(ctx context.Context, repos []string, branch string, change branchPolicyChange, dryRun bool) []map[string]interface{} {
+	results := []map[string]interface{}{}
+	for i, repoName := range repos {
+		tools.ReportProgress(ctx, i, len(repos), "Setting policy on "+repoName)
+		if ctx.Err() != nil {
+			results = append(results, map[string]interface{}{
+				"repository": repoName,
+				"status":     "skipped",
+				"error":      "cancelled",
+			})
+			continue
+		}
+		results = append(results, c.setBranchPolicy(ctx, repoName, branch, change, dryRun))
+	}
+	return results
+}
+
+// branchPolicyTarget reads the repositories and branch a policy tool sets a policy on, and
+// whether the policy blocks completion and is enabled, both by default.
+func branchPolicyTarget(arguments map[string]interface{}) ([]string, string, bool, bool, error) {
+	repos, err := tools.StringSliceArgument(arguments, "repositories")
+	if err != nil {
+		return nil, "", false, false, err
+	}
+	if len(repos) == 0 {
+		return nil, "", false, false, fmt.Errorf("repositories must name at least one repository")
+	}
+	branch, ok := arguments["branch"].(string)
+	if !ok || branch == "" {
+		return nil, "", false, false, fmt.Errorf("branch must be a string")
+	}
+	blocking, enabled := true, true
+	if value, ok := arguments["blocking"].(bool); ok {
+		blocking = value
+	}
+	if value, ok := arguments["enabled"].(bool); ok {
+		enabled = value
+	}
+	return repos, branch, blocking, enabled, nil
+}
+
+// optionalSettings copies the boolean arguments that were passed into a policy's settings.
+func optionalSettings(settings, arguments map[string]interface{}, keys ...string) {
+	for _, key := range keys {
+		if value, ok := arguments[key].(bool); ok {
+			settings[key] = value
+		}
+	}
+}
+
+// branchPolicyResult wraps the results of a policy tool, as a dry run when nothing was changed.
+func branchPolicyResult(action string, results []map[string]interface{}, dryRun bool) (*mcp.CallToolResult, error) {
+	if dryRun {
+		return tools.JSONResult(tools.DryRunResult(action, map[string]interface{}{"policies": results}))
+	}
+	return tools.JSONResult(results)
+}
+
+func addPolicyTools(s tools.Server, client *AzureDevOpsClient) {
+	branchPoliciesTool := mcp.NewTool("list_branch_policies",
+		mcp.WithDescription("List the branch policies configured on a repository or branch: minimum reviewers, build validation, required reviewers, comment resolution, work item linking, and merge strategies"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("branch",
+			mcp.Description("Optional branch name, e.g. main; omit to list all policies on the repository"),
+		),
+	)
+
+	s.AddTool(branchPoliciesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		branch, _ := request.Params.Arguments["branch"].(string)
+
+		results, err := client.listBranchPolicies(ctx, repo, branch)
+		if err != nil {
+			log.Printf("Error listing branch policies: %v", err)
+			return nil, fmt.Errorf("error listing branch policies: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	minimumReviewersTool := mcp.NewTool("set_minimum_reviewers_policy",
+		mcp.WithDescription("Require a minimum number of approving reviewers on a branch of one or many repositories, e.g. to roll a policy out across a project. The repositories' existing policy on the branch is updated, and one is created where there is none"),
+		mcp.WithArray("repositories",
+			mcp.Required(),
+			mcp.Description("Names of the repositories to set the policy on"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("branch",
+			mcp.Required(),
+			mcp.Description("Branch the policy protects, e.g. main"),
+		),
+		mcp.WithNumber("minimumApproverCount",
+			mcp.Required(),
+			mcp.Description("Number of approvals required"),
+		),
+		mcp.WithBoolean("creatorVoteCounts",
+			mcp.Description("Let the pull request's creator approve their own changes"),
+		),
+		mcp.WithBoolean("allowDownvotes",
+			mcp.Description("Allow completion even when some reviewers wait or reject"),
+		),
+		mcp.WithBoolean("resetOnSourcePush",
+			mcp.Description("Reset all votes when new changes are pushed"),
+		),
+		mcp.WithBoolean("requireVoteOnLastIteration",
+			mcp.Description("Require approval of the most recent push"),
+		),
+		mcp.WithBoolean("blocking",
+			mcp.Description("Whether the policy must pass for pull requests to complete, defaults to true; false makes it optional"),
+		),
+		mcp.WithBoolean("enabled",
+			mcp.Description("Whether the policy is enabled, defaults to true"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(minimumReviewersTool, writeHandler(client, minimumReviewersTool, tools.WithProgress(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repos, branch, blocking, enabled, err := branchPolicyTarget(request.Params.Arguments)
+		if err != nil {
+			log.Printf("Invalid policy target: %v", err)
+			return nil, err
+		}
+		count, ok := request.Params.Arguments["minimumApproverCount"].(float64)
+		if !ok || count < 1 {
+			log.Print("Minimum approver count must be a positive number")
+			return nil, fmt.Errorf("minimumApproverCount must be a positive number")
+		}
+		settings := map[string]interface{}{"minimumApproverCount": int(count)}
+		optionalSettings(settings, request.Params.Arguments, "creatorVoteCounts", "allowDownvotes", "resetOnSourcePush", "requireVoteOnLastIteration")
+
+		dryRun := client.dryRun(request)
+		results := client.setBranchPolicies(ctx, repos, branch, branchPolicyChange{
+			policyType: minimumReviewersPolicyType,
+			settings:   settings,
+			blocking:   blocking,
+			enabled:    enabled,
+			// A branch has one minimum reviewers policy.
+			matches: func(map[string]interface{}) bool { return true },
+		}, dryRun)
+
+		return branchPolicyResult("set minimum reviewers policy", results, dryRun)
+	})))
+
+	buildValidationTool := mcp.NewTool("set_build_validation_policy",
+		mcp.WithDescription("Require a successful build of a pipeline for pull requests into a branch of one or many repositories, e.g. to roll a policy out across a project. The repositories' existing build validation of the same pipeline on the branch is updated, and one is created where there is none"),
+		mcp.WithArray("repositories",
+			mcp.Required(),
+			mcp.Description("Names of the repositories to set the policy on"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("branch",
+			mcp.Required(),
+			mcp.Description("Branch the policy protects, e.g. main"),
+		),
+		mcp.WithNumber("buildDefinitionId",
+			mcp.Required(),
+			mcp.Description("ID of the pipeline that validates pull requests"),
+		),
+		mcp.WithString("displayName",
+			mcp.Description("Name of the check shown on pull requests"),
+		),
+		mcp.WithNumber("validDuration",
+			mcp.Description("Minutes a successful build stays valid after the target branch changes, 0 for as long as it does not; defaults to 720"),
+		),
+		mcp.WithBoolean("queueOnSourceUpdateOnly",
+			mcp.Description("Only queue a build when the source branch changes, not the target"),
+		),
+		mcp.WithBoolean("manualQueueOnly",
+			mcp.Description("Only build when someone queues it, instead of on every push"),
+		),
+		mcp.WithArray("filenamePatterns",
+			mcp.Description("Path filters the build is triggered by, e.g. /src/*; prefix with ! to exclude"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("blocking",
+			mcp.Description("Whether the policy must pass for pull requests to complete, defaults to true; false makes it optional"),
+		),
+		mcp.WithBoolean("enabled",
+			mcp.Description("Whether the policy is enabled, defaults to true"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(buildValidationTool, writeHandler(client, buildValidationTool, tools.WithProgress(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repos, branch, blocking, enabled, err := branchPolicyTarget(request.Params.Arguments)
+		if err != nil {
+			log.Printf("Invalid policy target: %v", err)
+			return nil, err
+		}
+		definitionID, ok := request.Params.Arguments["buildDefinitionId"].(float64)
+		if !ok || definitionID < 1 {
+			log.Print("Build definition ID must be a number")
+			return nil, fmt.Errorf("buildDefinitionId must be a number")
+		}
+		validDuration := 720.0
+		if value, ok := request.Params.Arguments["validDuration"].(float64); ok && value >= 0 {
+			validDuration = value
+		}
+		settings := map[string]interface{}{
+			"buildDefinitionId": int(definitionID),
+			"validDuration":     int(validDuration),
+		}
+		if displayName, _ := request.Params.Arguments["displayName"].(string); displayName != "" {
+			settings["displayName"] = displayName
+		}
+		optionalSettings(settings, request.Params.Arguments, "queueOnSourceUpdateOnly", "manualQueueOnly")
+		if _, ok := request.Params.Arguments["filenamePatterns"]; ok {
+			patterns, err := tools.StringSliceArgument(request.Params.Arguments, "filenamePatterns")
+			if err != nil {
+				log.Printf("Invalid filename patterns: %v", err)
+				return nil, err
+			}
+			settings["filenamePatterns"] = patterns
+		}
+
+		dryRun := client.dryRun(request)
+		results := client.setBranchPolicies(ctx, repos, branch, branchPolicyChange{
+			policyType: buildPolicyType,
+			settings:   settings,
+			blocking:   blocking,
+			enabled:    enabled,
+			matches: func(current map[string]interface{}) bool {
+				return sameSetting(current["buildDefinitionId"], int(definitionID))
+			},
+		}, dryRun)
+
+		return branchPolicyResult("set build validation policy", results, dryRun)
+	})))
+}
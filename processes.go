@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtrackingprocess"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// projectProcess returns the process the project uses, which for an inherited process names the
+// system process it derives from.
+func (c *AzureDevOpsClient) projectProcess(ctx context.Context) (*workitemtrackingprocess.ProcessInfo, error) {
+	includeCapabilities := true
+	project, err := c.coreClient.GetProject(ctx, core.GetProjectArgs{
+		ProjectId:           &c.config.AzureDevOps.Project,
+		IncludeCapabilities: &includeCapabilities,
+	})
+	if err != nil {
+		log.Printf("Error getting project: %v", err)
+		return nil, fmt.Errorf("error getting project %s: %w", c.config.AzureDevOps.Project, err)
+	}
+	if project.Capabilities == nil {
+		return nil, fmt.Errorf("project %s reports no process", c.config.AzureDevOps.Project)
+	}
+	processID, err := uuid.Parse((*project.Capabilities)["processTemplate"]["templateTypeId"])
+	if err != nil {
+		return nil, fmt.Errorf("project %s reports no process: %w", c.config.AzureDevOps.Project, err)
+	}
+	process, err := c.processClient.GetProcessByItsId(ctx, workitemtrackingprocess.GetProcessByItsIdArgs{
+		ProcessTypeId: &processID,
+	})
+	if err != nil {
+		log.Printf("Error getting process: %v", err)
+		return nil, fmt.Errorf("error getting process %s: %w", processID, err)
+	}
+	return process, nil
+}
+
+// processWorkItemTypes lists the work item types of a process with their states.
+func (c *AzureDevOpsClient) processWorkItemTypes(ctx context.Context, processID *uuid.UUID) ([]workitemtrackingprocess.ProcessWorkItemType, error) {
+	expand := workitemtrackingprocess.GetWorkItemTypeExpandValues.States
+	types, err := c.processClient.GetProcessWorkItemTypes(ctx, workitemtrackingprocess.GetProcessWorkItemTypesArgs{
+		ProcessId: processID,
+		Expand:    &expand,
+	})
+	if err != nil {
+		log.Printf("Error getting process work item types: %v", err)
+		return nil, fmt.Errorf("error getting process work item types: %w", err)
+	}
+	return *types, nil
+}
+
+// strictPicklistFields returns the lower case reference names of the organization's picklist
+// fields that only take values from their list. Suggested picklists also accept other values.
+func (c *AzureDevOpsClient) strictPicklistFields(ctx context.Context) (map[string]bool, error) {
+	fields, err := c.witClient.GetWorkItemFields(ctx, workitemtracking.GetWorkItemFieldsArgs{})
+	if err != nil {
+		log.Printf("Error getting fields: %v", err)
+		return nil, fmt.Errorf("error getting fields: %w", err)
+	}
+	strict := map[string]bool{}
+	for _, field := range *fields {
+		if field.IsPicklist != nil && *field.IsPicklist && (field.IsPicklistSuggested == nil || !*field.IsPicklistSuggested) {
+			strict[strings.ToLower(stringValue(field.ReferenceName))] = true
+		}
+	}
+	return strict, nil
+}
+
+// customization names how a process element came to be: system, inherited, or custom.
+func customization(value *workitemtrackingprocess.CustomizationType) string {
+	if value == nil {
+		return ""
+	}
+	return string(*value)
+}
+
+// getProcess describes the process of the project: whether it is inherited and from which system
+// process, and its work item types with how each was customized.
+func (c *AzureDevOpsClient) getProcess(ctx context.Context) (map[string]interface{}, error) {
+	process, err := c.projectProcess(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{
+		"name":          stringValue(process.Name),
+		"referenceName": stringValue(process.ReferenceName),
+		"customization": customization(process.CustomizationType),
+		"description":   stringValue(process.Description),
+	}
+	if process.TypeId != nil {
+		result["id"] = process.TypeId.String()
+	}
+	if process.ParentProcessTypeId != nil && *process.ParentProcessTypeId != uuid.Nil {
+		parent, err := c.processClient.GetProcessByItsId(ctx, workitemtrackingprocess.GetProcessByItsIdArgs{
+			ProcessTypeId: process.ParentProcessTypeId,
+		})
+		if err != nil {
+			log.Printf("Error getting parent process: %v", err)
+			result["parentProcess"] = process.ParentProcessTypeId.String()
+		} else {
+			result["parentProcess"] = stringValue(parent.Name)
+		}
+	}
+
+	types, err := c.processWorkItemTypes(ctx, process.TypeId)
+	if err != nil {
+		return nil, err
+	}
+	list := []map[string]interface{}{}
+	for _, t := range types {
+		entry := map[string]interface{}{
+			"name":          stringValue(t.Name),
+			"referenceName": stringValue(t.ReferenceName),
+			"customization": customization(t.Customization),
+			"isDisabled":    t.IsDisabled != nil && *t.IsDisabled,
+		}
+		if inherits := stringValue(t.Inherits); inherits != "" {
+			entry["inherits"] = inherits
+		}
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i]["name"].(string) < list[j]["name"].(string) })
+	result["workItemTypes"] = list
+	return result, nil
+}
+
+// ruleText reads a rule condition or action as "type field value".
+func ruleText(kind, field, value string) string {
+	return strings.TrimSpace(strings.Join([]string{kind, field, value}, " "))
+}
+
+// getProcessWorkItemType describes a work item type as the process of the project defines it:
+// its fields with how each was customized and the values of picklists, its rules, and its states.
+// System rules, which every process has, are left out unless includeSystemRules is set.
+func (c *AzureDevOpsClient) getProcessWorkItemType(ctx context.Context, name string, includeSystemRules bool) (map[string]interface{}, error) {
+	process, err := c.projectProcess(ctx)
+	if err != nil {
+		return nil, err
+	}
+	types, err := c.processWorkItemTypes(ctx, process.TypeId)
+	if err != nil {
+		return nil, err
+	}
+	var workItemType *workitemtrackingprocess.ProcessWorkItemType
+	for i, t := range types {
+		if strings.EqualFold(stringValue(t.Name), name) || strings.EqualFold(stringValue(t.ReferenceName), name) {
+			workItemType = &types[i]
+			break
+		}
+	}
+	if workItemType == nil {
+		return nil, fmt.Errorf("work item type %s not found in process %s", name, stringValue(process.Name))
+	}
+
+	fields, err := c.processClient.GetAllWorkItemTypeFields(ctx, workitemtrackingprocess.GetAllWorkItemTypeFieldsArgs{
+		ProcessId:  process.TypeId,
+		WitRefName: workItemType.ReferenceName,
+	})
+	if err != nil {
+		log.Printf("Error getting process work item type fields: %v", err)
+		return nil, fmt.Errorf("error getting fields of %s: %w", name, err)
+	}
+	// Picklists are organization fields, so the work item type's fields name none of them.
+	organizationFields, err := c.witClient.GetWorkItemFields(ctx, workitemtracking.GetWorkItemFieldsArgs{})
+	if err != nil {
+		log.Printf("Error getting fields: %v", err)
+		return nil, fmt.Errorf("error getting fields: %w", err)
+	}
+	picklists := map[string]uuid.UUID{}
+	for _, field := range *organizationFields {
+		if field.IsPicklist != nil && *field.IsPicklist && field.PicklistId != nil {
+			picklists[stringValue(field.ReferenceName)] = *field.PicklistId
+		}
+	}
+
+	errors := map[string]string{}
+	fieldList := []map[string]interface{}{}
+	for _, field := range *fields {
+		referenceName := stringValue(field.ReferenceName)
+		entry := map[string]interface{}{
+			"name":          stringValue(field.Name),
+			"referenceName": referenceName,
+			"customization": customization(field.Customization),
+			"required":      field.Required != nil && *field.Required,
+			"readOnly":      field.ReadOnly != nil && *field.ReadOnly,
+		}
+		if field.Type != nil {
+			entry["type"] = string(*field.Type)
+		}
+		if field.DefaultValue != nil && field.DefaultValue != "" {
+			entry["defaultValue"] = field.DefaultValue
+		}
+		if description := stringValue(field.Description); description != "" {
+			entry["description"] = description
+		}
+		if id, ok := picklists[referenceName]; ok {
+			list, err := c.processClient.GetList(ctx, workitemtrackingprocess.GetListArgs{ListId: &id})
+			if err != nil {
+				log.Printf("Error getting picklist: %v", err)
+				errors[referenceName] = err.Error()
+			} else {
+				items := []string{}
+				if list.Items != nil {
+					items = *list.Items
+				}
+				entry["picklist"] = map[string]interface{}{
+					"items":     items,
+					"suggested": list.IsSuggested != nil && *list.IsSuggested,
+				}
+			}
+		}
+		fieldList = append(fieldList, entry)
+	}
+	sort.Slice(fieldList, func(i, j int) bool {
+		return fieldList[i]["referenceName"].(string) < fieldList[j]["referenceName"].(string)
+	})
+
+	rules, err := c.processClient.GetProcessWorkItemTypeRules(ctx, workitemtrackingprocess.GetProcessWorkItemTypeRulesArgs{
+		ProcessId:  process.TypeId,
+		WitRefName: workItemType.ReferenceName,
+	})
+	ruleList := []map[string]interface{}{}
+	if err != nil {
+		log.Printf("Error getting process work item type rules: %v", err)
+		errors["rules"] = err.Error()
+	} else {
+		for _, rule := range *rules {
+			kind := customization(rule.CustomizationType)
+			if kind == string(workitemtrackingprocess.CustomizationTypeValues.System) && !includeSystemRules {
+				continue
+			}
+			conditions := []string{}
+			if rule.Conditions != nil {
+				for _, condition := range *rule.Conditions {
+					conditionType := ""
+					if condition.ConditionType != nil {
+						conditionType = string(*condition.ConditionType)
+					}
+					conditions = append(conditions, ruleText(conditionType, stringValue(condition.Field), stringValue(condition.Value)))
+				}
+			}
+			actions := []string{}
+			if rule.Actions != nil {
+				for _, action := range *rule.Actions {
+					actionType := ""
+					if action.ActionType != nil {
+						actionType = string(*action.ActionType)
+					}
+					actions = append(actions, ruleText(actionType, stringValue(action.TargetField), stringValue(action.Value)))
+				}
+			}
+			ruleList = append(ruleList, map[string]interface{}{
+				"name":          stringValue(rule.Name),
+				"customization": kind,
+				"isDisabled":    rule.IsDisabled != nil && *rule.IsDisabled,
+				"conditions":    conditions,
+				"actions":       actions,
+			})
+		}
+	}
+
+	states := []map[string]interface{}{}
+	if workItemType.States != nil {
+		order := func(state workitemtrackingprocess.WorkItemStateResultModel) int {
+			if state.Order == nil {
+				return 0
+			}
+			return *state.Order
+		}
+		sort.SliceStable(*workItemType.States, func(i, j int) bool {
+			return order((*workItemType.States)[i]) < order((*workItemType.States)[j])
+		})
+		for _, state := range *workItemType.States {
+			states = append(states, map[string]interface{}{
+				"name":          stringValue(state.Name),
+				"category":      stringValue(state.StateCategory),
+				"customization": customization(state.CustomizationType),
+				"hidden":        state.Hidden != nil && *state.Hidden,
+			})
+		}
+	}
+
+	result := map[string]interface{}{
+		"process":       stringValue(process.Name),
+		"name":          stringValue(workItemType.Name),
+		"referenceName": stringValue(workItemType.ReferenceName),
+		"customization": customization(workItemType.Customization),
+		"fields":        fieldList,
+		"rules":         ruleList,
+		"states":        states,
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addProcessTools(s tools.Server, client *AzureDevOpsClient) {
+	processTool := mcp.NewTool("get_process",
+		mcp.WithDescription("Get the process of the project: whether it is a system or inherited process, the system process it inherits from, and its work item types with whether each is a system, inherited, or custom type"),
+	)
+
+	s.AddTool(processTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := client.getProcess(ctx)
+		if err != nil {
+			log.Printf("Error getting process: %v", err)
+			return nil, fmt.Errorf("error getting process: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	typeTool := mcp.NewTool("get_process_work_item_type",
+		mcp.WithDescription("Get a work item type as the project's process defines it: its fields with whether each is required, read-only, or custom and the items of its picklist, its custom and inherited rules with their conditions and actions, and its states in order. Use it to learn the organization's custom fields and validation before creating or updating work items"),
+		mcp.WithString("type",
+			mcp.Required(),
+			mcp.Description("Work item type name or reference name, e.g. Bug"),
+		),
+		mcp.WithBoolean("includeSystemRules",
+			mcp.Description("Also list the rules every process has"),
+		),
+	)
+
+	s.AddTool(typeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		workItemType, ok := request.Params.Arguments["type"].(string)
+		if !ok {
+			log.Print("Type must be a string")
+			return nil, fmt.Errorf("type must be a string")
+		}
+		includeSystemRules, _ := request.Params.Arguments["includeSystemRules"].(bool)
+
+		result, err := client.getProcessWorkItemType(ctx, workItemType, includeSystemRules)
+		if err != nil {
+			log.Printf("Error getting process work item type: %v", err)
+			return nil, fmt.Errorf("error getting process work item type: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// progressReader reports the bytes read from a download as progress.
+type progressReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	read    int
+	message string
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += n
+	tools.ReportProgress(r.ctx, r.read, 0, r.message)
+	return n, err
+}
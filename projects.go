@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtrackingprocess"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// projectProcesses maps project IDs to the name of the process each project uses. Processes are
+// read with their projects expanded, which avoids a project lookup per project.
+func (c *AzureDevOpsClient) projectProcesses(ctx context.Context) (map[string]string, error) {
+	expand := workitemtrackingprocess.GetProcessExpandLevelValues.Projects
+	processes, err := c.processClient.GetListOfProcesses(ctx, workitemtrackingprocess.GetListOfProcessesArgs{
+		Expand: &expand,
+	})
+	if err != nil {
+		log.Printf("Error listing processes: %v", err)
+		return nil, fmt.Errorf("error listing processes: %w", err)
+	}
+
+	result := map[string]string{}
+	if processes == nil {
+		return result, nil
+	}
+	for _, process := range *processes {
+		if process.Projects == nil {
+			continue
+		}
+		for _, project := range *process.Projects {
+			if project.Id != nil {
+				result[project.Id.String()] = stringValue(process.Name)
+			}
+		}
+	}
+	return result, nil
+}
+
+func projectSummary(project core.TeamProjectReference) map[string]interface{} {
+	entry := map[string]interface{}{
+		"name":        stringValue(project.Name),
+		"description": stringValue(project.Description),
+	}
+	if project.Id != nil {
+		entry["id"] = project.Id.String()
+	}
+	if project.Visibility != nil {
+		entry["visibility"] = string(*project.Visibility)
+	}
+	if project.State != nil {
+		entry["state"] = string(*project.State)
+	}
+	if project.LastUpdateTime != nil {
+		entry["lastUpdateTime"] = project.LastUpdateTime.Time.Format("2006-01-02")
+	}
+	return entry
+}
+
+// listProjects returns every project in the organization with its process, visibility, and description.
+func (c *AzureDevOpsClient) listProjects(ctx context.Context) ([]map[string]interface{}, error) {
+	processes, err := c.projectProcesses(ctx)
+	if err != nil {
+		// The process is informational; listing projects should not fail without it.
+		log.Printf("Listing projects without processes: %v", err)
+		processes = map[string]string{}
+	}
+
+	args := core.GetProjectsArgs{}
+	results := []map[string]interface{}{}
+	for {
+		page, err := c.coreClient.GetProjects(ctx, args)
+		if err != nil {
+			log.Printf("Error listing projects: %v", err)
+			return nil, fmt.Errorf("error listing projects: %w", err)
+		}
+		for _, project := range page.Value {
+			entry := projectSummary(project)
+			if project.Id != nil {
+				if process, ok := processes[project.Id.String()]; ok {
+					entry["process"] = process
+				}
+			}
+			results = append(results, entry)
+		}
+		// The v7 client takes the token it returns as a number
+		token, err := strconv.Atoi(page.ContinuationToken)
+		if err != nil {
+			break
+		}
+		args.ContinuationToken = &token
+	}
+
+	return results, nil
+}
+
+// getProject returns a project's metadata, including its process, source control type, and
+// default team. An empty name returns the configured project.
+func (c *AzureDevOpsClient) getProject(ctx context.Context, name string) (map[string]interface{}, error) {
+	if name == "" {
+		name = c.config.AzureDevOps.Project
+	}
+
+	includeCapabilities := true
+	project, err := c.coreClient.GetProject(ctx, core.GetProjectArgs{
+		ProjectId:           &name,
+		IncludeCapabilities: &includeCapabilities,
+	})
+	if err != nil {
+		log.Printf("Error getting project: %v", err)
+		return nil, fmt.Errorf("error getting project %s: %w", name, err)
+	}
+
+	result := projectSummary(core.TeamProjectReference{
+		Id:             project.Id,
+		Name:           project.Name,
+		Description:    project.Description,
+		Visibility:     project.Visibility,
+		State:          project.State,
+		LastUpdateTime: project.LastUpdateTime,
+	})
+	if project.Capabilities != nil {
+		capabilities := *project.Capabilities
+		if process, ok := capabilities["processTemplate"]; ok {
+			result["process"] = process["templateName"]
+		}
+		if versionControl, ok := capabilities["versioncontrol"]; ok {
+			result["sourceControlType"] = versionControl["sourceControlType"]
+		}
+	}
+	if project.DefaultTeam != nil {
+		result["defaultTeam"] = stringValue(project.DefaultTeam.Name)
+	}
+	result["configured"] = strings.EqualFold(stringValue(project.Name), c.config.AzureDevOps.Project)
+
+	return result, nil
+}
+
+func addProjectTools(s tools.Server, client *AzureDevOpsClient) {
+	listProjectsTool := mcp.NewTool("list_projects",
+		mcp.WithDescription("List all projects in the organization with their process, visibility, and description"),
+	)
+
+	s.AddTool(listProjectsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		results, err := client.listProjects(ctx)
+		if err != nil {
+			log.Printf("Error listing projects: %v", err)
+			return nil, fmt.Errorf("error listing projects: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	getProjectTool := mcp.NewTool("get_project",
+		mcp.WithDescription("Get a project's metadata: process, visibility, source control type, default team, and description"),
+		mcp.WithString("project",
+			mcp.Description("Project name or ID; defaults to the configured project"),
+		),
+	)
+
+	s.AddTool(getProjectTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name, _ := request.Params.Arguments["project"].(string)
+
+		result, err := client.getProject(ctx, name)
+		if err != nil {
+			log.Printf("Error getting project: %v", err)
+			return nil, fmt.Errorf("error getting project: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
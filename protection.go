@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+)
+
+// protectedBranchError refuses a direct push to a protected branch, naming why it is protected
+// and how to make the change instead: on a new branch, through a pull request.
+type protectedBranchError struct {
+	Branch  string
+	Reasons []string
+	// NewBranch is a branch name to retry the write with
+	NewBranch string
+}
+
+func (e *protectedBranchError) Error() string {
+	return fmt.Sprintf("%s is protected (%s); write to a new branch with newBranch, e.g. %s, and open a pull request into %s",
+		e.Branch, strings.Join(e.Reasons, "; "), e.NewBranch, e.Branch)
+}
+
+// result returns the refusal as a tool error whose content an agent can act on.
+func (e *protectedBranchError) result() (*mcp.CallToolResult, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"error":              "protected branch",
+		"message":            e.Error(),
+		"branch":             e.Branch,
+		"reasons":            e.Reasons,
+		"suggestedNewBranch": e.NewBranch,
+		"nextSteps": []string{
+			fmt.Sprintf("Repeat the call with newBranch set to %s to commit there instead", e.NewBranch),
+			fmt.Sprintf("Open a pull request from %s into %s", e.NewBranch, e.Branch),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling results: %w", err)
+	}
+	return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+}
+
+// checkBranchProtection refuses a direct push to a branch that is locked, has blocking branch
+// policies, which make Azure DevOps require pull requests for it, or matches
+// server.protected_branches. Policies that cannot be read do not stop the push, since Azure
+// DevOps enforces them itself.
+func (c *AzureDevOpsClient) checkBranchProtection(ctx context.Context, project, repoID, ref, head string) error {
+	name := strings.TrimPrefix(ref, "refs/heads/")
+	reasons := []string{}
+	for _, pattern := range c.config.Server.ProtectedBranches {
+		if matched, _ := path.Match(strings.TrimPrefix(pattern, "refs/heads/"), name); matched {
+			reasons = append(reasons, fmt.Sprintf("it matches %s in server.protected_branches", pattern))
+			break
+		}
+	}
+
+	filter := "heads/" + name
+	refs, err := c.gitClient.GetRefs(ctx, git.GetRefsArgs{
+		RepositoryId: &repoID,
+		Project:      &project,
+		Filter:       &filter,
+	})
+	if err != nil {
+		log.Printf("Error getting refs: %v", err)
+	} else {
+		for _, branch := range refs.Value {
+			if stringValue(branch.Name) == ref && branch.IsLocked != nil && *branch.IsLocked {
+				reasons = append(reasons, "it is locked")
+			}
+		}
+	}
+
+	repoUUID, err := uuid.Parse(repoID)
+	if err != nil {
+		return fmt.Errorf("invalid repository ID %s: %w", repoID, err)
+	}
+	configurations, err := c.gitClient.GetPolicyConfigurations(ctx, git.GetPolicyConfigurationsArgs{
+		Project:      &project,
+		RepositoryId: &repoUUID,
+		RefName:      &ref,
+	})
+	if err != nil {
+		log.Printf("Error getting policy configurations: %v", err)
+	} else if configurations.PolicyConfigurations != nil {
+		for _, configuration := range *configurations.PolicyConfigurations {
+			deleted := configuration.IsDeleted != nil && *configuration.IsDeleted
+			enabled := configuration.IsEnabled != nil && *configuration.IsEnabled
+			blocking := configuration.IsBlocking != nil && *configuration.IsBlocking
+			if deleted || !enabled || !blocking {
+				continue
+			}
+			reasons = append(reasons, fmt.Sprintf("its %q policy requires pull requests", policySummary(configuration)["type"]))
+		}
+	}
+
+	if len(reasons) == 0 {
+		return nil
+	}
+	short := head
+	if len(short) > 8 {
+		short = short[:8]
+	}
+	return &protectedBranchError{Branch: name, Reasons: reasons, NewBranch: fmt.Sprintf("agent/%s-%s", path.Base(name), short)}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultStatsDays = 30
+	// maxStatsPullRequests caps the pull requests measured; each costs three requests.
+	maxStatsPullRequests = 200
+	// statsLookups caps the pull requests read at once.
+	statsLookups = 8
+)
+
+// prSizeBuckets sorts pull requests by the number of files they change, smallest first.
+var prSizeBuckets = []struct {
+	name     string
+	maxFiles int
+}{
+	{"xs", 2},
+	{"s", 10},
+	{"m", 30},
+	{"l", 100},
+	{"xl", math.MaxInt},
+}
+
+// pullRequestMetrics is what one pull request contributes to the statistics.
+type pullRequestMetrics struct {
+	pr          git.GitPullRequest
+	firstReview time.Time
+	comments    int
+	files       int
+	iterations  int
+	err         error
+}
+
+// measurePullRequest reads the threads and changes of a pull request. Its first review is the
+// earliest comment or vote by anyone but its author.
+func (c *AzureDevOpsClient) measurePullRequest(ctx context.Context, pr git.GitPullRequest) pullRequestMetrics {
+	metrics := pullRequestMetrics{pr: pr}
+	repoID := pr.Repository.Id.String()
+	author := ""
+	if pr.CreatedBy != nil {
+		author = strings.ToLower(stringValue(pr.CreatedBy.Id))
+	}
+
+	threads, err := c.gitClient.GetThreads(ctx, git.GetThreadsArgs{
+		RepositoryId:  &repoID,
+		PullRequestId: pr.PullRequestId,
+		Project:       &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting pull request threads: %v", err)
+		metrics.err = fmt.Errorf("error getting threads: %w", err)
+		return metrics
+	}
+	for _, thread := range *threads {
+		if thread.Comments == nil {
+			continue
+		}
+		vote := identityProperty(thread.Properties, "CodeReviewThreadType") == "VoteUpdate"
+		for _, comment := range *thread.Comments {
+			if comment.IsDeleted != nil && *comment.IsDeleted {
+				continue
+			}
+			system := comment.CommentType != nil && *comment.CommentType == git.CommentTypeValues.System
+			if !system {
+				metrics.comments++
+			}
+			if system && !vote || comment.Author == nil || comment.PublishedDate == nil {
+				continue
+			}
+			if strings.ToLower(stringValue(comment.Author.Id)) == author {
+				continue
+			}
+			if published := comment.PublishedDate.Time; metrics.firstReview.IsZero() || published.Before(metrics.firstReview) {
+				metrics.firstReview = published
+			}
+		}
+	}
+
+	iterations, err := c.pullRequestIterations(ctx, repoID, *pr.PullRequestId)
+	if err != nil {
+		metrics.err = err
+		return metrics
+	}
+	metrics.iterations = len(iterations)
+	changes, err := c.iterationChanges(ctx, repoID, *pr.PullRequestId, *iterations[len(iterations)-1].Id, 0)
+	if err != nil {
+		metrics.err = err
+		return metrics
+	}
+	metrics.files = len(pullRequestFiles(changes))
+	return metrics
+}
+
+// durationStats summarizes durations in hours.
+func durationStats(durations []time.Duration) map[string]interface{} {
+	if len(durations) == 0 {
+		return map[string]interface{}{"count": 0}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	total := time.Duration(0)
+	for _, d := range durations {
+		total += d
+	}
+	hours := func(d time.Duration) float64 { return math.Round(d.Hours()*10) / 10 }
+	return map[string]interface{}{
+		"count":        len(durations),
+		"medianHours":  hours(durations[len(durations)/2]),
+		"p90Hours":     hours(durations[(len(durations)*9)/10]),
+		"averageHours": hours(total / time.Duration(len(durations))),
+	}
+}
+
+// pullRequestStats computes the review cycle of the pull requests of a repository created in the
+// last days, optionally only those into targetBranch: time to first review, time to merge,
+// comments, pushes, and size by files changed.
+func (c *AzureDevOpsClient) pullRequestStats(ctx context.Context, repoName, targetBranch string, days int, details bool) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+	since := time.Now().AddDate(0, 0, -days)
+
+	status := git.PullRequestStatusValues.All
+	criteria := &git.GitPullRequestSearchCriteria{Status: &status}
+	if targetBranch != "" {
+		ref := branchRef(targetBranch)
+		criteria.TargetRefName = &ref
+	}
+	pullRequests := []git.GitPullRequest{}
+	top := pullRequestPageSize
+	skip := 0
+	truncated := false
+	// Pull requests come newest first, so paging stops at the first one created before the window.
+	for done := false; !done; skip += top {
+		page, err := c.gitClient.GetPullRequests(ctx, git.GetPullRequestsArgs{
+			RepositoryId:   &repoID,
+			Project:        &c.config.AzureDevOps.Project,
+			SearchCriteria: criteria,
+			Top:            &top,
+			Skip:           &skip,
+		})
+		if err != nil {
+			log.Printf("Error getting pull requests: %v", err)
+			return nil, fmt.Errorf("error getting pull requests of %s: %w", repoName, err)
+		}
+		for _, pr := range *page {
+			if pr.CreationDate == nil || pr.CreationDate.Time.Before(since) {
+				done = true
+				break
+			}
+			if pr.Repository == nil || pr.Repository.Id == nil || pr.PullRequestId == nil {
+				continue
+			}
+			if len(pullRequests) == maxStatsPullRequests {
+				truncated, done = true, true
+				break
+			}
+			pullRequests = append(pullRequests, pr)
+		}
+		if len(*page) < top {
+			done = true
+		}
+	}
+
+	measured := make([]pullRequestMetrics, len(pullRequests))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	lookups := make(chan struct{}, statsLookups)
+	finished := 0
+	for i, pr := range pullRequests {
+		wg.Add(1)
+		go func(i int, pr git.GitPullRequest) {
+			defer wg.Done()
+			lookups <- struct{}{}
+			defer func() { <-lookups }()
+			measured[i] = c.measurePullRequest(ctx, pr)
+			mu.Lock()
+			finished++
+			tools.ReportProgress(ctx, finished, len(pullRequests), "measuring pull requests")
+			mu.Unlock()
+		}(i, pr)
+	}
+	wg.Wait()
+
+	statuses := map[string]int{}
+	toFirstReview := []time.Duration{}
+	toMerge := []time.Duration{}
+	comments := []int{}
+	sizes := map[string]int{}
+	for _, bucket := range prSizeBuckets {
+		sizes[bucket.name] = 0
+	}
+	unreviewed := 0
+	list := []map[string]interface{}{}
+	errors := map[string]string{}
+	for _, metrics := range measured {
+		pr := metrics.pr
+		if pr.Status != nil {
+			statuses[string(*pr.Status)]++
+		}
+		if metrics.err != nil {
+			errors[fmt.Sprint(*pr.PullRequestId)] = metrics.err.Error()
+			continue
+		}
+		created := pr.CreationDate.Time
+		entry := map[string]interface{}{
+			"id":         *pr.PullRequestId,
+			"title":      stringValue(pr.Title),
+			"files":      metrics.files,
+			"comments":   metrics.comments,
+			"iterations": metrics.iterations,
+		}
+		if metrics.firstReview.IsZero() {
+			unreviewed++
+		} else {
+			toFirstReview = append(toFirstReview, metrics.firstReview.Sub(created))
+			entry["hoursToFirstReview"] = math.Round(metrics.firstReview.Sub(created).Hours()*10) / 10
+		}
+		if pr.Status != nil && *pr.Status == git.PullRequestStatusValues.Completed && pr.ClosedDate != nil {
+			toMerge = append(toMerge, pr.ClosedDate.Time.Sub(created))
+			entry["hoursToMerge"] = math.Round(pr.ClosedDate.Time.Sub(created).Hours()*10) / 10
+		}
+		comments = append(comments, metrics.comments)
+		for _, bucket := range prSizeBuckets {
+			if metrics.files <= bucket.maxFiles {
+				sizes[bucket.name]++
+				entry["size"] = bucket.name
+				break
+			}
+		}
+		list = append(list, entry)
+	}
+
+	commentStats := map[string]interface{}{"total": 0}
+	if len(comments) > 0 {
+		sort.Ints(comments)
+		total := 0
+		for _, n := range comments {
+			total += n
+		}
+		commentStats = map[string]interface{}{
+			"total":   total,
+			"median":  comments[len(comments)/2],
+			"average": math.Round(float64(total)/float64(len(comments))*10) / 10,
+		}
+	}
+
+	result := map[string]interface{}{
+		"repository":        stringValue(repo.Name),
+		"since":             since.Format("2006-01-02"),
+		"pullRequests":      len(pullRequests),
+		"byStatus":          statuses,
+		"timeToFirstReview": durationStats(toFirstReview),
+		"timeToMerge":       durationStats(toMerge),
+		"unreviewed":        unreviewed,
+		"comments":          commentStats,
+		"sizes":             sizes,
+		"sizeBuckets":       "files changed: xs up to 2, s up to 10, m up to 30, l up to 100, xl more",
+	}
+	if targetBranch != "" {
+		result["targetBranch"] = branchRef(targetBranch)
+	}
+	if details {
+		result["pullRequestList"] = list
+	}
+	if truncated {
+		result["truncated"] = fmt.Sprintf("only the latest %d pull requests were measured", maxStatsPullRequests)
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addPullRequestStatsTools(s tools.Server, client *AzureDevOpsClient) {
+	statsTool := mcp.NewTool("get_pr_stats",
+		mcp.WithDescription(fmt.Sprintf("Compute review cycle metrics of the pull requests of a repository created in a time window, for engineering health reports: time to first review and to merge (median, p90, average), comment counts, unreviewed pull requests, and size buckets by files changed. Measures at most the latest %d pull requests", maxStatsPullRequests)),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("targetBranch",
+			mcp.Description("Only pull requests into this branch, e.g. main"),
+		),
+		mcp.WithNumber("days",
+			mcp.Description("Length of the window in days, ending today"),
+			mcp.DefaultNumber(defaultStatsDays),
+		),
+		mcp.WithBoolean("details",
+			mcp.Description("Also list the metrics of each pull request"),
+		),
+	)
+
+	s.AddTool(statsTool, tools.WithProgress(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		targetBranch, _ := request.Params.Arguments["targetBranch"].(string)
+		days := defaultStatsDays
+		if value, ok := request.Params.Arguments["days"].(float64); ok && value > 0 {
+			days = int(value)
+		}
+		details, _ := request.Params.Arguments["details"].(bool)
+
+		result, err := client.pullRequestStats(ctx, repo, targetBranch, days, details)
+		if err != nil {
+			log.Printf("Error getting pull request stats: %v", err)
+			return nil, fmt.Errorf("error getting pull request stats: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
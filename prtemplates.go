@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// prTemplateFolders are the folders Azure DevOps looks up pull request templates in, in order.
+var prTemplateFolders = []string{"/.azuredevops", "/.vsts", "/docs", ""}
+
+// prTemplateFile is a pull request template of a repository.
+type prTemplateFile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// prTemplates are the pull request templates of a repository: the default one, those for a target
+// branch keyed by its lowercase name, and the additional ones a pull request can be started from.
+// Where several folders have the same template, the first folder wins.
+type prTemplates struct {
+	Default    string
+	Branches   map[string]string
+	Additional []prTemplateFile
+}
+
+// isPRTemplate reports whether a file name is a template, which is Markdown or plain text.
+func isPRTemplate(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".txt":
+		return true
+	}
+	return false
+}
+
+// findPRTemplates lists the pull request templates of a repository at a commit: the
+// pull_request_template.md (or .txt) of each template folder, and the files of its
+// pull_request_template folder, where those under branches/ are named after the target branch they
+// apply to.
+func (c *AzureDevOpsClient) findPRTemplates(ctx context.Context, repoID, commit string) prTemplates {
+	templates := prTemplates{Branches: map[string]string{}}
+	additional := map[string]bool{}
+	project := c.config.AzureDevOps.Project
+	for _, folder := range prTemplateFolders {
+		scope := folder
+		if scope == "" {
+			scope = "/"
+		}
+		items, err := c.itemsAt(ctx, project, repoID, scope, commit, git.VersionControlRecursionTypeValues.OneLevel)
+		if err != nil {
+			// Most repositories have only some of the folders.
+			continue
+		}
+		hasFolder := false
+		for _, item := range items {
+			itemPath := stringValue(item.Path)
+			name := path.Base(itemPath)
+			isFolder := item.IsFolder != nil && *item.IsFolder
+			switch {
+			case isFolder && strings.EqualFold(name, "pull_request_template"):
+				hasFolder = true
+			case !isFolder && templates.Default == "" && isPRTemplate(name) &&
+				strings.EqualFold(strings.TrimSuffix(name, path.Ext(name)), "pull_request_template"):
+				templates.Default = itemPath
+			}
+		}
+		if !hasFolder {
+			continue
+		}
+
+		templateFolder := folder + "/pull_request_template"
+		items, err = c.itemsAt(ctx, project, repoID, templateFolder, commit, git.VersionControlRecursionTypeValues.Full)
+		if err != nil {
+			log.Printf("Error listing %s: %v", templateFolder, err)
+			continue
+		}
+		for _, item := range items {
+			itemPath := stringValue(item.Path)
+			if item.IsFolder != nil && *item.IsFolder || !isPRTemplate(itemPath) {
+				continue
+			}
+			relative := strings.TrimPrefix(itemPath, templateFolder+"/")
+			if branch, ok := strings.CutPrefix(relative, "branches/"); ok {
+				key := strings.ToLower(strings.TrimSuffix(branch, path.Ext(branch)))
+				if _, exists := templates.Branches[key]; !exists {
+					templates.Branches[key] = itemPath
+				}
+				continue
+			}
+			if !additional[strings.ToLower(relative)] {
+				additional[strings.ToLower(relative)] = true
+				templates.Additional = append(templates.Additional, prTemplateFile{Name: relative, Path: itemPath})
+			}
+		}
+	}
+	sort.Slice(templates.Additional, func(i, j int) bool {
+		return templates.Additional[i].Name < templates.Additional[j].Name
+	})
+	return templates
+}
+
+// pick returns the template a pull request starts from: the additional template called name, or
+// else the one for its target branch, or else the default one, with which of these it is. It
+// returns an empty path when the repository has none.
+func (t prTemplates) pick(targetBranch, name string) (string, string, error) {
+	if name != "" {
+		for _, template := range t.Additional {
+			if strings.EqualFold(template.Name, name) || strings.EqualFold(strings.TrimSuffix(template.Name, path.Ext(template.Name)), name) {
+				return template.Path, "additional", nil
+			}
+		}
+		return "", "", fmt.Errorf("no pull request template %s", name)
+	}
+	if templatePath, ok := t.Branches[strings.ToLower(strings.TrimPrefix(targetBranch, "refs/heads/"))]; ok {
+		return templatePath, "branch", nil
+	}
+	if t.Default != "" {
+		return t.Default, "default", nil
+	}
+	return "", "", nil
+}
+
+// pullRequestTemplate returns the pull request template of a repository that applies to a target
+// branch, or the additional template called name, read from the default branch like Azure DevOps
+// reads them. The additional templates are listed too. template is nil when the repository has
+// none.
+func (c *AzureDevOpsClient) pullRequestTemplate(ctx context.Context, repo *git.GitRepository, targetBranch, name string) (map[string]interface{}, error) {
+	if repo.DefaultBranch == nil {
+		return nil, fmt.Errorf("repository %s has no default branch", stringValue(repo.Name))
+	}
+	repoID := repo.Id.String()
+	if targetBranch == "" {
+		targetBranch = *repo.DefaultBranch
+	}
+	commit, err := c.resolveCommit(ctx, repoID, *repo.DefaultBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := c.findPRTemplates(ctx, repoID, commit)
+	templatePath, kind, err := templates.pick(targetBranch, name)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{
+		"repository":   stringValue(repo.Name),
+		"targetBranch": branchRef(targetBranch),
+		"template":     nil,
+		"additional":   templates.Additional,
+	}
+	if templatePath == "" {
+		return result, nil
+	}
+	content, err := c.contentAt(ctx, repoID, templatePath, commit)
+	if err != nil {
+		return nil, err
+	}
+	result["template"] = map[string]interface{}{
+		"path":    templatePath,
+		"kind":    kind,
+		"content": content,
+		"webUrl":  c.links.file(c.config.AzureDevOps.Project, stringValue(repo.Name), templatePath, *repo.DefaultBranch),
+	}
+	return result, nil
+}
+
+func addPullRequestTemplateTools(s tools.Server, client *AzureDevOpsClient) {
+	templateTool := mcp.NewTool("get_pr_template",
+		mcp.WithDescription("Get the pull request description template of a repository that applies to a target branch, so a drafted description follows the team's conventions. Templates are pull_request_template.md files in /.azuredevops, /.vsts, /docs, or the root, with branch-specific ones under pull_request_template/branches; the additional templates under pull_request_template are listed and can be picked by name"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("targetBranch",
+			mcp.Description("Branch the pull request merges into; defaults to the repository's default branch"),
+		),
+		mcp.WithString("name",
+			mcp.Description("Name of an additional template to get instead, e.g. hotfix.md"),
+		),
+	)
+
+	s.AddTool(templateTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repoName, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		targetBranch, _ := request.Params.Arguments["targetBranch"].(string)
+		name, _ := request.Params.Arguments["name"].(string)
+
+		repo, err := client.findRepository(ctx, repoName)
+		if err != nil {
+			log.Printf("Error getting pull request template: %v", err)
+			return nil, fmt.Errorf("error getting pull request template: %w", err)
+		}
+		result, err := client.pullRequestTemplate(ctx, repo, targetBranch, name)
+		if err != nil {
+			log.Printf("Error getting pull request template: %v", err)
+			return nil, fmt.Errorf("error getting pull request template: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,1009 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/policy"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultReviewTokens = 30000
+	maxReviewTokens     = 200000
+	// charsPerToken approximates how many characters of JSON make up a model token.
+	charsPerToken        = 4
+	pullRequestPageSize  = 100
+	maxPullRequestChange = 1000
+	// defaultDescriptionTokens leaves room for the description in a typical context window.
+	defaultDescriptionTokens = 20000
+)
+
+var (
+	// conventionalCommit matches the type of a conventional commit subject, e.g. fix(api)!: ...
+	conventionalCommit = regexp.MustCompile(`^([a-zA-Z]+)(\([^)]*\))?!?:`)
+	// workItemMention matches a work item mentioned in a commit message, e.g. AB#123 or #123.
+	workItemMention = regexp.MustCompile(`(?:^|[^\w&])(?:AB)?#(\d+)\b`)
+)
+
+// reviewerVotes names the votes a reviewer can cast on a pull request.
+var reviewerVotes = map[int]string{
+	10:  "approved",
+	5:   "approved with suggestions",
+	0:   "no vote",
+	-5:  "waiting for author",
+	-10: "rejected",
+}
+
+// getPullRequest returns a pull request of the project by ID.
+func (c *AzureDevOpsClient) getPullRequest(ctx context.Context, id int) (*git.GitPullRequest, error) {
+	pr, err := c.gitClient.GetPullRequestById(ctx, git.GetPullRequestByIdArgs{
+		PullRequestId: &id,
+		Project:       &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting pull request: %v", err)
+		return nil, fmt.Errorf("error getting pull request %d: %w", id, err)
+	}
+	return pr, nil
+}
+
+func (c *AzureDevOpsClient) pullRequestSummary(pr git.GitPullRequest) map[string]interface{} {
+	entry := map[string]interface{}{
+		"title":        stringValue(pr.Title),
+		"description":  stringValue(pr.Description),
+		"sourceBranch": stringValue(pr.SourceRefName),
+		"targetBranch": stringValue(pr.TargetRefName),
+		"isDraft":      pr.IsDraft != nil && *pr.IsDraft,
+	}
+	if pr.PullRequestId != nil {
+		entry["id"] = *pr.PullRequestId
+	}
+	if pr.Repository != nil {
+		entry["repository"] = stringValue(pr.Repository.Name)
+		project := c.config.AzureDevOps.Project
+		if pr.Repository.Project != nil && pr.Repository.Project.Name != nil {
+			project = *pr.Repository.Project.Name
+		}
+		if pr.PullRequestId != nil {
+			entry["webUrl"] = c.links.pullRequest(project, stringValue(pr.Repository.Name), *pr.PullRequestId)
+			entry["uri"] = c.idURI(pullRequestEntity, project, *pr.PullRequestId)
+		}
+	}
+	if pr.Status != nil {
+		entry["status"] = string(*pr.Status)
+	}
+	if pr.MergeStatus != nil {
+		entry["mergeStatus"] = string(*pr.MergeStatus)
+	}
+	if pr.CreatedBy != nil {
+		entry["createdBy"] = stringValue(pr.CreatedBy.DisplayName)
+	}
+	if pr.CreationDate != nil {
+		entry["creationDate"] = pr.CreationDate.Time.Format("2006-01-02")
+	}
+	if pr.Reviewers != nil {
+		reviewers := []map[string]interface{}{}
+		for _, reviewer := range *pr.Reviewers {
+			vote := 0
+			if reviewer.Vote != nil {
+				vote = *reviewer.Vote
+			}
+			reviewers = append(reviewers, map[string]interface{}{
+				"name":       stringValue(reviewer.DisplayName),
+				"vote":       reviewerVotes[vote],
+				"isRequired": reviewer.IsRequired != nil && *reviewer.IsRequired,
+			})
+		}
+		entry["reviewers"] = reviewers
+	}
+	return entry
+}
+
+// pullRequestIterations returns the iterations of a pull request, oldest first; each push to the
+// source branch adds one.
+func (c *AzureDevOpsClient) pullRequestIterations(ctx context.Context, repoID string, prID int) ([]git.GitPullRequestIteration, error) {
+	iterations, err := c.gitClient.GetPullRequestIterations(ctx, git.GetPullRequestIterationsArgs{
+		RepositoryId:  &repoID,
+		PullRequestId: &prID,
+		Project:       &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting pull request iterations: %v", err)
+		return nil, fmt.Errorf("error getting iterations of pull request %d: %w", prID, err)
+	}
+	if iterations == nil || len(*iterations) == 0 {
+		return nil, fmt.Errorf("pull request %d has no iterations", prID)
+	}
+	return *iterations, nil
+}
+
+// pullRequestChanges returns the latest iteration of a pull request and the files it changes
+// compared to the target branch.
+func (c *AzureDevOpsClient) pullRequestChanges(ctx context.Context, repoID string, prID int) (*git.GitPullRequestIteration, []git.GitPullRequestChange, error) {
+	iterations, err := c.pullRequestIterations(ctx, repoID, prID)
+	if err != nil {
+		return nil, nil, err
+	}
+	iteration := iterations[len(iterations)-1]
+	changes, err := c.iterationChanges(ctx, repoID, prID, *iteration.Id, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &iteration, changes, nil
+}
+
+// iterationChanges returns the files an iteration of a pull request changes compared to an
+// earlier iteration, or to the target branch when compareTo is 0.
+func (c *AzureDevOpsClient) iterationChanges(ctx context.Context, repoID string, prID, iterationID, compareTo int) ([]git.GitPullRequestChange, error) {
+	changes := []git.GitPullRequestChange{}
+	top := pullRequestPageSize
+	skip := 0
+	for len(changes) < maxPullRequestChange {
+		page, err := c.gitClient.GetPullRequestIterationChanges(ctx, git.GetPullRequestIterationChangesArgs{
+			RepositoryId:  &repoID,
+			PullRequestId: &prID,
+			IterationId:   &iterationID,
+			Project:       &c.config.AzureDevOps.Project,
+			Top:           &top,
+			Skip:          &skip,
+			CompareTo:     &compareTo,
+		})
+		if err != nil {
+			log.Printf("Error getting pull request changes: %v", err)
+			return nil, fmt.Errorf("error getting changes of pull request %d: %w", prID, err)
+		}
+		if page.ChangeEntries != nil {
+			changes = append(changes, *page.ChangeEntries...)
+		}
+		if page.NextSkip == nil || *page.NextSkip == 0 {
+			break
+		}
+		skip = *page.NextSkip
+	}
+	return changes, nil
+}
+
+// fileChange is a file changed between two commits.
+type fileChange struct {
+	Path         string
+	OriginalPath string
+	ChangeType   string
+}
+
+// changedFiles converts pull request or commit diff changes, whose items are untyped in the SDK,
+// into the files they change. Folder changes are skipped.
+func changedFiles(changes []interface{}) []fileChange {
+	files := []fileChange{}
+	for _, raw := range changes {
+		change, _ := raw.(map[string]interface{})
+		item, _ := change["item"].(map[string]interface{})
+		if isFolder, _ := item["isFolder"].(bool); isFolder {
+			continue
+		}
+		file := fileChange{}
+		file.Path, _ = item["path"].(string)
+		file.OriginalPath, _ = change["originalPath"].(string)
+		file.ChangeType, _ = change["changeType"].(string)
+		if file.Path != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// pullRequestFiles returns the files changed by pull request changes.
+func pullRequestFiles(changes []git.GitPullRequestChange) []fileChange {
+	raw := make([]interface{}, 0, len(changes))
+	for _, change := range changes {
+		entry := map[string]interface{}{
+			"item": change.Item,
+		}
+		if change.ChangeType != nil {
+			entry["changeType"] = string(*change.ChangeType)
+		}
+		if change.OriginalPath != nil {
+			entry["originalPath"] = *change.OriginalPath
+		}
+		raw = append(raw, entry)
+	}
+	return changedFiles(raw)
+}
+
+// contentAt returns the content of a file at a commit.
+func (c *AzureDevOpsClient) contentAt(ctx context.Context, repoID, path, commitID string) (string, error) {
+	file, err := c.fileAt(ctx, c.config.AzureDevOps.Project, repoID, path, commitID)
+	if err != nil {
+		log.Printf("Error getting file content: %v", err)
+		return "", fmt.Errorf("error getting %s at %s: %w", path, commitID, err)
+	}
+	return file.Content, nil
+}
+
+// fileDiff returns the unified diff of a changed file between the base and target commits.
+func (c *AzureDevOpsClient) fileDiff(ctx context.Context, repoID string, change fileChange, baseCommit, targetCommit string) (string, error) {
+	oldPath := change.Path
+	if change.OriginalPath != "" {
+		oldPath = change.OriginalPath
+	}
+
+	oldText, newText := "", ""
+	var err error
+	if !strings.Contains(change.ChangeType, "add") {
+		if oldText, err = c.contentAt(ctx, repoID, oldPath, baseCommit); err != nil {
+			return "", err
+		}
+	}
+	if !strings.Contains(change.ChangeType, "delete") {
+		if newText, err = c.contentAt(ctx, repoID, change.Path, targetCommit); err != nil {
+			return "", err
+		}
+	}
+	if strings.ContainsRune(oldText, 0) || strings.ContainsRune(newText, 0) {
+		return "", fmt.Errorf("binary file")
+	}
+	return unifiedDiff(oldPath, change.Path, oldText, newText)
+}
+
+// budgetedDiffs lists changed files with their diffs, adding diffs in order until the estimated
+// tokens reach maxTokens. A diff that does not fit is summarized by the client's model when the
+// client supports sampling. used is the budget already spent; the updated total and the number
+// of diffs left out for lack of budget are returned alongside the files.
+func (c *AzureDevOpsClient) budgetedDiffs(ctx context.Context, repoID string, changes []fileChange, baseCommit, targetCommit string, used, maxTokens int) ([]map[string]interface{}, int, int) {
+	files := []map[string]interface{}{}
+	omitted := 0
+	sampled := 0
+	canSample := true
+	for _, change := range changes {
+		file := map[string]interface{}{
+			"path":       change.Path,
+			"changeType": change.ChangeType,
+		}
+		if change.OriginalPath != "" {
+			file["originalPath"] = change.OriginalPath
+		}
+		files = append(files, file)
+
+		if used >= maxTokens {
+			file["omitted"] = "token budget reached"
+			omitted++
+			continue
+		}
+		diff, err := c.fileDiff(ctx, repoID, change, baseCommit, targetCommit)
+		if err != nil {
+			file["omitted"] = err.Error()
+			continue
+		}
+		tokens := estimateTokens(diff)
+		if used+tokens > maxTokens {
+			summaryTokens := diffSummaryTokens
+			if remaining := maxTokens - used; remaining < summaryTokens {
+				summaryTokens = remaining
+			}
+			if canSample && sampled < maxSampledDiffs {
+				summary, err := sampleText(ctx, diffSummaryPrompt, diff, summaryTokens)
+				if err == nil {
+					sampled++
+					file["diffSummary"] = summary
+					used += estimateTokens(summary)
+					continue
+				}
+				// Without sampling support every later attempt fails the same way.
+				canSample = err != errSamplingUnavailable
+			}
+			file["omitted"] = fmt.Sprintf("diff of about %d tokens exceeds the remaining budget", tokens)
+			omitted++
+			continue
+		}
+		file["diff"] = diff
+		used += tokens
+	}
+	return files, used, omitted
+}
+
+// pullRequestPolicies returns the evaluations of the branch policies that apply to a pull request.
+func (c *AzureDevOpsClient) pullRequestPolicies(ctx context.Context, prID int) ([]policy.PolicyEvaluationRecord, error) {
+	projectID, err := c.projectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	artifactID := fmt.Sprintf("vstfs:///CodeReview/CodeReviewId/%s/%d", projectID, prID)
+	evaluations, err := c.policyClient.GetPolicyEvaluations(ctx, policy.GetPolicyEvaluationsArgs{
+		Project:    &c.config.AzureDevOps.Project,
+		ArtifactId: &artifactID,
+	})
+	if err != nil {
+		log.Printf("Error getting policy evaluations: %v", err)
+		return nil, fmt.Errorf("error getting policy evaluations of pull request %d: %w", prID, err)
+	}
+	if evaluations == nil {
+		return []policy.PolicyEvaluationRecord{}, nil
+	}
+	return *evaluations, nil
+}
+
+func (c *AzureDevOpsClient) policyEvaluationSummary(evaluation policy.PolicyEvaluationRecord) map[string]interface{} {
+	entry := map[string]interface{}{}
+	if evaluation.Status != nil {
+		entry["status"] = string(*evaluation.Status)
+	}
+	if evaluation.Configuration != nil {
+		if evaluation.Configuration.Type != nil {
+			entry["type"] = stringValue(evaluation.Configuration.Type.DisplayName)
+		}
+		entry["isBlocking"] = evaluation.Configuration.IsBlocking != nil && *evaluation.Configuration.IsBlocking
+		if settings, ok := evaluation.Configuration.Settings.(map[string]interface{}); ok {
+			if name, ok := settings["displayName"].(string); ok && name != "" {
+				entry["name"] = name
+			}
+		}
+	}
+	if context, ok := evaluation.Context.(map[string]interface{}); ok {
+		if buildID, ok := context["buildId"]; ok {
+			entry["buildId"] = buildID
+			entry["buildUrl"] = c.links.build(c.config.AzureDevOps.Project, buildID)
+			if id, ok := buildID.(float64); ok {
+				entry["buildUri"] = c.idURI(buildEntity, c.config.AzureDevOps.Project, int(id))
+			}
+		}
+	}
+	return entry
+}
+
+// pullRequestWorkItems returns the work items linked to a pull request.
+func (c *AzureDevOpsClient) pullRequestWorkItems(ctx context.Context, repoID string, prID int) ([]map[string]interface{}, error) {
+	refs, err := c.gitClient.GetPullRequestWorkItemRefs(ctx, git.GetPullRequestWorkItemRefsArgs{
+		RepositoryId:  &repoID,
+		PullRequestId: &prID,
+		Project:       &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting pull request work items: %v", err)
+		return nil, fmt.Errorf("error getting work items of pull request %d: %w", prID, err)
+	}
+
+	ids := []int{}
+	for _, ref := range *refs {
+		if id, err := strconv.Atoi(stringValue(ref.Id)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return c.workItemSummaries(ctx, ids)
+}
+
+// workItemSummaries returns the summaries of the work items with the given IDs, skipping any that
+// do not exist or cannot be read.
+func (c *AzureDevOpsClient) workItemSummaries(ctx context.Context, ids []int) ([]map[string]interface{}, error) {
+	results := []map[string]interface{}{}
+	if len(ids) == 0 {
+		return results, nil
+	}
+	items, err := c.getWorkItemsBatch(ctx, ids, []string{"System.WorkItemType", "System.Title", "System.State", "System.AssignedTo"})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.Id != nil {
+			results = append(results, c.workItemSummary(item))
+		}
+	}
+	return results, nil
+}
+
+// estimateTokens approximates the model tokens v takes up once marshaled.
+func estimateTokens(v interface{}) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(data) / charsPerToken
+}
+
+// reviewPullRequest gathers everything needed to review a pull request: its metadata, linked work
+// items, policy evaluations, failing checks, and file diffs. Diffs are added file by file until
+// maxTokens is reached; later files are listed without their diff.
+func (c *AzureDevOpsClient) reviewPullRequest(ctx context.Context, prID, maxTokens int) (map[string]interface{}, error) {
+	pr, err := c.getPullRequest(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Repository == nil || pr.Repository.Id == nil {
+		return nil, fmt.Errorf("pull request %d has no repository", prID)
+	}
+	repoID := pr.Repository.Id.String()
+
+	result := map[string]interface{}{
+		"pullRequest": c.pullRequestSummary(*pr),
+	}
+	errors := map[string]string{}
+
+	workItems, err := c.pullRequestWorkItems(ctx, repoID, prID)
+	if err != nil {
+		errors["workItems"] = err.Error()
+	} else {
+		result["workItems"] = workItems
+	}
+
+	failingChecks := []map[string]interface{}{}
+	evaluations, err := c.pullRequestPolicies(ctx, prID)
+	if err != nil {
+		errors["policies"] = err.Error()
+	} else {
+		policies := []map[string]interface{}{}
+		for _, evaluation := range evaluations {
+			entry := c.policyEvaluationSummary(evaluation)
+			policies = append(policies, entry)
+			if evaluation.Status != nil && (*evaluation.Status == policy.PolicyEvaluationStatusValues.Rejected || *evaluation.Status == policy.PolicyEvaluationStatusValues.Broken) {
+				failingChecks = append(failingChecks, entry)
+			}
+		}
+		result["policies"] = policies
+	}
+
+	statuses, err := c.gitClient.GetPullRequestStatuses(ctx, git.GetPullRequestStatusesArgs{
+		RepositoryId:  &repoID,
+		PullRequestId: &prID,
+		Project:       &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting pull request statuses: %v", err)
+		errors["statuses"] = err.Error()
+	} else {
+		for _, status := range *statuses {
+			if status.State == nil || (*status.State != git.GitStatusStateValues.Failed && *status.State != git.GitStatusStateValues.Error) {
+				continue
+			}
+			check := commitStatusSummary(git.GitStatus{
+				State:        status.State,
+				Context:      status.Context,
+				Description:  status.Description,
+				TargetUrl:    status.TargetUrl,
+				CreatedBy:    status.CreatedBy,
+				CreationDate: status.CreationDate,
+			})
+			failingChecks = append(failingChecks, check)
+		}
+	}
+	result["failingChecks"] = failingChecks
+
+	iteration, changes, err := c.pullRequestChanges(ctx, repoID, prID)
+	if err != nil {
+		errors["files"] = err.Error()
+	} else {
+		baseCommit, targetCommit := "", ""
+		if iteration.CommonRefCommit != nil {
+			baseCommit = stringValue(iteration.CommonRefCommit.CommitId)
+		}
+		if iteration.SourceRefCommit != nil {
+			targetCommit = stringValue(iteration.SourceRefCommit.CommitId)
+		}
+
+		files, used, omitted := c.budgetedDiffs(ctx, repoID, pullRequestFiles(changes), baseCommit, targetCommit, estimateTokens(result), maxTokens)
+		result["files"] = files
+		result["budget"] = map[string]interface{}{
+			"maxTokens":    maxTokens,
+			"usedTokens":   used,
+			"omittedDiffs": omitted,
+			"baseCommit":   baseCommit,
+			"sourceCommit": targetCommit,
+			"iteration":    iteration.Id,
+		}
+	}
+
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// iterationCommit returns the commit of a ref of an iteration, or "" when it is not set.
+func iterationCommit(commit *git.GitCommitRef) string {
+	if commit == nil {
+		return ""
+	}
+	return stringValue(commit.CommitId)
+}
+
+// reviewPullRequestSince gathers what changed in a pull request after iteration since, typically
+// the one last reviewed: the iterations pushed since, their commits, and the diff of each file
+// between the source commits of since and the latest iteration, sized to maxTokens.
+func (c *AzureDevOpsClient) reviewPullRequestSince(ctx context.Context, prID, since, maxTokens int) (map[string]interface{}, error) {
+	pr, err := c.getPullRequest(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Repository == nil || pr.Repository.Id == nil {
+		return nil, fmt.Errorf("pull request %d has no repository", prID)
+	}
+	repoID := pr.Repository.Id.String()
+
+	iterations, err := c.pullRequestIterations(ctx, repoID, prID)
+	if err != nil {
+		return nil, err
+	}
+	latest := iterations[len(iterations)-1]
+	var reviewed *git.GitPullRequestIteration
+	for i := range iterations {
+		if iterations[i].Id != nil && *iterations[i].Id == since {
+			reviewed = &iterations[i]
+		}
+	}
+	if reviewed == nil {
+		return nil, fmt.Errorf("pull request %d has no iteration %d; its iterations are 1 to %d", prID, since, *latest.Id)
+	}
+
+	summary := c.pullRequestSummary(*pr)
+	delete(summary, "description")
+	result := map[string]interface{}{
+		"pullRequest":     summary,
+		"sinceIteration":  since,
+		"latestIteration": *latest.Id,
+	}
+	if since == *latest.Id {
+		result["files"] = []map[string]interface{}{}
+		result["message"] = fmt.Sprintf("nothing was pushed after iteration %d", since)
+		return result, nil
+	}
+
+	baseCommit := iterationCommit(reviewed.SourceRefCommit)
+	targetCommit := iterationCommit(latest.SourceRefCommit)
+	pushed := []map[string]interface{}{}
+	for _, iteration := range iterations {
+		if iteration.Id == nil || *iteration.Id <= since {
+			continue
+		}
+		entry := map[string]interface{}{
+			"id":           *iteration.Id,
+			"description":  stringValue(iteration.Description),
+			"sourceCommit": iterationCommit(iteration.SourceRefCommit),
+		}
+		if iteration.Author != nil {
+			entry["author"] = stringValue(iteration.Author.DisplayName)
+		}
+		if iteration.CreatedDate != nil {
+			entry["createdDate"] = iteration.CreatedDate.Time.Format(time.RFC3339)
+		}
+		if iteration.Reason != nil {
+			entry["reason"] = string(*iteration.Reason)
+		}
+		pushed = append(pushed, entry)
+	}
+	result["iterations"] = pushed
+	errors := map[string]string{}
+
+	// A changed merge base means the source branch was rebased or merged with the target since the
+	// review, so the diff below includes changes that came from the target.
+	if iterationCommit(reviewed.CommonRefCommit) != iterationCommit(latest.CommonRefCommit) {
+		result["targetMergedIn"] = true
+	}
+
+	commits, err := c.commitsBetween(ctx, repoID, baseCommit, targetCommit)
+	if err != nil {
+		errors["commits"] = err.Error()
+	} else {
+		commitList := []map[string]interface{}{}
+		for _, commit := range commits {
+			entry := map[string]interface{}{
+				"commitId": stringValue(commit.CommitId),
+				"subject":  commitSubject(stringValue(commit.Comment)),
+			}
+			if commit.Author != nil {
+				entry["author"] = stringValue(commit.Author.Name)
+			}
+			commitList = append(commitList, entry)
+		}
+		result["commits"] = commitList
+	}
+
+	changes, err := c.iterationChanges(ctx, repoID, prID, *latest.Id, since)
+	if err != nil {
+		errors["files"] = err.Error()
+	} else {
+		files, used, omitted := c.budgetedDiffs(ctx, repoID, pullRequestFiles(changes), baseCommit, targetCommit, estimateTokens(result), maxTokens)
+		result["files"] = files
+		result["budget"] = map[string]interface{}{
+			"maxTokens":    maxTokens,
+			"usedTokens":   used,
+			"omittedDiffs": omitted,
+			"baseCommit":   baseCommit,
+			"sourceCommit": targetCommit,
+		}
+	}
+
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// commitSubject returns the first line of a commit message.
+func commitSubject(comment string) string {
+	subject, _, _ := strings.Cut(comment, "\n")
+	return strings.TrimSpace(subject)
+}
+
+// commitWorkItemIDs returns the work items linked to a commit or mentioned in its message.
+func commitWorkItemIDs(commit git.GitCommitRef) []int {
+	ids := []int{}
+	for _, match := range workItemMention.FindAllStringSubmatch(stringValue(commit.Comment), -1) {
+		if id, err := strconv.Atoi(match[1]); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if commit.WorkItems != nil {
+		for _, ref := range *commit.WorkItems {
+			if id, err := strconv.Atoi(stringValue(ref.Id)); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// sortedIDs returns the keys of a set of IDs in ascending order.
+func sortedIDs(set map[int]bool) []int {
+	ids := make([]int, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// prepareDescription gathers what a pull request description for sourceBranch into targetBranch
+// is written from: the commits, changed files grouped by top-level folder, themes from
+// conventional commit types, linked work items, and diffs sized to maxTokens. targetBranch
+// defaults to the repository's default branch.
+func (c *AzureDevOpsClient) prepareDescription(ctx context.Context, repoName, sourceBranch, targetBranch string, maxTokens int) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+	if targetBranch == "" {
+		if repo.DefaultBranch == nil {
+			return nil, fmt.Errorf("repository %s has no default branch; pass targetBranch", repoName)
+		}
+		targetBranch = *repo.DefaultBranch
+	}
+	sourceBranch = branchRef(sourceBranch)
+	targetBranch = branchRef(targetBranch)
+
+	sourceCommit, err := c.resolveCommit(ctx, repoID, sourceBranch)
+	if err != nil {
+		return nil, err
+	}
+	targetCommit, err := c.resolveCommit(ctx, repoID, targetBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := c.commitsBetween(ctx, repoID, targetCommit, sourceCommit)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("%s has no commits that are not in %s", sourceBranch, targetBranch)
+	}
+	result := map[string]interface{}{
+		"repository":   stringValue(repo.Name),
+		"sourceBranch": sourceBranch,
+		"targetBranch": targetBranch,
+		"sourceCommit": sourceCommit,
+		"targetCommit": targetCommit,
+	}
+	errors := map[string]string{}
+
+	commitList := []map[string]interface{}{}
+	themes := map[string]int{}
+	workItemIDs := map[int]bool{}
+	for _, commit := range commits {
+		comment := stringValue(commit.Comment)
+		entry := map[string]interface{}{
+			"commitId": stringValue(commit.CommitId),
+			"subject":  commitSubject(comment),
+			"webUrl":   c.links.commit(c.config.AzureDevOps.Project, repoName, stringValue(commit.CommitId)),
+			"uri":      c.commitURI(c.config.AzureDevOps.Project, repoName, stringValue(commit.CommitId)),
+		}
+		if commit.Author != nil {
+			entry["author"] = stringValue(commit.Author.Name)
+		}
+		commitList = append(commitList, entry)
+
+		if match := conventionalCommit.FindStringSubmatch(commitSubject(comment)); match != nil {
+			themes[strings.ToLower(match[1])]++
+		}
+		for _, id := range commitWorkItemIDs(commit) {
+			workItemIDs[id] = true
+		}
+	}
+	result["commits"] = commitList
+	result["themes"] = themes
+	// Commits are newest first; a single commit's subject is usually the best title.
+	if len(commits) == 1 {
+		result["suggestedTitle"] = commitSubject(stringValue(commits[0].Comment))
+	} else {
+		result["suggestedTitle"] = strings.TrimPrefix(sourceBranch, "refs/heads/")
+	}
+
+	// Mentions like #12 can be issue numbers elsewhere; missing work items are skipped.
+	workItems, err := c.workItemSummaries(ctx, sortedIDs(workItemIDs))
+	if err != nil {
+		errors["workItems"] = err.Error()
+	} else {
+		result["workItems"] = workItems
+	}
+
+	// The description follows the repository's template for the target branch, if it has one.
+	template, err := c.pullRequestTemplate(ctx, repo, targetBranch, "")
+	if err != nil {
+		errors["template"] = err.Error()
+	} else if template["template"] != nil {
+		result["template"] = template["template"]
+	}
+
+	changes, commonCommit, err := c.changesBetween(ctx, repoID, targetCommit, sourceCommit)
+	if err != nil {
+		errors["files"] = err.Error()
+	} else {
+		areas := map[string]int{}
+		for _, change := range changes {
+			// Files at the root count toward "/", everything else toward its top-level folder.
+			area, _, nested := strings.Cut(strings.TrimPrefix(change.Path, "/"), "/")
+			if !nested {
+				area = "/"
+			}
+			areas[area]++
+		}
+		result["areas"] = areas
+
+		files, used, omitted := c.budgetedDiffs(ctx, repoID, changes, commonCommit, sourceCommit, estimateTokens(result), maxTokens)
+		result["files"] = files
+		result["budget"] = map[string]interface{}{
+			"maxTokens":    maxTokens,
+			"usedTokens":   used,
+			"omittedDiffs": omitted,
+			"baseCommit":   commonCommit,
+		}
+	}
+
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// setPullRequestDescription replaces the description, and optionally the title, of a pull request.
+func (c *AzureDevOpsClient) setPullRequestDescription(ctx context.Context, prID int, description, title string, dryRun bool) (map[string]interface{}, error) {
+	pr, err := c.getPullRequest(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Repository == nil || pr.Repository.Id == nil {
+		return nil, fmt.Errorf("pull request %d has no repository", prID)
+	}
+	repoID := pr.Repository.Id.String()
+
+	update := git.GitPullRequest{
+		Description: &description,
+	}
+	if title != "" {
+		update.Title = &title
+	}
+	if dryRun {
+		changes := map[string]interface{}{
+			"pullRequestId": prID,
+			"description": map[string]interface{}{
+				"from": stringValue(pr.Description),
+				"to":   description,
+			},
+		}
+		if title != "" {
+			changes["title"] = map[string]interface{}{
+				"from": stringValue(pr.Title),
+				"to":   title,
+			}
+		}
+		return tools.DryRunResult("update pull request", changes), nil
+	}
+	updated, err := c.gitClient.UpdatePullRequest(ctx, git.UpdatePullRequestArgs{
+		GitPullRequestToUpdate: &update,
+		RepositoryId:           &repoID,
+		PullRequestId:          &prID,
+		Project:                &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error updating pull request: %v", err)
+		return nil, fmt.Errorf("error updating pull request %d: %w", prID, err)
+	}
+	return c.pullRequestSummary(*updated), nil
+}
+
+func addPullRequestTools(s tools.Server, client *AzureDevOpsClient) {
+	reviewTool := mcp.NewTool("review_pr",
+		mcp.WithDescription("Gather everything needed to review a pull request in one call: metadata and reviewers, linked work items, policy status, failing checks, and the diff of each changed file, sized to a token budget"),
+		mcp.WithNumber("pullRequestId",
+			mcp.Description("Pull request ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the pull request"),
+		),
+		mcp.WithNumber("maxTokens",
+			mcp.Description(fmt.Sprintf("Approximate token budget of the result, at most %d; diffs that do not fit are listed without content", maxReviewTokens)),
+			mcp.DefaultNumber(defaultReviewTokens),
+		),
+	)
+
+	s.AddTool(reviewTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := client.idArgument(request, "pullRequestId", pullRequestEntity)
+		if err != nil {
+			log.Printf("Invalid pull request: %v", err)
+			return nil, err
+		}
+		maxTokens := defaultReviewTokens
+		if value, ok := request.Params.Arguments["maxTokens"].(float64); ok && value > 0 {
+			maxTokens = int(value)
+		}
+		if maxTokens > maxReviewTokens {
+			maxTokens = maxReviewTokens
+		}
+
+		result, err := client.reviewPullRequest(ctx, id, maxTokens)
+		if err != nil {
+			log.Printf("Error reviewing pull request: %v", err)
+			return nil, fmt.Errorf("error reviewing pull request: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+	sinceTool := mcp.NewTool("review_pr_since",
+		mcp.WithDescription("Review only what changed in a pull request after a given iteration, e.g. the one last reviewed: the pushes and commits since, and the diff of each file changed since, sized to a token budget. Each push to the source branch is a new iteration; review_pr reports the latest"),
+		mcp.WithNumber("pullRequestId",
+			mcp.Description("Pull request ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the pull request"),
+		),
+		mcp.WithNumber("sinceIteration",
+			mcp.Required(),
+			mcp.Description("Iteration already reviewed; changes pushed after it are returned"),
+		),
+		mcp.WithNumber("maxTokens",
+			mcp.Description(fmt.Sprintf("Approximate token budget of the result, at most %d; diffs that do not fit are listed without content", maxReviewTokens)),
+			mcp.DefaultNumber(defaultReviewTokens),
+		),
+	)
+
+	s.AddTool(sinceTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := client.idArgument(request, "pullRequestId", pullRequestEntity)
+		if err != nil {
+			log.Printf("Invalid pull request: %v", err)
+			return nil, err
+		}
+		since, ok := request.Params.Arguments["sinceIteration"].(float64)
+		if !ok || since < 1 {
+			log.Print("Since iteration must be a positive number")
+			return nil, fmt.Errorf("sinceIteration must be a positive number")
+		}
+		maxTokens := defaultReviewTokens
+		if value, ok := request.Params.Arguments["maxTokens"].(float64); ok && value > 0 {
+			maxTokens = int(value)
+		}
+		if maxTokens > maxReviewTokens {
+			maxTokens = maxReviewTokens
+		}
+
+		result, err := client.reviewPullRequestSince(ctx, id, int(since), maxTokens)
+		if err != nil {
+			log.Printf("Error reviewing pull request changes: %v", err)
+			return nil, fmt.Errorf("error reviewing pull request changes: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+	descriptionTool := mcp.NewTool("prepare_pr_description",
+		mcp.WithDescription("Gather what a pull request description is written from: commits of the source branch not in the target, themes from conventional commit types, changed areas, linked work items (including AB#123 mentions), the repository's pull request template, and diffs sized to a token budget. Draft the description from the result, then set it with set_pr_description"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("sourceBranch",
+			mcp.Required(),
+			mcp.Description("Branch with the changes, e.g. feature/login"),
+		),
+		mcp.WithString("targetBranch",
+			mcp.Description("Branch the changes merge into; defaults to the repository's default branch"),
+		),
+		mcp.WithNumber("maxTokens",
+			mcp.Description(fmt.Sprintf("Approximate token budget of the result, at most %d; diffs that do not fit are listed without content", maxReviewTokens)),
+			mcp.DefaultNumber(defaultDescriptionTokens),
+		),
+	)
+
+	s.AddTool(descriptionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		sourceBranch, ok := request.Params.Arguments["sourceBranch"].(string)
+		if !ok {
+			log.Print("Source branch must be a string")
+			return nil, fmt.Errorf("sourceBranch must be a string")
+		}
+		targetBranch, _ := request.Params.Arguments["targetBranch"].(string)
+		maxTokens := defaultDescriptionTokens
+		if value, ok := request.Params.Arguments["maxTokens"].(float64); ok && value > 0 {
+			maxTokens = int(value)
+		}
+		if maxTokens > maxReviewTokens {
+			maxTokens = maxReviewTokens
+		}
+
+		result, err := client.prepareDescription(ctx, repo, sourceBranch, targetBranch, maxTokens)
+		if err != nil {
+			log.Printf("Error preparing pull request description: %v", err)
+			return nil, fmt.Errorf("error preparing pull request description: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	setDescriptionTool := mcp.NewTool("set_pr_description",
+		mcp.WithDescription("Replace the description, and optionally the title, of a pull request, e.g. with one drafted from prepare_pr_description"),
+		mcp.WithNumber("pullRequestId",
+			mcp.Description("Pull request ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the pull request"),
+		),
+		mcp.WithString("description",
+			mcp.Required(),
+			mcp.Description("New description in Markdown"),
+		),
+		mcp.WithString("title",
+			mcp.Description("New title; omit to keep the current one"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(setDescriptionTool, writeHandler(client, setDescriptionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := client.idArgument(request, "pullRequestId", pullRequestEntity)
+		if err != nil {
+			log.Printf("Invalid pull request: %v", err)
+			return nil, err
+		}
+		description, ok := request.Params.Arguments["description"].(string)
+		if !ok {
+			log.Print("Description must be a string")
+			return nil, fmt.Errorf("description must be a string")
+		}
+		title, _ := request.Params.Arguments["title"].(string)
+
+		result, err := client.setPullRequestDescription(ctx, id, description, title, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error setting pull request description: %v", err)
+			return nil, fmt.Errorf("error setting pull request description: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/signify/sgfy-mcp/config"
+)
+
+const (
+	defaultRedisTTLHours = 24 * 7
+	// redisTimeout bounds each cache command; a slow Redis should cost a miss, not a slow tool call.
+	redisTimeout = 2 * time.Second
+	// maxRedisConnections caps the idle connections kept for reuse.
+	maxRedisConnections = 8
+)
+
+// redisCache stores entries in Redis with an expiry, so several server instances share them.
+// Eviction beyond the expiry is left to the server's maxmemory policy. It speaks the few RESP
+// commands it needs itself rather than pulling in a client library.
+type redisCache struct {
+	config config.RedisConfig
+	ttl    time.Duration
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newRedisCache(config config.RedisConfig) (*redisCache, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("cache.redis.address is required for the redis cache")
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "sgfy-mcp:"
+	}
+	if config.TTLHours <= 0 {
+		config.TTLHours = defaultRedisTTLHours
+	}
+	cache := &redisCache{config: config, ttl: time.Duration(config.TTLHours) * time.Hour}
+
+	// Fail at startup on a wrong address or password rather than on every tool call.
+	conn, err := cache.connect()
+	if err != nil {
+		log.Printf("Error connecting to Redis: %v", err)
+		return nil, fmt.Errorf("error connecting to Redis at %s: %w", config.Address, err)
+	}
+	cache.release(conn)
+	return cache, nil
+}
+
+func (c *redisCache) get(key string) ([]byte, bool) {
+	value, err := c.do("GET", c.config.KeyPrefix+key)
+	if err != nil {
+		log.Printf("Error reading from Redis: %v", err)
+		return nil, false
+	}
+	if value == nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func (c *redisCache) put(key string, value []byte) {
+	seconds := strconv.Itoa(int(c.ttl / time.Second))
+	if _, err := c.do("SET", c.config.KeyPrefix+key, string(value), "EX", seconds); err != nil {
+		log.Printf("Error writing to Redis: %v", err)
+	}
+}
+
+// do sends a command and returns its reply: the value of a bulk string, nil for a null reply, or
+// an error for an error reply.
+func (c *redisCache) do(args ...string) ([]byte, error) {
+	conn, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.command(args...)
+	if err != nil {
+		// The connection may be half-way through a reply; drop it.
+		conn.conn.Close()
+		return nil, err
+	}
+	c.release(conn)
+	return reply, nil
+}
+
+func (c *redisCache) acquire() (*redisConn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+	return c.connect()
+}
+
+func (c *redisCache) release(conn *redisConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.idle) >= maxRedisConnections {
+		conn.conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+func (c *redisCache) connect() (*redisConn, error) {
+	netConn, err := net.DialTimeout("tcp", c.config.Address, redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	if c.config.Password != "" {
+		if _, err := conn.command("AUTH", c.config.Password); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("error authenticating: %w", err)
+		}
+	}
+	if c.config.DB != 0 {
+		if _, err := conn.command("SELECT", strconv.Itoa(c.config.DB)); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("error selecting database %d: %w", c.config.DB, err)
+		}
+	}
+	return conn, nil
+}
+
+func (r *redisConn) command(args ...string) ([]byte, error) {
+	if err := r.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+	request := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		request += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(r.conn, request); err != nil {
+		return nil, err
+	}
+
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("malformed Redis reply %q", line)
+	}
+	body := line[1 : len(line)-2]
+	switch line[0] {
+	case '+', ':':
+		return []byte(body), nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", body)
+	case '$':
+		size, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("malformed Redis reply %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(r.reader, value); err != nil {
+			return nil, err
+		}
+		return value[:size], nil
+	}
+	return nil, fmt.Errorf("unexpected Redis reply %q", line)
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/search"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxRelatedReferences caps the files returned as referencing the requested file.
+const maxRelatedReferences = 20
+
+// importPatterns find the imported modules of a source file, keyed by file extension. The first
+// submatch of each pattern is the imported module or path.
+var importPatterns = map[string][]*regexp.Regexp{
+	".go": {
+		regexp.MustCompile(`(?m)^\s*import\s+(?:[\w.]+\s+)?"([^"]+)"`),
+	},
+	".js":  jsImportPatterns,
+	".jsx": jsImportPatterns,
+	".mjs": jsImportPatterns,
+	".ts":  jsImportPatterns,
+	".tsx": jsImportPatterns,
+	".py": {
+		regexp.MustCompile(`(?m)^\s*from\s+(\.*[\w.]*)\s+import\b`),
+		regexp.MustCompile(`(?m)^\s*import\s+([\w.]+)`),
+	},
+	".cs": {
+		regexp.MustCompile(`(?m)^\s*using\s+(?:static\s+)?([\w.]+)\s*;`),
+	},
+	".java": {
+		regexp.MustCompile(`(?m)^\s*import\s+(?:static\s+)?([\w.*]+)\s*;`),
+	},
+	".kt": {
+		regexp.MustCompile(`(?m)^\s*import\s+([\w.*]+)`),
+	},
+	".c":   cIncludePatterns,
+	".cpp": cIncludePatterns,
+	".h":   cIncludePatterns,
+	".hpp": cIncludePatterns,
+}
+
+var jsImportPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bfrom\s+['"]([^'"]+)['"]`),
+	regexp.MustCompile(`\bimport\s+['"]([^'"]+)['"]`),
+	regexp.MustCompile(`\brequire\(\s*['"]([^'"]+)['"]\s*\)`),
+}
+
+var cIncludePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^\s*#\s*include\s+"([^"]+)"`),
+}
+
+// goImportBlock matches a parenthesized Go import declaration, and goImportSpec an import in it.
+var (
+	goImportBlock = regexp.MustCompile(`(?s)\bimport\s*\((.*?)\)`)
+	goImportSpec  = regexp.MustCompile(`(?m)^\s*(?:[\w.]+\s+)?"([^"]+)"`)
+)
+
+// fileImports returns the modules imported by a source file, in order of first appearance.
+func fileImports(filePath, content string) []string {
+	imports := []string{}
+	seen := map[string]bool{}
+	add := func(matches [][]string) {
+		for _, match := range matches {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				imports = append(imports, match[1])
+			}
+		}
+	}
+
+	ext := strings.ToLower(path.Ext(filePath))
+	if ext == ".go" {
+		for _, block := range goImportBlock.FindAllStringSubmatch(content, -1) {
+			add(goImportSpec.FindAllStringSubmatch(block[1], -1))
+		}
+	}
+	for _, pattern := range importPatterns[ext] {
+		add(pattern.FindAllStringSubmatch(content, -1))
+	}
+	return imports
+}
+
+// importCandidate returns the repository path a relative import most likely refers to, without
+// an extension, or an empty string for package imports that cannot be resolved to a path.
+func importCandidate(filePath, module string) string {
+	dir := path.Dir(filePath)
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".js", ".jsx", ".mjs", ".ts", ".tsx":
+		if strings.HasPrefix(module, ".") {
+			return path.Join(dir, module)
+		}
+	case ".c", ".cpp", ".h", ".hpp":
+		// Quoted includes are looked up next to the including file first.
+		return path.Join(dir, module)
+	case ".py":
+		if strings.HasPrefix(module, ".") {
+			trimmed := strings.TrimLeft(module, ".")
+			for i := 1; i < len(module)-len(trimmed); i++ {
+				dir = path.Dir(dir)
+			}
+			return path.Join(dir, strings.ReplaceAll(trimmed, ".", "/"))
+		}
+	}
+	return ""
+}
+
+// testCounterparts returns the file names a test of fileName would have, or, for a test file, the
+// names of the file it tests.
+func testCounterparts(fileName string) []string {
+	ext := path.Ext(fileName)
+	stem := strings.TrimSuffix(fileName, ext)
+	switch strings.ToLower(ext) {
+	case ".go":
+		if strings.HasSuffix(stem, "_test") {
+			return []string{strings.TrimSuffix(stem, "_test") + ext}
+		}
+		return []string{stem + "_test" + ext}
+	case ".js", ".jsx", ".mjs", ".ts", ".tsx":
+		for _, suffix := range []string{".test", ".spec"} {
+			if strings.HasSuffix(stem, suffix) {
+				return []string{strings.TrimSuffix(stem, suffix) + ext}
+			}
+		}
+		return []string{stem + ".test" + ext, stem + ".spec" + ext}
+	case ".py":
+		if strings.HasPrefix(stem, "test_") {
+			return []string{strings.TrimPrefix(stem, "test_") + ext}
+		}
+		if strings.HasSuffix(stem, "_test") {
+			return []string{strings.TrimSuffix(stem, "_test") + ext}
+		}
+		return []string{"test_" + stem + ext, stem + "_test" + ext}
+	case ".cs", ".java", ".kt":
+		for _, suffix := range []string{"Tests", "Test"} {
+			if strings.HasSuffix(stem, suffix) {
+				return []string{strings.TrimSuffix(stem, suffix) + ext}
+			}
+		}
+		return []string{stem + "Tests" + ext, stem + "Test" + ext}
+	}
+	return nil
+}
+
+// listFolder returns the paths of the files directly in a folder of a repository.
+func (c *AzureDevOpsClient) listFolder(ctx context.Context, repoID, folder, ref string) ([]string, error) {
+	items, err := c.itemsAt(ctx, c.config.AzureDevOps.Project, repoID, folder, ref, git.VersionControlRecursionTypeValues.OneLevel)
+	if err != nil {
+		log.Printf("Error listing folder: %v", err)
+		return nil, fmt.Errorf("error listing %s: %w", folder, err)
+	}
+
+	files := []string{}
+	for _, item := range items {
+		if item.IsFolder != nil && *item.IsFolder {
+			continue
+		}
+		files = append(files, stringValue(item.Path))
+	}
+	return files, nil
+}
+
+// searchPaths returns the paths of files in a repository matching a code search query.
+func (c *AzureDevOpsClient) searchPaths(ctx context.Context, query, repoName string, top int) ([]string, error) {
+	filters := map[string][]string{
+		"Project":    {c.config.AzureDevOps.Project},
+		"Repository": {repoName},
+	}
+	response, err := c.searchClient.FetchCodeSearchResults(ctx, search.FetchCodeSearchResultsArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Request: &search.CodeSearchRequest{
+			SearchText: &query,
+			Filters:    &filters,
+			Top:        &top,
+		},
+	})
+	if err != nil {
+		log.Printf("Error searching code: %v", err)
+		return nil, fmt.Errorf("error searching code: %w", err)
+	}
+
+	paths := []string{}
+	if response != nil && response.Results != nil {
+		for _, result := range *response.Results {
+			if result.Path != nil {
+				paths = append(paths, *result.Path)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// relatedFiles returns files likely related to a file: the others in its folder, the modules it
+// imports, its test counterpart, and files that mention it. Each part is gathered independently
+// and a failing part is reported under errors.
+func (c *AzureDevOpsClient) relatedFiles(ctx context.Context, repoName, filePath, ref string) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+	if !strings.HasPrefix(filePath, "/") {
+		filePath = "/" + filePath
+	}
+
+	file, err := c.fileAt(ctx, c.config.AzureDevOps.Project, repoID, filePath, ref)
+	if err != nil {
+		log.Printf("Error getting file content: %v", err)
+		return nil, fmt.Errorf("error getting %s: %w", filePath, err)
+	}
+
+	result := map[string]interface{}{
+		"repository": stringValue(repo.Name),
+		"path":       filePath,
+	}
+	errors := map[string]string{}
+
+	// Folder listings are shared between siblings, import resolution, and test lookup.
+	folders := map[string][]string{}
+	listFolder := func(folder string) ([]string, error) {
+		if files, ok := folders[folder]; ok {
+			return files, nil
+		}
+		files, err := c.listFolder(ctx, repoID, folder, ref)
+		if err != nil {
+			return nil, err
+		}
+		folders[folder] = files
+		return files, nil
+	}
+
+	dir := path.Dir(filePath)
+	siblings := []string{}
+	files, err := listFolder(dir)
+	if err != nil {
+		errors["siblings"] = err.Error()
+	} else {
+		for _, file := range files {
+			if file != filePath {
+				siblings = append(siblings, file)
+			}
+		}
+		result["siblings"] = siblings
+	}
+
+	imports := []map[string]interface{}{}
+	for _, module := range fileImports(filePath, file.Content) {
+		entry := map[string]interface{}{
+			"import": module,
+		}
+		if candidate := importCandidate(filePath, module); candidate != "" {
+			// A missing folder only means the import is not a file of this repository.
+			if candidateFiles, err := listFolder(path.Dir(candidate)); err == nil {
+				for _, file := range candidateFiles {
+					if file == candidate || strings.TrimSuffix(file, path.Ext(file)) == candidate {
+						entry["path"] = file
+						break
+					}
+				}
+			}
+		}
+		imports = append(imports, entry)
+	}
+	result["imports"] = imports
+
+	tests := []string{}
+	counterparts := testCounterparts(path.Base(filePath))
+	for _, sibling := range siblings {
+		for _, name := range counterparts {
+			if path.Base(sibling) == name {
+				tests = append(tests, sibling)
+			}
+		}
+	}
+	if len(tests) == 0 && len(counterparts) > 0 {
+		queries := []string{}
+		for _, name := range counterparts {
+			queries = append(queries, "file:"+name)
+		}
+		found, err := c.searchPaths(ctx, strings.Join(queries, " OR "), repoName, maxRelatedReferences)
+		if err != nil {
+			errors["testCounterparts"] = err.Error()
+		} else {
+			tests = append(tests, found...)
+		}
+	}
+	result["testCounterparts"] = tests
+
+	stem := strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+	references, err := c.searchPaths(ctx, stem, repoName, maxRelatedReferences+1)
+	if err != nil {
+		errors["referencedBy"] = err.Error()
+	} else {
+		referencedBy := []string{}
+		for _, reference := range references {
+			if reference != filePath && len(referencedBy) < maxRelatedReferences {
+				referencedBy = append(referencedBy, reference)
+			}
+		}
+		result["referencedBy"] = referencedBy
+	}
+
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addRelatedTools(s tools.Server, client *AzureDevOpsClient) {
+	relatedTool := mcp.NewTool("get_related_files",
+		mcp.WithDescription("Find files likely related to a file, to build review context: other files in its folder, the modules it imports (resolved to paths where possible), its test counterpart, and files that mention it"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("File path"),
+		),
+		mcp.WithString("ref",
+			mcp.Description("Branch name, refs/tags/<tag>, or commit SHA; defaults to the default branch. Code search always uses the indexed default branch"),
+		),
+	)
+
+	s.AddTool(relatedTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		filePath, ok := request.Params.Arguments["path"].(string)
+		if !ok {
+			log.Print("Path must be a string")
+			return nil, fmt.Errorf("path must be a string")
+		}
+		ref, _ := request.Params.Arguments["ref"].(string)
+
+		result, err := client.relatedFiles(ctx, repo, filePath, ref)
+		if err != nil {
+			log.Printf("Error finding related files: %v", err)
+			return nil, fmt.Errorf("error finding related files: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
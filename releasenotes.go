@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	// pullRequestQueryBatchSize caps the commits looked up in one pull request query.
+	pullRequestQueryBatchSize = 100
+	// maxReleasePullRequests caps the pull requests whose linked work items are read.
+	maxReleasePullRequests = 100
+)
+
+var (
+	// mergedPullRequest matches the default subject of a pull request merge commit.
+	mergedPullRequest = regexp.MustCompile(`^Merged PR (\d+): `)
+	// breakingChangeFooter matches a conventional commit breaking change footer.
+	breakingChangeFooter = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:`)
+)
+
+// releaseNoteSections maps conventional commit types and work item types to release note
+// sections. Anything else is listed under other.
+var releaseNoteSections = map[string]string{
+	"feat":                 "features",
+	"feature":              "features",
+	"fix":                  "fixes",
+	"bugfix":               "fixes",
+	"hotfix":               "fixes",
+	"User Story":           "features",
+	"Feature":              "features",
+	"Product Backlog Item": "features",
+	"Requirement":          "features",
+	"Bug":                  "fixes",
+	"Issue":                "fixes",
+}
+
+// releaseNoteEntry is one change of a release: a pull request, or a commit pushed directly.
+type releaseNoteEntry struct {
+	title         string
+	pullRequestID int
+	commits       []string
+	authors       map[string]bool
+	workItems     map[int]bool
+	breaking      bool
+}
+
+// resolveReleaseRef resolves a commit SHA, a full ref, or a short name that is tried as a tag and
+// then as a branch.
+func (c *AzureDevOpsClient) resolveReleaseRef(ctx context.Context, repoID, ref string) (string, error) {
+	if commitSHA.MatchString(ref) || strings.HasPrefix(ref, "refs/") {
+		return c.resolveCommit(ctx, repoID, ref)
+	}
+	if commitID, err := c.resolveTag(ctx, repoID, "refs/tags/"+ref); err == nil {
+		return commitID, nil
+	}
+	return c.resolveCommit(ctx, repoID, ref)
+}
+
+// commitPullRequests returns the pull request that merged each of the given commits.
+func (c *AzureDevOpsClient) commitPullRequests(ctx context.Context, repoID string, commitIDs []string) (map[string]git.GitPullRequest, error) {
+	queryType := git.GitPullRequestQueryTypeValues.Commit
+	pullRequests := map[string]git.GitPullRequest{}
+	for start := 0; start < len(commitIDs); start += pullRequestQueryBatchSize {
+		end := start + pullRequestQueryBatchSize
+		if end > len(commitIDs) {
+			end = len(commitIDs)
+		}
+		chunk := commitIDs[start:end]
+
+		query, err := c.gitClient.GetPullRequestQuery(ctx, git.GetPullRequestQueryArgs{
+			Queries: &git.GitPullRequestQuery{
+				Queries: &[]git.GitPullRequestQueryInput{{Items: &chunk, Type: &queryType}},
+			},
+			RepositoryId: &repoID,
+			Project:      &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error querying pull requests: %v", err)
+			return nil, fmt.Errorf("error finding pull requests of commits: %w", err)
+		}
+		if query.Results == nil {
+			continue
+		}
+		for _, results := range *query.Results {
+			for commitID, prs := range results {
+				if len(prs) > 0 {
+					pullRequests[strings.ToLower(commitID)] = prs[0]
+				}
+			}
+		}
+	}
+	return pullRequests, nil
+}
+
+// releaseNoteSection picks the section of an entry from its conventional commit type, falling back
+// to the types of its work items.
+func releaseNoteSection(entry *releaseNoteEntry, workItemTypes map[int]string) string {
+	match := conventionalCommit.FindStringSubmatch(entry.title)
+	if entry.breaking || (match != nil && strings.HasSuffix(match[0], "!:")) {
+		return "breakingChanges"
+	}
+	if match != nil {
+		if section, ok := releaseNoteSections[strings.ToLower(match[1])]; ok {
+			return section
+		}
+	}
+	for _, id := range sortedIDs(entry.workItems) {
+		if section, ok := releaseNoteSections[workItemTypes[id]]; ok {
+			return section
+		}
+	}
+	return "other"
+}
+
+// releaseNotes collects the changes between two refs, grouped into breaking changes, features,
+// fixes, and other changes. Commits merged by a pull request are grouped under it.
+func (c *AzureDevOpsClient) releaseNotes(ctx context.Context, repoName, fromRef, toRef string) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+	if toRef == "" {
+		if repo.DefaultBranch == nil {
+			return nil, fmt.Errorf("repository %s has no default branch; pass to", repoName)
+		}
+		toRef = *repo.DefaultBranch
+	}
+
+	fromCommit, err := c.resolveReleaseRef(ctx, repoID, fromRef)
+	if err != nil {
+		return nil, err
+	}
+	toCommit, err := c.resolveReleaseRef(ctx, repoID, toRef)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := c.commitsBetween(ctx, repoID, fromCommit, toCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"repository":  stringValue(repo.Name),
+		"from":        fromRef,
+		"to":          toRef,
+		"fromCommit":  fromCommit,
+		"toCommit":    toCommit,
+		"commitCount": len(commits),
+	}
+	errors := map[string]string{}
+
+	commitIDs := []string{}
+	for _, commit := range commits {
+		commitIDs = append(commitIDs, stringValue(commit.CommitId))
+	}
+	pullRequests, err := c.commitPullRequests(ctx, repoID, commitIDs)
+	if err != nil {
+		errors["pullRequests"] = err.Error()
+	}
+
+	// Commits are newest first, so entries are too.
+	entries := []*releaseNoteEntry{}
+	byPullRequest := map[int]*releaseNoteEntry{}
+	for _, commit := range commits {
+		commitID := stringValue(commit.CommitId)
+		comment := stringValue(commit.Comment)
+		subject := commitSubject(comment)
+
+		prID := 0
+		title := subject
+		if pr, ok := pullRequests[strings.ToLower(commitID)]; ok && pr.PullRequestId != nil {
+			prID = *pr.PullRequestId
+			title = stringValue(pr.Title)
+		} else if match := mergedPullRequest.FindStringSubmatch(subject); match != nil {
+			prID, _ = strconv.Atoi(match[1])
+			title = strings.TrimPrefix(subject, match[0])
+		}
+
+		entry := byPullRequest[prID]
+		if entry == nil {
+			entry = &releaseNoteEntry{
+				title:         title,
+				pullRequestID: prID,
+				authors:       map[string]bool{},
+				workItems:     map[int]bool{},
+			}
+			entries = append(entries, entry)
+			if prID != 0 {
+				byPullRequest[prID] = entry
+			}
+		}
+		entry.commits = append(entry.commits, commitID)
+		if commit.Author != nil && commit.Author.Name != nil {
+			entry.authors[*commit.Author.Name] = true
+		}
+		for _, id := range commitWorkItemIDs(commit) {
+			entry.workItems[id] = true
+		}
+		if match := conventionalCommit.FindString(subject); strings.HasSuffix(match, "!:") || breakingChangeFooter.MatchString(comment) {
+			entry.breaking = true
+		}
+	}
+
+	prIDs := []int{}
+	for id := range byPullRequest {
+		prIDs = append(prIDs, id)
+	}
+	sort.Ints(prIDs)
+	if len(prIDs) > maxReleasePullRequests {
+		errors["pullRequestWorkItems"] = fmt.Sprintf("work items were read for the first %d of %d pull requests", maxReleasePullRequests, len(prIDs))
+		prIDs = prIDs[:maxReleasePullRequests]
+	}
+	for _, id := range prIDs {
+		refs, err := c.gitClient.GetPullRequestWorkItemRefs(ctx, git.GetPullRequestWorkItemRefsArgs{
+			RepositoryId:  &repoID,
+			PullRequestId: &id,
+			Project:       &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error getting pull request work items: %v", err)
+			errors[fmt.Sprintf("pullRequest %d", id)] = err.Error()
+			continue
+		}
+		for _, ref := range *refs {
+			if workItemID, err := strconv.Atoi(stringValue(ref.Id)); err == nil {
+				byPullRequest[id].workItems[workItemID] = true
+			}
+		}
+	}
+
+	allWorkItems := map[int]bool{}
+	for _, entry := range entries {
+		for id := range entry.workItems {
+			allWorkItems[id] = true
+		}
+	}
+	workItemTypes := map[int]string{}
+	workItems, err := c.workItemSummaries(ctx, sortedIDs(allWorkItems))
+	if err != nil {
+		errors["workItems"] = err.Error()
+	} else {
+		for _, item := range workItems {
+			id, _ := item["id"].(int)
+			workItemTypes[id], _ = item["type"].(string)
+		}
+		result["workItems"] = workItems
+	}
+
+	sections := map[string][]map[string]interface{}{
+		"breakingChanges": {},
+		"features":        {},
+		"fixes":           {},
+		"other":           {},
+	}
+	for _, entry := range entries {
+		authors := []string{}
+		for author := range entry.authors {
+			authors = append(authors, author)
+		}
+		sort.Strings(authors)
+		// Only work items that exist are listed; #12 mentions can refer to something else.
+		linked := []int{}
+		for _, id := range sortedIDs(entry.workItems) {
+			if _, ok := workItemTypes[id]; ok {
+				linked = append(linked, id)
+			}
+		}
+
+		item := map[string]interface{}{
+			"title":     entry.title,
+			"commits":   entry.commits,
+			"authors":   authors,
+			"workItems": linked,
+		}
+		if entry.pullRequestID != 0 {
+			item["pullRequestId"] = entry.pullRequestID
+			item["webUrl"] = c.links.pullRequest(c.config.AzureDevOps.Project, stringValue(repo.Name), entry.pullRequestID)
+			item["uri"] = c.idURI(pullRequestEntity, c.config.AzureDevOps.Project, entry.pullRequestID)
+		}
+		section := releaseNoteSection(entry, workItemTypes)
+		sections[section] = append(sections[section], item)
+	}
+	for name, items := range sections {
+		result[name] = items
+	}
+
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addReleaseNoteTools(s tools.Server, client *AzureDevOpsClient) {
+	releaseNotesTool := mcp.NewTool("get_release_notes",
+		mcp.WithDescription("Collect release notes data for the changes between two tags, branches, or commits: pull requests and directly pushed commits with their authors and linked work items, grouped into breaking changes, features, fixes, and other changes by conventional commit type or work item type"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("from",
+			mcp.Required(),
+			mcp.Description("Previous release: a tag such as v1.2.0, a branch, or a commit SHA"),
+		),
+		mcp.WithString("to",
+			mcp.Description("New release: a tag, branch, or commit SHA; defaults to the repository's default branch"),
+		),
+	)
+
+	s.AddTool(releaseNotesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		from, ok := request.Params.Arguments["from"].(string)
+		if !ok {
+			log.Print("From must be a string")
+			return nil, fmt.Errorf("from must be a string")
+		}
+		to, _ := request.Params.Arguments["to"].(string)
+
+		result, err := client.releaseNotes(ctx, repo, from, to)
+		if err != nil {
+			log.Printf("Error getting release notes: %v", err)
+			return nil, fmt.Errorf("error getting release notes: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/config"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultIndexRefreshMinutes = 60
+	indexedRecentCommits       = 20
+	indexedLargestFiles        = 10
+	defaultIndexedFiles        = 200
+	maxIndexedFiles            = 5000
+)
+
+// indexedFile is a file of an indexed repository.
+type indexedFile struct {
+	Path string
+	Size uint64
+}
+
+// repositorySnapshot is the indexed state of a repository at the head of its default branch.
+type repositorySnapshot struct {
+	Repository    string
+	DefaultBranch string
+	CommitID      string
+	IndexedAt     time.Time
+	Files         []indexedFile
+	RecentCommits []map[string]interface{}
+}
+
+// repositoryIndex keeps snapshots of the configured repositories and refreshes them on a schedule,
+// so file listings and statistics are answered without calling Azure DevOps.
+type repositoryIndex struct {
+	client *AzureDevOpsClient
+	config config.RepositoryIndexConfig
+
+	mu        sync.RWMutex
+	snapshots map[string]*repositorySnapshot
+	errors    map[string]string
+}
+
+func newRepositoryIndex(client *AzureDevOpsClient) *repositoryIndex {
+	config := client.config.RepositoryIndex
+	if config.RefreshMinutes <= 0 {
+		config.RefreshMinutes = defaultIndexRefreshMinutes
+	}
+	return &repositoryIndex{
+		client:    client,
+		config:    config,
+		snapshots: map[string]*repositorySnapshot{},
+		errors:    map[string]string{},
+	}
+}
+
+// run refreshes the index immediately and then every refresh interval until ctx is done.
+func (idx *repositoryIndex) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(idx.config.RefreshMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		idx.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh re-indexes every configured repository whose default branch moved.
+func (idx *repositoryIndex) refresh(ctx context.Context) {
+	for _, repoName := range idx.config.Repositories {
+		err := idx.indexRepository(ctx, repoName)
+		idx.mu.Lock()
+		if err != nil {
+			log.Printf("Error indexing %s: %v", repoName, err)
+			idx.errors[strings.ToLower(repoName)] = err.Error()
+		} else {
+			delete(idx.errors, strings.ToLower(repoName))
+		}
+		idx.mu.Unlock()
+	}
+}
+
+func (idx *repositoryIndex) indexRepository(ctx context.Context, repoName string) error {
+	c := idx.client
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return err
+	}
+	if repo.DefaultBranch == nil {
+		return fmt.Errorf("repository %s has no default branch", repoName)
+	}
+	repoID := repo.Id.String()
+	key := strings.ToLower(repoName)
+
+	commitID, err := c.resolveCommit(ctx, repoID, *repo.DefaultBranch)
+	if err != nil {
+		return err
+	}
+	idx.mu.RLock()
+	current := idx.snapshots[key]
+	idx.mu.RUnlock()
+	if current != nil && current.CommitID == commitID {
+		return nil
+	}
+
+	files, err := c.listTreeFiles(ctx, repoID, commitID)
+	if err != nil {
+		return err
+	}
+
+	top := indexedRecentCommits
+	commits, err := c.gitClient.GetCommits(ctx, git.GetCommitsArgs{
+		RepositoryId: &repoID,
+		Project:      &c.config.AzureDevOps.Project,
+		SearchCriteria: &git.GitQueryCommitsCriteria{
+			Top:         &top,
+			ItemVersion: versionDescriptor(commitID),
+		},
+	})
+	if err != nil {
+		log.Printf("Error getting commits: %v", err)
+		return fmt.Errorf("error getting commits of %s: %w", repoName, err)
+	}
+	recent := []map[string]interface{}{}
+	for _, commit := range *commits {
+		entry := map[string]interface{}{
+			"commitId": stringValue(commit.CommitId),
+			"comment":  stringValue(commit.Comment),
+		}
+		if commit.Author != nil {
+			entry["author"] = stringValue(commit.Author.Name)
+			if commit.Author.Date != nil {
+				entry["date"] = commit.Author.Date.Time.Format(time.RFC3339)
+			}
+		}
+		recent = append(recent, entry)
+	}
+
+	idx.mu.Lock()
+	idx.snapshots[key] = &repositorySnapshot{
+		Repository:    stringValue(repo.Name),
+		DefaultBranch: *repo.DefaultBranch,
+		CommitID:      commitID,
+		IndexedAt:     time.Now(),
+		Files:         files,
+		RecentCommits: recent,
+	}
+	idx.mu.Unlock()
+	log.Printf("Indexed %d files of %s at %s", len(files), repoName, commitID)
+	return nil
+}
+
+// listTreeFiles returns every file of a repository at a commit with its size, sorted by path.
+func (c *AzureDevOpsClient) listTreeFiles(ctx context.Context, repoID, commitID string) ([]indexedFile, error) {
+	commit, err := c.gitClient.GetCommit(ctx, git.GetCommitArgs{
+		CommitId:     &commitID,
+		RepositoryId: &repoID,
+		Project:      &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting commit: %v", err)
+		return nil, fmt.Errorf("error getting commit %s: %w", commitID, err)
+	}
+	if commit.TreeId == nil {
+		return nil, fmt.Errorf("commit %s has no tree", commitID)
+	}
+
+	recursive := true
+	tree, err := c.gitClient.GetTree(ctx, git.GetTreeArgs{
+		RepositoryId: &repoID,
+		Sha1:         commit.TreeId,
+		Project:      &c.config.AzureDevOps.Project,
+		Recursive:    &recursive,
+	})
+	if err != nil {
+		log.Printf("Error getting tree: %v", err)
+		return nil, fmt.Errorf("error getting tree of commit %s: %w", commitID, err)
+	}
+
+	files := []indexedFile{}
+	if tree.TreeEntries != nil {
+		for _, entry := range *tree.TreeEntries {
+			if entry.GitObjectType == nil || *entry.GitObjectType != git.GitObjectTypeValues.Blob {
+				continue
+			}
+			file := indexedFile{Path: "/" + stringValue(entry.RelativePath)}
+			if entry.Size != nil {
+				file.Size = *entry.Size
+			}
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files, nil
+}
+
+// snapshot returns the indexed state of a repository. Snapshots are replaced, never modified, so
+// the result can be read without holding the lock.
+func (idx *repositoryIndex) snapshot(ctx context.Context, repoName string) (*repositorySnapshot, error) {
+	canRead, err := idx.client.readableRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !canRead(repoName) {
+		return nil, fmt.Errorf("repository not found: %s", repoName)
+	}
+	key := strings.ToLower(repoName)
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if snapshot, ok := idx.snapshots[key]; ok {
+		return snapshot, nil
+	}
+	if message, ok := idx.errors[key]; ok {
+		return nil, fmt.Errorf("repository %s could not be indexed: %s", repoName, message)
+	}
+	for _, configured := range idx.config.Repositories {
+		if strings.EqualFold(configured, repoName) {
+			return nil, fmt.Errorf("repository %s is still being indexed", repoName)
+		}
+	}
+	return nil, fmt.Errorf("repository %s is not indexed; indexed repositories are %s", repoName, strings.Join(idx.config.Repositories, ", "))
+}
+
+// listFiles returns the indexed files of a repository under a folder whose path or name matches
+// an optional glob pattern, leaving out ignored ones.
+func (idx *repositoryIndex) listFiles(ctx context.Context, repoName, folder, pattern string, ignore pathPatterns, top int) (map[string]interface{}, error) {
+	snapshot, err := idx.snapshot(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	if folder != "" && !strings.HasPrefix(folder, "/") {
+		folder = "/" + folder
+	}
+	folder = strings.TrimSuffix(folder, "/")
+
+	files := []map[string]interface{}{}
+	matched := 0
+	for _, file := range snapshot.Files {
+		if folder != "" && !strings.HasPrefix(file.Path, folder+"/") || ignore.match(file.Path) {
+			continue
+		}
+		if pattern != "" {
+			nameMatch, err := path.Match(pattern, path.Base(file.Path))
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+			}
+			pathMatch, _ := path.Match(pattern, file.Path)
+			if !nameMatch && !pathMatch {
+				continue
+			}
+		}
+		matched++
+		if len(files) < top {
+			files = append(files, map[string]interface{}{
+				"path": file.Path,
+				"size": file.Size,
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"repository": snapshot.Repository,
+		"commitId":   snapshot.CommitID,
+		"indexedAt":  snapshot.IndexedAt.Format(time.RFC3339),
+		"files":      files,
+		"matched":    matched,
+	}, nil
+}
+
+// stats summarizes an indexed repository: file counts and sizes by language, the largest files,
+// and the latest commits of its default branch.
+func (idx *repositoryIndex) stats(ctx context.Context, repoName string) (map[string]interface{}, error) {
+	snapshot, err := idx.snapshot(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	type languageStats struct {
+		files int
+		bytes uint64
+	}
+	languages := map[string]*languageStats{}
+	var totalBytes uint64
+	for _, file := range snapshot.Files {
+		totalBytes += file.Size
+		language := fileLanguage(file.Path)
+		if language == "" {
+			language = "Other"
+		}
+		if languages[language] == nil {
+			languages[language] = &languageStats{}
+		}
+		languages[language].files++
+		languages[language].bytes += file.Size
+	}
+
+	languageList := []map[string]interface{}{}
+	for name, stats := range languages {
+		languageList = append(languageList, map[string]interface{}{
+			"language": name,
+			"files":    stats.files,
+			"bytes":    stats.bytes,
+		})
+	}
+	sort.Slice(languageList, func(i, j int) bool {
+		return languageList[i]["bytes"].(uint64) > languageList[j]["bytes"].(uint64)
+	})
+
+	largest := make([]indexedFile, len(snapshot.Files))
+	copy(largest, snapshot.Files)
+	sort.Slice(largest, func(i, j int) bool {
+		return largest[i].Size > largest[j].Size
+	})
+	if len(largest) > indexedLargestFiles {
+		largest = largest[:indexedLargestFiles]
+	}
+	largestFiles := []map[string]interface{}{}
+	for _, file := range largest {
+		largestFiles = append(largestFiles, map[string]interface{}{
+			"path": file.Path,
+			"size": file.Size,
+		})
+	}
+
+	return map[string]interface{}{
+		"repository":    snapshot.Repository,
+		"defaultBranch": snapshot.DefaultBranch,
+		"commitId":      snapshot.CommitID,
+		"indexedAt":     snapshot.IndexedAt.Format(time.RFC3339),
+		"fileCount":     len(snapshot.Files),
+		"totalBytes":    totalBytes,
+		"languages":     languageList,
+		"largestFiles":  largestFiles,
+		"recentCommits": snapshot.RecentCommits,
+	}, nil
+}
+
+// readableRepositories returns whether the caller of a tool call may read a repository of the
+// project. The background indexes are built with the service credential, so under session auth
+// their answers are limited to the repositories the caller's own credential can read.
+func (c *AzureDevOpsClient) readableRepositories(ctx context.Context) (func(repoName string) bool, error) {
+	if c.config.Server.SessionAuth == "" || c.config.Server.SessionAuth == config.SessionAuthOff {
+		return func(string) bool { return true }, nil
+	}
+	repos, err := c.gitClient.GetRepositories(ctx, git.GetRepositoriesArgs{
+		Project: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting repositories: %v", err)
+		return nil, fmt.Errorf("error checking repository access: %w", err)
+	}
+	readable := map[string]bool{}
+	for _, repo := range *repos {
+		readable[strings.ToLower(stringValue(repo.Name))] = true
+	}
+	return func(repoName string) bool {
+		return readable[strings.ToLower(repoName)]
+	}, nil
+}
+
+func addRepositoryIndexTools(s tools.Server, index *repositoryIndex) {
+	listFilesTool := mcp.NewTool("list_indexed_files",
+		mcp.WithDescription(fmt.Sprintf("List files of an indexed repository (%s) instantly from the local index, optionally under a folder or matching a glob pattern, e.g. *.csproj. The index follows the default branch and refreshes every %d minutes", strings.Join(index.config.Repositories, ", "), index.config.RefreshMinutes)),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Indexed repository name"),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Only files under this folder, e.g. /src"),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("Glob matched against the file name or full path, e.g. *_test.go or /src/*/Program.cs"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description(fmt.Sprintf("Maximum number of files, at most %d", maxIndexedFiles)),
+			mcp.DefaultNumber(defaultIndexedFiles),
+		),
+		mcp.WithArray("ignore",
+			mcp.Description(ignoreDescription),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+
+	s.AddTool(listFilesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		folder, _ := request.Params.Arguments["folder"].(string)
+		pattern, _ := request.Params.Arguments["pattern"].(string)
+		top := defaultIndexedFiles
+		if value, ok := request.Params.Arguments["top"].(float64); ok && value > 0 {
+			top = int(value)
+		}
+		if top > maxIndexedFiles {
+			top = maxIndexedFiles
+		}
+		extra, err := tools.StringSliceArgument(request.Params.Arguments, "ignore")
+		if err != nil {
+			log.Printf("Invalid ignore: %v", err)
+			return nil, err
+		}
+		ignore, err := index.client.ignorePatterns(extra)
+		if err != nil {
+			log.Printf("Invalid ignore: %v", err)
+			return nil, err
+		}
+
+		result, err := index.listFiles(ctx, repo, folder, pattern, ignore, top)
+		if err != nil {
+			log.Printf("Error listing indexed files: %v", err)
+			return nil, fmt.Errorf("error listing indexed files: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	statsTool := mcp.NewTool("get_repository_stats",
+		mcp.WithDescription("Summarize an indexed repository from the local index: file count, size and files per language, largest files, and latest commits of the default branch"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Indexed repository name"),
+		),
+	)
+
+	s.AddTool(statsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+
+		result, err := index.stats(ctx, repo)
+		if err != nil {
+			log.Printf("Error getting repository stats: %v", err)
+			return nil, fmt.Errorf("error getting repository stats: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,674 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+func repositorySummary(repo git.GitRepository) map[string]interface{} {
+	entry := map[string]interface{}{
+		"name":          stringValue(repo.Name),
+		"defaultBranch": stringValue(repo.DefaultBranch),
+		"remoteUrl":     stringValue(repo.RemoteUrl),
+		"sshUrl":        stringValue(repo.SshUrl),
+		"webUrl":        stringValue(repo.WebUrl),
+		"isFork":        repo.IsFork != nil && *repo.IsFork,
+	}
+	if repo.Id != nil {
+		entry["id"] = repo.Id.String()
+	}
+	if repo.Size != nil {
+		entry["size"] = *repo.Size
+	}
+	if repo.Project != nil {
+		entry["project"] = stringValue(repo.Project.Name)
+	}
+	if repo.ParentRepository != nil {
+		parent := map[string]interface{}{
+			"name": stringValue(repo.ParentRepository.Name),
+		}
+		if repo.ParentRepository.Project != nil {
+			parent["project"] = stringValue(repo.ParentRepository.Project.Name)
+		}
+		entry["parentRepository"] = parent
+	}
+	return entry
+}
+
+// getRepositorySettings returns a repository's settings: default branch, size, URLs, fork
+// parent, and whether it is disabled.
+func (c *AzureDevOpsClient) getRepositorySettings(ctx context.Context, repoName string) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	repoID := repo.Id.String()
+	details, err := c.gitClient.GetRepository(ctx, git.GetRepositoryArgs{
+		RepositoryId: &repoID,
+		Project:      &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting repository: %v", err)
+		return nil, fmt.Errorf("error getting repository %s: %w", repoName, err)
+	}
+
+	result := repositorySummary(*details)
+	result["isDisabled"] = details.IsDisabled != nil && *details.IsDisabled
+
+	// Disabled repositories reject branch reads, so the count is best effort.
+	branches, err := c.gitClient.GetBranches(ctx, git.GetBranchesArgs{
+		RepositoryId: &repoID,
+		Project:      &c.config.AzureDevOps.Project,
+	})
+	if err == nil && branches != nil {
+		result["branchCount"] = len(*branches)
+	}
+
+	return result, nil
+}
+
+// createRepository creates an empty repository. A dry run checks that the name is free.
+func (c *AzureDevOpsClient) createRepository(ctx context.Context, name string, dryRun bool) (map[string]interface{}, error) {
+	if dryRun {
+		if _, err := c.findRepository(ctx, name); err == nil {
+			return nil, fmt.Errorf("repository %s already exists", name)
+		}
+		return tools.DryRunResult("create repository", map[string]interface{}{
+			"name":    name,
+			"project": c.config.AzureDevOps.Project,
+		}), nil
+	}
+
+	repo, err := c.gitClient.CreateRepository(ctx, git.CreateRepositoryArgs{
+		GitRepositoryToCreate: &git.GitRepositoryCreateOptions{
+			Name: &name,
+		},
+		Project: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error creating repository: %v", err)
+		return nil, fmt.Errorf("error creating repository %s: %w", name, err)
+	}
+
+	return repositorySummary(*repo), nil
+}
+
+// updateRepository changes a repository's default branch and/or enables or disables it. nil
+// arguments leave the setting unchanged.
+func (c *AzureDevOpsClient) updateRepository(ctx context.Context, repoName string, defaultBranch *string, disabled *bool, dryRun bool) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		current, err := c.getRepositorySettings(ctx, repoName)
+		if err != nil {
+			return nil, err
+		}
+		changes := map[string]interface{}{
+			"repositoryId": repo.Id.String(),
+		}
+		if defaultBranch != nil {
+			ref := branchRef(*defaultBranch)
+			// Branches of a disabled repository cannot be read to check the new default.
+			if current["isDisabled"] == false {
+				if _, err := c.resolveCommit(ctx, repo.Id.String(), ref); err != nil {
+					return nil, err
+				}
+			}
+			changes["defaultBranch"] = map[string]interface{}{
+				"from": current["defaultBranch"],
+				"to":   ref,
+			}
+		}
+		if disabled != nil {
+			changes["isDisabled"] = map[string]interface{}{
+				"from": current["isDisabled"],
+				"to":   *disabled,
+			}
+		}
+		return tools.DryRunResult("update repository", changes), nil
+	}
+
+	setDisabled := func() error {
+		if _, err := c.gitClient.UpdateRepository(ctx, git.UpdateRepositoryArgs{
+			NewRepositoryInfo: &git.GitRepository{IsDisabled: disabled},
+			RepositoryId:      repo.Id,
+			Project:           &c.config.AzureDevOps.Project,
+		}); err != nil {
+			log.Printf("Error updating repository: %v", err)
+			return fmt.Errorf("error setting disabled state of %s: %w", repoName, err)
+		}
+		return nil
+	}
+
+	// A disabled repository rejects other updates, so enabling happens first and disabling last.
+	if disabled != nil && !*disabled {
+		if err := setDisabled(); err != nil {
+			return nil, err
+		}
+	}
+
+	if defaultBranch != nil {
+		ref := branchRef(*defaultBranch)
+		if _, err := c.gitClient.UpdateRepository(ctx, git.UpdateRepositoryArgs{
+			NewRepositoryInfo: &git.GitRepository{DefaultBranch: &ref},
+			RepositoryId:      repo.Id,
+			Project:           &c.config.AzureDevOps.Project,
+		}); err != nil {
+			log.Printf("Error updating repository: %v", err)
+			return nil, fmt.Errorf("error setting default branch of %s: %w", repoName, err)
+		}
+	}
+
+	if disabled != nil && *disabled {
+		if err := setDisabled(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.getRepositorySettings(ctx, repoName)
+}
+
+// collectionID returns the ID of the organization's project collection, which scopes fork queries.
+func (c *AzureDevOpsClient) collectionID(ctx context.Context) (*uuid.UUID, error) {
+	collections, err := c.coreClient.GetProjectCollections(ctx, core.GetProjectCollectionsArgs{})
+	if err != nil {
+		log.Printf("Error getting project collections: %v", err)
+		return nil, fmt.Errorf("error getting project collection: %w", err)
+	}
+	if collections == nil || len(*collections) == 0 || (*collections)[0].Id == nil {
+		return nil, fmt.Errorf("no project collection found")
+	}
+	return (*collections)[0].Id, nil
+}
+
+// listForks returns the forks of a repository across all projects of the organization.
+func (c *AzureDevOpsClient) listForks(ctx context.Context, repoName string) ([]map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	collectionID, err := c.collectionID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	repoID := repo.Id.String()
+	forks, err := c.gitClient.GetForks(ctx, git.GetForksArgs{
+		RepositoryNameOrId: &repoID,
+		CollectionId:       collectionID,
+		Project:            &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting forks: %v", err)
+		return nil, fmt.Errorf("error getting forks of %s: %w", repoName, err)
+	}
+
+	results := []map[string]interface{}{}
+	if forks == nil {
+		return results, nil
+	}
+	for _, fork := range *forks {
+		entry := map[string]interface{}{
+			"name":      stringValue(fork.Name),
+			"remoteUrl": stringValue(fork.RemoteUrl),
+		}
+		if fork.Id != nil {
+			entry["id"] = fork.Id.String()
+		}
+		if fork.Project != nil {
+			entry["project"] = stringValue(fork.Project.Name)
+		}
+		results = append(results, entry)
+	}
+
+	return results, nil
+}
+
+// createFork forks a repository of the configured project into targetProject, which defaults
+// to the configured project. With sourceBranch only that branch is copied.
+func (c *AzureDevOpsClient) createFork(ctx context.Context, repoName, targetProject, name, sourceBranch string, dryRun bool) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	if repo.Project == nil || repo.Project.Id == nil {
+		return nil, fmt.Errorf("repository %s has no project", repoName)
+	}
+	if targetProject == "" {
+		targetProject = c.config.AzureDevOps.Project
+	}
+	if name == "" {
+		name = stringValue(repo.Name)
+	}
+
+	target, err := c.coreClient.GetProject(ctx, core.GetProjectArgs{
+		ProjectId: &targetProject,
+	})
+	if err != nil {
+		log.Printf("Error getting project: %v", err)
+		return nil, fmt.Errorf("error getting project %s: %w", targetProject, err)
+	}
+
+	args := git.CreateRepositoryArgs{
+		GitRepositoryToCreate: &git.GitRepositoryCreateOptions{
+			Name:    &name,
+			Project: &core.TeamProjectReference{Id: target.Id},
+			ParentRepository: &git.GitRepositoryRef{
+				Id:      repo.Id,
+				Project: &core.TeamProjectReference{Id: repo.Project.Id},
+			},
+		},
+		Project: &targetProject,
+	}
+	if sourceBranch != "" {
+		ref := branchRef(sourceBranch)
+		args.SourceRef = &ref
+	}
+	if dryRun {
+		changes := map[string]interface{}{
+			"name":               name,
+			"targetProject":      targetProject,
+			"targetProjectId":    target.Id.String(),
+			"parentRepositoryId": repo.Id.String(),
+		}
+		if args.SourceRef != nil {
+			changes["sourceRef"] = *args.SourceRef
+		}
+		return tools.DryRunResult("create fork", changes), nil
+	}
+
+	fork, err := c.gitClient.CreateRepository(ctx, args)
+	if err != nil {
+		log.Printf("Error creating fork: %v", err)
+		return nil, fmt.Errorf("error forking %s into %s: %w", repoName, targetProject, err)
+	}
+
+	return repositorySummary(*fork), nil
+}
+
+func importRequestSummary(request git.GitImportRequest) map[string]interface{} {
+	entry := map[string]interface{}{}
+	if request.ImportRequestId != nil {
+		entry["importRequestId"] = *request.ImportRequestId
+	}
+	if request.Status != nil {
+		entry["status"] = string(*request.Status)
+	}
+	if request.Repository != nil {
+		entry["repository"] = stringValue(request.Repository.Name)
+	}
+	if request.Parameters != nil && request.Parameters.GitSource != nil {
+		entry["sourceUrl"] = stringValue(request.Parameters.GitSource.Url)
+	}
+	if detail := request.DetailedStatus; detail != nil {
+		if detail.AllSteps != nil && detail.CurrentStep != nil {
+			steps := *detail.AllSteps
+			entry["totalSteps"] = len(steps)
+			entry["currentStep"] = *detail.CurrentStep
+			// currentStep is 1-based
+			if *detail.CurrentStep >= 1 && *detail.CurrentStep <= len(steps) {
+				entry["currentStepName"] = steps[*detail.CurrentStep-1]
+			}
+		}
+		if detail.ErrorMessage != nil {
+			entry["error"] = *detail.ErrorMessage
+		}
+	}
+	return entry
+}
+
+// importRepository imports an external Git repository into repoName, creating the repository
+// when it does not exist yet. Private sources need a Git service connection holding the
+// credentials, passed as serviceEndpointID.
+func (c *AzureDevOpsClient) importRepository(ctx context.Context, repoName, sourceURL, serviceEndpointID string, dryRun bool) (map[string]interface{}, error) {
+	parameters := &git.GitImportRequestParameters{
+		GitSource: &git.GitImportGitSource{Url: &sourceURL},
+	}
+	if serviceEndpointID != "" {
+		endpointID, err := uuid.Parse(serviceEndpointID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service endpoint ID %s: %w", serviceEndpointID, err)
+		}
+		parameters.ServiceEndpointId = &endpointID
+	}
+
+	var repoID string
+	repo, err := c.findRepository(ctx, repoName)
+	if dryRun {
+		changes := map[string]interface{}{
+			"repository":        repoName,
+			"createsRepository": err != nil,
+			"parameters":        parameters,
+		}
+		if err == nil {
+			changes["repositoryId"] = repo.Id.String()
+		}
+		return tools.DryRunResult("import repository", changes), nil
+	}
+	if err == nil {
+		repoID = repo.Id.String()
+	} else {
+		created, err := c.gitClient.CreateRepository(ctx, git.CreateRepositoryArgs{
+			GitRepositoryToCreate: &git.GitRepositoryCreateOptions{Name: &repoName},
+			Project:               &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error creating repository: %v", err)
+			return nil, fmt.Errorf("error creating repository %s: %w", repoName, err)
+		}
+		repoID = created.Id.String()
+	}
+
+	request, err := c.gitClient.CreateImportRequest(ctx, git.CreateImportRequestArgs{
+		ImportRequest: &git.GitImportRequest{Parameters: parameters},
+		Project:       &c.config.AzureDevOps.Project,
+		RepositoryId:  &repoID,
+	})
+	if err != nil {
+		log.Printf("Error creating import request: %v", err)
+		return nil, fmt.Errorf("error importing %s into %s: %w", sourceURL, repoName, err)
+	}
+
+	return importRequestSummary(*request), nil
+}
+
+// getImportStatus reports the progress of an import request, or of the latest import into the
+// repository when importRequestID is 0.
+func (c *AzureDevOpsClient) getImportStatus(ctx context.Context, repoName string, importRequestID int) (map[string]interface{}, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+
+	if importRequestID == 0 {
+		includeAbandoned := true
+		requests, err := c.gitClient.QueryImportRequests(ctx, git.QueryImportRequestsArgs{
+			Project:          &c.config.AzureDevOps.Project,
+			RepositoryId:     &repoID,
+			IncludeAbandoned: &includeAbandoned,
+		})
+		if err != nil {
+			log.Printf("Error querying import requests: %v", err)
+			return nil, fmt.Errorf("error querying imports into %s: %w", repoName, err)
+		}
+		if requests == nil || len(*requests) == 0 {
+			return nil, fmt.Errorf("no import requests found for %s", repoName)
+		}
+		for _, request := range *requests {
+			if request.ImportRequestId != nil && *request.ImportRequestId > importRequestID {
+				importRequestID = *request.ImportRequestId
+			}
+		}
+	}
+
+	request, err := c.gitClient.GetImportRequest(ctx, git.GetImportRequestArgs{
+		Project:         &c.config.AzureDevOps.Project,
+		RepositoryId:    &repoID,
+		ImportRequestId: &importRequestID,
+	})
+	if err != nil {
+		log.Printf("Error getting import request: %v", err)
+		return nil, fmt.Errorf("error getting import request %d: %w", importRequestID, err)
+	}
+
+	return importRequestSummary(*request), nil
+}
+
+func addRepositoryTools(s tools.Server, client *AzureDevOpsClient) {
+	repoSettingsTool := mcp.NewTool("get_repository",
+		mcp.WithDescription("Get a repository's settings: default branch, size, clone URLs, fork parent, branch count, and whether it is disabled"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+	)
+
+	s.AddTool(repoSettingsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+
+		result, err := client.getRepositorySettings(ctx, repo)
+		if err != nil {
+			log.Printf("Error getting repository: %v", err)
+			return nil, fmt.Errorf("error getting repository: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	listForksTool := mcp.NewTool("list_repository_forks",
+		mcp.WithDescription("List the forks of a repository across the organization's projects"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+	)
+
+	s.AddTool(listForksTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+
+		results, err := client.listForks(ctx, repo)
+		if err != nil {
+			log.Printf("Error listing forks: %v", err)
+			return nil, fmt.Errorf("error listing forks: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	importStatusTool := mcp.NewTool("get_repository_import_status",
+		mcp.WithDescription("Report the progress of a repository import: status, current step, and any error"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name the import targets"),
+		),
+		mcp.WithNumber("importRequestId",
+			mcp.Description("Import request ID; defaults to the latest import into the repository"),
+		),
+	)
+
+	s.AddTool(importStatusTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		importRequestID := 0
+		if id, ok := request.Params.Arguments["importRequestId"].(float64); ok {
+			importRequestID = int(id)
+		}
+
+		result, err := client.getImportStatus(ctx, repo, importRequestID)
+		if err != nil {
+			log.Printf("Error getting import status: %v", err)
+			return nil, fmt.Errorf("error getting import status: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	importTool := mcp.NewTool("import_repository",
+		mcp.WithDescription("Import an external Git repository by URL into a new or empty repository of the project. The import runs in the background; follow it with get_repository_import_status"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Target repository name; created if it does not exist"),
+		),
+		mcp.WithString("sourceUrl",
+			mcp.Required(),
+			mcp.Description("Clone URL of the repository to import"),
+		),
+		mcp.WithString("serviceEndpointId",
+			mcp.Description("ID of a Git service connection with credentials, for private sources"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(importTool, writeHandler(client, importTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		sourceURL, ok := request.Params.Arguments["sourceUrl"].(string)
+		if !ok {
+			log.Print("Source URL must be a string")
+			return nil, fmt.Errorf("sourceUrl must be a string")
+		}
+		serviceEndpointID, _ := request.Params.Arguments["serviceEndpointId"].(string)
+
+		result, err := client.importRepository(ctx, repo, sourceURL, serviceEndpointID, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error importing repository: %v", err)
+			return nil, fmt.Errorf("error importing repository: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	createForkTool := mcp.NewTool("create_fork",
+		mcp.WithDescription("Fork a repository, optionally into another project, for inner-source contributions"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name to fork"),
+		),
+		mcp.WithString("targetProject",
+			mcp.Description("Project to create the fork in; defaults to the configured project"),
+		),
+		mcp.WithString("name",
+			mcp.Description("Name of the fork; defaults to the repository name"),
+		),
+		mcp.WithString("sourceBranch",
+			mcp.Description("Only copy this branch; omit to copy all branches"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(createForkTool, writeHandler(client, createForkTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		targetProject, _ := request.Params.Arguments["targetProject"].(string)
+		name, _ := request.Params.Arguments["name"].(string)
+		sourceBranch, _ := request.Params.Arguments["sourceBranch"].(string)
+
+		result, err := client.createFork(ctx, repo, targetProject, name, sourceBranch, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error creating fork: %v", err)
+			return nil, fmt.Errorf("error creating fork: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	createRepoTool := mcp.NewTool("create_repository",
+		mcp.WithDescription("Create an empty Git repository in the project"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(createRepoTool, writeHandler(client, createRepoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name, ok := request.Params.Arguments["name"].(string)
+		if !ok {
+			log.Print("Name must be a string")
+			return nil, fmt.Errorf("name must be a string")
+		}
+
+		result, err := client.createRepository(ctx, name, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error creating repository: %v", err)
+			return nil, fmt.Errorf("error creating repository: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	updateRepoTool := mcp.NewTool("update_repository",
+		mcp.WithDescription("Set a repository's default branch and/or enable or disable it"),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("defaultBranch",
+			mcp.Description("New default branch, e.g. main"),
+		),
+		mcp.WithBoolean("disabled",
+			mcp.Description("true to disable the repository, false to enable it"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(updateRepoTool, writeHandler(client, updateRepoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+
+		var defaultBranch *string
+		if value, ok := request.Params.Arguments["defaultBranch"].(string); ok && value != "" {
+			defaultBranch = &value
+		}
+		var disabled *bool
+		if value, ok := request.Params.Arguments["disabled"].(bool); ok {
+			disabled = &value
+		}
+		if defaultBranch == nil && disabled == nil {
+			return nil, fmt.Errorf("defaultBranch or disabled is required")
+		}
+
+		result, err := client.updateRepository(ctx, repo, defaultBranch, disabled, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error updating repository: %v", err)
+			return nil, fmt.Errorf("error updating repository: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
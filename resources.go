@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// docsFolder is the folder whose files are indexed by a repository's docs resource.
+const docsFolder = "/docs"
+
+// repositoryResourceURI names a resource of a repository, e.g. its readme or docs index. The
+// repository segment is used as given, so it can be a template variable.
+func (c *AzureDevOpsClient) repositoryResourceURI(repoSegment, resource string) string {
+	return fmt.Sprintf("azure-devops://%s/%s/%s/%s", c.config.AzureDevOps.Organization,
+		url.PathEscape(c.config.AzureDevOps.Project), repoSegment, resource)
+}
+
+// markdownMIMEType returns the MIME type to serve a documentation file with.
+func markdownMIMEType(filePath string) string {
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".md", ".markdown":
+		return "text/markdown"
+	}
+	return "text/plain"
+}
+
+// readRepositoryReadme returns the README at the root of a repository's default branch.
+func (c *AzureDevOpsClient) readRepositoryReadme(ctx context.Context, uri, repoName string) ([]mcp.ResourceContents, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+
+	_, readmePath, err := c.exploreTree(ctx, repoID, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if readmePath == "" {
+		return nil, fmt.Errorf("repository %s has no README", repoName)
+	}
+
+	file, err := c.fileAt(ctx, c.config.AzureDevOps.Project, repoID, readmePath, "")
+	if err != nil {
+		log.Printf("Error getting README: %v", err)
+		return nil, fmt.Errorf("error getting README of %s: %w", repoName, err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: markdownMIMEType(readmePath),
+			Text:     file.Content,
+		},
+	}, nil
+}
+
+// readRepositoryDocsIndex returns a markdown index of the files under a repository's docs folder.
+func (c *AzureDevOpsClient) readRepositoryDocsIndex(ctx context.Context, uri, repoName string) ([]mcp.ResourceContents, error) {
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	repoID := repo.Id.String()
+
+	items, err := c.itemsAt(ctx, c.config.AzureDevOps.Project, repoID, docsFolder, "", git.VersionControlRecursionTypeValues.Full)
+	if err != nil {
+		log.Printf("Error listing docs: %v", err)
+		return nil, fmt.Errorf("error listing %s of %s: %w", docsFolder, repoName, err)
+	}
+
+	var index strings.Builder
+	fmt.Fprintf(&index, "# Documentation of %s\n\n", stringValue(repo.Name))
+	for _, item := range items {
+		if item.IsFolder != nil && *item.IsFolder {
+			continue
+		}
+		itemPath := stringValue(item.Path)
+		fmt.Fprintf(&index, "- [%s](%s)\n", strings.TrimPrefix(itemPath, docsFolder+"/"), itemPath)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "text/markdown",
+			Text:     index.String(),
+		},
+	}, nil
+}
+
+// addRepositoryResources exposes the README and docs index of each repository as resources.
+// Repositories that exist at startup are listed by name; templates cover repositories created
+// later.
+func addRepositoryResources(s tools.Server, client *AzureDevOpsClient) {
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(client.repositoryResourceURI("{repository}", "readme"), "Repository README",
+			mcp.WithTemplateDescription("README at the root of a repository's default branch"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			repoName, err := resourceArgument(request, "repository")
+			if err != nil {
+				return nil, err
+			}
+			return client.readRepositoryReadme(ctx, request.Params.URI, repoName)
+		},
+	)
+
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(client.repositoryResourceURI("{repository}", "docs"), "Repository documentation index",
+			mcp.WithTemplateDescription(fmt.Sprintf("Index of the files under a repository's %s folder", docsFolder)),
+			mcp.WithTemplateMIMEType("text/markdown"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			repoName, err := resourceArgument(request, "repository")
+			if err != nil {
+				return nil, err
+			}
+			return client.readRepositoryDocsIndex(ctx, request.Params.URI, repoName)
+		},
+	)
+
+	repos, err := client.gitClient.GetRepositories(context.Background(), git.GetRepositoriesArgs{
+		Project: &client.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		// The templates still resolve; only the per-repository listing is missing.
+		log.Printf("Error listing repositories for resources: %v", err)
+		return
+	}
+
+	for _, repo := range *repos {
+		repoName := stringValue(repo.Name)
+
+		readmeURI := client.repositoryResourceURI(url.PathEscape(repoName), "readme")
+		s.AddResource(
+			mcp.NewResource(readmeURI, repoName+" README",
+				mcp.WithResourceDescription(fmt.Sprintf("README of the %s repository", repoName)),
+			),
+			func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+				return client.readRepositoryReadme(ctx, readmeURI, repoName)
+			},
+		)
+
+		docsURI := client.repositoryResourceURI(url.PathEscape(repoName), "docs")
+		s.AddResource(
+			mcp.NewResource(docsURI, repoName+" documentation index",
+				mcp.WithResourceDescription(fmt.Sprintf("Index of the files under %s in the %s repository", docsFolder, repoName)),
+				mcp.WithMIMEType("text/markdown"),
+			),
+			func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+				return client.readRepositoryDocsIndex(ctx, docsURI, repoName)
+			},
+		)
+	}
+}
+
+// resourceArgument reads a variable matched from a resource template URI. Matched values are
+// already percent-decoded.
+func resourceArgument(request mcp.ReadResourceRequest, key string) (string, error) {
+	value, ok := request.Params.Arguments[key].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%s is missing from the resource URI", key)
+	}
+	return value, nil
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultSuggestedReviewers = 5
+	// maxReviewerFiles caps the changed files whose history is read; each is one request.
+	maxReviewerFiles = 30
+	// reviewerFileCommits is how many of the latest commits of each file are considered.
+	reviewerFileCommits = 20
+	reviewerHistoryDays = 365
+	// reviewerHalfLifeDays halves the weight of a commit for every this many days of age.
+	reviewerHalfLifeDays = 90
+)
+
+// suggestedReviewer is an author of recent commits to the files under review.
+type suggestedReviewer struct {
+	name       string
+	email      string
+	score      float64
+	files      map[string]bool
+	lastCommit time.Time
+}
+
+// suggestReviewers ranks the recent authors of the files changed by a pull request, or of the
+// given paths of a repository, as reviewers. Each commit to a file counts for its author, weighted
+// down with age; the latest author of each file, who owns most of its current lines, counts
+// double. The pull request's author and current reviewers are left out.
+func (c *AzureDevOpsClient) suggestReviewers(ctx context.Context, prID int, repoName string, paths []string, top int) (map[string]interface{}, error) {
+	var repoID, branch string
+	changed := []string{}
+	excluded := map[string]bool{}
+	current := map[string]bool{}
+	if prID > 0 {
+		pr, err := c.getPullRequest(ctx, prID)
+		if err != nil {
+			return nil, err
+		}
+		if pr.Repository == nil || pr.Repository.Id == nil {
+			return nil, fmt.Errorf("pull request %d has no repository", prID)
+		}
+		repoID = pr.Repository.Id.String()
+		branch = strings.TrimPrefix(stringValue(pr.TargetRefName), "refs/heads/")
+		if pr.CreatedBy != nil {
+			excluded[strings.ToLower(stringValue(pr.CreatedBy.UniqueName))] = true
+		}
+		if pr.Reviewers != nil {
+			for _, reviewer := range *pr.Reviewers {
+				current[strings.ToLower(stringValue(reviewer.UniqueName))] = true
+			}
+		}
+
+		_, changes, err := c.pullRequestChanges(ctx, repoID, prID)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range pullRequestFiles(changes) {
+			changed = append(changed, change.Path)
+			// Added files have no history to learn from.
+			if change.ChangeType != "add" {
+				paths = append(paths, change.Path)
+			}
+		}
+	} else {
+		if repoName == "" || len(paths) == 0 {
+			return nil, fmt.Errorf("pass a pullRequestId, or a repository and paths")
+		}
+		var err error
+		repoID, branch, err = c.historyRepository(ctx, repoName, "")
+		if err != nil {
+			return nil, err
+		}
+		changed = paths
+	}
+
+	result := map[string]interface{}{}
+	errors := map[string]string{}
+	if len(paths) > maxReviewerFiles {
+		errors["files"] = fmt.Sprintf("history was read for the first %d of %d files", maxReviewerFiles, len(paths))
+		paths = paths[:maxReviewerFiles]
+	}
+
+	now := time.Now()
+	since := now.AddDate(0, 0, -reviewerHistoryDays)
+	authors := map[string]*suggestedReviewer{}
+	for _, filePath := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(filePath, "/") {
+			filePath = "/" + filePath
+		}
+		commits, err := c.commitsSince(ctx, repoID, branch, filePath, since, reviewerFileCommits)
+		if err != nil {
+			errors[filePath] = err.Error()
+			continue
+		}
+		for i, commit := range commits {
+			if commit.Author == nil || commit.Author.Date == nil {
+				continue
+			}
+			email := strings.ToLower(stringValue(commit.Author.Email))
+			author := authors[email]
+			if author == nil {
+				author = &suggestedReviewer{name: stringValue(commit.Author.Name), email: email, files: map[string]bool{}}
+				authors[email] = author
+			}
+			date := commit.Author.Date.Time
+			weight := math.Pow(0.5, now.Sub(date).Hours()/24/reviewerHalfLifeDays)
+			// Commits are newest first, so the first is the file's latest change.
+			if i == 0 {
+				weight *= 2
+			}
+			author.score += weight
+			author.files[filePath] = true
+			if date.After(author.lastCommit) {
+				author.lastCommit = date
+			}
+		}
+	}
+
+	ranked := []*suggestedReviewer{}
+	for email, author := range authors {
+		if !excluded[email] {
+			ranked = append(ranked, author)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].email < ranked[j].email
+	})
+	if len(ranked) > top {
+		ranked = ranked[:top]
+	}
+
+	reviewers := []map[string]interface{}{}
+	for _, author := range ranked {
+		files := []string{}
+		for file := range author.files {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		entry := map[string]interface{}{
+			"name":            author.name,
+			"email":           author.email,
+			"score":           math.Round(author.score*100) / 100,
+			"files":           files,
+			"lastCommit":      author.lastCommit.Format("2006-01-02"),
+			"alreadyReviewer": current[author.email],
+		}
+		// Resolve the identity so the suggestion can be passed straight to add_pr_reviewers.
+		identities, err := c.resolveIdentities(ctx, author.email)
+		if err != nil {
+			errors[author.email] = err.Error()
+		} else if len(identities) == 1 {
+			entry["id"] = identities[0].ID
+		}
+		reviewers = append(reviewers, entry)
+	}
+
+	// Owners from a CODEOWNERS file are listed alongside; a repository without one has none.
+	if owners, err := c.codeOwners(ctx, repoID, branch, changed); err == nil {
+		result["codeOwners"] = owners["owners"]
+	}
+
+	result["filesAnalyzed"] = len(paths)
+	result["reviewers"] = reviewers
+	if prID > 0 {
+		result["pullRequestId"] = prID
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// addPullRequestReviewers adds reviewers to a pull request by identity ID, as optional or required
+// reviewers.
+func (c *AzureDevOpsClient) addPullRequestReviewers(ctx context.Context, prID int, reviewerIDs []string, required bool, dryRun bool) (map[string]interface{}, error) {
+	pr, err := c.getPullRequest(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Repository == nil || pr.Repository.Id == nil {
+		return nil, fmt.Errorf("pull request %d has no repository", prID)
+	}
+	repoID := pr.Repository.Id.String()
+	if dryRun {
+		return c.previewPullRequestReviewers(ctx, prID, pr, reviewerIDs, required)
+	}
+
+	added := []map[string]interface{}{}
+	errors := map[string]string{}
+	for _, reviewerID := range reviewerIDs {
+		vote := 0
+		reviewer, err := c.gitClient.CreatePullRequestReviewer(ctx, git.CreatePullRequestReviewerArgs{
+			Reviewer: &git.IdentityRefWithVote{
+				Id:         &reviewerID,
+				Vote:       &vote,
+				IsRequired: &required,
+			},
+			RepositoryId:  &repoID,
+			PullRequestId: &prID,
+			ReviewerId:    &reviewerID,
+			Project:       &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error adding reviewer: %v", err)
+			errors[reviewerID] = err.Error()
+			continue
+		}
+		added = append(added, map[string]interface{}{
+			"id":         reviewerID,
+			"name":       stringValue(reviewer.DisplayName),
+			"isRequired": reviewer.IsRequired != nil && *reviewer.IsRequired,
+		})
+	}
+
+	result := map[string]interface{}{
+		"pullRequestId": prID,
+		"added":         added,
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// previewPullRequestReviewers resolves the reviewers addPullRequestReviewers would add, flagging
+// IDs that match no identity and identities that already review the pull request.
+func (c *AzureDevOpsClient) previewPullRequestReviewers(ctx context.Context, prID int, pr *git.GitPullRequest, reviewerIDs []string, required bool) (map[string]interface{}, error) {
+	ids := strings.Join(reviewerIDs, ",")
+	identities, err := c.identityClient.ReadIdentities(ctx, identity.ReadIdentitiesArgs{
+		IdentityIds: &ids,
+	})
+	if err != nil {
+		log.Printf("Error reading identities: %v", err)
+		return nil, fmt.Errorf("error resolving reviewers: %w", err)
+	}
+	names := map[string]string{}
+	if identities != nil {
+		for _, ident := range *identities {
+			if ident.Id != nil {
+				names[strings.ToLower(ident.Id.String())] = stringValue(ident.ProviderDisplayName)
+			}
+		}
+	}
+	current := map[string]bool{}
+	if pr.Reviewers != nil {
+		for _, reviewer := range *pr.Reviewers {
+			current[strings.ToLower(stringValue(reviewer.Id))] = true
+		}
+	}
+
+	reviewers := []map[string]interface{}{}
+	errors := map[string]string{}
+	for _, reviewerID := range reviewerIDs {
+		name, ok := names[strings.ToLower(reviewerID)]
+		if !ok {
+			errors[reviewerID] = "no identity has this ID"
+			continue
+		}
+		reviewers = append(reviewers, map[string]interface{}{
+			"id":              reviewerID,
+			"name":            name,
+			"isRequired":      required,
+			"alreadyReviewer": current[strings.ToLower(reviewerID)],
+		})
+	}
+
+	changes := map[string]interface{}{
+		"pullRequestId": prID,
+		"repository":    stringValue(pr.Repository.Name),
+		"reviewers":     reviewers,
+	}
+	if len(errors) > 0 {
+		changes["errors"] = errors
+	}
+	return tools.DryRunResult("add pull request reviewers", changes), nil
+}
+
+func addReviewerTools(s tools.Server, client *AzureDevOpsClient) {
+	suggestTool := mcp.NewTool("suggest_reviewers",
+		mcp.WithDescription("Suggest reviewers for a pull request, or for paths of a repository, from the recent authorship of the touched files: recent commits count more, and each file's latest author counts double. The pull request's author is left out, and identity IDs are included for add_pr_reviewers"),
+		mcp.WithNumber("pullRequestId",
+			mcp.Description("Pull request whose changed files are analyzed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the pull request"),
+		),
+		mcp.WithString("repository",
+			mcp.Description("Repository name, when passing paths instead of a pull request"),
+		),
+		mcp.WithArray("paths",
+			mcp.Description("File paths to analyze, when no pull request is given"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of reviewers to suggest"),
+			mcp.DefaultNumber(defaultSuggestedReviewers),
+		),
+	)
+
+	s.AddTool(suggestTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		prID := 0
+		if value, ok := request.Params.Arguments["pullRequestId"].(float64); ok {
+			prID = int(value)
+		} else if pr, ok, err := client.uriArgument(request, pullRequestEntity); err != nil {
+			log.Printf("Invalid pull request: %v", err)
+			return nil, err
+		} else if ok {
+			prID = pr.ID
+		}
+		repo, _ := request.Params.Arguments["repository"].(string)
+		paths, err := tools.StringSliceArgument(request.Params.Arguments, "paths")
+		if err != nil {
+			log.Printf("Invalid paths: %v", err)
+			return nil, err
+		}
+		top := defaultSuggestedReviewers
+		if value, ok := request.Params.Arguments["top"].(float64); ok && value > 0 {
+			top = int(value)
+		}
+
+		result, err := client.suggestReviewers(ctx, prID, repo, paths, top)
+		if err != nil {
+			log.Printf("Error suggesting reviewers: %v", err)
+			return nil, fmt.Errorf("error suggesting reviewers: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	addReviewersTool := mcp.NewTool("add_pr_reviewers",
+		mcp.WithDescription("Add reviewers to a pull request by identity ID, e.g. from suggest_reviewers or resolve_identity"),
+		mcp.WithNumber("pullRequestId",
+			mcp.Description("Pull request ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the pull request"),
+		),
+		mcp.WithArray("reviewerIds",
+			mcp.Required(),
+			mcp.Description("Identity IDs of the users or groups to add"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("required",
+			mcp.Description("Add them as required rather than optional reviewers"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(addReviewersTool, writeHandler(client, addReviewersTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := client.idArgument(request, "pullRequestId", pullRequestEntity)
+		if err != nil {
+			log.Printf("Invalid pull request: %v", err)
+			return nil, err
+		}
+		reviewerIDs, err := tools.StringSliceArgument(request.Params.Arguments, "reviewerIds")
+		if err != nil {
+			log.Printf("Invalid reviewer IDs: %v", err)
+			return nil, err
+		}
+		if len(reviewerIDs) == 0 {
+			log.Print("No reviewers to add")
+			return nil, fmt.Errorf("reviewerIds must list at least one reviewer")
+		}
+		required, _ := request.Params.Arguments["required"].(bool)
+
+		result, err := client.addPullRequestReviewers(ctx, id, reviewerIDs, required, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error adding reviewers: %v", err)
+			return nil, fmt.Errorf("error adding reviewers: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// repositoryRoot is a client root within the configured organization: a whole project, or one
+// repository of it when repository is set.
+type repositoryRoot struct {
+	project    string
+	repository string
+}
+
+// parseRoot reads a root URI of the form azuredevops://<organization>/<project>[/<repository>],
+// also accepted as azure-devops:// like the server's resources, or a clone or web URL such as
+// https://dev.azure.com/<organization>/<project>/_git/<repository>, or one below the configured
+// organization or server collection, such as https://<server>/tfs/<collection>/<project>. Roots of
+// other organizations and other schemes, e.g. an IDE's file:// workspace folders, are not
+// repository roots.
+func parseRoot(uri, organization string, links webLinks) (repositoryRoot, bool) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return repositoryRoot{}, false
+	}
+	segments := []string{}
+	for _, segment := range strings.Split(strings.Trim(parsed.Path, "/"), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	var org string
+	below, underBase := links.contains(parsed)
+	switch {
+	case parsed.Scheme == "azuredevops" || parsed.Scheme == "azure-devops":
+		org = parsed.Host
+	case (parsed.Scheme == "https" || parsed.Scheme == "http") && underBase:
+		org = organization
+		segments = []string{}
+		for _, segment := range below {
+			if segment != "" {
+				segments = append(segments, segment)
+			}
+		}
+	case parsed.Scheme == "https" && strings.EqualFold(parsed.Host, "dev.azure.com") && len(segments) > 0:
+		org = segments[0]
+		segments = segments[1:]
+	default:
+		return repositoryRoot{}, false
+	}
+	// Clone and web URLs put _git between the project and the repository.
+	if parsed.Scheme != "azuredevops" && parsed.Scheme != "azure-devops" && len(segments) > 2 && segments[1] == "_git" {
+		segments = append(segments[:1], segments[2:]...)
+	}
+	if !strings.EqualFold(org, organization) || len(segments) == 0 {
+		return repositoryRoot{}, false
+	}
+
+	root := repositoryRoot{project: segments[0]}
+	if len(segments) > 1 {
+		root.repository = segments[1]
+	}
+	return root, true
+}
+
+// sessionRoots returns the client's roots within the configured organization. nil means the
+// client declared none, and calls are not constrained.
+func (c *AzureDevOpsClient) sessionRoots(ctx context.Context) ([]repositoryRoot, error) {
+	requests := clientRequestsFromContext(ctx)
+	session := server.ClientSessionFromContext(ctx)
+	if requests == nil || session == nil {
+		return nil, nil
+	}
+	roots, err := requests.listRoots(ctx, session.SessionID())
+	if err != nil {
+		log.Printf("Error listing roots: %v", err)
+		return nil, fmt.Errorf("error listing the client's roots: %w", err)
+	}
+
+	var scoped []repositoryRoot
+	for _, root := range roots {
+		if parsed, ok := parseRoot(root.URI, c.config.AzureDevOps.Organization, c.links); ok {
+			scoped = append(scoped, parsed)
+		}
+	}
+	return scoped, nil
+}
+
+// rootRepositories returns the repositories of a project the client's roots allow: nil when the
+// whole project is allowed, and an error when the roots leave the project out.
+func (c *AzureDevOpsClient) rootRepositories(ctx context.Context, project string) ([]string, error) {
+	roots, err := c.sessionRoots(ctx)
+	if err != nil || roots == nil {
+		return nil, err
+	}
+
+	repos := []string{}
+	for _, root := range roots {
+		if !strings.EqualFold(root.project, project) {
+			continue
+		}
+		if root.repository == "" {
+			return nil, nil
+		}
+		repos = append(repos, root.repository)
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("project %s is outside the client's roots", project)
+	}
+	return repos, nil
+}
+
+// inRoots reports whether roots allow a repository of a project.
+func inRoots(roots []repositoryRoot, project, repoName string) bool {
+	for _, root := range roots {
+		if strings.EqualFold(root.project, project) && (root.repository == "" || strings.EqualFold(root.repository, repoName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRoots fails when the client's roots leave out a repository of a project.
+func (c *AzureDevOpsClient) checkRoots(ctx context.Context, project, repoName string) error {
+	repos, err := c.rootRepositories(ctx, project)
+	if err != nil || repos == nil {
+		return err
+	}
+	for _, repo := range repos {
+		if strings.EqualFold(repo, repoName) {
+			return nil
+		}
+	}
+	return fmt.Errorf("repository %s is outside the client's roots", repoName)
+}
+
+// searchRepositories returns the Repository filter of a code search in a project: the named
+// repository, or without one the repositories the client's roots allow. nil searches them all.
+func (c *AzureDevOpsClient) searchRepositories(ctx context.Context, project, repoName string) ([]string, error) {
+	if repoName == "" {
+		return c.rootRepositories(ctx, project)
+	}
+	if err := c.checkRoots(ctx, project, repoName); err != nil {
+		return nil, err
+	}
+	return []string{repoName}, nil
+}
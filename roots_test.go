@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestParseRoot(t *testing.T) {
+	services := newWebLinks("contoso", "")
+	server := newWebLinks("DefaultCollection", "https://tfs.example.com/tfs/DefaultCollection")
+	tests := []struct {
+		name         string
+		uri          string
+		organization string
+		links        webLinks
+		want         repositoryRoot
+		ok           bool
+	}{
+		{"scheme", "azuredevops://contoso/Fabrikam/web", "contoso", services, repositoryRoot{"Fabrikam", "web"}, true},
+		{"clone URL", "https://dev.azure.com/contoso/Fabrikam/_git/web", "contoso", services, repositoryRoot{"Fabrikam", "web"}, true},
+		{"other organization", "https://dev.azure.com/other/Fabrikam/_git/web", "contoso", services, repositoryRoot{}, false},
+		{"server clone URL", "https://tfs.example.com/tfs/DefaultCollection/Fabrikam/_git/web", "DefaultCollection", server, repositoryRoot{"Fabrikam", "web"}, true},
+		{"server project", "https://TFS.example.com/tfs/defaultcollection/Fabrikam", "DefaultCollection", server, repositoryRoot{"Fabrikam", ""}, true},
+		{"other collection", "https://tfs.example.com/tfs/Other/Fabrikam/_git/web", "DefaultCollection", server, repositoryRoot{}, false},
+		{"file", "file:///home/me/web", "DefaultCollection", server, repositoryRoot{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := parseRoot(test.uri, test.organization, test.links)
+			if got != test.want || ok != test.ok {
+				t.Errorf("parseRoot(%q) = %+v, %v; want %+v, %v", test.uri, got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestAnalyticsRoot(t *testing.T) {
+	tests := map[string]string{
+		"":                                 "https://analytics.dev.azure.com/contoso",
+		"https://contoso.visualstudio.com": "https://contoso.analytics.visualstudio.com",
+		"https://tfs.example.com/tfs/DefaultCollection/": "https://tfs.example.com/tfs/DefaultCollection",
+	}
+	for base, want := range tests {
+		if got := newWebLinks("contoso", base).analytics(); got != want {
+			t.Errorf("analytics() for %q = %q, want %q", base, got, want)
+		}
+	}
+}
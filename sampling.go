@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// diffSummaryTokens caps the summary of a diff too large for a tool's token budget.
+	diffSummaryTokens = 500
+	// maxSampledDiffs caps the diffs one tool call summarizes; clients may ask the user to
+	// approve each request.
+	maxSampledDiffs = 5
+	// diffSummaryPrompt instructs the client's model how to condense an oversized diff.
+	diffSummaryPrompt = "Summarize this unified diff for a code reviewer. List the behavioral changes, new or removed functions and types, and anything risky such as changed error handling, security checks, or public interfaces. Be concise and do not restate unchanged code."
+)
+
+// errSamplingUnavailable is returned when the calling client cannot sample.
+var errSamplingUnavailable = fmt.Errorf("the client does not support sampling")
+
+// sampleText asks the model of the client making the current tool call to respond to a prompt
+// about content, using at most maxTokens. The client decides which model answers and may ask the
+// user to approve the request first.
+func sampleText(ctx context.Context, prompt, content string, maxTokens int) (string, error) {
+	requests := clientRequestsFromContext(ctx)
+	session := server.ClientSessionFromContext(ctx)
+	if requests == nil || session == nil || requests.clientCapabilities(session.SessionID()).Sampling == nil {
+		return "", errSamplingUnavailable
+	}
+
+	params := map[string]interface{}{
+		"messages": []mcp.SamplingMessage{{
+			Role:    mcp.RoleUser,
+			Content: mcp.NewTextContent(content),
+		}},
+		"systemPrompt": prompt,
+		"maxTokens":    maxTokens,
+	}
+	var result mcp.CreateMessageResult
+	if err := requests.call(ctx, session.SessionID(), "sampling/createMessage", params, &result); err != nil {
+		return "", err
+	}
+
+	// Content is decoded as a generic map since it may be text or an image.
+	message, _ := result.Content.(map[string]interface{})
+	text, _ := message["text"].(string)
+	if text == "" {
+		return "", fmt.Errorf("the client's model returned no text")
+	}
+	return text, nil
+}
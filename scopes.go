@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/audit"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/dashboard"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/extensionmanagement"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/notification"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/servicehooks"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/test"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/wiki"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+)
+
+// scopeProbeTimeout bounds how long startup waits for the scope probes.
+const scopeProbeTimeout = 30 * time.Second
+
+// scopeProbe is a cheap request that needs the same PAT scope as the tools of groups.
+type scopeProbe struct {
+	scope  string
+	groups []string
+	probe  func(ctx context.Context, c *AzureDevOpsClient) error
+}
+
+// scopeProbes has a probe for each PAT scope that tools need. Groups of no probe, such as the
+// context tools, are always listed.
+var scopeProbes = []scopeProbe{
+	{
+		scope: "Code (Read)",
+		groups: []string{"code", "repositories", "commits", "edits", "contents", "explore", "related", "symbols", "pullrequests",
+			"prtemplates", "threads", "reviewers", "codeowners", "releasenotes", "dependencies", "usage", "history", "activity",
+			"prstats", "resources", "policies"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.gitClient.GetRepositories(ctx, git.GetRepositoriesArgs{Project: &c.config.AzureDevOps.Project})
+			return err
+		},
+	},
+	{
+		scope:  "Build (Read)",
+		groups: []string{"pipelines", "builds", "triage"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.buildClient.GetDefinitions(ctx, build.GetDefinitionsArgs{Project: &c.config.AzureDevOps.Project, Top: &[]int{1}[0]})
+			return err
+		},
+	},
+	{
+		scope:  "Environment (Read & manage)",
+		groups: []string{"deployments"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.taskAgentClient.GetEnvironments(ctx, taskagent.GetEnvironmentsArgs{Project: &c.config.AzureDevOps.Project, Top: &[]int{1}[0]})
+			return err
+		},
+	},
+	{
+		scope:  "Secure Files (Read)",
+		groups: []string{"securefiles"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.secureFiles(ctx)
+			return err
+		},
+	},
+	{
+		scope:  "Task Groups (Read)",
+		groups: []string{"taskgroups"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.taskAgentClient.GetTaskGroups(ctx, taskagent.GetTaskGroupsArgs{Project: &c.config.AzureDevOps.Project, Top: &[]int{1}[0]})
+			return err
+		},
+	},
+	{
+		scope:  "Extensions (Read)",
+		groups: []string{"extensions"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.extensionClient.GetInstalledExtensions(ctx, extensionmanagement.GetInstalledExtensionsArgs{})
+			return err
+		},
+	},
+	{
+		scope:  "Member Entitlement Management (Read)",
+		groups: []string{"entitlements"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, _, err := c.searchEntitlements(ctx, "", "", "", 1)
+			return err
+		},
+	},
+	{
+		scope:  "Wiki (Read)",
+		groups: []string{"wikiattachments"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.wikiClient.GetAllWikis(ctx, wiki.GetAllWikisArgs{Project: &c.config.AzureDevOps.Project})
+			return err
+		},
+	},
+	{
+		scope:  "Test Management (Read)",
+		groups: []string{"tests"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.testClient.GetTestRuns(ctx, test.GetTestRunsArgs{Project: &c.config.AzureDevOps.Project, Top: &[]int{1}[0]})
+			return err
+		},
+	},
+	{
+		scope:  "Work Items (Read)",
+		groups: []string{"workitems", "processes", "templates", "workitemclone", "mywork", "tags", "workitemattachments", "boards", "sprints", "plans"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.witClient.GetWorkItemTypes(ctx, workitemtracking.GetWorkItemTypesArgs{Project: &c.config.AzureDevOps.Project})
+			return err
+		},
+	},
+	{
+		scope:  "Project and Team (Read)",
+		groups: []string{"projects", "teams"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.coreClient.GetTeams(ctx, core.GetTeamsArgs{ProjectId: &c.config.AzureDevOps.Project, Top: &[]int{1}[0]})
+			return err
+		},
+	},
+	{
+		scope:  "Identity (Read)",
+		groups: []string{"identities"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.identityClient.ListGroups(ctx, identity.ListGroupsArgs{})
+			return err
+		},
+	},
+	{
+		scope:  "Graph (Read)",
+		groups: []string{"groups"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.graphClient.ListGroups(ctx, graph.ListGroupsArgs{})
+			return err
+		},
+	},
+	{
+		scope:  "Security (Manage)",
+		groups: []string{"permissions"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.securityClient.QuerySecurityNamespaces(ctx, security.QuerySecurityNamespacesArgs{})
+			return err
+		},
+	},
+	{
+		scope:  "Service Hooks (Read)",
+		groups: []string{"servicehooks"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.serviceHooksClient.ListPublishers(ctx, servicehooks.ListPublishersArgs{})
+			return err
+		},
+	},
+	{
+		scope:  "Team Dashboard (Read)",
+		groups: []string{"dashboards"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.dashboardClient.GetDashboardsByProject(ctx, dashboard.GetDashboardsByProjectArgs{Project: &c.config.AzureDevOps.Project})
+			return err
+		},
+	},
+	{
+		scope:  "Analytics (Read)",
+		groups: []string{"analytics"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.queryAnalytics(ctx, "WorkItems", map[string]string{"$select": "WorkItemId", "$top": "1"})
+			return err
+		},
+	},
+	{
+		scope:  "Notifications (Read)",
+		groups: []string{"notifications"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.notificationClient.ListSubscriptions(ctx, notification.ListSubscriptionsArgs{})
+			return err
+		},
+	},
+	{
+		scope:  "Audit Log (Read)",
+		groups: []string{"audit"},
+		probe: func(ctx context.Context, c *AzureDevOpsClient) error {
+			_, err := c.auditClient.GetActions(ctx, audit.GetActionsArgs{})
+			return err
+		},
+	},
+}
+
+// probeScopes returns the tool groups whose tools would always fail because the PAT lacks their
+// scope, with the scope each needs. Only a 401 or 403 answer hides a group; any other failure,
+// such as an API Azure DevOps Server does not have or an outage, leaves it listed. When every
+// probe is refused the PAT itself is the problem, and nothing is hidden.
+func (c *AzureDevOpsClient) probeScopes(ctx context.Context) map[string]string {
+	ctx, cancel := context.WithTimeout(ctx, scopeProbeTimeout)
+	defer cancel()
+
+	denied := make([]bool, len(scopeProbes))
+	var wg sync.WaitGroup
+	for i, probe := range scopeProbes {
+		wg.Add(1)
+		go func(i int, probe scopeProbe) {
+			defer wg.Done()
+			switch statusCode(probe.probe(ctx, c)) {
+			case http.StatusUnauthorized, http.StatusForbidden:
+				denied[i] = true
+			}
+		}(i, probe)
+	}
+	wg.Wait()
+
+	refused := 0
+	for _, d := range denied {
+		if d {
+			refused++
+		}
+	}
+	if refused == len(scopeProbes) {
+		log.Print("Azure DevOps refused the PAT for every scope; it may have expired. Listing all tools")
+		return nil
+	}
+
+	hidden := map[string]string{}
+	for i, probe := range scopeProbes {
+		if !denied[i] {
+			continue
+		}
+		log.Printf("The PAT lacks the %s scope; hiding the %s tools", probe.scope, strings.Join(probe.groups, ", "))
+		for _, group := range probe.groups {
+			hidden[group] = probe.scope
+		}
+	}
+	return hidden
+}
+
+// statusCode returns the HTTP status of an Azure DevOps error, or 0 for other errors.
+func statusCode(err error) int {
+	var wrapped azuredevops.WrappedError
+	if errors.As(err, &wrapped) && wrapped.StatusCode != nil {
+		return *wrapped.StatusCode
+	}
+	var pointer *azuredevops.WrappedError
+	if errors.As(err, &pointer) && pointer.StatusCode != nil {
+		return *pointer.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/search"
+)
+
+// codeSearchOptions narrow and order the results of searchRepository.
+type codeSearchOptions struct {
+	// Projects to search instead of the session's; "*" searches the organization.
+	Projects []string
+	// Folder limits the search to the files under a folder of the repository searched.
+	Folder string
+	// Collapse merges the hits of the same file, e.g. on several branches, into one.
+	Collapse bool
+	// DownRank lists patterns of paths, such as vendored or generated code, listed after the rest.
+	DownRank []string
+	// Ignore lists patterns of paths left out, in addition to search.ignore.
+	Ignore []string
+}
+
+// ignoreDescription documents the ignore argument of the tools that list files.
+const ignoreDescription = "Path patterns in .gitignore syntax to leave out, e.g. node_modules/, bin/, obj/, or *.min.js, in addition to search.ignore"
+
+// pathPatterns are .gitignore-style patterns matched against repository paths, like CODEOWNERS
+// patterns.
+type pathPatterns []*regexp.Regexp
+
+// compilePathPatterns compiles the patterns of one or more lists, skipping empty ones.
+func compilePathPatterns(lists ...[]string) (pathPatterns, error) {
+	compiled := pathPatterns{}
+	for _, list := range lists {
+		for _, pattern := range list {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			re, err := codeOwnersPattern(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path pattern %s: %w", pattern, err)
+			}
+			compiled = append(compiled, re)
+		}
+	}
+	return compiled, nil
+}
+
+// match reports whether any of the patterns matches a path.
+func (p pathPatterns) match(filePath string) bool {
+	filePath = strings.TrimPrefix(filePath, "/")
+	for _, re := range p {
+		if re.MatchString(filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchItem reports whether any of the patterns matches a file, or a folder, which patterns ending
+// in a slash match too.
+func (p pathPatterns) matchItem(itemPath string, isFolder bool) bool {
+	if isFolder {
+		itemPath = strings.TrimSuffix(itemPath, "/") + "/"
+	}
+	return p.match(itemPath)
+}
+
+// ignorePatterns compiles the patterns of paths search results and tree listings leave out: those
+// of search.ignore and extra ones passed to a call.
+func (c *AzureDevOpsClient) ignorePatterns(extra []string) (pathPatterns, error) {
+	return compilePathPatterns(c.config.Search.Ignore, extra)
+}
+
+// codeSearchMatches counts the hits of a code search result across the fields it matched in.
+func codeSearchMatches(result search.CodeResult) int {
+	count := 0
+	if result.Matches != nil {
+		for _, hits := range *result.Matches {
+			count += len(hits)
+		}
+	}
+	return count
+}
+
+// codeSearchBranches lists the branches a code search result was found on.
+func codeSearchBranches(result search.CodeResult) []string {
+	branches := []string{}
+	if result.Versions != nil {
+		for _, version := range *result.Versions {
+			if name := stringValue(version.BranchName); name != "" {
+				branches = append(branches, name)
+			}
+		}
+	}
+	return branches
+}
+
+// collapseSearchHits merges the hits of the same file of a repository into the first of them,
+// adding up their matches and listing the branches they were found on.
+func collapseSearchHits(hits []searchHit) []searchHit {
+	collapsed := []searchHit{}
+	seen := map[string]searchHit{}
+	for _, hit := range hits {
+		key := strings.ToLower(hit.project + "/" + hit.repoID + "/" + hit.result["path"].(string))
+		first, ok := seen[key]
+		if !ok {
+			seen[key] = hit
+			collapsed = append(collapsed, hit)
+			continue
+		}
+		first.result["matches"] = first.result["matches"].(int) + hit.result["matches"].(int)
+		branches, _ := first.result["branches"].([]string)
+		more, _ := hit.result["branches"].([]string)
+		for _, branch := range more {
+			if !slices.Contains(branches, branch) {
+				branches = append(branches, branch)
+			}
+		}
+		if len(branches) > 0 {
+			first.result["branches"] = branches
+		}
+	}
+	return collapsed
+}
+
+// downRankSearchHits moves the hits whose paths match patterns after the rest, keeping the order
+// of each, and marks them downRanked.
+func downRankSearchHits(hits []searchHit, patterns pathPatterns) {
+	if len(patterns) == 0 {
+		return
+	}
+	for _, hit := range hits {
+		if patterns.match(hit.result["path"].(string)) {
+			hit.result["downRanked"] = true
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].result["downRanked"] == nil && hits[j].result["downRanked"] != nil
+	})
+}
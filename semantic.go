@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/config"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	// semanticChunkLines is the number of lines per indexed chunk, and semanticChunkOverlap how
+	// many of them repeat the end of the previous chunk so code is not cut off at a boundary.
+	semanticChunkLines   = 60
+	semanticChunkOverlap = 10
+	// maxEmbeddingChars caps the text of a chunk sent to the embedding provider.
+	maxEmbeddingChars     = 4000
+	embeddingBatchSize    = 32
+	maxSemanticFileBytes  = 200 << 10
+	defaultSemanticResult = 10
+	maxSemanticResults    = 50
+)
+
+// defaultSemanticExtensions are the file types indexed when semantic_search.extensions is empty.
+var defaultSemanticExtensions = []string{
+	".go", ".cs", ".java", ".kt", ".py", ".js", ".jsx", ".ts", ".tsx", ".rb", ".rs",
+	".c", ".cpp", ".h", ".hpp", ".sql", ".ps1", ".sh", ".md",
+}
+
+// semanticChunk is a run of lines of a file with its embedding.
+type semanticChunk struct {
+	Repository string
+	Path       string
+	StartLine  int
+	EndLine    int
+	Text       string
+	Vector     []float32
+}
+
+// semanticIndexData is the persisted part of the index: the chunks of each repository and the
+// commit they were read at, so unchanged repositories are not embedded again.
+type semanticIndexData struct {
+	Commits map[string]string
+	Chunks  map[string][]semanticChunk
+}
+
+// semanticIndex holds embedded chunks of the configured repositories in memory and answers
+// similarity queries against them.
+type semanticIndex struct {
+	client     *AzureDevOpsClient
+	embedder   Embedder
+	config     config.SemanticSearchConfig
+	extensions map[string]bool
+
+	mu        sync.RWMutex
+	data      semanticIndexData
+	building  bool
+	lastBuilt time.Time
+	errors    map[string]string
+}
+
+func newSemanticIndex(client *AzureDevOpsClient) (*semanticIndex, error) {
+	config := client.config.SemanticSearch
+	embedder, err := newEmbedder(config)
+	if err != nil {
+		return nil, err
+	}
+
+	extensions := map[string]bool{}
+	if len(config.Extensions) == 0 {
+		config.Extensions = defaultSemanticExtensions
+	}
+	for _, ext := range config.Extensions {
+		extensions[strings.ToLower(ext)] = true
+	}
+
+	index := &semanticIndex{
+		client:     client,
+		embedder:   embedder,
+		config:     config,
+		extensions: extensions,
+		data: semanticIndexData{
+			Commits: map[string]string{},
+			Chunks:  map[string][]semanticChunk{},
+		},
+		errors: map[string]string{},
+	}
+	if err := index.load(); err != nil {
+		// A missing or unreadable index is rebuilt from scratch.
+		log.Printf("Starting with an empty semantic index: %v", err)
+	}
+	return index, nil
+}
+
+// load reads the persisted index, if one is configured and exists.
+func (idx *semanticIndex) load() error {
+	if idx.config.IndexPath == "" {
+		return nil
+	}
+	file, err := os.Open(idx.config.IndexPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var data semanticIndexData
+	if err := gob.NewDecoder(file).Decode(&data); err != nil {
+		return fmt.Errorf("error decoding %s: %w", idx.config.IndexPath, err)
+	}
+	idx.data = data
+	return nil
+}
+
+// save persists the index, if an index path is configured. It writes to a temporary file first
+// so a crash never leaves a truncated index behind.
+func (idx *semanticIndex) save() error {
+	if idx.config.IndexPath == "" {
+		return nil
+	}
+	tmp := idx.config.IndexPath + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.RLock()
+	err = gob.NewEncoder(file).Encode(idx.data)
+	idx.mu.RUnlock()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, idx.config.IndexPath)
+}
+
+// build indexes every configured repository whose default branch moved since it was last indexed.
+func (idx *semanticIndex) build(ctx context.Context) {
+	idx.mu.Lock()
+	if idx.building {
+		idx.mu.Unlock()
+		return
+	}
+	idx.building = true
+	idx.mu.Unlock()
+
+	defer func() {
+		idx.mu.Lock()
+		idx.building = false
+		idx.lastBuilt = time.Now()
+		idx.mu.Unlock()
+	}()
+
+	for _, repoName := range idx.config.Repositories {
+		err := idx.indexRepository(ctx, repoName)
+		idx.mu.Lock()
+		if err != nil {
+			log.Printf("Error indexing %s for semantic search: %v", repoName, err)
+			idx.errors[repoName] = err.Error()
+		} else {
+			delete(idx.errors, repoName)
+		}
+		idx.mu.Unlock()
+		if err == nil {
+			if err := idx.save(); err != nil {
+				log.Printf("Error saving semantic index: %v", err)
+			}
+		}
+	}
+}
+
+// indexRepository chunks and embeds the indexed file types of a repository's default branch.
+func (idx *semanticIndex) indexRepository(ctx context.Context, repoName string) error {
+	c := idx.client
+	repo, err := c.findRepository(ctx, repoName)
+	if err != nil {
+		return err
+	}
+	if repo.DefaultBranch == nil {
+		return fmt.Errorf("repository %s has no default branch", repoName)
+	}
+	repoID := repo.Id.String()
+	key := strings.ToLower(repoName)
+
+	commitID, err := c.resolveCommit(ctx, repoID, *repo.DefaultBranch)
+	if err != nil {
+		return err
+	}
+	idx.mu.RLock()
+	indexed := idx.data.Commits[key]
+	idx.mu.RUnlock()
+	if indexed == commitID {
+		return nil
+	}
+
+	items, err := c.itemsAt(ctx, c.config.AzureDevOps.Project, repoID, "/", commitID, git.VersionControlRecursionTypeValues.Full)
+	if err != nil {
+		log.Printf("Error listing repository files: %v", err)
+		return fmt.Errorf("error listing files of %s: %w", repoName, err)
+	}
+
+	chunks := []semanticChunk{}
+	for _, item := range items {
+		itemPath := stringValue(item.Path)
+		if (item.IsFolder != nil && *item.IsFolder) || !idx.extensions[strings.ToLower(path.Ext(itemPath))] {
+			continue
+		}
+		file, err := c.fileAt(ctx, c.config.AzureDevOps.Project, repoID, itemPath, commitID)
+		if err != nil {
+			// One unreadable file should not stop the repository from being indexed.
+			log.Printf("Skipping %s in semantic index: %v", itemPath, err)
+			continue
+		}
+		content := file.Content
+		if len(content) > maxSemanticFileBytes || strings.ContainsRune(content, 0) {
+			continue
+		}
+		chunks = append(chunks, chunkFile(stringValue(repo.Name), itemPath, content)...)
+	}
+
+	for start := 0; start < len(chunks); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		texts := make([]string, 0, end-start)
+		for _, chunk := range chunks[start:end] {
+			text := chunk.Path + "\n" + chunk.Text
+			if len(text) > maxEmbeddingChars {
+				text = text[:maxEmbeddingChars]
+			}
+			texts = append(texts, text)
+		}
+		vectors, err := idx.embedder.Embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("error embedding %s: %w", repoName, err)
+		}
+		for i, vector := range vectors {
+			chunks[start+i].Vector = normalize(vector)
+		}
+	}
+
+	idx.mu.Lock()
+	idx.data.Chunks[key] = chunks
+	idx.data.Commits[key] = commitID
+	idx.mu.Unlock()
+	log.Printf("Indexed %d chunks of %s at %s for semantic search", len(chunks), repoName, commitID)
+	return nil
+}
+
+// chunkFile splits a file into overlapping runs of lines.
+func chunkFile(repoName, filePath, content string) []semanticChunk {
+	lines := strings.Split(content, "\n")
+	chunks := []semanticChunk{}
+	for start := 0; start < len(lines); start += semanticChunkLines - semanticChunkOverlap {
+		end := start + semanticChunkLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) != "" {
+			chunks = append(chunks, semanticChunk{
+				Repository: repoName,
+				Path:       filePath,
+				StartLine:  start + 1,
+				EndLine:    end,
+				Text:       text,
+			})
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks
+}
+
+// normalize scales a vector to unit length, so similarity is a dot product.
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := float32(math.Sqrt(sum))
+	result := make([]float32, len(vector))
+	for i, v := range vector {
+		result[i] = v / norm
+	}
+	return result
+}
+
+// search returns the chunks most similar to a query, optionally only from one repository, of the
+// repositories the caller can read.
+func (idx *semanticIndex) search(ctx context.Context, query, repoName string, top int) (map[string]interface{}, error) {
+	canRead, err := idx.client.readableRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if repoName != "" && !canRead(repoName) {
+		return nil, fmt.Errorf("repository not found: %s", repoName)
+	}
+	vectors, err := idx.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		log.Printf("Error embedding query: %v", err)
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+	queryVector := normalize(vectors[0])
+
+	type scored struct {
+		chunk *semanticChunk
+		score float32
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	matches := []scored{}
+	indexed := 0
+	for key, chunks := range idx.data.Chunks {
+		if repoName != "" && key != strings.ToLower(repoName) || !canRead(key) {
+			continue
+		}
+		for i := range chunks {
+			indexed++
+			if len(chunks[i].Vector) != len(queryVector) {
+				continue
+			}
+			var score float32
+			for j, v := range chunks[i].Vector {
+				score += v * queryVector[j]
+			}
+			matches = append(matches, scored{chunk: &chunks[i], score: score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) > top {
+		matches = matches[:top]
+	}
+
+	results := []map[string]interface{}{}
+	for _, match := range matches {
+		results = append(results, map[string]interface{}{
+			"repository": match.chunk.Repository,
+			"path":       match.chunk.Path,
+			"startLine":  match.chunk.StartLine,
+			"endLine":    match.chunk.EndLine,
+			"score":      match.score,
+			"content":    match.chunk.Text,
+		})
+	}
+
+	result := map[string]interface{}{
+		"results":       results,
+		"indexedChunks": indexed,
+		"indexBuilding": idx.building,
+	}
+	if !idx.lastBuilt.IsZero() {
+		result["lastIndexed"] = idx.lastBuilt.Format(time.RFC3339)
+	}
+	if len(idx.errors) > 0 {
+		indexErrors := map[string]string{}
+		for repo, message := range idx.errors {
+			if canRead(repo) {
+				indexErrors[repo] = message
+			}
+		}
+		if len(indexErrors) > 0 {
+			result["indexErrors"] = indexErrors
+		}
+	}
+	return result, nil
+}
+
+func addSemanticSearchTools(s tools.Server, index *semanticIndex) {
+	semanticTool := mcp.NewTool("semantic_search",
+		mcp.WithDescription(fmt.Sprintf("Search the indexed repositories (%s) by meaning rather than keywords, e.g. \"where do we retry failed payments\". Returns the most similar code chunks with their line ranges", strings.Join(index.config.Repositories, ", "))),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Natural language description of the code to find"),
+		),
+		mcp.WithString("repository",
+			mcp.Description("Optional indexed repository to search in"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description(fmt.Sprintf("Maximum number of chunks, at most %d", maxSemanticResults)),
+			mcp.DefaultNumber(defaultSemanticResult),
+		),
+	)
+
+	s.AddTool(semanticTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		query, ok := request.Params.Arguments["query"].(string)
+		if !ok {
+			log.Print("Query must be a string")
+			return nil, fmt.Errorf("query must be a string")
+		}
+		repo, _ := request.Params.Arguments["repository"].(string)
+		top := defaultSemanticResult
+		if value, ok := request.Params.Arguments["top"].(float64); ok && value > 0 {
+			top = int(value)
+		}
+		if top > maxSemanticResults {
+			top = maxSemanticResults
+		}
+
+		result, err := index.search(ctx, query, repo, top)
+		if err != nil {
+			log.Printf("Error running semantic search: %v", err)
+			return nil, fmt.Errorf("error running semantic search: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/signify/sgfy-mcp/config"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	serverName    = "Azure DevOps MCP Server"
+	serverVersion = "1.0.0"
+)
+
+// serverInfo describes what the server can do for a client: where it is connected, which tools
+// it lists, whether it writes, and how close its Azure DevOps requests are to their limits. It
+// holds no credentials.
+func (c *AzureDevOpsClient) serverInfo() map[string]interface{} {
+	groups := []string{}
+	for _, group := range enabledToolGroups(c).Groups() {
+		groups = append(groups, group.Name())
+	}
+
+	sessionAuth := c.config.Server.SessionAuth
+	if sessionAuth == "" {
+		sessionAuth = config.SessionAuthOff
+	}
+
+	info := map[string]interface{}{
+		"name":         serverName,
+		"version":      serverVersion,
+		"organization": c.config.AzureDevOps.Organization,
+		"project":      c.config.AzureDevOps.Project,
+		"team":         c.teamName(""),
+		"readOnly":     !c.config.Server.AllowWrites,
+		"dryRun":       c.config.Server.DryRun,
+		"sessionAuth":  sessionAuth,
+		"multiTenant":  c.config.Server.MultiTenant,
+		"toolGroups":   groups,
+		"features": map[string]bool{
+			"repositoryIndex": len(c.config.RepositoryIndex.Repositories) > 0,
+			"semanticSearch":  len(c.config.SemanticSearch.Repositories) > 0,
+			"events":          c.config.Server.WebhookSecret != "",
+			"auditLog":        c.audit != nil,
+		},
+		"rateLimit": map[string]interface{}{
+			"maxConcurrentRequests": c.config.RateLimit.MaxConcurrentRequests,
+			"requestsPerMinute":     c.config.RateLimit.RequestsPerMinute,
+			"organizations":         c.requests.RateLimits(),
+		},
+		"failingHosts": c.requests.Circuits(),
+	}
+	if c.config.AzureDevOps.URL != "" {
+		info["serverUrl"] = c.config.AzureDevOps.URL
+	}
+	if c.config.AzureDevOps.APIVersion != "" {
+		info["apiVersion"] = c.config.AzureDevOps.APIVersion
+	}
+	if len(c.config.Server.ConfirmTools) > 0 {
+		info["confirmTools"] = c.config.Server.ConfirmTools
+	}
+	if len(c.hiddenGroups) > 0 {
+		// Each hidden group with the PAT scope it needs
+		info["hiddenToolGroups"] = c.hiddenGroups
+	}
+	return info
+}
+
+func addServerInfoTools(s tools.Server, client *AzureDevOpsClient) {
+	serverInfoTool := mcp.NewTool("server_info",
+		mcp.WithDescription("Describe this server: its version, the organization and project it is connected to, the tool groups it lists and any hidden for lack of PAT scope, whether it is read-only, and the state of its Azure DevOps rate limits and failing hosts"),
+	)
+
+	s.AddTool(serverInfoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return tools.JSONResult(client.serverInfo())
+	})
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/servicehooks"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultServiceHookPublisher = "tfs"
+	webHooksConsumerID          = "webHooks"
+	webHooksConsumerActionID    = "httpRequest"
+)
+
+func serviceHookSubscriptionSummary(subscription servicehooks.Subscription) map[string]interface{} {
+	entry := map[string]interface{}{
+		"publisherId":       stringValue(subscription.PublisherId),
+		"eventType":         stringValue(subscription.EventType),
+		"eventDescription":  stringValue(subscription.EventDescription),
+		"consumerId":        stringValue(subscription.ConsumerId),
+		"consumerActionId":  stringValue(subscription.ConsumerActionId),
+		"actionDescription": stringValue(subscription.ActionDescription),
+	}
+	if subscription.Id != nil {
+		entry["id"] = subscription.Id.String()
+	}
+	if subscription.Status != nil {
+		entry["status"] = string(*subscription.Status)
+	}
+	if subscription.PublisherInputs != nil {
+		entry["publisherInputs"] = *subscription.PublisherInputs
+	}
+	if subscription.ConsumerInputs != nil {
+		// Consumer inputs can hold credentials (basic auth passwords, HTTP headers), so only the target is returned.
+		if url, ok := (*subscription.ConsumerInputs)["url"]; ok {
+			entry["url"] = url
+		}
+	}
+	if subscription.CreatedBy != nil {
+		entry["createdBy"] = stringValue(subscription.CreatedBy.DisplayName)
+	}
+	return entry
+}
+
+// listServiceHookSubscriptions returns the service hook subscriptions of the configured project,
+// optionally filtered to one event type.
+func (c *AzureDevOpsClient) listServiceHookSubscriptions(ctx context.Context, eventType string) ([]map[string]interface{}, error) {
+	projectID, err := c.projectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := servicehooks.ListSubscriptionsArgs{}
+	if eventType != "" {
+		args.EventType = &eventType
+	}
+	subscriptions, err := c.serviceHooksClient.ListSubscriptions(ctx, args)
+	if err != nil {
+		log.Printf("Error listing service hook subscriptions: %v", err)
+		return nil, fmt.Errorf("error listing service hook subscriptions: %w", err)
+	}
+
+	results := []map[string]interface{}{}
+	if subscriptions == nil {
+		return results, nil
+	}
+	for _, subscription := range *subscriptions {
+		if subscription.PublisherInputs == nil || (*subscription.PublisherInputs)["projectId"] != projectID {
+			continue
+		}
+		results = append(results, serviceHookSubscriptionSummary(subscription))
+	}
+
+	return results, nil
+}
+
+func (c *AzureDevOpsClient) listServiceHookEventTypes(ctx context.Context, publisher string) ([]map[string]interface{}, error) {
+	if publisher == "" {
+		publisher = defaultServiceHookPublisher
+	}
+
+	eventTypes, err := c.serviceHooksClient.ListEventTypes(ctx, servicehooks.ListEventTypesArgs{
+		PublisherId: &publisher,
+	})
+	if err != nil {
+		log.Printf("Error listing service hook event types: %v", err)
+		return nil, fmt.Errorf("error listing event types for publisher %s: %w", publisher, err)
+	}
+
+	results := []map[string]interface{}{}
+	if eventTypes == nil {
+		return results, nil
+	}
+	for _, eventType := range *eventTypes {
+		inputs := []map[string]interface{}{}
+		if eventType.InputDescriptors != nil {
+			for _, input := range *eventType.InputDescriptors {
+				inputs = append(inputs, map[string]interface{}{
+					"id":          stringValue(input.Id),
+					"name":        stringValue(input.Name),
+					"description": stringValue(input.Description),
+				})
+			}
+		}
+		entry := map[string]interface{}{
+			"id":          stringValue(eventType.Id),
+			"name":        stringValue(eventType.Name),
+			"description": stringValue(eventType.Description),
+			"inputs":      inputs,
+		}
+		if eventType.SupportedResourceVersions != nil {
+			entry["resourceVersions"] = *eventType.SupportedResourceVersions
+		}
+		results = append(results, entry)
+	}
+
+	return results, nil
+}
+
+// createWebHookSubscription subscribes url to an event in the configured project. Publisher
+// inputs narrow the event further (e.g. repository, branch, definitionName). With
+// useWebhookSecret the server's webhook secret is sent as the basic auth password, so the
+// subscription can target this server's own webhook endpoint.
+func (c *AzureDevOpsClient) createWebHookSubscription(ctx context.Context, publisher, eventType, url, resourceVersion string, publisherInputs map[string]string, useWebhookSecret bool, dryRun bool) (map[string]interface{}, error) {
+	projectID, err := c.projectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if publisher == "" {
+		publisher = defaultServiceHookPublisher
+	}
+	if resourceVersion == "" {
+		resourceVersion = "1.0"
+	}
+
+	inputs := map[string]string{}
+	for key, value := range publisherInputs {
+		inputs[key] = value
+	}
+	inputs["projectId"] = projectID
+	consumerInputs := map[string]string{"url": url}
+	if useWebhookSecret {
+		if c.config.Server.WebhookSecret == "" {
+			return nil, fmt.Errorf("no webhook secret is configured")
+		}
+		consumerInputs["basicAuthUsername"] = webhookUsername
+		consumerInputs["basicAuthPassword"] = c.config.Server.WebhookSecret
+	}
+	consumerID := webHooksConsumerID
+	consumerActionID := webHooksConsumerActionID
+	if dryRun {
+		shownInputs := map[string]string{"url": url}
+		if useWebhookSecret {
+			shownInputs["basicAuthUsername"] = webhookUsername
+			shownInputs["basicAuthPassword"] = "(server.webhook_secret)"
+		}
+		return tools.DryRunResult("create service hook subscription", map[string]interface{}{
+			"publisherId":      publisher,
+			"eventType":        eventType,
+			"resourceVersion":  resourceVersion,
+			"consumerId":       consumerID,
+			"consumerActionId": consumerActionID,
+			"publisherInputs":  inputs,
+			"consumerInputs":   shownInputs,
+		}), nil
+	}
+
+	subscription, err := c.serviceHooksClient.CreateSubscription(ctx, servicehooks.CreateSubscriptionArgs{
+		Subscription: &servicehooks.Subscription{
+			PublisherId:      &publisher,
+			EventType:        &eventType,
+			ResourceVersion:  &resourceVersion,
+			ConsumerId:       &consumerID,
+			ConsumerActionId: &consumerActionID,
+			PublisherInputs:  &inputs,
+			ConsumerInputs:   &consumerInputs,
+		},
+	})
+	if err != nil {
+		log.Printf("Error creating service hook subscription: %v", err)
+		return nil, fmt.Errorf("error creating service hook subscription: %w", err)
+	}
+
+	return serviceHookSubscriptionSummary(*subscription), nil
+}
+
+// previewServiceHookDeletion returns the subscription deleteServiceHookSubscription would delete.
+func (c *AzureDevOpsClient) previewServiceHookDeletion(ctx context.Context, id string) (map[string]interface{}, error) {
+	subscriptionID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription ID %s: %w", id, err)
+	}
+
+	subscription, err := c.serviceHooksClient.GetSubscription(ctx, servicehooks.GetSubscriptionArgs{
+		SubscriptionId: &subscriptionID,
+	})
+	if err != nil {
+		log.Printf("Error getting service hook subscription: %v", err)
+		return nil, fmt.Errorf("error getting service hook subscription %s: %w", id, err)
+	}
+
+	return tools.DryRunResult("delete service hook subscription", serviceHookSubscriptionSummary(*subscription)), nil
+}
+
+func (c *AzureDevOpsClient) deleteServiceHookSubscription(ctx context.Context, id string) error {
+	subscriptionID, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("invalid subscription ID %s: %w", id, err)
+	}
+
+	if err := c.serviceHooksClient.DeleteSubscription(ctx, servicehooks.DeleteSubscriptionArgs{
+		SubscriptionId: &subscriptionID,
+	}); err != nil {
+		log.Printf("Error deleting service hook subscription: %v", err)
+		return fmt.Errorf("error deleting service hook subscription %s: %w", id, err)
+	}
+
+	return nil
+}
+
+func addServiceHookTools(s tools.Server, client *AzureDevOpsClient) {
+	listSubscriptionsTool := mcp.NewTool("list_service_hook_subscriptions",
+		mcp.WithDescription("List the service hook subscriptions of the project, e.g. webhooks fired when a pull request is created or a build completes"),
+		mcp.WithString("eventType",
+			mcp.Description("Optional event type filter, e.g. git.pullrequest.created or build.complete"),
+		),
+	)
+
+	s.AddTool(listSubscriptionsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		eventType, _ := request.Params.Arguments["eventType"].(string)
+
+		results, err := client.listServiceHookSubscriptions(ctx, eventType)
+		if err != nil {
+			log.Printf("Error listing service hook subscriptions: %v", err)
+			return nil, fmt.Errorf("error listing service hook subscriptions: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	listEventTypesTool := mcp.NewTool("list_service_hook_event_types",
+		mcp.WithDescription("List the events a service hook publisher can send, with the inputs that filter each event"),
+		mcp.WithString("publisher",
+			mcp.Description("Publisher ID: tfs (code, builds, work items), rm (releases), or pipelines"),
+			mcp.DefaultString(defaultServiceHookPublisher),
+		),
+	)
+
+	s.AddTool(listEventTypesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		publisher, _ := request.Params.Arguments["publisher"].(string)
+
+		results, err := client.listServiceHookEventTypes(ctx, publisher)
+		if err != nil {
+			log.Printf("Error listing service hook event types: %v", err)
+			return nil, fmt.Errorf("error listing service hook event types: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	createSubscriptionTool := mcp.NewTool("create_service_hook_subscription",
+		mcp.WithDescription("Create a webhook subscription that POSTs an event of the project to a URL"),
+		mcp.WithString("eventType",
+			mcp.Required(),
+			mcp.Description("Event type, e.g. git.pullrequest.created or build.complete; see list_service_hook_event_types"),
+		),
+		mcp.WithString("url",
+			mcp.Required(),
+			mcp.Description("URL the event is POSTed to"),
+		),
+		mcp.WithObject("publisherInputs",
+			mcp.Description("Optional event filters, e.g. {\"repository\": \"<repo id>\", \"branch\": \"main\"}"),
+		),
+		mcp.WithString("publisher",
+			mcp.Description("Publisher ID: tfs, rm, or pipelines"),
+			mcp.DefaultString(defaultServiceHookPublisher),
+		),
+		mcp.WithString("resourceVersion",
+			mcp.Description("Event payload version"),
+			mcp.DefaultString("1.0"),
+		),
+		mcp.WithBoolean("useWebhookSecret",
+			mcp.Description("Authenticate with this server's webhook secret, for subscriptions that deliver to its "+webhookPath+" endpoint"),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(createSubscriptionTool, writeHandler(client, createSubscriptionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		eventType, ok := request.Params.Arguments["eventType"].(string)
+		if !ok {
+			log.Print("Event type must be a string")
+			return nil, fmt.Errorf("eventType must be a string")
+		}
+		url, ok := request.Params.Arguments["url"].(string)
+		if !ok {
+			log.Print("URL must be a string")
+			return nil, fmt.Errorf("url must be a string")
+		}
+		publisher, _ := request.Params.Arguments["publisher"].(string)
+		resourceVersion, _ := request.Params.Arguments["resourceVersion"].(string)
+		useWebhookSecret, _ := request.Params.Arguments["useWebhookSecret"].(bool)
+
+		publisherInputs := map[string]string{}
+		if raw, ok := request.Params.Arguments["publisherInputs"].(map[string]interface{}); ok {
+			for key, value := range raw {
+				publisherInputs[key] = fmt.Sprint(value)
+			}
+		}
+
+		result, err := client.createWebHookSubscription(ctx, publisher, eventType, url, resourceVersion, publisherInputs, useWebhookSecret, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error creating service hook subscription: %v", err)
+			return nil, fmt.Errorf("error creating service hook subscription: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	deleteSubscriptionTool := mcp.NewTool("delete_service_hook_subscription",
+		mcp.WithDescription("Delete a service hook subscription"),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("Subscription ID"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(deleteSubscriptionTool, writeHandler(client, deleteSubscriptionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, ok := request.Params.Arguments["id"].(string)
+		if !ok {
+			log.Print("ID must be a string")
+			return nil, fmt.Errorf("id must be a string")
+		}
+
+		if client.dryRun(request) {
+			result, err := client.previewServiceHookDeletion(ctx, id)
+			if err != nil {
+				log.Printf("Error deleting service hook subscription: %v", err)
+				return nil, fmt.Errorf("error deleting service hook subscription: %w", err)
+			}
+			return tools.JSONResult(result)
+		}
+
+		if err := client.deleteServiceHookSubscription(ctx, id); err != nil {
+			log.Printf("Error deleting service hook subscription: %v", err)
+			return nil, fmt.Errorf("error deleting service hook subscription: %w", err)
+		}
+
+		return mcp.NewToolResultText(fmt.Sprintf("Deleted service hook subscription %s", id)), nil
+	}))
+}
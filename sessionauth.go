@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/signify/sgfy-mcp/config"
+)
+
+const (
+	// patHeader and tokenHeader carry a client's own Azure DevOps credential: a personal access
+	// token, or a Microsoft Entra ID bearer token.
+	patHeader   = "X-Azure-DevOps-PAT"
+	tokenHeader = "X-Azure-DevOps-Token"
+)
+
+// credentialKey is the context key of the Authorization header value a tool call's Azure DevOps
+// requests are made with. An empty value marks a call whose client sent no credential.
+type credentialKey struct{}
+
+// clientCredential is the credential a client supplied, in the headers of its request or in the
+// _meta of its initialize request.
+type clientCredential struct {
+	PAT   string `json:"azureDevOpsPat"`
+	Token string `json:"azureDevOpsToken"`
+}
+
+// authorization returns the Authorization header value of the credential, or "" without one.
+func (c clientCredential) authorization() string {
+	switch {
+	case c.Token != "":
+		return "Bearer " + c.Token
+	case c.PAT != "":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(":"+c.PAT))
+	}
+	return ""
+}
+
+func headerCredential(header http.Header) clientCredential {
+	return clientCredential{PAT: header.Get(patHeader), Token: header.Get(tokenHeader)}
+}
+
+// withCredential makes the Azure DevOps requests of a tool call use the client's credential.
+func withCredential(ctx context.Context, credential clientCredential) context.Context {
+	return context.WithValue(ctx, credentialKey{}, credential.authorization())
+}
+
+// credentialID identifies the credential of a tool call without revealing it, so state such as
+// idempotent results is not shared between users. It is empty for the service credential.
+func credentialID(ctx context.Context) string {
+	authorization, _ := ctx.Value(credentialKey{}).(string)
+	if authorization == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(authorization))
+	return hex.EncodeToString(sum[:8])
+}
+
+// sessionAuthTransport swaps the service credential of Azure DevOps requests for the credential
+// of the tool call that made them. The SDK creates its own HTTP clients for each resource area
+// with no way to pass a transport, so this is installed as http.DefaultTransport, which they use;
+// only requests carrying the service credential are touched. Background work such as indexing
+// has no tool call and keeps the service credential.
+type sessionAuthTransport struct {
+	base     http.RoundTripper
+	service  string
+	required bool
+}
+
+func (t *sessionAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	authorization, ok := req.Context().Value(credentialKey{}).(string)
+	if !ok || req.Header.Get("Authorization") != t.service {
+		return t.base.RoundTrip(req)
+	}
+	if authorization == "" {
+		if t.required {
+			return nil, fmt.Errorf("this server requires your own Azure DevOps credential in the %s or %s header", patHeader, tokenHeader)
+		}
+		return t.base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the request it was given.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", authorization)
+	return t.base.RoundTrip(req)
+}
+
+// installSessionAuth routes Azure DevOps requests through sessionAuthTransport for the
+// server.session_auth mode.
+func installSessionAuth(mode, serviceAuthorization string) error {
+	switch mode {
+	case "", config.SessionAuthOff:
+		return nil
+	case config.SessionAuthOptional, config.SessionAuthRequired:
+		http.DefaultTransport = &sessionAuthTransport{
+			base:     http.DefaultTransport,
+			service:  serviceAuthorization,
+			required: mode == config.SessionAuthRequired,
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown session_auth %q; use off, optional, or required", mode)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// sessionContext holds the defaults a session set with set_context. Branch belongs to Repository
+// and only applies to calls on it.
+type sessionContext struct {
+	Project    string `json:"project,omitempty"`
+	Repository string `json:"repository,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+}
+
+// sessionContextTTL is how long the defaults of a session are kept after they were last set.
+const sessionContextTTL = 7 * 24 * time.Hour
+
+// sessionKeyName is the state store key of the key Streamable HTTP session IDs are signed with.
+const sessionKeyName = "session-key"
+
+// sessionContexts stores the defaults of each session in the state store, so every replica of the
+// server sees them. It also issues the session IDs of Streamable HTTP clients, which name their
+// session themselves: an ID is signed with a key in the state store and bound to the credential it
+// was issued to, so a client can neither make one up nor use another client's defaults.
+type sessionContexts struct {
+	store stateStore
+	key   []byte
+}
+
+func newSessionContexts(store stateStore) (*sessionContexts, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("error creating session key: %w", err)
+	}
+	// The first replica to start picks the key; the others use it.
+	if _, err := store.setNX(sessionKeyName, []byte(hex.EncodeToString(secret)), 0); err != nil {
+		return nil, fmt.Errorf("error storing session key: %w", err)
+	}
+	value, ok, err := store.get(sessionKeyName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading session key: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("session key is missing from the state store")
+	}
+	key, err := hex.DecodeString(string(value))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding session key: %w", err)
+	}
+	return &sessionContexts{store: store, key: key}, nil
+}
+
+// newSessionID returns a session ID for the client of a request, usable only with its credential.
+func (s *sessionContexts) newSessionID(ctx context.Context) string {
+	return s.signedSessionID(ctx, uuid.New().String())
+}
+
+// signedSessionID binds an ID, such as that of an SSE session, to the credential of a request.
+func (s *sessionContexts) signedSessionID(ctx context.Context, id string) string {
+	return id + "." + s.signature(ctx, id)
+}
+
+// validSessionID reports whether a session ID was issued by newSessionID or signedSessionID to the
+// credential of a request.
+func (s *sessionContexts) validSessionID(ctx context.Context, sessionID string) bool {
+	id, signature, ok := strings.Cut(sessionID, ".")
+	return ok && hmac.Equal([]byte(signature), []byte(s.signature(ctx, id)))
+}
+
+func (s *sessionContexts) signature(ctx context.Context, id string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(credentialID(ctx) + "/" + id))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// get returns the defaults of the session making a tool call. A store that cannot be read leaves
+// the call without defaults.
+func (s *sessionContexts) get(ctx context.Context) sessionContext {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return sessionContext{}
+	}
+	value, ok, err := s.store.get("context/" + session.SessionID())
+	if err != nil {
+		log.Printf("Error reading session context: %v", err)
+		return sessionContext{}
+	}
+	var defaults sessionContext
+	if ok {
+		if err := json.Unmarshal(value, &defaults); err != nil {
+			log.Printf("Error decoding session context: %v", err)
+		}
+	}
+	return defaults
+}
+
+func (s *sessionContexts) set(sessionID string, defaults sessionContext) error {
+	value, err := json.Marshal(defaults)
+	if err != nil {
+		return fmt.Errorf("error encoding defaults: %w", err)
+	}
+	return s.store.set("context/"+sessionID, value, sessionContextTTL)
+}
+
+// copy gives the session to the defaults of the session from, such as when a client resumes its
+// session on a new stream.
+func (s *sessionContexts) copy(from, to string) error {
+	value, ok, err := s.store.get("context/" + from)
+	if err != nil || !ok {
+		return err
+	}
+	return s.store.set("context/"+to, value, sessionContextTTL)
+}
+
+// sessionProject returns the project the session set with set_context, or the configured one.
+func (c *AzureDevOpsClient) sessionProject(ctx context.Context) string {
+	if project := c.contexts.get(ctx).Project; project != "" {
+		return project
+	}
+	return c.config.AzureDevOps.Project
+}
+
+// sessionRepository fills in the session's repository when repoName is empty, and the session's
+// branch when ref is empty and the repository is the session's.
+func (c *AzureDevOpsClient) sessionRepository(ctx context.Context, repoName, ref string) (string, string) {
+	defaults := c.contexts.get(ctx)
+	if repoName == "" {
+		repoName = defaults.Repository
+	}
+	if ref == "" && strings.EqualFold(repoName, defaults.Repository) {
+		ref = defaults.Branch
+	}
+	return repoName, ref
+}
+
+// setSessionContext validates and stores defaults for the session making the call. Empty values
+// clear a default; omitted ones keep it.
+func (c *AzureDevOpsClient) setSessionContext(ctx context.Context, arguments map[string]interface{}) (sessionContext, error) {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return sessionContext{}, fmt.Errorf("defaults need a client session")
+	}
+	defaults := c.contexts.get(ctx)
+	if project, ok := arguments["project"].(string); ok {
+		defaults.Project = project
+	}
+	if repo, ok := arguments["repository"].(string); ok {
+		if !strings.EqualFold(repo, defaults.Repository) {
+			defaults.Branch = ""
+		}
+		defaults.Repository = repo
+	}
+	if branch, ok := arguments["branch"].(string); ok {
+		defaults.Branch = branch
+	}
+	if defaults.Branch != "" && defaults.Repository == "" {
+		return sessionContext{}, fmt.Errorf("a default branch needs a default repository")
+	}
+
+	project := c.config.AzureDevOps.Project
+	if defaults.Project != "" {
+		found, err := c.coreClient.GetProject(ctx, core.GetProjectArgs{ProjectId: &defaults.Project})
+		if err != nil {
+			log.Printf("Error getting project: %v", err)
+			return sessionContext{}, fmt.Errorf("error getting project %s: %w", defaults.Project, err)
+		}
+		defaults.Project = stringValue(found.Name)
+		project = defaults.Project
+	}
+	if defaults.Repository != "" {
+		repo, err := c.findRepositoryIn(ctx, project, defaults.Repository)
+		if err != nil {
+			return sessionContext{}, err
+		}
+		defaults.Repository = stringValue(repo.Name)
+		if defaults.Branch != "" {
+			repoID := repo.Id.String()
+			name := strings.TrimPrefix(branchRef(defaults.Branch), "refs/heads/")
+			if _, err := c.gitClient.GetBranch(ctx, git.GetBranchArgs{
+				RepositoryId: &repoID,
+				Name:         &name,
+				Project:      &project,
+			}); err != nil {
+				log.Printf("Error getting branch: %v", err)
+				return sessionContext{}, fmt.Errorf("error getting branch %s of %s: %w", defaults.Branch, defaults.Repository, err)
+			}
+			defaults.Branch = name
+		}
+	}
+
+	if err := c.contexts.set(session.SessionID(), defaults); err != nil {
+		log.Printf("Error storing session context: %v", err)
+		return sessionContext{}, err
+	}
+	return defaults, nil
+}
+
+func addContextTools(s tools.Server, client *AzureDevOpsClient) {
+	setContextTool := mcp.NewTool("set_context",
+		mcp.WithDescription("Set defaults for this session, so search, read, and read_files calls can omit them: a project (instead of the configured one), a repository, and a branch of that repository. Omitted values are kept; pass an empty string to clear one"),
+		mcp.WithString("project",
+			mcp.Description("Default project for search and read"),
+		),
+		mcp.WithString("repository",
+			mcp.Description("Default repository name"),
+		),
+		mcp.WithString("branch",
+			mcp.Description("Default branch of the default repository"),
+		),
+	)
+
+	s.AddTool(setContextTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		defaults, err := client.setSessionContext(ctx, request.Params.Arguments)
+		if err != nil {
+			log.Printf("Error setting context: %v", err)
+			return nil, fmt.Errorf("error setting context: %w", err)
+		}
+
+		return tools.JSONResult(defaults)
+	})
+
+	getContextTool := mcp.NewTool("get_context",
+		mcp.WithDescription("Get the defaults set for this session with set_context, and the project calls use"),
+	)
+
+	s.AddTool(getContextTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return tools.JSONResult(map[string]interface{}{
+			"defaults":         client.contexts.get(ctx),
+			"effectiveProject": client.sessionProject(ctx),
+		})
+	})
+}
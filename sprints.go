@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/work"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const remainingWorkField = "Microsoft.VSTS.Scheduling.RemainingWork"
+
+func iterationSummary(iteration work.TeamSettingsIteration) map[string]interface{} {
+	summary := map[string]interface{}{
+		"name": stringValue(iteration.Name),
+		"path": stringValue(iteration.Path),
+	}
+	if iteration.Id != nil {
+		summary["id"] = iteration.Id.String()
+	}
+	if attributes := iteration.Attributes; attributes != nil {
+		if attributes.StartDate != nil {
+			summary["startDate"] = attributes.StartDate.Time.Format("2006-01-02")
+		}
+		if attributes.FinishDate != nil {
+			summary["finishDate"] = attributes.FinishDate.Time.Format("2006-01-02")
+		}
+		if attributes.TimeFrame != nil {
+			summary["timeFrame"] = string(*attributes.TimeFrame)
+		}
+	}
+	return summary
+}
+
+func (c *AzureDevOpsClient) listIterations(ctx context.Context, team, timeframe string) ([]map[string]interface{}, error) {
+	team = c.teamName(team)
+	args := work.GetTeamIterationsArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Team:    &team,
+	}
+	if timeframe != "" {
+		args.Timeframe = &timeframe
+	}
+
+	iterations, err := c.workClient.GetTeamIterations(ctx, args)
+	if err != nil {
+		log.Printf("Error getting team iterations: %v", err)
+		return nil, fmt.Errorf("error getting iterations for team %s: %w", team, err)
+	}
+
+	results := []map[string]interface{}{}
+	if iterations == nil {
+		return results, nil
+	}
+	for _, iteration := range *iterations {
+		results = append(results, iterationSummary(iteration))
+	}
+
+	return results, nil
+}
+
+// resolveIteration finds a team iteration by ID, name, or path. An empty value or "current"
+// selects the team's current sprint.
+func (c *AzureDevOpsClient) resolveIteration(ctx context.Context, team, iteration string) (*work.TeamSettingsIteration, error) {
+	args := work.GetTeamIterationsArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Team:    &team,
+	}
+	current := iteration == "" || strings.EqualFold(iteration, "current")
+	if current {
+		timeframe := "current"
+		args.Timeframe = &timeframe
+	}
+
+	iterations, err := c.workClient.GetTeamIterations(ctx, args)
+	if err != nil {
+		log.Printf("Error getting team iterations: %v", err)
+		return nil, fmt.Errorf("error getting iterations for team %s: %w", team, err)
+	}
+	if iterations != nil {
+		for _, candidate := range *iterations {
+			if candidate.Id == nil {
+				continue
+			}
+			if current ||
+				strings.EqualFold(candidate.Id.String(), iteration) ||
+				strings.EqualFold(stringValue(candidate.Name), iteration) ||
+				strings.EqualFold(stringValue(candidate.Path), iteration) {
+				return &candidate, nil
+			}
+		}
+	}
+
+	if current {
+		return nil, fmt.Errorf("team %s has no current iteration", team)
+	}
+	return nil, fmt.Errorf("iteration not found for team %s: %s", team, iteration)
+}
+
+func (c *AzureDevOpsClient) getIterationWorkItemIDs(ctx context.Context, team string, iteration *work.TeamSettingsIteration) ([]int, error) {
+	links, err := c.workClient.GetIterationWorkItems(ctx, work.GetIterationWorkItemsArgs{
+		Project:     &c.config.AzureDevOps.Project,
+		IterationId: iteration.Id,
+		Team:        &team,
+	})
+	if err != nil {
+		log.Printf("Error getting iteration work items: %v", err)
+		return nil, fmt.Errorf("error getting work items for iteration %s: %w", stringValue(iteration.Name), err)
+	}
+
+	ids := []int{}
+	seen := map[int]bool{}
+	if links.WorkItemRelations != nil {
+		for _, link := range *links.WorkItemRelations {
+			if link.Target == nil || link.Target.Id == nil || seen[*link.Target.Id] {
+				continue
+			}
+			seen[*link.Target.Id] = true
+			ids = append(ids, *link.Target.Id)
+		}
+	}
+
+	return ids, nil
+}
+
+func (c *AzureDevOpsClient) getIterationWorkItems(ctx context.Context, team, iterationName string) (map[string]interface{}, error) {
+	team = c.teamName(team)
+	iteration, err := c.resolveIteration(ctx, team, iterationName)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := c.getIterationWorkItemIDs(ctx, team, iteration)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := c.getWorkItemsBatch(ctx, ids, []string{"System.WorkItemType", "System.Title", "System.State", "System.AssignedTo", remainingWorkField})
+	if err != nil {
+		return nil, err
+	}
+
+	workItems := []map[string]interface{}{}
+	totalRemaining := 0.0
+	for _, item := range items {
+		summary := c.workItemSummary(item)
+		if item.Fields != nil {
+			if remaining, ok := (*item.Fields)[remainingWorkField].(float64); ok {
+				summary["remainingWork"] = remaining
+				totalRemaining += remaining
+			}
+		}
+		workItems = append(workItems, summary)
+	}
+
+	return map[string]interface{}{
+		"iteration":     iterationSummary(*iteration),
+		"workItems":     workItems,
+		"remainingWork": totalRemaining,
+	}, nil
+}
+
+// teamWorkingDays returns the weekdays the team works, defaulting to Monday through Friday.
+func (c *AzureDevOpsClient) teamWorkingDays(ctx context.Context, team string) (map[time.Weekday]bool, error) {
+	settings, err := c.workClient.GetTeamSettings(ctx, work.GetTeamSettingsArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Team:    &team,
+	})
+	if err != nil {
+		log.Printf("Error getting team settings: %v", err)
+		return nil, fmt.Errorf("error getting settings for team %s: %w", team, err)
+	}
+
+	days := map[time.Weekday]bool{}
+	if settings.WorkingDays != nil {
+		for _, name := range *settings.WorkingDays {
+			for d := time.Sunday; d <= time.Saturday; d++ {
+				if strings.EqualFold(d.String(), name) {
+					days[d] = true
+				}
+			}
+		}
+	}
+	if len(days) == 0 {
+		for d := time.Monday; d <= time.Friday; d++ {
+			days[d] = true
+		}
+	}
+
+	return days, nil
+}
+
+func (c *AzureDevOpsClient) getTeamDaysOff(ctx context.Context, team string, iteration *work.TeamSettingsIteration) ([]work.DateRange, error) {
+	daysOff, err := c.workClient.GetTeamDaysOff(ctx, work.GetTeamDaysOffArgs{
+		Project:     &c.config.AzureDevOps.Project,
+		IterationId: iteration.Id,
+		Team:        &team,
+	})
+	if err != nil {
+		log.Printf("Error getting team days off: %v", err)
+		return nil, fmt.Errorf("error getting team days off: %w", err)
+	}
+	if daysOff.DaysOff == nil {
+		return []work.DateRange{}, nil
+	}
+	return *daysOff.DaysOff, nil
+}
+
+// iterationWorkingDays lists the dates in the iteration that fall on working days and are not days off.
+func iterationWorkingDays(iteration *work.TeamSettingsIteration, workingDays map[time.Weekday]bool, daysOff []work.DateRange) []time.Time {
+	days := []time.Time{}
+	if iteration.Attributes == nil || iteration.Attributes.StartDate == nil || iteration.Attributes.FinishDate == nil {
+		return days
+	}
+	start := iteration.Attributes.StartDate.Time.UTC()
+	finish := iteration.Attributes.FinishDate.Time.UTC()
+	for day := start; !day.After(finish); day = day.AddDate(0, 0, 1) {
+		if workingDays[day.Weekday()] && !inDateRanges(day, daysOff) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+func inDateRanges(day time.Time, ranges []work.DateRange) bool {
+	for _, r := range ranges {
+		if r.Start == nil || r.End == nil {
+			continue
+		}
+		if !day.Before(r.Start.Time.UTC()) && !day.After(r.End.Time.UTC()) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatDateRanges(ranges []work.DateRange) []map[string]string {
+	results := []map[string]string{}
+	for _, r := range ranges {
+		if r.Start == nil || r.End == nil {
+			continue
+		}
+		results = append(results, map[string]string{
+			"start": r.Start.Time.Format("2006-01-02"),
+			"end":   r.End.Time.Format("2006-01-02"),
+		})
+	}
+	return results
+}
+
+// getIterationCapacity returns per-member capacity for an iteration, with totals that account
+// for weekends, team days off, and each member's own days off.
+func (c *AzureDevOpsClient) getIterationCapacity(ctx context.Context, team, iterationName string) (map[string]interface{}, error) {
+	team = c.teamName(team)
+	iteration, err := c.resolveIteration(ctx, team, iterationName)
+	if err != nil {
+		return nil, err
+	}
+
+	capacities, err := c.workClient.GetCapacitiesWithIdentityRefAndTotals(ctx, work.GetCapacitiesWithIdentityRefAndTotalsArgs{
+		Project:     &c.config.AzureDevOps.Project,
+		IterationId: iteration.Id,
+		Team:        &team,
+	})
+	if err != nil {
+		log.Printf("Error getting team capacity: %v", err)
+		return nil, fmt.Errorf("error getting capacity for iteration %s: %w", stringValue(iteration.Name), err)
+	}
+
+	teamDaysOff, err := c.getTeamDaysOff(ctx, team, iteration)
+	if err != nil {
+		return nil, err
+	}
+
+	workingDays, err := c.teamWorkingDays(ctx, team)
+	if err != nil {
+		return nil, err
+	}
+	teamDays := iterationWorkingDays(iteration, workingDays, teamDaysOff)
+
+	members := []map[string]interface{}{}
+	teamTotal := 0.0
+	if capacities != nil && capacities.TeamMembers != nil {
+		for _, capacity := range *capacities.TeamMembers {
+			perDay := 0.0
+			activities := []map[string]interface{}{}
+			if capacity.Activities != nil {
+				for _, activity := range *capacity.Activities {
+					if activity.CapacityPerDay == nil {
+						continue
+					}
+					perDay += float64(*activity.CapacityPerDay)
+					activities = append(activities, map[string]interface{}{
+						"name":           stringValue(activity.Name),
+						"capacityPerDay": *activity.CapacityPerDay,
+					})
+				}
+			}
+
+			memberDaysOff := []work.DateRange{}
+			if capacity.DaysOff != nil {
+				memberDaysOff = *capacity.DaysOff
+			}
+			available := 0
+			for _, day := range teamDays {
+				if !inDateRanges(day, memberDaysOff) {
+					available++
+				}
+			}
+
+			name := ""
+			if capacity.TeamMember != nil {
+				name = stringValue(capacity.TeamMember.DisplayName)
+			}
+			total := perDay * float64(available)
+			teamTotal += total
+			members = append(members, map[string]interface{}{
+				"name":           name,
+				"activities":     activities,
+				"capacityPerDay": perDay,
+				"daysOff":        formatDateRanges(memberDaysOff),
+				"availableDays":  available,
+				"totalCapacity":  total,
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"iteration":     iterationSummary(*iteration),
+		"workingDays":   len(teamDays),
+		"teamDaysOff":   formatDateRanges(teamDaysOff),
+		"members":       members,
+		"totalCapacity": teamTotal,
+	}, nil
+}
+
+// getIterationBurndown reconstructs the sprint's remaining work for each working day so far by
+// reading the iteration's work items as of the end of that day. Only items that are in the
+// iteration now are considered, so work moved out mid-sprint is not reflected.
+func (c *AzureDevOpsClient) getIterationBurndown(ctx context.Context, team, iterationName string) (map[string]interface{}, error) {
+	team = c.teamName(team)
+	iteration, err := c.resolveIteration(ctx, team, iterationName)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := c.getIterationWorkItemIDs(ctx, team, iteration)
+	if err != nil {
+		return nil, err
+	}
+
+	teamDaysOff, err := c.getTeamDaysOff(ctx, team, iteration)
+	if err != nil {
+		return nil, err
+	}
+	workingDays, err := c.teamWorkingDays(ctx, team)
+	if err != nil {
+		return nil, err
+	}
+	days := iterationWorkingDays(iteration, workingDays, teamDaysOff)
+
+	now := time.Now().UTC()
+	points := []map[string]interface{}{}
+	for _, day := range days {
+		if day.After(now) {
+			break
+		}
+		asOf := day.AddDate(0, 0, 1).Add(-time.Second)
+		if asOf.After(now) {
+			asOf = now
+		}
+
+		items, err := c.getWorkItemsBatchAsOf(ctx, ids, []string{"System.IterationPath", remainingWorkField}, &asOf)
+		if err != nil {
+			return nil, err
+		}
+
+		remaining := 0.0
+		for _, item := range items {
+			if item.Fields == nil {
+				continue
+			}
+			if path, _ := (*item.Fields)["System.IterationPath"].(string); !strings.EqualFold(path, stringValue(iteration.Path)) {
+				continue
+			}
+			if value, ok := (*item.Fields)[remainingWorkField].(float64); ok {
+				remaining += value
+			}
+		}
+		points = append(points, map[string]interface{}{
+			"date":          day.Format("2006-01-02"),
+			"remainingWork": remaining,
+		})
+	}
+
+	// The ideal trend burns the first day's remaining work down linearly to zero on the last working day.
+	if len(points) > 0 && len(days) > 1 {
+		start := points[0]["remainingWork"].(float64)
+		for i, point := range points {
+			point["idealRemainingWork"] = start * float64(len(days)-1-i) / float64(len(days)-1)
+		}
+	}
+
+	return map[string]interface{}{
+		"iteration":   iterationSummary(*iteration),
+		"workingDays": len(days),
+		"burndown":    points,
+	}, nil
+}
+
+func addSprintTools(s tools.Server, client *AzureDevOpsClient) {
+	listIterationsTool := mcp.NewTool("list_iterations",
+		mcp.WithDescription("List the iterations (sprints) of a team with their dates"),
+		mcp.WithString("timeframe",
+			mcp.Description("Optional filter; only 'current' is supported by Azure DevOps"),
+			mcp.Enum("current"),
+		),
+		mcp.WithString("team",
+			mcp.Description("Optional team name, defaults to the configured team"),
+		),
+	)
+
+	s.AddTool(listIterationsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		timeframe, _ := request.Params.Arguments["timeframe"].(string)
+		team, _ := request.Params.Arguments["team"].(string)
+
+		results, err := client.listIterations(ctx, team, timeframe)
+		if err != nil {
+			log.Printf("Error listing iterations: %v", err)
+			return nil, fmt.Errorf("error listing iterations: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	iterationOptions := []mcp.ToolOption{
+		mcp.WithString("iteration",
+			mcp.Description("Iteration ID, name, or path; defaults to the current sprint"),
+		),
+		mcp.WithString("team",
+			mcp.Description("Optional team name, defaults to the configured team"),
+		),
+	}
+
+	iterationWorkItemsTool := mcp.NewTool("get_iteration_work_items",
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Get the work items planned in a sprint with their state, assignee, and remaining work"),
+		}, iterationOptions...)...,
+	)
+
+	s.AddTool(iterationWorkItemsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		iteration, _ := request.Params.Arguments["iteration"].(string)
+		team, _ := request.Params.Arguments["team"].(string)
+
+		result, err := client.getIterationWorkItems(ctx, team, iteration)
+		if err != nil {
+			log.Printf("Error getting iteration work items: %v", err)
+			return nil, fmt.Errorf("error getting iteration work items: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	capacityTool := mcp.NewTool("get_iteration_capacity",
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Get team capacity for a sprint: per-member capacity per day, activities, days off, and total available hours"),
+		}, iterationOptions...)...,
+	)
+
+	s.AddTool(capacityTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		iteration, _ := request.Params.Arguments["iteration"].(string)
+		team, _ := request.Params.Arguments["team"].(string)
+
+		result, err := client.getIterationCapacity(ctx, team, iteration)
+		if err != nil {
+			log.Printf("Error getting iteration capacity: %v", err)
+			return nil, fmt.Errorf("error getting iteration capacity: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	burndownTool := mcp.NewTool("get_iteration_burndown",
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Get the remaining-work burndown of a sprint for each working day so far, with the ideal trend"),
+		}, iterationOptions...)...,
+	)
+
+	s.AddTool(burndownTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		iteration, _ := request.Params.Arguments["iteration"].(string)
+		team, _ := request.Params.Arguments["team"].(string)
+
+		result, err := client.getIterationBurndown(ctx, team, iteration)
+		if err != nil {
+			log.Printf("Error getting iteration burndown: %v", err)
+			return nil, fmt.Errorf("error getting iteration burndown: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/signify/sgfy-mcp/config"
+)
+
+// sseHeartbeat is the SSE comment written to keep an idle stream open; clients ignore comments.
+var sseHeartbeat = []byte(": keep-alive\n\n")
+
+// sseStream is an SSE session as its client knows it. A resumed session keeps the ID the client
+// first got, while each stream opened for it is a new session of the SSE server.
+type sseStream struct {
+	// session is the SSE server's session of the current or last stream.
+	session string
+	// cancel closes the current stream; it is nil once the stream has ended.
+	cancel      context.CancelFunc
+	ended       time.Time
+	lastMessage time.Time
+	// resumed is set until the new session of a resumed stream has been marked initialized.
+	resumed bool
+}
+
+// sseStreams keeps SSE sessions alive through proxies that drop quiet connections: it writes a
+// heartbeat comment to each stream, closes sessions whose client has gone quiet, and lets a
+// client that lost its stream reconnect to its session by passing its session ID to the SSE
+// endpoint, keeping its capabilities, credential, roots, and set_context defaults. mcp-go gives
+// every stream a new session, so the endpoint event of each stream is rewritten to the session ID
+// its client uses, and its messages routed to the current session. Client session IDs are signed
+// and bound to the credential the stream was opened with, like those of Streamable HTTP, so only
+// that credential can resume a session, and only once its stream has dropped. Requests that were
+// running when the stream dropped are not replayed, and event watches must be made again.
+type sseStreams struct {
+	next      http.Handler
+	sse       *server.SSEServer
+	requests  *clientRequests
+	contexts  *sessionContexts
+	heartbeat time.Duration
+	idle      time.Duration
+	resume    time.Duration
+
+	mu      sync.Mutex
+	streams map[string]*sseStream
+	// clientIDs maps the SSE server's sessions to the session IDs their clients use.
+	clientIDs map[string]string
+}
+
+func newSSEStreams(next http.Handler, sse *server.SSEServer, requests *clientRequests, contexts *sessionContexts, config config.SSEConfig) *sseStreams {
+	return &sseStreams{
+		next:      next,
+		sse:       sse,
+		requests:  requests,
+		contexts:  contexts,
+		heartbeat: time.Duration(config.HeartbeatSeconds) * time.Second,
+		idle:      time.Duration(config.IdleTimeoutMinutes) * time.Minute,
+		resume:    time.Duration(config.ResumeSeconds) * time.Second,
+		streams:   map[string]*sseStream{},
+		clientIDs: map[string]string{},
+	}
+}
+
+func (s *sseStreams) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == s.sse.CompleteSsePath():
+		s.serveStream(w, req)
+	case req.Method == http.MethodPost && req.URL.Path == s.sse.CompleteMessagePath():
+		s.next.ServeHTTP(w, s.route(req))
+	default:
+		s.next.ServeHTTP(w, req)
+	}
+}
+
+// route sends a message to the current session of the client's session ID.
+func (s *sseStreams) route(req *http.Request) *http.Request {
+	query := req.URL.Query()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream, ok := s.streams[query.Get("sessionId")]
+	if !ok {
+		return req
+	}
+	stream.lastMessage = time.Now()
+	if stream.session == query.Get("sessionId") {
+		return req
+	}
+	req = req.Clone(req.Context())
+	query.Set("sessionId", stream.session)
+	req.URL.RawQuery = query.Encode()
+	return req
+}
+
+// withContext marks the session of a resumed stream initialized on its first message, since its
+// client does not initialize again. It runs before the SSE server handles the message.
+func (s *sseStreams) withContext(ctx context.Context, req *http.Request) context.Context {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return ctx
+	}
+	s.mu.Lock()
+	stream, ok := s.streams[s.clientIDs[session.SessionID()]]
+	resumed := ok && stream.resumed && stream.session == session.SessionID()
+	if resumed {
+		stream.resumed = false
+	}
+	s.mu.Unlock()
+	if resumed {
+		session.Initialize()
+	}
+	return ctx
+}
+
+func (s *sseStreams) serveStream(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.next.ServeHTTP(w, req)
+		return
+	}
+	resumeID := req.URL.Query().Get("sessionId")
+	if resumeID != "" {
+		if status, err := s.resumable(req, resumeID); err != nil {
+			log.Printf("Error resuming SSE session: %v", err)
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	credentialCtx := s.credentialContext(req)
+	writer := &sseWriter{ResponseWriter: w, flusher: flusher}
+	writer.rewrite = func(session string, event []byte) []byte {
+		clientID := s.attach(credentialCtx, session, resumeID, cancel)
+		return bytes.Replace(event, []byte("sessionId="+session), []byte("sessionId="+clientID), 1)
+	}
+
+	// Ask proxies such as nginx not to buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	go s.keepAlive(ctx, cancel, writer)
+	s.next.ServeHTTP(writer, req.WithContext(ctx))
+	s.detach(writer.session())
+}
+
+// keepAlive writes heartbeats to a stream, and closes it once its client has sent nothing for
+// longer than the idle timeout.
+func (s *sseStreams) keepAlive(ctx context.Context, cancel context.CancelFunc, writer *sseWriter) {
+	interval := s.heartbeat
+	if interval <= 0 {
+		if s.idle <= 0 {
+			return
+		}
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		id := writer.session()
+		if s.idle > 0 && id != "" && s.idleSince(id) > s.idle {
+			log.Printf("Closing SSE session %s: no messages for %s", s.clientID(id), s.idle)
+			cancel()
+			return
+		}
+		if s.heartbeat > 0 {
+			if err := writer.ping(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// credentialContext returns the context of a stream's request with the credential of its headers,
+// which its session ID is bound to.
+func (s *sseStreams) credentialContext(req *http.Request) context.Context {
+	if !s.requests.sessionAuth {
+		return req.Context()
+	}
+	return withCredential(req.Context(), headerCredential(req.Header))
+}
+
+// resumable checks that a stream may resume the session of resumeID before it is opened: the
+// session's own stream must have dropped, and the stream must bring the session's credential. That
+// is the one the client passed in its initialize request, or else the one its session ID was
+// signed for. An ID that is unknown or has expired is not an error when it was issued to the
+// stream's credential; the stream gets a new session.
+func (s *sseStreams) resumable(req *http.Request, resumeID string) (int, error) {
+	signed := s.contexts.validSessionID(s.credentialContext(req), resumeID)
+	s.mu.Lock()
+	stream, ok := s.streams[resumeID]
+	var session string
+	var connected bool
+	if ok {
+		session, connected = stream.session, stream.cancel != nil
+	}
+	s.mu.Unlock()
+
+	owned := signed
+	if initialized := s.requests.credential(session).authorization(); ok && initialized != "" {
+		owned = initialized == headerCredential(req.Header).authorization()
+	}
+	switch {
+	case !owned:
+		return http.StatusNotFound, fmt.Errorf("session %q was not issued to this credential", resumeID)
+	case connected:
+		return http.StatusConflict, fmt.Errorf("session %q is still connected", resumeID)
+	}
+	return 0, nil
+}
+
+// attach records the new session of a stream, and returns the session ID its client is to use:
+// that of the session it resumes, when resumable allowed it and the session's stream has not come
+// back in the meantime, or else a new one signed for the stream's credential.
+func (s *sseStreams) attach(ctx context.Context, session, resumeID string, cancel context.CancelFunc) string {
+	now := time.Now()
+	s.mu.Lock()
+	for id, stream := range s.streams {
+		if stream.cancel == nil && now.Sub(stream.ended) > s.resume {
+			delete(s.streams, id)
+		}
+	}
+	stream, ok := s.streams[resumeID]
+	if !ok || stream.cancel != nil || s.resume <= 0 {
+		clientID := s.contexts.signedSessionID(ctx, session)
+		s.streams[clientID] = &sseStream{session: session, cancel: cancel, lastMessage: now}
+		s.clientIDs[session] = clientID
+		s.mu.Unlock()
+		return clientID
+	}
+
+	previous := stream.session
+	stream.session = session
+	stream.cancel = cancel
+	stream.lastMessage = now
+	stream.resumed = true
+	s.clientIDs[session] = resumeID
+	s.mu.Unlock()
+
+	s.requests.resume(previous, session)
+	if err := s.contexts.copy(previous, session); err != nil {
+		log.Printf("Error copying session context: %v", err)
+	}
+	log.Printf("Resumed SSE session %s", resumeID)
+	return resumeID
+}
+
+// detach records that the stream of a session ended; its client may resume it for a while.
+func (s *sseStreams) detach(session string) {
+	if session == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clientID := s.clientIDs[session]
+	delete(s.clientIDs, session)
+	// A resumed session has moved on to a newer stream already
+	if stream, ok := s.streams[clientID]; ok && stream.session == session {
+		stream.cancel = nil
+		stream.ended = time.Now()
+	}
+}
+
+func (s *sseStreams) idleSince(session string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream, ok := s.streams[s.clientIDs[session]]
+	if !ok {
+		return 0
+	}
+	return time.Since(stream.lastMessage)
+}
+
+func (s *sseStreams) clientID(session string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clientIDs[session]
+}
+
+// endpointSession returns the session ID of an SSE endpoint event, or "" for other events.
+func endpointSession(event []byte) string {
+	if !bytes.HasPrefix(event, []byte("event: endpoint")) {
+		return ""
+	}
+	_, rest, ok := bytes.Cut(event, []byte("sessionId="))
+	if !ok {
+		return ""
+	}
+	if end := bytes.IndexAny(rest, "&\r\n"); end >= 0 {
+		rest = rest[:end]
+	}
+	return string(rest)
+}
+
+// sseWriter lets heartbeats be written to a stream between the SSE server's events, and passes
+// the stream's first event, which names the message endpoint of its session, through rewrite.
+type sseWriter struct {
+	http.ResponseWriter
+	flusher http.Flusher
+	rewrite func(session string, event []byte) []byte
+
+	mu      sync.Mutex
+	started bool
+	id      string
+}
+
+func (w *sseWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	event := p
+	if !w.started {
+		if w.id = endpointSession(p); w.id != "" {
+			event = w.rewrite(w.id, p)
+		}
+	}
+	w.started = true
+	if _, err := w.ResponseWriter.Write(event); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *sseWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flusher.Flush()
+}
+
+// ping writes a heartbeat, once the stream has started.
+func (w *sseWriter) ping() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.started {
+		return nil
+	}
+	if _, err := w.ResponseWriter.Write(sseHeartbeat); err != nil {
+		return err
+	}
+	w.flusher.Flush()
+	return nil
+}
+
+// session returns the SSE server's session of the stream, or "" before its endpoint event.
+func (w *sseWriter) session() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.id
+}
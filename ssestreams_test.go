@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/signify/sgfy-mcp/config"
+)
+
+func TestSSEResumeIsBoundToCredential(t *testing.T) {
+	contexts, err := newSessionContexts(newMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	streams := newSSEStreams(nil, nil, newClientRequests(true), contexts, config.SSEConfig{ResumeSeconds: 300})
+	resumeRequest := func(id, pat string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/sse?sessionId="+id, nil)
+		if pat != "" {
+			req.Header.Set(patHeader, pat)
+		}
+		return req
+	}
+
+	_, cancel := context.WithCancel(context.Background())
+	owner := streams.credentialContext(resumeRequest("", "alice"))
+	id := streams.attach(owner, "session-1", "", cancel)
+	if id == "session-1" {
+		t.Fatalf("attach returned the unsigned session ID")
+	}
+
+	if status, err := streams.resumable(resumeRequest(id, "alice"), id); status != http.StatusConflict || err == nil {
+		t.Errorf("resuming a connected session = %d, %v; want 409", status, err)
+	}
+	streams.detach("session-1")
+	if status, err := streams.resumable(resumeRequest(id, "mallory"), id); status != http.StatusNotFound || err == nil {
+		t.Errorf("resuming with another credential = %d, %v; want 404", status, err)
+	}
+	if status, err := streams.resumable(resumeRequest(id, ""), id); status != http.StatusNotFound || err == nil {
+		t.Errorf("resuming without a credential = %d, %v; want 404", status, err)
+	}
+	if _, err := streams.resumable(resumeRequest(id, "alice"), id); err != nil {
+		t.Errorf("resuming with the owner's credential: %v", err)
+	}
+	if got := streams.attach(owner, "session-2", id, cancel); got != id {
+		t.Errorf("attach resumed as %q, want %q", got, id)
+	}
+}
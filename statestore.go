@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/signify/sgfy-mcp/config"
+)
+
+// stateStore holds state that must be the same on every replica of the server: session defaults
+// and idempotent write results. Unlike the content cache, values change under their key.
+type stateStore interface {
+	get(key string) ([]byte, bool, error)
+	// set stores a value; a zero ttl keeps it until it is deleted.
+	set(key string, value []byte, ttl time.Duration) error
+	// setNX stores a value only if the key is unset, and reports whether it did.
+	setNX(key string, value []byte, ttl time.Duration) (bool, error)
+	delete(key string) error
+}
+
+// newStateStore returns the store selected by server.state_store: this process's memory, or the
+// Redis server of the cache so that replicas behind a load balancer share it.
+func newStateStore(backend string, redis config.RedisConfig) (stateStore, error) {
+	switch backend {
+	case "", "memory":
+		return newMemoryStore(), nil
+	case "redis":
+		cache, err := newRedisCache(redis)
+		if err != nil {
+			return nil, err
+		}
+		return &redisStore{redis: cache}, nil
+	}
+	return nil, fmt.Errorf("unknown state store %q; use memory or redis", backend)
+}
+
+// memorySweepInterval is how often a memoryStore drops all the keys whose time is up.
+const memorySweepInterval = 10 * time.Minute
+
+// memoryStore is a stateStore for a single server process.
+type memoryStore struct {
+	mu      sync.Mutex
+	values  map[string][]byte
+	expires map[string]time.Time
+	swept   time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: map[string][]byte{}, expires: map[string]time.Time{}, swept: time.Now()}
+}
+
+func (s *memoryStore) get(key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(key)
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+func (s *memoryStore) set(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store(key, value, ttl)
+	return nil
+}
+
+func (s *memoryStore) setNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(key)
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+	s.store(key, value, ttl)
+	return true, nil
+}
+
+func (s *memoryStore) delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	delete(s.expires, key)
+	return nil
+}
+
+func (s *memoryStore) store(key string, value []byte, ttl time.Duration) {
+	s.sweep()
+	s.values[key] = value
+	delete(s.expires, key)
+	if ttl > 0 {
+		s.expires[key] = time.Now().Add(ttl)
+	}
+}
+
+// expire drops a key whose time is up, so it is not read after it expired.
+func (s *memoryStore) expire(key string) {
+	if expires, ok := s.expires[key]; ok && time.Now().After(expires) {
+		delete(s.values, key)
+		delete(s.expires, key)
+	}
+}
+
+// sweep drops every key whose time is up, at most once per memorySweepInterval. Most keys, such as
+// idempotency keys, are never read again, so expiring them as they are read would keep them for
+// the life of the process.
+func (s *memoryStore) sweep() {
+	now := time.Now()
+	if now.Sub(s.swept) < memorySweepInterval {
+		return
+	}
+	s.swept = now
+	for key, expires := range s.expires {
+		if now.After(expires) {
+			delete(s.values, key)
+			delete(s.expires, key)
+		}
+	}
+}
+
+// redisStore is a stateStore shared through Redis. Its keys sit under the cache's key prefix,
+// apart from cache entries.
+type redisStore struct {
+	redis *redisCache
+}
+
+func (s *redisStore) key(key string) string {
+	return s.redis.config.KeyPrefix + "state/" + key
+}
+
+func (s *redisStore) get(key string) ([]byte, bool, error) {
+	value, err := s.redis.do("GET", s.key(key))
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading %s from Redis: %w", key, err)
+	}
+	return value, value != nil, nil
+}
+
+func (s *redisStore) set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", s.key(key), string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	if _, err := s.redis.do(args...); err != nil {
+		return fmt.Errorf("error writing %s to Redis: %w", key, err)
+	}
+	return nil
+}
+
+func (s *redisStore) setNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	args := []string{"SET", s.key(key), string(value), "NX"}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	// A null reply means the key was already set.
+	reply, err := s.redis.do(args...)
+	if err != nil {
+		return false, fmt.Errorf("error writing %s to Redis: %w", key, err)
+	}
+	return reply != nil, nil
+}
+
+func (s *redisStore) delete(key string) error {
+	if _, err := s.redis.do("DEL", s.key(key)); err != nil {
+		return fmt.Errorf("error deleting %s from Redis: %w", key, err)
+	}
+	return nil
+}
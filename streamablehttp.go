@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// streamablePath is the endpoint of the Streamable HTTP transport.
+	streamablePath = "/mcp"
+	// sessionHeader carries the session ID of Streamable HTTP requests.
+	sessionHeader = "Mcp-Session-Id"
+	// maxStreamedNotifications buffers the notifications of a request between stream writes.
+	maxStreamedNotifications = 64
+)
+
+// statelessSession is the session of one Streamable HTTP request. Its ID comes from the client,
+// signed by the server when the session was initialized, and anything kept for the session lives
+// in the state store, so consecutive requests can reach
+// different replicas. Notifications only reach the client while the request is streamed.
+type statelessSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *statelessSession) Initialize()       {}
+func (s *statelessSession) Initialized() bool { return true }
+func (s *statelessSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+func (s *statelessSession) SessionID() string { return s.id }
+
+// streamableHandler serves the Streamable HTTP transport without per-process state: each POSTed
+// message is answered in its own response, as JSON or, when the client accepts it, as an event
+// stream that carries the request's progress notifications before its response. There is no
+// server-initiated stream, so requests to the client such as sampling, elicitation, and roots are
+// not available, and a call is cancelled by closing its request.
+type streamableHandler struct {
+	server      *server.MCPServer
+	contexts    *sessionContexts
+	sessionAuth bool
+}
+
+func newStreamableHandler(s *server.MCPServer, contexts *sessionContexts, sessionAuth bool) *streamableHandler {
+	return &streamableHandler{server: s, contexts: contexts, sessionAuth: sessionAuth}
+}
+
+func (h *streamableHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is supported; the server does not open streams", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxClientMessageBytes))
+	if err != nil {
+		http.Error(w, "error reading message", http.StatusBadRequest)
+		return
+	}
+	var message struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.Unmarshal(body, &message); err != nil {
+		http.Error(w, "message must be a JSON-RPC object", http.StatusBadRequest)
+		return
+	}
+
+	ctx := withResultEncoding(req.Context(), req)
+	if h.sessionAuth {
+		ctx = withCredential(ctx, headerCredential(req.Header))
+	}
+	sessionID := req.Header.Get(sessionHeader)
+	if message.Method == string(mcp.MethodInitialize) {
+		sessionID = h.contexts.newSessionID(ctx)
+		w.Header().Set(sessionHeader, sessionID)
+	} else if sessionID != "" && !h.contexts.validSessionID(ctx, sessionID) {
+		http.Error(w, "unknown session; initialize a new one", http.StatusNotFound)
+		return
+	}
+	session := &statelessSession{id: sessionID, notifications: make(chan mcp.JSONRPCNotification, maxStreamedNotifications)}
+	if sessionID != "" {
+		ctx = h.server.WithContext(ctx, session)
+	}
+
+	// Notifications and the client's responses get no reply.
+	if len(message.ID) == 0 || message.Method == "" {
+		h.server.HandleMessage(ctx, body)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	flusher, canFlush := w.(http.Flusher)
+	if !canFlush || !strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		response := h.server.HandleMessage(ctx, body)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "error encoding response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	done := make(chan mcp.JSONRPCMessage, 1)
+	go func(ctx context.Context) {
+		done <- h.server.HandleMessage(ctx, body)
+	}(ctx)
+	for {
+		select {
+		case notification := <-session.notifications:
+			writeStreamEvent(w, notification)
+			flusher.Flush()
+		case response := <-done:
+			// Notifications sent just before the response still precede it.
+			for len(session.notifications) > 0 {
+				writeStreamEvent(w, <-session.notifications)
+			}
+			writeStreamEvent(w, response)
+			flusher.Flush()
+			return
+		}
+	}
+}
+
+// writeStreamEvent writes a JSON-RPC message as a server-sent event.
+func writeStreamEvent(w io.Writer, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error encoding event: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/search"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultSymbolResults = 25
+	maxSymbolResults     = 200
+)
+
+// symbolFilters maps symbol kinds to the code search code element filters that find their
+// definitions.
+var symbolFilters = map[string][]string{
+	"any":       {"def"},
+	"class":     {"class"},
+	"function":  {"func"},
+	"method":    {"method"},
+	"interface": {"interface"},
+	"struct":    {"struct"},
+	"enum":      {"enum"},
+	"type":      {"type", "typedef"},
+	"field":     {"field"},
+	"property":  {"prop"},
+	"namespace": {"namespace"},
+	"macro":     {"macrodef"},
+}
+
+// symbolKinds lists the keys of symbolFilters in the order offered to clients.
+var symbolKinds = []string{"any", "class", "function", "method", "interface", "struct", "enum", "type", "field", "property", "namespace", "macro"}
+
+// searchSymbols finds declarations of a symbol by name with code search code element filters,
+// returning the line and column of each match.
+func (c *AzureDevOpsClient) searchSymbols(ctx context.Context, name, kind, repoName string, top int) ([]map[string]interface{}, error) {
+	filterNames, ok := symbolFilters[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown symbol kind: %s", kind)
+	}
+	terms := []string{}
+	for _, filter := range filterNames {
+		terms = append(terms, filter+":"+name)
+	}
+	query := strings.Join(terms, " OR ")
+
+	filters := map[string][]string{
+		"Project": {c.config.AzureDevOps.Project},
+	}
+	repos, err := c.searchRepositories(ctx, c.config.AzureDevOps.Project, repoName)
+	if err != nil {
+		return nil, err
+	}
+	if repos != nil {
+		filters["Repository"] = repos
+	}
+	includeSnippet := true
+	response, err := c.searchClient.FetchCodeSearchResults(ctx, search.FetchCodeSearchResultsArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Request: &search.CodeSearchRequest{
+			SearchText:     &query,
+			Filters:        &filters,
+			IncludeSnippet: &includeSnippet,
+			Top:            &top,
+		},
+	})
+	if err != nil {
+		log.Printf("Error searching symbols: %v", err)
+		return nil, fmt.Errorf("error searching for %s: %w", query, err)
+	}
+
+	results := []map[string]interface{}{}
+	if response == nil || response.Results == nil {
+		return results, nil
+	}
+	for _, result := range *response.Results {
+		if result.Repository == nil || result.Path == nil {
+			continue
+		}
+		locations := []map[string]interface{}{}
+		if result.Matches != nil {
+			for field, hits := range *result.Matches {
+				if field == "fileName" || field == "path" {
+					continue
+				}
+				for _, hit := range hits {
+					location := map[string]interface{}{}
+					if hit.Line != nil {
+						location["line"] = *hit.Line
+					}
+					if hit.Column != nil {
+						location["column"] = *hit.Column
+					}
+					if hit.CodeSnippet != nil {
+						location["snippet"] = *hit.CodeSnippet
+					}
+					if len(location) > 0 {
+						locations = append(locations, location)
+					}
+				}
+			}
+		}
+		results = append(results, map[string]interface{}{
+			"repository": stringValue(result.Repository.Name),
+			"path":       *result.Path,
+			"locations":  locations,
+			"webUrl":     c.links.file(c.config.AzureDevOps.Project, stringValue(result.Repository.Name), *result.Path, ""),
+			"uri":        c.fileURI(c.config.AzureDevOps.Project, stringValue(result.Repository.Name), *result.Path, ""),
+		})
+	}
+
+	return results, nil
+}
+
+func addSymbolTools(s tools.Server, client *AzureDevOpsClient) {
+	symbolTool := mcp.NewTool("search_symbols",
+		mcp.WithDescription("Find where classes, functions, and other symbols are defined by name, rather than every file that mentions the text. Returns the file and line of each declaration"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Symbol name; * wildcards are allowed, e.g. Parse*"),
+		),
+		mcp.WithString("kind",
+			mcp.Description("Kind of symbol to find; any matches all definitions"),
+			mcp.Enum(symbolKinds...),
+			mcp.DefaultString("any"),
+		),
+		mcp.WithString("repository",
+			mcp.Description("Optional repository name to search in"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description(fmt.Sprintf("Maximum number of files, at most %d", maxSymbolResults)),
+			mcp.DefaultNumber(defaultSymbolResults),
+		),
+	)
+
+	s.AddTool(symbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name, ok := request.Params.Arguments["name"].(string)
+		if !ok {
+			log.Print("Name must be a string")
+			return nil, fmt.Errorf("name must be a string")
+		}
+		kind, _ := request.Params.Arguments["kind"].(string)
+		if kind == "" {
+			kind = "any"
+		}
+		repo, _ := request.Params.Arguments["repository"].(string)
+		top := defaultSymbolResults
+		if value, ok := request.Params.Arguments["top"].(float64); ok && value > 0 {
+			top = int(value)
+		}
+		if top > maxSymbolResults {
+			top = maxSymbolResults
+		}
+
+		results, err := client.searchSymbols(ctx, name, kind, repo, top)
+		if err != nil {
+			log.Printf("Error searching symbols: %v", err)
+			return nil, fmt.Errorf("error searching symbols: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxTaggedWorkItems caps the work items read to count tag usage or to merge a tag.
+const maxTaggedWorkItems = 5000
+
+// splitTags splits the value of System.Tags, which separates tags with semicolons.
+func splitTags(value interface{}) []string {
+	text, _ := value.(string)
+	tags := []string{}
+	for _, tag := range strings.Split(text, ";") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// hasTag reports whether tags holds tag; tags are case insensitive.
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// wiqlString quotes a value for a WIQL query.
+func wiqlString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// taggedWorkItems returns the work items of the project with tags, or with tag when one is given,
+// up to maxTaggedWorkItems, with their tags.
+func (c *AzureDevOpsClient) taggedWorkItems(ctx context.Context, tag string) ([]workitemtracking.WorkItem, bool, error) {
+	wiql := "SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @project AND [System.Tags] <> ''"
+	if tag != "" {
+		wiql = "SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @project AND [System.Tags] CONTAINS " + wiqlString(tag)
+	}
+	ids, err := c.queryWorkItemIDs(ctx, wiql)
+	if err != nil {
+		return nil, false, err
+	}
+	truncated := len(ids) > maxTaggedWorkItems
+	if truncated {
+		ids = ids[:maxTaggedWorkItems]
+	}
+	items, err := c.getWorkItemsBatch(ctx, ids, []string{"System.Tags"})
+	if err != nil {
+		return nil, false, err
+	}
+	return items, truncated, nil
+}
+
+// listTags lists the work item tags of the project. includeCounts adds how many work items carry
+// each tag, so unused tags and near duplicates can be cleaned up.
+func (c *AzureDevOpsClient) listTags(ctx context.Context, includeCounts bool) (map[string]interface{}, error) {
+	tags, err := c.witClient.GetTags(ctx, workitemtracking.GetTagsArgs{
+		Project: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting tags: %v", err)
+		return nil, fmt.Errorf("error getting tags: %w", err)
+	}
+
+	counts := map[string]int{}
+	truncated := false
+	if includeCounts {
+		var items []workitemtracking.WorkItem
+		items, truncated, err = c.taggedWorkItems(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if item.Fields == nil {
+				continue
+			}
+			for _, tag := range splitTags((*item.Fields)["System.Tags"]) {
+				counts[strings.ToLower(tag)]++
+			}
+		}
+	}
+
+	list := []map[string]interface{}{}
+	for _, tag := range *tags {
+		name := stringValue(tag.Name)
+		entry := map[string]interface{}{
+			"name": name,
+		}
+		if tag.Id != nil {
+			entry["id"] = tag.Id.String()
+		}
+		if tag.LastUpdated != nil {
+			entry["lastUpdated"] = tag.LastUpdated.Time.Format(time.RFC3339)
+		}
+		if includeCounts {
+			entry["workItems"] = counts[strings.ToLower(name)]
+		}
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(list[i]["name"].(string)) < strings.ToLower(list[j]["name"].(string))
+	})
+
+	result := map[string]interface{}{
+		"tags":  list,
+		"count": len(list),
+	}
+	if truncated {
+		result["truncated"] = fmt.Sprintf("counts are from the first %d tagged work items", maxTaggedWorkItems)
+	}
+	return result, nil
+}
+
+// retagWorkItems adds and removes tags on work items, writing only the items whose tags change.
+// System.Tags is replaced as a whole, so each item's other tags are kept as read. A dry run returns
+// the tags each item would get instead.
+func (c *AzureDevOpsClient) retagWorkItems(ctx context.Context, items []workitemtracking.WorkItem, add, remove []string, dryRun bool) []map[string]interface{} {
+	results := []map[string]interface{}{}
+	for i, item := range items {
+		tools.ReportProgress(ctx, i, len(items), "Tagging work items")
+		if item.Id == nil {
+			continue
+		}
+		id := *item.Id
+		if ctx.Err() != nil {
+			results = append(results, map[string]interface{}{
+				"id":     id,
+				"status": "skipped",
+				"error":  "cancelled",
+			})
+			continue
+		}
+		current := []string{}
+		if item.Fields != nil {
+			current = splitTags((*item.Fields)["System.Tags"])
+		}
+		tags := []string{}
+		for _, tag := range current {
+			if !hasTag(remove, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		for _, tag := range add {
+			if !hasTag(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) == len(current) && strings.EqualFold(strings.Join(tags, ";"), strings.Join(current, ";")) {
+			results = append(results, map[string]interface{}{
+				"id":     id,
+				"status": "unchanged",
+			})
+			continue
+		}
+
+		value := strings.Join(tags, "; ")
+		if dryRun {
+			results = append(results, map[string]interface{}{
+				"id":     id,
+				"status": "wouldUpdate",
+				"from":   current,
+				"tags":   tags,
+			})
+			continue
+		}
+		path := "/fields/System.Tags"
+		document := []webapi.JsonPatchOperation{{
+			Op:    &webapi.OperationValues.Add,
+			Path:  &path,
+			Value: value,
+		}}
+		_, err := c.witClient.UpdateWorkItem(ctx, workitemtracking.UpdateWorkItemArgs{
+			Document: &document,
+			Id:       &id,
+			Project:  &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error updating work item %d: %v", id, err)
+			results = append(results, map[string]interface{}{
+				"id":     id,
+				"status": "failed",
+				"error":  err.Error(),
+			})
+			continue
+		}
+		results = append(results, map[string]interface{}{
+			"id":     id,
+			"status": "updated",
+			"tags":   tags,
+		})
+	}
+	return results
+}
+
+// tagWorkItems adds and removes tags on the work items in ids.
+func (c *AzureDevOpsClient) tagWorkItems(ctx context.Context, ids []int, add, remove []string, dryRun bool) ([]map[string]interface{}, error) {
+	items, err := c.getWorkItemsBatch(ctx, ids, []string{"System.Tags"})
+	if err != nil {
+		return nil, err
+	}
+	results := c.retagWorkItems(ctx, items, add, remove, dryRun)
+	found := map[int]bool{}
+	for _, item := range items {
+		if item.Id != nil {
+			found[*item.Id] = true
+		}
+	}
+	for _, id := range ids {
+		if !found[id] {
+			results = append(results, map[string]interface{}{
+				"id":     id,
+				"status": "skipped",
+				"error":  "work item not found",
+			})
+		}
+	}
+	return results, nil
+}
+
+// renameTag renames a tag of the project on every work item that carries it. When a tag named to
+// already exists the two are merged instead: from is replaced by to on each work item and then
+// deleted. A dry run describes the rename or the work items a merge would change instead.
+func (c *AzureDevOpsClient) renameTag(ctx context.Context, from, to string, dryRun bool) (map[string]interface{}, error) {
+	existing, err := c.witClient.GetTag(ctx, workitemtracking.GetTagArgs{
+		Project:     &c.config.AzureDevOps.Project,
+		TagIdOrName: &from,
+	})
+	if err != nil {
+		log.Printf("Error getting tag: %v", err)
+		return nil, fmt.Errorf("error getting tag %s: %w", from, err)
+	}
+	if _, err := c.witClient.GetTag(ctx, workitemtracking.GetTagArgs{
+		Project:     &c.config.AzureDevOps.Project,
+		TagIdOrName: &to,
+	}); err != nil || strings.EqualFold(stringValue(existing.Name), to) {
+		// A missing target, or a change of case only, is a plain rename.
+		if dryRun {
+			return tools.DryRunResult("rename tag", map[string]interface{}{"from": stringValue(existing.Name), "to": to}), nil
+		}
+		renamed, err := c.witClient.UpdateTag(ctx, workitemtracking.UpdateTagArgs{
+			TagData:     &workitemtracking.WorkItemTagDefinition{Name: &to},
+			Project:     &c.config.AzureDevOps.Project,
+			TagIdOrName: &from,
+		})
+		if err != nil {
+			log.Printf("Error renaming tag: %v", err)
+			return nil, fmt.Errorf("error renaming tag %s: %w", from, err)
+		}
+		return map[string]interface{}{
+			"action": "renamed",
+			"from":   stringValue(existing.Name),
+			"to":     stringValue(renamed.Name),
+		}, nil
+	}
+
+	items, truncated, err := c.taggedWorkItems(ctx, stringValue(existing.Name))
+	if err != nil {
+		return nil, err
+	}
+	results := c.retagWorkItems(ctx, items, []string{to}, []string{stringValue(existing.Name)}, dryRun)
+	if dryRun {
+		return tools.DryRunResult("merge tag", map[string]interface{}{
+			"from":      stringValue(existing.Name),
+			"to":        to,
+			"workItems": results,
+		}), nil
+	}
+
+	result := map[string]interface{}{
+		"action":    "merged",
+		"from":      stringValue(existing.Name),
+		"to":        to,
+		"workItems": results,
+	}
+	failed := false
+	for _, entry := range results {
+		if entry["status"] != "updated" && entry["status"] != "unchanged" {
+			failed = true
+		}
+	}
+	// The old tag is kept while work items still carry it, so nothing loses a tag.
+	if failed || truncated {
+		result["deleted"] = false
+		if truncated {
+			result["truncated"] = fmt.Sprintf("only the first %d work items were retagged; run the merge again for the rest", maxTaggedWorkItems)
+		}
+		return result, nil
+	}
+	err = c.witClient.DeleteTag(ctx, workitemtracking.DeleteTagArgs{
+		Project:     &c.config.AzureDevOps.Project,
+		TagIdOrName: existing.Name,
+	})
+	if err != nil {
+		log.Printf("Error deleting tag: %v", err)
+		result["deleted"] = false
+		result["error"] = err.Error()
+		return result, nil
+	}
+	result["deleted"] = true
+	return result, nil
+}
+
+func addTagTools(s tools.Server, client *AzureDevOpsClient) {
+	listTool := mcp.NewTool("list_work_item_tags",
+		mcp.WithDescription("List the work item tags of the project, optionally with how many work items carry each, to find unused tags and near duplicates to clean up"),
+		mcp.WithBoolean("includeCounts",
+			mcp.Description(fmt.Sprintf("Count the work items carrying each tag, reading at most %d tagged work items", maxTaggedWorkItems)),
+		),
+	)
+
+	s.AddTool(listTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		includeCounts, _ := request.Params.Arguments["includeCounts"].(bool)
+
+		result, err := client.listTags(ctx, includeCounts)
+		if err != nil {
+			log.Printf("Error listing work item tags: %v", err)
+			return nil, fmt.Errorf("error listing work item tags: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	tagTool := mcp.NewTool("tag_work_items",
+		mcp.WithDescription("Add and remove tags on many work items at once. Provide ids, azdo:// URIs, or a WIQL query. Other tags of each work item are kept, and work items whose tags would not change are left alone"),
+		mcp.WithArray("ids",
+			mcp.Description("Work item IDs to tag"),
+			mcp.Items(map[string]interface{}{"type": "number"}),
+		),
+		mcp.WithArray("uris",
+			mcp.Description("azdo:// URIs of work items to tag, used with or instead of ids"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("wiql",
+			mcp.Description("WIQL query selecting the work items to tag, used instead of ids"),
+		),
+		mcp.WithArray("add",
+			mcp.Description("Tags to add"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("remove",
+			mcp.Description("Tags to remove"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(tagTool, writeHandler(client, tagTool, tools.WithProgress(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ids, err := tools.IntSliceArgument(request.Params.Arguments, "ids")
+		if err != nil {
+			log.Printf("Invalid ids: %v", err)
+			return nil, err
+		}
+		uris, err := tools.StringSliceArgument(request.Params.Arguments, "uris")
+		if err != nil {
+			log.Printf("Invalid uris: %v", err)
+			return nil, err
+		}
+		uriIDs, err := client.uriIDs(uris, workItemEntity)
+		if err != nil {
+			log.Printf("Invalid uris: %v", err)
+			return nil, err
+		}
+		ids = append(ids, uriIDs...)
+
+		if wiql, _ := request.Params.Arguments["wiql"].(string); wiql != "" {
+			queried, err := client.queryWorkItemIDs(ctx, wiql)
+			if err != nil {
+				log.Printf("Error querying work items: %v", err)
+				return nil, fmt.Errorf("error querying work items: %w", err)
+			}
+			ids = append(ids, queried...)
+		}
+
+		if len(ids) == 0 {
+			log.Print("No work items to tag")
+			return nil, fmt.Errorf("ids, uris, or wiql must select at least one work item")
+		}
+
+		add, err := tools.StringSliceArgument(request.Params.Arguments, "add")
+		if err != nil {
+			log.Printf("Invalid add: %v", err)
+			return nil, err
+		}
+		remove, err := tools.StringSliceArgument(request.Params.Arguments, "remove")
+		if err != nil {
+			log.Printf("Invalid remove: %v", err)
+			return nil, err
+		}
+		if len(add) == 0 && len(remove) == 0 {
+			log.Print("No tags to change")
+			return nil, fmt.Errorf("add or remove must name at least one tag")
+		}
+
+		dryRun := client.dryRun(request)
+		results, err := client.tagWorkItems(ctx, ids, add, remove, dryRun)
+		if err != nil {
+			log.Printf("Error tagging work items: %v", err)
+			return nil, fmt.Errorf("error tagging work items: %w", err)
+		}
+		if dryRun {
+			return tools.JSONResult(tools.DryRunResult("tag work items", map[string]interface{}{"workItems": results}))
+		}
+
+		return tools.JSONResult(results)
+	})))
+
+	renameTool := mcp.NewTool("rename_work_item_tag",
+		mcp.WithDescription("Rename a work item tag of the project everywhere it is used. When the new name is an existing tag, the two are merged: every work item with the old tag gets the new one instead, and the old tag is deleted once none carry it"),
+		mcp.WithString("from",
+			mcp.Required(),
+			mcp.Description("Tag to rename"),
+		),
+		mcp.WithString("to",
+			mcp.Required(),
+			mcp.Description("New name, or the existing tag to merge into"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(renameTool, writeHandler(client, renameTool, tools.WithProgress(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		from, ok := request.Params.Arguments["from"].(string)
+		if !ok {
+			log.Print("From must be a string")
+			return nil, fmt.Errorf("from must be a string")
+		}
+		to, ok := request.Params.Arguments["to"].(string)
+		if !ok || strings.TrimSpace(to) == "" {
+			log.Print("To must be a string")
+			return nil, fmt.Errorf("to must be a non-empty string")
+		}
+
+		result, err := client.renameTag(ctx, from, strings.TrimSpace(to), client.dryRun(request))
+		if err != nil {
+			log.Printf("Error renaming work item tag: %v", err)
+			return nil, fmt.Errorf("error renaming work item tag: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})))
+
+	deleteTool := mcp.NewTool("delete_work_item_tag",
+		mcp.WithDescription("Delete a work item tag of the project, which removes it from every work item that carries it"),
+		mcp.WithString("tag",
+			mcp.Required(),
+			mcp.Description("Tag to delete"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(deleteTool, writeHandler(client, deleteTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tag, ok := request.Params.Arguments["tag"].(string)
+		if !ok {
+			log.Print("Tag must be a string")
+			return nil, fmt.Errorf("tag must be a string")
+		}
+
+		if client.dryRun(request) {
+			return tools.JSONResult(tools.DryRunResult("delete tag", map[string]interface{}{"tag": tag}))
+		}
+		err := client.witClient.DeleteTag(ctx, workitemtracking.DeleteTagArgs{
+			Project:     &client.config.AzureDevOps.Project,
+			TagIdOrName: &tag,
+		})
+		if err != nil {
+			log.Printf("Error deleting work item tag: %v", err)
+			return nil, fmt.Errorf("error deleting work item tag %s: %w", tag, err)
+		}
+
+		return tools.JSONResult(map[string]interface{}{
+			"tag":     tag,
+			"deleted": true,
+		})
+	}))
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/work"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxClassificationDepth limits how deep area and iteration trees are expanded.
+const maxClassificationDepth = 10
+
+func (c *AzureDevOpsClient) listTeams(ctx context.Context) ([]map[string]interface{}, error) {
+	teams, err := c.coreClient.GetTeams(ctx, core.GetTeamsArgs{
+		ProjectId: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting teams: %v", err)
+		return nil, fmt.Errorf("error getting teams: %w", err)
+	}
+
+	results := []map[string]interface{}{}
+	if teams == nil {
+		return results, nil
+	}
+	for _, team := range *teams {
+		if team.Id == nil || team.Name == nil {
+			continue
+		}
+		results = append(results, map[string]interface{}{
+			"id":          team.Id.String(),
+			"name":        *team.Name,
+			"description": stringValue(team.Description),
+		})
+	}
+
+	return results, nil
+}
+
+// getTeamConfiguration returns the area paths a team owns and its iteration settings, which
+// together determine where new work items for the team should be filed.
+func (c *AzureDevOpsClient) getTeamConfiguration(ctx context.Context, team string) (map[string]interface{}, error) {
+	team = c.teamName(team)
+
+	fieldValues, err := c.workClient.GetTeamFieldValues(ctx, work.GetTeamFieldValuesArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Team:    &team,
+	})
+	if err != nil {
+		log.Printf("Error getting team field values: %v", err)
+		return nil, fmt.Errorf("error getting area paths for team %s: %w", team, err)
+	}
+
+	areas := []map[string]interface{}{}
+	if fieldValues.Values != nil {
+		for _, value := range *fieldValues.Values {
+			areas = append(areas, map[string]interface{}{
+				"path":            stringValue(value.Value),
+				"includeChildren": value.IncludeChildren != nil && *value.IncludeChildren,
+			})
+		}
+	}
+	teamField := ""
+	if fieldValues.Field != nil {
+		teamField = stringValue(fieldValues.Field.ReferenceName)
+	}
+
+	settings, err := c.workClient.GetTeamSettings(ctx, work.GetTeamSettingsArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Team:    &team,
+	})
+	if err != nil {
+		log.Printf("Error getting team settings: %v", err)
+		return nil, fmt.Errorf("error getting settings for team %s: %w", team, err)
+	}
+
+	iterationSettings := map[string]interface{}{}
+	if settings.BacklogIteration != nil {
+		iterationSettings["backlogIteration"] = iterationSummary(*settings.BacklogIteration)
+	}
+	if settings.DefaultIteration != nil {
+		iterationSettings["defaultIteration"] = iterationSummary(*settings.DefaultIteration)
+	}
+	if settings.DefaultIterationMacro != nil {
+		iterationSettings["defaultIterationMacro"] = *settings.DefaultIterationMacro
+	}
+	if settings.WorkingDays != nil {
+		iterationSettings["workingDays"] = *settings.WorkingDays
+	}
+	if settings.BugsBehavior != nil {
+		iterationSettings["bugsBehavior"] = string(*settings.BugsBehavior)
+	}
+
+	iterations, err := c.listIterations(ctx, team, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"team":              team,
+		"teamField":         teamField,
+		"defaultAreaPath":   stringValue(fieldValues.DefaultValue),
+		"areaPaths":         areas,
+		"iterationSettings": iterationSettings,
+		"iterations":        iterations,
+	}, nil
+}
+
+// classificationNodePath converts a node path such as \Project\Area\Web into the form used by
+// work item fields (Project\Web).
+func classificationNodePath(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, `\`), `\`)
+	if len(parts) < 2 {
+		return strings.Join(parts, `\`)
+	}
+	return strings.Join(append(parts[:1], parts[2:]...), `\`)
+}
+
+func classificationNodeTree(node workitemtracking.WorkItemClassificationNode) map[string]interface{} {
+	entry := map[string]interface{}{
+		"name": stringValue(node.Name),
+		"path": classificationNodePath(stringValue(node.Path)),
+	}
+	if node.Attributes != nil {
+		if start, ok := (*node.Attributes)["startDate"]; ok {
+			entry["startDate"] = start
+		}
+		if finish, ok := (*node.Attributes)["finishDate"]; ok {
+			entry["finishDate"] = finish
+		}
+	}
+	if node.Children != nil {
+		children := []map[string]interface{}{}
+		for _, child := range *node.Children {
+			children = append(children, classificationNodeTree(child))
+		}
+		entry["children"] = children
+	}
+	return entry
+}
+
+func (c *AzureDevOpsClient) getClassificationNodes(ctx context.Context, structure string, depth int) (map[string]interface{}, error) {
+	group := workitemtracking.TreeStructureGroupValues.Areas
+	if strings.EqualFold(structure, "iterations") {
+		group = workitemtracking.TreeStructureGroupValues.Iterations
+	}
+	if depth <= 0 || depth > maxClassificationDepth {
+		depth = maxClassificationDepth
+	}
+
+	root, err := c.witClient.GetClassificationNode(ctx, workitemtracking.GetClassificationNodeArgs{
+		Project:        &c.config.AzureDevOps.Project,
+		StructureGroup: &group,
+		Depth:          &depth,
+	})
+	if err != nil {
+		log.Printf("Error getting classification nodes: %v", err)
+		return nil, fmt.Errorf("error getting %s: %w", group, err)
+	}
+
+	return classificationNodeTree(*root), nil
+}
+
+func addTeamTools(s tools.Server, client *AzureDevOpsClient) {
+	listTeamsTool := mcp.NewTool("list_teams",
+		mcp.WithDescription("List the teams in the project"),
+	)
+
+	s.AddTool(listTeamsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		results, err := client.listTeams(ctx)
+		if err != nil {
+			log.Printf("Error listing teams: %v", err)
+			return nil, fmt.Errorf("error listing teams: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	teamConfigTool := mcp.NewTool("get_team_configuration",
+		mcp.WithDescription("Get a team's area paths, default area path, iteration settings, and subscribed iterations. Use this to pick the right area and iteration path when creating work items for a team"),
+		mcp.WithString("team",
+			mcp.Description("Optional team name, defaults to the configured team"),
+		),
+	)
+
+	s.AddTool(teamConfigTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		team, _ := request.Params.Arguments["team"].(string)
+
+		result, err := client.getTeamConfiguration(ctx, team)
+		if err != nil {
+			log.Printf("Error getting team configuration: %v", err)
+			return nil, fmt.Errorf("error getting team configuration: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	classificationTool := mcp.NewTool("list_classification_nodes",
+		mcp.WithDescription("Get the project's area or iteration path tree, with paths in the form used by work item fields"),
+		mcp.WithString("structure",
+			mcp.Required(),
+			mcp.Description("Which tree to return"),
+			mcp.Enum("areas", "iterations"),
+		),
+		mcp.WithNumber("depth",
+			mcp.Description("Levels of children to include"),
+			mcp.DefaultNumber(maxClassificationDepth),
+			mcp.Min(1),
+			mcp.Max(maxClassificationDepth),
+		),
+	)
+
+	s.AddTool(classificationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		structure, ok := request.Params.Arguments["structure"].(string)
+		if !ok {
+			log.Print("Structure must be a string")
+			return nil, fmt.Errorf("structure must be a string")
+		}
+		depth, _ := request.Params.Arguments["depth"].(float64)
+
+		result, err := client.getClassificationNodes(ctx, structure, int(depth))
+		if err != nil {
+			log.Printf("Error getting classification nodes: %v", err)
+			return nil, fmt.Errorf("error getting classification nodes: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
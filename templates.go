@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// listTemplates lists the work item templates of a team, optionally only those of a work item type.
+func (c *AzureDevOpsClient) listTemplates(ctx context.Context, team, workItemType string) ([]map[string]interface{}, error) {
+	team = c.teamName(team)
+	args := workitemtracking.GetTemplatesArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Team:    &team,
+	}
+	if workItemType != "" {
+		args.Workitemtypename = &workItemType
+	}
+	templates, err := c.witClient.GetTemplates(ctx, args)
+	if err != nil {
+		log.Printf("Error getting work item templates: %v", err)
+		return nil, fmt.Errorf("error getting work item templates of %s: %w", team, err)
+	}
+
+	results := []map[string]interface{}{}
+	for _, template := range *templates {
+		if template.Id == nil {
+			continue
+		}
+		results = append(results, map[string]interface{}{
+			"id":           template.Id.String(),
+			"name":         stringValue(template.Name),
+			"description":  stringValue(template.Description),
+			"workItemType": stringValue(template.WorkItemTypeName),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i]["workItemType"] != results[j]["workItemType"] {
+			return results[i]["workItemType"].(string) < results[j]["workItemType"].(string)
+		}
+		return results[i]["name"].(string) < results[j]["name"].(string)
+	})
+	return results, nil
+}
+
+// findTemplate returns a work item template of a team by ID or, failing that, by name.
+func (c *AzureDevOpsClient) findTemplate(ctx context.Context, team, template string) (*workitemtracking.WorkItemTemplate, error) {
+	team = c.teamName(team)
+	id, err := uuid.Parse(template)
+	if err != nil {
+		templates, err := c.listTemplates(ctx, team, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range templates {
+			if strings.EqualFold(t["name"].(string), template) {
+				id = uuid.MustParse(t["id"].(string))
+				break
+			}
+		}
+		if id == uuid.Nil {
+			return nil, fmt.Errorf("team %s has no work item template %s", team, template)
+		}
+	}
+
+	result, err := c.witClient.GetTemplate(ctx, workitemtracking.GetTemplateArgs{
+		Project:    &c.config.AzureDevOps.Project,
+		Team:       &team,
+		TemplateId: &id,
+	})
+	if err != nil {
+		log.Printf("Error getting work item template: %v", err)
+		return nil, fmt.Errorf("error getting work item template %s: %w", template, err)
+	}
+	return result, nil
+}
+
+// templateFields returns the field values a template sets with overrides applied on top. Field
+// reference names are matched case insensitively, and an override keeps its own spelling.
+func templateFields(template *workitemtracking.WorkItemTemplate, overrides map[string]interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if template.Fields != nil {
+		for name, value := range *template.Fields {
+			fields[name] = value
+		}
+	}
+	for name, value := range overrides {
+		for existing := range fields {
+			if strings.EqualFold(existing, name) {
+				delete(fields, existing)
+			}
+		}
+		fields[name] = value
+	}
+	return fields
+}
+
+// createFromTemplate creates a work item of a template's type with the template's field values and
+// overrides. The fields are validated against the work item type like update_work_items does. A
+// dry run returns the fields the work item would be created with instead.
+func (c *AzureDevOpsClient) createFromTemplate(ctx context.Context, team, template string, overrides map[string]interface{}, dryRun bool) (map[string]interface{}, error) {
+	found, err := c.findTemplate(ctx, team, template)
+	if err != nil {
+		return nil, err
+	}
+	workItemType := stringValue(found.WorkItemTypeName)
+	fields := templateFields(found, overrides)
+
+	typeFields, err := c.getWorkItemTypeFields(ctx, workItemType)
+	if err != nil {
+		return nil, err
+	}
+	strict, err := c.strictPicklistFields(ctx)
+	if err != nil {
+		log.Printf("Creating work item without picklist validation: %v", err)
+		strict = map[string]bool{}
+	}
+	names := map[string]bool{}
+	values := map[string][]interface{}{}
+	for _, f := range typeFields {
+		name := strings.ToLower(f.ReferenceName)
+		names[name] = true
+		if strict[name] && len(f.AllowedValues) > 0 {
+			values[name] = f.AllowedValues
+		}
+	}
+	if problem := validateWorkItemUpdate(names, values, nil, fields, "", ""); problem != "" {
+		return nil, fmt.Errorf("%s", problem)
+	}
+
+	if dryRun {
+		return tools.DryRunResult("create work item from template", map[string]interface{}{
+			"template":     stringValue(found.Name),
+			"workItemType": workItemType,
+			"fields":       fields,
+		}), nil
+	}
+
+	document := workItemPatchDocument(fields, "")
+	item, err := c.witClient.CreateWorkItem(ctx, workitemtracking.CreateWorkItemArgs{
+		Document: &document,
+		Project:  &c.config.AzureDevOps.Project,
+		Type:     &workItemType,
+	})
+	if err != nil {
+		log.Printf("Error creating work item: %v", err)
+		return nil, fmt.Errorf("error creating %s: %w", workItemType, err)
+	}
+
+	result := map[string]interface{}{
+		"template":     stringValue(found.Name),
+		"workItemType": workItemType,
+	}
+	if item.Id != nil {
+		result["id"] = *item.Id
+		result["uri"] = c.idURI(workItemEntity, c.config.AzureDevOps.Project, *item.Id)
+		result["webUrl"] = c.links.workItem(c.config.AzureDevOps.Project, *item.Id)
+	}
+	if item.Fields != nil {
+		result["title"] = (*item.Fields)["System.Title"]
+		result["state"] = (*item.Fields)["System.State"]
+	}
+	return result, nil
+}
+
+func addTemplateTools(s tools.Server, client *AzureDevOpsClient) {
+	listTool := mcp.NewTool("list_work_item_templates",
+		mcp.WithDescription("List the work item templates of a team, such as a standard bug report, with their work item type"),
+		mcp.WithString("team",
+			mcp.Description("Optional team name, defaults to the configured team"),
+		),
+		mcp.WithString("type",
+			mcp.Description("Only templates of this work item type, e.g. Bug"),
+		),
+	)
+
+	s.AddTool(listTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		team, _ := request.Params.Arguments["team"].(string)
+		workItemType, _ := request.Params.Arguments["type"].(string)
+
+		results, err := client.listTemplates(ctx, team, workItemType)
+		if err != nil {
+			log.Printf("Error listing work item templates: %v", err)
+			return nil, fmt.Errorf("error listing work item templates: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	getTool := mcp.NewTool("get_work_item_template",
+		mcp.WithDescription("Get a work item template of a team with the field values it sets"),
+		mcp.WithString("template",
+			mcp.Required(),
+			mcp.Description("Template name or ID"),
+		),
+		mcp.WithString("team",
+			mcp.Description("Optional team name, defaults to the configured team"),
+		),
+	)
+
+	s.AddTool(getTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		template, ok := request.Params.Arguments["template"].(string)
+		if !ok {
+			log.Print("Template must be a string")
+			return nil, fmt.Errorf("template must be a string")
+		}
+		team, _ := request.Params.Arguments["team"].(string)
+
+		result, err := client.findTemplate(ctx, team, template)
+		if err != nil {
+			log.Printf("Error getting work item template: %v", err)
+			return nil, fmt.Errorf("error getting work item template: %w", err)
+		}
+
+		entry := map[string]interface{}{
+			"name":         stringValue(result.Name),
+			"description":  stringValue(result.Description),
+			"workItemType": stringValue(result.WorkItemTypeName),
+			"fields":       map[string]string{},
+		}
+		if result.Id != nil {
+			entry["id"] = result.Id.String()
+		}
+		if result.Fields != nil {
+			entry["fields"] = *result.Fields
+		}
+		return tools.JSONResult(entry)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	createTool := mcp.NewTool("create_work_item_from_template",
+		mcp.WithDescription("Create a work item from a team's work item template, with field overrides such as the title and repro steps. The fields are validated against the work item type before anything is created"),
+		mcp.WithString("template",
+			mcp.Required(),
+			mcp.Description("Template name or ID"),
+		),
+		mcp.WithString("team",
+			mcp.Description("Optional team name, defaults to the configured team"),
+		),
+		mcp.WithObject("fields",
+			mcp.Description("Field values that replace or add to the template's, keyed by field reference name (e.g. System.Title)"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(createTool, writeHandler(client, createTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		template, ok := request.Params.Arguments["template"].(string)
+		if !ok {
+			log.Print("Template must be a string")
+			return nil, fmt.Errorf("template must be a string")
+		}
+		team, _ := request.Params.Arguments["team"].(string)
+		fields, _ := request.Params.Arguments["fields"].(map[string]interface{})
+
+		result, err := client.createFromTemplate(ctx, team, template, fields, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error creating work item from template: %v", err)
+			return nil, fmt.Errorf("error creating work item from template: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+)
+
+const (
+	// organizationHeader and projectHeader name the tenant of a request in multi-tenant mode. They
+	// are meant to be set by a gateway in front of the server, next to the tenant's credential.
+	organizationHeader = "X-Azure-DevOps-Organization"
+	projectHeader      = "X-Azure-DevOps-Project"
+	// maxTenants caps the tenants whose clients are kept for reuse.
+	maxTenants = 256
+)
+
+// organizationName matches the names Azure DevOps allows for organizations. Names become part of
+// request URLs, so anything else is rejected.
+var organizationName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,49}$`)
+
+// tenant is the clients and MCP server of one organization and project.
+type tenant struct {
+	key     string
+	handler *streamableHandler
+}
+
+// tenantPool serves each request with the MCP server of the organization and project named by its
+// headers, creating the clients of a tenant on its first request and keeping the most recently
+// used ones. Every tenant's Azure DevOps requests are made with the caller's own credential: the
+// pool's connections carry the service credential only for sessionAuthTransport to swap it. The
+// content cache and state store are shared between tenants.
+type tenantPool struct {
+	home    *AzureDevOpsClient
+	allowed map[string]bool
+
+	mu      sync.Mutex
+	tenants map[string]*list.Element
+	order   *list.List
+}
+
+// newTenantPool returns a pool serving the configured organization and project with the clients of
+// home, and other tenants with clients of their own. Every tenant's server has the same tools: the
+// tools of the repository and semantic indexes and of service hook events are left out, since they
+// would answer every tenant's callers from state built with the service credential.
+func newTenantPool(home *AzureDevOpsClient) *tenantPool {
+	pool := &tenantPool{
+		home:    home,
+		allowed: map[string]bool{},
+		tenants: map[string]*list.Element{},
+		order:   list.New(),
+	}
+	for _, organization := range home.config.Server.TenantOrganizations {
+		pool.allowed[strings.ToLower(organization)] = true
+	}
+	key := tenantKey(home.config.AzureDevOps.Organization, home.config.AzureDevOps.Project)
+	pool.tenants[key] = pool.order.PushFront(&tenant{key: key, handler: newStreamableHandler(newMCPServer(home).MCPServer, home.contexts, true)})
+	return pool
+}
+
+func tenantKey(organization, project string) string {
+	return strings.ToLower(organization) + "/" + strings.ToLower(project)
+}
+
+func (p *tenantPool) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	organization := req.Header.Get(organizationHeader)
+	project := req.Header.Get(projectHeader)
+	if organization == "" || project == "" {
+		http.Error(w, fmt.Sprintf("the %s and %s headers are required", organizationHeader, projectHeader), http.StatusBadRequest)
+		return
+	}
+	if !organizationName.MatchString(organization) {
+		http.Error(w, "invalid organization name", http.StatusBadRequest)
+		return
+	}
+	if len(p.allowed) > 0 && !p.allowed[strings.ToLower(organization)] {
+		http.Error(w, fmt.Sprintf("organization %s is not served here", organization), http.StatusForbidden)
+		return
+	}
+	if headerCredential(req.Header).authorization() == "" {
+		http.Error(w, fmt.Sprintf("the %s or %s header is required", patHeader, tokenHeader), http.StatusUnauthorized)
+		return
+	}
+
+	handler, err := p.handler(withCredential(req.Context(), headerCredential(req.Header)), organization, project)
+	if err != nil {
+		log.Printf("Error creating clients of %s/%s: %v", organization, project, err)
+		http.Error(w, fmt.Sprintf("error connecting to organization %s", organization), http.StatusBadGateway)
+		return
+	}
+	handler.ServeHTTP(w, req)
+}
+
+// handler returns the handler of a tenant, creating its clients when it has none. ctx carries the
+// credential of the request, which the first lookups of a new tenant are made with.
+func (p *tenantPool) handler(ctx context.Context, organization, project string) (*streamableHandler, error) {
+	key := tenantKey(organization, project)
+	p.mu.Lock()
+	if element, ok := p.tenants[key]; ok {
+		p.order.MoveToFront(element)
+		p.mu.Unlock()
+		return element.Value.(*tenant).handler, nil
+	}
+	p.mu.Unlock()
+
+	// Tenants are created outside the lock, so a slow organization does not hold up the others;
+	// of two requests creating the same tenant, the first to finish is kept.
+	client, err := p.connect(ctx, organization, project)
+	if err != nil {
+		return nil, err
+	}
+	created := &tenant{key: key, handler: newStreamableHandler(newMCPServer(client).MCPServer, client.contexts, true)}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if element, ok := p.tenants[key]; ok {
+		p.order.MoveToFront(element)
+		return element.Value.(*tenant).handler, nil
+	}
+	p.tenants[key] = p.order.PushFront(created)
+	for p.order.Len() > maxTenants {
+		oldest := p.order.Back()
+		p.order.Remove(oldest)
+		delete(p.tenants, oldest.Value.(*tenant).key)
+	}
+	return created.handler, nil
+}
+
+// connect creates the clients of a tenant. The tenant's configuration is the server's with the
+// organization and project replaced; its team defaults to the project's default team, and it builds
+// no background indexes.
+func (p *tenantPool) connect(ctx context.Context, organization, project string) (*AzureDevOpsClient, error) {
+	config := *p.home.config
+	config.AzureDevOps.Organization = organization
+	config.AzureDevOps.URL = ""
+	config.AzureDevOps.Project = project
+	config.AzureDevOps.Team = ""
+	config.RepositoryIndex.Repositories = nil
+	config.SemanticSearch.Repositories = nil
+
+	links := newWebLinks(organization, "")
+	connection := azuredevops.NewPatConnection(links.base, p.home.config.AzureDevOps.PAT)
+	return newAzureDevOpsClient(ctx, &config, connection, p.home)
+}
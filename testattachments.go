@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/test"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxTestAttachmentBytes caps the size of a downloaded test attachment, which is returned in the
+// tool result, base64 encoded unless it is text.
+const maxTestAttachmentBytes = 10 << 20
+
+// textAttachmentExtensions are returned as text when they are valid UTF-8, whatever their MIME type.
+var textAttachmentExtensions = map[string]bool{
+	".log": true, ".txt": true, ".trx": true, ".xml": true, ".json": true, ".stacktrace": true,
+}
+
+// testAttachments lists the attachments of a test run or, when resultID is not 0, of one of its
+// results.
+func (c *AzureDevOpsClient) testAttachments(ctx context.Context, runID, resultID int) ([]test.TestAttachment, error) {
+	var attachments *[]test.TestAttachment
+	var err error
+	if resultID != 0 {
+		attachments, err = c.testClient.GetTestResultAttachments(ctx, test.GetTestResultAttachmentsArgs{
+			Project:          &c.config.AzureDevOps.Project,
+			RunId:            &runID,
+			TestCaseResultId: &resultID,
+		})
+	} else {
+		attachments, err = c.testClient.GetTestRunAttachments(ctx, test.GetTestRunAttachmentsArgs{
+			Project: &c.config.AzureDevOps.Project,
+			RunId:   &runID,
+		})
+	}
+	if err != nil {
+		log.Printf("Error getting test attachments: %v", err)
+		return nil, fmt.Errorf("error getting attachments of test run %d: %w", runID, err)
+	}
+	return *attachments, nil
+}
+
+// testAttachmentURL is the REST URL of a test attachment, used to name it as a resource.
+func (c *AzureDevOpsClient) testAttachmentURL(runID, resultID, attachmentID int) string {
+	url := fmt.Sprintf("%s/_apis/test/Runs/%d", c.links.project(c.config.AzureDevOps.Project), runID)
+	if resultID != 0 {
+		url += fmt.Sprintf("/Results/%d", resultID)
+	}
+	return url + fmt.Sprintf("/Attachments/%d", attachmentID)
+}
+
+// listTestAttachments describes the attachments of a test run or result, such as screenshots,
+// logs, and crash dumps.
+func (c *AzureDevOpsClient) listTestAttachments(ctx context.Context, runID, resultID int) ([]map[string]interface{}, error) {
+	attachments, err := c.testAttachments(ctx, runID, resultID)
+	if err != nil {
+		return nil, err
+	}
+	list := []map[string]interface{}{}
+	for _, attachment := range attachments {
+		if attachment.Id == nil {
+			continue
+		}
+		entry := map[string]interface{}{
+			"id":       *attachment.Id,
+			"fileName": stringValue(attachment.FileName),
+			"uri":      c.testAttachmentURL(runID, resultID, *attachment.Id),
+		}
+		if attachment.AttachmentType != nil {
+			entry["type"] = string(*attachment.AttachmentType)
+		}
+		if attachment.Size != nil {
+			entry["size"] = *attachment.Size
+		}
+		if comment := stringValue(attachment.Comment); comment != "" {
+			entry["comment"] = comment
+		}
+		if attachment.CreatedDate != nil {
+			entry["createdDate"] = attachment.CreatedDate.Time.Format(time.RFC3339)
+		}
+		list = append(list, entry)
+	}
+	return list, nil
+}
+
+// downloadTestAttachment returns an attachment of a test run or result as a resource: text for
+// logs and other UTF-8 text, a base64 blob for screenshots, dumps, and other binaries.
+func (c *AzureDevOpsClient) downloadTestAttachment(ctx context.Context, runID, resultID, attachmentID int) (mcp.ResourceContents, string, error) {
+	attachments, err := c.testAttachments(ctx, runID, resultID)
+	if err != nil {
+		return nil, "", err
+	}
+	var attachment *test.TestAttachment
+	for i := range attachments {
+		if attachments[i].Id != nil && *attachments[i].Id == attachmentID {
+			attachment = &attachments[i]
+			break
+		}
+	}
+	if attachment == nil {
+		return nil, "", fmt.Errorf("attachment %d not found; list_test_attachments lists them", attachmentID)
+	}
+	if attachment.Size != nil && *attachment.Size > maxTestAttachmentBytes {
+		return nil, "", fmt.Errorf("attachment %d is %d bytes, more than the %d MB that can be downloaded", attachmentID, *attachment.Size, maxTestAttachmentBytes>>20)
+	}
+
+	var reader io.ReadCloser
+	if resultID != 0 {
+		reader, err = c.testClient.GetTestResultAttachmentContent(ctx, test.GetTestResultAttachmentContentArgs{
+			Project:          &c.config.AzureDevOps.Project,
+			RunId:            &runID,
+			TestCaseResultId: &resultID,
+			AttachmentId:     &attachmentID,
+		})
+	} else {
+		reader, err = c.testClient.GetTestRunAttachmentContent(ctx, test.GetTestRunAttachmentContentArgs{
+			Project:      &c.config.AzureDevOps.Project,
+			RunId:        &runID,
+			AttachmentId: &attachmentID,
+		})
+	}
+	if err != nil {
+		log.Printf("Error downloading test attachment: %v", err)
+		return nil, "", fmt.Errorf("error downloading attachment %d: %w", attachmentID, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxTestAttachmentBytes+1))
+	if err != nil {
+		log.Printf("Error reading test attachment: %v", err)
+		return nil, "", fmt.Errorf("error reading attachment %d: %w", attachmentID, err)
+	}
+	if len(data) > maxTestAttachmentBytes {
+		return nil, "", fmt.Errorf("attachment %d exceeds %d MB", attachmentID, maxTestAttachmentBytes>>20)
+	}
+
+	fileName := stringValue(attachment.FileName)
+	uri := c.testAttachmentURL(runID, resultID, attachmentID)
+	extension := strings.ToLower(path.Ext(fileName))
+	mimeType := mime.TypeByExtension(extension)
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	description := fmt.Sprintf("Attachment %s of test run %d (%d bytes)", fileName, runID, len(data))
+	if resultID != 0 {
+		description = fmt.Sprintf("Attachment %s of result %d of test run %d (%d bytes)", fileName, resultID, runID, len(data))
+	}
+
+	text := strings.HasPrefix(mimeType, "text/") || textAttachmentExtensions[extension]
+	if text && utf8.Valid(data) {
+		if !strings.HasPrefix(mimeType, "text/") {
+			mimeType = "text/plain"
+		}
+		return mcp.TextResourceContents{URI: uri, MIMEType: mimeType, Text: string(data)}, description, nil
+	}
+	return mcp.BlobResourceContents{
+		URI:      uri,
+		MIMEType: mimeType,
+		Blob:     base64.StdEncoding.EncodeToString(data),
+	}, description, nil
+}
+
+func addTestTools(s tools.Server, client *AzureDevOpsClient) {
+	listTool := mcp.NewTool("list_test_attachments",
+		mcp.WithDescription("List the attachments of a test run, or of one of its results: screenshots, logs, crash dumps, and other evidence of a failure"),
+		mcp.WithNumber("runId",
+			mcp.Required(),
+			mcp.Description("Test run ID"),
+		),
+		mcp.WithNumber("resultId",
+			mcp.Description("Test result ID within the run; lists the run's own attachments when left out"),
+		),
+	)
+
+	s.AddTool(listTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		runID, ok := request.Params.Arguments["runId"].(float64)
+		if !ok {
+			log.Print("Run ID must be a number")
+			return nil, fmt.Errorf("runId must be a number")
+		}
+		resultID, _ := request.Params.Arguments["resultId"].(float64)
+
+		result, err := client.listTestAttachments(ctx, int(runID), int(resultID))
+		if err != nil {
+			log.Printf("Error listing test attachments: %v", err)
+			return nil, fmt.Errorf("error listing test attachments: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	downloadTool := mcp.NewTool("download_test_attachment",
+		mcp.WithDescription(fmt.Sprintf("Download an attachment of a test run or result as a resource: logs and other text as text, screenshots and dumps as binary. Attachments are limited to %d MB", maxTestAttachmentBytes>>20)),
+		mcp.WithNumber("runId",
+			mcp.Required(),
+			mcp.Description("Test run ID"),
+		),
+		mcp.WithNumber("resultId",
+			mcp.Description("Test result ID, for an attachment of a result rather than of the run"),
+		),
+		mcp.WithNumber("attachmentId",
+			mcp.Required(),
+			mcp.Description("Attachment ID, from list_test_attachments"),
+		),
+	)
+
+	s.AddTool(downloadTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		runID, ok := request.Params.Arguments["runId"].(float64)
+		if !ok {
+			log.Print("Run ID must be a number")
+			return nil, fmt.Errorf("runId must be a number")
+		}
+		resultID, _ := request.Params.Arguments["resultId"].(float64)
+		attachmentID, ok := request.Params.Arguments["attachmentId"].(float64)
+		if !ok {
+			log.Print("Attachment ID must be a number")
+			return nil, fmt.Errorf("attachmentId must be a number")
+		}
+
+		resource, description, err := client.downloadTestAttachment(ctx, int(runID), int(resultID), int(attachmentID))
+		if err != nil {
+			log.Printf("Error downloading test attachment: %v", err)
+			return nil, fmt.Errorf("error downloading test attachment: %w", err)
+		}
+
+		return mcp.NewToolResultResource(description, resource), nil
+	})
+}
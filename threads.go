@@ -0,0 +1,587 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/location"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// maxThreadPullRequests caps the active pull requests whose threads are read when no pull request
+// is given; each costs a request.
+const maxThreadPullRequests = 50
+
+// threadStatuses are the statuses a thread can be resolved with.
+var threadStatuses = map[string]git.CommentThreadStatus{
+	"fixed":    git.CommentThreadStatusValues.Fixed,
+	"wontFix":  git.CommentThreadStatusValues.WontFix,
+	"closed":   git.CommentThreadStatusValues.Closed,
+	"byDesign": git.CommentThreadStatusValues.ByDesign,
+}
+
+// threadUser is the user whose threads are listed.
+type threadUser struct {
+	ID   string
+	Name string
+}
+
+// currentUser returns the user the server authenticates to Azure DevOps as.
+func (c *AzureDevOpsClient) currentUser(ctx context.Context) (threadUser, error) {
+	data, err := c.locationClient.GetConnectionData(ctx, location.GetConnectionDataArgs{})
+	if err != nil {
+		log.Printf("Error getting connection data: %v", err)
+		return threadUser{}, fmt.Errorf("error getting the authenticated user: %w", err)
+	}
+	if data.AuthenticatedUser == nil || data.AuthenticatedUser.Id == nil {
+		return threadUser{}, fmt.Errorf("the authenticated user is not known")
+	}
+	return threadUser{ID: data.AuthenticatedUser.Id.String(), Name: stringValue(data.AuthenticatedUser.ProviderDisplayName)}, nil
+}
+
+// threadUserFor returns the user an identity ID, name, or email names, or the authenticated user
+// when query is "".
+func (c *AzureDevOpsClient) threadUserFor(ctx context.Context, query string) (threadUser, error) {
+	if query == "" {
+		return c.currentUser(ctx)
+	}
+	if _, err := uuid.Parse(query); err == nil {
+		return threadUser{ID: query, Name: query}, nil
+	}
+	identities, err := c.resolveIdentities(ctx, query)
+	if err != nil {
+		return threadUser{}, err
+	}
+	users := []ResolvedIdentity{}
+	for _, identity := range identities {
+		if !identity.IsGroup {
+			users = append(users, identity)
+		}
+	}
+	switch len(users) {
+	case 0:
+		return threadUser{}, fmt.Errorf("no user matches %s", query)
+	case 1:
+		return threadUser{ID: users[0].ID, Name: users[0].DisplayName}, nil
+	}
+	names := []string{}
+	for _, user := range users {
+		names = append(names, fmt.Sprintf("%s (%s)", user.DisplayName, user.ID))
+	}
+	return threadUser{}, fmt.Errorf("%s matches %d users: %s; pass an email or identity ID", query, len(users), strings.Join(names, ", "))
+}
+
+// mentions reports whether a comment @mentions a user, which Azure DevOps stores as @<id>.
+func mentions(content, userID string) bool {
+	return strings.Contains(strings.ToLower(content), "@<"+strings.ToLower(userID)+">")
+}
+
+// isUserThread reports whether a thread is comments from people rather than system messages, such
+// as votes and pushes, which cannot be resolved.
+func isUserThread(thread git.GitPullRequestCommentThread) bool {
+	if thread.IsDeleted != nil && *thread.IsDeleted || thread.Comments == nil || len(*thread.Comments) == 0 {
+		return false
+	}
+	first := (*thread.Comments)[0]
+	return first.CommentType == nil || *first.CommentType != git.CommentTypeValues.System
+}
+
+// isUnresolved reports whether a thread still waits for an answer.
+func isUnresolved(thread git.GitPullRequestCommentThread) bool {
+	return thread.Status != nil && (*thread.Status == git.CommentThreadStatusValues.Active || *thread.Status == git.CommentThreadStatusValues.Pending)
+}
+
+// threadSummary lists the comments of a thread with where in the code it is.
+func threadSummary(thread git.GitPullRequestCommentThread) map[string]interface{} {
+	entry := map[string]interface{}{}
+	if thread.Id != nil {
+		entry["id"] = *thread.Id
+	}
+	if thread.Status != nil {
+		entry["status"] = string(*thread.Status)
+	}
+	if context := thread.ThreadContext; context != nil && context.FilePath != nil {
+		entry["filePath"] = *context.FilePath
+		if context.RightFileStart != nil && context.RightFileStart.Line != nil {
+			entry["line"] = *context.RightFileStart.Line
+		} else if context.LeftFileStart != nil && context.LeftFileStart.Line != nil {
+			entry["line"] = *context.LeftFileStart.Line
+		}
+	}
+	if thread.LastUpdatedDate != nil {
+		entry["lastUpdated"] = thread.LastUpdatedDate.Time.Format(time.RFC3339)
+	}
+	comments := []map[string]interface{}{}
+	for _, comment := range *thread.Comments {
+		if comment.IsDeleted != nil && *comment.IsDeleted {
+			continue
+		}
+		item := map[string]interface{}{
+			"content": stringValue(comment.Content),
+		}
+		if comment.Author != nil {
+			item["author"] = stringValue(comment.Author.DisplayName)
+		}
+		comments = append(comments, item)
+	}
+	entry["comments"] = comments
+	return entry
+}
+
+// userThreads returns the unresolved threads for a user: those on pull requests the user created,
+// other than the user's own, and those that @mention the user. Azure DevOps does not assign threads
+// to people, so these are the threads waiting on the user. Without prID the active pull requests
+// of the project, or of repoName, are searched.
+func (c *AzureDevOpsClient) userThreads(ctx context.Context, query string, prID int, repoName string) (map[string]interface{}, error) {
+	user, err := c.threadUserFor(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var pullRequests []git.GitPullRequest
+	if prID > 0 {
+		pr, err := c.getPullRequest(ctx, prID)
+		if err != nil {
+			return nil, err
+		}
+		pullRequests = []git.GitPullRequest{*pr}
+	} else {
+		status := git.PullRequestStatusValues.Active
+		top := maxThreadPullRequests
+		criteria := &git.GitPullRequestSearchCriteria{Status: &status}
+		var page *[]git.GitPullRequest
+		if repoName != "" {
+			repo, err := c.findRepository(ctx, repoName)
+			if err != nil {
+				return nil, err
+			}
+			repoID := repo.Id.String()
+			page, err = c.gitClient.GetPullRequests(ctx, git.GetPullRequestsArgs{
+				RepositoryId:   &repoID,
+				Project:        &c.config.AzureDevOps.Project,
+				SearchCriteria: criteria,
+				Top:            &top,
+			})
+		} else {
+			page, err = c.gitClient.GetPullRequestsByProject(ctx, git.GetPullRequestsByProjectArgs{
+				Project:        &c.config.AzureDevOps.Project,
+				SearchCriteria: criteria,
+				Top:            &top,
+			})
+		}
+		if err != nil {
+			log.Printf("Error getting pull requests: %v", err)
+			return nil, fmt.Errorf("error getting active pull requests: %w", err)
+		}
+		pullRequests = *page
+	}
+
+	results := []map[string]interface{}{}
+	errors := map[string]string{}
+	for _, pr := range pullRequests {
+		if pr.Repository == nil || pr.Repository.Id == nil || pr.PullRequestId == nil {
+			continue
+		}
+		repoID := pr.Repository.Id.String()
+		threads, err := c.gitClient.GetThreads(ctx, git.GetThreadsArgs{
+			RepositoryId:  &repoID,
+			PullRequestId: pr.PullRequestId,
+			Project:       &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error getting pull request threads: %v", err)
+			errors[fmt.Sprint(*pr.PullRequestId)] = err.Error()
+			continue
+		}
+		ownPR := pr.CreatedBy != nil && strings.EqualFold(stringValue(pr.CreatedBy.Id), user.ID)
+
+		for _, thread := range *threads {
+			if !isUserThread(thread) || !isUnresolved(thread) {
+				continue
+			}
+			first := (*thread.Comments)[0]
+			startedByUser := first.Author != nil && strings.EqualFold(stringValue(first.Author.Id), user.ID)
+			reasons := []string{}
+			if ownPR && !startedByUser {
+				reasons = append(reasons, "author")
+			}
+			for _, comment := range *thread.Comments {
+				if mentions(stringValue(comment.Content), user.ID) {
+					reasons = append(reasons, "mentioned")
+					break
+				}
+			}
+			if len(reasons) == 0 {
+				continue
+			}
+
+			entry := threadSummary(thread)
+			entry["reasons"] = reasons
+			entry["pullRequestId"] = *pr.PullRequestId
+			entry["pullRequestTitle"] = stringValue(pr.Title)
+			entry["webUrl"] = c.links.pullRequest(c.config.AzureDevOps.Project, stringValue(pr.Repository.Name), *pr.PullRequestId)
+			results = append(results, entry)
+		}
+	}
+	// Most recently active first; RFC 3339 times of one zone sort as strings.
+	sort.SliceStable(results, func(i, j int) bool {
+		return fmt.Sprint(results[i]["lastUpdated"]) > fmt.Sprint(results[j]["lastUpdated"])
+	})
+
+	result := map[string]interface{}{
+		"user":                 user.Name,
+		"userId":               user.ID,
+		"pullRequestsSearched": len(pullRequests),
+		"threads":              results,
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// resolveThreads sets the status of threads of a pull request, after adding comment to each when
+// it is given. @mentions in comment are resolved so the people mentioned are notified.
+func (c *AzureDevOpsClient) resolveThreads(ctx context.Context, prID int, threadIDs []int, status git.CommentThreadStatus, comment string, dryRun bool) (map[string]interface{}, error) {
+	comment, mentioned, err := c.resolveMentions(ctx, comment, markdownMentions)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := c.getPullRequest(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Repository == nil || pr.Repository.Id == nil {
+		return nil, fmt.Errorf("pull request %d has no repository", prID)
+	}
+	repoID := pr.Repository.Id.String()
+
+	threads, err := c.gitClient.GetThreads(ctx, git.GetThreadsArgs{
+		RepositoryId:  &repoID,
+		PullRequestId: &prID,
+		Project:       &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting pull request threads: %v", err)
+		return nil, fmt.Errorf("error getting threads of pull request %d: %w", prID, err)
+	}
+	byID := map[int]git.GitPullRequestCommentThread{}
+	for _, thread := range *threads {
+		if thread.Id != nil && isUserThread(thread) {
+			byID[*thread.Id] = thread
+		}
+	}
+	for _, id := range threadIDs {
+		if _, ok := byID[id]; !ok {
+			return nil, fmt.Errorf("pull request %d has no comment thread %d", prID, id)
+		}
+	}
+
+	if dryRun {
+		changes := []map[string]interface{}{}
+		for _, id := range threadIDs {
+			entry := threadSummary(byID[id])
+			entry["newStatus"] = string(status)
+			changes = append(changes, entry)
+		}
+		preview := map[string]interface{}{
+			"pullRequestId": prID,
+			"threads":       changes,
+		}
+		if comment != "" {
+			preview["comment"] = comment
+		}
+		if len(mentioned) > 0 {
+			preview["mentions"] = mentionNames(mentioned)
+		}
+		return tools.DryRunResult("resolve_pr_threads", preview), nil
+	}
+
+	resolved := []map[string]interface{}{}
+	errors := map[string]string{}
+	for _, id := range threadIDs {
+		threadID := id
+		if comment != "" {
+			content := comment
+			_, err := c.gitClient.CreateComment(ctx, git.CreateCommentArgs{
+				Comment:       &git.Comment{Content: &content},
+				RepositoryId:  &repoID,
+				PullRequestId: &prID,
+				ThreadId:      &threadID,
+				Project:       &c.config.AzureDevOps.Project,
+			})
+			if err != nil {
+				log.Printf("Error adding comment: %v", err)
+				errors[fmt.Sprint(id)] = err.Error()
+				continue
+			}
+		}
+		thread, err := c.gitClient.UpdateThread(ctx, git.UpdateThreadArgs{
+			CommentThread: &git.GitPullRequestCommentThread{Status: &status},
+			RepositoryId:  &repoID,
+			PullRequestId: &prID,
+			ThreadId:      &threadID,
+			Project:       &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error updating thread: %v", err)
+			errors[fmt.Sprint(id)] = err.Error()
+			continue
+		}
+		resolved = append(resolved, map[string]interface{}{
+			"id":     id,
+			"status": string(*thread.Status),
+		})
+	}
+
+	result := map[string]interface{}{
+		"pullRequestId": prID,
+		"resolved":      resolved,
+	}
+	if len(mentioned) > 0 {
+		result["mentions"] = mentionNames(mentioned)
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+// addPullRequestComment posts content to a pull request as a new thread, or as a reply to threadID
+// when it is not 0. @mentions in content are resolved so the people mentioned are notified.
+func (c *AzureDevOpsClient) addPullRequestComment(ctx context.Context, prID, threadID int, content string, dryRun bool) (map[string]interface{}, error) {
+	content, mentioned, err := c.resolveMentions(ctx, content, markdownMentions)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := c.getPullRequest(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Repository == nil || pr.Repository.Id == nil {
+		return nil, fmt.Errorf("pull request %d has no repository", prID)
+	}
+	repoID := pr.Repository.Id.String()
+
+	if dryRun {
+		changes := map[string]interface{}{
+			"pullRequestId": prID,
+			"content":       content,
+			"mentions":      mentionNames(mentioned),
+		}
+		if threadID != 0 {
+			changes["threadId"] = threadID
+		}
+		return tools.DryRunResult("add_pr_comment", changes), nil
+	}
+
+	result := map[string]interface{}{
+		"pullRequestId": prID,
+		"mentions":      mentionNames(mentioned),
+	}
+	if threadID != 0 {
+		comment, err := c.gitClient.CreateComment(ctx, git.CreateCommentArgs{
+			Comment:       &git.Comment{Content: &content},
+			RepositoryId:  &repoID,
+			PullRequestId: &prID,
+			ThreadId:      &threadID,
+			Project:       &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error adding comment: %v", err)
+			return nil, fmt.Errorf("error replying to thread %d: %w", threadID, err)
+		}
+		result["threadId"] = threadID
+		if comment.Id != nil {
+			result["commentId"] = *comment.Id
+		}
+		return result, nil
+	}
+
+	status := git.CommentThreadStatusValues.Active
+	thread, err := c.gitClient.CreateThread(ctx, git.CreateThreadArgs{
+		CommentThread: &git.GitPullRequestCommentThread{
+			Comments: &[]git.Comment{{Content: &content}},
+			Status:   &status,
+		},
+		RepositoryId:  &repoID,
+		PullRequestId: &prID,
+		Project:       &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error creating thread: %v", err)
+		return nil, fmt.Errorf("error commenting on pull request %d: %w", prID, err)
+	}
+	if thread.Id != nil {
+		result["threadId"] = *thread.Id
+	}
+	return result, nil
+}
+
+func addThreadTools(s tools.Server, client *AzureDevOpsClient) {
+	listTool := mcp.NewTool("list_my_pr_threads",
+		mcp.WithDescription("List the unresolved pull request comment threads waiting on a user: feedback on pull requests the user created and threads that @mention the user, with file, line, and comments. Pass a pull request to look at only that one; otherwise active pull requests are searched"),
+		mcp.WithString("user",
+			mcp.Description("Identity ID, email, or name of the user; defaults to the user the server authenticates as"),
+		),
+		mcp.WithNumber("pullRequestId",
+			mcp.Description("Pull request to list threads of"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the pull request"),
+		),
+		mcp.WithString("repository",
+			mcp.Description("Repository whose active pull requests are searched, when no pull request is given"),
+		),
+	)
+
+	s.AddTool(listTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		user, _ := request.Params.Arguments["user"].(string)
+		repo, _ := request.Params.Arguments["repository"].(string)
+		prID := 0
+		if value, ok := request.Params.Arguments["pullRequestId"].(float64); ok {
+			prID = int(value)
+		} else if pr, ok, err := client.uriArgument(request, pullRequestEntity); err != nil {
+			log.Printf("Invalid pull request: %v", err)
+			return nil, err
+		} else if ok {
+			prID = pr.ID
+		}
+
+		result, err := client.userThreads(ctx, user, prID, repo)
+		if err != nil {
+			log.Printf("Error listing pull request threads: %v", err)
+			return nil, fmt.Errorf("error listing pull request threads: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	resolveTool := mcp.NewTool("resolve_pr_threads",
+		mcp.WithDescription("Resolve comment threads of a pull request, e.g. the ones from list_my_pr_threads once addressed, optionally replying to each first"),
+		mcp.WithNumber("pullRequestId",
+			mcp.Description("Pull request ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the pull request"),
+		),
+		mcp.WithArray("threadIds",
+			mcp.Required(),
+			mcp.Description("IDs of the threads to resolve"),
+			mcp.Items(map[string]interface{}{"type": "number"}),
+		),
+		mcp.WithString("status",
+			mcp.Description("Status to set"),
+			mcp.Enum("fixed", "wontFix", "closed", "byDesign"),
+			mcp.DefaultString("fixed"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Reply to add to each thread before resolving it, e.g. how the feedback was addressed. "+tools.MentionsDescription),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(resolveTool, writeHandler(client, resolveTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := client.idArgument(request, "pullRequestId", pullRequestEntity)
+		if err != nil {
+			log.Printf("Invalid pull request: %v", err)
+			return nil, err
+		}
+		rawIDs, ok := request.Params.Arguments["threadIds"].([]interface{})
+		if !ok || len(rawIDs) == 0 {
+			log.Print("Thread IDs must be a non-empty array")
+			return nil, fmt.Errorf("threadIds must list at least one thread")
+		}
+		threadIDs := []int{}
+		for _, raw := range rawIDs {
+			threadID, ok := raw.(float64)
+			if !ok {
+				log.Print("Thread IDs must be numbers")
+				return nil, fmt.Errorf("threadIds must be numbers")
+			}
+			threadIDs = append(threadIDs, int(threadID))
+		}
+		statusName, _ := request.Params.Arguments["status"].(string)
+		if statusName == "" {
+			statusName = "fixed"
+		}
+		status, ok := threadStatuses[statusName]
+		if !ok {
+			log.Printf("Invalid thread status: %s", statusName)
+			return nil, fmt.Errorf("status must be fixed, wontFix, closed, or byDesign")
+		}
+		comment, _ := request.Params.Arguments["comment"].(string)
+
+		result, err := client.resolveThreads(ctx, id, threadIDs, status, comment, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error resolving pull request threads: %v", err)
+			return nil, fmt.Errorf("error resolving pull request threads: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	commentTool := mcp.NewTool("add_pr_comment",
+		mcp.WithDescription("Comment on a pull request in a new thread, or reply to a thread. "+tools.MentionsDescription),
+		mcp.WithNumber("pullRequestId",
+			mcp.Description("Pull request ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the pull request"),
+		),
+		mcp.WithString("content",
+			mcp.Required(),
+			mcp.Description("Markdown text of the comment"),
+		),
+		mcp.WithNumber("threadId",
+			mcp.Description("Thread to reply to; a new thread is started without one"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(commentTool, writeHandler(client, commentTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := client.idArgument(request, "pullRequestId", pullRequestEntity)
+		if err != nil {
+			log.Printf("Invalid pull request: %v", err)
+			return nil, err
+		}
+		content, ok := request.Params.Arguments["content"].(string)
+		if !ok || strings.TrimSpace(content) == "" {
+			log.Print("Content must be a string")
+			return nil, fmt.Errorf("content must be a non-empty string")
+		}
+		threadID := 0
+		if value, ok := request.Params.Arguments["threadId"].(float64); ok {
+			threadID = int(value)
+		}
+
+		result, err := client.addPullRequestComment(ctx, id, threadID, content, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error commenting on pull request: %v", err)
+			return nil, fmt.Errorf("error commenting on pull request: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
@@ -0,0 +1,51 @@
+package tools
+
+import "fmt"
+
+// IntSliceArgument reads an array of numbers from the tool arguments.
+func IntSliceArgument(arguments map[string]interface{}, key string) ([]int, error) {
+	raw, ok := arguments[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	values, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of numbers", key)
+	}
+
+	result := make([]int, 0, len(values))
+	for _, v := range values {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of numbers", key)
+		}
+		result = append(result, int(n))
+	}
+
+	return result, nil
+}
+
+// StringSliceArgument reads an array of strings from the tool arguments.
+func StringSliceArgument(arguments map[string]interface{}, key string) ([]string, error) {
+	raw, ok := arguments[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	values, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings", key)
+	}
+
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of strings", key)
+		}
+		result = append(result, s)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,250 @@
+// Package pipelines registers the tools that report on, create, and change YAML pipelines.
+package pipelines
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// Client is what the pipeline tools need of an Azure DevOps organization. Pipelines are named by
+// ID or name.
+type Client interface {
+	tools.Writer
+	// PipelineTriggers reports what starts a pipeline.
+	PipelineTriggers(ctx context.Context, pipeline string) (map[string]interface{}, error)
+	// PipelineInputs lists the runtime parameters and queue-time variables of a pipeline, and
+	// checks those of a planned run when given.
+	PipelineInputs(ctx context.Context, pipeline, branch string, runParameters, runVariables map[string]interface{}) (map[string]interface{}, error)
+	CreatePipeline(ctx context.Context, name, repoName, yamlPath, folder, defaultBranch string, variables map[string]*build.BuildDefinitionVariable, dryRun bool) (map[string]interface{}, error)
+	// UpdatePipeline changes the settings of a pipeline that are not empty, and the variables
+	// given, removing those that are nil.
+	UpdatePipeline(ctx context.Context, pipeline, name, folder, yamlPath, defaultBranch string, variables map[string]*build.BuildDefinitionVariable, dryRun bool) (map[string]interface{}, error)
+}
+
+// NewGroup returns the pipelines tool group of client.
+func NewGroup(client Client) tools.Group {
+	return tools.NewGroup("pipelines", func(s tools.Server) { register(s, client) })
+}
+
+// variablesArgument reads pipeline variables from a tool argument that maps each name to a value
+// or to {value, isSecret, allowOverride}. A null value, which removes a variable on update, is
+// returned as nil.
+func variablesArgument(arguments map[string]interface{}) (map[string]*build.BuildDefinitionVariable, error) {
+	raw, ok := arguments["variables"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	variables := map[string]*build.BuildDefinitionVariable{}
+	for name, value := range raw {
+		switch value := value.(type) {
+		case nil:
+			variables[name] = nil
+		case map[string]interface{}:
+			variable := &build.BuildDefinitionVariable{}
+			if text, ok := value["value"]; ok {
+				variable.Value = &[]string{fmt.Sprint(text)}[0]
+			}
+			if secret, ok := value["isSecret"].(bool); ok {
+				variable.IsSecret = &secret
+			}
+			if allowOverride, ok := value["allowOverride"].(bool); ok {
+				variable.AllowOverride = &allowOverride
+			}
+			variables[name] = variable
+		case string, float64, bool:
+			variables[name] = &build.BuildDefinitionVariable{Value: &[]string{fmt.Sprint(value)}[0]}
+		default:
+			return nil, fmt.Errorf("variable %s must be a value or an object with value, isSecret, and allowOverride", name)
+		}
+	}
+	return variables, nil
+}
+
+func register(s tools.Server, client Client) {
+	triggersTool := mcp.NewTool("get_pipeline_triggers",
+		mcp.WithDescription("Report what starts a pipeline: CI branch and path filters, pull request triggers, cron schedules (in UTC), and pipeline completion triggers, from its definition and its YAML file on the default branch, with the reason each of its latest runs started. Answers questions like why a build started at 3am"),
+		mcp.WithString("pipeline",
+			mcp.Required(),
+			mcp.Description("Pipeline ID or name"),
+		),
+	)
+
+	s.AddTool(triggersTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pipeline, ok := request.Params.Arguments["pipeline"].(string)
+		if !ok {
+			log.Print("Pipeline must be a string")
+			return nil, fmt.Errorf("pipeline must be a string")
+		}
+
+		result, err := client.PipelineTriggers(ctx, pipeline)
+		if err != nil {
+			log.Printf("Error getting pipeline triggers: %v", err)
+			return nil, fmt.Errorf("error getting pipeline triggers: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	inputsTool := mcp.NewTool("get_pipeline_parameters",
+		mcp.WithDescription("List what can be passed when running a pipeline: the runtime parameters of its YAML file (name, type, default, allowed values, and whether required) and the variables settable at queue time. Pass parameters and/or variables to check a run's inputs before queueing it"),
+		mcp.WithString("pipeline",
+			mcp.Required(),
+			mcp.Description("Pipeline ID or name"),
+		),
+		mcp.WithString("branch",
+			mcp.Description("Branch whose YAML file to read, as a run of that branch would; defaults to the pipeline's default branch"),
+		),
+		mcp.WithObject("parameters",
+			mcp.Description("Runtime parameter values of a planned run to check, e.g. {\"environment\": \"staging\"}"),
+		),
+		mcp.WithObject("variables",
+			mcp.Description("Variable values of a planned run to check"),
+		),
+	)
+
+	s.AddTool(inputsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pipeline, ok := request.Params.Arguments["pipeline"].(string)
+		if !ok {
+			log.Print("Pipeline must be a string")
+			return nil, fmt.Errorf("pipeline must be a string")
+		}
+		branch, _ := request.Params.Arguments["branch"].(string)
+		runParameters, _ := request.Params.Arguments["parameters"].(map[string]interface{})
+		runVariables, _ := request.Params.Arguments["variables"].(map[string]interface{})
+
+		result, err := client.PipelineInputs(ctx, pipeline, branch, runParameters, runVariables)
+		if err != nil {
+			log.Printf("Error getting pipeline parameters: %v", err)
+			return nil, fmt.Errorf("error getting pipeline parameters: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+
+	if !client.AllowWrites() {
+		return
+	}
+
+	createTool := mcp.NewTool("create_pipeline",
+		mcp.WithDescription("Create a YAML pipeline that runs a YAML file of a repository, with its triggers taken from the file"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Pipeline name"),
+		),
+		mcp.WithString("repository",
+			mcp.Required(),
+			mcp.Description("Repository name"),
+		),
+		mcp.WithString("yamlPath",
+			mcp.Required(),
+			mcp.Description("Path of the YAML file in the repository, e.g. /azure-pipelines.yml; it must exist on the default branch"),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Pipeline folder, e.g. \\services\\api; defaults to the root"),
+		),
+		mcp.WithString("defaultBranch",
+			mcp.Description("Branch manual and scheduled runs use; defaults to the repository's default branch"),
+		),
+		mcp.WithObject("variables",
+			mcp.Description("Pipeline variables, each a value or {\"value\": ..., \"isSecret\": true, \"allowOverride\": true}"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(createTool, client.WriteHandler(createTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name, ok := request.Params.Arguments["name"].(string)
+		if !ok {
+			log.Print("Name must be a string")
+			return nil, fmt.Errorf("name must be a string")
+		}
+		repo, ok := request.Params.Arguments["repository"].(string)
+		if !ok {
+			log.Print("Repository must be a string")
+			return nil, fmt.Errorf("repository must be a string")
+		}
+		yamlPath, ok := request.Params.Arguments["yamlPath"].(string)
+		if !ok {
+			log.Print("YAML path must be a string")
+			return nil, fmt.Errorf("yamlPath must be a string")
+		}
+		folder, _ := request.Params.Arguments["folder"].(string)
+		defaultBranch, _ := request.Params.Arguments["defaultBranch"].(string)
+		variables, err := variablesArgument(request.Params.Arguments)
+		if err != nil {
+			log.Printf("Invalid variables: %v", err)
+			return nil, err
+		}
+
+		result, err := client.CreatePipeline(ctx, name, repo, yamlPath, folder, defaultBranch, variables, client.DryRun(request))
+		if err != nil {
+			log.Printf("Error creating pipeline: %v", err)
+			return nil, fmt.Errorf("error creating pipeline: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+
+	updateTool := mcp.NewTool("update_pipeline",
+		mcp.WithDescription("Change the name, folder, YAML file, default branch, or variables of a YAML pipeline; settings not passed are kept"),
+		mcp.WithString("pipeline",
+			mcp.Required(),
+			mcp.Description("Pipeline ID or name"),
+		),
+		mcp.WithString("name",
+			mcp.Description("New pipeline name"),
+		),
+		mcp.WithString("folder",
+			mcp.Description("New pipeline folder"),
+		),
+		mcp.WithString("yamlPath",
+			mcp.Description("New path of the YAML file in the repository"),
+		),
+		mcp.WithString("defaultBranch",
+			mcp.Description("New default branch for manual and scheduled runs"),
+		),
+		mcp.WithObject("variables",
+			mcp.Description("Variables to add or change, each a value or {\"value\": ..., \"isSecret\": true, \"allowOverride\": true}; null removes a variable. Others are kept"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(updateTool, client.WriteHandler(updateTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pipeline, ok := request.Params.Arguments["pipeline"].(string)
+		if !ok {
+			log.Print("Pipeline must be a string")
+			return nil, fmt.Errorf("pipeline must be a string")
+		}
+		name, _ := request.Params.Arguments["name"].(string)
+		folder, _ := request.Params.Arguments["folder"].(string)
+		yamlPath, _ := request.Params.Arguments["yamlPath"].(string)
+		defaultBranch, _ := request.Params.Arguments["defaultBranch"].(string)
+		variables, err := variablesArgument(request.Params.Arguments)
+		if err != nil {
+			log.Printf("Invalid variables: %v", err)
+			return nil, err
+		}
+
+		result, err := client.UpdatePipeline(ctx, pipeline, name, folder, yamlPath, defaultBranch, variables, client.DryRun(request))
+		if err != nil {
+			log.Printf("Error updating pipeline: %v", err)
+			return nil, fmt.Errorf("error updating pipeline: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
@@ -0,0 +1,119 @@
+package pipelines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+)
+
+// fakeServer keeps the handlers of the tools added to it by name.
+type fakeServer struct {
+	handlers map[string]server.ToolHandlerFunc
+}
+
+func (s *fakeServer) AddTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	s.handlers[tool.Name] = handler
+}
+
+func (s *fakeServer) AddResource(resource mcp.Resource, handler server.ResourceHandlerFunc) {}
+
+func (s *fakeServer) AddResourceTemplate(template mcp.ResourceTemplate, handler server.ResourceTemplateHandlerFunc) {
+}
+
+// fakeClient records the variables of the pipelines it is asked to create and update.
+type fakeClient struct {
+	writes    bool
+	variables map[string]*build.BuildDefinitionVariable
+	updated   string
+}
+
+func (c *fakeClient) AllowWrites() bool { return c.writes }
+
+func (c *fakeClient) DryRun(request mcp.CallToolRequest) bool { return false }
+
+func (c *fakeClient) WriteHandler(tool mcp.Tool, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return handler
+}
+
+func (c *fakeClient) PipelineTriggers(ctx context.Context, pipeline string) (map[string]interface{}, error) {
+	return map[string]interface{}{"pipeline": pipeline}, nil
+}
+
+func (c *fakeClient) PipelineInputs(ctx context.Context, pipeline, branch string, runParameters, runVariables map[string]interface{}) (map[string]interface{}, error) {
+	return map[string]interface{}{"pipeline": pipeline}, nil
+}
+
+func (c *fakeClient) CreatePipeline(ctx context.Context, name, repoName, yamlPath, folder, defaultBranch string, variables map[string]*build.BuildDefinitionVariable, dryRun bool) (map[string]interface{}, error) {
+	c.variables = variables
+	return map[string]interface{}{"name": name}, nil
+}
+
+func (c *fakeClient) UpdatePipeline(ctx context.Context, pipeline, name, folder, yamlPath, defaultBranch string, variables map[string]*build.BuildDefinitionVariable, dryRun bool) (map[string]interface{}, error) {
+	c.updated, c.variables = pipeline, variables
+	return map[string]interface{}{"pipeline": pipeline}, nil
+}
+
+func registered(client *fakeClient) *fakeServer {
+	s := &fakeServer{handlers: map[string]server.ToolHandlerFunc{}}
+	NewGroup(client).Register(s)
+	return s
+}
+
+func call(s *fakeServer, name string, arguments map[string]interface{}) error {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = arguments
+	_, err := s.handlers[name](context.Background(), request)
+	return err
+}
+
+func TestRegisterLeavesOutWriteToolsWithoutWrites(t *testing.T) {
+	if s := registered(&fakeClient{}); len(s.handlers) != 2 || s.handlers["create_pipeline"] != nil {
+		t.Errorf("read-only tools = %d, want get_pipeline_triggers and get_pipeline_parameters", len(s.handlers))
+	}
+	if s := registered(&fakeClient{writes: true}); s.handlers["create_pipeline"] == nil || s.handlers["update_pipeline"] == nil {
+		t.Error("write tools missing with writes allowed")
+	}
+}
+
+func TestPipelineVariables(t *testing.T) {
+	client := &fakeClient{writes: true}
+	s := registered(client)
+
+	err := call(s, "create_pipeline", map[string]interface{}{
+		"name":       "api",
+		"repository": "api",
+		"yamlPath":   "/azure-pipelines.yml",
+		"variables": map[string]interface{}{
+			"region":  "westeurope",
+			"retries": float64(3),
+			"token":   map[string]interface{}{"value": "s3cret", "isSecret": true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := client.variables["retries"]; v == nil || *v.Value != "3" {
+		t.Errorf("retries = %+v, want 3", v)
+	}
+	if v := client.variables["token"]; v == nil || *v.Value != "s3cret" || v.IsSecret == nil || !*v.IsSecret {
+		t.Errorf("token = %+v, want a secret", v)
+	}
+
+	if err := call(s, "update_pipeline", map[string]interface{}{"pipeline": "api", "variables": map[string]interface{}{"region": nil}}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := client.variables["region"]; client.updated != "api" || !ok || v != nil {
+		t.Errorf("update of %q removed region = %v, %v", client.updated, v, ok)
+	}
+
+	if err := call(s, "create_pipeline", map[string]interface{}{
+		"name": "api", "repository": "api", "yamlPath": "/azure-pipelines.yml",
+		"variables": map[string]interface{}{"bad": []interface{}{"x"}},
+	}); err == nil {
+		t.Error("create_pipeline with a list variable succeeded")
+	}
+}
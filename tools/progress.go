@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressInterval is the least time between two progress notifications of a tool call.
+const progressInterval = time.Second
+
+// progressKey is the context key of the progressTracker of a tool call.
+type progressKey struct{}
+
+// progressTracker sends progress notifications for a tool call whose client asked for them.
+type progressTracker struct {
+	token mcp.ProgressToken
+
+	mu   sync.Mutex
+	sent time.Time
+}
+
+// WithProgress lets a long-running tool report progress with ReportProgress when the client
+// passed a progress token with the call.
+func WithProgress(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if request.Params.Meta != nil && request.Params.Meta.ProgressToken != nil {
+			ctx = context.WithValue(ctx, progressKey{}, &progressTracker{token: request.Params.Meta.ProgressToken})
+		}
+		return handler(ctx, request)
+	}
+}
+
+// ReportProgress tells the client how far the current tool call got, as done out of total, with
+// total 0 when it is unknown. Updates closer than progressInterval are dropped, except the last
+// one. Calls the client did not ask progress for are left alone.
+func ReportProgress(ctx context.Context, done, total int, message string) {
+	tracker, _ := ctx.Value(progressKey{}).(*progressTracker)
+	srv := server.ServerFromContext(ctx)
+	if tracker == nil || srv == nil {
+		return
+	}
+
+	tracker.mu.Lock()
+	now := time.Now()
+	if now.Sub(tracker.sent) < progressInterval && (total == 0 || done < total) {
+		tracker.mu.Unlock()
+		return
+	}
+	tracker.sent = now
+	tracker.mu.Unlock()
+
+	params := map[string]interface{}{
+		"progressToken": tracker.token,
+		"progress":      done,
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	// Progress is advisory; a client that stopped reading notifications still gets the result.
+	_ = srv.SendNotificationToClient(ctx, "notifications/progress", params)
+}
@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Descriptions of the arguments many tools share, so every tool documents them alike.
+const (
+	// DryRunDescription documents the dryRun argument every write tool accepts.
+	DryRunDescription = "Validate the call and return what would change, without changing anything"
+	// IdempotencyKeyDescription documents the idempotencyKey argument every write tool accepts.
+	IdempotencyKeyDescription = "Optional unique key for this change, e.g. a UUID. Repeating a call with the same key returns the first call's result instead of writing again"
+	// URIDescription documents the uri argument of tools that accept an entity URI instead of its
+	// separate arguments.
+	URIDescription = "azdo:// URI from another tool's result; replaces the arguments it names"
+	// MentionsDescription tells the callers of tools that resolve mentions how to mention people.
+	MentionsDescription = "Mention people or groups with @name, @user@example.com, or @[Display Name]; mentions are resolved so they are notified, and a mention that matches no one or several fails the call. Text in backticks is left alone"
+)
+
+// JSONResult marshals v and wraps it in a text tool result.
+func JSONResult(v interface{}) (*mcp.CallToolResult, error) {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error marshaling results: %v", err)
+		return nil, fmt.Errorf("error marshaling results: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// DryRunResult describes a write that was validated but not performed: the action and the exact
+// changes that would have been sent.
+func DryRunResult(action string, changes map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"dryRun":  true,
+		"action":  action,
+		"changes": changes,
+	}
+}
@@ -0,0 +1,103 @@
+// Package tools holds the registry of the server's tool groups and the helpers their tools share.
+// A tool group adds its tools and resources to a Server, so the groups of a server can be listed,
+// left out, or registered on any implementation of it. Groups moved out of the root package live
+// in packages of their own, such as tools/workitems and tools/pipelines, and reach Azure DevOps
+// through an interface of what they need, which the root package's client satisfies; the others
+// are still registered from the root package.
+package tools
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Server is what a tool group adds its tools and resources to.
+type Server interface {
+	AddTool(tool mcp.Tool, handler server.ToolHandlerFunc)
+	AddResource(resource mcp.Resource, handler server.ResourceHandlerFunc)
+	AddResourceTemplate(template mcp.ResourceTemplate, handler server.ResourceTemplateHandlerFunc)
+}
+
+// Writer is what the write tools of a group need of the server they are registered on.
+type Writer interface {
+	// AllowWrites reports whether the server registers write tools at all.
+	AllowWrites() bool
+	// DryRun reports whether a write tool call is only to be validated.
+	DryRun(request mcp.CallToolRequest) bool
+	// WriteHandler wraps the handler of a write tool, answering repeated calls by idempotency key
+	// and asking the user to confirm where configured.
+	WriteHandler(tool mcp.Tool, handler server.ToolHandlerFunc) server.ToolHandlerFunc
+}
+
+// Group is a set of related tools, such as those of work items or pull requests, that are
+// registered together.
+type Group interface {
+	// Name identifies the group, such as workitems.
+	Name() string
+	// Register adds the group's tools and resources to s.
+	Register(s Server)
+}
+
+// funcGroup is a Group registered by a function.
+type funcGroup struct {
+	name     string
+	register func(s Server)
+}
+
+func (g funcGroup) Name() string      { return g.name }
+func (g funcGroup) Register(s Server) { g.register(s) }
+
+// NewGroup returns a Group named name whose tools are added by register.
+func NewGroup(name string, register func(s Server)) Group {
+	return funcGroup{name: name, register: register}
+}
+
+// Registry is the list of tool groups of a server, in the order they are registered.
+type Registry struct {
+	groups []Group
+}
+
+// Add appends groups to the registry. It panics when a group's name is taken, which is a
+// programming error.
+func (r *Registry) Add(groups ...Group) {
+	for _, group := range groups {
+		if r.Group(group.Name()) != nil {
+			panic(fmt.Sprintf("tools: group %s registered twice", group.Name()))
+		}
+		r.groups = append(r.groups, group)
+	}
+}
+
+// Remove takes the group named name out of the registry, if it is there.
+func (r *Registry) Remove(name string) {
+	for i, group := range r.groups {
+		if group.Name() == name {
+			r.groups = append(r.groups[:i:i], r.groups[i+1:]...)
+			return
+		}
+	}
+}
+
+// Group returns the group named name, or nil without one.
+func (r *Registry) Group(name string) Group {
+	for _, group := range r.groups {
+		if group.Name() == name {
+			return group
+		}
+	}
+	return nil
+}
+
+// Groups returns the groups of the registry in the order they were added.
+func (r *Registry) Groups() []Group {
+	return append([]Group(nil), r.groups...)
+}
+
+// Register adds the tools and resources of every group to s.
+func (r *Registry) Register(s Server) {
+	for _, group := range r.groups {
+		group.Register(s)
+	}
+}
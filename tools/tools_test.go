@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// fakeServer records the names of the tools added to it.
+type fakeServer struct {
+	tools []string
+}
+
+func (s *fakeServer) AddTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	s.tools = append(s.tools, tool.Name)
+}
+
+func (s *fakeServer) AddResource(resource mcp.Resource, handler server.ResourceHandlerFunc) {}
+
+func (s *fakeServer) AddResourceTemplate(template mcp.ResourceTemplate, handler server.ResourceTemplateHandlerFunc) {
+}
+
+func toolGroup(name string, toolNames ...string) Group {
+	return NewGroup(name, func(s Server) {
+		for _, toolName := range toolNames {
+			s.AddTool(mcp.NewTool(toolName), nil)
+		}
+	})
+}
+
+func TestRegistryRegistersGroupsInOrder(t *testing.T) {
+	registry := &Registry{}
+	registry.Add(toolGroup("workitems", "get_work_item", "create_work_item"), toolGroup("wiki", "get_wiki_page"), toolGroup("pipelines", "list_pipelines"))
+	registry.Remove("wiki")
+	registry.Remove("unknown")
+
+	s := &fakeServer{}
+	registry.Register(s)
+
+	want := []string{"get_work_item", "create_work_item", "list_pipelines"}
+	if len(s.tools) != len(want) {
+		t.Fatalf("registered %v, want %v", s.tools, want)
+	}
+	for i := range want {
+		if s.tools[i] != want[i] {
+			t.Fatalf("registered %v, want %v", s.tools, want)
+		}
+	}
+	if registry.Group("wiki") != nil {
+		t.Error("removed group wiki is still in the registry")
+	}
+	if len(registry.Groups()) != 2 {
+		t.Errorf("registry has %d groups, want 2", len(registry.Groups()))
+	}
+}
+
+func TestRegistryRejectsDuplicateGroup(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("adding a group twice did not panic")
+		}
+	}()
+	registry := &Registry{}
+	registry.Add(toolGroup("workitems"), toolGroup("workitems"))
+}
@@ -0,0 +1,245 @@
+// Package workitems registers the tools that read work item types and update work items.
+package workitems
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+// Entity is the kind of azdo:// URI that names a work item.
+const Entity = "workitem"
+
+// TypeField describes a field of a work item type as exposed to MCP clients.
+type TypeField struct {
+	Name           string        `json:"name"`
+	ReferenceName  string        `json:"referenceName"`
+	AlwaysRequired bool          `json:"alwaysRequired"`
+	HelpText       string        `json:"helpText,omitempty"`
+	AllowedValues  []interface{} `json:"allowedValues,omitempty"`
+	DefaultValue   interface{}   `json:"defaultValue,omitempty"`
+}
+
+// TypeState describes a state of a work item type and the states it can move to.
+type TypeState struct {
+	Name        string   `json:"name"`
+	Category    string   `json:"category,omitempty"`
+	Transitions []string `json:"transitions"`
+}
+
+// Client is what the work item tools need of an Azure DevOps organization.
+type Client interface {
+	tools.Writer
+	// IDArgument reads the ID of an entity of kind from the named number argument or, failing
+	// that, from the uri argument.
+	IDArgument(request mcp.CallToolRequest, name, kind string) (int, error)
+	// URIIDs reads the IDs of entities of kind from their azdo:// URIs.
+	URIIDs(uris []string, kind string) ([]int, error)
+
+	ListWorkItemTypes(ctx context.Context) ([]map[string]interface{}, error)
+	WorkItemTypeFields(ctx context.Context, workItemType string) ([]TypeField, error)
+	WorkItemTypeStates(ctx context.Context, workItemType string) ([]TypeState, error)
+	// QueryWorkItemIDs returns the IDs of the work items a WIQL query selects.
+	QueryWorkItemIDs(ctx context.Context, wiql string) ([]int, error)
+	// BulkUpdateWorkItems applies fields and an optional state to each work item of ids, and
+	// reports the result of each.
+	BulkUpdateWorkItems(ctx context.Context, ids []int, fields map[string]interface{}, state string, dryRun bool) ([]map[string]interface{}, error)
+	AddWorkItemComment(ctx context.Context, id int, text string, dryRun bool) (map[string]interface{}, error)
+}
+
+// NewGroup returns the workitems tool group of client.
+func NewGroup(client Client) tools.Group {
+	return tools.NewGroup("workitems", func(s tools.Server) { register(s, client) })
+}
+
+func register(s tools.Server, client Client) {
+	listTypesTool := mcp.NewTool("list_work_item_types",
+		mcp.WithDescription("List the work item types available in the project with their states"),
+	)
+
+	s.AddTool(listTypesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		results, err := client.ListWorkItemTypes(ctx)
+		if err != nil {
+			log.Printf("Error listing work item types: %v", err)
+			return nil, fmt.Errorf("error listing work item types: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	typeFieldsTool := mcp.NewTool("get_work_item_type_fields",
+		mcp.WithDescription("Get the fields of a work item type, including reference names, whether they are required, and their allowed values. Use the reference names when creating or updating work items"),
+		mcp.WithString("type",
+			mcp.Required(),
+			mcp.Description("Work item type name, e.g. Bug or User Story"),
+		),
+	)
+
+	s.AddTool(typeFieldsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		workItemType, ok := request.Params.Arguments["type"].(string)
+		if !ok {
+			log.Print("Type must be a string")
+			return nil, fmt.Errorf("type must be a string")
+		}
+
+		results, err := client.WorkItemTypeFields(ctx, workItemType)
+		if err != nil {
+			log.Printf("Error getting work item type fields: %v", err)
+			return nil, fmt.Errorf("error getting work item type fields: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	typeStatesTool := mcp.NewTool("get_work_item_type_states",
+		mcp.WithDescription("Get the states of a work item type and the valid transitions out of each state"),
+		mcp.WithString("type",
+			mcp.Required(),
+			mcp.Description("Work item type name, e.g. Bug or User Story"),
+		),
+	)
+
+	s.AddTool(typeStatesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		workItemType, ok := request.Params.Arguments["type"].(string)
+		if !ok {
+			log.Print("Type must be a string")
+			return nil, fmt.Errorf("type must be a string")
+		}
+
+		results, err := client.WorkItemTypeStates(ctx, workItemType)
+		if err != nil {
+			log.Printf("Error getting work item type states: %v", err)
+			return nil, fmt.Errorf("error getting work item type states: %w", err)
+		}
+
+		return tools.JSONResult(results)
+	})
+
+	if !client.AllowWrites() {
+		return
+	}
+
+	bulkUpdateTool := mcp.NewTool("update_work_items",
+		mcp.WithDescription("Apply the same field changes and/or state transition to many work items at once. Provide ids, azdo:// URIs, or a WIQL query. Each item is validated against its type's fields and allowed transitions; invalid items are skipped and reported. Updates are sent in batches, and each item gets its own result"),
+		mcp.WithArray("ids",
+			mcp.Description("Work item IDs to update"),
+			mcp.Items(map[string]interface{}{"type": "number"}),
+		),
+		mcp.WithArray("uris",
+			mcp.Description("azdo:// URIs of work items to update, from other tools' results, used with or instead of ids"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("wiql",
+			mcp.Description("WIQL query selecting the work items to update, used instead of ids"),
+		),
+		mcp.WithObject("fields",
+			mcp.Description("Field values to set, keyed by field reference name (e.g. System.IterationPath)"),
+		),
+		mcp.WithString("state",
+			mcp.Description("Optional state to transition the work items to"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(bulkUpdateTool, client.WriteHandler(bulkUpdateTool, tools.WithProgress(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ids, err := tools.IntSliceArgument(request.Params.Arguments, "ids")
+		if err != nil {
+			log.Printf("Invalid ids: %v", err)
+			return nil, err
+		}
+		uris, err := tools.StringSliceArgument(request.Params.Arguments, "uris")
+		if err != nil {
+			log.Printf("Invalid uris: %v", err)
+			return nil, err
+		}
+		uriIDs, err := client.URIIDs(uris, Entity)
+		if err != nil {
+			log.Printf("Invalid uris: %v", err)
+			return nil, err
+		}
+		ids = append(ids, uriIDs...)
+
+		if wiql, _ := request.Params.Arguments["wiql"].(string); wiql != "" {
+			queried, err := client.QueryWorkItemIDs(ctx, wiql)
+			if err != nil {
+				log.Printf("Error querying work items: %v", err)
+				return nil, fmt.Errorf("error querying work items: %w", err)
+			}
+			ids = append(ids, queried...)
+		}
+
+		if len(ids) == 0 {
+			log.Print("No work items to update")
+			return nil, fmt.Errorf("ids, uris, or wiql must select at least one work item")
+		}
+
+		fields, _ := request.Params.Arguments["fields"].(map[string]interface{})
+		state, _ := request.Params.Arguments["state"].(string)
+		if len(fields) == 0 && state == "" {
+			log.Print("Nothing to update")
+			return nil, fmt.Errorf("fields or state must be provided")
+		}
+
+		dryRun := client.DryRun(request)
+		results, err := client.BulkUpdateWorkItems(ctx, ids, fields, state, dryRun)
+		if err != nil {
+			log.Printf("Error updating work items: %v", err)
+			return nil, fmt.Errorf("error updating work items: %w", err)
+		}
+		if dryRun {
+			return tools.JSONResult(tools.DryRunResult("update work items", map[string]interface{}{"workItems": results}))
+		}
+
+		return tools.JSONResult(results)
+	})))
+
+	commentTool := mcp.NewTool("add_work_item_comment",
+		mcp.WithDescription("Add a comment to the discussion of a work item. "+tools.MentionsDescription),
+		mcp.WithNumber("workItemId",
+			mcp.Description("Work item ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the work item"),
+		),
+		mcp.WithString("text",
+			mcp.Required(),
+			mcp.Description("Text of the comment, which may contain HTML"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(commentTool, client.WriteHandler(commentTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := client.IDArgument(request, "workItemId", Entity)
+		if err != nil {
+			log.Printf("Invalid work item: %v", err)
+			return nil, err
+		}
+		text, ok := request.Params.Arguments["text"].(string)
+		if !ok || strings.TrimSpace(text) == "" {
+			log.Print("Text must be a string")
+			return nil, fmt.Errorf("text must be a non-empty string")
+		}
+
+		result, err := client.AddWorkItemComment(ctx, id, text, client.DryRun(request))
+		if err != nil {
+			log.Printf("Error commenting on work item: %v", err)
+			return nil, fmt.Errorf("error commenting on work item: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
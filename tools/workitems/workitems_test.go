@@ -0,0 +1,182 @@
+package workitems
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// fakeServer keeps the handlers of the tools added to it by name.
+type fakeServer struct {
+	names    []string
+	handlers map[string]server.ToolHandlerFunc
+}
+
+func (s *fakeServer) AddTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	s.names = append(s.names, tool.Name)
+	s.handlers[tool.Name] = handler
+}
+
+func (s *fakeServer) AddResource(resource mcp.Resource, handler server.ResourceHandlerFunc) {}
+
+func (s *fakeServer) AddResourceTemplate(template mcp.ResourceTemplate, handler server.ResourceTemplateHandlerFunc) {
+}
+
+// fakeClient records the updates it is asked for. URIs are azdo://workitem/<id>.
+type fakeClient struct {
+	writes   bool
+	wrapped  []string
+	updated  []int
+	dryRun   bool
+	comments map[int]string
+}
+
+func (c *fakeClient) AllowWrites() bool { return c.writes }
+
+func (c *fakeClient) DryRun(request mcp.CallToolRequest) bool {
+	dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+	return dryRun
+}
+
+func (c *fakeClient) WriteHandler(tool mcp.Tool, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	c.wrapped = append(c.wrapped, tool.Name)
+	return handler
+}
+
+func (c *fakeClient) IDArgument(request mcp.CallToolRequest, name, kind string) (int, error) {
+	id, _ := request.Params.Arguments[name].(float64)
+	return int(id), nil
+}
+
+func (c *fakeClient) URIIDs(uris []string, kind string) ([]int, error) {
+	ids := []int{}
+	for _, uri := range uris {
+		id, err := strconv.Atoi(strings.TrimPrefix(uri, "azdo://"+kind+"/"))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (c *fakeClient) ListWorkItemTypes(ctx context.Context) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{{"name": "Bug"}}, nil
+}
+
+func (c *fakeClient) WorkItemTypeFields(ctx context.Context, workItemType string) ([]TypeField, error) {
+	return []TypeField{{Name: "Title", ReferenceName: "System.Title"}}, nil
+}
+
+func (c *fakeClient) WorkItemTypeStates(ctx context.Context, workItemType string) ([]TypeState, error) {
+	return []TypeState{{Name: "New", Transitions: []string{"Active"}}}, nil
+}
+
+func (c *fakeClient) QueryWorkItemIDs(ctx context.Context, wiql string) ([]int, error) {
+	return []int{30}, nil
+}
+
+func (c *fakeClient) BulkUpdateWorkItems(ctx context.Context, ids []int, fields map[string]interface{}, state string, dryRun bool) ([]map[string]interface{}, error) {
+	c.updated, c.dryRun = ids, dryRun
+	results := []map[string]interface{}{}
+	for _, id := range ids {
+		results = append(results, map[string]interface{}{"id": id, "status": "updated"})
+	}
+	return results, nil
+}
+
+func (c *fakeClient) AddWorkItemComment(ctx context.Context, id int, text string, dryRun bool) (map[string]interface{}, error) {
+	c.comments[id] = text
+	return map[string]interface{}{"id": id}, nil
+}
+
+func registered(client *fakeClient) *fakeServer {
+	s := &fakeServer{handlers: map[string]server.ToolHandlerFunc{}}
+	NewGroup(client).Register(s)
+	return s
+}
+
+func call(t *testing.T, s *fakeServer, name string, arguments map[string]interface{}) (string, error) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = arguments
+	result, err := s.handlers[name](context.Background(), request)
+	if err != nil {
+		return "", err
+	}
+	return result.Content[0].(mcp.TextContent).Text, nil
+}
+
+func TestRegisterLeavesOutWriteToolsWithoutWrites(t *testing.T) {
+	s := registered(&fakeClient{})
+	want := []string{"list_work_item_types", "get_work_item_type_fields", "get_work_item_type_states"}
+	if !reflect.DeepEqual(s.names, want) {
+		t.Errorf("tools = %v, want %v", s.names, want)
+	}
+
+	client := &fakeClient{writes: true}
+	s = registered(client)
+	if len(s.names) != 5 {
+		t.Errorf("tools with writes = %v, want 5", s.names)
+	}
+	if want := []string{"update_work_items", "add_work_item_comment"}; !reflect.DeepEqual(client.wrapped, want) {
+		t.Errorf("write handlers = %v, want %v", client.wrapped, want)
+	}
+}
+
+func TestUpdateWorkItemsSelectsByIDsURIsAndQuery(t *testing.T) {
+	client := &fakeClient{writes: true}
+	s := registered(client)
+
+	text, err := call(t, s, "update_work_items", map[string]interface{}{
+		"ids":    []interface{}{float64(10)},
+		"uris":   []interface{}{"azdo://workitem/20"},
+		"wiql":   "SELECT [System.Id] FROM WorkItems",
+		"state":  "Done",
+		"dryRun": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{10, 20, 30}; !reflect.DeepEqual(client.updated, want) || !client.dryRun {
+		t.Errorf("updated %v (dry run %v), want %v as a dry run", client.updated, client.dryRun, want)
+	}
+	var result struct {
+		DryRun  bool `json:"dryRun"`
+		Changes struct {
+			WorkItems []map[string]interface{} `json:"workItems"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal([]byte(text), &result); err != nil || !result.DryRun || len(result.Changes.WorkItems) != 3 {
+		t.Errorf("result = %s, %v", text, err)
+	}
+
+	if _, err := call(t, s, "update_work_items", map[string]interface{}{"state": "Done"}); err == nil {
+		t.Error("update_work_items without work items succeeded")
+	}
+	if _, err := call(t, s, "update_work_items", map[string]interface{}{"ids": []interface{}{float64(1)}}); err == nil {
+		t.Error("update_work_items without fields or state succeeded")
+	}
+}
+
+func TestAddWorkItemCommentNeedsText(t *testing.T) {
+	client := &fakeClient{writes: true, comments: map[int]string{}}
+	s := registered(client)
+
+	if _, err := call(t, s, "add_work_item_comment", map[string]interface{}{"workItemId": float64(7), "text": " "}); err == nil {
+		t.Error("add_work_item_comment with blank text succeeded")
+	}
+	if _, err := call(t, s, "add_work_item_comment", map[string]interface{}{"workItemId": float64(7), "text": "Looks good"}); err != nil {
+		t.Fatal(err)
+	}
+	if client.comments[7] != "Looks good" {
+		t.Errorf("comments = %v", client.comments)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolServer is the MCP server tools are added to. It wraps every tool added to it in the layers
+// all tools share: result compression, and outside it the tool audit log, so no tool can be called
+// without leaving an entry.
+type toolServer struct {
+	*server.MCPServer
+	client *AzureDevOpsClient
+}
+
+func (s *toolServer) AddTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	threshold := s.client.config.Compression.ResultThresholdKB << 10
+	handler = withResultCompression(threshold, handler)
+	handler = withAuditLog(s.client, tool, handler)
+	s.MCPServer.AddTool(tool, handler)
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/test"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	defaultTriageTokens = 20000
+	// triageLogLines is how much of the end of each failed task's log is returned.
+	triageLogLines = 100
+	// maxTriageTestResults caps the failed test results listed per test run.
+	maxTriageTestResults = 20
+	// triageStackTraceLines is how much of the start of each failed test's stack trace is returned.
+	triageStackTraceLines = 15
+)
+
+// failedTimelineRecords returns the failed stages, jobs, and tasks of a build's timeline, each
+// with the names of the records it is part of and the errors it logged.
+func failedTimelineRecords(timeline *build.Timeline) []map[string]interface{} {
+	if timeline == nil || timeline.Records == nil {
+		return nil
+	}
+	names := map[string]string{}
+	parents := map[string]string{}
+	for _, record := range *timeline.Records {
+		if record.Id == nil {
+			continue
+		}
+		names[record.Id.String()] = stringValue(record.Name)
+		if record.ParentId != nil {
+			parents[record.Id.String()] = record.ParentId.String()
+		}
+	}
+
+	failed := []map[string]interface{}{}
+	for _, record := range *timeline.Records {
+		if record.Result == nil || *record.Result != build.TaskResultValues.Failed || record.Id == nil {
+			continue
+		}
+		path := []string{}
+		for parent := parents[record.Id.String()]; parent != ""; parent = parents[parent] {
+			if name := names[parent]; name != "" {
+				path = append([]string{name}, path...)
+			}
+		}
+		entry := map[string]interface{}{
+			"name": stringValue(record.Name),
+			"type": stringValue(record.Type),
+		}
+		if len(path) > 0 {
+			entry["in"] = strings.Join(path, " > ")
+		}
+		if record.Log != nil && record.Log.Id != nil {
+			entry["logId"] = *record.Log.Id
+		}
+		if record.Issues != nil {
+			errors := []string{}
+			for _, issue := range *record.Issues {
+				if issue.Type != nil && *issue.Type == build.IssueTypeValues.Error {
+					errors = append(errors, stringValue(issue.Message))
+				}
+			}
+			if len(errors) > 0 {
+				entry["errors"] = errors
+			}
+		}
+		failed = append(failed, entry)
+	}
+	return failed
+}
+
+// failedTests lists the failed results of the test runs of a build, with their error messages
+// and the start of their stack traces.
+func (c *AzureDevOpsClient) failedTests(ctx context.Context, buildURI string) ([]map[string]interface{}, error) {
+	runs, err := c.testClient.GetTestRuns(ctx, test.GetTestRunsArgs{
+		Project:  &c.config.AzureDevOps.Project,
+		BuildUri: &buildURI,
+	})
+	if err != nil {
+		log.Printf("Error getting test runs: %v", err)
+		return nil, fmt.Errorf("error getting test runs: %w", err)
+	}
+
+	failed := []map[string]interface{}{}
+	top := maxTriageTestResults
+	outcomes := []test.TestOutcome{test.TestOutcomeValues.Failed}
+	for _, run := range *runs {
+		if run.Id == nil {
+			continue
+		}
+		total, passed := 0, 0
+		if run.TotalTests != nil {
+			total = *run.TotalTests
+		}
+		if run.PassedTests != nil {
+			passed = *run.PassedTests
+		}
+		if total > 0 && passed == total {
+			continue
+		}
+		results, err := c.testClient.GetTestResults(ctx, test.GetTestResultsArgs{
+			Project:  &c.config.AzureDevOps.Project,
+			RunId:    run.Id,
+			Top:      &top,
+			Outcomes: &outcomes,
+		})
+		if err != nil {
+			log.Printf("Error getting test results: %v", err)
+			return nil, fmt.Errorf("error getting results of test run %d: %w", *run.Id, err)
+		}
+		for _, result := range *results {
+			entry := map[string]interface{}{
+				"testRun": stringValue(run.Name),
+				"runId":   *run.Id,
+				"test":    stringValue(result.TestCaseTitle),
+				"error":   stringValue(result.ErrorMessage),
+			}
+			if result.Id != nil {
+				entry["resultId"] = *result.Id
+			}
+			if name := stringValue(result.AutomatedTestName); name != "" {
+				entry["automatedTestName"] = name
+			}
+			if trace := stringValue(result.StackTrace); trace != "" {
+				lines := strings.Split(trace, "\n")
+				if len(lines) > triageStackTraceLines {
+					lines = append(lines[:triageStackTraceLines], "...")
+				}
+				entry["stackTrace"] = strings.Join(lines, "\n")
+			}
+			if result.FailingSince != nil && result.FailingSince.Build != nil && result.FailingSince.Build.Id != nil {
+				entry["failingSinceBuild"] = *result.FailingSince.Build.Id
+			}
+			failed = append(failed, entry)
+		}
+	}
+	return failed, nil
+}
+
+// triageBuild gathers what explains a build's failure in one call: its failed stages, jobs, and
+// tasks with their errors, failed tests, the commit and pull request it built, and the tail of
+// each failed task's log. Logs are added until maxTokens is reached; later ones are listed
+// without their lines.
+func (c *AzureDevOpsClient) triageBuild(ctx context.Context, buildID, maxTokens int) (map[string]interface{}, error) {
+	project := c.config.AzureDevOps.Project
+	run, err := c.buildClient.GetBuild(ctx, build.GetBuildArgs{
+		Project: &project,
+		BuildId: &buildID,
+	})
+	if err != nil {
+		log.Printf("Error getting build: %v", err)
+		return nil, fmt.Errorf("error getting build %d: %w", buildID, err)
+	}
+
+	summary := map[string]interface{}{
+		"id":           buildID,
+		"buildNumber":  stringValue(run.BuildNumber),
+		"sourceBranch": stringValue(run.SourceBranch),
+		"webUrl":       c.links.build(project, buildID),
+		"uri":          c.idURI(buildEntity, project, buildID),
+	}
+	if run.Definition != nil {
+		summary["pipeline"] = stringValue(run.Definition.Name)
+	}
+	if run.Status != nil {
+		summary["status"] = string(*run.Status)
+	}
+	if run.Result != nil {
+		summary["result"] = string(*run.Result)
+	}
+	if run.Reason != nil {
+		summary["reason"] = string(*run.Reason)
+	}
+	if run.RequestedFor != nil {
+		summary["requestedFor"] = stringValue(run.RequestedFor.DisplayName)
+	}
+	if run.StartTime != nil {
+		summary["startTime"] = run.StartTime.Time.Format(time.RFC3339)
+	}
+	if run.FinishTime != nil {
+		summary["finishTime"] = run.FinishTime.Time.Format(time.RFC3339)
+	}
+	result := map[string]interface{}{
+		"build": summary,
+	}
+	errors := map[string]string{}
+
+	timeline, err := c.buildClient.GetBuildTimeline(ctx, build.GetBuildTimelineArgs{
+		Project: &project,
+		BuildId: &buildID,
+	})
+	var failed []map[string]interface{}
+	if err != nil {
+		log.Printf("Error getting build timeline: %v", err)
+		errors["timeline"] = err.Error()
+	} else {
+		failed = failedTimelineRecords(timeline)
+		result["failed"] = failed
+	}
+
+	if run.Uri != nil {
+		if tests, err := c.failedTests(ctx, *run.Uri); err != nil {
+			errors["tests"] = err.Error()
+		} else {
+			result["failedTests"] = tests
+		}
+	}
+
+	// The commit is only looked up in Azure Repos; other repositories are named by its SHA.
+	commitID := stringValue(run.SourceVersion)
+	if repo := run.Repository; repo != nil && commitID != "" {
+		entry := map[string]interface{}{
+			"commitId":   commitID,
+			"repository": stringValue(repo.Name),
+		}
+		if stringValue(repo.Type) == "TfsGit" && repo.Id != nil {
+			commit, err := c.gitClient.GetCommit(ctx, git.GetCommitArgs{
+				CommitId:     &commitID,
+				RepositoryId: repo.Id,
+				Project:      &project,
+			})
+			if err != nil {
+				log.Printf("Error getting commit: %v", err)
+				errors["commit"] = err.Error()
+			} else {
+				entry["comment"] = stringValue(commit.Comment)
+				if commit.Author != nil {
+					entry["author"] = stringValue(commit.Author.Name)
+					if commit.Author.Date != nil {
+						entry["date"] = commit.Author.Date.Time.Format(time.RFC3339)
+					}
+				}
+				entry["uri"] = c.commitURI(project, stringValue(repo.Name), commitID)
+			}
+		}
+		result["commit"] = entry
+	}
+
+	// Pull request builds name the pull request in their trigger info, or build its merge ref.
+	prID := 0
+	if run.TriggerInfo != nil {
+		prID, _ = strconv.Atoi((*run.TriggerInfo)["pr.number"])
+	}
+	if match := pullRequestBuildRef.FindStringSubmatch(stringValue(run.SourceBranch)); prID == 0 && match != nil {
+		prID, _ = strconv.Atoi(match[1])
+	}
+	if prID != 0 {
+		if pr, err := c.getPullRequest(ctx, prID); err != nil {
+			errors["pullRequest"] = err.Error()
+		} else {
+			entry := c.pullRequestSummary(*pr)
+			delete(entry, "reviewers")
+			result["pullRequest"] = entry
+		}
+	}
+
+	used := estimateTokens(result)
+	omitted := 0
+	if len(failed) > 0 {
+		logs, err := c.buildClient.GetBuildLogs(ctx, build.GetBuildLogsArgs{
+			Project: &project,
+			BuildId: &buildID,
+		})
+		if err != nil {
+			log.Printf("Error getting build logs: %v", err)
+			errors["logs"] = err.Error()
+		} else {
+			lineCounts := map[int]uint64{}
+			for _, entry := range *logs {
+				if entry.Id != nil && entry.LineCount != nil {
+					lineCounts[*entry.Id] = *entry.LineCount
+				}
+			}
+			for _, record := range failed {
+				// Stage and job logs repeat those of their tasks.
+				logID, ok := record["logId"].(int)
+				if !ok || record["type"] != "Task" {
+					continue
+				}
+				if used >= maxTokens {
+					record["logOmitted"] = true
+					omitted++
+					continue
+				}
+				start := uint64(1)
+				if count := lineCounts[logID]; count > triageLogLines {
+					start = count - triageLogLines + 1
+				}
+				lines, err := c.buildClient.GetBuildLogLines(ctx, build.GetBuildLogLinesArgs{
+					Project:   &project,
+					BuildId:   &buildID,
+					LogId:     &logID,
+					StartLine: &start,
+				})
+				if err != nil {
+					log.Printf("Error getting build log: %v", err)
+					record["logError"] = err.Error()
+					continue
+				}
+				tail := strings.Join(*lines, "\n")
+				tokens := estimateTokens(tail)
+				if used+tokens > maxTokens {
+					record["logOmitted"] = true
+					omitted++
+					continue
+				}
+				used += tokens
+				record["logTail"] = tail
+				record["logStartLine"] = start
+			}
+		}
+	}
+	result["budget"] = map[string]interface{}{
+		"maxTokens":   maxTokens,
+		"usedTokens":  used,
+		"omittedLogs": omitted,
+	}
+	if len(errors) > 0 {
+		result["errors"] = errors
+	}
+	return result, nil
+}
+
+func addTriageTools(s tools.Server, client *AzureDevOpsClient) {
+	triageTool := mcp.NewTool("triage_build",
+		mcp.WithDescription(fmt.Sprintf("Gather why a build failed in one call: its failed stages, jobs, and tasks with their errors, the last %d lines of each failed task's log, failed tests with their messages and stack traces, and the commit and pull request it built, sized to a token budget", triageLogLines)),
+		mcp.WithNumber("buildId",
+			mcp.Description("Build ID; required unless uri is passed"),
+		),
+		mcp.WithString("uri",
+			mcp.Description(tools.URIDescription+": the build"),
+		),
+		mcp.WithNumber("maxTokens",
+			mcp.Description(fmt.Sprintf("Approximate token budget of the result, at most %d; logs that do not fit are listed without their lines", maxReviewTokens)),
+			mcp.DefaultNumber(defaultTriageTokens),
+		),
+	)
+
+	s.AddTool(triageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		buildID, err := client.idArgument(request, "buildId", buildEntity)
+		if err != nil {
+			log.Printf("Invalid build: %v", err)
+			return nil, err
+		}
+		maxTokens := defaultTriageTokens
+		if value, ok := request.Params.Arguments["maxTokens"].(float64); ok && value > 0 {
+			maxTokens = int(value)
+		}
+		if maxTokens > maxReviewTokens {
+			maxTokens = maxReviewTokens
+		}
+
+		result, err := client.triageBuild(ctx, buildID, maxTokens)
+		if err != nil {
+			log.Printf("Error triaging build: %v", err)
+			return nil, fmt.Errorf("error triaging build: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/search"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	usageSearchPageSize = 200
+	defaultUsageFiles   = 1000
+	// maxUsageFiles is the most results code search pages through for one query.
+	maxUsageFiles = 5000
+)
+
+// usageReport searches every repository of the project for a symbol or package name and groups the
+// matching files by repository, with the number of matches in each, to size the impact of a
+// breaking change. With references set, only code references to the symbol are matched.
+func (c *AzureDevOpsClient) usageReport(ctx context.Context, name string, references bool, maxFiles int) (map[string]interface{}, error) {
+	query := name
+	if references {
+		query = "ref:" + name
+	}
+	filters := map[string][]string{
+		"Project": {c.config.AzureDevOps.Project},
+	}
+
+	type fileUsage struct {
+		path    string
+		matches int
+	}
+	byRepository := map[string][]fileUsage{}
+	total := 0
+	read := 0
+	for read < maxFiles {
+		top := usageSearchPageSize
+		if maxFiles-read < top {
+			top = maxFiles - read
+		}
+		skip := read
+		response, err := c.searchClient.FetchCodeSearchResults(ctx, search.FetchCodeSearchResultsArgs{
+			Project: &c.config.AzureDevOps.Project,
+			Request: &search.CodeSearchRequest{
+				SearchText: &query,
+				Filters:    &filters,
+				Top:        &top,
+				Skip:       &skip,
+			},
+		})
+		if err != nil {
+			log.Printf("Error searching code: %v", err)
+			return nil, fmt.Errorf("error searching for %s: %w", query, err)
+		}
+		if response == nil || response.Results == nil || len(*response.Results) == 0 {
+			break
+		}
+		if response.Count != nil {
+			total = *response.Count
+		}
+
+		for _, result := range *response.Results {
+			if result.Repository == nil || result.Path == nil {
+				continue
+			}
+			matches := 0
+			if result.Matches != nil {
+				for field, hits := range *result.Matches {
+					if field != "fileName" && field != "path" {
+						matches += len(hits)
+					}
+				}
+			}
+			repoName := stringValue(result.Repository.Name)
+			byRepository[repoName] = append(byRepository[repoName], fileUsage{*result.Path, matches})
+		}
+		read += len(*response.Results)
+		tools.ReportProgress(ctx, read, min(total, maxFiles), "Searching code")
+		if len(*response.Results) < top {
+			break
+		}
+	}
+
+	repositories := []map[string]interface{}{}
+	for repoName, files := range byRepository {
+		sort.Slice(files, func(i, j int) bool {
+			if files[i].matches != files[j].matches {
+				return files[i].matches > files[j].matches
+			}
+			return files[i].path < files[j].path
+		})
+		matchCount := 0
+		fileList := []map[string]interface{}{}
+		for _, file := range files {
+			matchCount += file.matches
+			fileList = append(fileList, map[string]interface{}{
+				"path":    file.path,
+				"matches": file.matches,
+				"webUrl":  c.links.file(c.config.AzureDevOps.Project, repoName, file.path, ""),
+				"uri":     c.fileURI(c.config.AzureDevOps.Project, repoName, file.path, ""),
+			})
+		}
+		repositories = append(repositories, map[string]interface{}{
+			"repository": repoName,
+			"fileCount":  len(files),
+			"matchCount": matchCount,
+			"files":      fileList,
+		})
+	}
+	sort.Slice(repositories, func(i, j int) bool {
+		if repositories[i]["matchCount"].(int) != repositories[j]["matchCount"].(int) {
+			return repositories[i]["matchCount"].(int) > repositories[j]["matchCount"].(int)
+		}
+		return repositories[i]["repository"].(string) < repositories[j]["repository"].(string)
+	})
+
+	return map[string]interface{}{
+		"query":           query,
+		"totalFiles":      total,
+		"filesRead":       read,
+		"repositoryCount": len(repositories),
+		"repositories":    repositories,
+	}, nil
+}
+
+func addUsageTools(s tools.Server, client *AzureDevOpsClient) {
+	usageTool := mcp.NewTool("get_usage_report",
+		mcp.WithDescription("Find every use of a symbol or package name across all repositories of the project and group it by repository and file with match counts, to assess the blast radius of a breaking change"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Symbol or package name, e.g. LegacyAuthClient or Newtonsoft.Json"),
+		),
+		mcp.WithBoolean("references",
+			mcp.Description("Only match code references to the symbol (ref: search, C#, C, C++, and Java) rather than any text"),
+		),
+		mcp.WithNumber("maxFiles",
+			mcp.Description(fmt.Sprintf("Maximum number of matching files to read, at most %d", maxUsageFiles)),
+			mcp.DefaultNumber(defaultUsageFiles),
+		),
+	)
+
+	s.AddTool(usageTool, tools.WithProgress(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name, ok := request.Params.Arguments["name"].(string)
+		if !ok {
+			log.Print("Name must be a string")
+			return nil, fmt.Errorf("name must be a string")
+		}
+		references, _ := request.Params.Arguments["references"].(bool)
+		maxFiles := defaultUsageFiles
+		if value, ok := request.Params.Arguments["maxFiles"].(float64); ok && value > 0 {
+			maxFiles = int(value)
+		}
+		if maxFiles > maxUsageFiles {
+			maxFiles = maxUsageFiles
+		}
+
+		result, err := client.usageReport(ctx, name, references, maxFiles)
+		if err != nil {
+			log.Printf("Error getting usage report: %v", err)
+			return nil, fmt.Errorf("error getting usage report: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
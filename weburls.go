@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// webLinks builds the web URLs of entities, so results can link to the page a user would open.
+// base is the organization URL on Azure DevOps Services (https://dev.azure.com/<organization>,
+// or a legacy https://<organization>.visualstudio.com) and the collection URL on Azure DevOps
+// Server (https://<server>/tfs/<collection>); pages sit at the same paths below both.
+type webLinks struct {
+	base string
+}
+
+// newWebLinks returns the links of the organization, or of the server collection at baseURL when
+// it is set.
+func newWebLinks(organization, baseURL string) webLinks {
+	if baseURL == "" {
+		baseURL = "https://dev.azure.com/" + url.PathEscape(organization)
+	}
+	return webLinks{base: strings.TrimSuffix(baseURL, "/")}
+}
+
+// analytics returns the root of the Analytics OData service: its own host on Azure DevOps
+// Services, and the collection itself on Azure DevOps Server.
+func (l webLinks) analytics() string {
+	parsed, err := url.Parse(l.base)
+	if err != nil {
+		return l.base
+	}
+	host := strings.ToLower(parsed.Hostname())
+	switch {
+	case host == "dev.azure.com":
+		return "https://analytics.dev.azure.com" + parsed.Path
+	case strings.HasSuffix(host, ".visualstudio.com"):
+		return "https://" + strings.TrimSuffix(host, ".visualstudio.com") + ".analytics.visualstudio.com"
+	}
+	return l.base
+}
+
+// contains returns the path segments of a URL below base, and whether the URL is there at all.
+func (l webLinks) contains(parsed *url.URL) ([]string, bool) {
+	base, err := url.Parse(l.base)
+	if err != nil || !strings.EqualFold(parsed.Host, base.Host) {
+		return nil, false
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	for _, segment := range strings.Split(strings.Trim(base.Path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if len(segments) == 0 || !strings.EqualFold(segments[0], segment) {
+			return nil, false
+		}
+		segments = segments[1:]
+	}
+	return segments, true
+}
+
+func (l webLinks) project(project string) string {
+	return l.base + "/" + url.PathEscape(project)
+}
+
+// repository links to the files of a repository.
+func (l webLinks) repository(project, repo string) string {
+	return l.project(project) + "/_git/" + url.PathEscape(repo)
+}
+
+// file links to a file or folder of a repository at a ref: a branch, refs/tags/<tag>, or commit
+// SHA. An empty ref shows the default branch.
+func (l webLinks) file(project, repo, path, ref string) string {
+	query := url.Values{"path": {path}}
+	switch {
+	case ref == "":
+	case commitSHA.MatchString(ref):
+		query.Set("version", "GC"+ref)
+	case strings.HasPrefix(ref, "refs/tags/"):
+		query.Set("version", "GT"+strings.TrimPrefix(ref, "refs/tags/"))
+	default:
+		query.Set("version", "GB"+strings.TrimPrefix(ref, "refs/heads/"))
+	}
+	return l.repository(project, repo) + "?" + query.Encode()
+}
+
+func (l webLinks) commit(project, repo, commitID string) string {
+	return l.repository(project, repo) + "/commit/" + commitID
+}
+
+func (l webLinks) pullRequest(project, repo string, id int) string {
+	return fmt.Sprintf("%s/pullrequest/%d", l.repository(project, repo), id)
+}
+
+func (l webLinks) workItem(project string, id int) string {
+	return fmt.Sprintf("%s/_workitems/edit/%d", l.project(project), id)
+}
+
+func (l webLinks) pipeline(project string, id int) string {
+	return fmt.Sprintf("%s/_build?definitionId=%d", l.project(project), id)
+}
+
+func (l webLinks) build(project string, id interface{}) string {
+	return fmt.Sprintf("%s/_build/results?buildId=%v", l.project(project), id)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/signify/sgfy-mcp/tools"
+)
+
+const (
+	hierarchyReverseRel = "System.LinkTypes.Hierarchy-Reverse"
+	relatedRel          = "System.LinkTypes.Related"
+	// maxClonedChildren caps the children cloned with a work item.
+	maxClonedChildren = 50
+)
+
+// uncopiedFields are writable fields a clone starts without: its state and history are its own,
+// and its parent comes from its links.
+var uncopiedFields = map[string]bool{
+	"system.state":                          true,
+	"system.reason":                         true,
+	"system.history":                        true,
+	"system.parent":                         true,
+	"system.boardcolumn":                    true,
+	"system.boardcolumndone":                true,
+	"system.boardlane":                      true,
+	"microsoft.vsts.common.activateddate":   true,
+	"microsoft.vsts.common.activatedby":     true,
+	"microsoft.vsts.common.resolveddate":    true,
+	"microsoft.vsts.common.resolvedby":      true,
+	"microsoft.vsts.common.resolvedreason":  true,
+	"microsoft.vsts.common.closeddate":      true,
+	"microsoft.vsts.common.closedby":        true,
+	"microsoft.vsts.common.statechangedate": true,
+}
+
+// cloneFields returns the fields of item a clone is created with. Read-only fields are left out, and
+// identities are set by their unique name. In another project, the area and iteration paths move
+// to that project's root, since the source project's paths do not exist there.
+func cloneFields(item workitemtracking.WorkItem, readOnly map[string]bool, sourceProject, targetProject string) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if item.Fields == nil {
+		return fields
+	}
+	for name, value := range *item.Fields {
+		key := strings.ToLower(name)
+		if readOnly[key] || uncopiedFields[key] || strings.HasPrefix(key, "wef_") {
+			continue
+		}
+		if identity, ok := value.(map[string]interface{}); ok {
+			if unique, ok := identity["uniqueName"].(string); ok && unique != "" {
+				value = unique
+			} else {
+				value = identityDisplayName(identity)
+			}
+		}
+		fields[name] = value
+	}
+	if !strings.EqualFold(sourceProject, targetProject) {
+		fields["System.AreaPath"] = targetProject
+		fields["System.IterationPath"] = targetProject
+	}
+	return fields
+}
+
+// cloneRelation is a link a clone is created with.
+type cloneRelation struct {
+	Rel        string                 `json:"rel"`
+	URL        string                 `json:"url"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// cloneRelations returns the links of item a clone keeps: all but its children, which are either
+// cloned themselves or left with the original.
+func cloneRelations(item workitemtracking.WorkItem) []cloneRelation {
+	relations := []cloneRelation{}
+	if item.Relations == nil {
+		return relations
+	}
+	for _, relation := range *item.Relations {
+		if relation.Rel == nil || relation.Url == nil || *relation.Rel == hierarchyForwardRel {
+			continue
+		}
+		entry := cloneRelation{Rel: *relation.Rel, URL: *relation.Url}
+		if relation.Attributes != nil {
+			attributes := map[string]interface{}{}
+			// Comments and names describe the link; the rest is set by the server.
+			for _, name := range []string{"comment", "name"} {
+				if value, ok := (*relation.Attributes)[name]; ok {
+					attributes[name] = value
+				}
+			}
+			if len(attributes) > 0 {
+				entry.Attributes = attributes
+			}
+		}
+		relations = append(relations, entry)
+	}
+	return relations
+}
+
+// createClone creates a work item of a type in project with fields and links.
+func (c *AzureDevOpsClient) createClone(ctx context.Context, project, workItemType string, fields map[string]interface{}, relations []cloneRelation) (*workitemtracking.WorkItem, error) {
+	document := workItemPatchDocument(fields, "")
+	for _, relation := range relations {
+		path := "/relations/-"
+		document = append(document, webapi.JsonPatchOperation{
+			Op:    &webapi.OperationValues.Add,
+			Path:  &path,
+			Value: relation,
+		})
+	}
+	item, err := c.witClient.CreateWorkItem(ctx, workitemtracking.CreateWorkItemArgs{
+		Document: &document,
+		Project:  &project,
+		Type:     &workItemType,
+	})
+	if err != nil {
+		log.Printf("Error creating work item: %v", err)
+		return nil, fmt.Errorf("error creating %s in %s: %w", workItemType, project, err)
+	}
+	return item, nil
+}
+
+// cloneWorkItem copies a work item, with its fields and tags, into targetProject (the configured
+// project by default) as a new work item related to the original. includeLinks keeps the
+// original's links, such as its parent and related items, and includeChildren clones its direct
+// children under the clone. fields override the copied values of the clone, not of its children.
+// A dry run returns what each work item would be created with instead.
+func (c *AzureDevOpsClient) cloneWorkItem(ctx context.Context, id int, targetProject string, includeLinks, includeChildren bool, overrides map[string]interface{}, dryRun bool) (map[string]interface{}, error) {
+	sourceProject := c.config.AzureDevOps.Project
+	if targetProject == "" {
+		targetProject = sourceProject
+	}
+	items, err := c.getWorkItemsWithRelations(ctx, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 || items[0].Fields == nil {
+		return nil, fmt.Errorf("work item %d not found", id)
+	}
+	original := items[0]
+
+	organizationFields, err := c.witClient.GetWorkItemFields(ctx, workitemtracking.GetWorkItemFieldsArgs{})
+	if err != nil {
+		log.Printf("Error getting fields: %v", err)
+		return nil, fmt.Errorf("error getting fields: %w", err)
+	}
+	readOnly := map[string]bool{}
+	for _, field := range *organizationFields {
+		if field.ReadOnly != nil && *field.ReadOnly {
+			readOnly[strings.ToLower(stringValue(field.ReferenceName))] = true
+		}
+	}
+
+	children := []workitemtracking.WorkItem{}
+	truncated := false
+	if includeChildren {
+		childIDs := relatedWorkItemIDs(original, hierarchyForwardRel)
+		if len(childIDs) > maxClonedChildren {
+			childIDs = childIDs[:maxClonedChildren]
+			truncated = true
+		}
+		if len(childIDs) > 0 {
+			children, err = c.getWorkItemsWithRelations(ctx, childIDs)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	fields := cloneFields(original, readOnly, sourceProject, targetProject)
+	for name, value := range overrides {
+		for existing := range fields {
+			if strings.EqualFold(existing, name) {
+				delete(fields, existing)
+			}
+		}
+		fields[name] = value
+	}
+	relations := []cloneRelation{{
+		Rel:        relatedRel,
+		URL:        stringValue(original.Url),
+		Attributes: map[string]interface{}{"comment": fmt.Sprintf("Cloned from #%d", id)},
+	}}
+	if includeLinks {
+		relations = append(relations, cloneRelations(original)...)
+	}
+	workItemType, _ := (*original.Fields)["System.WorkItemType"].(string)
+
+	if dryRun {
+		planned := []map[string]interface{}{}
+		for _, child := range children {
+			childType, _ := (*child.Fields)["System.WorkItemType"].(string)
+			planned = append(planned, map[string]interface{}{
+				"from":   *child.Id,
+				"type":   childType,
+				"fields": cloneFields(child, readOnly, sourceProject, targetProject),
+			})
+		}
+		changes := map[string]interface{}{
+			"project":   targetProject,
+			"from":      id,
+			"type":      workItemType,
+			"fields":    fields,
+			"relations": relations,
+			"children":  planned,
+		}
+		if truncated {
+			changes["truncated"] = fmt.Sprintf("only the first %d children would be cloned", maxClonedChildren)
+		}
+		return tools.DryRunResult("clone work item", changes), nil
+	}
+
+	clone, err := c.createClone(ctx, targetProject, workItemType, fields, relations)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{
+		"id":      *clone.Id,
+		"from":    id,
+		"project": targetProject,
+		"webUrl":  c.links.workItem(targetProject, *clone.Id),
+		"uri":     c.idURI(workItemEntity, targetProject, *clone.Id),
+	}
+
+	// A child that fails is reported and the rest are still cloned.
+	clonedChildren := []map[string]interface{}{}
+	for _, child := range children {
+		childType, _ := (*child.Fields)["System.WorkItemType"].(string)
+		entry := map[string]interface{}{"from": *child.Id}
+		created, err := c.createClone(ctx, targetProject, childType, cloneFields(child, readOnly, sourceProject, targetProject), []cloneRelation{
+			{Rel: hierarchyReverseRel, URL: stringValue(clone.Url)},
+			{Rel: relatedRel, URL: stringValue(child.Url), Attributes: map[string]interface{}{"comment": fmt.Sprintf("Cloned from #%d", *child.Id)}},
+		})
+		if err != nil {
+			entry["error"] = err.Error()
+		} else {
+			entry["id"] = *created.Id
+			entry["webUrl"] = c.links.workItem(targetProject, *created.Id)
+		}
+		clonedChildren = append(clonedChildren, entry)
+	}
+	if includeChildren {
+		result["children"] = clonedChildren
+	}
+	if truncated {
+		result["truncated"] = fmt.Sprintf("only the first %d children were cloned", maxClonedChildren)
+	}
+	return result, nil
+}
+
+func addWorkItemCloneTools(s tools.Server, client *AzureDevOpsClient) {
+	if !client.config.Server.AllowWrites {
+		return
+	}
+
+	cloneTool := mcp.NewTool("clone_work_item",
+		mcp.WithDescription("Clone a work item, with its fields and tags, into the same or another project as a new work item linked to the original. Optionally keep its links, such as its parent, and clone its direct children under the clone. The clone starts in its type's initial state"),
+		mcp.WithNumber("workItemId",
+			mcp.Description("ID of the work item to clone"),
+		),
+		mcp.WithString("uri",
+			mcp.Description("azdo:// URI of the work item to clone, used instead of workItemId"),
+		),
+		mcp.WithString("targetProject",
+			mcp.Description("Project to create the clone in, defaults to the configured project. Its area and iteration are set to the project's root"),
+		),
+		mcp.WithBoolean("includeLinks",
+			mcp.Description("Keep the original's links other than its children: parent, related items, commits, hyperlinks, and attachments"),
+		),
+		mcp.WithBoolean("includeChildren",
+			mcp.Description(fmt.Sprintf("Also clone the direct children, at most %d, as children of the clone", maxClonedChildren)),
+		),
+		mcp.WithObject("fields",
+			mcp.Description("Field values of the clone that replace the copied ones, keyed by field reference name (e.g. System.IterationPath)"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description(tools.DryRunDescription),
+		),
+		mcp.WithString("idempotencyKey",
+			mcp.Description(tools.IdempotencyKeyDescription),
+		),
+	)
+
+	s.AddTool(cloneTool, writeHandler(client, cloneTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := client.idArgument(request, "workItemId", workItemEntity)
+		if err != nil {
+			log.Printf("Invalid work item: %v", err)
+			return nil, err
+		}
+		targetProject, _ := request.Params.Arguments["targetProject"].(string)
+		includeLinks, _ := request.Params.Arguments["includeLinks"].(bool)
+		includeChildren, _ := request.Params.Arguments["includeChildren"].(bool)
+		fields, _ := request.Params.Arguments["fields"].(map[string]interface{})
+
+		result, err := client.cloneWorkItem(ctx, id, targetProject, includeLinks, includeChildren, fields, client.dryRun(request))
+		if err != nil {
+			log.Printf("Error cloning work item: %v", err)
+			return nil, fmt.Errorf("error cloning work item: %w", err)
+		}
+
+		return tools.JSONResult(result)
+	}))
+}
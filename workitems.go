@@ -0,0 +1,560 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/signify/sgfy-mcp/tools"
+	"github.com/signify/sgfy-mcp/tools/workitems"
+)
+
+// maxWorkItemBatchSize is the largest number of work items the batch API accepts per call.
+const maxWorkItemBatchSize = 200
+
+func (c *AzureDevOpsClient) listWorkItemTypes(ctx context.Context) ([]map[string]interface{}, error) {
+	types, err := c.witClient.GetWorkItemTypes(ctx, workitemtracking.GetWorkItemTypesArgs{
+		Project: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error getting work item types: %v", err)
+		return nil, fmt.Errorf("error getting work item types: %w", err)
+	}
+
+	results := []map[string]interface{}{}
+	if types == nil {
+		return results, nil
+	}
+	for _, t := range *types {
+		if t.Name == nil {
+			continue
+		}
+		states := []string{}
+		if t.States != nil {
+			for _, state := range *t.States {
+				states = append(states, stringValue(state.Name))
+			}
+		}
+		results = append(results, map[string]interface{}{
+			"name":          *t.Name,
+			"referenceName": stringValue(t.ReferenceName),
+			"description":   stringValue(t.Description),
+			"isDisabled":    t.IsDisabled != nil && *t.IsDisabled,
+			"states":        states,
+		})
+	}
+
+	return results, nil
+}
+
+func (c *AzureDevOpsClient) getWorkItemTypeFields(ctx context.Context, workItemType string) ([]workitems.TypeField, error) {
+	expand := workitemtracking.WorkItemTypeFieldsExpandLevelValues.AllowedValues
+	fields, err := c.witClient.GetWorkItemTypeFieldsWithReferences(ctx, workitemtracking.GetWorkItemTypeFieldsWithReferencesArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Type:    &workItemType,
+		Expand:  &expand,
+	})
+	if err != nil {
+		log.Printf("Error getting work item type fields: %v", err)
+		return nil, fmt.Errorf("error getting fields for work item type %s: %w", workItemType, err)
+	}
+
+	results := []workitems.TypeField{}
+	if fields == nil {
+		return results, nil
+	}
+	for _, field := range *fields {
+		f := workitems.TypeField{
+			Name:           stringValue(field.Name),
+			ReferenceName:  stringValue(field.ReferenceName),
+			AlwaysRequired: field.AlwaysRequired != nil && *field.AlwaysRequired,
+			HelpText:       stringValue(field.HelpText),
+			DefaultValue:   field.DefaultValue,
+		}
+		if field.AllowedValues != nil {
+			f.AllowedValues = *field.AllowedValues
+		}
+		results = append(results, f)
+	}
+
+	return results, nil
+}
+
+func (c *AzureDevOpsClient) getWorkItemTypeStates(ctx context.Context, workItemType string) ([]workitems.TypeState, error) {
+	t, err := c.witClient.GetWorkItemType(ctx, workitemtracking.GetWorkItemTypeArgs{
+		Project: &c.config.AzureDevOps.Project,
+		Type:    &workItemType,
+	})
+	if err != nil {
+		log.Printf("Error getting work item type: %v", err)
+		return nil, fmt.Errorf("error getting work item type %s: %w", workItemType, err)
+	}
+
+	results := []workitems.TypeState{}
+	if t.States == nil {
+		return results, nil
+	}
+	for _, state := range *t.States {
+		name := stringValue(state.Name)
+		s := workitems.TypeState{
+			Name:        name,
+			Category:    stringValue(state.Category),
+			Transitions: []string{},
+		}
+		if t.Transitions != nil {
+			for _, transition := range (*t.Transitions)[name] {
+				if transition.To != nil && *transition.To != name {
+					s.Transitions = append(s.Transitions, *transition.To)
+				}
+			}
+		}
+		results = append(results, s)
+	}
+
+	return results, nil
+}
+
+func (c *AzureDevOpsClient) queryWorkItemIDs(ctx context.Context, wiql string) ([]int, error) {
+	result, err := c.witClient.QueryByWiql(ctx, workitemtracking.QueryByWiqlArgs{
+		Wiql:    &workitemtracking.Wiql{Query: &wiql},
+		Project: &c.config.AzureDevOps.Project,
+	})
+	if err != nil {
+		log.Printf("Error running WIQL query: %v", err)
+		return nil, fmt.Errorf("error running WIQL query: %w", err)
+	}
+
+	ids := []int{}
+	if result.WorkItems != nil {
+		for _, ref := range *result.WorkItems {
+			if ref.Id != nil {
+				ids = append(ids, *ref.Id)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+func (c *AzureDevOpsClient) getWorkItemsBatch(ctx context.Context, ids []int, fields []string) ([]workitemtracking.WorkItem, error) {
+	return c.getWorkItemsBatchAsOf(ctx, ids, fields, nil)
+}
+
+// getWorkItemsBatchAsOf fetches work items as they were at asOf, or their current revision when asOf is nil.
+func (c *AzureDevOpsClient) getWorkItemsBatchAsOf(ctx context.Context, ids []int, fields []string, asOf *time.Time) ([]workitemtracking.WorkItem, error) {
+	errorPolicy := workitemtracking.WorkItemErrorPolicyValues.Omit
+	items := []workitemtracking.WorkItem{}
+	for start := 0; start < len(ids); start += maxWorkItemBatchSize {
+		end := start + maxWorkItemBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		request := &workitemtracking.WorkItemBatchGetRequest{
+			Ids:         &chunk,
+			Fields:      &fields,
+			ErrorPolicy: &errorPolicy,
+		}
+		if asOf != nil {
+			request.AsOf = &azuredevops.Time{Time: *asOf}
+		}
+
+		batch, err := c.witClient.GetWorkItemsBatch(ctx, workitemtracking.GetWorkItemsBatchArgs{
+			WorkItemGetRequest: request,
+			Project:            &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error getting work items: %v", err)
+			return nil, fmt.Errorf("error getting work items: %w", err)
+		}
+		if batch != nil {
+			items = append(items, *batch...)
+		}
+	}
+
+	return items, nil
+}
+
+func (c *AzureDevOpsClient) getWorkItemsWithRelations(ctx context.Context, ids []int) ([]workitemtracking.WorkItem, error) {
+	expand := workitemtracking.WorkItemExpandValues.Relations
+	errorPolicy := workitemtracking.WorkItemErrorPolicyValues.Omit
+	items := []workitemtracking.WorkItem{}
+	for start := 0; start < len(ids); start += maxWorkItemBatchSize {
+		end := start + maxWorkItemBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		batch, err := c.witClient.GetWorkItemsBatch(ctx, workitemtracking.GetWorkItemsBatchArgs{
+			WorkItemGetRequest: &workitemtracking.WorkItemBatchGetRequest{
+				Ids:         &chunk,
+				Expand:      &expand,
+				ErrorPolicy: &errorPolicy,
+			},
+			Project: &c.config.AzureDevOps.Project,
+		})
+		if err != nil {
+			log.Printf("Error getting work items: %v", err)
+			return nil, fmt.Errorf("error getting work items: %w", err)
+		}
+		if batch != nil {
+			items = append(items, *batch...)
+		}
+	}
+
+	return items, nil
+}
+
+// workItemSummary returns the fields of a work item that are useful in most listings.
+func (c *AzureDevOpsClient) workItemSummary(item workitemtracking.WorkItem) map[string]interface{} {
+	summary := map[string]interface{}{}
+	if item.Id != nil {
+		summary["id"] = *item.Id
+		summary["webUrl"] = c.links.workItem(c.config.AzureDevOps.Project, *item.Id)
+		summary["uri"] = c.idURI(workItemEntity, c.config.AzureDevOps.Project, *item.Id)
+	}
+	if item.Fields == nil {
+		return summary
+	}
+	fields := *item.Fields
+	summary["type"] = fields["System.WorkItemType"]
+	summary["title"] = fields["System.Title"]
+	summary["state"] = fields["System.State"]
+	if assignedTo := identityDisplayName(fields["System.AssignedTo"]); assignedTo != "" {
+		summary["assignedTo"] = assignedTo
+	}
+	return summary
+}
+
+// identityDisplayName extracts the display name from an identity field value.
+func identityDisplayName(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		name, _ := v["displayName"].(string)
+		return name
+	}
+	return ""
+}
+
+// relatedWorkItemIDs returns the IDs of the work items linked from item with the given relation type.
+func relatedWorkItemIDs(item workitemtracking.WorkItem, rel string) []int {
+	ids := []int{}
+	if item.Relations == nil {
+		return ids
+	}
+	for _, relation := range *item.Relations {
+		if relation.Rel == nil || *relation.Rel != rel || relation.Url == nil {
+			continue
+		}
+		url := *relation.Url
+		id, err := strconv.Atoi(url[strings.LastIndex(url, "/")+1:])
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// bulkUpdateWorkItems applies the same field changes and optional state transition to every
+// work item in ids, once each however often it is listed. Each item is validated against its
+// type's fields and transitions first, so a bad field name, picklist value, or transition skips
+// that item instead of failing the whole batch. The valid items are updated through the work item
+// $batch endpoint, up to maxWorkItemBatchSize per request, and each gets its own result. A dry run
+// returns the patch each valid item would get instead.
+func (c *AzureDevOpsClient) bulkUpdateWorkItems(ctx context.Context, ids []int, fields map[string]interface{}, state string, dryRun bool) ([]map[string]interface{}, error) {
+	unique := []int{}
+	seen := map[int]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	items, err := c.getWorkItemsBatch(ctx, unique, []string{"System.WorkItemType", "System.State"})
+	if err != nil {
+		return nil, err
+	}
+
+	// Values of picklists the process does not let users extend are checked too; without the
+	// organization's fields only field names are.
+	strict, err := c.strictPicklistFields(ctx)
+	if err != nil {
+		log.Printf("Updating work items without picklist validation: %v", err)
+		strict = map[string]bool{}
+	}
+	typeFields := map[string]map[string]bool{}
+	typeValues := map[string]map[string][]interface{}{}
+	typeStates := map[string]map[string][]string{}
+	results := map[int]map[string]interface{}{}
+	document := workItemPatchDocument(fields, state)
+	valid := []int{}
+
+	for _, item := range items {
+		if item.Id == nil || item.Fields == nil {
+			continue
+		}
+		id := *item.Id
+		workItemType, _ := (*item.Fields)["System.WorkItemType"].(string)
+		currentState, _ := (*item.Fields)["System.State"].(string)
+
+		if _, ok := typeFields[workItemType]; !ok {
+			fieldList, err := c.getWorkItemTypeFields(ctx, workItemType)
+			if err != nil {
+				return nil, err
+			}
+			names := map[string]bool{}
+			values := map[string][]interface{}{}
+			for _, f := range fieldList {
+				name := strings.ToLower(f.ReferenceName)
+				names[name] = true
+				if strict[name] && len(f.AllowedValues) > 0 {
+					values[name] = f.AllowedValues
+				}
+			}
+			typeFields[workItemType] = names
+			typeValues[workItemType] = values
+
+			stateList, err := c.getWorkItemTypeStates(ctx, workItemType)
+			if err != nil {
+				return nil, err
+			}
+			transitions := map[string][]string{}
+			for _, st := range stateList {
+				transitions[st.Name] = st.Transitions
+			}
+			typeStates[workItemType] = transitions
+		}
+
+		if problem := validateWorkItemUpdate(typeFields[workItemType], typeValues[workItemType], typeStates[workItemType], fields, currentState, state); problem != "" {
+			results[id] = map[string]interface{}{
+				"id":     id,
+				"status": "skipped",
+				"error":  problem,
+			}
+			continue
+		}
+		if dryRun {
+			results[id] = map[string]interface{}{
+				"id":           id,
+				"status":       "wouldUpdate",
+				"currentState": currentState,
+				"patch":        document,
+			}
+			continue
+		}
+		valid = append(valid, id)
+	}
+
+	for start := 0; start < len(valid); start += maxWorkItemBatchSize {
+		tools.ReportProgress(ctx, start, len(valid), "Updating work items")
+		end := start + maxWorkItemBatchSize
+		if end > len(valid) {
+			end = len(valid)
+		}
+		chunk := valid[start:end]
+		// Items already updated stay reported when the call is cancelled part way.
+		if ctx.Err() != nil {
+			for _, id := range chunk {
+				results[id] = map[string]interface{}{
+					"id":     id,
+					"status": "skipped",
+					"error":  "cancelled",
+				}
+			}
+			continue
+		}
+
+		responses, err := c.updateWorkItemsBatch(ctx, chunk, document)
+		for i, id := range chunk {
+			result := map[string]interface{}{"id": id, "status": "updated"}
+			switch {
+			case err != nil:
+				result["status"] = "failed"
+				result["error"] = err.Error()
+			case i >= len(responses):
+				result["status"] = "failed"
+				result["error"] = "the batch returned no result for this work item"
+			case responses[i].Code >= 300:
+				result["status"] = "failed"
+				result["error"] = responses[i].message()
+			}
+			results[id] = result
+		}
+	}
+
+	ordered := []map[string]interface{}{}
+	for _, id := range unique {
+		result, ok := results[id]
+		if !ok {
+			result = map[string]interface{}{
+				"id":     id,
+				"status": "skipped",
+				"error":  "work item not found",
+			}
+		}
+		ordered = append(ordered, result)
+	}
+	return ordered, nil
+}
+
+// workItemBatchResponse is the result of one request of a work item $batch call. Body holds the
+// JSON of the updated work item, or of the error.
+type workItemBatchResponse struct {
+	Code int    `json:"code"`
+	Body string `json:"body"`
+}
+
+// message returns the error message of a failed request.
+func (r workItemBatchResponse) message() string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(r.Body), &body) == nil && body.Message != "" {
+		return body.Message
+	}
+	return fmt.Sprintf("update failed with status %d", r.Code)
+}
+
+// updateWorkItemsBatch applies the same patch to each of ids in one request to the work item
+// $batch endpoint, and returns the result of each in the order of ids. The SDK has no client for
+// the endpoint, which takes a list of requests rather than a resource.
+func (c *AzureDevOpsClient) updateWorkItemsBatch(ctx context.Context, ids []int, document []webapi.JsonPatchOperation) ([]workItemBatchResponse, error) {
+	apiVersion := "7.1"
+	if c.config.AzureDevOps.APIVersion != "" {
+		apiVersion = c.config.AzureDevOps.APIVersion
+	}
+	requests := []map[string]interface{}{}
+	for _, id := range ids {
+		requests = append(requests, map[string]interface{}{
+			"method":  http.MethodPatch,
+			"uri":     fmt.Sprintf("/_apis/wit/workitems/%d?api-version=%s", id, apiVersion),
+			"headers": map[string]string{"Content-Type": "application/json-patch+json"},
+			"body":    document,
+		})
+	}
+	data, err := json.Marshal(requests)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding work item updates: %w", err)
+	}
+
+	client := azuredevops.NewClient(c.connection, c.connection.BaseUrl)
+	request, err := client.CreateRequestMessage(ctx, http.MethodPost, strings.TrimSuffix(c.connection.BaseUrl, "/")+"/_apis/wit/$batch", apiVersion, bytes.NewReader(data), azuredevops.MediaTypeApplicationJson, azuredevops.MediaTypeApplicationJson, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating work item batch request: %w", err)
+	}
+	response, err := client.SendRequest(request)
+	if err != nil {
+		log.Printf("Error updating work items: %v", err)
+		return nil, fmt.Errorf("error updating work items: %w", err)
+	}
+	var batch struct {
+		Value []workItemBatchResponse `json:"value"`
+	}
+	if err := client.UnmarshalBody(response, &batch); err != nil {
+		log.Printf("Error decoding work item batch response: %v", err)
+		return nil, fmt.Errorf("error decoding work item batch response: %w", err)
+	}
+	return batch.Value, nil
+}
+
+func validateWorkItemUpdate(knownFields map[string]bool, allowedValues map[string][]interface{}, transitions map[string][]string, fields map[string]interface{}, currentState, targetState string) string {
+	for name, value := range fields {
+		if !knownFields[strings.ToLower(name)] {
+			return fmt.Sprintf("field %s does not exist on this work item type", name)
+		}
+		if allowed, ok := allowedValues[strings.ToLower(name)]; ok && value != nil && !containsValue(allowed, value) {
+			return fmt.Sprintf("%v is not an allowed value of field %s", value, name)
+		}
+	}
+
+	if targetState == "" || strings.EqualFold(targetState, currentState) {
+		return ""
+	}
+	for _, next := range transitions[currentState] {
+		if strings.EqualFold(next, targetState) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("cannot transition from %s to %s", currentState, targetState)
+}
+
+// containsValue reports whether value is one of a picklist's values. Numbers arrive as float64 and
+// picklist values may be strings, so values are compared as text.
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if strings.EqualFold(fmt.Sprint(v), fmt.Sprint(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// addWorkItemComment posts text to the discussion of a work item. @mentions in text are resolved
+// so the people mentioned are notified.
+func (c *AzureDevOpsClient) addWorkItemComment(ctx context.Context, id int, text string, dryRun bool) (map[string]interface{}, error) {
+	text, mentioned, err := c.resolveMentions(ctx, text, htmlMentions)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return tools.DryRunResult("add_work_item_comment", map[string]interface{}{
+			"workItemId": id,
+			"text":       text,
+			"mentions":   mentionNames(mentioned),
+		}), nil
+	}
+
+	comment, err := c.witClient.AddComment(ctx, workitemtracking.AddCommentArgs{
+		Request:    &workitemtracking.CommentCreate{Text: &text},
+		Project:    &c.config.AzureDevOps.Project,
+		WorkItemId: &id,
+	})
+	if err != nil {
+		log.Printf("Error adding work item comment: %v", err)
+		return nil, fmt.Errorf("error commenting on work item %d: %w", id, err)
+	}
+	result := map[string]interface{}{
+		"workItemId": id,
+		"mentions":   mentionNames(mentioned),
+		"webUrl":     c.links.workItem(c.config.AzureDevOps.Project, id),
+	}
+	if comment.Id != nil {
+		result["commentId"] = *comment.Id
+	}
+	return result, nil
+}
+
+func workItemPatchDocument(fields map[string]interface{}, state string) []webapi.JsonPatchOperation {
+	document := []webapi.JsonPatchOperation{}
+	for name, value := range fields {
+		path := "/fields/" + name
+		document = append(document, webapi.JsonPatchOperation{
+			Op:    &webapi.OperationValues.Add,
+			Path:  &path,
+			Value: value,
+		})
+	}
+	if state != "" {
+		path := "/fields/System.State"
+		document = append(document, webapi.JsonPatchOperation{
+			Op:    &webapi.OperationValues.Add,
+			Path:  &path,
+			Value: state,
+		})
+	}
+	return document
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/signify/sgfy-mcp/config"
+)
+
+func TestUpdateWorkItemsBatch(t *testing.T) {
+	var requests []struct {
+		Method string        `json:"method"`
+		URI    string        `json:"uri"`
+		Body   []interface{} `json:"body"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.URL.Path != "/contoso/_apis/wit/$batch" {
+			t.Errorf("request = %s %s, want POST /contoso/_apis/wit/$batch", req.Method, req.URL.Path)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&requests); err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"count":2,"value":[{"code":200,"body":"{\"id\":1}"},{"code":400,"body":"{\"message\":\"TF401320: rule error\"}"}]}`))
+	}))
+	defer server.Close()
+
+	client := &AzureDevOpsClient{
+		config:     &config.Config{},
+		connection: azuredevops.NewPatConnection(server.URL+"/contoso", "pat"),
+	}
+	responses, err := client.updateWorkItemsBatch(context.Background(), []int{1, 2}, workItemPatchDocument(nil, "Done"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 || requests[0].Method != http.MethodPatch || requests[1].URI != "/_apis/wit/workitems/2?api-version=7.1" || len(requests[0].Body) != 1 {
+		t.Errorf("batch requests = %+v", requests)
+	}
+	if len(responses) != 2 || responses[0].Code != 200 || responses[1].message() != "TF401320: rule error" {
+		t.Errorf("responses = %+v", responses)
+	}
+}